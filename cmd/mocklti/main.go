@@ -0,0 +1,585 @@
+// Command mocklti is a local LTI 1.3 platform for trying the LTI integration during development.
+// The home page starts deep linking as a teacher, launches the links made by it as any user and
+// lists the scores the app sent back.
+//
+//	go run ./cmd/mocklti -addr :9091 -tool http://localhost:8080
+//
+// Then register the platform on /admin/lti with issuer http://localhost:9091, client ID saygenfix,
+// authentication request URL http://localhost:9091/authorize, access token URL
+// http://localhost:9091/token and public keyset URL http://localhost:9091/jwks.
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/jose"
+)
+
+const keyId = "mock-lti-1"
+
+const (
+	claimMessageType   = "https://purl.imsglobal.org/spec/lti/claim/message_type"
+	claimVersion       = "https://purl.imsglobal.org/spec/lti/claim/version"
+	claimDeploymentID  = "https://purl.imsglobal.org/spec/lti/claim/deployment_id"
+	claimTargetLinkURI = "https://purl.imsglobal.org/spec/lti/claim/target_link_uri"
+	claimRoles         = "https://purl.imsglobal.org/spec/lti/claim/roles"
+	claimContext       = "https://purl.imsglobal.org/spec/lti/claim/context"
+	claimResourceLink  = "https://purl.imsglobal.org/spec/lti/claim/resource_link"
+	claimCustom        = "https://purl.imsglobal.org/spec/lti/claim/custom"
+	claimAGSEndpoint   = "https://purl.imsglobal.org/spec/lti-ags/claim/endpoint"
+	claimDeepLinking   = "https://purl.imsglobal.org/spec/lti-dl/claim/deep_linking_settings"
+	claimContentItems  = "https://purl.imsglobal.org/spec/lti-dl/claim/content_items"
+	claimDeepLinkData  = "https://purl.imsglobal.org/spec/lti-dl/claim/data"
+	scopeScore         = "https://purl.imsglobal.org/spec/lti-ags/scope/score"
+	scopeLineItem      = "https://purl.imsglobal.org/spec/lti-ags/scope/lineitem"
+	roleInstructor     = "http://purl.imsglobal.org/vocab/lis/v2/membership#Instructor"
+	roleLearner        = "http://purl.imsglobal.org/vocab/lis/v2/membership#Learner"
+	clientAssertionJWT = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+)
+
+// launchRequest is started on the home page and finished when the tool sends the browser to /authorize
+type launchRequest struct {
+	Email       string
+	Name        string
+	Instructor  bool
+	DeepLinking bool
+	LinkId      string
+	ExpiresAt   time.Time
+}
+
+// link is a resource link made by deep linking, with the line item of its gradebook column
+type link struct {
+	Id         string
+	Title      string
+	URL        string
+	Custom     map[string]string
+	LineItemId string
+	Label      string
+	Maximum    float64
+}
+
+type score struct {
+	LineItemId string
+	UserId     string
+	Given      float64
+	Maximum    float64
+	Activity   string
+	Grading    string
+	Timestamp  string
+	ReceivedAt time.Time
+}
+
+type mockPlatform struct {
+	issuer       string
+	clientId     string
+	deploymentId string
+	toolURL      string
+	key          *rsa.PrivateKey
+	toolKeys     *jose.RemoteKeySet
+
+	mu       sync.Mutex
+	launches map[string]launchRequest
+	dlData   map[string]bool
+	tokens   map[string]time.Time
+	links    []link
+	scores   []score
+}
+
+var homeTemplate = template.Must(template.New("home").Parse(`<!DOCTYPE html>
+<html lang="id">
+<head><meta charset="UTF-8"><title>Mock LTI Platform</title></head>
+<body style="font-family: sans-serif; max-width: 760px; margin: 3rem auto;">
+    <h2>Mock LTI Platform</h2>
+    <p>Issuer <code>{{ .Issuer }}</code>, client ID <code>{{ .ClientId }}</code>, deployment <code>{{ .DeploymentId }}</code>, tool <code>{{ .ToolURL }}</code>.</p>
+
+    <h3>Tambah aktivitas (deep linking)</h3>
+    <form method="POST" action="/start">
+        <input type="hidden" name="action" value="deep-link">
+        <p><label>Email guru<br><input type="email" name="email" value="guru@sekolah.test" required style="width: 100%"></label></p>
+        <p><label>Nama<br><input type="text" name="name" value="Guru Contoh" style="width: 100%"></label></p>
+        <input type="hidden" name="role" value="instructor">
+        <button type="submit">Pilih ujian di tool</button>
+    </form>
+
+    <h3>Aktivitas</h3>
+    {{ if .Links }}
+    {{ range .Links }}
+    <fieldset style="margin-bottom: 1rem;">
+        <legend>{{ .Title }}</legend>
+        <p>Custom: {{ range $name, $value := .Custom }}<code>{{ $name }}={{ $value }}</code> {{ end }}</p>
+        <p>Line item: <code>{{ .LineItemId }}</code> {{ .Label }}, nilai maksimal {{ .Maximum }}</p>
+        <!-- Dibuka di jendela baru agar cookie session SameSite=Lax dari tool tetap terkirim -->
+        <form method="POST" action="/start" target="_blank">
+            <input type="hidden" name="action" value="launch">
+            <input type="hidden" name="link_id" value="{{ .Id }}">
+            <p><label>Email <input type="email" name="email" value="siswa@sekolah.test" required></label>
+            <label>Nama <input type="text" name="name" value="Siswa Contoh"></label>
+            <select name="role">
+                <option value="learner">Learner</option>
+                <option value="instructor">Instructor</option>
+            </select>
+            <button type="submit">Launch</button></p>
+        </form>
+    </fieldset>
+    {{ end }}
+    {{ else }}
+    <p>Belum ada aktivitas. Tambahkan lewat deep linking.</p>
+    {{ end }}
+
+    <h3>Nilai yang diterima</h3>
+    {{ if .Scores }}
+    <table border="1" cellpadding="4" style="border-collapse: collapse;">
+        <tr><th>Line item</th><th>User</th><th>Nilai</th><th>Progress</th><th>Timestamp</th><th>Diterima</th></tr>
+        {{ range .Scores }}
+        <tr>
+            <td><code>{{ .LineItemId }}</code></td>
+            <td><code>{{ .UserId }}</code></td>
+            <td>{{ .Given }} / {{ .Maximum }}</td>
+            <td>{{ .Activity }}, {{ .Grading }}</td>
+            <td>{{ .Timestamp }}</td>
+            <td>{{ .ReceivedAt.Format "15:04:05" }}</td>
+        </tr>
+        {{ end }}
+    </table>
+    {{ else }}
+    <p>Belum ada nilai.</p>
+    {{ end }}
+</body>
+</html>`))
+
+var autoPostTemplate = template.Must(template.New("auto-post").Parse(`<!DOCTYPE html>
+<html lang="id">
+<head><meta charset="UTF-8"><title>Mock LTI Platform</title></head>
+<body>
+    <form id="launch" method="POST" action="{{ .URL }}">
+        {{ range $name, $value := .Fields }}<input type="hidden" name="{{ $name }}" value="{{ $value }}">
+        {{ end }}
+        <noscript><button type="submit">Lanjutkan</button></noscript>
+    </form>
+    <script>document.getElementById('launch').submit();</script>
+</body>
+</html>`))
+
+func main() {
+	addr := flag.String("addr", ":9091", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9091", "issuer URL, must match the issuer registered in the app")
+	clientId := flag.String("client-id", "saygenfix", "client id of the tool")
+	deploymentId := flag.String("deployment-id", "1", "deployment id sent in every launch")
+	toolURL := flag.String("tool", "http://localhost:8080", "base URL of the app, the same as APP_BASE_URL")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		slog.Error("failed to generate signing key", "err", err)
+		os.Exit(1)
+	}
+
+	platform := &mockPlatform{
+		issuer:       strings.TrimSuffix(*issuer, "/"),
+		clientId:     *clientId,
+		deploymentId: *deploymentId,
+		toolURL:      strings.TrimSuffix(*toolURL, "/"),
+		key:          key,
+		launches:     map[string]launchRequest{},
+		dlData:       map[string]bool{},
+		tokens:       map[string]time.Time{},
+	}
+	platform.toolKeys = jose.NewRemoteKeySet(platform.toolURL+"/lti/jwks", &http.Client{Timeout: 10 * time.Second})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", platform.home)
+	mux.HandleFunc("GET /jwks", platform.jwks)
+	mux.HandleFunc("POST /start", platform.start)
+	mux.HandleFunc("GET /authorize", platform.authorize)
+	mux.HandleFunc("POST /authorize", platform.authorize)
+	mux.HandleFunc("POST /deep-link-return", platform.deepLinkReturn)
+	mux.HandleFunc("POST /token", platform.token)
+	mux.HandleFunc("POST /lineitems/{id}/scores", platform.postScore)
+
+	slog.Info("mock lti platform listening", "addr", *addr, "issuer", platform.issuer, "client_id", platform.clientId, "tool", platform.toolURL)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		slog.Error("mock lti platform stopped", "err", err)
+		os.Exit(1)
+	}
+}
+
+func (platform *mockPlatform) home(w http.ResponseWriter, r *http.Request) {
+	platform.mu.Lock()
+	data := map[string]any{
+		"Issuer":       platform.issuer,
+		"ClientId":     platform.clientId,
+		"DeploymentId": platform.deploymentId,
+		"ToolURL":      platform.toolURL,
+		"Links":        slices.Clone(platform.links),
+		"Scores":       slices.Clone(platform.scores),
+	}
+	platform.mu.Unlock()
+
+	if err := homeTemplate.Execute(w, data); err != nil {
+		slog.Error("failed to render home page", "err", err)
+	}
+}
+
+func (platform *mockPlatform) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, jose.KeySet{Keys: []jose.JSONWebKey{jose.PublicJSONWebKey(&platform.key.PublicKey, keyId)}})
+}
+
+// start sends the browser to the login initiation of the tool, the hint ties the launch to the form
+func (platform *mockPlatform) start(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	request := launchRequest{
+		Email:       strings.ToLower(strings.TrimSpace(r.PostFormValue("email"))),
+		Name:        r.PostFormValue("name"),
+		Instructor:  r.PostFormValue("role") == "instructor",
+		DeepLinking: r.PostFormValue("action") == "deep-link",
+		LinkId:      r.PostFormValue("link_id"),
+		ExpiresAt:   time.Now().Add(5 * time.Minute),
+	}
+	if !request.DeepLinking && platform.findLink(request.LinkId) == nil {
+		http.Error(w, "unknown link", http.StatusNotFound)
+		return
+	}
+
+	hint := randomString()
+	platform.mu.Lock()
+	platform.launches[hint] = request
+	platform.mu.Unlock()
+
+	query := url.Values{}
+	query.Set("iss", platform.issuer)
+	query.Set("login_hint", subject(request.Email))
+	query.Set("lti_message_hint", hint)
+	query.Set("target_link_uri", platform.toolURL+"/lti/launch")
+	query.Set("client_id", platform.clientId)
+	query.Set("lti_deployment_id", platform.deploymentId)
+
+	http.Redirect(w, r, platform.toolURL+"/lti/login?"+query.Encode(), http.StatusSeeOther)
+}
+
+// authorize answers the authentication request of the tool with a signed launch posted to redirect_uri
+func (platform *mockPlatform) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	if r.FormValue("scope") != "openid" || r.FormValue("response_type") != "id_token" ||
+		r.FormValue("response_mode") != "form_post" || r.FormValue("prompt") != "none" {
+		http.Error(w, "invalid authentication request", http.StatusBadRequest)
+		return
+	}
+	if r.FormValue("client_id") != platform.clientId || r.FormValue("redirect_uri") != platform.toolURL+"/lti/launch" {
+		http.Error(w, "unknown client or redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if r.FormValue("state") == "" || r.FormValue("nonce") == "" {
+		http.Error(w, "state and nonce are required", http.StatusBadRequest)
+		return
+	}
+
+	platform.mu.Lock()
+	request, ok := platform.launches[r.FormValue("lti_message_hint")]
+	delete(platform.launches, r.FormValue("lti_message_hint"))
+	platform.mu.Unlock()
+	if !ok || time.Now().After(request.ExpiresAt) || r.FormValue("login_hint") != subject(request.Email) {
+		http.Error(w, "unknown or expired launch", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := platform.launchClaims(request, r.FormValue("nonce"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	idToken, err := jose.Sign(platform.key, keyId, claims)
+	if err != nil {
+		slog.Error("failed to sign id token", "err", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = autoPostTemplate.Execute(w, map[string]any{
+		"URL":    r.FormValue("redirect_uri"),
+		"Fields": map[string]string{"id_token": idToken, "state": r.FormValue("state")},
+	})
+	if err != nil {
+		slog.Error("failed to render launch form", "err", err)
+	}
+}
+
+func (platform *mockPlatform) launchClaims(request launchRequest, nonce string) (map[string]any, error) {
+	now := time.Now()
+	role := roleLearner
+	if request.Instructor {
+		role = roleInstructor
+	}
+
+	claims := map[string]any{
+		"iss":              platform.issuer,
+		"aud":              platform.clientId,
+		"sub":              subject(request.Email),
+		"iat":              now.Unix(),
+		"exp":              now.Add(5 * time.Minute).Unix(),
+		"nonce":            nonce,
+		"email":            request.Email,
+		"name":             request.Name,
+		claimVersion:       "1.3.0",
+		claimDeploymentID:  platform.deploymentId,
+		claimTargetLinkURI: platform.toolURL + "/lti/launch",
+		claimRoles:         []string{role},
+		claimContext: map[string]string{
+			"id":    "course-1",
+			"title": "Kursus Contoh",
+		},
+	}
+
+	if request.DeepLinking {
+		data := randomString()
+		platform.mu.Lock()
+		platform.dlData[data] = true
+		platform.mu.Unlock()
+
+		claims[claimMessageType] = "LtiDeepLinkingRequest"
+		claims[claimDeepLinking] = map[string]any{
+			"deep_link_return_url":                 platform.issuer + "/deep-link-return",
+			"accept_types":                         []string{"ltiResourceLink"},
+			"accept_presentation_document_targets": []string{"iframe", "window"},
+			"accept_multiple":                      false,
+			"data":                                 data,
+		}
+		return claims, nil
+	}
+
+	found := platform.findLink(request.LinkId)
+	if found == nil {
+		return nil, fmt.Errorf("unknown link %q", request.LinkId)
+	}
+
+	claims[claimMessageType] = "LtiResourceLinkRequest"
+	claims[claimResourceLink] = map[string]string{
+		"id":    found.Id,
+		"title": found.Title,
+	}
+	claims[claimCustom] = found.Custom
+	claims[claimAGSEndpoint] = map[string]any{
+		"scope":    []string{scopeLineItem, scopeScore},
+		"lineitem": platform.issuer + "/lineitems/" + found.LineItemId,
+	}
+
+	return claims, nil
+}
+
+// deepLinkReturn verifies the response of the tool and adds the picked item as a link
+func (platform *mockPlatform) deepLinkReturn(w http.ResponseWriter, r *http.Request) {
+	claims, err := jose.Verify(r.Context(), r.PostFormValue("JWT"), platform.toolKeys.Key)
+	if err == nil {
+		err = jose.CheckExpiry(claims)
+	}
+	if err != nil {
+		http.Error(w, "invalid deep linking response: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if claims["iss"] != platform.clientId || !slices.Contains(jose.Strings(claims, "aud"), platform.issuer) ||
+		claims[claimMessageType] != "LtiDeepLinkingResponse" || claims[claimDeploymentID] != platform.deploymentId {
+		http.Error(w, "deep linking response has the wrong iss, aud, message type or deployment", http.StatusBadRequest)
+		return
+	}
+
+	data, _ := claims[claimDeepLinkData].(string)
+	platform.mu.Lock()
+	known := platform.dlData[data]
+	delete(platform.dlData, data)
+	platform.mu.Unlock()
+	if !known {
+		http.Error(w, "deep linking response does not return the data claim", http.StatusBadRequest)
+		return
+	}
+
+	var items []struct {
+		Type     string            `json:"type"`
+		Title    string            `json:"title"`
+		URL      string            `json:"url"`
+		Custom   map[string]string `json:"custom"`
+		LineItem *struct {
+			ScoreMaximum float64 `json:"scoreMaximum"`
+			Label        string  `json:"label"`
+		} `json:"lineItem"`
+	}
+	encoded, _ := json.Marshal(claims[claimContentItems])
+	if err := json.Unmarshal(encoded, &items); err != nil {
+		http.Error(w, "invalid content items: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	platform.mu.Lock()
+	for _, item := range items {
+		if item.Type != "ltiResourceLink" {
+			continue
+		}
+
+		added := link{Id: "link-" + randomString()[:8], Title: item.Title, URL: item.URL, Custom: item.Custom}
+		if item.LineItem != nil {
+			added.LineItemId = "lineitem-" + randomString()[:8]
+			added.Label = item.LineItem.Label
+			added.Maximum = item.LineItem.ScoreMaximum
+		}
+		platform.links = append(platform.links, added)
+	}
+	platform.mu.Unlock()
+
+	slog.Info("deep linking response accepted", "items", len(items))
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// token grants an access token to a client assertion signed with the tool key
+func (platform *mockPlatform) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	if r.PostFormValue("grant_type") != "client_credentials" || r.PostFormValue("client_assertion_type") != clientAssertionJWT {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	claims, err := jose.Verify(r.Context(), r.PostFormValue("client_assertion"), platform.toolKeys.Key)
+	if err == nil {
+		err = jose.CheckExpiry(claims)
+	}
+	if err != nil || claims["iss"] != platform.clientId || claims["sub"] != platform.clientId ||
+		!slices.Contains(jose.Strings(claims, "aud"), platform.issuer+"/token") {
+		slog.Warn("client assertion rejected", "err", err)
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	scopes := strings.Fields(r.PostFormValue("scope"))
+	if !slices.Contains(scopes, scopeScore) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_scope"})
+		return
+	}
+
+	accessToken := randomString()
+	platform.mu.Lock()
+	platform.tokens[accessToken] = time.Now().Add(time.Hour)
+	platform.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"scope":        scopeScore,
+	})
+}
+
+// postScore stores a score for the line item, the latest score per user is what a gradebook shows
+func (platform *mockPlatform) postScore(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	platform.mu.Lock()
+	expiresAt, known := platform.tokens[accessToken]
+	platform.mu.Unlock()
+	if !ok || !known || time.Now().After(expiresAt) {
+		http.Error(w, "invalid access token", http.StatusUnauthorized)
+		return
+	}
+	if r.Header.Get("Content-Type") != "application/vnd.ims.lis.v1.score+json" {
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var body struct {
+		UserID           string  `json:"userId"`
+		ScoreGiven       float64 `json:"scoreGiven"`
+		ScoreMaximum     float64 `json:"scoreMaximum"`
+		ActivityProgress string  `json:"activityProgress"`
+		GradingProgress  string  `json:"gradingProgress"`
+		Timestamp        string  `json:"timestamp"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.UserID == "" || body.Timestamp == "" {
+		http.Error(w, "invalid score", http.StatusBadRequest)
+		return
+	}
+
+	lineItemId := r.PathValue("id")
+	platform.mu.Lock()
+	known = slices.ContainsFunc(platform.links, func(l link) bool { return l.LineItemId == lineItemId })
+	if known {
+		platform.scores = append(platform.scores, score{
+			LineItemId: lineItemId,
+			UserId:     body.UserID,
+			Given:      body.ScoreGiven,
+			Maximum:    body.ScoreMaximum,
+			Activity:   body.ActivityProgress,
+			Grading:    body.GradingProgress,
+			Timestamp:  body.Timestamp,
+			ReceivedAt: time.Now(),
+		})
+	}
+	platform.mu.Unlock()
+	if !known {
+		http.Error(w, "unknown line item", http.StatusNotFound)
+		return
+	}
+
+	slog.Info("score received", "lineitem", lineItemId, "user", body.UserID, "score", body.ScoreGiven)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (platform *mockPlatform) findLink(linkId string) *link {
+	platform.mu.Lock()
+	defer platform.mu.Unlock()
+
+	for i := range platform.links {
+		if platform.links[i].Id == linkId {
+			found := platform.links[i]
+			return &found
+		}
+	}
+
+	return nil
+}
+
+// subject is stable per email, like the user id of a real platform
+func subject(email string) string {
+	sum := sha256.Sum256([]byte(email))
+
+	return "mock-" + hex.EncodeToString(sum[:8])
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Error("failed to write json response", "err", err)
+	}
+}
@@ -0,0 +1,301 @@
+// Command mockoidc is a local OpenID Connect provider for trying single sign-on during development.
+// Every login shows a form to pick the email, name and groups of the user, no password is asked.
+//
+//	go run ./cmd/mockoidc -addr :9090
+//
+// Then start the app with OIDC_ISSUER_URL=http://localhost:9090, OIDC_CLIENT_ID=saygenfix and
+// OIDC_CLIENT_SECRET=secret.
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"html/template"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const keyId = "mock-1"
+
+// authorization is what the token endpoint needs to redeem a code
+type authorization struct {
+	ClientId      string
+	RedirectURI   string
+	CodeChallenge string
+	Nonce         string
+	Claims        map[string]any
+	ExpiresAt     time.Time
+}
+
+type mockProvider struct {
+	issuer       string
+	clientId     string
+	clientSecret string
+	key          *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]authorization
+}
+
+var loginTemplate = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="id">
+<head><meta charset="UTF-8"><title>Mock OIDC Provider</title></head>
+<body style="font-family: sans-serif; max-width: 420px; margin: 3rem auto;">
+    <h2>Mock OIDC Provider</h2>
+    <p>Pilih identitas yang dikirim ke aplikasi.</p>
+    <form method="POST" action="/authorize">
+        {{ range $name, $value := .Params }}<input type="hidden" name="{{ $name }}" value="{{ $value }}">
+        {{ end }}
+        <p><label>Email<br><input type="email" name="email" value="guru@sekolah.test" required style="width: 100%"></label></p>
+        <p><label>Nama<br><input type="text" name="name" value="Guru Contoh" style="width: 100%"></label></p>
+        <p><label>Grup (pisahkan dengan koma)<br><input type="text" name="groups" value="teachers" style="width: 100%"></label></p>
+        <p><label><input type="checkbox" name="email_verified" value="true" checked> Email terverifikasi</label></p>
+        <button type="submit">Login</button>
+    </form>
+</body>
+</html>`))
+
+func main() {
+	addr := flag.String("addr", ":9090", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9090", "issuer URL, must match OIDC_ISSUER_URL")
+	clientId := flag.String("client-id", "saygenfix", "accepted client id")
+	clientSecret := flag.String("client-secret", "secret", "accepted client secret")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		slog.Error("failed to generate signing key", "err", err)
+		os.Exit(1)
+	}
+
+	provider := &mockProvider{
+		issuer:       strings.TrimSuffix(*issuer, "/"),
+		clientId:     *clientId,
+		clientSecret: *clientSecret,
+		key:          key,
+		codes:        map[string]authorization{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", provider.discovery)
+	mux.HandleFunc("GET /jwks", provider.jwks)
+	mux.HandleFunc("GET /authorize", provider.authorizeView)
+	mux.HandleFunc("POST /authorize", provider.authorize)
+	mux.HandleFunc("POST /token", provider.token)
+
+	slog.Info("mock oidc provider listening", "addr", *addr, "issuer", provider.issuer, "client_id", provider.clientId)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		slog.Error("mock oidc provider stopped", "err", err)
+		os.Exit(1)
+	}
+}
+
+func (provider *mockProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                provider.issuer,
+		"authorization_endpoint":                provider.issuer + "/authorize",
+		"token_endpoint":                        provider.issuer + "/token",
+		"jwks_uri":                              provider.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+	})
+}
+
+func (provider *mockProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	publicKey := provider.key.PublicKey
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyId,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+		}},
+	})
+}
+
+func (provider *mockProvider) authorizeView(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != provider.clientId || query.Get("response_type") != "code" || query.Get("redirect_uri") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	params := map[string]string{}
+	for _, name := range []string{"client_id", "redirect_uri", "state", "nonce", "code_challenge"} {
+		params[name] = query.Get(name)
+	}
+
+	if err := loginTemplate.Execute(w, map[string]any{"Params": params}); err != nil {
+		slog.Error("failed to render login form", "err", err)
+	}
+}
+
+// authorize issues a single-use code for the identity chosen in the form
+func (provider *mockProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	redirectURI, err := url.Parse(r.PostFormValue("redirect_uri"))
+	if err != nil || r.PostFormValue("client_id") != provider.clientId {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(r.PostFormValue("email")))
+	subject := sha256.Sum256([]byte(email))
+	groups := []string{}
+	for _, group := range strings.Split(r.PostFormValue("groups"), ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, group)
+		}
+	}
+
+	code := randomString()
+	provider.mu.Lock()
+	provider.codes[code] = authorization{
+		ClientId:      provider.clientId,
+		RedirectURI:   redirectURI.String(),
+		CodeChallenge: r.PostFormValue("code_challenge"),
+		Nonce:         r.PostFormValue("nonce"),
+		Claims: map[string]any{
+			"sub":            "mock-" + hex.EncodeToString(subject[:8]),
+			"email":          email,
+			"email_verified": r.PostFormValue("email_verified") == "true",
+			"name":           r.PostFormValue("name"),
+			"groups":         groups,
+		},
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+	provider.mu.Unlock()
+
+	query := redirectURI.Query()
+	query.Set("code", code)
+	query.Set("state", r.PostFormValue("state"))
+	redirectURI.RawQuery = query.Encode()
+
+	http.Redirect(w, r, redirectURI.String(), http.StatusSeeOther)
+}
+
+func (provider *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	// Client boleh mengirim kredensial lewat Basic auth atau form
+	clientId, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientId, clientSecret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	if clientId != provider.clientId || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(provider.clientSecret)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	provider.mu.Lock()
+	auth, ok := provider.codes[r.PostFormValue("code")]
+	delete(provider.codes, r.PostFormValue("code"))
+	provider.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || time.Now().After(auth.ExpiresAt) ||
+		auth.RedirectURI != r.PostFormValue("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(challenge[:]) != auth.CodeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]any{
+		"iss": provider.issuer,
+		"aud": auth.ClientId,
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	}
+	if auth.Nonce != "" {
+		claims["nonce"] = auth.Nonce
+	}
+	for name, value := range auth.Claims {
+		claims[name] = value
+	}
+
+	idToken, err := provider.sign(claims)
+	if err != nil {
+		slog.Error("failed to sign id token", "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+// sign builds an RS256 JWT
+func (provider *mockProvider) sign(claims map[string]any) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyId})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, provider.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Error("failed to write json response", "err", err)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"log/slog"
 	"net/http"
@@ -9,10 +10,14 @@ import (
 	"github.com/mhaatha/go-template-saygenfix/internal/config"
 	"github.com/mhaatha/go-template-saygenfix/internal/database"
 	"github.com/mhaatha/go-template-saygenfix/internal/handler"
+	"github.com/mhaatha/go-template-saygenfix/internal/lti"
+	"github.com/mhaatha/go-template-saygenfix/internal/mail"
 	"github.com/mhaatha/go-template-saygenfix/internal/middleware"
+	"github.com/mhaatha/go-template-saygenfix/internal/oidc"
 	"github.com/mhaatha/go-template-saygenfix/internal/repository"
 	"github.com/mhaatha/go-template-saygenfix/internal/router"
 	"github.com/mhaatha/go-template-saygenfix/internal/service"
+	"github.com/mhaatha/go-template-saygenfix/internal/storage"
 )
 
 func main() {
@@ -37,6 +42,34 @@ func main() {
 	}
 	defer db.Close()
 
+	// File store init
+	fileStore, err := storage.NewFileStore(cfg)
+	if err != nil {
+		slog.Error("failed to init file store", "err", err)
+		os.Exit(1)
+	}
+
+	// Mailer init
+	mailer, err := mail.NewMailer(cfg)
+	if err != nil {
+		slog.Error("failed to init mailer", "err", err)
+		os.Exit(1)
+	}
+
+	// Single sign-on provider init, nil when OIDC is not configured
+	oidcProvider, err := oidc.NewProviderFromConfig(cfg)
+	if err != nil {
+		slog.Error("failed to init oidc provider", "err", err)
+		os.Exit(1)
+	}
+
+	// LTI tool init, the key signs deep linking responses and grade passback token requests
+	ltiTool, err := lti.NewToolFromConfig(cfg)
+	if err != nil {
+		slog.Error("failed to init lti tool", "err", err)
+		os.Exit(1)
+	}
+
 	// Main ServeMux
 	mux := http.NewServeMux()
 
@@ -51,28 +84,101 @@ func main() {
 	mux.Handle("/assets/", http.StripPrefix("/assets", fileServer))
 	cssFileServer := http.FileServer(http.Dir("../../internal/templates/public/css"))
 	mux.Handle("/css/", http.StripPrefix("/css", cssFileServer))
+	jsFileServer := http.FileServer(http.Dir("../../internal/templates/public/js"))
+	mux.Handle("/js/", http.StripPrefix("/js", jsFileServer))
 
 	// User resources
 	userRepository := repository.NewUserRepository()
-	userService := service.NewUserService(userRepository, db, validate)
-	userHandler := handler.NewUserHandler(userService)
+	authRepository := repository.NewAuthRepository()
+	studentRepository := repository.NewStudentRepository()
+	userService := service.NewUserService(userRepository, authRepository, db, validate, cfg)
+	emailVerificationService := service.NewEmailVerificationService(userRepository, studentRepository, mailer, db, validate, cfg)
+	userHandler := handler.NewUserHandler(userService, emailVerificationService)
+	emailVerificationHandler := handler.NewEmailVerificationHandler(emailVerificationService)
 
 	// User router
 	router.UserRouter(userHandler, mux)
+	router.EmailVerificationRouter(emailVerificationHandler, mux)
 
 	// Authentication resources
-	authRepository := repository.NewAuthRepository()
-	authService := service.NewAuthService(authRepository, db, validate)
-	authHandler := handler.NewAuthHandler(authService, userService, cfg)
+	authService := service.NewAuthService(authRepository, db, validate, cfg)
+
+	// Two-factor resources
+	settingRepository := repository.NewSettingRepository()
+	twoFactorRepository := repository.NewTwoFactorRepository()
+	twoFactorService := service.NewTwoFactorService(twoFactorRepository, userRepository, authRepository, settingRepository, db, validate)
+	twoFactorHandler := handler.NewTwoFactorHandler(twoFactorService)
+
+	// Single sign-on resources
+	adminRepository := repository.NewAdminRepository()
+	userIdentityRepository := repository.NewUserIdentityRepository()
+	oidcService := service.NewOIDCService(oidcProvider, userRepository, userIdentityRepository, adminRepository, authRepository, db, validate, cfg)
+
+	authHandler := handler.NewAuthHandler(authService, userService, twoFactorService, oidcService, cfg)
 
 	// Authentication router
 	router.AuthRouter(authHandler, mux)
 
-	authMiddleware := middleware.NewAuthMiddleware(authService, cfg)
+	// Password reset resources
+	passwordResetRepository := repository.NewPasswordResetRepository()
+	passwordResetService := service.NewPasswordResetService(passwordResetRepository, userRepository, authRepository, mailer, db, validate, cfg)
+	passwordResetHandler := handler.NewPasswordResetHandler(passwordResetService, cfg)
+
+	// Password reset router
+	router.PasswordResetRouter(passwordResetHandler, mux)
+
+	// API token resources
+	apiTokenRepository := repository.NewAPITokenRepository()
+	apiTokenService := service.NewAPITokenService(apiTokenRepository, userRepository, db, validate)
+	apiTokenHandler := handler.NewAPITokenHandler(apiTokenService)
+
+	authMiddleware := middleware.NewAuthMiddleware(authService, apiTokenService, cfg)
+	twoFactorMiddleware := middleware.NewTwoFactorMiddleware(twoFactorService)
+
+	// Account resources
+	accountHandler := handler.NewAccountHandler(userService, emailVerificationService, cfg)
+	sessionHandler := handler.NewSessionHandler(authService)
+
+	// Account router, shared by every role
+	accountRouter := http.NewServeMux()
+	router.AccountRouter(accountHandler, accountRouter)
+	router.SessionRouter(sessionHandler, accountRouter)
+	router.APITokenRouter(apiTokenHandler, accountRouter)
+
+	// Middleware for account
+	mux.Handle("/account/", authMiddleware.Authenticate(authMiddleware.RequireAnyRole("student", "teacher", "admin")(accountRouter)))
+
+	// Two-factor router, only for teacher and admin
+	twoFactorRouter := http.NewServeMux()
+	router.TwoFactorRouter(twoFactorHandler, twoFactorRouter)
+
+	// Middleware for two-factor
+	twoFactorAccountHandler := authMiddleware.Authenticate(authMiddleware.RequireAnyRole("teacher", "admin")(twoFactorRouter))
+	mux.Handle("/account/two-factor", twoFactorAccountHandler)
+	mux.Handle("/account/two-factor/", twoFactorAccountHandler)
+
+	// Webhook resources, events are queued by the student and teacher services
+	webhookRepository := repository.NewWebhookRepository()
+	webhookService := service.NewWebhookService(webhookRepository, db, validate, cfg)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+
+	// Webhook router, only for teacher and admin
+	webhookRouter := http.NewServeMux()
+	router.WebhookRouter(webhookHandler, webhookRouter)
+
+	// Middleware for webhook
+	webhookAccountHandler := authMiddleware.Authenticate(authMiddleware.RequireAnyRole("teacher", "admin")(twoFactorMiddleware.RequireEnrollment(webhookRouter)))
+	mux.Handle("/account/webhooks", webhookAccountHandler)
+	mux.Handle("/account/webhooks/", webhookAccountHandler)
+
+	// Worker that sends queued webhook deliveries and retries the failed ones
+	go webhookService.RunDeliveries(context.Background(), cfg.WebhookPollInterval)
+
+	// LTI repository, scores of completed attempts are queued by the student service
+	ltiRepository := repository.NewLTIRepository()
 
 	// Student resources
-	studentRepository := repository.NewStudentRepository()
-	studentService := service.NewStudentService(studentRepository, db, validate, cfg)
+	studentService := service.NewStudentService(studentRepository, db, validate, cfg, webhookRepository, ltiRepository)
 	studentHandler := handler.NewStudentHandler(studentService)
 
 	// Student router with middleware
@@ -82,22 +188,93 @@ func main() {
 	// Middleware for student
 	mux.Handle("/student/", authMiddleware.Authenticate(authMiddleware.RequireRole("student")(studentRouter)))
 
+	// Material resources
+	materialRepository := repository.NewMaterialRepository()
+	materialService := service.NewMaterialService(materialRepository, db, fileStore)
+	materialHandler := handler.NewMaterialHandler(materialService)
+
 	// Teacher resources
 	teacherRepository := repository.NewTeacherRepository()
-	teacherService := service.NewTeacherService(teacherRepository, db, validate, cfg)
-	teacherHandler := handler.NewTeacherHandler(teacherService, studentService)
+	teacherService := service.NewTeacherService(teacherRepository, db, validate, cfg, fileStore, webhookRepository)
+
+	// Class resources
+	classRepository := repository.NewClassRepository()
+	classService := service.NewClassService(classRepository, teacherRepository, userRepository, db, validate)
+	classHandler := handler.NewClassHandler(classService)
+
+	teacherHandler := handler.NewTeacherHandler(teacherService, studentService, materialService, classService)
+
+	// Question bank resources
+	questionBankRepository := repository.NewQuestionBankRepository()
+	questionBankService := service.NewQuestionBankService(questionBankRepository, teacherRepository, db, validate)
+	questionBankHandler := handler.NewQuestionBankHandler(questionBankService)
+
+	// User import resources, shared by teacher and admin panel
+	userImportService := service.NewUserImportService(userRepository, classRepository, db, validate, cfg)
+	userImportHandler := handler.NewUserImportHandler(userImportService)
 
 	// Teacher router with middleware
 	teacherRouter := http.NewServeMux()
 	router.TeacherRouter(teacherHandler, teacherRouter)
+	router.MaterialRouter(materialHandler, teacherRouter)
+	router.QuestionBankRouter(questionBankHandler, teacherRouter)
+	router.ClassRouter(classHandler, teacherRouter)
+	router.UserImportRouter(userImportHandler, teacherRouter, "/teacher")
+
+	// Middleware for teacher, 2FA enrollment is checked when the admin requires it
+	mux.Handle("/teacher/", authMiddleware.Authenticate(authMiddleware.RequireRole("teacher")(twoFactorMiddleware.RequireEnrollment(teacherRouter))))
+
+	// Admin resources
+	adminService := service.NewAdminService(adminRepository, settingRepository, db, validate)
+	adminHandler := handler.NewAdminHandler(adminService)
+
+	// LTI resources, platforms are registered by admins
+	ltiService := service.NewLTIService(ltiTool, ltiRepository, userRepository, userIdentityRepository, authRepository, teacherRepository, studentRepository, db, validate, cfg)
+	ltiHandler := handler.NewLTIHandler(ltiService, twoFactorService, cfg)
+
+	// LTI router, called by the platform without a session
+	router.LTIRouter(ltiHandler, mux)
+
+	// Worker that sends the scores of LTI launches to the gradebook of the platform
+	go ltiService.RunScoreSync(context.Background(), cfg.LTIScoreSyncInterval)
+
+	// Admin router with middleware
+	adminRouter := http.NewServeMux()
+	router.AdminRouter(adminHandler, adminRouter)
+	router.UserImportRouter(userImportHandler, adminRouter, "/admin")
+	router.LTIPlatformRouter(ltiHandler, adminRouter)
+
+	// Middleware for admin
+	mux.Handle("/admin/", authMiddleware.Authenticate(authMiddleware.RequireRole("admin")(adminRouter)))
+
+	// JSON API resources, every route accepts a personal API token or the session cookie
+	teacherAPIHandler := handler.NewTeacherAPIHandler(teacherService)
+	studentAPIHandler := handler.NewStudentAPIHandler(studentService)
+
+	// JSON API router, unknown routes also answer with a JSON error
+	teacherAPIRouter := http.NewServeMux()
+	router.TeacherAPIRouter(teacherAPIHandler, teacherAPIRouter, authMiddleware)
+	teacherAPIRouter.HandleFunc("/api/v1/teacher/", handler.APINotFound)
+
+	studentAPIRouter := http.NewServeMux()
+	router.StudentAPIRouter(studentAPIHandler, studentAPIRouter, authMiddleware)
+	studentAPIRouter.HandleFunc("/api/v1/student/", handler.APINotFound)
+
+	// Middleware for JSON API
+	mux.Handle("/api/v1/teacher/", authMiddleware.Authenticate(authMiddleware.RequireRole("teacher")(twoFactorMiddleware.RequireEnrollment(teacherAPIRouter))))
+	mux.Handle("/api/v1/student/", authMiddleware.Authenticate(authMiddleware.RequireRole("student")(studentAPIRouter)))
+
+	// OpenAPI document of the JSON API, public so clients can be generated from it
+	mux.HandleFunc("GET /api/openapi.json", handler.OpenAPI)
+	mux.HandleFunc("/api/", handler.APINotFound)
 
-	// Middleware for teacher
-	mux.Handle("/teacher/", authMiddleware.Authenticate(authMiddleware.RequireRole("teacher")(teacherRouter)))
+	// Every state-changing request must carry the CSRF token
+	csrfMiddleware := middleware.NewCSRFMiddleware()
 
 	// Server
 	server := http.Server{
 		Addr:    ":" + cfg.AppPort,
-		Handler: mux,
+		Handler: csrfMiddleware.Protect(mux),
 	}
 
 	slog.Info("starting server on :" + cfg.AppPort)
@@ -2,6 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -10,13 +13,81 @@ type Config struct {
 	AppPort string
 	DBURL   string
 
+	// AppBaseURL is used to build absolute links in emails, such as password reset links
+	AppBaseURL string
+	// AppSecret signs tokens such as email verification links
+	AppSecret string
+
 	SessionName   string
 	SessionMaxAge string
 
-	GeminiAPIKey string
+	// Login throttling, failures are counted per email and per IP within LoginLockoutDuration
+	LoginMaxFailures     int
+	LoginIPMaxFailures   int
+	LoginLockoutDuration time.Duration
+	// TrustedProxyHops is the number of reverse proxies in front of the app. The client IP is read
+	// from X-Forwarded-For that many entries from the right, 0 ignores the header.
+	TrustedProxyHops int
+
+	// EmailVerificationPolicy decides what unverified accounts can not do: "login" or "exam", empty means no restriction
+	EmailVerificationPolicy string
+
+	// TeacherAutoApproveDomains lists email domains whose teacher sign-ups are approved once the email is verified
+	TeacherAutoApproveDomains []string
+
+	// OpenID Connect single sign-on, enabled when the issuer and client id are set
+	OIDCIssuerURL    string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+	OIDCScopes       []string
+	// OIDCProviderName is the label of the SSO button on the login page
+	OIDCProviderName string
+	// OIDCRoleClaim names the ID token claim with the groups of the user, the groups listed in
+	// OIDCAdminGroups and OIDCTeacherGroups map to those roles, everyone else gets OIDCDefaultRole
+	OIDCRoleClaim     string
+	OIDCAdminGroups   []string
+	OIDCTeacherGroups []string
+	OIDCDefaultRole   string
+
+	GeminiAPIKey       string
+	GenerationCacheTTL string
 
 	ScoringAPIURL string
 	ScoringAPIKey string
+
+	// Outbound webhooks, a delivery is given up after WebhookMaxAttempts failed attempts
+	WebhookTimeout      time.Duration
+	WebhookMaxAttempts  int
+	WebhookPollInterval time.Duration
+	// WebhookAllowPrivateNetworks allows endpoints on localhost and private addresses, only for development
+	WebhookAllowPrivateNetworks bool
+
+	// LTI 1.3 tool, LTIPrivateKeyFile is a PEM RSA key that signs messages to the platforms. Without
+	// it a new key is made on every start and platforms that cached the old one must fetch /lti/jwks again.
+	LTIPrivateKeyFile string
+	// Scores are sent back to the platform by a worker, a failed sync is given up after LTIScoreMaxAttempts attempts
+	LTIScoreSyncInterval time.Duration
+	LTIScoreMaxAttempts  int
+
+	FileStoreDriver   string
+	FileStoreLocalDir string
+
+	S3Endpoint     string
+	S3Region       string
+	S3Bucket       string
+	S3AccessKey    string
+	S3SecretKey    string
+	S3UsePathStyle bool
+
+	MailDriver string
+	MailFrom   string
+	MailLogDir string
+
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
 }
 
 func LoadConfig() (*Config, error) {
@@ -29,12 +100,113 @@ func LoadConfig() (*Config, error) {
 		AppPort: os.Getenv("APP_PORT"),
 		DBURL:   os.Getenv("DB_URL"),
 
+		AppBaseURL: appBaseURL(),
+		AppSecret:  os.Getenv("APP_SECRET"),
+
 		SessionName:   os.Getenv("SESSION_NAME"),
 		SessionMaxAge: os.Getenv("SESSION_MAX_AGE"),
 
-		GeminiAPIKey: os.Getenv("GEMINI_API_KEY"),
+		LoginMaxFailures:     intEnv("LOGIN_MAX_FAILURES", 5),
+		LoginIPMaxFailures:   intEnv("LOGIN_IP_MAX_FAILURES", 20),
+		LoginLockoutDuration: time.Duration(intEnv("LOGIN_LOCKOUT_MINUTES", 15)) * time.Minute,
+		TrustedProxyHops:     trustedProxyHops(),
+
+		EmailVerificationPolicy: os.Getenv("EMAIL_VERIFICATION_POLICY"),
+
+		TeacherAutoApproveDomains: splitList(strings.ToLower(os.Getenv("TEACHER_AUTO_APPROVE_DOMAINS"))),
+
+		OIDCIssuerURL:     strings.TrimSuffix(os.Getenv("OIDC_ISSUER_URL"), "/"),
+		OIDCClientID:      os.Getenv("OIDC_CLIENT_ID"),
+		OIDCClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+		OIDCRedirectURL:   stringEnv("OIDC_REDIRECT_URL", appBaseURL()+"/auth/oidc/callback"),
+		OIDCScopes:        splitList(stringEnv("OIDC_SCOPES", "openid,email,profile")),
+		OIDCProviderName:  stringEnv("OIDC_PROVIDER_NAME", "SSO Sekolah"),
+		OIDCRoleClaim:     stringEnv("OIDC_ROLE_CLAIM", "groups"),
+		OIDCAdminGroups:   splitList(os.Getenv("OIDC_ADMIN_GROUPS")),
+		OIDCTeacherGroups: splitList(os.Getenv("OIDC_TEACHER_GROUPS")),
+		OIDCDefaultRole:   stringEnv("OIDC_DEFAULT_ROLE", "student"),
+
+		GeminiAPIKey:       os.Getenv("GEMINI_API_KEY"),
+		GenerationCacheTTL: os.Getenv("GENERATION_CACHE_TTL"),
 
 		ScoringAPIURL: os.Getenv("SCORING_API_URL"),
 		ScoringAPIKey: os.Getenv("SCORING_API_KEY"),
+
+		WebhookTimeout:              time.Duration(intEnv("WEBHOOK_TIMEOUT_SECONDS", 10)) * time.Second,
+		WebhookMaxAttempts:          intEnv("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookPollInterval:         time.Duration(intEnv("WEBHOOK_POLL_INTERVAL_SECONDS", 5)) * time.Second,
+		WebhookAllowPrivateNetworks: os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS") == "true",
+
+		LTIPrivateKeyFile:    os.Getenv("LTI_PRIVATE_KEY_FILE"),
+		LTIScoreSyncInterval: time.Duration(intEnv("LTI_SCORE_SYNC_INTERVAL_SECONDS", 10)) * time.Second,
+		LTIScoreMaxAttempts:  intEnv("LTI_SCORE_MAX_ATTEMPTS", 8),
+
+		FileStoreDriver:   os.Getenv("FILE_STORE_DRIVER"),
+		FileStoreLocalDir: os.Getenv("FILE_STORE_LOCAL_DIR"),
+
+		S3Endpoint:     os.Getenv("S3_ENDPOINT"),
+		S3Region:       os.Getenv("S3_REGION"),
+		S3Bucket:       os.Getenv("S3_BUCKET"),
+		S3AccessKey:    os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey:    os.Getenv("S3_SECRET_KEY"),
+		S3UsePathStyle: os.Getenv("S3_USE_PATH_STYLE") == "true",
+
+		MailDriver: os.Getenv("MAIL_DRIVER"),
+		MailFrom:   os.Getenv("MAIL_FROM"),
+		MailLogDir: os.Getenv("MAIL_LOG_DIR"),
+
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     os.Getenv("SMTP_PORT"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
 	}, nil
 }
+
+// splitList parses a comma separated env value, empty items are dropped
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// appBaseURL reads APP_BASE_URL without the trailing slash, local development falls back to localhost
+func appBaseURL() string {
+	if baseURL := strings.TrimSuffix(os.Getenv("APP_BASE_URL"), "/"); baseURL != "" {
+		return baseURL
+	}
+
+	return "http://localhost:" + os.Getenv("APP_PORT")
+}
+
+// stringEnv reads an env value, fallback is used when it is empty
+func stringEnv(name, fallback string) string {
+	if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+// trustedProxyHops reads TRUSTED_PROXY_HOPS, TRUST_PROXY_HEADERS=true alone means one proxy
+func trustedProxyHops() int {
+	if os.Getenv("TRUST_PROXY_HEADERS") != "true" {
+		return 0
+	}
+
+	return intEnv("TRUSTED_PROXY_HOPS", 1)
+}
+
+// intEnv reads a positive number, fallback is used when the value is empty or invalid
+func intEnv(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+
+	return value
+}
@@ -1,13 +1,65 @@
+DROP TABLE IF EXISTS password_reset_requests;
+
+DROP TABLE IF EXISTS lti_link_users;
+
+DROP TABLE IF EXISTS lti_resource_links;
+
+DROP TABLE IF EXISTS lti_login_states;
+
+DROP TABLE IF EXISTS lti_platforms;
+
+DROP TABLE IF EXISTS webhook_deliveries;
+
+DROP TABLE IF EXISTS webhook_endpoints;
+
+DROP TABLE IF EXISTS api_token_usages;
+
+DROP TABLE IF EXISTS api_tokens;
+
+DROP TABLE IF EXISTS user_identities;
+
+DROP TABLE IF EXISTS system_settings;
+
+DROP TABLE IF EXISTS two_factor_challenges;
+
+DROP TABLE IF EXISTS user_recovery_codes;
+
+DROP TABLE IF EXISTS login_attempts;
+
+DROP TABLE IF EXISTS password_reset_tokens;
+
+DROP TABLE IF EXISTS generation_cache;
+
 DROP TABLE IF EXISTS student_answers;
 
 DROP TABLE IF EXISTS exam_attempts;
 
+DROP TABLE IF EXISTS exam_members;
+
+DROP TABLE IF EXISTS exam_collaborators;
+
+DROP TABLE IF EXISTS exam_classes;
+
+DROP TABLE IF EXISTS class_invitations;
+
+DROP TABLE IF EXISTS class_members;
+
+DROP TABLE IF EXISTS classes;
+
 DROP TABLE IF EXISTS questions;
 
+DROP TABLE IF EXISTS bank_question_tags;
+
+DROP TABLE IF EXISTS bank_questions;
+
 DROP TABLE IF EXISTS exams;
 
+DROP TABLE IF EXISTS materials;
+
 DROP TABLE IF EXISTS sessions;
 
 DROP TABLE IF EXISTS users;
 
+DROP TYPE IF EXISTS exam_role;
+
 DROP TYPE IF EXISTS user_role;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE exams
+ADD COLUMN source_file_uri TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,18 @@
+CREATE TABLE materials (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    teacher_id UUID NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_hash CHAR(64) NOT NULL,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    storage_key TEXT NOT NULL,
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (teacher_id, content_hash),
+    CONSTRAINT fk_teacher
+        FOREIGN KEY(teacher_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+ALTER TABLE exams
+ADD COLUMN material_id UUID REFERENCES materials(id) ON DELETE SET NULL;
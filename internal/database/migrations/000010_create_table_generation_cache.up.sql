@@ -0,0 +1,10 @@
+CREATE TABLE generation_cache (
+    cache_key CHAR(64) PRIMARY KEY,
+    model VARCHAR(100) NOT NULL,
+    total_question INT NOT NULL,
+    qa_items JSONB NOT NULL,
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_generation_cache_expires_at ON generation_cache (expires_at);
@@ -0,0 +1,31 @@
+CREATE TABLE bank_questions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    teacher_id UUID NOT NULL,
+    question TEXT NOT NULL,
+    correct_answer TEXT NOT NULL,
+    topic VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_teacher
+        FOREIGN KEY(teacher_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE bank_question_tags (
+    bank_question_id UUID NOT NULL,
+    tag VARCHAR(50) NOT NULL,
+
+    PRIMARY KEY (bank_question_id, tag),
+    CONSTRAINT fk_bank_question
+        FOREIGN KEY(bank_question_id)
+        REFERENCES bank_questions(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_bank_question_tags_tag ON bank_question_tags(tag);
+
+-- Soal ujian yang diambil dari bank hanya menyimpan referensi, teks soal dan jawaban dibaca dari bank_questions
+ALTER TABLE questions
+ADD COLUMN bank_question_id UUID REFERENCES bank_questions(id) ON DELETE RESTRICT;
@@ -0,0 +1,9 @@
+-- Pengaturan urutan soal per ujian, question_draw_count 0 berarti semua soal dipakai
+ALTER TABLE exams
+ADD COLUMN shuffle_questions BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN question_draw_count INT NOT NULL DEFAULT 0;
+
+-- Setiap attempt menyimpan seed dan daftar soal miliknya sendiri, kosong untuk attempt lama
+ALTER TABLE exam_attempts
+ADD COLUMN question_seed BIGINT NOT NULL DEFAULT 0,
+ADD COLUMN question_ids UUID[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,14 @@
+-- Ujian privat hanya bisa diikuti siswa yang sudah bergabung dengan join code
+ALTER TABLE exams
+ADD COLUMN is_private BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN join_code VARCHAR(8) UNIQUE;
+
+CREATE TABLE exam_members (
+    exam_id VARCHAR(100) NOT NULL,
+    student_id UUID NOT NULL,
+    joined_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (exam_id, student_id),
+    FOREIGN KEY (exam_id) REFERENCES exams(id) ON DELETE CASCADE,
+    FOREIGN KEY (student_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,45 @@
+CREATE TABLE classes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    teacher_id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    join_code VARCHAR(8) UNIQUE,
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_teacher
+        FOREIGN KEY(teacher_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE class_members (
+    class_id UUID NOT NULL,
+    student_id UUID NOT NULL,
+    joined_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (class_id, student_id),
+    FOREIGN KEY (class_id) REFERENCES classes(id) ON DELETE CASCADE,
+    FOREIGN KEY (student_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Undangan untuk email yang belum terdaftar, diklaim saat siswa membuka dashboard
+CREATE TABLE class_invitations (
+    class_id UUID NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    invited_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (class_id, email),
+    FOREIGN KEY (class_id) REFERENCES classes(id) ON DELETE CASCADE
+);
+
+-- Ujian yang ditugaskan ke kelas hanya terlihat oleh anggota kelas tersebut
+CREATE TABLE exam_classes (
+    exam_id VARCHAR(100) NOT NULL,
+    class_id UUID NOT NULL,
+
+    PRIMARY KEY (exam_id, class_id),
+    FOREIGN KEY (exam_id) REFERENCES exams(id) ON DELETE CASCADE,
+    FOREIGN KEY (class_id) REFERENCES classes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_class_members_student_id ON class_members(student_id);
@@ -0,0 +1,21 @@
+CREATE TYPE exam_role
+AS
+ENUM('owner', 'editor', 'grader', 'viewer');
+
+CREATE TABLE exam_collaborators (
+    exam_id VARCHAR(100) NOT NULL,
+    teacher_id UUID NOT NULL,
+    role exam_role NOT NULL DEFAULT 'viewer',
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (exam_id, teacher_id),
+    FOREIGN KEY (exam_id) REFERENCES exams(id) ON DELETE CASCADE,
+    FOREIGN KEY (teacher_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_exam_collaborators_teacher_id ON exam_collaborators(teacher_id);
+
+-- Pembuat ujian yang sudah ada menjadi owner, exams.teacher_id tetap menyimpan pembuat ujian
+INSERT INTO exam_collaborators (exam_id, teacher_id, role)
+SELECT id, teacher_id, 'owner'
+FROM exams;
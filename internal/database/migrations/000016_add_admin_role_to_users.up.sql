@@ -0,0 +1,9 @@
+ALTER TYPE user_role ADD VALUE IF NOT EXISTS 'admin';
+
+-- Akun yang dinonaktifkan admin tidak bisa login, is_approved dipakai untuk persetujuan pendaftaran guru
+ALTER TABLE users
+    ADD COLUMN is_disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN is_approved BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Admin pertama dibuat manual, contoh:
+-- UPDATE users SET role = 'admin' WHERE email = 'admin@sekolah.sch.id';
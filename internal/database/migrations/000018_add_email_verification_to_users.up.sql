@@ -0,0 +1,6 @@
+-- Akun lama dianggap sudah terverifikasi agar tidak terkunci saat kebijakan verifikasi diaktifkan
+ALTER TABLE users
+    ADD COLUMN email_verified_at TIMESTAMP(0) WITHOUT TIME ZONE,
+    ADD COLUMN email_verification_sent_at TIMESTAMP(0) WITHOUT TIME ZONE;
+
+UPDATE users SET email_verified_at = created_at;
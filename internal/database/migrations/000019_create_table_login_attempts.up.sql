@@ -0,0 +1,11 @@
+-- Riwayat percobaan login untuk pembatasan brute-force dan konsol admin
+CREATE TABLE login_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    ip_address VARCHAR(64) NOT NULL,
+    is_success BOOLEAN NOT NULL,
+    attempted_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_login_attempts_email ON login_attempts(email, attempted_at);
+CREATE INDEX idx_login_attempts_ip_address ON login_attempts(ip_address, attempted_at);
@@ -0,0 +1,11 @@
+-- Info perangkat untuk halaman sesi aktif, id dipakai di URL agar session_id tidak pernah ditampilkan
+ALTER TABLE sessions
+    ADD COLUMN id UUID NOT NULL DEFAULT gen_random_uuid(),
+    ADD COLUMN user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    ADD COLUMN ip_address VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN last_seen_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD CONSTRAINT uq_sessions_id UNIQUE (id);
+
+UPDATE sessions SET last_seen_at = created_at;
+
+CREATE INDEX idx_sessions_user_id ON sessions(user_id, last_seen_at);
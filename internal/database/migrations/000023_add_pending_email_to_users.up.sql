@@ -0,0 +1,3 @@
+-- Email baru menunggu konfirmasi dari link yang dikirim ke alamat tersebut
+ALTER TABLE users
+    ADD COLUMN pending_email VARCHAR(255);
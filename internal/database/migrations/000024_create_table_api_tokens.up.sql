@@ -0,0 +1,38 @@
+-- Token API pribadi, hanya hash yang disimpan. Token yang dicabut tidak dihapus agar riwayat pemakaiannya tetap ada
+CREATE TABLE api_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    token_prefix VARCHAR(16) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+    last_used_at TIMESTAMP(0) WITHOUT TIME ZONE,
+    revoked_at TIMESTAMP(0) WITHOUT TIME ZONE,
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_api_tokens_user_id ON api_tokens(user_id);
+
+-- Jejak audit setiap request yang memakai token
+CREATE TABLE api_token_usages (
+    id BIGSERIAL PRIMARY KEY,
+    token_id UUID NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    path VARCHAR(255) NOT NULL,
+    ip_address VARCHAR(64) NOT NULL,
+    user_agent VARCHAR(512) NOT NULL,
+    used_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_api_token
+        FOREIGN KEY(token_id)
+        REFERENCES api_tokens(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_api_token_usages_token_id ON api_token_usages(token_id, used_at);
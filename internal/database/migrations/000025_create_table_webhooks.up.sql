@@ -0,0 +1,43 @@
+-- Endpoint webhook milik guru atau admin. Secret disimpan apa adanya karena dipakai untuk menandatangani payload
+CREATE TABLE webhook_endpoints (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(100) NOT NULL,
+    events TEXT[] NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhook_endpoints_user_id ON webhook_endpoints(user_id);
+
+-- Antrian pengiriman, setiap event menjadi satu baris per endpoint dan dicoba ulang sampai berhasil atau menyerah.
+-- Payload disimpan sebagai TEXT agar byte yang ditandatangani sama persis dengan yang dibuat aplikasi
+CREATE TABLE webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    endpoint_id UUID NOT NULL,
+    event VARCHAR(50) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_status_code INT NOT NULL DEFAULT 0,
+    last_error VARCHAR(1000) NOT NULL DEFAULT '',
+    last_attempt_at TIMESTAMP(0) WITHOUT TIME ZONE,
+    delivered_at TIMESTAMP(0) WITHOUT TIME ZONE,
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_webhook_endpoint
+        FOREIGN KEY(endpoint_id)
+        REFERENCES webhook_endpoints(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_endpoint_id ON webhook_deliveries(endpoint_id, created_at);
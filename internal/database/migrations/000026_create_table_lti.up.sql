@@ -0,0 +1,86 @@
+-- Platform LTI 1.3 (Moodle, Canvas, dan lainnya) yang didaftarkan admin. deployment_ids kosong berarti semua deployment diterima
+CREATE TABLE lti_platforms (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    issuer VARCHAR(255) NOT NULL,
+    client_id VARCHAR(255) NOT NULL,
+    auth_login_url VARCHAR(2048) NOT NULL,
+    auth_token_url VARCHAR(2048) NOT NULL,
+    jwks_url VARCHAR(2048) NOT NULL,
+    deployment_ids TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (issuer, client_id)
+);
+
+-- State dan nonce dari login initiation, dipakai sekali saat launch. Tidak disimpan di cookie
+-- karena launch adalah POST lintas situs yang tidak membawa cookie SameSite=Lax
+CREATE TABLE lti_login_states (
+    state VARCHAR(100) PRIMARY KEY,
+    platform_id UUID NOT NULL,
+    nonce VARCHAR(100) NOT NULL,
+    expires_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+
+    CONSTRAINT fk_lti_platform
+        FOREIGN KEY(platform_id)
+        REFERENCES lti_platforms(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_lti_login_states_expires_at ON lti_login_states(expires_at);
+
+-- Link di kursus platform yang membuka satu ujian, lineitem_url kosong berarti nilai tidak dikirim balik
+CREATE TABLE lti_resource_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    platform_id UUID NOT NULL,
+    deployment_id VARCHAR(255) NOT NULL,
+    resource_link_id VARCHAR(255) NOT NULL,
+    exam_id VARCHAR(100) NOT NULL,
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    context_id VARCHAR(255) NOT NULL DEFAULT '',
+    context_title VARCHAR(255) NOT NULL DEFAULT '',
+    lineitem_url VARCHAR(2048) NOT NULL DEFAULT '',
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (platform_id, resource_link_id),
+    CONSTRAINT fk_lti_platform
+        FOREIGN KEY(platform_id)
+        REFERENCES lti_platforms(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_exam
+        FOREIGN KEY(exam_id)
+        REFERENCES exams(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_lti_resource_links_exam_id ON lti_resource_links(exam_id);
+
+-- Pengguna yang pernah membuka link beserta id-nya di platform, sekaligus antrian pengiriman nilai.
+-- Nilai terbaik dari attempt yang selesai dibaca saat dikirim, jadi satu baris cukup per pengguna
+CREATE TABLE lti_link_users (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    resource_link_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    lti_user_id VARCHAR(255) NOT NULL,
+    score_status VARCHAR(20) NOT NULL DEFAULT 'none',
+    score_attempts INT NOT NULL DEFAULT 0,
+    next_sync_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error VARCHAR(1000) NOT NULL DEFAULT '',
+    last_score SMALLINT,
+    synced_at TIMESTAMP(0) WITHOUT TIME ZONE,
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (resource_link_id, user_id),
+    CONSTRAINT fk_lti_resource_link
+        FOREIGN KEY(resource_link_id)
+        REFERENCES lti_resource_links(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_lti_link_users_due ON lti_link_users(next_sync_at) WHERE score_status = 'pending';
+CREATE INDEX idx_lti_link_users_user_id ON lti_link_users(user_id);
@@ -0,0 +1,11 @@
+-- Soal ujian menyimpan salinan teks dari bank, bank_question_id hanya menandai asal soal
+UPDATE questions q
+SET question = b.question, correct_answer = b.correct_answer
+FROM bank_questions b
+WHERE b.id = q.bank_question_id;
+
+-- Menghapus soal dari bank tidak mengubah ujian yang sudah memakainya
+ALTER TABLE questions
+    DROP CONSTRAINT questions_bank_question_id_fkey,
+    ADD CONSTRAINT questions_bank_question_id_fkey
+        FOREIGN KEY (bank_question_id) REFERENCES bank_questions(id) ON DELETE SET NULL;
@@ -0,0 +1,10 @@
+-- Riwayat permintaan reset password untuk membatasi pengiriman email per alamat dan per IP
+CREATE TABLE password_reset_requests (
+    id BIGSERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    ip_address VARCHAR(64) NOT NULL,
+    requested_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_password_reset_requests_email ON password_reset_requests(email, requested_at);
+CREATE INDEX idx_password_reset_requests_ip_address ON password_reset_requests(ip_address, requested_at);
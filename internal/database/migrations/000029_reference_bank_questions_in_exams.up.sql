@@ -0,0 +1,11 @@
+-- Soal ujian dari bank kembali hanya menyimpan referensi, teks soal dan jawaban dibaca dari bank_questions
+UPDATE questions
+SET question = '', correct_answer = ''
+WHERE bank_question_id IS NOT NULL;
+
+-- Soal bank yang masih dipakai ujian tidak bisa dihapus. NO ACTION diperiksa di akhir statement,
+-- jadi menghapus guru beserta ujian dan bank soalnya sekaligus tetap berhasil.
+ALTER TABLE questions
+    DROP CONSTRAINT questions_bank_question_id_fkey,
+    ADD CONSTRAINT questions_bank_question_id_fkey
+        FOREIGN KEY (bank_question_id) REFERENCES bank_questions(id) ON DELETE NO ACTION;
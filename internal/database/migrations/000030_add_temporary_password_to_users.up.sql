@@ -0,0 +1,4 @@
+-- Kata sandi sementara dari impor CSV harus diganti saat login pertama dan tidak bisa dipakai lagi setelah kedaluwarsa.
+-- NULL berarti kata sandi dipilih sendiri oleh pengguna.
+ALTER TABLE users
+    ADD COLUMN temporary_password_expires_at TIMESTAMP(0) WITHOUT TIME ZONE;
@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mhaatha/go-template-saygenfix/internal/config"
+	appError "github.com/mhaatha/go-template-saygenfix/internal/errors"
+	"github.com/mhaatha/go-template-saygenfix/internal/middleware"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/service"
+)
+
+// accountFieldMessages are the inline messages for validation errors, keyed by "form.Field"
+var accountFieldMessages = map[string]string{
+	"profile.FullName":         "Nama harus 3-255 karakter dan hanya berisi huruf, spasi, titik, apostrof, atau tanda hubung.",
+	"password.CurrentPassword": "Masukkan password saat ini.",
+	"password.NewPassword":     "Password baru harus 6-255 karakter.",
+	"password.ConfirmPassword": "Konfirmasi password tidak sama dengan password baru.",
+	"email.Email":              "Masukkan alamat email yang valid.",
+	"email.Password":           "Masukkan password untuk mengonfirmasi.",
+	"delete.Password":          "Masukkan password untuk mengonfirmasi.",
+}
+
+func NewAccountHandler(userService service.UserService, emailVerificationService service.EmailVerificationService, cfg *config.Config) AccountHandler {
+	return &AccountHandlerImpl{
+		UserService:              userService,
+		EmailVerificationService: emailVerificationService,
+		Cfg:                      cfg,
+		Template: template.Must(template.ParseFiles(
+			"../../internal/templates/views/account.html",
+			"../../internal/templates/views/partial/teacher_navbar.html",
+			"../../internal/templates/views/partial/admin_navbar.html",
+			"../../internal/templates/views/partial/student_dashboard_navbar.html",
+			"../../internal/templates/views/error.html",
+		)),
+	}
+}
+
+type AccountHandlerImpl struct {
+	UserService              service.UserService
+	EmailVerificationService service.EmailVerificationService
+	Cfg                      *config.Config
+	Template                 *template.Template
+}
+
+func (handler *AccountHandlerImpl) AccountView(w http.ResponseWriter, r *http.Request) {
+	pageResponse := web.AccountPageResponse{}
+	switch r.URL.Query().Get("status") {
+	case "profile":
+		pageResponse.FlashMessage = "Nama berhasil diperbarui."
+	case "password":
+		pageResponse.FlashMessage = "Password berhasil diubah. Perangkat lain sudah dikeluarkan dari akun Anda."
+	case "email":
+		pageResponse.FlashMessage = "Link konfirmasi sudah dikirim ke email baru. Email lama tetap dipakai sampai link tersebut dibuka."
+	case "must-change-password":
+		pageResponse.ErrorMessage = "Akun Anda masih memakai kata sandi sementara. Ganti kata sandi terlebih dahulu untuk melanjutkan."
+	}
+
+	handler.renderAccount(w, r, http.StatusOK, pageResponse)
+}
+
+func (handler *AccountHandlerImpl) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	request := web.UpdateProfileRequest{
+		FullName: r.PostFormValue("full_name"),
+	}
+
+	if err := handler.UserService.UpdateProfile(r.Context(), user.Id, request); err != nil {
+		slog.Error("error when calling update profile service", "err", err)
+
+		handler.renderAccountError(w, r, "profile", err, web.AccountPageResponse{FullName: request.FullName})
+		return
+	}
+
+	http.Redirect(w, r, "/account/?status=profile", http.StatusSeeOther)
+}
+
+func (handler *AccountHandlerImpl) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	currentSessionId := r.Context().Value(middleware.CurrentSessionKey).(string)
+
+	err := handler.UserService.ChangePassword(r.Context(), user.Id, currentSessionId, web.ChangePasswordRequest{
+		CurrentPassword: r.PostFormValue("current_password"),
+		NewPassword:     r.PostFormValue("new_password"),
+		ConfirmPassword: r.PostFormValue("confirm_password"),
+	})
+	if err != nil {
+		slog.Error("error when calling change password service", "err", err)
+
+		handler.renderAccountError(w, r, "password", err, web.AccountPageResponse{})
+		return
+	}
+
+	http.Redirect(w, r, "/account/?status=password", http.StatusSeeOther)
+}
+
+func (handler *AccountHandlerImpl) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	request := web.ChangeEmailRequest{
+		Email:    r.PostFormValue("email"),
+		Password: r.PostFormValue("password"),
+	}
+
+	updatedUser, err := handler.UserService.RequestEmailChange(r.Context(), user.Id, request)
+	if err != nil {
+		slog.Error("error when calling request email change service", "err", err)
+
+		handler.renderAccountError(w, r, "email", err, web.AccountPageResponse{Email: request.Email})
+		return
+	}
+
+	if err := handler.EmailVerificationService.SendEmailChange(r.Context(), updatedUser); err != nil {
+		slog.Error("error when calling send email change service", "err", err)
+
+		// Email baru tetap tersimpan, link bisa diminta lagi setelah jeda kirim ulang
+		pageResponse := web.AccountPageResponse{Email: request.Email}
+		statusCode := http.StatusInternalServerError
+		pageResponse.ErrorMessage = "Link konfirmasi gagal dikirim. Coba lagi beberapa saat lagi."
+		if errors.Is(err, service.ErrVerificationThrottled) {
+			statusCode = http.StatusTooManyRequests
+			pageResponse.ErrorMessage = "Link konfirmasi baru saja dikirim. Tunggu beberapa menit sebelum meminta lagi."
+		}
+
+		handler.renderAccount(w, r, statusCode, pageResponse)
+		return
+	}
+
+	http.Redirect(w, r, "/account/?status=email", http.StatusSeeOther)
+}
+
+func (handler *AccountHandlerImpl) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	err := handler.UserService.DeleteAccount(r.Context(), user.Id, web.DeleteAccountRequest{
+		Password: r.PostFormValue("password"),
+	})
+	if err != nil {
+		slog.Error("error when calling delete account service", "err", err)
+
+		handler.renderAccountError(w, r, "delete", err, web.AccountPageResponse{})
+		return
+	}
+
+	// Sesi ikut terhapus bersama akun, cookie di browser juga dibersihkan
+	http.SetCookie(w, &http.Cookie{Name: handler.Cfg.SessionName, Value: "", Path: "/", MaxAge: -1})
+	http.Redirect(w, r, "/login?status=account-deleted", http.StatusSeeOther)
+}
+
+// renderAccountError shows the page again with the errors next to the inputs of the submitted form
+func (handler *AccountHandlerImpl) renderAccountError(w http.ResponseWriter, r *http.Request, form string, err error, pageResponse web.AccountPageResponse) {
+	var validationErrors validator.ValidationErrors
+	pageResponse.FieldErrors = map[string]string{}
+	statusCode := http.StatusBadRequest
+
+	switch {
+	case errors.As(err, &validationErrors):
+		for _, fieldError := range validationErrors {
+			key := form + "." + fieldError.StructField()
+			if message, ok := accountFieldMessages[key]; ok {
+				pageResponse.FieldErrors[key] = message
+			}
+		}
+	case errors.Is(err, service.ErrInvalidCredentials):
+		if form == "password" {
+			pageResponse.FieldErrors["password.CurrentPassword"] = "Password saat ini salah."
+		} else {
+			pageResponse.FieldErrors[form+".Password"] = "Password salah."
+		}
+	case errors.Is(err, service.ErrPasswordUnchanged):
+		pageResponse.FieldErrors["password.NewPassword"] = "Password baru harus berbeda dari kata sandi sementara."
+	case errors.Is(err, service.ErrEmailAlreadyUsed):
+		pageResponse.FieldErrors["email.Email"] = "Email tersebut sudah dipakai akun lain."
+		statusCode = http.StatusConflict
+	case errors.Is(err, service.ErrEmailUnchanged):
+		pageResponse.FieldErrors["email.Email"] = "Email tersebut adalah email Anda saat ini."
+	case errors.Is(err, service.ErrLastAdmin):
+		pageResponse.FieldErrors["delete.Password"] = "Akun ini adalah satu-satunya admin aktif. Jadikan pengguna lain admin terlebih dahulu."
+		statusCode = http.StatusForbidden
+	case errors.Is(err, service.ErrSharedExamsOwned):
+		pageResponse.FieldErrors["delete.Password"] = "Anda masih satu-satunya owner ujian yang dipakai guru lain atau sudah dikerjakan siswa."
+		statusCode = http.StatusConflict
+	default:
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	handler.renderAccount(w, r, statusCode, pageResponse)
+}
+
+func (handler *AccountHandlerImpl) renderAccount(w http.ResponseWriter, r *http.Request, statusCode int, pageResponse web.AccountPageResponse) {
+	sessionUser := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	user, err := handler.UserService.GetProfile(r.Context(), sessionUser.Id)
+	if err != nil {
+		slog.Error("error when calling get profile service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	if pageResponse.FullName == "" {
+		pageResponse.FullName = user.FullName
+	}
+	switch user.Role {
+	case "student":
+		user.Role = "Student"
+	case "teacher":
+		user.Role = "Teacher"
+	case "admin":
+		user.Role = "Admin"
+	}
+	pageResponse.User = user
+
+	if user.Role == "Teacher" {
+		pageResponse.SharedExamCount, err = handler.UserService.GetSharedExamCount(r.Context(), user.Id)
+		if err != nil {
+			slog.Error("error when calling get shared exam count service", "err", err)
+
+			appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+	}
+
+	w.WriteHeader(statusCode)
+	if err := handler.Template.ExecuteTemplate(w, "account", pageResponse); err != nil {
+		slog.Error("error when executing account template", "err", err)
+		return
+	}
+}
@@ -0,0 +1,15 @@
+package handler
+
+import "net/http"
+
+type AdminHandler interface {
+	DashboardView(w http.ResponseWriter, r *http.Request)
+	UsersView(w http.ResponseWriter, r *http.Request)
+	DisableUser(w http.ResponseWriter, r *http.Request)
+	EnableUser(w http.ResponseWriter, r *http.Request)
+	ChangeUserRole(w http.ResponseWriter, r *http.Request)
+	ApproveTeacher(w http.ResponseWriter, r *http.Request)
+	RejectTeacher(w http.ResponseWriter, r *http.Request)
+	SetTeacherTwoFactor(w http.ResponseWriter, r *http.Request)
+	DeleteUser(w http.ResponseWriter, r *http.Request)
+}
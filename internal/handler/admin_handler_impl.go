@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	appError "github.com/mhaatha/go-template-saygenfix/internal/errors"
+	"github.com/mhaatha/go-template-saygenfix/internal/middleware"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/service"
+)
+
+func NewAdminHandler(adminService service.AdminService) AdminHandler {
+	funcMap := template.FuncMap{
+		"score": func(score float64) string {
+			return fmt.Sprintf("%.1f", score)
+		},
+	}
+
+	return &AdminHandlerImpl{
+		AdminService: adminService,
+		Template: template.Must(template.New("admin").Funcs(funcMap).ParseFiles(
+			"../../internal/templates/views/admin/dashboard.html",
+			"../../internal/templates/views/admin/users.html",
+			"../../internal/templates/views/partial/admin_navbar.html",
+			"../../internal/templates/views/error.html",
+		)),
+	}
+}
+
+type AdminHandlerImpl struct {
+	AdminService service.AdminService
+	Template     *template.Template
+}
+
+func (handler *AdminHandlerImpl) DashboardView(w http.ResponseWriter, r *http.Request) {
+	user := currentAdmin(r)
+
+	stats, exams, pendingTeachers, err := handler.AdminService.GetDashboard(r.Context())
+	if err != nil {
+		slog.Error("error when calling get admin dashboard service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	loginFailures, err := handler.AdminService.GetLoginFailures(r.Context())
+	if err != nil {
+		slog.Error("error when calling get login failures service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	requireTeacherTwoFactor, err := handler.AdminService.IsTeacherTwoFactorRequired(r.Context())
+	if err != nil {
+		slog.Error("error when calling is teacher two-factor required service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	dashboardResponse := web.AdminDashboardResponse{
+		User:                    user,
+		Stats:                   stats,
+		Exams:                   exams,
+		PendingTeachers:         pendingTeachers,
+		LoginFailures:           loginFailures,
+		RequireTeacherTwoFactor: requireTeacherTwoFactor,
+	}
+	switch r.URL.Query().Get("status") {
+	case "approved":
+		dashboardResponse.FlashMessage = "Pendaftaran guru berhasil disetujui."
+	case "rejected":
+		dashboardResponse.FlashMessage = "Pendaftaran guru ditolak dan akunnya dihapus."
+	case "two-factor":
+		dashboardResponse.FlashMessage = "Pengaturan verifikasi dua langkah untuk guru berhasil disimpan."
+	}
+
+	if err := handler.Template.ExecuteTemplate(w, "admin-dashboard", dashboardResponse); err != nil {
+		slog.Error("error when executing admin-dashboard template", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+}
+
+func (handler *AdminHandlerImpl) UsersView(w http.ResponseWriter, r *http.Request) {
+	flashMessage := ""
+	switch r.URL.Query().Get("status") {
+	case "disabled":
+		flashMessage = "Akun berhasil dinonaktifkan, semua sesi pengguna sudah dihapus."
+	case "enabled":
+		flashMessage = "Akun berhasil diaktifkan kembali."
+	case "role":
+		flashMessage = "Role pengguna berhasil diubah."
+	case "deleted":
+		flashMessage = "Akun berhasil dihapus."
+	}
+
+	handler.renderUsers(w, r, http.StatusOK, flashMessage, "")
+}
+
+func (handler *AdminHandlerImpl) DisableUser(w http.ResponseWriter, r *http.Request) {
+	handler.setUserDisabled(w, r, true)
+}
+
+func (handler *AdminHandlerImpl) EnableUser(w http.ResponseWriter, r *http.Request) {
+	handler.setUserDisabled(w, r, false)
+}
+
+func (handler *AdminHandlerImpl) setUserDisabled(w http.ResponseWriter, r *http.Request, isDisabled bool) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if err := handler.AdminService.SetUserDisabled(r.Context(), user.Id, r.PathValue("id"), isDisabled); err != nil {
+		slog.Error("error when calling set user disabled service", "err", err)
+
+		handler.renderUsers(w, r, http.StatusBadRequest, "", adminErrorMessage(err, "Status akun gagal diubah."))
+		return
+	}
+
+	status := "enabled"
+	if isDisabled {
+		status = "disabled"
+	}
+
+	http.Redirect(w, r, "/admin/users?status="+status, http.StatusSeeOther)
+}
+
+func (handler *AdminHandlerImpl) ChangeUserRole(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	request := web.AdminUserRoleRequest{
+		Role: r.FormValue("role"),
+	}
+
+	if err := handler.AdminService.ChangeUserRole(r.Context(), user.Id, r.PathValue("id"), request); err != nil {
+		slog.Error("error when calling change user role service", "err", err)
+
+		handler.renderUsers(w, r, http.StatusBadRequest, "", adminErrorMessage(err, "Role pengguna gagal diubah."))
+		return
+	}
+
+	http.Redirect(w, r, "/admin/users?status=role", http.StatusSeeOther)
+}
+
+func (handler *AdminHandlerImpl) ApproveTeacher(w http.ResponseWriter, r *http.Request) {
+	if err := handler.AdminService.ApproveTeacher(r.Context(), r.PathValue("id")); err != nil {
+		slog.Error("error when calling approve teacher service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "Pendaftaran guru gagal disetujui")
+		return
+	}
+
+	http.Redirect(w, r, "/admin/dashboard?status=approved", http.StatusSeeOther)
+}
+
+func (handler *AdminHandlerImpl) RejectTeacher(w http.ResponseWriter, r *http.Request) {
+	if err := handler.AdminService.RejectTeacher(r.Context(), r.PathValue("id")); err != nil {
+		slog.Error("error when calling reject teacher service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "Pendaftaran guru gagal ditolak")
+		return
+	}
+
+	http.Redirect(w, r, "/admin/dashboard?status=rejected", http.StatusSeeOther)
+}
+
+func (handler *AdminHandlerImpl) SetTeacherTwoFactor(w http.ResponseWriter, r *http.Request) {
+	isRequired := r.PostFormValue("required") == "true"
+
+	if err := handler.AdminService.SetTeacherTwoFactorRequired(r.Context(), isRequired); err != nil {
+		slog.Error("error when calling set teacher two-factor required service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	http.Redirect(w, r, "/admin/dashboard?status=two-factor", http.StatusSeeOther)
+}
+
+func (handler *AdminHandlerImpl) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if err := handler.AdminService.DeleteUser(r.Context(), user.Id, r.PathValue("id")); err != nil {
+		slog.Error("error when calling delete user service", "err", err)
+
+		handler.renderUsers(w, r, http.StatusBadRequest, "", adminErrorMessage(err, "Akun gagal dihapus."))
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/admin/users?status=deleted")
+}
+
+func (handler *AdminHandlerImpl) renderUsers(w http.ResponseWriter, r *http.Request, statusCode int, flashMessage, errorMessage string) {
+	user := currentAdmin(r)
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	role := r.URL.Query().Get("role")
+
+	users, err := handler.AdminService.SearchUsers(r.Context(), query, role)
+	if err != nil {
+		slog.Error("error when calling search users service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	usersResponse := web.AdminUsersResponse{
+		User:         user,
+		Users:        users,
+		Query:        query,
+		SelectedRole: role,
+		FlashMessage: flashMessage,
+		ErrorMessage: errorMessage,
+	}
+
+	w.WriteHeader(statusCode)
+	if err := handler.Template.ExecuteTemplate(w, "admin-users", usersResponse); err != nil {
+		slog.Error("error when executing admin-users template", "err", err)
+		return
+	}
+}
+
+func currentAdmin(r *http.Request) domain.User {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	if user.Role == "admin" {
+		user.Role = "Admin"
+	}
+
+	return user
+}
+
+func adminErrorMessage(err error, fallback string) string {
+	if errors.Is(err, service.ErrAdminSelfAction) {
+		return "Anda tidak bisa menonaktifkan, menghapus, atau mengubah role akun Anda sendiri."
+	}
+
+	return fallback
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/openapi"
+)
+
+// APINotFound answers unknown API routes with the JSON error body instead of the plain text 404
+func APINotFound(w http.ResponseWriter, r *http.Request) {
+	helper.WriteJSONError(w, http.StatusNotFound, "not_found", "No API endpoint matches "+r.Method+" "+r.URL.Path)
+}
+
+// OpenAPI serves the OpenAPI document of the JSON API, it does not need authentication
+func OpenAPI(w http.ResponseWriter, r *http.Request) {
+	body, err := openapi.JSON()
+	if err != nil {
+		slog.Error("failed to encode openapi document", "err", err)
+
+		helper.WriteJSONError(w, http.StatusInternalServerError, "internal_error", "Internal Server Error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(body); err != nil {
+		slog.Error("failed to write openapi document", "err", err)
+	}
+}
+
+// readAPIRequest decodes the JSON body and answers 400 when it can not be decoded
+func readAPIRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := helper.ReadJSON(r, v); err != nil {
+		helper.WriteJSONError(w, http.StatusBadRequest, "invalid_json", "Request body is not valid JSON for this endpoint")
+		return false
+	}
+
+	return true
+}
+
+// writeAPIValidationError answers 422 with one message per invalid field and reports whether err was a validation error
+func writeAPIValidationError(w http.ResponseWriter, err error) bool {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return false
+	}
+
+	fields := map[string]string{}
+	for _, fieldError := range validationErrors {
+		// Namespace diawali nama struct, misalnya "ExamCreateRequest.questions[0].answer"
+		_, field, _ := strings.Cut(fieldError.Namespace(), ".")
+		fields[field] = apiFieldMessage(fieldError)
+	}
+
+	helper.WriteJSONFieldErrors(w, fields)
+	return true
+}
+
+func apiFieldMessage(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return fmt.Sprintf("must be at least %s", fieldError.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s", fieldError.Param())
+	default:
+		return "is invalid"
+	}
+}
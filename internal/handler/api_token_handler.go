@@ -0,0 +1,9 @@
+package handler
+
+import "net/http"
+
+type APITokenHandler interface {
+	TokensView(w http.ResponseWriter, r *http.Request)
+	CreateToken(w http.ResponseWriter, r *http.Request)
+	RevokeToken(w http.ResponseWriter, r *http.Request)
+}
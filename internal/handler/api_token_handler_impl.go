@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	appError "github.com/mhaatha/go-template-saygenfix/internal/errors"
+	"github.com/mhaatha/go-template-saygenfix/internal/middleware"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/service"
+)
+
+// apiTokenFieldMessages are the inline messages for validation errors of the create form
+var apiTokenFieldMessages = map[string]string{
+	"Name":          "Nama token wajib diisi, maksimal 100 karakter.",
+	"Scopes":        "Pilih minimal satu izin.",
+	"ExpiresInDays": "Pilih masa berlaku token.",
+}
+
+func NewAPITokenHandler(apiTokenService service.APITokenService) APITokenHandler {
+	return &APITokenHandlerImpl{
+		APITokenService: apiTokenService,
+		Template: template.Must(template.ParseFiles(
+			"../../internal/templates/views/api_tokens.html",
+			"../../internal/templates/views/partial/teacher_navbar.html",
+			"../../internal/templates/views/partial/admin_navbar.html",
+			"../../internal/templates/views/partial/student_dashboard_navbar.html",
+			"../../internal/templates/views/error.html",
+		)),
+	}
+}
+
+type APITokenHandlerImpl struct {
+	APITokenService service.APITokenService
+	Template        *template.Template
+}
+
+func (handler *APITokenHandlerImpl) TokensView(w http.ResponseWriter, r *http.Request) {
+	pageResponse := web.APITokenPageResponse{}
+	if r.URL.Query().Get("status") == "revoked" {
+		pageResponse.FlashMessage = "Token berhasil dicabut dan tidak bisa dipakai lagi."
+	}
+
+	handler.renderTokens(w, r, http.StatusOK, pageResponse)
+}
+
+func (handler *APITokenHandlerImpl) CreateToken(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if err := r.ParseForm(); err != nil {
+		slog.Error("failed to parse form", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "Bad Request")
+		return
+	}
+
+	// Masa berlaku yang tidak valid ditolak oleh validasi di service
+	expiresInDays, _ := strconv.Atoi(r.PostFormValue("expires_in_days"))
+
+	token, err := handler.APITokenService.CreateToken(r.Context(), user, web.APITokenCreateRequest{
+		Name:          r.PostFormValue("name"),
+		Scopes:        r.PostForm["scopes"],
+		ExpiresInDays: expiresInDays,
+	})
+	if err != nil {
+		slog.Error("error when calling create api token service", "err", err)
+
+		var validationErrors validator.ValidationErrors
+		pageResponse := web.APITokenPageResponse{FieldErrors: map[string]string{}}
+		statusCode := http.StatusBadRequest
+
+		switch {
+		case errors.As(err, &validationErrors):
+			for _, fieldError := range validationErrors {
+				if message, ok := apiTokenFieldMessages[fieldError.StructField()]; ok {
+					pageResponse.FieldErrors[fieldError.StructField()] = message
+				}
+			}
+		case errors.Is(err, service.ErrAPITokenScopeNotAllowed):
+			pageResponse.FieldErrors["Scopes"] = "Izin tersebut tidak tersedia untuk akun Anda."
+			statusCode = http.StatusForbidden
+		case errors.Is(err, service.ErrAPITokenLimit):
+			pageResponse.ErrorMessage = "Jumlah token aktif sudah mencapai batas. Cabut token yang tidak dipakai terlebih dahulu."
+			statusCode = http.StatusConflict
+		default:
+			appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		handler.renderTokens(w, r, statusCode, pageResponse)
+		return
+	}
+
+	// Token langsung ditampilkan, tidak lewat redirect karena hanya muncul sekali
+	handler.renderTokens(w, r, http.StatusCreated, web.APITokenPageResponse{
+		NewToken:     token,
+		FlashMessage: "Token berhasil dibuat. Salin sekarang, token tidak akan ditampilkan lagi.",
+	})
+}
+
+func (handler *APITokenHandlerImpl) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if err := handler.APITokenService.RevokeToken(r.Context(), user.Id, r.PathValue("id")); err != nil {
+		slog.Error("error when calling revoke api token service", "err", err)
+
+		if errors.Is(err, service.ErrAPITokenNotFound) {
+			handler.renderTokens(w, r, http.StatusNotFound, web.APITokenPageResponse{
+				ErrorMessage: "Token tidak ditemukan atau sudah dicabut.",
+			})
+			return
+		}
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	http.Redirect(w, r, "/account/tokens?status=revoked", http.StatusSeeOther)
+}
+
+func (handler *APITokenHandlerImpl) renderTokens(w http.ResponseWriter, r *http.Request, statusCode int, pageResponse web.APITokenPageResponse) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	tokens, err := handler.APITokenService.ListTokens(r.Context(), user.Id)
+	if err != nil {
+		slog.Error("error when calling list api tokens service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	usages, err := handler.APITokenService.ListUsages(r.Context(), user.Id)
+	if err != nil {
+		slog.Error("error when calling list api token usages service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	pageResponse.Tokens = tokens
+	pageResponse.Usages = usages
+	pageResponse.ScopeOptions = handler.APITokenService.ScopesForRole(user.Role)
+	switch user.Role {
+	case "student":
+		user.Role = "Student"
+	case "teacher":
+		user.Role = "Teacher"
+	case "admin":
+		user.Role = "Admin"
+	}
+	pageResponse.User = user
+
+	w.WriteHeader(statusCode)
+	if err := handler.Template.ExecuteTemplate(w, "api-tokens", pageResponse); err != nil {
+		slog.Error("error when executing api-tokens template", "err", err)
+		return
+	}
+}
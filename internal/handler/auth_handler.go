@@ -5,4 +5,8 @@ import "net/http"
 type AuthHandler interface {
 	Login(w http.ResponseWriter, r *http.Request)
 	LoginView(w http.ResponseWriter, r *http.Request)
+	TwoFactorLoginView(w http.ResponseWriter, r *http.Request)
+	TwoFactorLogin(w http.ResponseWriter, r *http.Request)
+	OIDCLogin(w http.ResponseWriter, r *http.Request)
+	OIDCCallback(w http.ResponseWriter, r *http.Request)
 }
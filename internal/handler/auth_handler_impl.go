@@ -1,33 +1,56 @@
 package handler
 
 import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
 	"html/template"
 	"log/slog"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/mhaatha/go-template-saygenfix/internal/config"
 	appError "github.com/mhaatha/go-template-saygenfix/internal/errors"
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/middleware"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
 	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/oidc"
 	"github.com/mhaatha/go-template-saygenfix/internal/service"
 )
 
-func NewAuthHandler(authService service.AuthService, userService service.UserService, cfg *config.Config) AuthHandler {
+// twoFactorCookieName holds the challenge token between the password and the TOTP step
+const twoFactorCookieName = "two_factor_challenge"
+
+// oidcFlowCookieName holds state, nonce and PKCE verifier between the redirect to the identity provider and the callback
+const oidcFlowCookieName = "oidc_flow"
+
+func NewAuthHandler(authService service.AuthService, userService service.UserService, twoFactorService service.TwoFactorService, oidcService service.OIDCService, cfg *config.Config) AuthHandler {
 	return &AuthHandlerImpl{
-		AuthService: authService,
-		UserService: userService,
+		AuthService:      authService,
+		UserService:      userService,
+		TwoFactorService: twoFactorService,
+		OIDCService:      oidcService,
 		Template: template.Must(template.ParseFiles(
 			"../../internal/templates/views/login.html",
+			"../../internal/templates/views/two_factor_login.html",
+			"../../internal/templates/views/error.html",
 		)),
 		Cfg: cfg,
 	}
 }
 
 type AuthHandlerImpl struct {
-	AuthService service.AuthService
-	UserService service.UserService
-	Template    *template.Template
-	Cfg         *config.Config
+	AuthService      service.AuthService
+	UserService      service.UserService
+	TwoFactorService service.TwoFactorService
+	OIDCService      service.OIDCService
+	Template         *template.Template
+	Cfg              *config.Config
 }
 
 func (handler *AuthHandlerImpl) Login(w http.ResponseWriter, r *http.Request) {
@@ -39,8 +62,27 @@ func (handler *AuthHandlerImpl) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	userRequest := web.LoginRequest{
-		Email:    r.PostFormValue("email"),
-		Password: r.PostFormValue("password"),
+		Email:     r.PostFormValue("email"),
+		Password:  r.PostFormValue("password"),
+		IPAddress: helper.ClientIP(r, handler.Cfg.TrustedProxyHops),
+		UserAgent: r.UserAgent(),
+	}
+
+	// Tolak lebih awal jika email atau IP ini terlalu sering gagal login
+	retryAfter, err := handler.AuthService.CheckLoginThrottle(r.Context(), userRequest.Email, userRequest.IPAddress)
+	if err != nil {
+		if errors.Is(err, service.ErrLoginLocked) {
+			slog.Warn("login locked", "email", userRequest.Email, "ip", userRequest.IPAddress)
+
+			minutes := max(1, int(math.Ceil(retryAfter.Minutes())))
+			w.Header().Set("HX-Redirect", "/login?status=locked&minutes="+strconv.Itoa(minutes))
+			return
+		}
+
+		slog.Error("failed when calling CheckLoginThrottle service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
 	}
 
 	// Get user
@@ -48,48 +90,358 @@ func (handler *AuthHandlerImpl) Login(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		slog.Error("failed to get user by email", "err", err)
 
-		appError.RenderErrorPage(w, handler.Template, http.StatusUnauthorized, "Incorrect email or password")
+		handler.loginFailed(w, r, userRequest)
 		return
 	}
 
-	// Set max age and session name
-	sessionName := handler.Cfg.SessionName
-	maxAge, _ := strconv.Atoi(handler.Cfg.SessionMaxAge)
-
 	// Call teacher service
-	sessionId, errr := handler.AuthService.Login(r.Context(), userRequest, user.Email, user.Password, user.Id)
+	sessionId, errr := handler.AuthService.Login(r.Context(), userRequest, user)
 	if errr != nil {
 		slog.Error("failed to when calling Login service", "err", errr)
 
-		appError.RenderErrorPage(w, handler.Template, http.StatusUnauthorized, "Incorrect email or password")
+		// Akun yang dinonaktifkan, guru yang belum disetujui admin, atau email yang belum diverifikasi tidak boleh login
+		if errors.Is(errr, service.ErrAccountDisabled) {
+			w.Header().Set("HX-Redirect", "/login?status=disabled")
+			return
+		}
+		if errors.Is(errr, service.ErrAccountPending) {
+			w.Header().Set("HX-Redirect", "/login?status=pending")
+			return
+		}
+		if errors.Is(errr, service.ErrEmailNotVerified) {
+			w.Header().Set("HX-Redirect", "/login?status=unverified")
+			return
+		}
+		if errors.Is(errr, service.ErrTemporaryPasswordExpired) {
+			w.Header().Set("HX-Redirect", "/login?status=temporary-expired")
+			return
+		}
+		// Password benar, sesi baru dibuat setelah kode TOTP diverifikasi
+		if errors.Is(errr, service.ErrTwoFactorRequired) {
+			if handler.startTwoFactor(w, r, user) {
+				w.Header().Set("HX-Redirect", "/login/two-factor")
+			}
+			return
+		}
+
+		handler.loginFailed(w, r, userRequest)
+		return
+	}
+
+	setSessionCookie(w, handler.Cfg, sessionId)
+
+	// Kata sandi sementara dari impor harus diganti sebelum halaman lain bisa dibuka
+	if user.MustChangePassword {
+		w.Header().Set("HX-Redirect", middleware.MustChangePasswordPath)
+		return
+	}
+
+	// Redirect to admin, teacher or student dashboard, depends on the what user role
+	w.Header().Set("HX-Redirect", dashboardPath(user.Role))
+}
+
+// OIDCLogin sends the browser to the identity provider, the flow values stay in a cookie until the callback
+func (handler *AuthHandlerImpl) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if handler.OIDCService == nil || !handler.OIDCService.IsEnabled() {
+		http.NotFound(w, r)
+		return
+	}
+
+	state, err := helper.GenerateToken()
+	if err != nil {
+		slog.Error("failed when calling GenerateToken helper", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	nonce, err := helper.GenerateToken()
+	if err != nil {
+		slog.Error("failed when calling GenerateToken helper", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	verifier := oidc.NewVerifier()
+
+	authCodeURL, err := handler.OIDCService.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		slog.Error("failed when calling AuthCodeURL service", "err", err)
+
+		http.Redirect(w, r, "/login?status=sso-failed", http.StatusSeeOther)
 		return
 	}
 
-	// Set cookie
 	http.SetCookie(w, &http.Cookie{
-		Name:     sessionName,
-		Value:    sessionId,
-		MaxAge:   maxAge,
+		Name:     oidcFlowCookieName,
+		Value:    state + "." + nonce + "." + verifier,
+		MaxAge:   600,
 		HttpOnly: true,
-		SameSite: http.SameSiteNoneMode,
+		SameSite: http.SameSiteLaxMode,
 		Secure:   true,
-		Path:     "/",
+		Path:     "/auth/oidc",
 	})
 
-	// Redirect to teacher or student dashboard, depends on the what user role
-	switch user.Role {
-	case "teacher":
-		w.Header().Set("HX-Redirect", "/teacher/dashboard")
-	case "student":
-		w.Header().Set("HX-Redirect", "/student/dashboard")
+	http.Redirect(w, r, authCodeURL, http.StatusSeeOther)
+}
+
+func (handler *AuthHandlerImpl) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if handler.OIDCService == nil || !handler.OIDCService.IsEnabled() {
+		http.NotFound(w, r)
+		return
+	}
+
+	cookie, err := r.Cookie(oidcFlowCookieName)
+	http.SetCookie(w, &http.Cookie{Name: oidcFlowCookieName, Value: "", Path: "/auth/oidc", MaxAge: -1})
+	if err != nil {
+		slog.Error("oidc flow cookie not found", "err", err)
+
+		http.Redirect(w, r, "/login?status=sso-failed", http.StatusSeeOther)
+		return
+	}
+
+	query := r.URL.Query()
+	if providerError := query.Get("error"); providerError != "" {
+		slog.Warn("identity provider returned an error", "error", providerError, "description", query.Get("error_description"))
+
+		http.Redirect(w, r, "/login?status=sso-failed", http.StatusSeeOther)
+		return
+	}
+
+	// State harus sama dengan cookie agar callback tidak bisa dipalsukan dari situs lain
+	state, rest, _ := strings.Cut(cookie.Value, ".")
+	nonce, verifier, _ := strings.Cut(rest, ".")
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+		slog.Error("oidc state mismatch")
+
+		http.Redirect(w, r, "/login?status=sso-failed", http.StatusSeeOther)
+		return
+	}
+
+	sessionId, user, err := handler.OIDCService.Login(r.Context(), web.OIDCCallbackRequest{
+		Code:      query.Get("code"),
+		Verifier:  verifier,
+		Nonce:     nonce,
+		IPAddress: helper.ClientIP(r, handler.Cfg.TrustedProxyHops),
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		slog.Error("failed when calling OIDC Login service", "err", err)
+
+		switch {
+		case errors.Is(err, service.ErrTwoFactorRequired):
+			if handler.startTwoFactor(w, r, user) {
+				http.Redirect(w, r, "/login/two-factor", http.StatusSeeOther)
+			}
+		case errors.Is(err, service.ErrAccountDisabled):
+			http.Redirect(w, r, "/login?status=disabled", http.StatusSeeOther)
+		case errors.Is(err, service.ErrAccountPending):
+			http.Redirect(w, r, "/login?status=pending", http.StatusSeeOther)
+		case errors.Is(err, service.ErrOIDCEmailNotVerified):
+			http.Redirect(w, r, "/login?status=sso-unverified", http.StatusSeeOther)
+		case errors.Is(err, service.ErrOIDCRoleNotAllowed):
+			http.Redirect(w, r, "/login?status=sso-not-allowed", http.StatusSeeOther)
+		default:
+			http.Redirect(w, r, "/login?status=sso-failed", http.StatusSeeOther)
+		}
+		return
+	}
+
+	setSessionCookie(w, handler.Cfg, sessionId)
+
+	http.Redirect(w, r, dashboardPath(user.Role), http.StatusSeeOther)
+}
+
+func (handler *AuthHandlerImpl) TwoFactorLoginView(w http.ResponseWriter, r *http.Request) {
+	if _, err := r.Cookie(twoFactorCookieName); err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
 	}
+
+	handler.renderTwoFactorLogin(w, http.StatusOK, "")
+}
+
+func (handler *AuthHandlerImpl) TwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		slog.Error("failed to parse form", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	cookie, err := r.Cookie(twoFactorCookieName)
+	if err != nil || cookie.Value == "" {
+		http.Redirect(w, r, "/login?status=two-factor-expired", http.StatusSeeOther)
+		return
+	}
+
+	twoFactorRequest := web.TwoFactorLoginRequest{
+		Token:     cookie.Value,
+		Code:      r.PostFormValue("code"),
+		IPAddress: helper.ClientIP(r, handler.Cfg.TrustedProxyHops),
+		UserAgent: r.UserAgent(),
+	}
+
+	sessionId, user, err := handler.TwoFactorService.VerifyChallenge(r.Context(), twoFactorRequest)
+	if err != nil {
+		slog.Error("failed when calling VerifyChallenge service", "err", err)
+
+		var validationErrors validator.ValidationErrors
+		switch {
+		case errors.As(err, &validationErrors):
+			handler.renderTwoFactorLogin(w, http.StatusBadRequest, "Masukkan kode dari aplikasi autentikator atau kode cadangan.")
+		case errors.Is(err, service.ErrTwoFactorCodeInvalid):
+			// Kode salah dihitung sebagai login gagal untuk email ini
+			handler.waitLoginFailureDelay(r, user.Email, twoFactorRequest.IPAddress)
+			handler.renderTwoFactorLogin(w, http.StatusUnauthorized, "Kode tidak valid atau sudah dipakai.")
+		case errors.Is(err, service.ErrTwoFactorChallengeInvalid):
+			handler.clearTwoFactorCookie(w)
+			http.Redirect(w, r, "/login?status=two-factor-expired", http.StatusSeeOther)
+		case errors.Is(err, service.ErrAccountDisabled):
+			handler.clearTwoFactorCookie(w)
+			http.Redirect(w, r, "/login?status=disabled", http.StatusSeeOther)
+		default:
+			appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+
+	handler.clearTwoFactorCookie(w)
+	setSessionCookie(w, handler.Cfg, sessionId)
+
+	http.Redirect(w, r, dashboardPath(user.Role), http.StatusSeeOther)
 }
 
 func (handler *AuthHandlerImpl) LoginView(w http.ResponseWriter, r *http.Request) {
-	if err := handler.Template.ExecuteTemplate(w, "login", nil); err != nil {
+	loginResponse := web.LoginPageResponse{}
+	switch r.URL.Query().Get("status") {
+	case "pending":
+		loginResponse.FlashMessage = "Akun guru Anda masih menunggu persetujuan admin."
+	case "locked":
+		loginResponse.ErrorMessage = "Terlalu banyak percobaan login gagal. Coba lagi nanti."
+		if minutes, err := strconv.Atoi(r.URL.Query().Get("minutes")); err == nil && minutes > 0 {
+			loginResponse.ErrorMessage = fmt.Sprintf("Terlalu banyak percobaan login gagal. Coba lagi dalam %d menit.", minutes)
+		}
+	case "verified":
+		loginResponse.FlashMessage = "Email berhasil diverifikasi. Silakan login."
+	case "unverified":
+		loginResponse.ErrorMessage = "Email Anda belum diverifikasi. Buka link di email verifikasi terlebih dahulu."
+		loginResponse.ShowResendVerification = true
+	case "reset":
+		loginResponse.FlashMessage = "Password berhasil diubah. Silakan login dengan password baru."
+	case "email-changed":
+		loginResponse.FlashMessage = "Email berhasil diganti. Silakan login dengan email baru."
+	case "account-deleted":
+		loginResponse.FlashMessage = "Akun Anda berhasil dihapus."
+	case "disabled":
+		loginResponse.ErrorMessage = "Akun Anda telah dinonaktifkan. Hubungi admin sekolah."
+	case "temporary-expired":
+		loginResponse.ErrorMessage = "Kata sandi sementara Anda sudah kedaluwarsa. Buat kata sandi baru lewat halaman lupa password."
+	case "two-factor-expired":
+		loginResponse.ErrorMessage = "Waktu verifikasi dua langkah habis. Silakan login kembali."
+	case "sso-failed":
+		loginResponse.ErrorMessage = "Login melalui " + handler.Cfg.OIDCProviderName + " gagal. Silakan coba lagi."
+	case "sso-unverified":
+		loginResponse.ErrorMessage = "Email akun " + handler.Cfg.OIDCProviderName + " Anda belum terverifikasi."
+	case "sso-not-allowed":
+		loginResponse.ErrorMessage = "Akun " + handler.Cfg.OIDCProviderName + " Anda belum terdaftar di SayGenFix. Hubungi admin sekolah."
+	}
+
+	if handler.OIDCService != nil && handler.OIDCService.IsEnabled() {
+		loginResponse.SSOProviderName = handler.Cfg.OIDCProviderName
+	}
+
+	if err := handler.Template.ExecuteTemplate(w, "login", loginResponse); err != nil {
 		slog.Error("failed to execute login template", "err", err)
 
 		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
 }
+
+// loginFailed records the failure and answers after a delay that grows with every consecutive
+// failure, so scripted password guessing gets slower
+func (handler *AuthHandlerImpl) loginFailed(w http.ResponseWriter, r *http.Request, userRequest web.LoginRequest) {
+	handler.waitLoginFailureDelay(r, userRequest.Email, userRequest.IPAddress)
+
+	appError.RenderErrorPage(w, handler.Template, http.StatusUnauthorized, "Incorrect email or password")
+}
+
+func (handler *AuthHandlerImpl) waitLoginFailureDelay(r *http.Request, email, ipAddress string) {
+	delay, err := handler.AuthService.RecordLoginFailure(r.Context(), email, ipAddress)
+	if err != nil {
+		slog.Error("failed when calling RecordLoginFailure service", "err", err)
+	}
+
+	select {
+	case <-time.After(delay):
+	case <-r.Context().Done():
+	}
+}
+
+// startTwoFactor keeps the challenge token in a short-lived cookie, the caller then sends the
+// browser to the code form. It returns false after rendering the error page.
+func (handler *AuthHandlerImpl) startTwoFactor(w http.ResponseWriter, r *http.Request, user domain.User) bool {
+	token, err := handler.TwoFactorService.CreateChallenge(r.Context(), user)
+	if err != nil {
+		slog.Error("failed when calling CreateChallenge service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return false
+	}
+
+	setTwoFactorCookie(w, token)
+
+	return true
+}
+
+// setTwoFactorCookie is shared with the LTI launch, which also ends on the code form for 2FA accounts
+func setTwoFactorCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     twoFactorCookieName,
+		Value:    token,
+		MaxAge:   300,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Secure:   true,
+		Path:     "/login/two-factor",
+	})
+}
+
+func (handler *AuthHandlerImpl) clearTwoFactorCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{Name: twoFactorCookieName, Value: "", Path: "/login/two-factor", MaxAge: -1})
+}
+
+func setSessionCookie(w http.ResponseWriter, cfg *config.Config, sessionId string) {
+	maxAge, _ := strconv.Atoi(cfg.SessionMaxAge)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     cfg.SessionName,
+		Value:    sessionId,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Secure:   true,
+		Path:     "/",
+	})
+}
+
+func (handler *AuthHandlerImpl) renderTwoFactorLogin(w http.ResponseWriter, statusCode int, errorMessage string) {
+	w.WriteHeader(statusCode)
+	if err := handler.Template.ExecuteTemplate(w, "two-factor-login", web.TwoFactorLoginPageResponse{
+		ErrorMessage: errorMessage,
+	}); err != nil {
+		slog.Error("failed to execute two-factor-login template", "err", err)
+		return
+	}
+}
+
+func dashboardPath(role string) string {
+	switch role {
+	case "admin":
+		return "/admin/dashboard"
+	case "teacher":
+		return "/teacher/dashboard"
+	}
+
+	return "/student/dashboard"
+}
@@ -0,0 +1,15 @@
+package handler
+
+import "net/http"
+
+type ClassHandler interface {
+	ClassesView(w http.ResponseWriter, r *http.Request)
+	CreateClass(w http.ResponseWriter, r *http.Request)
+	ClassDetailView(w http.ResponseWriter, r *http.Request)
+	DeleteClass(w http.ResponseWriter, r *http.Request)
+	RegenerateJoinCode(w http.ResponseWriter, r *http.Request)
+	InviteStudent(w http.ResponseWriter, r *http.Request)
+	RemoveMember(w http.ResponseWriter, r *http.Request)
+	CancelInvitation(w http.ResponseWriter, r *http.Request)
+	AssignExamClasses(w http.ResponseWriter, r *http.Request)
+}
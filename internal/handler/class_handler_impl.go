@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	appError "github.com/mhaatha/go-template-saygenfix/internal/errors"
+	"github.com/mhaatha/go-template-saygenfix/internal/middleware"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/service"
+)
+
+func NewClassHandler(classService service.ClassService) ClassHandler {
+	return &ClassHandlerImpl{
+		ClassService: classService,
+		Template: template.Must(template.ParseFiles(
+			"../../internal/templates/views/teacher/classes.html",
+			"../../internal/templates/views/teacher/class_detail.html",
+			"../../internal/templates/views/partial/teacher_navbar.html",
+			"../../internal/templates/views/partial/exam_classes.html",
+			"../../internal/templates/views/error.html",
+		)),
+	}
+}
+
+type ClassHandlerImpl struct {
+	ClassService service.ClassService
+	Template     *template.Template
+}
+
+func (handler *ClassHandlerImpl) ClassesView(w http.ResponseWriter, r *http.Request) {
+	flashMessage := ""
+	if r.URL.Query().Get("status") == "deleted" {
+		flashMessage = "Kelas berhasil dihapus."
+	}
+
+	handler.renderClasses(w, r, http.StatusOK, flashMessage, "")
+}
+
+func (handler *ClassHandlerImpl) CreateClass(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	request := web.ClassCreateRequest{
+		Name: strings.TrimSpace(r.FormValue("name")),
+	}
+
+	class, err := handler.ClassService.CreateClass(r.Context(), user.Id, request)
+	if err != nil {
+		slog.Error("error when calling create class service", "err", err)
+
+		handler.renderClasses(w, r, http.StatusBadRequest, "", "Kelas gagal dibuat. Pastikan nama kelas terisi, maksimal 255 karakter.")
+		return
+	}
+
+	http.Redirect(w, r, "/teacher/classes/"+class.Id+"?status=created", http.StatusSeeOther)
+}
+
+func (handler *ClassHandlerImpl) ClassDetailView(w http.ResponseWriter, r *http.Request) {
+	flashMessage := ""
+	switch r.URL.Query().Get("status") {
+	case "created":
+		flashMessage = "Kelas berhasil dibuat. Bagikan kode kelas atau undang siswa lewat email."
+	case "added":
+		flashMessage = "Siswa berhasil ditambahkan ke kelas."
+	case "invited":
+		flashMessage = "Email belum terdaftar. Siswa otomatis masuk kelas setelah mendaftar dengan email tersebut."
+	case "code":
+		flashMessage = "Kode kelas baru sudah dibuat, kode lama tidak bisa dipakai lagi."
+	}
+
+	handler.renderClassDetail(w, r, http.StatusOK, flashMessage, "")
+}
+
+func (handler *ClassHandlerImpl) DeleteClass(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if err := handler.ClassService.DeleteClass(r.Context(), user.Id, r.PathValue("id")); err != nil {
+		slog.Error("error when calling delete class service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/teacher/classes?status=deleted")
+}
+
+func (handler *ClassHandlerImpl) RegenerateJoinCode(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	classId := r.PathValue("id")
+
+	if _, err := handler.ClassService.RegenerateJoinCode(r.Context(), user.Id, classId); err != nil {
+		slog.Error("error when calling regenerate class join code service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	http.Redirect(w, r, "/teacher/classes/"+classId+"?status=code", http.StatusSeeOther)
+}
+
+func (handler *ClassHandlerImpl) InviteStudent(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	classId := r.PathValue("id")
+
+	request := web.ClassInviteRequest{
+		Email: r.FormValue("email"),
+	}
+
+	isAdded, err := handler.ClassService.InviteStudent(r.Context(), user.Id, classId, request)
+	if err != nil {
+		if errors.Is(err, service.ErrInviteeNotStudent) {
+			handler.renderClassDetail(w, r, http.StatusBadRequest, "", "Email tersebut milik akun guru, hanya siswa yang bisa diundang.")
+			return
+		}
+		slog.Error("error when calling invite student service", "err", err)
+
+		handler.renderClassDetail(w, r, http.StatusBadRequest, "", "Undangan gagal dikirim. Pastikan alamat email valid.")
+		return
+	}
+
+	status := "invited"
+	if isAdded {
+		status = "added"
+	}
+
+	http.Redirect(w, r, "/teacher/classes/"+classId+"?status="+status, http.StatusSeeOther)
+}
+
+func (handler *ClassHandlerImpl) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if err := handler.ClassService.RemoveMember(r.Context(), user.Id, r.PathValue("id"), r.PathValue("studentId")); err != nil {
+		slog.Error("error when calling remove class member service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	// HTMX menghapus baris siswa dengan response kosong
+	w.WriteHeader(http.StatusOK)
+}
+
+func (handler *ClassHandlerImpl) CancelInvitation(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if err := handler.ClassService.CancelInvitation(r.Context(), user.Id, r.PathValue("id"), r.FormValue("email")); err != nil {
+		slog.Error("error when calling cancel class invitation service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	// HTMX menghapus baris undangan dengan response kosong
+	w.WriteHeader(http.StatusOK)
+}
+
+func (handler *ClassHandlerImpl) AssignExamClasses(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if err := r.ParseForm(); err != nil {
+		slog.Error("error parsing form data", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "form tidak valid")
+		return
+	}
+
+	examClasses, err := handler.ClassService.AssignExamToClasses(r.Context(), user.Id, r.PathValue("id"), r.Form["class_ids"])
+	if err != nil {
+		slog.Error("error when calling assign exam to classes service", "err", err)
+
+		if errors.Is(err, service.ErrExamForbidden) {
+			appError.RenderErrorPage(w, handler.Template, http.StatusForbidden, "Anda tidak memiliki akses ke ujian ini")
+			return
+		}
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	if err := handler.Template.ExecuteTemplate(w, "exam-classes", examClasses); err != nil {
+		slog.Error("error when executing exam-classes template", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+}
+
+func (handler *ClassHandlerImpl) renderClasses(w http.ResponseWriter, r *http.Request, statusCode int, flashMessage, errorMessage string) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	if user.Role == "teacher" {
+		user.Role = "Teacher"
+	}
+
+	classes, err := handler.ClassService.GetClassesByTeacherId(r.Context(), user.Id)
+	if err != nil {
+		slog.Error("error when calling get classes by teacher id service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	classesResponse := web.TeacherClassesResponse{
+		User:         user,
+		Classes:      classes,
+		FlashMessage: flashMessage,
+		ErrorMessage: errorMessage,
+	}
+
+	w.WriteHeader(statusCode)
+	if err := handler.Template.ExecuteTemplate(w, "teacher-classes", classesResponse); err != nil {
+		slog.Error("error when executing teacher-classes template", "err", err)
+		return
+	}
+}
+
+func (handler *ClassHandlerImpl) renderClassDetail(w http.ResponseWriter, r *http.Request, statusCode int, flashMessage, errorMessage string) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	if user.Role == "teacher" {
+		user.Role = "Teacher"
+	}
+
+	detailResponse, err := handler.ClassService.GetClassDetail(r.Context(), user.Id, r.PathValue("id"))
+	if err != nil {
+		slog.Error("error when calling get class detail service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusNotFound, "Kelas tidak ditemukan")
+		return
+	}
+
+	detailResponse.User = user
+	detailResponse.FlashMessage = flashMessage
+	detailResponse.ErrorMessage = errorMessage
+
+	w.WriteHeader(statusCode)
+	if err := handler.Template.ExecuteTemplate(w, "teacher-class-detail", detailResponse); err != nil {
+		slog.Error("error when executing teacher-class-detail template", "err", err)
+		return
+	}
+}
@@ -0,0 +1,10 @@
+package handler
+
+import "net/http"
+
+type EmailVerificationHandler interface {
+	VerifyEmail(w http.ResponseWriter, r *http.Request)
+	ConfirmEmailChange(w http.ResponseWriter, r *http.Request)
+	ResendVerificationView(w http.ResponseWriter, r *http.Request)
+	ResendVerification(w http.ResponseWriter, r *http.Request)
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	appError "github.com/mhaatha/go-template-saygenfix/internal/errors"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/service"
+)
+
+func NewEmailVerificationHandler(emailVerificationService service.EmailVerificationService) EmailVerificationHandler {
+	return &EmailVerificationHandlerImpl{
+		EmailVerificationService: emailVerificationService,
+		Template: template.Must(template.ParseFiles(
+			"../../internal/templates/views/resend_verification.html",
+			"../../internal/templates/views/error.html",
+		)),
+	}
+}
+
+type EmailVerificationHandlerImpl struct {
+	EmailVerificationService service.EmailVerificationService
+	Template                 *template.Template
+}
+
+func (handler *EmailVerificationHandlerImpl) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if err := handler.EmailVerificationService.VerifyEmail(r.Context(), r.URL.Query().Get("token")); err != nil {
+		if !errors.Is(err, service.ErrVerificationTokenInvalid) {
+			slog.Error("error when calling verify email service", "err", err)
+
+			appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		handler.renderResend(w, http.StatusBadRequest, web.EmailVerificationPageResponse{
+			ErrorMessage: "Link verifikasi tidak valid atau sudah kedaluwarsa. Masukkan email Anda untuk menerima link baru.",
+		})
+		return
+	}
+
+	http.Redirect(w, r, "/login?status=verified", http.StatusSeeOther)
+}
+
+func (handler *EmailVerificationHandlerImpl) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	if err := handler.EmailVerificationService.ConfirmEmailChange(r.Context(), r.URL.Query().Get("token")); err != nil {
+		slog.Error("error when calling confirm email change service", "err", err)
+
+		switch {
+		case errors.Is(err, service.ErrVerificationTokenInvalid):
+			appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "Link konfirmasi email tidak valid atau sudah kedaluwarsa")
+		case errors.Is(err, service.ErrEmailAlreadyUsed):
+			appError.RenderErrorPage(w, handler.Template, http.StatusConflict, "Email tersebut sudah dipakai akun lain")
+		default:
+			appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+
+	http.Redirect(w, r, "/login?status=email-changed", http.StatusSeeOther)
+}
+
+func (handler *EmailVerificationHandlerImpl) ResendVerificationView(w http.ResponseWriter, r *http.Request) {
+	pageResponse := web.EmailVerificationPageResponse{}
+	if r.URL.Query().Get("status") == "sent" {
+		pageResponse.FlashMessage = "Jika email tersebut terdaftar dan belum diverifikasi, link verifikasi baru sudah dikirim."
+	}
+
+	handler.renderResend(w, http.StatusOK, pageResponse)
+}
+
+func (handler *EmailVerificationHandlerImpl) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	request := web.ResendVerificationRequest{
+		Email: r.PostFormValue("email"),
+	}
+
+	if err := handler.EmailVerificationService.ResendVerification(r.Context(), request); err != nil {
+		slog.Error("error when calling resend verification service", "err", err)
+
+		statusCode := http.StatusBadRequest
+		errorMessage := "Link verifikasi gagal dikirim. Periksa email Anda lalu coba lagi."
+		if errors.Is(err, service.ErrVerificationThrottled) {
+			statusCode = http.StatusTooManyRequests
+			errorMessage = "Link verifikasi baru saja dikirim. Tunggu beberapa menit sebelum meminta lagi."
+		}
+
+		handler.renderResend(w, statusCode, web.EmailVerificationPageResponse{
+			ErrorMessage: errorMessage,
+		})
+		return
+	}
+
+	http.Redirect(w, r, "/verify-email/resend?status=sent", http.StatusSeeOther)
+}
+
+func (handler *EmailVerificationHandlerImpl) renderResend(w http.ResponseWriter, statusCode int, pageResponse web.EmailVerificationPageResponse) {
+	w.WriteHeader(statusCode)
+	if err := handler.Template.ExecuteTemplate(w, "resend-verification", pageResponse); err != nil {
+		slog.Error("failed to execute resend-verification template", "err", err)
+		return
+	}
+}
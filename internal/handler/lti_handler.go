@@ -0,0 +1,16 @@
+package handler
+
+import "net/http"
+
+type LTIHandler interface {
+	// Launch flow, called by the platform
+	Login(w http.ResponseWriter, r *http.Request)
+	Launch(w http.ResponseWriter, r *http.Request)
+	JWKS(w http.ResponseWriter, r *http.Request)
+	DeepLink(w http.ResponseWriter, r *http.Request)
+
+	// Platform registrations, admin only
+	PlatformsView(w http.ResponseWriter, r *http.Request)
+	CreatePlatform(w http.ResponseWriter, r *http.Request)
+	DeletePlatform(w http.ResponseWriter, r *http.Request)
+}
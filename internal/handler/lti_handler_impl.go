@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mhaatha/go-template-saygenfix/internal/config"
+	appError "github.com/mhaatha/go-template-saygenfix/internal/errors"
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/middleware"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/service"
+)
+
+// ltiFieldMessages are the inline messages for validation errors of the platform form
+var ltiFieldMessages = map[string]string{
+	"Name":          "Nama wajib diisi, maksimal 255 karakter.",
+	"Issuer":        "Masukkan issuer berupa URL http atau https, maksimal 255 karakter.",
+	"ClientId":      "Client ID wajib diisi, maksimal 255 karakter.",
+	"AuthLoginURL":  "Masukkan URL http atau https yang valid.",
+	"AuthTokenURL":  "Masukkan URL http atau https yang valid.",
+	"JWKSURL":       "Masukkan URL http atau https yang valid.",
+	"DeploymentIds": "Maksimal 20 deployment ID, masing-masing maksimal 255 karakter.",
+}
+
+// ltiStatusMessages are the flash messages shown after a redirect back to the page
+var ltiStatusMessages = map[string]string{
+	"created": "Platform berhasil didaftarkan.",
+	"deleted": "Platform dan link kursusnya berhasil dihapus.",
+}
+
+func NewLTIHandler(ltiService service.LTIService, twoFactorService service.TwoFactorService, cfg *config.Config) LTIHandler {
+	return &LTIHandlerImpl{
+		LTIService:       ltiService,
+		TwoFactorService: twoFactorService,
+		Template: template.Must(template.ParseFiles(
+			"../../internal/templates/views/admin/lti.html",
+			"../../internal/templates/views/lti/deep_link.html",
+			"../../internal/templates/views/lti/auto_post.html",
+			"../../internal/templates/views/partial/admin_navbar.html",
+			"../../internal/templates/views/error.html",
+		)),
+		Cfg: cfg,
+	}
+}
+
+type LTIHandlerImpl struct {
+	LTIService       service.LTIService
+	TwoFactorService service.TwoFactorService
+	Template         *template.Template
+	Cfg              *config.Config
+}
+
+// Login answers the third-party login initiation, platforms send it as GET or POST
+func (handler *LTIHandlerImpl) Login(w http.ResponseWriter, r *http.Request) {
+	authRequestURL, err := handler.LTIService.InitiateLogin(r.Context(), web.LTILoginRequest{
+		Issuer:        r.FormValue("iss"),
+		LoginHint:     r.FormValue("login_hint"),
+		TargetLinkURI: r.FormValue("target_link_uri"),
+		MessageHint:   r.FormValue("lti_message_hint"),
+		ClientId:      r.FormValue("client_id"),
+		DeploymentId:  r.FormValue("lti_deployment_id"),
+	})
+	if err != nil {
+		slog.Error("failed when calling InitiateLogin service", "err", err)
+
+		var validationErrors validator.ValidationErrors
+		switch {
+		case errors.As(err, &validationErrors):
+			appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "Permintaan login LTI tidak lengkap.")
+		case errors.Is(err, service.ErrLTIPlatformNotFound):
+			appError.RenderErrorPage(w, handler.Template, http.StatusNotFound, "Platform belum didaftarkan oleh admin SayGenFix.")
+		default:
+			appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+
+	http.Redirect(w, r, authRequestURL, http.StatusFound)
+}
+
+// Launch signs the user in and opens the linked exam, or shows the exam picker for deep linking
+func (handler *LTIHandlerImpl) Launch(w http.ResponseWriter, r *http.Request) {
+	result, err := handler.LTIService.Launch(r.Context(), web.LTILaunchRequest{
+		IDToken:   r.PostFormValue("id_token"),
+		State:     r.PostFormValue("state"),
+		IPAddress: helper.ClientIP(r, handler.Cfg.TrustedProxyHops),
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		slog.Error("failed when calling LTI Launch service", "err", err)
+
+		var validationErrors validator.ValidationErrors
+		switch {
+		case errors.Is(err, service.ErrTwoFactorRequired):
+			token, err := handler.TwoFactorService.CreateChallenge(r.Context(), result.User)
+			if err != nil {
+				slog.Error("failed when calling CreateChallenge service", "err", err)
+
+				appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+				return
+			}
+
+			setTwoFactorCookie(w, token)
+			http.Redirect(w, r, "/login/two-factor", http.StatusSeeOther)
+		case errors.As(err, &validationErrors), errors.Is(err, service.ErrLTILaunchInvalid):
+			appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "Launch LTI tidak valid atau sudah kedaluwarsa. Buka kembali aktivitas dari kursus Anda.")
+		case errors.Is(err, service.ErrAccountDisabled):
+			appError.RenderErrorPage(w, handler.Template, http.StatusForbidden, "Akun Anda dinonaktifkan. Hubungi admin.")
+		case errors.Is(err, service.ErrAccountPending):
+			appError.RenderErrorPage(w, handler.Template, http.StatusForbidden, "Akun Anda masih menunggu persetujuan admin.")
+		case errors.Is(err, service.ErrLTIEmailMissing):
+			appError.RenderErrorPage(w, handler.Template, http.StatusForbidden, "Platform tidak mengirim email Anda. Minta admin platform untuk membagikan email ke SayGenFix.")
+		case errors.Is(err, service.ErrLTIEmailTaken):
+			appError.RenderErrorPage(w, handler.Template, http.StatusConflict, "Email Anda di platform sudah dipakai akun SayGenFix lain. Hubungi admin SayGenFix untuk menghubungkan akun Anda.")
+		case errors.Is(err, service.ErrLTIAccountNotAllowed):
+			appError.RenderErrorPage(w, handler.Template, http.StatusForbidden, "Akun admin tidak dapat masuk melalui LTI.")
+		case errors.Is(err, service.ErrLTIExamNotLinked):
+			appError.RenderErrorPage(w, handler.Template, http.StatusNotFound, "Aktivitas ini belum terhubung ke ujian SayGenFix. Minta guru memilih ujian melalui deep linking.")
+		case errors.Is(err, service.ErrLTIInstructorOnly):
+			appError.RenderErrorPage(w, handler.Template, http.StatusForbidden, "Hanya guru yang dapat memilih ujian.")
+		default:
+			appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+
+	if result.DeepLink != nil {
+		handler.renderDeepLink(w, http.StatusOK, *result.DeepLink)
+		return
+	}
+
+	setSessionCookie(w, handler.Cfg, result.SessionId)
+
+	http.Redirect(w, r, result.RedirectPath, http.StatusSeeOther)
+}
+
+// JWKS publishes the public key the platform uses to verify the tool messages and token requests. The
+// document is not wrapped like the JSON API responses, platforms expect a bare JWKS.
+func (handler *LTIHandlerImpl) JWKS(w http.ResponseWriter, r *http.Request) {
+	body, err := json.Marshal(handler.LTIService.KeySet())
+	if err != nil {
+		slog.Error("failed to encode lti key set", "err", err)
+
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(body); err != nil {
+		slog.Error("failed to write lti key set", "err", err)
+	}
+}
+
+// DeepLink sends the picked exam back to the platform
+func (handler *LTIHandlerImpl) DeepLink(w http.ResponseWriter, r *http.Request) {
+	response, err := handler.LTIService.CompleteDeepLink(r.Context(), web.LTIDeepLinkRequest{
+		Token:  r.PostFormValue("token"),
+		ExamId: r.PostFormValue("exam_id"),
+	})
+	if err != nil {
+		slog.Error("failed when calling CompleteDeepLink service", "err", err)
+
+		var validationErrors validator.ValidationErrors
+		switch {
+		case errors.Is(err, service.ErrLTIDeepLinkInvalid):
+			appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "Halaman pemilihan ujian sudah kedaluwarsa. Mulai lagi dari kursus Anda.")
+		case errors.As(err, &validationErrors), errors.Is(err, service.ErrExamForbidden):
+			appError.RenderErrorPage(w, handler.Template, http.StatusForbidden, "Ujian tidak ditemukan atau Anda tidak dapat menyuntingnya.")
+		case errors.Is(err, service.ErrAccountDisabled):
+			appError.RenderErrorPage(w, handler.Template, http.StatusForbidden, "Akun Anda dinonaktifkan. Hubungi admin.")
+		case errors.Is(err, service.ErrLTIPlatformNotFound):
+			appError.RenderErrorPage(w, handler.Template, http.StatusNotFound, "Platform sudah dihapus oleh admin SayGenFix.")
+		default:
+			appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+
+	if err := handler.Template.ExecuteTemplate(w, "lti-auto-post", response); err != nil {
+		slog.Error("error when executing lti-auto-post template", "err", err)
+		return
+	}
+}
+
+func (handler *LTIHandlerImpl) PlatformsView(w http.ResponseWriter, r *http.Request) {
+	pageResponse := web.LTIPlatformPageResponse{
+		FlashMessage: ltiStatusMessages[r.URL.Query().Get("status")],
+	}
+
+	handler.renderPlatforms(w, r, http.StatusOK, pageResponse)
+}
+
+func (handler *LTIHandlerImpl) CreatePlatform(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		slog.Error("failed to parse form", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "Bad Request")
+		return
+	}
+
+	// Deployment ID ditulis satu per baris atau dipisah koma
+	deploymentIds := []string{}
+	for _, deploymentId := range strings.FieldsFunc(r.PostFormValue("deployment_ids"), func(c rune) bool { return c == ',' || c == '\n' || c == '\r' }) {
+		if deploymentId = strings.TrimSpace(deploymentId); deploymentId != "" {
+			deploymentIds = append(deploymentIds, deploymentId)
+		}
+	}
+
+	request := web.LTIPlatformCreateRequest{
+		Name:          r.PostFormValue("name"),
+		Issuer:        r.PostFormValue("issuer"),
+		ClientId:      r.PostFormValue("client_id"),
+		AuthLoginURL:  r.PostFormValue("auth_login_url"),
+		AuthTokenURL:  r.PostFormValue("auth_token_url"),
+		JWKSURL:       r.PostFormValue("jwks_url"),
+		DeploymentIds: deploymentIds,
+	}
+
+	if _, err := handler.LTIService.CreatePlatform(r.Context(), request); err != nil {
+		slog.Error("error when calling create lti platform service", "err", err)
+
+		var validationErrors validator.ValidationErrors
+		pageResponse := web.LTIPlatformPageResponse{Form: request, FieldErrors: map[string]string{}}
+		statusCode := http.StatusBadRequest
+
+		switch {
+		case errors.As(err, &validationErrors):
+			for _, fieldError := range validationErrors {
+				field := fieldError.StructField()
+				if strings.HasPrefix(field, "DeploymentIds") {
+					field = "DeploymentIds"
+				}
+				if message, ok := ltiFieldMessages[field]; ok {
+					pageResponse.FieldErrors[field] = message
+				}
+			}
+		case errors.Is(err, service.ErrLTIPlatformExists):
+			pageResponse.ErrorMessage = "Platform dengan issuer dan client ID ini sudah terdaftar."
+			statusCode = http.StatusConflict
+		default:
+			appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		handler.renderPlatforms(w, r, statusCode, pageResponse)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/lti?status=created", http.StatusSeeOther)
+}
+
+func (handler *LTIHandlerImpl) DeletePlatform(w http.ResponseWriter, r *http.Request) {
+	if err := handler.LTIService.DeletePlatform(r.Context(), r.PathValue("id")); err != nil {
+		slog.Error("error when calling delete lti platform service", "err", err)
+
+		if errors.Is(err, service.ErrLTIPlatformNotFound) {
+			handler.renderPlatforms(w, r, http.StatusNotFound, web.LTIPlatformPageResponse{
+				ErrorMessage: "Platform tidak ditemukan.",
+			})
+			return
+		}
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	http.Redirect(w, r, "/admin/lti?status=deleted", http.StatusSeeOther)
+}
+
+func (handler *LTIHandlerImpl) renderPlatforms(w http.ResponseWriter, r *http.Request, statusCode int, pageResponse web.LTIPlatformPageResponse) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	platforms, err := handler.LTIService.ListPlatforms(r.Context())
+	if err != nil {
+		slog.Error("error when calling list lti platforms service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	scoreSyncs, err := handler.LTIService.ListScoreSyncs(r.Context())
+	if err != nil {
+		slog.Error("error when calling list lti score syncs service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	pageResponse.Platforms = platforms
+	pageResponse.ScoreSyncs = scoreSyncs
+	pageResponse.Tool = handler.LTIService.ToolURLs()
+	user.Role = "Admin"
+	pageResponse.User = user
+
+	w.WriteHeader(statusCode)
+	if err := handler.Template.ExecuteTemplate(w, "admin-lti", pageResponse); err != nil {
+		slog.Error("error when executing admin-lti template", "err", err)
+		return
+	}
+}
+
+func (handler *LTIHandlerImpl) renderDeepLink(w http.ResponseWriter, statusCode int, pageResponse web.LTIDeepLinkPageResponse) {
+	w.WriteHeader(statusCode)
+	if err := handler.Template.ExecuteTemplate(w, "lti-deep-link", pageResponse); err != nil {
+		slog.Error("error when executing lti-deep-link template", "err", err)
+		return
+	}
+}
@@ -0,0 +1,9 @@
+package handler
+
+import "net/http"
+
+type MaterialHandler interface {
+	MaterialsView(w http.ResponseWriter, r *http.Request)
+	UploadMaterial(w http.ResponseWriter, r *http.Request)
+	DeleteMaterial(w http.ResponseWriter, r *http.Request)
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	appError "github.com/mhaatha/go-template-saygenfix/internal/errors"
+	"github.com/mhaatha/go-template-saygenfix/internal/middleware"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/service"
+)
+
+func NewMaterialHandler(materialService service.MaterialService) MaterialHandler {
+	funcMap := template.FuncMap{
+		"fileSize": fileSize,
+	}
+
+	return &MaterialHandlerImpl{
+		MaterialService: materialService,
+		Template: template.Must(
+			template.New("base").Funcs(funcMap).ParseFiles(
+				"../../internal/templates/views/teacher/materials.html",
+				"../../internal/templates/views/partial/teacher_navbar.html",
+				"../../internal/templates/views/error.html",
+			),
+		),
+	}
+}
+
+type MaterialHandlerImpl struct {
+	MaterialService service.MaterialService
+	Template        *template.Template
+}
+
+func (handler *MaterialHandlerImpl) MaterialsView(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	if user.Role == "teacher" {
+		user.Role = "Teacher"
+	}
+
+	materials, err := handler.MaterialService.GetMaterialsByTeacherId(r.Context(), user.Id)
+	if err != nil {
+		slog.Error("error when calling get materials by teacher id service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	materialsResponse := web.TeacherMaterialsResponse{
+		User:      user,
+		Materials: materials,
+	}
+
+	if err := handler.Template.ExecuteTemplate(w, "teacher-materials", materialsResponse); err != nil {
+		slog.Error("error when executing teacher-materials template", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+}
+
+func (handler *MaterialHandlerImpl) UploadMaterial(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		slog.Error("error when parsing multipart form", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "file tidak valid")
+		return
+	}
+
+	file, fileHeader, err := r.FormFile("pdf_file")
+	if err != nil {
+		slog.Error("error when getting file from form data", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "file tidak valid")
+		return
+	}
+	defer file.Close()
+
+	if _, err := handler.MaterialService.SaveMaterial(r.Context(), user.Id, fileHeader.Filename, file); err != nil {
+		slog.Error("error when calling save material service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "file tidak valid")
+		return
+	}
+
+	http.Redirect(w, r, "/teacher/materials", http.StatusSeeOther)
+}
+
+func (handler *MaterialHandlerImpl) DeleteMaterial(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	materialId := r.PathValue("id")
+
+	if err := handler.MaterialService.DeleteMaterial(r.Context(), user.Id, materialId); err != nil {
+		slog.Error("error when calling delete material service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	// HTMX menghapus baris materi dengan response kosong
+	w.WriteHeader(http.StatusOK)
+}
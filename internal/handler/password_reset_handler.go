@@ -0,0 +1,10 @@
+package handler
+
+import "net/http"
+
+type PasswordResetHandler interface {
+	ForgotPasswordView(w http.ResponseWriter, r *http.Request)
+	ForgotPassword(w http.ResponseWriter, r *http.Request)
+	ResetPasswordView(w http.ResponseWriter, r *http.Request)
+	ResetPassword(w http.ResponseWriter, r *http.Request)
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mhaatha/go-template-saygenfix/internal/config"
+	appError "github.com/mhaatha/go-template-saygenfix/internal/errors"
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/service"
+)
+
+func NewPasswordResetHandler(passwordResetService service.PasswordResetService, cfg *config.Config) PasswordResetHandler {
+	return &PasswordResetHandlerImpl{
+		PasswordResetService: passwordResetService,
+		Cfg:                  cfg,
+		Template: template.Must(template.ParseFiles(
+			"../../internal/templates/views/forgot_password.html",
+			"../../internal/templates/views/reset_password.html",
+			"../../internal/templates/views/error.html",
+		)),
+	}
+}
+
+type PasswordResetHandlerImpl struct {
+	PasswordResetService service.PasswordResetService
+	Cfg                  *config.Config
+	Template             *template.Template
+}
+
+func (handler *PasswordResetHandlerImpl) ForgotPasswordView(w http.ResponseWriter, r *http.Request) {
+	pageResponse := web.PasswordResetPageResponse{}
+	if r.URL.Query().Get("status") == "sent" {
+		pageResponse.FlashMessage = "Jika email tersebut terdaftar, link untuk mengatur ulang password sudah dikirim. Periksa kotak masuk Anda."
+	}
+
+	handler.renderPage(w, http.StatusOK, "forgot-password", pageResponse)
+}
+
+func (handler *PasswordResetHandlerImpl) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	request := web.ForgotPasswordRequest{
+		Email:     r.PostFormValue("email"),
+		IPAddress: helper.ClientIP(r, handler.Cfg.TrustedProxyHops),
+	}
+
+	if err := handler.PasswordResetService.RequestReset(r.Context(), request); err != nil {
+		if errors.Is(err, service.ErrResetThrottled) {
+			slog.Warn("password reset throttled", "email", request.Email, "ip", request.IPAddress)
+
+			handler.renderPage(w, http.StatusTooManyRequests, "forgot-password", web.PasswordResetPageResponse{
+				ErrorMessage: "Terlalu banyak permintaan reset password. Silakan coba lagi dalam satu jam.",
+			})
+			return
+		}
+		slog.Error("error when calling request password reset service", "err", err)
+
+		handler.renderPage(w, http.StatusBadRequest, "forgot-password", web.PasswordResetPageResponse{
+			ErrorMessage: "Link reset password gagal dikirim. Periksa email Anda lalu coba lagi.",
+		})
+		return
+	}
+
+	// Pesan yang sama untuk email terdaftar maupun tidak
+	http.Redirect(w, r, "/forgot-password?status=sent", http.StatusSeeOther)
+}
+
+func (handler *PasswordResetHandlerImpl) ResetPasswordView(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	pageResponse := web.PasswordResetPageResponse{
+		Token: token,
+	}
+	if err := handler.PasswordResetService.CheckToken(r.Context(), token); err != nil {
+		if !errors.Is(err, service.ErrResetTokenInvalid) {
+			slog.Error("error when calling check password reset token service", "err", err)
+
+			appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		pageResponse.Token = ""
+		pageResponse.ErrorMessage = "Link reset password tidak valid atau sudah kedaluwarsa. Silakan minta link baru."
+	}
+
+	handler.renderPage(w, http.StatusOK, "reset-password", pageResponse)
+}
+
+func (handler *PasswordResetHandlerImpl) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	token := r.PostFormValue("token")
+	password := r.PostFormValue("password")
+
+	if !helper.CheckPasswordConfirmation(password, r.PostFormValue("confirm_password")) {
+		handler.renderPage(w, http.StatusBadRequest, "reset-password", web.PasswordResetPageResponse{
+			Token:        token,
+			ErrorMessage: "Password dan konfirmasi password tidak sama.",
+		})
+		return
+	}
+
+	request := web.ResetPasswordRequest{
+		Token:    token,
+		Password: password,
+	}
+
+	if err := handler.PasswordResetService.ResetPassword(r.Context(), request); err != nil {
+		slog.Error("error when calling reset password service", "err", err)
+
+		var validationErrors validator.ValidationErrors
+		switch {
+		case errors.Is(err, service.ErrResetTokenInvalid):
+			handler.renderPage(w, http.StatusBadRequest, "reset-password", web.PasswordResetPageResponse{
+				ErrorMessage: "Link reset password tidak valid atau sudah kedaluwarsa. Silakan minta link baru.",
+			})
+		case errors.As(err, &validationErrors):
+			handler.renderPage(w, http.StatusBadRequest, "reset-password", web.PasswordResetPageResponse{
+				Token:        token,
+				ErrorMessage: "Password harus 6-255 karakter.",
+			})
+		default:
+			appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+
+	http.Redirect(w, r, "/login?status=reset", http.StatusSeeOther)
+}
+
+func (handler *PasswordResetHandlerImpl) renderPage(w http.ResponseWriter, statusCode int, name string, pageResponse web.PasswordResetPageResponse) {
+	w.WriteHeader(statusCode)
+	if err := handler.Template.ExecuteTemplate(w, name, pageResponse); err != nil {
+		slog.Error("failed to execute "+name+" template", "err", err)
+		return
+	}
+}
@@ -0,0 +1,12 @@
+package handler
+
+import "net/http"
+
+type QuestionBankHandler interface {
+	QuestionBankView(w http.ResponseWriter, r *http.Request)
+	CreateBankQuestion(w http.ResponseWriter, r *http.Request)
+	UpdateBankQuestion(w http.ResponseWriter, r *http.Request)
+	DeleteBankQuestion(w http.ResponseWriter, r *http.Request)
+	ComposeExam(w http.ResponseWriter, r *http.Request)
+	SaveQuestionToBank(w http.ResponseWriter, r *http.Request)
+}
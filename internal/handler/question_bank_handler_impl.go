@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	appError "github.com/mhaatha/go-template-saygenfix/internal/errors"
+	"github.com/mhaatha/go-template-saygenfix/internal/middleware"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/service"
+)
+
+func NewQuestionBankHandler(questionBankService service.QuestionBankService) QuestionBankHandler {
+	return &QuestionBankHandlerImpl{
+		QuestionBankService: questionBankService,
+		Template: template.Must(template.New("base").Funcs(template.FuncMap{"join": strings.Join}).ParseFiles(
+			"../../internal/templates/views/teacher/question_bank.html",
+			"../../internal/templates/views/partial/teacher_navbar.html",
+			"../../internal/templates/views/partial/question_bank_status.html",
+			"../../internal/templates/views/error.html",
+		)),
+	}
+}
+
+type QuestionBankHandlerImpl struct {
+	QuestionBankService service.QuestionBankService
+	Template            *template.Template
+}
+
+func (handler *QuestionBankHandlerImpl) QuestionBankView(w http.ResponseWriter, r *http.Request) {
+	flashMessage := ""
+	switch r.URL.Query().Get("status") {
+	case "created":
+		flashMessage = "Soal berhasil ditambahkan ke bank."
+	case "updated":
+		flashMessage = "Soal berhasil diperbarui, ujian yang memakainya ikut berubah."
+	}
+
+	handler.renderQuestionBank(w, r, http.StatusOK, flashMessage, "")
+}
+
+func (handler *QuestionBankHandlerImpl) CreateBankQuestion(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if err := r.ParseForm(); err != nil {
+		slog.Error("error parsing form data", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "form tidak valid")
+		return
+	}
+
+	request := web.BankQuestionCreateRequest{
+		Question: strings.TrimSpace(r.FormValue("question")),
+		Answer:   strings.TrimSpace(r.FormValue("answer")),
+		Topic:    strings.TrimSpace(r.FormValue("topic")),
+		Tags:     parseTags(r.FormValue("tags")),
+	}
+
+	if _, err := handler.QuestionBankService.CreateBankQuestion(r.Context(), user.Id, request); err != nil {
+		slog.Error("error when calling create bank question service", "err", err)
+
+		handler.renderQuestionBank(w, r, http.StatusBadRequest, "", "Soal gagal disimpan. Pastikan soal dan jawaban terisi, maksimal 10 tag.")
+		return
+	}
+
+	http.Redirect(w, r, "/teacher/bank?status=created", http.StatusSeeOther)
+}
+
+func (handler *QuestionBankHandlerImpl) UpdateBankQuestion(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	bankQuestionId := r.PathValue("id")
+
+	if err := r.ParseForm(); err != nil {
+		slog.Error("error parsing form data", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "form tidak valid")
+		return
+	}
+
+	request := web.BankQuestionCreateRequest{
+		Question: strings.TrimSpace(r.FormValue("question")),
+		Answer:   strings.TrimSpace(r.FormValue("answer")),
+		Topic:    strings.TrimSpace(r.FormValue("topic")),
+		Tags:     parseTags(r.FormValue("tags")),
+	}
+
+	if _, err := handler.QuestionBankService.UpdateBankQuestion(r.Context(), user.Id, bankQuestionId, request); err != nil {
+		slog.Error("error when calling update bank question service", "err", err)
+
+		handler.renderQuestionBank(w, r, http.StatusBadRequest, "", "Soal gagal diperbarui. Pastikan soal dan jawaban terisi, maksimal 10 tag.")
+		return
+	}
+
+	http.Redirect(w, r, "/teacher/bank?status=updated", http.StatusSeeOther)
+}
+
+func (handler *QuestionBankHandlerImpl) DeleteBankQuestion(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	bankQuestionId := r.PathValue("id")
+
+	if err := handler.QuestionBankService.DeleteBankQuestion(r.Context(), user.Id, bankQuestionId); err != nil {
+		if errors.Is(err, service.ErrBankQuestionInUse) {
+			// Pesan ditampilkan HTMX lewat event htmx:responseError
+			http.Error(w, "Soal masih dipakai di ujian, hapus dari ujian terlebih dahulu.", http.StatusConflict)
+			return
+		}
+		slog.Error("error when calling delete bank question service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	// HTMX menghapus baris soal dengan response kosong
+	w.WriteHeader(http.StatusOK)
+}
+
+func (handler *QuestionBankHandlerImpl) ComposeExam(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if err := r.ParseForm(); err != nil {
+		slog.Error("error parsing form data", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "form tidak valid")
+		return
+	}
+
+	year, _ := strconv.Atoi(r.FormValue("year"))
+	duration, _ := strconv.Atoi(r.FormValue("duration"))
+
+	// Input jumlah soal acak per tag dikirim dengan nama draw_<tag>
+	tagDraws := map[string]int{}
+	for key, values := range r.Form {
+		tag, ok := strings.CutPrefix(key, "draw_")
+		if !ok || len(values) == 0 || values[0] == "" {
+			continue
+		}
+
+		count, err := strconv.Atoi(values[0])
+		if err != nil {
+			handler.renderQuestionBank(w, r, http.StatusBadRequest, "", "Jumlah soal acak harus berupa angka.")
+			return
+		}
+		tagDraws[tag] = count
+	}
+
+	request := web.ComposeExamRequest{
+		RoomName:        strings.TrimSpace(r.FormValue("roomName")),
+		Year:            year,
+		Duration:        duration,
+		BankQuestionIds: r.Form["bank_question_ids"],
+		TagDraws:        tagDraws,
+	}
+
+	examId, err := handler.QuestionBankService.ComposeExam(r.Context(), user.Id, request)
+	if err != nil {
+		slog.Error("error when calling compose exam service", "err", err)
+
+		handler.renderQuestionBank(w, r, http.StatusBadRequest, "", "Ujian gagal dibuat. Isi nama, tahun dan durasi, pilih minimal satu soal, dan pastikan soal per tag mencukupi.")
+		return
+	}
+
+	http.Redirect(w, r, "/teacher/edit-exam/"+examId, http.StatusSeeOther)
+}
+
+func (handler *QuestionBankHandlerImpl) SaveQuestionToBank(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	examId := r.PathValue("id")
+	questionId := r.PathValue("questionId")
+
+	topic := strings.TrimSpace(r.FormValue("bank_topic_" + questionId))
+	tags := parseTags(r.FormValue("bank_tags_" + questionId))
+
+	bankQuestion, err := handler.QuestionBankService.SaveExamQuestionToBank(r.Context(), user.Id, questionId, topic, tags)
+	if err != nil {
+		slog.Error("error when calling save exam question to bank service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "soal gagal disimpan ke bank")
+		return
+	}
+
+	question := domain.QAItem{
+		Id:             questionId,
+		ExamId:         examId,
+		BankQuestionId: bankQuestion.Id,
+	}
+
+	if err := handler.Template.ExecuteTemplate(w, "question-bank-status", question); err != nil {
+		slog.Error("error when executing question-bank-status template", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+}
+
+func (handler *QuestionBankHandlerImpl) renderQuestionBank(w http.ResponseWriter, r *http.Request, statusCode int, flashMessage, errorMessage string) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	if user.Role == "teacher" {
+		user.Role = "Teacher"
+	}
+
+	selectedTag := r.URL.Query().Get("tag")
+	selectedTopic := r.URL.Query().Get("topic")
+
+	questions, tags, topics, err := handler.QuestionBankService.GetQuestionBank(r.Context(), user.Id, selectedTag, selectedTopic)
+	if err != nil {
+		slog.Error("error when calling get question bank service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	bankResponse := web.TeacherQuestionBankResponse{
+		User:          user,
+		Questions:     questions,
+		Tags:          tags,
+		Topics:        topics,
+		SelectedTag:   selectedTag,
+		SelectedTopic: selectedTopic,
+		FlashMessage:  flashMessage,
+		ErrorMessage:  errorMessage,
+	}
+
+	w.WriteHeader(statusCode)
+	if err := handler.Template.ExecuteTemplate(w, "teacher-question-bank", bankResponse); err != nil {
+		slog.Error("error when executing teacher-question-bank template", "err", err)
+		return
+	}
+}
+
+// parseTags splits a comma separated list into lowercase tags without duplicates
+func parseTags(raw string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(raw, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || slices.Contains(tags, tag) {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
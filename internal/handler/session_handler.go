@@ -0,0 +1,9 @@
+package handler
+
+import "net/http"
+
+type SessionHandler interface {
+	SessionsView(w http.ResponseWriter, r *http.Request)
+	Revoke(w http.ResponseWriter, r *http.Request)
+	RevokeOthers(w http.ResponseWriter, r *http.Request)
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	appError "github.com/mhaatha/go-template-saygenfix/internal/errors"
+	"github.com/mhaatha/go-template-saygenfix/internal/middleware"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/service"
+)
+
+func NewSessionHandler(authService service.AuthService) SessionHandler {
+	return &SessionHandlerImpl{
+		AuthService: authService,
+		Template: template.Must(template.ParseFiles(
+			"../../internal/templates/views/sessions.html",
+			"../../internal/templates/views/partial/teacher_navbar.html",
+			"../../internal/templates/views/partial/admin_navbar.html",
+			"../../internal/templates/views/partial/student_dashboard_navbar.html",
+			"../../internal/templates/views/error.html",
+		)),
+	}
+}
+
+type SessionHandlerImpl struct {
+	AuthService service.AuthService
+	Template    *template.Template
+}
+
+func (handler *SessionHandlerImpl) SessionsView(w http.ResponseWriter, r *http.Request) {
+	pageResponse := web.SessionPageResponse{}
+	switch r.URL.Query().Get("status") {
+	case "revoked":
+		pageResponse.FlashMessage = "Perangkat berhasil dikeluarkan."
+	case "revoked-others":
+		pageResponse.FlashMessage = "Semua perangkat lain berhasil dikeluarkan."
+	}
+
+	handler.renderSessions(w, r, http.StatusOK, pageResponse)
+}
+
+func (handler *SessionHandlerImpl) Revoke(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	currentSessionId := r.Context().Value(middleware.CurrentSessionKey).(string)
+
+	err := handler.AuthService.RevokeSession(r.Context(), user.Id, currentSessionId, r.PathValue("id"))
+	if err != nil {
+		slog.Error("error when calling revoke session service", "err", err)
+
+		if errors.Is(err, service.ErrSessionNotFound) {
+			handler.renderSessions(w, r, http.StatusNotFound, web.SessionPageResponse{
+				ErrorMessage: "Sesi tidak ditemukan atau sudah berakhir.",
+			})
+			return
+		}
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	http.Redirect(w, r, "/account/sessions?status=revoked", http.StatusSeeOther)
+}
+
+func (handler *SessionHandlerImpl) RevokeOthers(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	currentSessionId := r.Context().Value(middleware.CurrentSessionKey).(string)
+
+	_, err := handler.AuthService.RevokeOtherSessions(r.Context(), user.Id, currentSessionId)
+	if err != nil {
+		slog.Error("error when calling revoke other sessions service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	http.Redirect(w, r, "/account/sessions?status=revoked-others", http.StatusSeeOther)
+}
+
+func (handler *SessionHandlerImpl) renderSessions(w http.ResponseWriter, r *http.Request, statusCode int, pageResponse web.SessionPageResponse) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	currentSessionId := r.Context().Value(middleware.CurrentSessionKey).(string)
+
+	sessions, err := handler.AuthService.FindSessions(r.Context(), user.Id, currentSessionId)
+	if err != nil {
+		slog.Error("error when calling find sessions service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	pageResponse.Sessions = sessions
+	switch user.Role {
+	case "student":
+		user.Role = "Student"
+	case "teacher":
+		user.Role = "Teacher"
+	case "admin":
+		user.Role = "Admin"
+	}
+	pageResponse.User = user
+
+	w.WriteHeader(statusCode)
+	if err := handler.Template.ExecuteTemplate(w, "sessions", pageResponse); err != nil {
+		slog.Error("error when executing sessions template", "err", err)
+		return
+	}
+}
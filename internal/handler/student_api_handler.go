@@ -0,0 +1,13 @@
+package handler
+
+import "net/http"
+
+type StudentAPIHandler interface {
+	ListExams(w http.ResponseWriter, r *http.Request)
+	StartAttempt(w http.ResponseWriter, r *http.Request)
+	GetAttempt(w http.ResponseWriter, r *http.Request)
+	SaveAnswer(w http.ResponseWriter, r *http.Request)
+	SubmitAttempt(w http.ResponseWriter, r *http.Request)
+	ListResults(w http.ResponseWriter, r *http.Request)
+	GetAttemptResult(w http.ResponseWriter, r *http.Request)
+}
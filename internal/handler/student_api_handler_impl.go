@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/middleware"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/api"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/service"
+)
+
+func NewStudentAPIHandler(studentService service.StudentService) StudentAPIHandler {
+	return &StudentAPIHandlerImpl{
+		StudentService: studentService,
+	}
+}
+
+type StudentAPIHandlerImpl struct {
+	StudentService service.StudentService
+}
+
+// ListExams returns the active exams the student can start
+func (handler *StudentAPIHandlerImpl) ListExams(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	exams, err := handler.StudentService.GetActiveExams(r.Context(), user.Id)
+	if err != nil {
+		slog.Error("error when calling get active exams service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	responses := []api.StudentExamResponse{}
+	for _, exam := range exams {
+		responses = append(responses, api.StudentExamResponse{
+			Id:              exam.Id,
+			Name:            exam.RoomName,
+			Year:            exam.Year,
+			DurationMinutes: exam.Duration,
+			TeacherName:     exam.TeacherName,
+		})
+	}
+
+	helper.WriteJSON(w, http.StatusOK, responses)
+}
+
+func (handler *StudentAPIHandlerImpl) StartAttempt(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	attemptId, err := handler.StudentService.CreateExamAttempt(r.Context(), user.Id, r.PathValue("examId"))
+	if err != nil {
+		slog.Error("error when calling create exam attempt service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	handler.writeAttempt(w, r, http.StatusCreated, user.Id, attemptId)
+}
+
+func (handler *StudentAPIHandlerImpl) GetAttempt(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	handler.writeAttempt(w, r, http.StatusOK, user.Id, r.PathValue("id"))
+}
+
+func (handler *StudentAPIHandlerImpl) SaveAnswer(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	request := api.AnswerRequest{}
+	if !readAPIRequest(w, r, &request) {
+		return
+	}
+
+	answerRequest := web.AttemptAnswerRequest{
+		AttemptId:  r.PathValue("id"),
+		QuestionId: r.PathValue("questionId"),
+		Answer:     request.Answer,
+	}
+	if err := handler.StudentService.SaveAttemptAnswer(r.Context(), user.Id, answerRequest); err != nil {
+		slog.Error("error when calling save attempt answer service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusOK, api.AnswerResponse{
+		QuestionId: answerRequest.QuestionId,
+		Answer:     answerRequest.Answer,
+	})
+}
+
+// SubmitAttempt scores the saved answers, the score is part of the returned attempt
+func (handler *StudentAPIHandlerImpl) SubmitAttempt(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	attemptId := r.PathValue("id")
+
+	if _, err := handler.StudentService.SubmitAttempt(r.Context(), user.Id, attemptId); err != nil {
+		slog.Error("error when calling submit attempt service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	handler.writeAttempt(w, r, http.StatusOK, user.Id, attemptId)
+}
+
+// ListResults returns the best score of the student on every exam they took
+func (handler *StudentAPIHandlerImpl) ListResults(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	examAttempts, err := handler.StudentService.GetBiggestExamAttemptsByStudentId(r.Context(), user.Id)
+	if err != nil {
+		slog.Error("error when calling get biggest exam attempts by student id service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	exams, err := handler.StudentService.GetExamsWithScoreAndTeacherNameByExamId(r.Context(), examAttempts)
+	if err != nil {
+		slog.Error("error when calling get exams with score and teacher name service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	results := []api.ResultResponse{}
+	for _, exam := range exams {
+		results = append(results, api.ResultResponse{
+			ExamId:      exam.Id,
+			ExamName:    exam.Name,
+			Year:        exam.Year,
+			TeacherName: exam.TeacherName,
+			Score:       exam.Score,
+		})
+	}
+
+	helper.WriteJSON(w, http.StatusOK, results)
+}
+
+// GetAttemptResult returns the scored answers of a submitted attempt
+func (handler *StudentAPIHandlerImpl) GetAttemptResult(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	attempt, err := handler.StudentService.GetAttempt(r.Context(), user.Id, r.PathValue("id"))
+	if err != nil {
+		slog.Error("error when calling get attempt service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+	if attempt.CompletedAt.IsZero() {
+		helper.WriteJSONError(w, http.StatusConflict, "attempt_not_completed", "Submit the attempt before reading its result")
+		return
+	}
+
+	studentAnswers, err := handler.StudentService.GetStudentAnswersByExamAttemptId(r.Context(), attempt.ID)
+	if err != nil {
+		slog.Error("error when calling get student answers by exam attempt id service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	answers := []api.AnswerResultResponse{}
+	for _, studentAnswer := range studentAnswers {
+		question, err := handler.StudentService.FindQuestionById(r.Context(), studentAnswer.QuestionID)
+		if err != nil {
+			slog.Error("error when calling find question by id service", "err", err)
+
+			handler.writeError(w, err)
+			return
+		}
+
+		answers = append(answers, api.AnswerResultResponse{
+			QuestionId:    studentAnswer.QuestionID,
+			Question:      question.Question,
+			CorrectAnswer: question.RightAnswer,
+			Answer:        studentAnswer.StudentAnswer,
+			Score:         studentAnswer.Score,
+			MaxScore:      studentAnswer.QuestionMaxScore,
+			Similarity:    studentAnswer.Similarity,
+			Feedback:      studentAnswer.Feedback,
+		})
+	}
+
+	helper.WriteJSON(w, http.StatusOK, api.AttemptResultResponse{
+		AttemptId: attempt.ID,
+		ExamId:    attempt.ExamID,
+		Score:     attempt.Score,
+		Answers:   answers,
+	})
+}
+
+// writeAttempt answers with the attempt, its questions in the attempt's order and the saved answers
+func (handler *StudentAPIHandlerImpl) writeAttempt(w http.ResponseWriter, r *http.Request, statusCode int, studentId, attemptId string) {
+	attempt, err := handler.StudentService.GetAttempt(r.Context(), studentId, attemptId)
+	if err != nil {
+		slog.Error("error when calling get attempt service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	questions, err := handler.StudentService.GetAttemptQuestions(r.Context(), attempt.ID)
+	if err != nil {
+		slog.Error("error when calling get attempt questions service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	answers, err := handler.StudentService.GetAnswersByAttemptId(r.Context(), attempt.ID)
+	if err != nil {
+		slog.Error("error when calling get answers by attempt id service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	savedAnswers := map[string]string{}
+	for _, answer := range answers {
+		savedAnswers[answer.QuestionID] = answer.StudentAnswer
+	}
+
+	// Kunci jawaban tidak pernah dikirim ke siswa
+	attemptQuestions := []api.AttemptQuestionResponse{}
+	for i, question := range questions {
+		attemptQuestions = append(attemptQuestions, api.AttemptQuestionResponse{
+			Id:       question.Id,
+			Number:   i + 1,
+			Question: question.Question,
+			Answer:   savedAnswers[question.Id],
+		})
+	}
+
+	helper.WriteJSON(w, statusCode, api.AttemptResponse{
+		Id:          attempt.ID,
+		ExamId:      attempt.ExamID,
+		IsCompleted: !attempt.CompletedAt.IsZero(),
+		Score:       attempt.Score,
+		StartedAt:   attempt.StartedAt,
+		CompletedAt: completedAt(attempt),
+		Questions:   attemptQuestions,
+	})
+}
+
+func (handler *StudentAPIHandlerImpl) writeError(w http.ResponseWriter, err error) {
+	if writeAPIValidationError(w, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, service.ErrExamNotFound):
+		helper.WriteJSONError(w, http.StatusNotFound, "exam_not_found", "Exam does not exist")
+	case errors.Is(err, service.ErrExamNotActive):
+		helper.WriteJSONError(w, http.StatusForbidden, "exam_not_active", "Exam has not been opened by the teacher")
+	case errors.Is(err, service.ErrExamNotJoined):
+		helper.WriteJSONError(w, http.StatusForbidden, "exam_not_joined", "Exam is private, join it with its join code first")
+	case errors.Is(err, service.ErrExamNotInClass):
+		helper.WriteJSONError(w, http.StatusForbidden, "exam_not_in_class", "Exam is only open to members of its classes")
+	case errors.Is(err, service.ErrEmailNotVerified):
+		helper.WriteJSONError(w, http.StatusForbidden, "email_not_verified", "Verify your email address before starting an exam")
+	case errors.Is(err, service.ErrAttemptNotFound):
+		helper.WriteJSONError(w, http.StatusNotFound, "attempt_not_found", "Attempt does not exist")
+	case errors.Is(err, service.ErrAttemptCompleted):
+		helper.WriteJSONError(w, http.StatusConflict, "attempt_completed", "Attempt was already submitted")
+	case errors.Is(err, service.ErrQuestionNotInAttempt):
+		helper.WriteJSONError(w, http.StatusNotFound, "question_not_found", "Question is not part of this attempt")
+	default:
+		helper.WriteJSONError(w, http.StatusInternalServerError, "internal_error", "Internal Server Error")
+	}
+}
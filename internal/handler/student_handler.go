@@ -4,6 +4,9 @@ import "net/http"
 
 type StudentHandler interface {
 	DashboardView(w http.ResponseWriter, r *http.Request)
+	JoinExam(w http.ResponseWriter, r *http.Request)
+	JoinPublicExam(w http.ResponseWriter, r *http.Request)
+	JoinClass(w http.ResponseWriter, r *http.Request)
 
 	TakeExamView(w http.ResponseWriter, r *http.Request)
 	HandleQuestionPartial(w http.ResponseWriter, r *http.Request)
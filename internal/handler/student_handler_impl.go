@@ -87,7 +87,16 @@ func (handler *StudentHandlerImpl) DashboardView(w http.ResponseWriter, r *http.
 		user.Role = "Student"
 	}
 
-	exams, err := handler.StudentService.GetActiveExams(r.Context())
+	// Kelas diambil lebih dulu agar undangan kelas yang baru diklaim ikut menentukan ujian yang tampil
+	classes, err := handler.StudentService.GetClasses(r.Context(), user.Id)
+	if err != nil {
+		slog.Error("failed to get student classes", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	exams, err := handler.StudentService.GetActiveExams(r.Context(), user.Id)
 	if err != nil {
 		slog.Error("failed to get active exams", "err", err)
 
@@ -126,6 +135,32 @@ func (handler *StudentHandlerImpl) DashboardView(w http.ResponseWriter, r *http.
 		Exams:    exams,
 		Years:    years,
 		Teachers: teachersMap,
+		Classes:  classes,
+	}
+
+	// Ujian publik hanya ditampilkan saat siswa memilih untuk menjelajahinya
+	if r.URL.Query().Get("browse") == "public" {
+		dashboardData.BrowsePublic = true
+		dashboardData.PublicExams, err = handler.StudentService.GetPublicExams(r.Context(), user.Id)
+		if err != nil {
+			slog.Error("failed to get public exams", "err", err)
+
+			appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+	}
+
+	switch r.URL.Query().Get("join") {
+	case "success":
+		dashboardData.FlashMessage = "Berhasil bergabung ke ujian."
+	case "invalid":
+		dashboardData.ErrorMessage = "Kode ujian tidak ditemukan atau sudah tidak berlaku."
+	case "class-success":
+		dashboardData.FlashMessage = "Berhasil bergabung ke kelas."
+	case "class-invalid":
+		dashboardData.ErrorMessage = "Kode kelas tidak ditemukan atau sudah tidak berlaku."
+	case "public-invalid":
+		dashboardData.ErrorMessage = "Ujian tidak ditemukan atau bukan ujian publik."
 	}
 
 	if err := handler.Template.ExecuteTemplate(w, "student-dashboard", dashboardData); err != nil {
@@ -136,6 +171,60 @@ func (handler *StudentHandlerImpl) DashboardView(w http.ResponseWriter, r *http.
 	}
 }
 
+// JoinExam menambahkan siswa ke ujian privat berdasarkan join code.
+func (handler *StudentHandlerImpl) JoinExam(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if _, err := handler.StudentService.JoinExamByCode(r.Context(), user.Id, r.FormValue("join_code")); err != nil {
+		if errors.Is(err, service.ErrJoinCodeNotFound) {
+			http.Redirect(w, r, "/student/dashboard?join=invalid", http.StatusSeeOther)
+			return
+		}
+		slog.Error("error when calling join exam by code service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	http.Redirect(w, r, "/student/dashboard?join=success", http.StatusSeeOther)
+}
+
+// JoinPublicExam menambahkan siswa ke ujian publik yang dipilih dari daftar ujian publik.
+func (handler *StudentHandlerImpl) JoinPublicExam(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if err := handler.StudentService.JoinPublicExam(r.Context(), user.Id, r.PathValue("examId")); err != nil {
+		if errors.Is(err, service.ErrExamNotFound) {
+			http.Redirect(w, r, "/student/dashboard?browse=public&join=public-invalid", http.StatusSeeOther)
+			return
+		}
+		slog.Error("error when calling join public exam service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	http.Redirect(w, r, "/student/dashboard?join=success", http.StatusSeeOther)
+}
+
+// JoinClass menambahkan siswa ke kelas berdasarkan join code kelas.
+func (handler *StudentHandlerImpl) JoinClass(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if _, err := handler.StudentService.JoinClassByCode(r.Context(), user.Id, r.FormValue("class_code")); err != nil {
+		if errors.Is(err, service.ErrJoinCodeNotFound) {
+			http.Redirect(w, r, "/student/dashboard?join=class-invalid", http.StatusSeeOther)
+			return
+		}
+		slog.Error("error when calling join class by code service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	http.Redirect(w, r, "/student/dashboard?join=class-success", http.StatusSeeOther)
+}
+
 // TakeExamView mempersiapkan ujian, membuat attempt, dan menampilkan soal pertama.
 func (handler *StudentHandlerImpl) TakeExamView(w http.ResponseWriter, r *http.Request) {
 	examId := r.PathValue("examId")
@@ -146,10 +235,26 @@ func (handler *StudentHandlerImpl) TakeExamView(w http.ResponseWriter, r *http.R
 	if err != nil {
 		slog.Error("error when calling create exam attempt service", "err", err)
 
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, service.ErrExamNotFound) {
 			appError.RenderErrorPage(w, handler.Template, http.StatusNotFound, fmt.Sprintf("Exam with id %s is not found", examId))
 			return
 		}
+		if errors.Is(err, service.ErrExamNotActive) {
+			appError.RenderErrorPage(w, handler.Template, http.StatusForbidden, "Ujian ini belum dibuka oleh guru")
+			return
+		}
+		if errors.Is(err, service.ErrExamNotJoined) {
+			appError.RenderErrorPage(w, handler.Template, http.StatusForbidden, "Ujian ini privat, masukkan kode ujian di dashboard terlebih dahulu")
+			return
+		}
+		if errors.Is(err, service.ErrExamNotInClass) {
+			appError.RenderErrorPage(w, handler.Template, http.StatusForbidden, "Ujian ini hanya untuk anggota kelas tertentu, gabung ke kelas di dashboard terlebih dahulu")
+			return
+		}
+		if errors.Is(err, service.ErrEmailNotVerified) {
+			appError.RenderErrorPage(w, handler.Template, http.StatusForbidden, "Verifikasi email Anda terlebih dahulu, link verifikasi bisa dikirim ulang dari dashboard")
+			return
+		}
 
 		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
 		return
@@ -228,7 +333,8 @@ func (handler *StudentHandlerImpl) serveQuestion(w http.ResponseWriter, r *http.
 		return
 	}
 
-	questionList, err := handler.StudentService.GetQuestionsByExamId(r.Context(), examID)
+	// Setiap attempt punya urutan dan pilihan soal sendiri
+	questionList, err := handler.StudentService.GetAttemptQuestions(r.Context(), attemptID)
 	if err != nil {
 		slog.Error("error getting question", "err", err)
 
@@ -289,6 +395,27 @@ func (handler *StudentHandlerImpl) SubmitExam(w http.ResponseWriter, r *http.Req
 		return
 	}
 	attemptID := cookie.Value
+	resultURL := fmt.Sprintf("/student/exam-result/%s", examId)
+
+	// Attempt harus milik siswa ini, attempt yang sudah dikumpulkan tidak boleh diubah lagi
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	attempt, err := handler.StudentService.GetAttempt(r.Context(), user.Id, attemptID)
+	if err != nil {
+		slog.Error("error getting exam attempt", "err", err)
+
+		if errors.Is(err, service.ErrAttemptNotFound) {
+			appError.RenderErrorPage(w, handler.Template, http.StatusUnauthorized, "Sesi ujian tidak valid atau telah berakhir")
+			return
+		}
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if !attempt.CompletedAt.IsZero() {
+		w.Header().Set("HX-Redirect", resultURL)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
 
 	if err := r.ParseForm(); err != nil {
 		slog.Error("failed to parse form", "err", err)
@@ -306,8 +433,25 @@ func (handler *StudentHandlerImpl) SubmitExam(w http.ResponseWriter, r *http.Req
 		}
 	}
 
+	// Hanya soal yang memang diberikan pada attempt ini yang boleh dijawab
+	attemptQuestions, err := handler.StudentService.GetAttemptQuestions(r.Context(), attemptID)
+	if err != nil {
+		slog.Error("error getting attempt questions", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	attemptQuestionIds := []string{}
+	for _, question := range attemptQuestions {
+		attemptQuestionIds = append(attemptQuestionIds, question.Id)
+	}
+
 	// 2. Simpan semua jawaban dari map ke database dalam satu perulangan.
 	for questionID, studentAnswer := range studentAnswers {
+		if !slices.Contains(attemptQuestionIds, questionID) {
+			continue
+		}
+
 		// Opsional: hanya simpan jawaban yang tidak kosong.
 		if studentAnswer != "" {
 			answer := web.StudentAnswer{
@@ -325,10 +469,10 @@ func (handler *StudentHandlerImpl) SubmitExam(w http.ResponseWriter, r *http.Req
 	}
 
 	// 3. Panggil service untuk menghitung skor dan menyelesaikan ujian.
-	// (Asumsi: service ini menangani kalkulasi dan update status attempt)
-	_, err = handler.StudentService.CalculateScore(r.Context(), attemptID)
-	if err != nil {
-		slog.Error("error calculating score", "err", err)
+	// Pengumpulan ganda yang bersamaan cukup diarahkan ke hasil.
+	_, err = handler.StudentService.SubmitAttempt(r.Context(), user.Id, attemptID)
+	if err != nil && !errors.Is(err, service.ErrAttemptCompleted) {
+		slog.Error("error submitting exam attempt", "err", err)
 
 		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
 		return
@@ -343,7 +487,6 @@ func (handler *StudentHandlerImpl) SubmitExam(w http.ResponseWriter, r *http.Req
 	})
 
 	// 4. Arahkan pengguna ke halaman hasil.
-	resultURL := fmt.Sprintf("/student/exam-result/%s", examId)
 	w.Header().Set("HX-Redirect", resultURL)
 	w.WriteHeader(http.StatusOK)
 }
@@ -0,0 +1,16 @@
+package handler
+
+import "net/http"
+
+type TeacherAPIHandler interface {
+	ListExams(w http.ResponseWriter, r *http.Request)
+	CreateExam(w http.ResponseWriter, r *http.Request)
+	GetExam(w http.ResponseWriter, r *http.Request)
+	UpdateExam(w http.ResponseWriter, r *http.Request)
+	DeleteExam(w http.ResponseWriter, r *http.Request)
+	ListQuestions(w http.ResponseWriter, r *http.Request)
+	CreateQuestion(w http.ResponseWriter, r *http.Request)
+	UpdateQuestion(w http.ResponseWriter, r *http.Request)
+	DeleteQuestion(w http.ResponseWriter, r *http.Request)
+	ListResults(w http.ResponseWriter, r *http.Request)
+}
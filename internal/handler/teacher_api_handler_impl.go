@@ -0,0 +1,327 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/middleware"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/api"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/service"
+)
+
+func NewTeacherAPIHandler(teacherService service.TeacherService) TeacherAPIHandler {
+	return &TeacherAPIHandlerImpl{
+		TeacherService: teacherService,
+	}
+}
+
+type TeacherAPIHandlerImpl struct {
+	TeacherService service.TeacherService
+}
+
+func (handler *TeacherAPIHandlerImpl) ListExams(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	dashboard, err := handler.TeacherService.TeacherDashboard(r.Context(), user.Id, r.URL.Query().Get("class_id"))
+	if err != nil {
+		slog.Error("error when calling teacher dashboard service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	exams := []api.ExamResponse{}
+	for _, exam := range dashboard.Exams {
+		exams = append(exams, toAPIExam(exam))
+	}
+
+	helper.WriteJSON(w, http.StatusOK, exams)
+}
+
+func (handler *TeacherAPIHandlerImpl) CreateExam(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	request := api.ExamCreateRequest{}
+	if !readAPIRequest(w, r, &request) {
+		return
+	}
+
+	questions := []web.QuestionSaveRequest{}
+	for _, question := range request.Questions {
+		questions = append(questions, web.QuestionSaveRequest{Question: question.Question, Answer: question.Answer})
+	}
+
+	exam, err := handler.TeacherService.CreateExam(r.Context(), user.Id, web.ExamCreateRequest{
+		RoomName:  request.Name,
+		Year:      request.Year,
+		Duration:  request.DurationMinutes,
+		Questions: questions,
+	})
+	if err != nil {
+		slog.Error("error when calling create exam service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	handler.writeExamDetail(w, r, http.StatusCreated, exam)
+}
+
+func (handler *TeacherAPIHandlerImpl) GetExam(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	exam, err := handler.TeacherService.GetExamForTeacher(r.Context(), user.Id, r.PathValue("id"), domain.ExamRoleViewer)
+	if err != nil {
+		slog.Error("error when calling get exam for teacher service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	handler.writeExamDetail(w, r, http.StatusOK, exam)
+}
+
+func (handler *TeacherAPIHandlerImpl) UpdateExam(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	request := api.ExamUpdateRequest{}
+	if !readAPIRequest(w, r, &request) {
+		return
+	}
+
+	exam, err := handler.TeacherService.UpdateExam(r.Context(), user.Id, r.PathValue("id"), web.ExamUpdateRequest{
+		RoomName:          request.Name,
+		Year:              request.Year,
+		Duration:          request.DurationMinutes,
+		ShuffleQuestions:  request.ShuffleQuestions,
+		QuestionDrawCount: request.QuestionDrawCount,
+		IsActive:          request.IsActive,
+	})
+	if err != nil {
+		slog.Error("error when calling update exam service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	handler.writeExamDetail(w, r, http.StatusOK, exam)
+}
+
+func (handler *TeacherAPIHandlerImpl) DeleteExam(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if err := handler.TeacherService.DeleteExam(r.Context(), user.Id, r.PathValue("id")); err != nil {
+		slog.Error("error when calling delete exam service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (handler *TeacherAPIHandlerImpl) ListQuestions(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	examId := r.PathValue("id")
+
+	if _, err := handler.TeacherService.GetExamForTeacher(r.Context(), user.Id, examId, domain.ExamRoleViewer); err != nil {
+		slog.Error("error when calling get exam for teacher service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	questions, err := handler.TeacherService.GetQAByExamId(r.Context(), examId)
+	if err != nil {
+		slog.Error("error when calling get qa by exam id service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusOK, toAPIQuestions(questions))
+}
+
+func (handler *TeacherAPIHandlerImpl) CreateQuestion(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	request := api.QuestionRequest{}
+	if !readAPIRequest(w, r, &request) {
+		return
+	}
+
+	question, err := handler.TeacherService.CreateQuestion(r.Context(), user.Id, r.PathValue("id"), web.QuestionSaveRequest{
+		Question: request.Question,
+		Answer:   request.Answer,
+	})
+	if err != nil {
+		slog.Error("error when calling create question service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusCreated, toAPIQuestion(question))
+}
+
+func (handler *TeacherAPIHandlerImpl) UpdateQuestion(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	request := api.QuestionRequest{}
+	if !readAPIRequest(w, r, &request) {
+		return
+	}
+
+	question, err := handler.TeacherService.UpdateQuestion(r.Context(), user.Id, r.PathValue("id"), r.PathValue("questionId"), web.QuestionSaveRequest{
+		Question: request.Question,
+		Answer:   request.Answer,
+	})
+	if err != nil {
+		slog.Error("error when calling update question service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusOK, toAPIQuestion(question))
+}
+
+func (handler *TeacherAPIHandlerImpl) DeleteQuestion(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if err := handler.TeacherService.DeleteQuestion(r.Context(), user.Id, r.PathValue("id"), r.PathValue("questionId")); err != nil {
+		slog.Error("error when calling delete question service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListResults returns the best attempt of every student, optionally only for the members of class_id
+func (handler *TeacherAPIHandlerImpl) ListResults(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	examId := r.PathValue("id")
+
+	if _, err := handler.TeacherService.GetExamForTeacher(r.Context(), user.Id, examId, domain.ExamRoleGrader); err != nil {
+		slog.Error("error when calling get exam for teacher service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	attempts, err := handler.TeacherService.GetBiggestExamAttemptsScoreByExamId(r.Context(), examId, r.URL.Query().Get("class_id"))
+	if err != nil {
+		slog.Error("error when calling get biggest exam attempts score by exam id service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	results := []api.ExamResultResponse{}
+	for _, attempt := range attempts {
+		studentName, studentId, err := handler.TeacherService.GetStudentFullNameByExamAttemptsId(r.Context(), attempt.ID)
+		if err != nil {
+			slog.Error("error when calling get student full name by exam attempts id service", "err", err)
+
+			handler.writeError(w, err)
+			return
+		}
+
+		results = append(results, api.ExamResultResponse{
+			AttemptId:   attempt.ID,
+			StudentId:   studentId,
+			StudentName: studentName,
+			Score:       attempt.Score,
+			StartedAt:   attempt.StartedAt,
+			CompletedAt: completedAt(attempt),
+		})
+	}
+
+	helper.WriteJSON(w, http.StatusOK, results)
+}
+
+func (handler *TeacherAPIHandlerImpl) writeExamDetail(w http.ResponseWriter, r *http.Request, statusCode int, exam domain.Exam) {
+	questions, err := handler.TeacherService.GetQAByExamId(r.Context(), exam.Id)
+	if err != nil {
+		slog.Error("error when calling get qa by exam id service", "err", err)
+
+		handler.writeError(w, err)
+		return
+	}
+
+	helper.WriteJSON(w, statusCode, api.ExamDetailResponse{
+		ExamResponse: toAPIExam(exam),
+		Questions:    toAPIQuestions(questions),
+	})
+}
+
+func (handler *TeacherAPIHandlerImpl) writeError(w http.ResponseWriter, err error) {
+	if writeAPIValidationError(w, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, service.ErrExamForbidden):
+		helper.WriteJSONError(w, http.StatusForbidden, "forbidden", "Exam does not exist or your role on it does not allow this")
+	case errors.Is(err, service.ErrQuestionNotFound):
+		helper.WriteJSONError(w, http.StatusNotFound, "question_not_found", "Question does not exist in this exam")
+	case errors.Is(err, service.ErrBankQuestionLinked):
+		helper.WriteJSONError(w, http.StatusConflict, "bank_question", "Question comes from the question bank, change it there")
+	case errors.Is(err, service.ErrQuestionDrawCount):
+		helper.WriteJSONFieldErrors(w, map[string]string{"question_draw_count": "must not be larger than the number of questions"})
+	default:
+		helper.WriteJSONError(w, http.StatusInternalServerError, "internal_error", "Internal Server Error")
+	}
+}
+
+func toAPIExam(exam domain.Exam) api.ExamResponse {
+	return api.ExamResponse{
+		Id:                exam.Id,
+		Name:              exam.RoomName,
+		Year:              exam.Year,
+		DurationMinutes:   exam.Duration,
+		TeacherName:       exam.TeacherName,
+		IsActive:          exam.IsActive,
+		IsPrivate:         exam.IsPrivate,
+		JoinCode:          exam.JoinCode,
+		ShuffleQuestions:  exam.ShuffleQuestions,
+		QuestionDrawCount: exam.QuestionDrawCount,
+		Role:              exam.Role,
+		CreatedAt:         exam.CreatedAt,
+		UpdatedAt:         exam.UpdatedAt,
+	}
+}
+
+func toAPIQuestion(question domain.QAItem) api.QuestionResponse {
+	return api.QuestionResponse{
+		Id:       question.Id,
+		Question: question.Question,
+		Answer:   question.Answer,
+		FromBank: question.BankQuestionId != "",
+	}
+}
+
+func toAPIQuestions(questions []domain.QAItem) []api.QuestionResponse {
+	responses := []api.QuestionResponse{}
+	for _, question := range questions {
+		responses = append(responses, toAPIQuestion(question))
+	}
+
+	return responses
+}
+
+// completedAt is nil for attempts that were not submitted, they keep the zero time in the database
+func completedAt(attempt web.ExamAttempt) *time.Time {
+	if attempt.CompletedAt.IsZero() {
+		return nil
+	}
+
+	return &attempt.CompletedAt
+}
@@ -8,8 +8,17 @@ type TeacherHandler interface {
 	CheckExamView(w http.ResponseWriter, r *http.Request)
 	EditExamView(w http.ResponseWriter, r *http.Request)
 	EditExam(w http.ResponseWriter, r *http.Request)
+	RegenerateQuestion(w http.ResponseWriter, r *http.Request)
+	AcceptRegeneratedQuestion(w http.ResponseWriter, r *http.Request)
 	ExamResultView(w http.ResponseWriter, r *http.Request)
+	RegradeExam(w http.ResponseWriter, r *http.Request)
 	ExamToggleButton(w http.ResponseWriter, r *http.Request)
+	SetExamPrivacy(w http.ResponseWriter, r *http.Request)
+	RegenerateJoinCode(w http.ResponseWriter, r *http.Request)
+	RevokeJoinCode(w http.ResponseWriter, r *http.Request)
+	AddCollaborator(w http.ResponseWriter, r *http.Request)
+	UpdateCollaboratorRole(w http.ResponseWriter, r *http.Request)
+	RemoveCollaborator(w http.ResponseWriter, r *http.Request)
 	GenerateAndCreateExamRoom(w http.ResponseWriter, r *http.Request)
 	GenerateResultView(w http.ResponseWriter, r *http.Request)
 }
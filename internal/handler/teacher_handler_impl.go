@@ -1,22 +1,26 @@
 package handler
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"html/template"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"slices"
 	"strconv"
 
+	"github.com/go-playground/validator/v10"
 	appError "github.com/mhaatha/go-template-saygenfix/internal/errors"
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
 	"github.com/mhaatha/go-template-saygenfix/internal/middleware"
 	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
 	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
 	"github.com/mhaatha/go-template-saygenfix/internal/service"
 )
 
-func NewTeacherHandler(teacherService service.TeacherService, studentService service.StudentService) TeacherHandler {
+func NewTeacherHandler(teacherService service.TeacherService, studentService service.StudentService, materialService service.MaterialService, classService service.ClassService) TeacherHandler {
 	funcMap := template.FuncMap{
 		"add": func(a, b int) int {
 			return a + b
@@ -41,11 +45,16 @@ func NewTeacherHandler(teacherService service.TeacherService, studentService ser
 			hasil := a * b
 			return float64(hasil)
 		},
+		"questionCard": func(index int, question domain.QAItem, role string) web.QuestionCardResponse {
+			return web.QuestionCardResponse{Number: index + 1, Question: question, Role: role}
+		},
 	}
 
 	return &TeacherHandlerImpl{
-		TeacherService: teacherService,
-		StudentService: studentService,
+		TeacherService:  teacherService,
+		StudentService:  studentService,
+		MaterialService: materialService,
+		ClassService:    classService,
 		Template: template.Must(
 			// 1. Mulai dengan membuat template baru. Nama "base" bisa apa saja.
 			template.New("base").
@@ -66,6 +75,13 @@ func NewTeacherHandler(teacherService service.TeacherService, studentService ser
 					"../../internal/templates/views/partial/teacher_check_exam_navbar.html",
 					"../../internal/templates/views/partial/teacher_edit_exam_navbar.html",
 					"../../internal/templates/views/partial/exam_card.html",
+					"../../internal/templates/views/partial/question_edit_card.html",
+					"../../internal/templates/views/partial/question_proposal.html",
+					"../../internal/templates/views/partial/question_bank_status.html",
+					"../../internal/templates/views/partial/exam_join_code.html",
+					"../../internal/templates/views/partial/exam_classes.html",
+					"../../internal/templates/views/partial/exam_collaborators.html",
+					"../../internal/templates/views/partial/duplicate_warnings.html",
 					"../../internal/templates/views/error.html",
 				),
 		),
@@ -73,14 +89,18 @@ func NewTeacherHandler(teacherService service.TeacherService, studentService ser
 }
 
 type TeacherHandlerImpl struct {
-	TeacherService service.TeacherService
-	StudentService service.StudentService
-	Template       *template.Template
+	TeacherService  service.TeacherService
+	StudentService  service.StudentService
+	MaterialService service.MaterialService
+	ClassService    service.ClassService
+	Template        *template.Template
 }
 
 func (handler *TeacherHandlerImpl) TeacherDashboard(w http.ResponseWriter, r *http.Request) {
 	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
-	dashboardResponse, err := handler.TeacherService.TeacherDashboard(r.Context(), user.Id)
+	selectedClassId := r.URL.Query().Get("class")
+
+	dashboardResponse, err := handler.TeacherService.TeacherDashboard(r.Context(), user.Id, selectedClassId)
 	if err != nil {
 		slog.Error("error when calling teacher dashboard service", "err", err)
 
@@ -104,6 +124,29 @@ func (handler *TeacherHandlerImpl) TeacherDashboard(w http.ResponseWriter, r *ht
 
 	dashboardResponse.Years = years
 
+	classes, err := handler.ClassService.GetClassesByTeacherId(r.Context(), user.Id)
+	if err != nil {
+		slog.Error("error when calling get classes by teacher id service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	dashboardResponse.Classes = classes
+	dashboardResponse.SelectedClassId = selectedClassId
+
+	if r.URL.Query().Get("generated") == "cached" {
+		dashboardResponse.FlashMessage = "Soal diambil dari cache karena dokumen dan jumlah soal sama dengan sebelumnya. Centang \"Generate ulang\" saat upload untuk membuat soal baru."
+	}
+
+	// Soal mirip dari ujian yang baru saja dibuat ditampilkan di dashboard
+	if examId := r.URL.Query().Get("duplicates"); examId != "" {
+		dashboardResponse.DuplicateWarnings, err = handler.duplicateWarnings(r.Context(), user.Id, examId)
+		if err != nil {
+			slog.Error("error when loading duplicate warnings", "err", err, "exam_id", examId)
+		}
+	}
+
 	if err := handler.Template.ExecuteTemplate(w, "teacher-dashboard", dashboardResponse); err != nil {
 		slog.Error("error when executing teacher dashboard template", "err", err)
 
@@ -123,7 +166,21 @@ func (handler *TeacherHandlerImpl) UploadView(w http.ResponseWriter, r *http.Req
 		user.Role = "Teacher"
 	}
 
-	if err := handler.Template.ExecuteTemplate(w, "teacher-upload", user); err != nil {
+	materials, err := handler.MaterialService.GetMaterialsByTeacherId(r.Context(), user.Id)
+	if err != nil {
+		slog.Error("error when calling get materials by teacher id service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	uploadResponse := web.TeacherUploadResponse{
+		User:               user,
+		Materials:          materials,
+		SelectedMaterialId: r.URL.Query().Get("material_id"),
+	}
+
+	if err := handler.Template.ExecuteTemplate(w, "teacher-upload", uploadResponse); err != nil {
 		slog.Error("error when executing teacher upload template", "err", err)
 
 		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
@@ -181,33 +238,252 @@ func (handler *TeacherHandlerImpl) GenerateAndCreateExamRoom(w http.ResponseWrit
 		return
 	}
 
-	// 2. Ambil file dari form data menggunakan 'name' dari input field
-	// "myFile" harus sama dengan atribut 'name' pada <input type="file" name="myFile">
-	file, _, err := r.FormFile("pdf_file")
+	// 2. Pakai materi yang sudah tersimpan jika dipilih, jika tidak simpan file yang di-upload ke library
+	var material domain.Material
+	if materialId := r.FormValue("material_id"); materialId != "" {
+		material, err = handler.MaterialService.GetMaterialById(r.Context(), user.Id, materialId)
+		if err != nil {
+			slog.Error("error when calling get material by id service", "err", err)
+
+			appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "materi tidak valid")
+			return
+		}
+	} else {
+		// "pdf_file" harus sama dengan atribut 'name' pada <input type="file" name="pdf_file">
+		file, fileHeader, err := r.FormFile("pdf_file")
+		if err != nil {
+			slog.Error("error when getting file from form data", "err", err)
+
+			appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "file tidak valid")
+			return
+		}
+		defer file.Close() // Jangan lupa untuk selalu menutup file
+
+		material, err = handler.MaterialService.SaveMaterial(r.Context(), user.Id, fileHeader.Filename, file)
+		if err != nil {
+			slog.Error("error when calling save material service", "err", err)
+
+			appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "file tidak valid")
+			return
+		}
+	}
+
+	// Guru bisa melewati cache jika ingin hasil generate yang baru
+	bypassCache := r.FormValue("bypass_cache") == "on"
+
+	examId, cacheHit, err := handler.TeacherService.GenerateQuestionAnswer(r.Context(), material, totalQuestion, examData, user.Id, bypassCache)
 	if err != nil {
-		slog.Error("error when getting file from form data", "err", err)
+		slog.Error("error when calling generate question answer service", "err", err)
 
-		appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "file tidak valid")
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
-	defer file.Close() // Jangan lupa untuk selalu menutup file
 
-	err = handler.TeacherService.GenerateQuestionAnswer(r.Context(), file, totalQuestion, examData, user.Id)
+	duplicates, err := handler.TeacherService.CheckDuplicateQuestions(r.Context(), user.Id, examId)
 	if err != nil {
-		slog.Error("error when calling generate question answer service", "err", err)
+		slog.Error("error when calling check duplicate questions service", "err", err)
 
 		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
 
-	// Redirect HTMX
-	w.Header().Set("HX-Redirect", "/teacher/dashboard")
+	// Redirect HTMX ke dashboard, soal yang mirip ditampilkan di sana bersama pesan cache
+	query := url.Values{}
+	if cacheHit {
+		query.Set("generated", "cached")
+	}
+	if len(duplicates) > 0 {
+		query.Set("duplicates", examId)
+	}
+	redirectURL := "/teacher/dashboard"
+	if len(query) > 0 {
+		redirectURL += "?" + query.Encode()
+	}
+	w.Header().Set("HX-Redirect", redirectURL)
+}
+
+func (handler *TeacherHandlerImpl) SetExamPrivacy(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	isPrivate := r.FormValue("is_private") == "on"
+
+	exam, err := handler.TeacherService.SetExamPrivate(r.Context(), user.Id, r.PathValue("id"), isPrivate)
+	if err != nil {
+		slog.Error("error when calling set exam private service", "err", err)
+
+		handler.renderExamError(w, err)
+		return
+	}
+
+	handler.renderJoinCode(w, exam)
+}
+
+func (handler *TeacherHandlerImpl) RegenerateJoinCode(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	exam, err := handler.TeacherService.RegenerateJoinCode(r.Context(), user.Id, r.PathValue("id"))
+	if err != nil {
+		slog.Error("error when calling regenerate join code service", "err", err)
+
+		handler.renderExamError(w, err)
+		return
+	}
+
+	handler.renderJoinCode(w, exam)
+}
+
+func (handler *TeacherHandlerImpl) RevokeJoinCode(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	exam, err := handler.TeacherService.RevokeJoinCode(r.Context(), user.Id, r.PathValue("id"))
+	if err != nil {
+		slog.Error("error when calling revoke join code service", "err", err)
+
+		handler.renderExamError(w, err)
+		return
+	}
+
+	handler.renderJoinCode(w, exam)
+}
+
+// renderExamError renders 403 when the teacher's role on the exam is too low for the action
+func (handler *TeacherHandlerImpl) renderExamError(w http.ResponseWriter, err error) {
+	if errors.Is(err, service.ErrExamForbidden) {
+		appError.RenderErrorPage(w, handler.Template, http.StatusForbidden, "Anda tidak memiliki akses ke ujian ini")
+		return
+	}
+
+	appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+}
+
+func (handler *TeacherHandlerImpl) AddCollaborator(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	examId := r.PathValue("id")
+
+	request := web.ExamCollaboratorRequest{
+		Email: r.FormValue("email"),
+		Role:  r.FormValue("role"),
+	}
+
+	collaborators, err := handler.TeacherService.AddExamCollaborator(r.Context(), user.Id, examId, request)
+	if err != nil {
+		slog.Error("error when calling add exam collaborator service", "err", err)
+
+		handler.renderCollaboratorsError(w, r, examId, err)
+		return
+	}
+
+	handler.renderCollaborators(w, collaborators)
+}
+
+func (handler *TeacherHandlerImpl) UpdateCollaboratorRole(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	examId := r.PathValue("id")
+
+	request := web.ExamCollaboratorRoleRequest{
+		Role: r.FormValue("role"),
+	}
+
+	collaborators, err := handler.TeacherService.UpdateExamCollaboratorRole(r.Context(), user.Id, examId, r.PathValue("teacherId"), request)
+	if err != nil {
+		slog.Error("error when calling update exam collaborator role service", "err", err)
+
+		handler.renderCollaboratorsError(w, r, examId, err)
+		return
+	}
+
+	handler.renderCollaborators(w, collaborators)
+}
+
+func (handler *TeacherHandlerImpl) RemoveCollaborator(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	examId := r.PathValue("id")
+
+	collaborators, err := handler.TeacherService.RemoveExamCollaborator(r.Context(), user.Id, examId, r.PathValue("teacherId"))
+	if err != nil {
+		slog.Error("error when calling remove exam collaborator service", "err", err)
+
+		handler.renderCollaboratorsError(w, r, examId, err)
+		return
+	}
+
+	// Guru yang keluar dari ujiannya sendiri tidak bisa membuka halaman ujian lagi
+	if user.Id == r.PathValue("teacherId") {
+		w.Header().Set("HX-Redirect", "/teacher/dashboard")
+		return
+	}
+
+	handler.renderCollaborators(w, collaborators)
+}
+
+// renderCollaboratorsError shows the error inside the collaborators section so the owner can correct the form
+func (handler *TeacherHandlerImpl) renderCollaboratorsError(w http.ResponseWriter, r *http.Request, examId string, err error) {
+	if errors.Is(err, service.ErrExamForbidden) {
+		handler.renderExamError(w, err)
+		return
+	}
+
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	collaborators, findErr := handler.TeacherService.GetExamCollaborators(r.Context(), user.Id, examId)
+	if findErr != nil {
+		slog.Error("error when calling get exam collaborators service", "err", findErr)
+
+		handler.renderExamError(w, findErr)
+		return
+	}
+
+	switch {
+	case errors.Is(err, service.ErrCollaboratorNotTeacher):
+		collaborators.ErrorMessage = "Email tersebut tidak terdaftar sebagai akun guru."
+	case errors.Is(err, service.ErrLastExamOwner):
+		collaborators.ErrorMessage = "Ujian harus memiliki setidaknya satu owner."
+	default:
+		collaborators.ErrorMessage = "Kolaborator gagal disimpan. Pastikan email dan role valid."
+	}
+
+	handler.renderCollaborators(w, collaborators)
+}
+
+func (handler *TeacherHandlerImpl) renderCollaborators(w http.ResponseWriter, collaborators web.ExamCollaboratorsResponse) {
+	if err := handler.Template.ExecuteTemplate(w, "exam-collaborators", collaborators); err != nil {
+		slog.Error("error when executing exam-collaborators template", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+}
+
+// duplicateWarnings loads the duplicate warnings of an exam the teacher has edit access to
+func (handler *TeacherHandlerImpl) duplicateWarnings(ctx context.Context, teacherId, examId string) (web.DuplicateWarningsPanel, error) {
+	exam, err := handler.TeacherService.GetExamForTeacher(ctx, teacherId, examId, domain.ExamRoleEditor)
+	if err != nil {
+		return web.DuplicateWarningsPanel{}, err
+	}
+
+	duplicates, err := handler.TeacherService.CheckDuplicateQuestions(ctx, teacherId, examId)
+	if err != nil {
+		return web.DuplicateWarningsPanel{}, err
+	}
+
+	return web.DuplicateWarningsPanel{Exam: exam, Warnings: duplicates}, nil
+}
+
+func (handler *TeacherHandlerImpl) renderJoinCode(w http.ResponseWriter, exam domain.Exam) {
+	if err := handler.Template.ExecuteTemplate(w, "exam-join-code", exam); err != nil {
+		slog.Error("error when executing exam-join-code template", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
 }
 
 func (handler *TeacherHandlerImpl) CheckExamView(w http.ResponseWriter, r *http.Request) {
 	var successMessage string
-	if r.URL.Query().Get("status") == "updated" {
+	switch r.URL.Query().Get("status") {
+	case "updated":
 		successMessage = "Data ujian berhasil diperbarui!"
+	case "regraded":
+		regraded, _ := strconv.Atoi(r.URL.Query().Get("count"))
+		successMessage = strconv.Itoa(regraded) + " attempt siswa berhasil dinilai ulang."
 	}
 
 	roomId := r.PathValue("examId")
@@ -223,16 +499,37 @@ func (handler *TeacherHandlerImpl) CheckExamView(w http.ResponseWriter, r *http.
 		user.Role = "Teacher"
 	}
 
-	exam, err := handler.TeacherService.GetExamById(r.Context(), roomId)
+	exam, err := handler.TeacherService.GetExamForTeacher(r.Context(), user.Id, roomId, domain.ExamRoleViewer)
+	if err != nil {
+		slog.Error("error when calling get exam for teacher service", "err", err)
+
+		handler.renderExamError(w, err)
+		return
+	}
+
+	examClasses, err := handler.ClassService.GetExamClasses(r.Context(), user.Id, roomId)
 	if err != nil {
-		slog.Error("error when calling get exam by id service", "err", err)
+		slog.Error("error when calling get exam classes service", "err", err)
 
 		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
 
-	// Get exam_attempts by examId
-	examAttempts, err := handler.TeacherService.GetBiggestExamAttemptsScoreByExamId(r.Context(), roomId)
+	collaborators, err := handler.TeacherService.GetExamCollaborators(r.Context(), user.Id, roomId)
+	if err != nil {
+		slog.Error("error when calling get exam collaborators service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	// Get exam_attempts by examId, optionally only from members of the selected class.
+	// Nilai siswa hanya ditampilkan untuk grader ke atas.
+	selectedClassId := r.URL.Query().Get("class")
+	examAttempts := []web.ExamAttempt{}
+	if exam.Role != domain.ExamRoleViewer {
+		examAttempts, err = handler.TeacherService.GetBiggestExamAttemptsScoreByExamId(r.Context(), roomId, selectedClassId)
+	}
 	if err != nil {
 		slog.Error("error when calling get biggest exam attempts score by exam id service", "err", err)
 
@@ -264,10 +561,25 @@ func (handler *TeacherHandlerImpl) CheckExamView(w http.ResponseWriter, r *http.
 	}
 
 	examCheckResponse := web.TeacherCheckExamResponse{
-		User:         user,
-		Exam:         exam,
-		FlashMessage: successMessage,
-		ExamAttempts: examAttemptsData,
+		User:            user,
+		Exam:            exam,
+		FlashMessage:    successMessage,
+		ExamAttempts:    examAttemptsData,
+		ExamClasses:     examClasses,
+		Collaborators:   collaborators,
+		SelectedClassId: selectedClassId,
+	}
+
+	// Setelah ujian diedit, soal yang sama atau mirip langsung ditunjukkan
+	if r.URL.Query().Get("status") == "updated" && (exam.Role == domain.ExamRoleOwner || exam.Role == domain.ExamRoleEditor) {
+		duplicates, err := handler.TeacherService.CheckDuplicateQuestions(r.Context(), user.Id, roomId)
+		if err != nil {
+			slog.Error("error when calling check duplicate questions service", "err", err)
+
+			appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		examCheckResponse.DuplicateWarnings = web.DuplicateWarningsPanel{Exam: exam, Warnings: duplicates}
 	}
 
 	if err := handler.Template.ExecuteTemplate(w, "teacher-check-exam", examCheckResponse); err != nil {
@@ -292,11 +604,11 @@ func (handler *TeacherHandlerImpl) EditExamView(w http.ResponseWriter, r *http.R
 		user.Role = "Teacher"
 	}
 
-	exam, err := handler.TeacherService.GetExamById(r.Context(), roomId)
+	exam, err := handler.TeacherService.GetExamForTeacher(r.Context(), user.Id, roomId, domain.ExamRoleEditor)
 	if err != nil {
-		slog.Error("error when calling get exam by id service", "err", err)
+		slog.Error("error when calling get exam for teacher service", "err", err)
 
-		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		handler.renderExamError(w, err)
 		return
 	}
 
@@ -308,10 +620,19 @@ func (handler *TeacherHandlerImpl) EditExamView(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	duplicates, err := handler.TeacherService.CheckDuplicateQuestions(r.Context(), user.Id, roomId)
+	if err != nil {
+		slog.Error("error when calling check duplicate questions service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
 	examEditResponse := web.TeacherEditExamResponse{
 		User:               user,
 		Exam:               exam,
 		QuestionAndAnswers: questionsAndAnswers,
+		DuplicateWarnings:  web.DuplicateWarningsPanel{Exam: exam, Warnings: duplicates, InPage: true},
 	}
 
 	if err := handler.Template.ExecuteTemplate(w, "teacher-edit-exam", examEditResponse); err != nil {
@@ -332,14 +653,11 @@ func (handler *TeacherHandlerImpl) EditExam(w http.ResponseWriter, r *http.Reque
 	}
 
 	examId := r.PathValue("id")
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
 
 	// 2. Ambil data ujian utama
-	roomName := r.FormValue("roomName")
-	yearStr := r.FormValue("year")
-	durationStr := r.FormValue("duration")
-
-	// Lakukan konversi tipe data (string to int) dan validasi di sini
-	yearInt, err := strconv.Atoi(yearStr)
+	// Lakukan konversi tipe data (string to int) di sini, validasi lainnya di service
+	yearInt, err := strconv.Atoi(r.FormValue("year"))
 	if err != nil {
 		slog.Error("error when converting year to int", "err", err)
 
@@ -347,7 +665,7 @@ func (handler *TeacherHandlerImpl) EditExam(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	durationInt, err := strconv.Atoi(durationStr)
+	durationInt, err := strconv.Atoi(r.FormValue("duration"))
 	if err != nil {
 		slog.Error("error when converting duration to int", "err", err)
 
@@ -355,38 +673,178 @@ func (handler *TeacherHandlerImpl) EditExam(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Panggil service untuk memperbarui data ujian
-	if err := handler.TeacherService.UpdateExamById(r.Context(), examId, roomName, yearInt, durationInt); err != nil {
-		slog.Error("error when calling update exam by id service", "err", err)
+	// Pengaturan acak soal per attempt, 0 berarti semua soal dipakai
+	questionDrawCount := 0
+	if drawStr := r.FormValue("question_draw_count"); drawStr != "" {
+		questionDrawCount, err = strconv.Atoi(drawStr)
+		if err != nil {
+			slog.Error("error when converting question draw count to int", "err", err)
+
+			appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "question draw count is not valid")
+			return
+		}
+	}
+
+	request := web.ExamEditRequest{
+		RoomName:          r.FormValue("roomName"),
+		Year:              yearInt,
+		Duration:          durationInt,
+		ShuffleQuestions:  r.FormValue("shuffle_questions") == "on",
+		QuestionDrawCount: questionDrawCount,
+	}
+
+	// 3. Ambil data soal dan jawaban
+	// r.Form["qa_ids"] akan berisi slice dari semua ID soal, contoh: ["id1", "id2", "id3"]
+	for _, id := range r.Form["qa_ids"] {
+		// Bentuk nama field sesuai dengan yang ada di template
+		request.Questions = append(request.Questions, web.QuestionEditRequest{
+			Id:       id,
+			Question: r.FormValue("question_" + id),
+			Answer:   r.FormValue("answer_" + id),
+		})
+	}
+
+	// Service memeriksa role, lalu memvalidasi semuanya sebelum menyimpan dalam satu transaksi
+	if err := handler.TeacherService.EditExam(r.Context(), user.Id, examId, request); err != nil {
+		slog.Error("error when calling edit exam service", "err", err)
+
+		var validationErrors validator.ValidationErrors
+		switch {
+		case errors.Is(err, service.ErrQuestionDrawCount):
+			appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "jumlah soal yang diambil melebihi jumlah soal ujian")
+		case errors.Is(err, service.ErrQuestionNotFound), errors.Is(err, service.ErrBankQuestionLinked):
+			appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "soal tidak ditemukan di ujian ini")
+		case errors.As(err, &validationErrors):
+			appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "data ujian tidak valid, pastikan semua soal dan jawaban terisi")
+		default:
+			handler.renderExamError(w, err)
+		}
+		return
+	}
+
+	// 4. Redirect pengguna kembali setelah selesai, soal yang mirip ditampilkan di halaman tujuan
+	http.Redirect(w, r, "/teacher/check-exam/"+examId+"?status=updated", http.StatusSeeOther)
+}
+
+func (handler *TeacherHandlerImpl) RegenerateQuestion(w http.ResponseWriter, r *http.Request) {
+	examId := r.PathValue("id")
+	questionId := r.PathValue("questionId")
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if _, err := handler.TeacherService.GetExamForTeacher(r.Context(), user.Id, examId, domain.ExamRoleEditor); err != nil {
+		slog.Error("error when calling get exam for teacher service", "err", err, "exam_id", examId, "user_id", user.Id)
+
+		helper.RenderError(w, "Anda tidak memiliki akses ke ujian ini")
+		return
+	}
+
+	number, _ := strconv.Atoi(r.URL.Query().Get("number"))
+
+	original, proposed, err := handler.TeacherService.RegenerateQuestion(r.Context(), examId, questionId)
+	if err != nil {
+		slog.Error("error when calling regenerate question service", "err", err)
+
+		helper.RenderError(w, "Gagal membuat ulang soal, silakan coba lagi")
+		return
+	}
+
+	proposalResponse := web.QuestionProposalResponse{
+		Number:       number,
+		Original:     original,
+		Proposed:     proposed,
+		QuestionDiff: helper.DiffWords(original.Question, proposed.Question),
+		AnswerDiff:   helper.DiffWords(original.Answer, proposed.Answer),
+	}
+
+	if err := handler.Template.ExecuteTemplate(w, "question-proposal", proposalResponse); err != nil {
+		slog.Error("error when executing question-proposal template", "err", err)
 
 		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
+}
 
-	// 3. Ambil dan proses data soal dan jawaban
-	// r.Form["qa_ids"] akan berisi slice dari semua ID soal, contoh: ["id1", "id2", "id3"]
-	qaIDs := r.Form["qa_ids"]
+func (handler *TeacherHandlerImpl) AcceptRegeneratedQuestion(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		slog.Error("error parsing form data", "err", err)
 
-	for _, id := range qaIDs {
-		// Bentuk nama field sesuai dengan yang ada di template
-		questionFieldName := "question_" + id
-		answerFieldName := "answer_" + id
+		helper.RenderError(w, "Data soal tidak valid")
+		return
+	}
+
+	examId := r.PathValue("id")
+	questionId := r.PathValue("questionId")
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	exam, err := handler.TeacherService.GetExamForTeacher(r.Context(), user.Id, examId, domain.ExamRoleEditor)
+	if err != nil {
+		slog.Error("error when calling get exam for teacher service", "err", err, "exam_id", examId, "user_id", user.Id)
 
-		// Ambil nilainya
-		questionText := r.FormValue(questionFieldName)
-		answerText := r.FormValue(answerFieldName)
+		helper.RenderError(w, "Anda tidak memiliki akses ke ujian ini")
+		return
+	}
 
-		// Panggil service Anda untuk mengupdate data soal ini di database.
-		if err := handler.TeacherService.UpdateQuestionById(r.Context(), id, questionText, answerText); err != nil {
-			slog.Error("error when calling update question by id service", "err", err)
+	questionText := r.PostFormValue("proposed_question_" + questionId)
+	answerText := r.PostFormValue("proposed_answer_" + questionId)
+	if questionText == "" || answerText == "" {
+		slog.Error("proposed question or answer is empty")
 
-			appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		helper.RenderError(w, "Soal dan jawaban pengganti tidak boleh kosong")
+		return
+	}
+
+	question, err := handler.TeacherService.UpdateQuestion(r.Context(), user.Id, examId, questionId, web.QuestionSaveRequest{
+		Question: questionText,
+		Answer:   answerText,
+	})
+	if err != nil {
+		slog.Error("error when calling update question service", "err", err, "question_id", questionId)
+
+		if errors.Is(err, service.ErrQuestionNotFound) || errors.Is(err, service.ErrBankQuestionLinked) {
+			helper.RenderError(w, "Soal tidak ditemukan")
 			return
 		}
+		helper.RenderError(w, "Gagal menyimpan soal pengganti")
+		return
 	}
 
-	// 4. Redirect pengguna kembali setelah selesai
-	http.Redirect(w, r, "/teacher/check-exam/"+examId+"?status=updated", http.StatusSeeOther)
+	number, _ := strconv.Atoi(r.URL.Query().Get("number"))
+
+	cardResponse := web.QuestionCardResponse{
+		Number:   number,
+		Question: question,
+		Role:     exam.Role,
+	}
+
+	if err := handler.Template.ExecuteTemplate(w, "question-edit-card", cardResponse); err != nil {
+		slog.Error("error when executing question-edit-card template", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+}
+
+// RegradeExam scores every submitted attempt again, graders and up can do this
+func (handler *TeacherHandlerImpl) RegradeExam(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+	examId := r.PathValue("id")
+
+	if _, err := handler.TeacherService.GetExamForTeacher(r.Context(), user.Id, examId, domain.ExamRoleGrader); err != nil {
+		slog.Error("error when calling get exam for teacher service", "err", err)
+
+		handler.renderExamError(w, err)
+		return
+	}
+
+	regraded, err := handler.StudentService.RegradeExamAttempts(r.Context(), examId)
+	if err != nil {
+		slog.Error("error when calling regrade exam attempts service", "err", err, "regraded", regraded)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusBadGateway, "Penilaian ulang gagal, sebagian attempt mungkin belum dinilai ulang. Silakan coba lagi.")
+		return
+	}
+
+	http.Redirect(w, r, "/teacher/check-exam/"+examId+"?status=regraded&count="+strconv.Itoa(regraded), http.StatusSeeOther)
 }
 
 func (handler *TeacherHandlerImpl) ExamResultView(w http.ResponseWriter, r *http.Request) {
@@ -409,6 +867,13 @@ func (handler *TeacherHandlerImpl) ExamResultView(w http.ResponseWriter, r *http
 		return
 	}
 
+	if _, err := handler.TeacherService.GetExamForTeacher(r.Context(), user.Id, examId, domain.ExamRoleGrader); err != nil {
+		slog.Error("error when calling get exam for teacher service", "err", err)
+
+		handler.renderExamError(w, err)
+		return
+	}
+
 	// Get exam_attempts.score by student_id and exam_id
 	examAttempId, totalScore, err := handler.StudentService.GetBiggestScoreByStudentIdAndExamId(r.Context(), studentId, examId)
 	if err != nil {
@@ -497,7 +962,7 @@ func (handler *TeacherHandlerImpl) ExamToggleButton(w http.ResponseWriter, r *ht
 	if err != nil {
 		slog.Error("error when calling update is active exam by id service", "err", err)
 
-		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		handler.renderExamError(w, err)
 		return
 	}
 
@@ -0,0 +1,11 @@
+package handler
+
+import "net/http"
+
+type TwoFactorHandler interface {
+	TwoFactorView(w http.ResponseWriter, r *http.Request)
+	Setup(w http.ResponseWriter, r *http.Request)
+	Confirm(w http.ResponseWriter, r *http.Request)
+	RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request)
+	Disable(w http.ResponseWriter, r *http.Request)
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	appError "github.com/mhaatha/go-template-saygenfix/internal/errors"
+	"github.com/mhaatha/go-template-saygenfix/internal/middleware"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/service"
+)
+
+func NewTwoFactorHandler(twoFactorService service.TwoFactorService) TwoFactorHandler {
+	return &TwoFactorHandlerImpl{
+		TwoFactorService: twoFactorService,
+		Template: template.Must(template.ParseFiles(
+			"../../internal/templates/views/two_factor.html",
+			"../../internal/templates/views/partial/teacher_navbar.html",
+			"../../internal/templates/views/partial/admin_navbar.html",
+			"../../internal/templates/views/error.html",
+		)),
+	}
+}
+
+type TwoFactorHandlerImpl struct {
+	TwoFactorService service.TwoFactorService
+	Template         *template.Template
+}
+
+func (handler *TwoFactorHandlerImpl) TwoFactorView(w http.ResponseWriter, r *http.Request) {
+	pageResponse := web.TwoFactorPageResponse{}
+	switch r.URL.Query().Get("status") {
+	case "required":
+		pageResponse.ErrorMessage = "Admin mewajibkan verifikasi dua langkah untuk akun guru. Aktifkan terlebih dahulu untuk melanjutkan."
+	case "disabled":
+		pageResponse.FlashMessage = "Verifikasi dua langkah berhasil dinonaktifkan."
+	}
+
+	handler.renderTwoFactor(w, r, http.StatusOK, pageResponse)
+}
+
+func (handler *TwoFactorHandlerImpl) Setup(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if err := handler.TwoFactorService.BeginEnrollment(r.Context(), user); err != nil {
+		slog.Error("error when calling begin two-factor enrollment service", "err", err)
+
+		if errors.Is(err, service.ErrTwoFactorAlreadyEnabled) {
+			http.Redirect(w, r, "/account/two-factor", http.StatusSeeOther)
+			return
+		}
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	http.Redirect(w, r, "/account/two-factor", http.StatusSeeOther)
+}
+
+func (handler *TwoFactorHandlerImpl) Confirm(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	recoveryCodes, err := handler.TwoFactorService.ConfirmEnrollment(r.Context(), user, web.TwoFactorCodeRequest{
+		Code: r.PostFormValue("code"),
+	})
+	if err != nil {
+		slog.Error("error when calling confirm two-factor enrollment service", "err", err)
+
+		handler.renderTwoFactorError(w, r, err)
+		return
+	}
+
+	// Kode cadangan langsung ditampilkan, tidak lewat redirect karena hanya muncul sekali
+	handler.renderTwoFactor(w, r, http.StatusOK, web.TwoFactorPageResponse{
+		RecoveryCodes: recoveryCodes,
+		FlashMessage:  "Verifikasi dua langkah berhasil diaktifkan.",
+	})
+}
+
+func (handler *TwoFactorHandlerImpl) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	recoveryCodes, err := handler.TwoFactorService.RegenerateRecoveryCodes(r.Context(), user, web.TwoFactorCodeRequest{
+		Code: r.PostFormValue("code"),
+	})
+	if err != nil {
+		slog.Error("error when calling regenerate recovery codes service", "err", err)
+
+		handler.renderTwoFactorError(w, r, err)
+		return
+	}
+
+	handler.renderTwoFactor(w, r, http.StatusOK, web.TwoFactorPageResponse{
+		RecoveryCodes: recoveryCodes,
+		FlashMessage:  "Kode cadangan baru berhasil dibuat.",
+	})
+}
+
+func (handler *TwoFactorHandlerImpl) Disable(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	err := handler.TwoFactorService.Disable(r.Context(), user, web.TwoFactorDisableRequest{
+		Password: r.PostFormValue("password"),
+		Code:     r.PostFormValue("code"),
+	})
+	if err != nil {
+		slog.Error("error when calling disable two-factor service", "err", err)
+
+		handler.renderTwoFactorError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/account/two-factor?status=disabled", http.StatusSeeOther)
+}
+
+// renderTwoFactorError shows the page again with a message for errors the user can fix
+func (handler *TwoFactorHandlerImpl) renderTwoFactorError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErrors validator.ValidationErrors
+	pageResponse := web.TwoFactorPageResponse{}
+	statusCode := http.StatusBadRequest
+
+	switch {
+	case errors.As(err, &validationErrors):
+		pageResponse.ErrorMessage = "Lengkapi semua isian terlebih dahulu."
+	case errors.Is(err, service.ErrTwoFactorCodeInvalid):
+		pageResponse.ErrorMessage = "Kode tidak valid atau sudah dipakai. Pastikan jam di ponsel Anda sudah tepat."
+	case errors.Is(err, service.ErrInvalidCredentials):
+		pageResponse.ErrorMessage = "Password salah."
+	case errors.Is(err, service.ErrTwoFactorEnforced):
+		pageResponse.ErrorMessage = "Admin mewajibkan verifikasi dua langkah untuk akun guru."
+		statusCode = http.StatusForbidden
+	case errors.Is(err, service.ErrTwoFactorAlreadyEnabled), errors.Is(err, service.ErrTwoFactorNotEnabled):
+		http.Redirect(w, r, "/account/two-factor", http.StatusSeeOther)
+		return
+	default:
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	handler.renderTwoFactor(w, r, statusCode, pageResponse)
+}
+
+func (handler *TwoFactorHandlerImpl) renderTwoFactor(w http.ResponseWriter, r *http.Request, statusCode int, pageResponse web.TwoFactorPageResponse) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	status, err := handler.TwoFactorService.GetStatus(r.Context(), user)
+	if err != nil {
+		slog.Error("error when calling get two-factor status service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	pageResponse.Status = status
+	switch user.Role {
+	case "teacher":
+		user.Role = "Teacher"
+	case "admin":
+		user.Role = "Admin"
+	}
+	pageResponse.User = user
+
+	w.WriteHeader(statusCode)
+	if err := handler.Template.ExecuteTemplate(w, "two-factor", pageResponse); err != nil {
+		slog.Error("error when executing two-factor template", "err", err)
+		return
+	}
+}
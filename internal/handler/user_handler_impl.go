@@ -11,9 +11,10 @@ import (
 	"github.com/mhaatha/go-template-saygenfix/internal/service"
 )
 
-func NewUserHandler(userService service.UserService) UserHandler {
+func NewUserHandler(userService service.UserService, emailVerificationService service.EmailVerificationService) UserHandler {
 	return &UserHandlerImpl{
-		UserService: userService,
+		UserService:              userService,
+		EmailVerificationService: emailVerificationService,
 		Template: template.Must(template.ParseFiles(
 			"../../internal/templates/views/register.html",
 			"../../internal/templates/views/success_register.html",
@@ -22,8 +23,9 @@ func NewUserHandler(userService service.UserService) UserHandler {
 }
 
 type UserHandlerImpl struct {
-	UserService service.UserService
-	Template    *template.Template
+	UserService              service.UserService
+	EmailVerificationService service.EmailVerificationService
+	Template                 *template.Template
 }
 
 func (handler *UserHandlerImpl) RegisterAction(w http.ResponseWriter, r *http.Request) {
@@ -54,7 +56,7 @@ func (handler *UserHandlerImpl) RegisterAction(w http.ResponseWriter, r *http.Re
 	}
 
 	// Call service
-	err := handler.UserService.RegisterNewUser(r.Context(), userRequest)
+	user, err := handler.UserService.RegisterNewUser(r.Context(), userRequest)
 	if err != nil {
 		slog.Error("failed to register new user", "err", err)
 
@@ -62,8 +64,17 @@ func (handler *UserHandlerImpl) RegisterAction(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	// Akun tetap dibuat walau email gagal dikirim, link bisa diminta ulang
+	if err := handler.EmailVerificationService.SendVerification(r.Context(), user); err != nil {
+		slog.Error("failed to send verification email", "err", err)
+	}
+
+	registerResponse := web.RegisterSuccessResponse{
+		IsPendingApproval: !user.IsApproved,
+	}
+
 	w.WriteHeader(http.StatusCreated)
-	if err := handler.Template.ExecuteTemplate(w, "success-register", nil); err != nil {
+	if err := handler.Template.ExecuteTemplate(w, "success-register", registerResponse); err != nil {
 		slog.Error("failed to execute success-register template", "err", err)
 
 		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
@@ -72,7 +83,13 @@ func (handler *UserHandlerImpl) RegisterAction(w http.ResponseWriter, r *http.Re
 }
 
 func (handler *UserHandlerImpl) RegisterView(w http.ResponseWriter, r *http.Request) {
-	if err := handler.Template.ExecuteTemplate(w, "register", nil); err != nil {
+	registerForm := web.RegisterFormResponse{
+		Email:    r.URL.Query().Get("email"),
+		FullName: r.URL.Query().Get("full_name"),
+		Role:     r.URL.Query().Get("role"),
+	}
+
+	if err := handler.Template.ExecuteTemplate(w, "register", registerForm); err != nil {
 		slog.Error("failed to execute register template", "err", err)
 
 		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
@@ -0,0 +1,8 @@
+package handler
+
+import "net/http"
+
+type UserImportHandler interface {
+	ImportView(w http.ResponseWriter, r *http.Request)
+	Import(w http.ResponseWriter, r *http.Request)
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+
+	appError "github.com/mhaatha/go-template-saygenfix/internal/errors"
+	"github.com/mhaatha/go-template-saygenfix/internal/middleware"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/service"
+)
+
+// maxImportFileSize is the largest CSV file accepted by the import form
+const maxImportFileSize = 1 << 20
+
+func NewUserImportHandler(userImportService service.UserImportService) UserImportHandler {
+	return &UserImportHandlerImpl{
+		UserImportService: userImportService,
+		Template: template.Must(template.ParseFiles(
+			"../../internal/templates/views/user_import.html",
+			"../../internal/templates/views/partial/teacher_navbar.html",
+			"../../internal/templates/views/partial/admin_navbar.html",
+			"../../internal/templates/views/error.html",
+		)),
+	}
+}
+
+type UserImportHandlerImpl struct {
+	UserImportService service.UserImportService
+	Template          *template.Template
+}
+
+func (handler *UserImportHandlerImpl) ImportView(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	mode := service.UserImportModePassword
+	if user.Role == "teacher" {
+		mode = service.UserImportModeInvite
+	}
+
+	handler.renderImport(w, r, http.StatusOK, web.UserImportResponse{
+		Mode: mode,
+	})
+}
+
+func (handler *UserImportHandlerImpl) Import(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if err := r.ParseMultipartForm(maxImportFileSize); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		slog.Error("error when parsing multipart form", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "file tidak valid")
+		return
+	}
+
+	importResponse := web.UserImportResponse{
+		Mode:       r.FormValue("mode"),
+		DryRun:     r.FormValue("action") != "import",
+		CSVContent: r.FormValue("csv_content"),
+	}
+	if importResponse.Mode != service.UserImportModeInvite {
+		importResponse.Mode = service.UserImportModePassword
+	}
+	// Guru hanya bisa mengundang siswa
+	if user.Role == "teacher" {
+		importResponse.Mode = service.UserImportModeInvite
+	}
+
+	// File yang diunggah menggantikan isi CSV dari pratinjau sebelumnya
+	file, _, err := r.FormFile("csv_file")
+	if err == nil {
+		defer file.Close()
+
+		content, err := io.ReadAll(io.LimitReader(file, maxImportFileSize+1))
+		if err != nil || len(content) > maxImportFileSize {
+			importResponse.ErrorMessage = "File CSV tidak bisa dibaca atau lebih dari 1 MB."
+			handler.renderImport(w, r, http.StatusBadRequest, importResponse)
+			return
+		}
+		importResponse.CSVContent = string(content)
+	}
+
+	results, err := handler.UserImportService.Import(r.Context(), user, importResponse.CSVContent, importResponse.Mode, importResponse.DryRun)
+	if err != nil {
+		slog.Error("error when calling import users service", "err", err)
+
+		switch {
+		case errors.Is(err, service.ErrImportEmpty):
+			importResponse.ErrorMessage = "File CSV tidak berisi data pengguna."
+		case errors.Is(err, service.ErrImportTooManyRows):
+			importResponse.ErrorMessage = "File CSV berisi lebih dari 1000 baris, pecah menjadi beberapa file."
+		default:
+			importResponse.ErrorMessage = "File CSV tidak valid, pastikan formatnya email, nama lengkap, role, kelas."
+		}
+		handler.renderImport(w, r, http.StatusBadRequest, importResponse)
+		return
+	}
+
+	importResponse.Results = results
+	for _, result := range results {
+		if result.Status == service.UserImportStatusError {
+			importResponse.TotalFailed++
+		} else if result.Status != service.UserImportStatusSkipped {
+			importResponse.TotalOk++
+		}
+	}
+
+	// Setelah impor selesai CSV tidak perlu dikirim ulang
+	if !importResponse.DryRun {
+		importResponse.CSVContent = ""
+	}
+
+	handler.renderImport(w, r, http.StatusOK, importResponse)
+}
+
+func (handler *UserImportHandlerImpl) renderImport(w http.ResponseWriter, r *http.Request, statusCode int, importResponse web.UserImportResponse) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	classes, err := handler.UserImportService.GetImporterClasses(r.Context(), user)
+	if err != nil {
+		slog.Error("error when calling get importer classes service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	importResponse.ActionPath = "/" + user.Role + "/import"
+	importResponse.Classes = classes
+	switch user.Role {
+	case "teacher":
+		user.Role = "Teacher"
+	case "admin":
+		user.Role = "Admin"
+	}
+	importResponse.User = user
+
+	w.WriteHeader(statusCode)
+	if err := handler.Template.ExecuteTemplate(w, "user-import", importResponse); err != nil {
+		slog.Error("error when executing user-import template", "err", err)
+		return
+	}
+}
@@ -0,0 +1,13 @@
+package handler
+
+import "net/http"
+
+type WebhookHandler interface {
+	WebhooksView(w http.ResponseWriter, r *http.Request)
+	CreateEndpoint(w http.ResponseWriter, r *http.Request)
+	ToggleEndpoint(w http.ResponseWriter, r *http.Request)
+	RotateSecret(w http.ResponseWriter, r *http.Request)
+	PingEndpoint(w http.ResponseWriter, r *http.Request)
+	DeleteEndpoint(w http.ResponseWriter, r *http.Request)
+	Redeliver(w http.ResponseWriter, r *http.Request)
+}
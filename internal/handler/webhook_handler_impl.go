@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	appError "github.com/mhaatha/go-template-saygenfix/internal/errors"
+	"github.com/mhaatha/go-template-saygenfix/internal/middleware"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/service"
+)
+
+// webhookFieldMessages are the inline messages for validation errors of the create form
+var webhookFieldMessages = map[string]string{
+	"URL":    "Masukkan URL http atau https yang valid, maksimal 2048 karakter.",
+	"Events": "Pilih minimal satu event.",
+}
+
+// webhookStatusMessages are the flash messages shown after a redirect back to the page
+var webhookStatusMessages = map[string]string{
+	"paused":      "Endpoint dijeda. Event baru tetap diantrikan dan dikirim setelah endpoint diaktifkan lagi.",
+	"resumed":     "Endpoint diaktifkan kembali.",
+	"ping":        "Event percobaan diantrikan dan akan segera dikirim.",
+	"deleted":     "Endpoint dan riwayat pengirimannya berhasil dihapus.",
+	"redelivered": "Pengiriman diantrikan ulang dengan payload yang sama.",
+}
+
+func NewWebhookHandler(webhookService service.WebhookService) WebhookHandler {
+	return &WebhookHandlerImpl{
+		WebhookService: webhookService,
+		Template: template.Must(template.ParseFiles(
+			"../../internal/templates/views/webhooks.html",
+			"../../internal/templates/views/partial/teacher_navbar.html",
+			"../../internal/templates/views/partial/admin_navbar.html",
+			"../../internal/templates/views/error.html",
+		)),
+	}
+}
+
+type WebhookHandlerImpl struct {
+	WebhookService service.WebhookService
+	Template       *template.Template
+}
+
+func (handler *WebhookHandlerImpl) WebhooksView(w http.ResponseWriter, r *http.Request) {
+	pageResponse := web.WebhookPageResponse{
+		FlashMessage: webhookStatusMessages[r.URL.Query().Get("status")],
+	}
+
+	handler.renderWebhooks(w, r, http.StatusOK, pageResponse)
+}
+
+func (handler *WebhookHandlerImpl) CreateEndpoint(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if err := r.ParseForm(); err != nil {
+		slog.Error("failed to parse form", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusBadRequest, "Bad Request")
+		return
+	}
+
+	endpoint, err := handler.WebhookService.CreateEndpoint(r.Context(), user, web.WebhookEndpointCreateRequest{
+		URL:    r.PostFormValue("url"),
+		Events: r.PostForm["events"],
+	})
+	if err != nil {
+		slog.Error("error when calling create webhook endpoint service", "err", err)
+
+		var validationErrors validator.ValidationErrors
+		pageResponse := web.WebhookPageResponse{FieldErrors: map[string]string{}}
+		statusCode := http.StatusBadRequest
+
+		switch {
+		case errors.As(err, &validationErrors):
+			for _, fieldError := range validationErrors {
+				if message, ok := webhookFieldMessages[fieldError.StructField()]; ok {
+					pageResponse.FieldErrors[fieldError.StructField()] = message
+				}
+			}
+		case errors.Is(err, service.ErrWebhookEndpointLimit):
+			pageResponse.ErrorMessage = "Jumlah endpoint sudah mencapai batas. Hapus endpoint yang tidak dipakai terlebih dahulu."
+			statusCode = http.StatusConflict
+		default:
+			appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		handler.renderWebhooks(w, r, statusCode, pageResponse)
+		return
+	}
+
+	// Secret langsung ditampilkan, tidak lewat redirect karena hanya muncul sekali
+	handler.renderWebhooks(w, r, http.StatusCreated, web.WebhookPageResponse{
+		NewSecret:    endpoint.Secret,
+		FlashMessage: "Endpoint berhasil ditambahkan. Simpan secret di bawah untuk memverifikasi tanda tangan setiap pengiriman.",
+	})
+}
+
+func (handler *WebhookHandlerImpl) ToggleEndpoint(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	isActive := r.PostFormValue("is_active") == "true"
+	if err := handler.WebhookService.SetEndpointActive(r.Context(), user.Id, r.PathValue("id"), isActive); err != nil {
+		handler.renderEndpointError(w, r, "toggle webhook endpoint", err)
+		return
+	}
+
+	status := "paused"
+	if isActive {
+		status = "resumed"
+	}
+	http.Redirect(w, r, "/account/webhooks?status="+status, http.StatusSeeOther)
+}
+
+func (handler *WebhookHandlerImpl) RotateSecret(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	secret, err := handler.WebhookService.RotateSecret(r.Context(), user.Id, r.PathValue("id"))
+	if err != nil {
+		handler.renderEndpointError(w, r, "rotate webhook secret", err)
+		return
+	}
+
+	handler.renderWebhooks(w, r, http.StatusOK, web.WebhookPageResponse{
+		NewSecret:    secret,
+		FlashMessage: "Secret berhasil diganti. Pengiriman berikutnya ditandatangani dengan secret baru di bawah.",
+	})
+}
+
+func (handler *WebhookHandlerImpl) PingEndpoint(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if err := handler.WebhookService.SendPing(r.Context(), user.Id, r.PathValue("id")); err != nil {
+		handler.renderEndpointError(w, r, "send webhook ping", err)
+		return
+	}
+
+	http.Redirect(w, r, "/account/webhooks?status=ping", http.StatusSeeOther)
+}
+
+func (handler *WebhookHandlerImpl) DeleteEndpoint(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if err := handler.WebhookService.DeleteEndpoint(r.Context(), user.Id, r.PathValue("id")); err != nil {
+		handler.renderEndpointError(w, r, "delete webhook endpoint", err)
+		return
+	}
+
+	http.Redirect(w, r, "/account/webhooks?status=deleted", http.StatusSeeOther)
+}
+
+func (handler *WebhookHandlerImpl) Redeliver(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	if err := handler.WebhookService.Redeliver(r.Context(), user.Id, r.PathValue("id")); err != nil {
+		slog.Error("error when calling redeliver webhook service", "err", err)
+
+		if errors.Is(err, service.ErrWebhookDeliveryNotFound) {
+			handler.renderWebhooks(w, r, http.StatusNotFound, web.WebhookPageResponse{
+				ErrorMessage: "Pengiriman tidak ditemukan atau masih dalam antrian.",
+			})
+			return
+		}
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	http.Redirect(w, r, "/account/webhooks?status=redelivered", http.StatusSeeOther)
+}
+
+// renderEndpointError shows the page again when the endpoint is not found, any other error is a 500
+func (handler *WebhookHandlerImpl) renderEndpointError(w http.ResponseWriter, r *http.Request, action string, err error) {
+	slog.Error("error when calling "+action+" service", "err", err)
+
+	if errors.Is(err, service.ErrWebhookEndpointNotFound) {
+		handler.renderWebhooks(w, r, http.StatusNotFound, web.WebhookPageResponse{
+			ErrorMessage: "Endpoint tidak ditemukan.",
+		})
+		return
+	}
+
+	appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+}
+
+func (handler *WebhookHandlerImpl) renderWebhooks(w http.ResponseWriter, r *http.Request, statusCode int, pageResponse web.WebhookPageResponse) {
+	user := r.Context().Value(middleware.CurrentUserKey).(domain.User)
+
+	endpoints, err := handler.WebhookService.ListEndpoints(r.Context(), user.Id)
+	if err != nil {
+		slog.Error("error when calling list webhook endpoints service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	deliveries, err := handler.WebhookService.ListDeliveries(r.Context(), user.Id)
+	if err != nil {
+		slog.Error("error when calling list webhook deliveries service", "err", err)
+
+		appError.RenderErrorPage(w, handler.Template, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	pageResponse.Endpoints = endpoints
+	pageResponse.Deliveries = deliveries
+	pageResponse.EventOptions = handler.WebhookService.EventOptions()
+	switch user.Role {
+	case "teacher":
+		user.Role = "Teacher"
+	case "admin":
+		user.Role = "Admin"
+	}
+	pageResponse.User = user
+
+	w.WriteHeader(statusCode)
+	if err := handler.Template.ExecuteTemplate(w, "webhooks", pageResponse); err != nil {
+		slog.Error("error when executing webhooks template", "err", err)
+		return
+	}
+}
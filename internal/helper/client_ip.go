@@ -0,0 +1,35 @@
+package helper
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIP returns the IP of the client. X-Forwarded-For is only read when the app runs behind
+// trustedProxyHops reverse proxies. Every proxy appends the address it received the request from,
+// so the client is trustedProxyHops entries from the right. Entries further left are sent by the
+// client itself and can be anything.
+func ClientIP(r *http.Request, trustedProxyHops int) string {
+	if trustedProxyHops > 0 {
+		entries := []string{}
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			entries = append(entries, strings.Split(header, ",")...)
+		}
+
+		if len(entries) > 0 {
+			// Lebih sedikit entri dari jumlah proxy berarti entri paling kiri ditambahkan proxy pertama
+			index := max(len(entries)-trustedProxyHops, 0)
+			if clientIP := strings.TrimSpace(entries[index]); net.ParseIP(clientIP) != nil {
+				return clientIP
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,73 @@
+package helper
+
+import "strings"
+
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+type DiffSegment struct {
+	Op   string
+	Text string
+}
+
+// DiffWords returns the word level difference between oldText and newText
+func DiffWords(oldText, newText string) []DiffSegment {
+	a := strings.Fields(oldText)
+	b := strings.Fields(newText)
+
+	// lcs[i][j] holds the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	segments := []DiffSegment{}
+	appendWord := func(op, word string) {
+		if n := len(segments); n > 0 && segments[n-1].Op == op {
+			segments[n-1].Text += " " + word
+			return
+		}
+		segments = append(segments, DiffSegment{Op: op, Text: word})
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			appendWord(DiffEqual, a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			appendWord(DiffDelete, a[i])
+			i++
+		default:
+			appendWord(DiffInsert, b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		appendWord(DiffDelete, a[i])
+	}
+	for ; j < len(b); j++ {
+		appendWord(DiffInsert, b[j])
+	}
+
+	return segments
+}
+
+// NormalizeText lowercases text and collapses whitespace so two texts can be compared loosely
+func NormalizeText(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
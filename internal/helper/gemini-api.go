@@ -2,10 +2,12 @@ package helper
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
-	"mime/multipart"
 	"strings"
 
 	"github.com/google/generative-ai-go/genai"
@@ -13,6 +15,18 @@ import (
 	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
 )
 
+// GeminiModel is the model used for every generation request
+const GeminiModel = "gemini-2.5-pro"
+
+// QAPromptTemplate asks for a number of essay questions with their answers as a JSON array
+const QAPromptTemplate = `Berdasarkan dokumen PDF ini, buat %d soal esai beserta jawabannya. Buat jawabannya singkat namun cocok untuk koreksi essay, untuk soal dan jawabannya mengikuti isi dari dokumen PDF tersebut, teruntuk referensi soal dan jawaban diambil dari dokumen PDF, kemudian untuk kombinasi soal dan jawabannya menggunakan model Sentence-BERT. Format respons Anda WAJIB sebagai JSON array. Contoh: [{"question": "Apa itu...", "answer": "Jawabannya adalah..."}, {"question": "Siapa...", "answer": "Dia adalah..."}] Jangan tambahkan format markdown atau teks lain di luar JSON tersebut. Gunakan plaintext tanpa format markdown dalam tiap value question dan answer.`
+
+// GenerationCacheKey identifies a generation result by the document, prompt, question count and model
+func GenerationCacheKey(contentHash, promptTemplate string, totalQuestion int, model string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%d\x00%s", contentHash, promptTemplate, totalQuestion, model)))
+	return hex.EncodeToString(sum[:])
+}
+
 func BuildResponse(resp *genai.GenerateContentResponse) string {
 	var rawResponse string
 
@@ -29,7 +43,7 @@ func BuildResponse(resp *genai.GenerateContentResponse) string {
 	return rawResponse
 }
 
-func UploadPDF(ctx context.Context, client *genai.Client, file multipart.File) (string, error) {
+func UploadPDF(ctx context.Context, client *genai.Client, file io.Reader) (string, error) {
 	// Generate random file name
 	fileName := uuid.NewString() + ".pdf"
 
@@ -49,13 +63,13 @@ func UploadPDF(ctx context.Context, client *genai.Client, file multipart.File) (
 }
 
 func GenerateQAFromPDF(ctx context.Context, client *genai.Client, fileURL string, totalQuestion int) ([]domain.QAItem, error) {
-	model := client.GenerativeModel("gemini-2.5-pro")
+	model := client.GenerativeModel(GeminiModel)
 	prompt := []genai.Part{
 		genai.FileData{
 			URI:      fileURL,
 			MIMEType: "application/pdf",
 		},
-		genai.Text(fmt.Sprintf(`Berdasarkan dokumen PDF ini, buat %d soal esai beserta jawabannya. Buat jawabannya singkat namun cocok untuk koreksi essay, untuk soal dan jawabannya mengikuti isi dari dokumen PDF tersebut, teruntuk referensi soal dan jawaban diambil dari dokumen PDF, kemudian untuk kombinasi soal dan jawabannya menggunakan model Sentence-BERT. Format respons Anda WAJIB sebagai JSON array. Contoh: [{"question": "Apa itu...", "answer": "Jawabannya adalah..."}, {"question": "Siapa...", "answer": "Dia adalah..."}] Jangan tambahkan format markdown atau teks lain di luar JSON tersebut. Gunakan plaintext tanpa format markdown dalam tiap value question dan answer.`, totalQuestion)),
+		genai.Text(fmt.Sprintf(QAPromptTemplate, totalQuestion)),
 	}
 
 	resp, err := model.GenerateContent(ctx, prompt...)
@@ -64,12 +78,7 @@ func GenerateQAFromPDF(ctx context.Context, client *genai.Client, fileURL string
 		return nil, err
 	}
 
-	rawResponse := BuildResponse(resp)
-
-	// Clean the response
-	cleanResponse := strings.TrimSpace(rawResponse)
-	cleanResponse = strings.TrimPrefix(cleanResponse, "```json")
-	cleanResponse = strings.TrimSuffix(cleanResponse, "```")
+	cleanResponse := CleanJSONResponse(BuildResponse(resp))
 
 	var qaList []domain.QAItem
 	err = json.Unmarshal([]byte(cleanResponse), &qaList)
@@ -80,3 +89,49 @@ func GenerateQAFromPDF(ctx context.Context, client *genai.Client, fileURL string
 
 	return qaList, nil
 }
+
+// GenerateReplacementQA asks the model for a single question that replaces target.
+// The questions in existing are listed in the prompt so the model avoids repeating them.
+func GenerateReplacementQA(ctx context.Context, client *genai.Client, fileURL string, target domain.QAItem, existing []domain.QAItem) (domain.QAItem, error) {
+	var existingList strings.Builder
+	for i, item := range existing {
+		fmt.Fprintf(&existingList, "%d. %s\n", i+1, item.Question)
+	}
+
+	model := client.GenerativeModel(GeminiModel)
+	prompt := []genai.Part{
+		genai.FileData{
+			URI:      fileURL,
+			MIMEType: "application/pdf",
+		},
+		genai.Text(fmt.Sprintf(`Berdasarkan dokumen PDF ini, buat 1 soal esai pengganti beserta jawabannya untuk menggantikan soal berikut: "%s". Soal baru harus membahas materi dari dokumen PDF tersebut dan TIDAK BOLEH sama atau merupakan parafrase dari soal yang diganti maupun soal-soal berikut:
+%s
+Buat jawabannya singkat namun cocok untuk koreksi essay. Format respons Anda WAJIB sebagai satu objek JSON. Contoh: {"question": "Apa itu...", "answer": "Jawabannya adalah..."} Jangan tambahkan format markdown atau teks lain di luar JSON tersebut. Gunakan plaintext tanpa format markdown dalam tiap value question dan answer.`, target.Question, existingList.String())),
+	}
+
+	resp, err := model.GenerateContent(ctx, prompt...)
+	if err != nil {
+		slog.Error("error producing content", "err", err)
+		return domain.QAItem{}, err
+	}
+
+	cleanResponse := CleanJSONResponse(BuildResponse(resp))
+
+	var qa domain.QAItem
+	err = json.Unmarshal([]byte(cleanResponse), &qa)
+	if err != nil {
+		slog.Error("error when unmarshaling the cleanResponse", "err", err)
+		return domain.QAItem{}, err
+	}
+
+	return qa, nil
+}
+
+// CleanJSONResponse strips whitespace and markdown code fences around a model response
+func CleanJSONResponse(rawResponse string) string {
+	cleanResponse := strings.TrimSpace(rawResponse)
+	cleanResponse = strings.TrimPrefix(cleanResponse, "```json")
+	cleanResponse = strings.TrimPrefix(cleanResponse, "```")
+	cleanResponse = strings.TrimSuffix(cleanResponse, "```")
+	return strings.TrimSpace(cleanResponse)
+}
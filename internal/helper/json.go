@@ -0,0 +1,58 @@
+package helper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/model/api"
+)
+
+// maxJSONBodySize limits API request bodies, the largest one is an exam with all its questions
+const maxJSONBodySize = 1 << 20
+
+// ReadJSON decodes the request body into v. Unknown fields are rejected so typos do not go unnoticed.
+func ReadJSON(r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxJSONBodySize))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("failed to decode json body: %w", err)
+	}
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return errors.New("json body must contain a single object")
+	}
+
+	return nil
+}
+
+// WriteJSON writes data as the body of a successful API response
+func WriteJSON(w http.ResponseWriter, statusCode int, data any) {
+	writeJSONBody(w, statusCode, api.Response{Data: data})
+}
+
+// WriteJSONError writes the error body used by every API route
+func WriteJSONError(w http.ResponseWriter, statusCode int, code, message string) {
+	writeJSONBody(w, statusCode, api.ErrorResponse{Error: api.Error{Code: code, Message: message}})
+}
+
+// WriteJSONFieldErrors is WriteJSONError for a request with invalid fields
+func WriteJSONFieldErrors(w http.ResponseWriter, fields map[string]string) {
+	writeJSONBody(w, http.StatusUnprocessableEntity, api.ErrorResponse{Error: api.Error{
+		Code:    "validation_failed",
+		Message: "Some fields are invalid",
+		Fields:  fields,
+	}})
+}
+
+func writeJSONBody(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Error("failed to write json body", "err", err)
+	}
+}
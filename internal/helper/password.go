@@ -1,6 +1,14 @@
 package helper
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const temporaryPasswordLength = 10
 
 func CheckPasswordConfirmation(password string, confirmPassword string) bool {
 	return password == confirmPassword
@@ -19,3 +27,19 @@ func CheckPasswordHash(hashedPassword, inputPassword string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(inputPassword))
 	return err == nil
 }
+
+// GenerateTemporaryPassword creates a random password for accounts created on behalf of the user.
+// Characters that are easy to confuse, such as 0/O and 1/l, are left out.
+func GenerateTemporaryPassword() (string, error) {
+	const charset = "abcdefghjkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	b := make([]byte, temporaryPasswordLength)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", fmt.Errorf("could not generate random bytes: %w", err)
+	}
+
+	for i := range b {
+		b[i] = charset[int(b[i])%len(charset)]
+	}
+
+	return string(b), nil
+}
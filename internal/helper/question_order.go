@@ -0,0 +1,40 @@
+package helper
+
+import (
+	"math/rand/v2"
+	"slices"
+)
+
+// AttemptQuestionIds picks the questions of one exam attempt. The same seed always gives
+// the same list, so an attempt can be rebuilt from its stored seed. drawCount 0 keeps every question.
+// Without shuffle the drawn questions keep the order of questionIds.
+func AttemptQuestionIds(questionIds []string, seed int64, shuffle bool, drawCount int) []string {
+	if !shuffle && (drawCount <= 0 || drawCount >= len(questionIds)) {
+		return slices.Clone(questionIds)
+	}
+
+	// Urutkan dulu agar hasil acak hanya bergantung pada seed, bukan urutan dari database
+	sorted := slices.Clone(questionIds)
+	slices.Sort(sorted)
+
+	random := rand.New(rand.NewPCG(uint64(seed), uint64(seed)>>32))
+	random.Shuffle(len(sorted), func(i, j int) {
+		sorted[i], sorted[j] = sorted[j], sorted[i]
+	})
+
+	if drawCount > 0 && drawCount < len(sorted) {
+		sorted = sorted[:drawCount]
+	}
+
+	if !shuffle {
+		drawn := []string{}
+		for _, id := range questionIds {
+			if slices.Contains(sorted, id) {
+				drawn = append(drawn, id)
+			}
+		}
+		return drawn
+	}
+
+	return sorted
+}
@@ -0,0 +1,59 @@
+package helper
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrSignedTokenInvalid is returned for tokens with a bad signature, a bad format or an expired time
+var ErrSignedTokenInvalid = errors.New("signed token is invalid")
+
+// SignToken returns "<payload>.<expiry>.<signature>" encoded for urls. The payload is readable
+// by anyone holding the token, so it must not contain secrets.
+func SignToken(secret []byte, payload string, expiresAt time.Time) string {
+	body := base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+
+	return body + "." + base64.RawURLEncoding.EncodeToString(tokenSignature(secret, body))
+}
+
+// VerifySignedToken checks the signature and expiry, then returns the payload
+func VerifySignedToken(secret []byte, token string) (string, error) {
+	lastDot := strings.LastIndex(token, ".")
+	if lastDot < 0 {
+		return "", ErrSignedTokenInvalid
+	}
+	body, signature := token[:lastDot], token[lastDot+1:]
+
+	decodedSignature, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(decodedSignature, tokenSignature(secret, body)) {
+		return "", ErrSignedTokenInvalid
+	}
+
+	encodedPayload, expiry, found := strings.Cut(body, ".")
+	if !found {
+		return "", ErrSignedTokenInvalid
+	}
+
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return "", ErrSignedTokenInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return "", ErrSignedTokenInvalid
+	}
+
+	return string(payload), nil
+}
+
+func tokenSignature(secret []byte, body string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,54 @@
+package helper
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ShingleSize is the number of consecutive words that make up one shingle
+const ShingleSize = 3
+
+// Shingles splits text into a set of overlapping word n-grams after normalizing it.
+// Texts shorter than size produce a single shingle containing every word.
+func Shingles(text string, size int) map[string]struct{} {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	shingles := map[string]struct{}{}
+	if len(words) == 0 {
+		return shingles
+	}
+	if len(words) < size {
+		shingles[strings.Join(words, " ")] = struct{}{}
+		return shingles
+	}
+
+	for i := 0; i+size <= len(words); i++ {
+		shingles[strings.Join(words[i:i+size], " ")] = struct{}{}
+	}
+
+	return shingles
+}
+
+// JaccardSimilarity returns |a ∩ b| / |a ∪ b|, two empty sets are not considered similar
+func JaccardSimilarity(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	intersection := 0
+	for shingle := range a {
+		if _, ok := b[shingle]; ok {
+			intersection++
+		}
+	}
+	union := len(a) + len(b) - intersection
+
+	return float64(intersection) / float64(union)
+}
+
+// TextSimilarity compares two texts using word shingles of ShingleSize
+func TextSimilarity(a, b string) float64 {
+	return JaccardSimilarity(Shingles(a, ShingleSize), Shingles(b, ShingleSize))
+}
@@ -0,0 +1,91 @@
+package helper
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 seed of RFC 6238 appendix B, "12345678901234567890" in base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// TestValidateTOTPVectors checks the SHA1 test vectors of RFC 6238 appendix B. The RFC lists
+// 8 digit codes, a 6 digit code is the last 6 digits of the same value.
+func TestValidateTOTPVectors(t *testing.T) {
+	tests := []struct {
+		unix        int64
+		code        string
+		wantCounter int64
+	}{
+		{59, "287082", 1},
+		{1111111109, "081804", 37037036},
+		{1111111111, "050471", 37037037},
+		{1234567890, "005924", 41152263},
+		{2000000000, "279037", 66666666},
+		{20000000000, "353130", 666666666},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			counter, ok := ValidateTOTP(rfc6238Secret, tt.code, time.Unix(tt.unix, 0))
+			if !ok || counter != tt.wantCounter {
+				t.Fatalf("ValidateTOTP = %d, %v, want %d, true", counter, ok, tt.wantCounter)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	// Kode dari vektor T = 1111111111, langkah waktu 37037037
+	const code = "050471"
+	stepStart := time.Unix(37037037*30, 0)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"same step", stepStart.Add(29 * time.Second), true},
+		{"one step later", stepStart.Add(30 * time.Second), true},
+		{"one step earlier", stepStart.Add(-time.Second), true},
+		{"two steps later", stepStart.Add(60 * time.Second), false},
+		{"two steps earlier", stepStart.Add(-31 * time.Second), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter, ok := ValidateTOTP(rfc6238Secret, code, tt.now)
+			if ok != tt.want {
+				t.Fatalf("ValidateTOTP ok = %v, want %v", ok, tt.want)
+			}
+			// Kode di luar langkah saat ini tetap melaporkan langkah miliknya untuk cek replay
+			if ok && counter != 37037037 {
+				t.Fatalf("counter = %d, want 37037037", counter)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPInput(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		want   bool
+	}{
+		{"spaces are ignored", rfc6238Secret, " 050 471 ", true},
+		{"lowercase secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "050471", true},
+		{"wrong code", rfc6238Secret, "050472", false},
+		{"8 digit code", rfc6238Secret, "14050471", false},
+		{"invalid secret", "not base32!", "050471", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(tt.secret, tt.code, now); ok != tt.want {
+				t.Fatalf("ValidateTOTP ok = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
@@ -16,3 +16,9 @@ func CommitOrRollback(ctx context.Context, tx pgx.Tx) {
 		}
 	}
 }
+
+// TxBeginner opens a transaction, *pgxpool.Pool is one. A service that takes it instead of the
+// pool can be tested with fake repositories and a fake transaction.
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
@@ -0,0 +1,52 @@
+package helper
+
+import "strings"
+
+// userAgentBrowsers is checked in order, Edge and Opera also send "Chrome" and Chrome also sends "Safari"
+var userAgentBrowsers = []struct{ token, name string }{
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"Firefox/", "Firefox"},
+	{"Chrome/", "Chrome"},
+	{"CriOS/", "Chrome"},
+	{"Safari/", "Safari"},
+}
+
+var userAgentSystems = []struct{ token, name string }{
+	{"Android", "Android"},
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Windows", "Windows"},
+	{"CrOS", "ChromeOS"},
+	{"Mac OS X", "macOS"},
+	{"Linux", "Linux"},
+}
+
+// DescribeUserAgent returns a short device description for the sessions page, e.g. "Chrome di Windows"
+func DescribeUserAgent(userAgent string) string {
+	browser, system := "", ""
+	for _, candidate := range userAgentBrowsers {
+		if strings.Contains(userAgent, candidate.token) {
+			browser = candidate.name
+			break
+		}
+	}
+	for _, candidate := range userAgentSystems {
+		if strings.Contains(userAgent, candidate.token) {
+			system = candidate.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " di " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+
+	return "Perangkat tidak dikenal"
+}
@@ -0,0 +1,59 @@
+package helper
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebhookSignatureHeader carries "t=<unix time>,v1=<hex hmac>" on every webhook delivery
+const WebhookSignatureHeader = "X-SayGenFix-Signature"
+
+var ErrWebhookSignature = errors.New("webhook signature is invalid")
+
+// SignWebhook signs "<unix time>.<body>" with HMAC-SHA256. The time is part of the signature so a
+// captured delivery can not be replayed later.
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + unix + ",v1=" + webhookMAC(secret, unix, body)
+}
+
+// VerifyWebhookSignature checks a signature header made by SignWebhook, receivers can use it as is.
+// Signatures older than tolerance are rejected.
+func VerifyWebhookSignature(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var unix, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			unix = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil || signature == "" {
+		return ErrWebhookSignature
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return ErrWebhookSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(webhookMAC(secret, unix, body))) {
+		return ErrWebhookSignature
+	}
+
+	return nil
+}
+
+func webhookMAC(secret, unix string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix + "."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
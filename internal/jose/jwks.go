@@ -0,0 +1,217 @@
+package jose
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// keyRefreshInterval limits how often an unknown key id triggers a new JWKS fetch
+const keyRefreshInterval = time.Minute
+
+// JSONWebKey is one public key of a JWKS document
+type JSONWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// KeySet is a JWKS document
+type KeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// RemoteKeySet fetches the JWKS of an issuer on first use and again when a token names a key id
+// it does not know yet, so key rotation at the issuer needs no restart
+type RemoteKeySet struct {
+	url        string
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+func NewRemoteKeySet(url string, httpClient *http.Client) *RemoteKeySet {
+	return &RemoteKeySet{url: url, httpClient: httpClient}
+}
+
+// Key is a KeyFunc
+func (set *RemoteKeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+
+	if set.keys != nil {
+		if key, ok := lookupKey(set.keys, kid); ok {
+			return key, nil
+		}
+		if time.Since(set.fetchedAt) < keyRefreshInterval {
+			return nil, fmt.Errorf("%w: unknown key id %q", ErrTokenInvalid, kid)
+		}
+	}
+
+	keys, err := set.fetch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+	set.keys = keys
+	set.fetchedAt = time.Now()
+
+	if key, ok := lookupKey(keys, kid); ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown key id %q", ErrTokenInvalid, kid)
+}
+
+func (set *RemoteKeySet) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, set.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := set.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s from %s", resp.Status, set.url)
+	}
+
+	var jwks KeySet
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := ParseJSONWebKey(jwk); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+
+	return keys, nil
+}
+
+// lookupKey also accepts a token without kid when the issuer publishes a single key
+func lookupKey(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, bool) {
+	if key, ok := keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+
+	return nil, false
+}
+
+// ParseJSONWebKey accepts RSA keys and EC keys on P-256
+func ParseJSONWebKey(jwk JSONWebKey) (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
+
+// PublicJSONWebKey describes the public half of an RS256 signing key
+func PublicJSONWebKey(key *rsa.PublicKey, kid string) JSONWebKey {
+	return JSONWebKey{
+		Kid: kid,
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// Thumbprint is the RFC 7638 thumbprint of an RSA key, stable across restarts so it makes a good key id
+func Thumbprint(key *rsa.PublicKey) string {
+	jwk := PublicJSONWebKey(key, "")
+	// Urutan field harus e, kty, n sesuai RFC 7638
+	digest := sha256.Sum256([]byte(`{"e":"` + jwk.E + `","kty":"RSA","n":"` + jwk.N + `"}`))
+
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// ParseRSAPrivateKey reads a PEM encoded RSA key in PKCS #1 or PKCS #8 form
+func ParseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+
+	return key, nil
+}
@@ -0,0 +1,181 @@
+package lti
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mhaatha/go-template-saygenfix/internal/jose"
+)
+
+// scoreContentType is the media type of the AGS score service
+const scoreContentType = "application/vnd.ims.lis.v1.score+json"
+
+// errorBodyLimit keeps platform error pages out of the sync log
+const errorBodyLimit = 300
+
+// Score is posted to the scores endpoint of a line item, the platform keeps the latest score per user
+type Score struct {
+	UserID           string  `json:"userId"`
+	ScoreGiven       float64 `json:"scoreGiven"`
+	ScoreMaximum     float64 `json:"scoreMaximum"`
+	ActivityProgress string  `json:"activityProgress"`
+	GradingProgress  string  `json:"gradingProgress"`
+	Timestamp        string  `json:"timestamp"`
+}
+
+// NewScore is a final score of a completed activity
+func NewScore(userId string, scoreGiven, scoreMaximum float64, timestamp time.Time) Score {
+	return Score{
+		UserID:           userId,
+		ScoreGiven:       scoreGiven,
+		ScoreMaximum:     scoreMaximum,
+		ActivityProgress: "Completed",
+		GradingProgress:  "FullyGraded",
+		Timestamp:        timestamp.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+	}
+}
+
+// PostScore sends the score to "<line item>/scores". A rejected access token is dropped from the
+// cache, so the next attempt asks for a new one.
+func (tool *Tool) PostScore(ctx context.Context, registration Registration, lineItemURL string, score Score) error {
+	scoresURL, err := scoresURL(lineItemURL)
+	if err != nil {
+		return err
+	}
+
+	token, err := tool.accessToken(ctx, registration, ScopeScore)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(score)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, scoresURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", scoreContentType)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := tool.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		tool.forgetAccessToken(registration, ScopeScore)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return responseError("score service", resp)
+	}
+
+	return nil
+}
+
+// accessToken runs the client credentials grant with a signed client assertion, tokens are reused until shortly before they expire
+func (tool *Tool) accessToken(ctx context.Context, registration Registration, scope string) (string, error) {
+	cacheKey := registration.AuthTokenURL + " " + registration.ClientID + " " + scope
+
+	tool.mu.Lock()
+	cached, ok := tool.tokens[cacheKey]
+	tool.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.value, nil
+	}
+
+	now := time.Now()
+	assertion, err := jose.Sign(tool.key, tool.keyId, map[string]any{
+		"iss": registration.ClientID,
+		"sub": registration.ClientID,
+		"aud": registration.AuthTokenURL,
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+		"jti": uuid.NewString(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to sign client assertion: %w", err)
+	}
+
+	form := url.Values{
+		"grant_type":            {"client_credentials"},
+		"client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"},
+		"client_assertion":      {assertion},
+		"scope":                 {scope},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, registration.AuthTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := tool.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", responseError("token endpoint", resp)
+	}
+
+	var tokenResponse struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if tokenResponse.AccessToken == "" {
+		return "", errors.New("token response has no access_token")
+	}
+
+	// Token dipakai ulang sampai satu menit sebelum kedaluwarsa
+	lifetime := time.Duration(tokenResponse.ExpiresIn)*time.Second - time.Minute
+	if lifetime > 0 {
+		tool.mu.Lock()
+		tool.tokens[cacheKey] = accessToken{value: tokenResponse.AccessToken, expiresAt: now.Add(lifetime)}
+		tool.mu.Unlock()
+	}
+
+	return tokenResponse.AccessToken, nil
+}
+
+func (tool *Tool) forgetAccessToken(registration Registration, scope string) {
+	tool.mu.Lock()
+	delete(tool.tokens, registration.AuthTokenURL+" "+registration.ClientID+" "+scope)
+	tool.mu.Unlock()
+}
+
+// scoresURL appends /scores to the path, some platforms put query parameters in the line item url
+func scoresURL(lineItemURL string) (string, error) {
+	parsed, err := url.Parse(lineItemURL)
+	if err != nil || parsed.Host == "" {
+		return "", fmt.Errorf("invalid line item url %q", lineItemURL)
+	}
+	parsed.Path = strings.TrimSuffix(parsed.Path, "/") + "/scores"
+
+	return parsed.String(), nil
+}
+
+func responseError(service string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, errorBodyLimit))
+	if message := strings.TrimSpace(string(body)); message != "" {
+		return fmt.Errorf("%s answered %s: %s", service, resp.Status, message)
+	}
+
+	return fmt.Errorf("%s answered %s", service, resp.Status)
+}
@@ -0,0 +1,174 @@
+// Package lti implements the tool side of LTI 1.3: the OpenID Connect launch, deep linking and
+// posting scores through Assignment and Grade Services. Platforms are passed in as a
+// Registration, storing them is up to the caller.
+package lti
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+const Version = "1.3.0"
+
+const (
+	MessageTypeResourceLink        = "LtiResourceLinkRequest"
+	MessageTypeDeepLinking         = "LtiDeepLinkingRequest"
+	MessageTypeDeepLinkingResponse = "LtiDeepLinkingResponse"
+)
+
+// Claim names of the launch and deep linking messages
+const (
+	ClaimMessageType         = "https://purl.imsglobal.org/spec/lti/claim/message_type"
+	ClaimVersion             = "https://purl.imsglobal.org/spec/lti/claim/version"
+	ClaimDeploymentID        = "https://purl.imsglobal.org/spec/lti/claim/deployment_id"
+	ClaimTargetLinkURI       = "https://purl.imsglobal.org/spec/lti/claim/target_link_uri"
+	ClaimResourceLink        = "https://purl.imsglobal.org/spec/lti/claim/resource_link"
+	ClaimContext             = "https://purl.imsglobal.org/spec/lti/claim/context"
+	ClaimRoles               = "https://purl.imsglobal.org/spec/lti/claim/roles"
+	ClaimCustom              = "https://purl.imsglobal.org/spec/lti/claim/custom"
+	ClaimDeepLinkingSettings = "https://purl.imsglobal.org/spec/lti-dl/claim/deep_linking_settings"
+	ClaimContentItems        = "https://purl.imsglobal.org/spec/lti-dl/claim/content_items"
+	ClaimDeepLinkingData     = "https://purl.imsglobal.org/spec/lti-dl/claim/data"
+	ClaimAGSEndpoint         = "https://purl.imsglobal.org/spec/lti-ags/claim/endpoint"
+)
+
+// ScopeScore lets the tool post scores to a line item
+const ScopeScore = "https://purl.imsglobal.org/spec/lti-ags/scope/score"
+
+// instructorRoles are the LIS roles that may pick exams and see results, sub-roles such as
+// membership/Instructor#TeachingAssistant count as well
+var instructorRoles = []string{
+	"http://purl.imsglobal.org/vocab/lis/v2/membership#Instructor",
+	"http://purl.imsglobal.org/vocab/lis/v2/membership#Administrator",
+	"http://purl.imsglobal.org/vocab/lis/v2/membership#ContentDeveloper",
+	"http://purl.imsglobal.org/vocab/lis/v2/institution/person#Instructor",
+	"http://purl.imsglobal.org/vocab/lis/v2/institution/person#Faculty",
+	"http://purl.imsglobal.org/vocab/lis/v2/institution/person#Administrator",
+	"http://purl.imsglobal.org/vocab/lis/v2/system/person#Administrator",
+}
+
+const instructorSubRolePrefix = "http://purl.imsglobal.org/vocab/lis/v2/membership/Instructor#"
+
+// Launch is a verified launch message
+type Launch struct {
+	Issuer  string
+	Subject string
+	Email   string
+	Name    string
+
+	MessageType   string
+	DeploymentID  string
+	TargetLinkURI string
+	Roles         []string
+	// Custom holds the custom parameters of the link, non-string values are dropped
+	Custom map[string]string
+
+	ResourceLinkID    string
+	ResourceLinkTitle string
+	ContextID         string
+	ContextTitle      string
+
+	// Deep linking settings, only set for LtiDeepLinkingRequest
+	DeepLinkReturnURL   string
+	DeepLinkAcceptTypes []string
+	DeepLinkData        string
+
+	// Assignment and Grade Services endpoint, LineItemURL is empty when the platform made no line item
+	LineItemURL string
+	AGSScopes   []string
+}
+
+type launchClaims struct {
+	Issuer        string         `json:"iss"`
+	Subject       string         `json:"sub"`
+	Email         string         `json:"email"`
+	Name          string         `json:"name"`
+	GivenName     string         `json:"given_name"`
+	FamilyName    string         `json:"family_name"`
+	MessageType   string         `json:"https://purl.imsglobal.org/spec/lti/claim/message_type"`
+	Version       string         `json:"https://purl.imsglobal.org/spec/lti/claim/version"`
+	DeploymentID  string         `json:"https://purl.imsglobal.org/spec/lti/claim/deployment_id"`
+	TargetLinkURI string         `json:"https://purl.imsglobal.org/spec/lti/claim/target_link_uri"`
+	Roles         []string       `json:"https://purl.imsglobal.org/spec/lti/claim/roles"`
+	Custom        map[string]any `json:"https://purl.imsglobal.org/spec/lti/claim/custom"`
+	ResourceLink  struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+	} `json:"https://purl.imsglobal.org/spec/lti/claim/resource_link"`
+	Context struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+	} `json:"https://purl.imsglobal.org/spec/lti/claim/context"`
+	DeepLinkingSettings struct {
+		ReturnURL   string   `json:"deep_link_return_url"`
+		AcceptTypes []string `json:"accept_types"`
+		Data        string   `json:"data"`
+	} `json:"https://purl.imsglobal.org/spec/lti-dl/claim/deep_linking_settings"`
+	AGSEndpoint struct {
+		Scope    []string `json:"scope"`
+		LineItem string   `json:"lineitem"`
+	} `json:"https://purl.imsglobal.org/spec/lti-ags/claim/endpoint"`
+}
+
+// parseLaunch reads the LTI claims out of the verified ID token claims
+func parseLaunch(raw map[string]any) (Launch, string, error) {
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return Launch{}, "", err
+	}
+
+	var claims launchClaims
+	if err := json.Unmarshal(encoded, &claims); err != nil {
+		return Launch{}, "", err
+	}
+
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name = strings.TrimSpace(claims.GivenName + " " + claims.FamilyName)
+	}
+
+	custom := map[string]string{}
+	for key, value := range claims.Custom {
+		if text, ok := value.(string); ok {
+			custom[key] = text
+		}
+	}
+
+	return Launch{
+		Issuer:              claims.Issuer,
+		Subject:             claims.Subject,
+		Email:               claims.Email,
+		Name:                name,
+		MessageType:         claims.MessageType,
+		DeploymentID:        claims.DeploymentID,
+		TargetLinkURI:       claims.TargetLinkURI,
+		Roles:               claims.Roles,
+		Custom:              custom,
+		ResourceLinkID:      claims.ResourceLink.ID,
+		ResourceLinkTitle:   claims.ResourceLink.Title,
+		ContextID:           claims.Context.ID,
+		ContextTitle:        claims.Context.Title,
+		DeepLinkReturnURL:   claims.DeepLinkingSettings.ReturnURL,
+		DeepLinkAcceptTypes: claims.DeepLinkingSettings.AcceptTypes,
+		DeepLinkData:        claims.DeepLinkingSettings.Data,
+		LineItemURL:         claims.AGSEndpoint.LineItem,
+		AGSScopes:           claims.AGSEndpoint.Scope,
+	}, claims.Version, nil
+}
+
+// IsInstructor reports whether the user teaches in the context or administers the platform
+func (launch Launch) IsInstructor() bool {
+	for _, role := range launch.Roles {
+		if slices.Contains(instructorRoles, role) || strings.HasPrefix(role, instructorSubRolePrefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CanPostScores reports whether the platform gave a line item and the score scope
+func (launch Launch) CanPostScores() bool {
+	return launch.LineItemURL != "" && slices.Contains(launch.AGSScopes, ScopeScore)
+}
@@ -0,0 +1,232 @@
+package lti
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mhaatha/go-template-saygenfix/internal/config"
+	"github.com/mhaatha/go-template-saygenfix/internal/jose"
+)
+
+// ErrLaunchInvalid is returned when the signature or a required claim of a launch does not check out
+var ErrLaunchInvalid = errors.New("lti launch is invalid")
+
+// Registration is a platform the tool trusts, DeploymentIDs empty accepts every deployment
+type Registration struct {
+	Issuer        string
+	ClientID      string
+	AuthLoginURL  string
+	AuthTokenURL  string
+	JWKSURL       string
+	DeploymentIDs []string
+}
+
+// Tool signs messages to platforms with one RSA key and verifies their launches against the JWKS
+// of each platform. Key sets and access tokens are cached per platform.
+type Tool struct {
+	key        *rsa.PrivateKey
+	keyId      string
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	keySets map[string]*jose.RemoteKeySet
+	tokens  map[string]accessToken
+}
+
+type accessToken struct {
+	value     string
+	expiresAt time.Time
+}
+
+// NewToolFromConfig loads LTI_PRIVATE_KEY_FILE, a missing setting gets a key that only lives until restart
+func NewToolFromConfig(cfg *config.Config) (*Tool, error) {
+	if cfg.LTIPrivateKeyFile == "" {
+		// Platform menyimpan kunci publik tool, kunci acak berganti setiap server restart
+		slog.Warn("LTI_PRIVATE_KEY_FILE tidak diset, menggunakan kunci acak untuk LTI")
+
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate lti key: %w", err)
+		}
+
+		return NewTool(key), nil
+	}
+
+	data, err := os.ReadFile(cfg.LTIPrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lti private key: %w", err)
+	}
+	key, err := jose.ParseRSAPrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse lti private key: %w", err)
+	}
+
+	return NewTool(key), nil
+}
+
+func NewTool(key *rsa.PrivateKey) *Tool {
+	return &Tool{
+		key:        key,
+		keyId:      jose.Thumbprint(&key.PublicKey),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		keySets:    map[string]*jose.RemoteKeySet{},
+		tokens:     map[string]accessToken{},
+	}
+}
+
+// KeySet is the JWKS document platforms use to verify the tool
+func (tool *Tool) KeySet() jose.KeySet {
+	return jose.KeySet{Keys: []jose.JSONWebKey{jose.PublicJSONWebKey(&tool.key.PublicKey, tool.keyId)}}
+}
+
+// AuthRequestURL answers a login initiation with the authentication request to the platform, the
+// platform then posts the launch to redirectURI. The caller keeps state and nonce until the launch.
+func (tool *Tool) AuthRequestURL(registration Registration, redirectURI, loginHint, messageHint, state, nonce string) (string, error) {
+	authURL, err := url.Parse(registration.AuthLoginURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid platform login url: %w", err)
+	}
+
+	query := authURL.Query()
+	query.Set("scope", "openid")
+	query.Set("response_type", "id_token")
+	query.Set("response_mode", "form_post")
+	query.Set("prompt", "none")
+	query.Set("client_id", registration.ClientID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("login_hint", loginHint)
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	if messageHint != "" {
+		query.Set("lti_message_hint", messageHint)
+	}
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), nil
+}
+
+// VerifyLaunch checks the ID token of a launch against the platform JWKS, then the OpenID
+// Connect claims and the LTI claims every message must carry
+func (tool *Tool) VerifyLaunch(ctx context.Context, registration Registration, idToken, nonce string) (Launch, error) {
+	raw, err := jose.Verify(ctx, idToken, tool.keySet(registration.JWKSURL).Key)
+	if err != nil {
+		if errors.Is(err, jose.ErrTokenInvalid) {
+			return Launch{}, fmt.Errorf("%w: %w", ErrLaunchInvalid, err)
+		}
+		return Launch{}, err
+	}
+
+	if err := jose.CheckExpiry(raw); err != nil {
+		return Launch{}, fmt.Errorf("%w: %w", ErrLaunchInvalid, err)
+	}
+	if issuer, _ := raw["iss"].(string); issuer != registration.Issuer {
+		return Launch{}, fmt.Errorf("%w: unexpected issuer %q", ErrLaunchInvalid, issuer)
+	}
+	audience := jose.Strings(raw, "aud")
+	if !slices.Contains(audience, registration.ClientID) {
+		return Launch{}, fmt.Errorf("%w: unexpected audience", ErrLaunchInvalid)
+	}
+	// Dengan lebih dari satu audience, azp wajib menunjuk client id tool ini
+	if authorizedParty, ok := raw["azp"].(string); (ok || len(audience) > 1) && authorizedParty != registration.ClientID {
+		return Launch{}, fmt.Errorf("%w: unexpected authorized party", ErrLaunchInvalid)
+	}
+	if tokenNonce, _ := raw["nonce"].(string); nonce == "" || tokenNonce != nonce {
+		return Launch{}, fmt.Errorf("%w: nonce mismatch", ErrLaunchInvalid)
+	}
+
+	launch, version, err := parseLaunch(raw)
+	if err != nil {
+		return Launch{}, fmt.Errorf("%w: malformed lti claims", ErrLaunchInvalid)
+	}
+
+	if version != Version {
+		return Launch{}, fmt.Errorf("%w: unsupported lti version %q", ErrLaunchInvalid, version)
+	}
+	if launch.Subject == "" {
+		return Launch{}, fmt.Errorf("%w: anonymous launches are not supported", ErrLaunchInvalid)
+	}
+	if launch.DeploymentID == "" {
+		return Launch{}, fmt.Errorf("%w: missing deployment id", ErrLaunchInvalid)
+	}
+	if len(registration.DeploymentIDs) > 0 && !slices.Contains(registration.DeploymentIDs, launch.DeploymentID) {
+		return Launch{}, fmt.Errorf("%w: unknown deployment id %q", ErrLaunchInvalid, launch.DeploymentID)
+	}
+
+	switch launch.MessageType {
+	case MessageTypeResourceLink:
+		if launch.ResourceLinkID == "" {
+			return Launch{}, fmt.Errorf("%w: missing resource link id", ErrLaunchInvalid)
+		}
+	case MessageTypeDeepLinking:
+		if launch.DeepLinkReturnURL == "" {
+			return Launch{}, fmt.Errorf("%w: missing deep link return url", ErrLaunchInvalid)
+		}
+	default:
+		return Launch{}, fmt.Errorf("%w: unsupported message type %q", ErrLaunchInvalid, launch.MessageType)
+	}
+
+	return launch, nil
+}
+
+// ContentItem is one link returned to the platform from deep linking
+type ContentItem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title,omitempty"`
+	Text     string            `json:"text,omitempty"`
+	URL      string            `json:"url,omitempty"`
+	Custom   map[string]string `json:"custom,omitempty"`
+	LineItem *LineItem         `json:"lineItem,omitempty"`
+}
+
+// LineItem asks the platform to create a gradebook column for the link
+type LineItem struct {
+	ScoreMaximum float64 `json:"scoreMaximum"`
+	Label        string  `json:"label,omitempty"`
+	ResourceID   string  `json:"resourceId,omitempty"`
+}
+
+// DeepLinkingResponse signs the message the browser posts back to the deep link return url
+func (tool *Tool) DeepLinkingResponse(registration Registration, deploymentId, data string, items []ContentItem) (string, error) {
+	now := time.Now()
+	claims := map[string]any{
+		"iss":             registration.ClientID,
+		"aud":             registration.Issuer,
+		"iat":             now.Unix(),
+		"exp":             now.Add(5 * time.Minute).Unix(),
+		"nonce":           uuid.NewString(),
+		ClaimMessageType:  MessageTypeDeepLinkingResponse,
+		ClaimVersion:      Version,
+		ClaimDeploymentID: deploymentId,
+		ClaimContentItems: items,
+	}
+	// Data dari platform wajib dikembalikan apa adanya
+	if data != "" {
+		claims[ClaimDeepLinkingData] = data
+	}
+
+	return jose.Sign(tool.key, tool.keyId, claims)
+}
+
+func (tool *Tool) keySet(jwksURL string) *jose.RemoteKeySet {
+	tool.mu.Lock()
+	defer tool.mu.Unlock()
+
+	keySet, ok := tool.keySets[jwksURL]
+	if !ok {
+		keySet = jose.NewRemoteKeySet(jwksURL, tool.httpClient)
+		tool.keySets[jwksURL] = keySet
+	}
+
+	return keySet
+}
@@ -0,0 +1,365 @@
+package lti
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/jose"
+)
+
+const (
+	testClientId     = "saygenfix-tool"
+	testDeploymentId = "deployment-1"
+	testPlatformKid  = "platform-key"
+)
+
+// fakePlatform is the platform side of LTI 1.3: it publishes a JWKS, signs launches, hands out
+// access tokens for client assertions signed by the tool and keeps the posted scores
+type fakePlatform struct {
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	toolKey crypto.PublicKey
+
+	mu            sync.Mutex
+	tokenRequests int
+	scores        []Score
+	// rejectToken answers the next score post with 401
+	rejectToken bool
+}
+
+func newFakePlatform(t *testing.T, tool *Tool) *fakePlatform {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	platform := &fakePlatform{key: key, toolKey: &tool.key.PublicKey}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.KeySet{Keys: []jose.JSONWebKey{jose.PublicJSONWebKey(&key.PublicKey, testPlatformKid)}})
+	})
+	mux.HandleFunc("POST /token", platform.token)
+	mux.HandleFunc("POST /lineitems/1/scores", platform.score)
+
+	platform.server = httptest.NewServer(mux)
+	t.Cleanup(platform.server.Close)
+
+	return platform
+}
+
+func (platform *fakePlatform) registration() Registration {
+	return Registration{
+		Issuer:        platform.server.URL,
+		ClientID:      testClientId,
+		AuthLoginURL:  platform.server.URL + "/auth",
+		AuthTokenURL:  platform.server.URL + "/token",
+		JWKSURL:       platform.server.URL + "/jwks",
+		DeploymentIDs: []string{testDeploymentId},
+	}
+}
+
+// launchClaims is a valid resource link launch, tests change single claims
+func (platform *fakePlatform) launchClaims(nonce string) map[string]any {
+	return map[string]any{
+		"iss":             platform.server.URL,
+		"sub":             "platform-user-1",
+		"aud":             testClientId,
+		"exp":             time.Now().Add(5 * time.Minute).Unix(),
+		"nonce":           nonce,
+		"email":           "siswa@sekolah.sch.id",
+		"name":            "Siswa Satu",
+		ClaimMessageType:  MessageTypeResourceLink,
+		ClaimVersion:      Version,
+		ClaimDeploymentID: testDeploymentId,
+		ClaimResourceLink: map[string]any{"id": "link-1", "title": "Ujian Biologi"},
+		ClaimRoles:        []string{"http://purl.imsglobal.org/vocab/lis/v2/membership#Learner"},
+		ClaimCustom:       map[string]any{"exam_id": "exam-1"},
+		ClaimAGSEndpoint: map[string]any{
+			"scope":    []string{ScopeScore},
+			"lineitem": platform.server.URL + "/lineitems/1",
+		},
+	}
+}
+
+func (platform *fakePlatform) sign(t *testing.T, key *rsa.PrivateKey, claims map[string]any) string {
+	t.Helper()
+
+	token, err := jose.Sign(key, testPlatformKid, claims)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return token
+}
+
+func (platform *fakePlatform) token(w http.ResponseWriter, r *http.Request) {
+	if r.PostFormValue("grant_type") != "client_credentials" || r.PostFormValue("scope") != ScopeScore {
+		http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+		return
+	}
+
+	claims, err := jose.Verify(r.Context(), r.PostFormValue("client_assertion"), func(ctx context.Context, kid string) (crypto.PublicKey, error) {
+		return platform.toolKey, nil
+	})
+	if err != nil || claims["iss"] != testClientId || claims["sub"] != testClientId || claims["aud"] != platform.server.URL+"/token" {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+
+	platform.mu.Lock()
+	platform.tokenRequests++
+	accessToken := "access-" + strconv.Itoa(platform.tokenRequests)
+	platform.mu.Unlock()
+
+	json.NewEncoder(w).Encode(map[string]any{"access_token": accessToken, "token_type": "Bearer", "expires_in": 3600})
+}
+
+func (platform *fakePlatform) score(w http.ResponseWriter, r *http.Request) {
+	platform.mu.Lock()
+	defer platform.mu.Unlock()
+
+	if platform.rejectToken || !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer access-") {
+		platform.rejectToken = false
+		http.Error(w, "token expired", http.StatusUnauthorized)
+		return
+	}
+	if r.Header.Get("Content-Type") != scoreContentType {
+		http.Error(w, "unsupported media type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var score Score
+	body, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(body, &score); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	platform.scores = append(platform.scores, score)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newTestTool(t *testing.T) *Tool {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return NewTool(key)
+}
+
+func TestToolKeySet(t *testing.T) {
+	tool := newTestTool(t)
+
+	keySet := tool.KeySet()
+	if len(keySet.Keys) != 1 || keySet.Keys[0].Kid != jose.Thumbprint(&tool.key.PublicKey) || keySet.Keys[0].Alg != "RS256" {
+		t.Fatalf("unexpected key set %+v", keySet)
+	}
+
+	key, err := jose.ParseJSONWebKey(keySet.Keys[0])
+	if err != nil {
+		t.Fatalf("ParseJSONWebKey: %v", err)
+	}
+	if !tool.key.PublicKey.Equal(key) {
+		t.Fatal("published key is not the public half of the tool key")
+	}
+}
+
+func TestToolAuthRequestURL(t *testing.T) {
+	tool := newTestTool(t)
+	platform := newFakePlatform(t, tool)
+
+	authRequestURL, err := tool.AuthRequestURL(platform.registration(), "https://app.test/lti/launch", "login-hint", "message-hint", "state-1", "nonce-1")
+	if err != nil {
+		t.Fatalf("AuthRequestURL: %v", err)
+	}
+
+	parsed, _ := url.Parse(authRequestURL)
+	query := parsed.Query()
+	want := map[string]string{
+		"scope":            "openid",
+		"response_type":    "id_token",
+		"response_mode":    "form_post",
+		"prompt":           "none",
+		"client_id":        testClientId,
+		"redirect_uri":     "https://app.test/lti/launch",
+		"login_hint":       "login-hint",
+		"lti_message_hint": "message-hint",
+		"state":            "state-1",
+		"nonce":            "nonce-1",
+	}
+	for name, value := range want {
+		if query.Get(name) != value {
+			t.Errorf("%s = %q, want %q", name, query.Get(name), value)
+		}
+	}
+	if parsed.Path != "/auth" {
+		t.Errorf("path = %q, want /auth", parsed.Path)
+	}
+}
+
+func TestToolVerifyLaunch(t *testing.T) {
+	tool := newTestTool(t)
+	platform := newFakePlatform(t, tool)
+
+	launch, err := tool.VerifyLaunch(context.Background(), platform.registration(), platform.sign(t, platform.key, platform.launchClaims("nonce-1")), "nonce-1")
+	if err != nil {
+		t.Fatalf("VerifyLaunch: %v", err)
+	}
+
+	if launch.Subject != "platform-user-1" || launch.Email != "siswa@sekolah.sch.id" || launch.ResourceLinkID != "link-1" || launch.Custom["exam_id"] != "exam-1" {
+		t.Fatalf("unexpected launch %+v", launch)
+	}
+	if launch.IsInstructor() || !launch.CanPostScores() || launch.LineItemURL != platform.server.URL+"/lineitems/1" {
+		t.Fatalf("unexpected roles or AGS claims %+v", launch)
+	}
+}
+
+func TestToolVerifyDeepLinkingLaunch(t *testing.T) {
+	tool := newTestTool(t)
+	platform := newFakePlatform(t, tool)
+
+	claims := platform.launchClaims("nonce-1")
+	delete(claims, ClaimResourceLink)
+	claims[ClaimMessageType] = MessageTypeDeepLinking
+	claims[ClaimRoles] = []string{"http://purl.imsglobal.org/vocab/lis/v2/membership#Instructor"}
+	claims[ClaimDeepLinkingSettings] = map[string]any{
+		"deep_link_return_url": platform.server.URL + "/deep-link-return",
+		"accept_types":         []string{"ltiResourceLink"},
+		"data":                 "opaque-data",
+	}
+
+	launch, err := tool.VerifyLaunch(context.Background(), platform.registration(), platform.sign(t, platform.key, claims), "nonce-1")
+	if err != nil {
+		t.Fatalf("VerifyLaunch: %v", err)
+	}
+	if !launch.IsInstructor() || launch.DeepLinkReturnURL != platform.server.URL+"/deep-link-return" || launch.DeepLinkData != "opaque-data" {
+		t.Fatalf("unexpected deep linking launch %+v", launch)
+	}
+}
+
+func TestToolVerifyLaunchRejects(t *testing.T) {
+	tool := newTestTool(t)
+	platform := newFakePlatform(t, tool)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		key    *rsa.PrivateKey
+		change func(claims map[string]any)
+		nonce  string
+	}{
+		{"bad signature", otherKey, func(claims map[string]any) {}, "nonce-1"},
+		{"wrong nonce", platform.key, func(claims map[string]any) {}, "nonce-lain"},
+		{"empty nonce", platform.key, func(claims map[string]any) { claims["nonce"] = "" }, ""},
+		{"other audience", platform.key, func(claims map[string]any) { claims["aud"] = "tool-lain" }, "nonce-1"},
+		{"other issuer", platform.key, func(claims map[string]any) { claims["iss"] = "https://lms.lain" }, "nonce-1"},
+		{"expired", platform.key, func(claims map[string]any) { claims["exp"] = time.Now().Add(-time.Hour).Unix() }, "nonce-1"},
+		{"unknown deployment", platform.key, func(claims map[string]any) { claims[ClaimDeploymentID] = "deployment-lain" }, "nonce-1"},
+		{"other version", platform.key, func(claims map[string]any) { claims[ClaimVersion] = "1.1" }, "nonce-1"},
+		{"missing resource link", platform.key, func(claims map[string]any) { delete(claims, ClaimResourceLink) }, "nonce-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := platform.launchClaims("nonce-1")
+			tt.change(claims)
+
+			_, err := tool.VerifyLaunch(context.Background(), platform.registration(), platform.sign(t, tt.key, claims), tt.nonce)
+			if !errors.Is(err, ErrLaunchInvalid) {
+				t.Fatalf("VerifyLaunch = %v, want ErrLaunchInvalid", err)
+			}
+		})
+	}
+}
+
+func TestToolDeepLinkingResponse(t *testing.T) {
+	tool := newTestTool(t)
+	platform := newFakePlatform(t, tool)
+
+	jwt, err := tool.DeepLinkingResponse(platform.registration(), testDeploymentId, "opaque-data", []ContentItem{{
+		Type:     "ltiResourceLink",
+		Title:    "Ujian Biologi",
+		Custom:   map[string]string{"exam_id": "exam-1"},
+		LineItem: &LineItem{ScoreMaximum: 100, Label: "Ujian Biologi"},
+	}})
+	if err != nil {
+		t.Fatalf("DeepLinkingResponse: %v", err)
+	}
+
+	// Platform memverifikasi respons dengan JWKS tool
+	claims, err := jose.Verify(context.Background(), jwt, func(ctx context.Context, kid string) (crypto.PublicKey, error) {
+		key, _ := jose.ParseJSONWebKey(tool.KeySet().Keys[0])
+		return key, nil
+	})
+	if err != nil {
+		t.Fatalf("platform could not verify the response: %v", err)
+	}
+
+	if claims["iss"] != testClientId || claims["aud"] != platform.server.URL || claims[ClaimMessageType] != MessageTypeDeepLinkingResponse ||
+		claims[ClaimDeploymentID] != testDeploymentId || claims[ClaimDeepLinkingData] != "opaque-data" {
+		t.Fatalf("unexpected claims %v", claims)
+	}
+	items, _ := claims[ClaimContentItems].([]any)
+	item, _ := items[0].(map[string]any)
+	if len(items) != 1 || item["title"] != "Ujian Biologi" || item["custom"].(map[string]any)["exam_id"] != "exam-1" {
+		t.Fatalf("unexpected content items %v", claims[ClaimContentItems])
+	}
+}
+
+func TestToolPostScore(t *testing.T) {
+	tool := newTestTool(t)
+	platform := newFakePlatform(t, tool)
+	ctx := context.Background()
+	lineItemURL := platform.server.URL + "/lineitems/1"
+	completedAt := time.Date(2026, time.March, 2, 8, 0, 0, 0, time.UTC)
+
+	if err := tool.PostScore(ctx, platform.registration(), lineItemURL, NewScore("platform-user-1", 85, 100, completedAt)); err != nil {
+		t.Fatalf("PostScore: %v", err)
+	}
+	if err := tool.PostScore(ctx, platform.registration(), lineItemURL, NewScore("platform-user-2", 70, 100, completedAt)); err != nil {
+		t.Fatalf("second PostScore: %v", err)
+	}
+	if platform.tokenRequests != 1 {
+		t.Fatalf("token requests = %d, want the access token to be reused", platform.tokenRequests)
+	}
+
+	want := Score{UserID: "platform-user-1", ScoreGiven: 85, ScoreMaximum: 100, ActivityProgress: "Completed", GradingProgress: "FullyGraded", Timestamp: "2026-03-02T08:00:00.000Z"}
+	if len(platform.scores) != 2 || platform.scores[0] != want {
+		t.Fatalf("scores = %+v, want %+v first", platform.scores, want)
+	}
+
+	// Token yang ditolak dibuang, percobaan berikutnya meminta token baru
+	platform.rejectToken = true
+	if err := tool.PostScore(ctx, platform.registration(), lineItemURL, NewScore("platform-user-1", 90, 100, completedAt)); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("PostScore with a rejected token = %v, want 401", err)
+	}
+	if err := tool.PostScore(ctx, platform.registration(), lineItemURL, NewScore("platform-user-1", 90, 100, completedAt)); err != nil {
+		t.Fatalf("PostScore after a rejected token: %v", err)
+	}
+	if platform.tokenRequests != 2 {
+		t.Fatalf("token requests = %d, want a new token after 401", platform.tokenRequests)
+	}
+	if !slices.ContainsFunc(platform.scores, func(score Score) bool { return score.ScoreGiven == 90 }) {
+		t.Fatal("score after the new token was not posted")
+	}
+}
@@ -0,0 +1,40 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// NewLogMailer writes every email to the log, or to a file per email when dir is set
+func NewLogMailer(dir string) Mailer {
+	return &LogMailer{
+		Dir: dir,
+	}
+}
+
+type LogMailer struct {
+	Dir string
+}
+
+func (mailer *LogMailer) Send(ctx context.Context, message Message) error {
+	if mailer.Dir == "" {
+		slog.Info("email not sent, log mailer is active", "to", message.To, "subject", message.Subject, "body", message.Body)
+		return nil
+	}
+
+	if err := os.MkdirAll(mailer.Dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	fileName := fmt.Sprintf("%s-%s.txt", time.Now().Format("20060102-150405.000000"), filepath.Base(message.To))
+	content := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", message.To, message.Subject, message.Body)
+	if err := os.WriteFile(filepath.Join(mailer.Dir, fileName), []byte(content), 0o640); err != nil {
+		return fmt.Errorf("failed to write mail file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/config"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends transactional emails such as password reset links
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// NewMailer returns the mailer selected by MAIL_DRIVER, the log mailer is the default for development
+func NewMailer(cfg *config.Config) (Mailer, error) {
+	switch cfg.MailDriver {
+	case "", "log":
+		return NewLogMailer(cfg.MailLogDir), nil
+	case "smtp":
+		return NewSMTPMailer(SMTPOptions{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		})
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.MailDriver)
+	}
+}
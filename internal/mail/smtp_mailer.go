@@ -0,0 +1,132 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// smtpTimeout bounds the whole SMTP conversation when ctx has no earlier deadline
+const smtpTimeout = 30 * time.Second
+
+type SMTPOptions struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func NewSMTPMailer(options SMTPOptions) (Mailer, error) {
+	if options.Host == "" || options.From == "" {
+		return nil, errors.New("smtp mailer needs SMTP_HOST and MAIL_FROM")
+	}
+	if options.Port == "" {
+		options.Port = "587"
+	}
+
+	return &SMTPMailer{
+		Options: options,
+	}, nil
+}
+
+type SMTPMailer struct {
+	Options SMTPOptions
+}
+
+// Send delivers the email, STARTTLS is used whenever the server offers it
+func (mailer *SMTPMailer) Send(ctx context.Context, message Message) error {
+	// Header tidak boleh berisi baris baru agar tidak bisa disisipi header lain
+	if strings.ContainsAny(message.To, "\r\n") || strings.ContainsAny(message.Subject, "\r\n") {
+		return errors.New("invalid email header")
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", mailer.Options.From)
+	fmt.Fprintf(&body, "To: %s\r\n", message.To)
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	body.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+
+	if err := mailer.deliver(ctx, message.To, []byte(body.String())); err != nil {
+		// Pembatalan dari ctx lebih jelas daripada error koneksi yang ditutup
+		if ctx.Err() != nil {
+			return fmt.Errorf("failed to send email: %w", ctx.Err())
+		}
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// deliver runs the same steps as smtp.SendMail, but the connection is dialed with ctx, has a
+// deadline and is closed as soon as ctx is cancelled
+func (mailer *SMTPMailer) deliver(ctx context.Context, to string, body []byte) error {
+	deadline := time.Now().Add(smtpTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	dialer := net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(mailer.Options.Host, mailer.Options.Port))
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, mailer.Options.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: mailer.Options.Host}); err != nil {
+			return err
+		}
+	}
+
+	if mailer.Options.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp server does not support AUTH")
+		}
+		auth := smtp.PlainAuth("", mailer.Options.Username, mailer.Options.Password, mailer.Options.Host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(mailer.Options.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(body); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
@@ -2,11 +2,16 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/mhaatha/go-template-saygenfix/internal/config"
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
 	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
 	"github.com/mhaatha/go-template-saygenfix/internal/service"
 )
 
@@ -15,26 +20,50 @@ type ContextKey string
 
 const CurrentUserKey ContextKey = "currentUser"
 
-func NewAuthMiddleware(authService service.AuthService, cfg *config.Config) AuthMiddleware {
+// CurrentSessionKey holds the session id of the request, used to mark the current device
+const CurrentSessionKey ContextKey = "currentSession"
+
+// CurrentAPITokenKey holds the domain.APIToken when the request was authenticated with a bearer token
+const CurrentAPITokenKey ContextKey = "currentAPIToken"
+
+// apiPathPrefix marks the routes that accept bearer tokens and answer with JSON
+const apiPathPrefix = "/api/"
+
+// MustChangePasswordPath is the account page with the change password form, the only page a
+// user with a temporary password can open
+const MustChangePasswordPath = "/account/?status=must-change-password"
+
+func NewAuthMiddleware(authService service.AuthService, apiTokenService service.APITokenService, cfg *config.Config) AuthMiddleware {
 	return &AuthMiddlewareImpl{
-		AuthService: authService,
-		Config:      cfg,
+		AuthService:     authService,
+		APITokenService: apiTokenService,
+		Config:          cfg,
 	}
 }
 
 type AuthMiddleware interface {
 	Authenticate(next http.Handler) http.Handler
 	RequireRole(role string) func(next http.Handler) http.Handler
+	RequireAnyRole(roles ...string) func(next http.Handler) http.Handler
+	RequireScope(scope string) func(next http.Handler) http.Handler
 }
 
 type AuthMiddlewareImpl struct {
-	AuthService service.AuthService
-	Config      *config.Config
+	AuthService     service.AuthService
+	APITokenService service.APITokenService
+	Config          *config.Config
 }
 
 // Authenticate checks for a valid session cookie and adds user info to the request context.
+// API routes also accept a personal token as "Authorization: Bearer", and answer 401 with JSON
+// instead of redirecting to the login page.
 func (m *AuthMiddlewareImpl) Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isAPIRequest(r) {
+			m.authenticateAPI(next, w, r)
+			return
+		}
+
 		cookie, err := r.Cookie(m.Config.SessionName)
 		if err != nil || cookie.Value == "" {
 			slog.Error("cookie not found", "err", err)
@@ -43,7 +72,7 @@ func (m *AuthMiddlewareImpl) Authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		user, err := m.AuthService.ValidateSession(r.Context(), cookie.Value)
+		user, err := m.AuthService.ValidateSession(r.Context(), cookie.Value, r.UserAgent(), helper.ClientIP(r, m.Config.TrustedProxyHops))
 		if err != nil {
 			slog.Error("failed to validate session", "err", err)
 			http.SetCookie(w, &http.Cookie{Name: m.Config.SessionName, Value: "", Path: "/", MaxAge: -1})
@@ -52,12 +81,113 @@ func (m *AuthMiddlewareImpl) Authenticate(next http.Handler) http.Handler {
 			return
 		}
 
+		if user.MustChangePassword && !isPasswordChangeRequest(r) {
+			http.Redirect(w, r, MustChangePasswordPath, http.StatusSeeOther)
+			return
+		}
+
 		// Send session data through context
 		ctx := context.WithValue(r.Context(), CurrentUserKey, user)
+		ctx = context.WithValue(ctx, CurrentSessionKey, cookie.Value)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+func (m *AuthMiddlewareImpl) authenticateAPI(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	bearerToken, hasBearer := BearerToken(r)
+	if !hasBearer {
+		// Tanpa token, API tetap bisa dipakai dari browser yang sudah login
+		cookie, err := r.Cookie(m.Config.SessionName)
+		if err != nil || cookie.Value == "" {
+			helper.WriteJSONError(w, http.StatusUnauthorized, "unauthorized", "Authentication is required")
+			return
+		}
+
+		user, err := m.AuthService.ValidateSession(r.Context(), cookie.Value, r.UserAgent(), helper.ClientIP(r, m.Config.TrustedProxyHops))
+		if err != nil {
+			slog.Error("failed to validate session", "err", err)
+
+			helper.WriteJSONError(w, http.StatusUnauthorized, "unauthorized", "Session is invalid or expired")
+			return
+		}
+		if user.MustChangePassword {
+			helper.WriteJSONError(w, http.StatusForbidden, "password_change_required", "Change the temporary password on the account page to use the API")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), CurrentUserKey, user)
+		ctx = context.WithValue(ctx, CurrentSessionKey, cookie.Value)
+		next.ServeHTTP(w, r.WithContext(ctx))
+		return
+	}
+
+	user, token, err := m.APITokenService.Authenticate(r.Context(), web.APITokenAuthRequest{
+		Token:     bearerToken,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		IPAddress: helper.ClientIP(r, m.Config.TrustedProxyHops),
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		if !errors.Is(err, service.ErrAPITokenInvalid) {
+			slog.Error("failed to authenticate api token", "err", err)
+
+			helper.WriteJSONError(w, http.StatusInternalServerError, "internal_error", "Internal Server Error")
+			return
+		}
+
+		slog.Warn("api token rejected", "ip", helper.ClientIP(r, m.Config.TrustedProxyHops), "path", r.URL.Path)
+
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		helper.WriteJSONError(w, http.StatusUnauthorized, "invalid_token", "Token is invalid, revoked or expired")
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), CurrentUserKey, user)
+	ctx = context.WithValue(ctx, CurrentAPITokenKey, token)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// RequireScope rejects bearer token requests whose token does not have the scope. Requests
+// authenticated with the session cookie act with every permission of the user.
+// This middleware MUST run AFTER the Authenticate middleware.
+func (m *AuthMiddlewareImpl) RequireScope(scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := r.Context().Value(CurrentAPITokenKey).(domain.APIToken)
+			if ok && !slices.Contains(token.Scopes, scope) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+				helper.WriteJSONError(w, http.StatusForbidden, "insufficient_scope", "Token does not have the "+scope+" scope")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BearerToken returns the token of an "Authorization: Bearer" header
+func BearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// isPasswordChangeRequest reports whether the request opens or submits the change password form
+func isPasswordChangeRequest(r *http.Request) bool {
+	return (r.Method == http.MethodGet && r.URL.Path == "/account/") ||
+		(r.Method == http.MethodPost && r.URL.Path == "/account/password")
+}
+
+// isAPIRequest reports whether the request goes to a JSON API route
+func isAPIRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, apiPathPrefix)
+}
+
 // RequireRole checks if the user in the context has the required role.
 // This middleware MUST run AFTER the Authenticate middleware.
 func (m *AuthMiddlewareImpl) RequireRole(role string) func(next http.Handler) http.Handler {
@@ -76,11 +206,19 @@ func (m *AuthMiddlewareImpl) RequireRole(role string) func(next http.Handler) ht
 
 			// Check user role
 			userData, ok := user.(domain.User)
+			if (!ok || userData.Role != role) && isAPIRequest(r) {
+				helper.WriteJSONError(w, http.StatusForbidden, "forbidden", "This endpoint is only for the "+role+" role")
+				return
+			}
 			if !ok || userData.Role != role {
 				// Role does not match, redirect based on role
 				// For example, a student trying to access a teacher page is redirected to the student dashboard
 				currentUserRole := userData.Role
 				if currentUserRole != "" {
+					if currentUserRole == "admin" {
+						http.Redirect(w, r, "/admin/dashboard", http.StatusForbidden)
+						return
+					}
 					if currentUserRole == "teacher" {
 						http.Redirect(w, r, "/teacher/dashboard", http.StatusForbidden)
 						return
@@ -101,3 +239,31 @@ func (m *AuthMiddlewareImpl) RequireRole(role string) func(next http.Handler) ht
 		})
 	}
 }
+
+// RequireAnyRole is RequireRole for pages shared by several roles, like the account pages.
+// This middleware MUST run AFTER the Authenticate middleware.
+func (m *AuthMiddlewareImpl) RequireAnyRole(roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := r.Context().Value(CurrentUserKey).(domain.User)
+			if !ok {
+				slog.Error("user not found in context")
+
+				http.Redirect(w, r, "/login", http.StatusSeeOther)
+				return
+			}
+
+			if !slices.Contains(roles, user.Role) && isAPIRequest(r) {
+				helper.WriteJSONError(w, http.StatusForbidden, "forbidden", "This endpoint is not available for the "+user.Role+" role")
+				return
+			}
+			if !slices.Contains(roles, user.Role) {
+				// Role lain dikembalikan ke dashboard masing-masing
+				http.Redirect(w, r, "/"+user.Role+"/dashboard", http.StatusSeeOther)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package middleware_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/middleware"
+)
+
+func TestMain(m *testing.M) {
+	// Request yang ditolak dicatat sebagai warning, tidak perlu muncul di output test
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	os.Exit(m.Run())
+}
+
+const testCSRFToken = "token-dari-cookie"
+
+func TestCSRFProtect(t *testing.T) {
+	reached := false
+	protected := middleware.NewCSRFMiddleware().Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		// header is the X-CSRF-Token header, form the csrf_token form field
+		header        string
+		form          string
+		authorization string
+		want          int
+	}{
+		{name: "safe method without token", method: http.MethodGet, path: "/teacher/dashboard", want: http.StatusNoContent},
+		{name: "missing token", method: http.MethodPost, path: "/account/profile", want: http.StatusForbidden},
+		{name: "wrong token in header", method: http.MethodPost, path: "/account/profile", header: "token-lain", want: http.StatusForbidden},
+		{name: "wrong token in form", method: http.MethodPost, path: "/account/profile", form: "token-lain", want: http.StatusForbidden},
+		{name: "matching token in header", method: http.MethodDelete, path: "/teacher/bank/1", header: testCSRFToken, want: http.StatusNoContent},
+		{name: "matching token in form", method: http.MethodPost, path: "/account/profile", form: testCSRFToken, want: http.StatusNoContent},
+		{name: "bearer token on api", method: http.MethodPost, path: "/api/v1/teacher/exams", authorization: "Bearer sgf_token", want: http.StatusNoContent},
+		{name: "bearer token outside api", method: http.MethodPost, path: "/account/profile", authorization: "Bearer sgf_token", want: http.StatusForbidden},
+		{name: "api without bearer token", method: http.MethodPost, path: "/api/v1/teacher/exams", want: http.StatusForbidden},
+		{name: "lti launch", method: http.MethodPost, path: "/lti/launch", want: http.StatusNoContent},
+		{name: "lti prefix only", method: http.MethodPost, path: "/ltixyz", want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached = false
+
+			var body io.Reader
+			if tt.form != "" {
+				body = strings.NewReader(url.Values{middleware.CSRFFormField: {tt.form}}.Encode())
+			}
+			request := httptest.NewRequest(tt.method, tt.path, body)
+			if tt.form != "" {
+				request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			request.AddCookie(&http.Cookie{Name: middleware.CSRFCookieName, Value: testCSRFToken})
+			if tt.header != "" {
+				request.Header.Set(middleware.CSRFHeaderName, tt.header)
+			}
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+
+			recorder := httptest.NewRecorder()
+			protected.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.want)
+			}
+			if reached != (tt.want == http.StatusNoContent) {
+				t.Fatalf("next handler reached = %v", reached)
+			}
+		})
+	}
+}
+
+func TestCSRFProtectSetsCookie(t *testing.T) {
+	protected := middleware.NewCSRFMiddleware().Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	t.Run("new visitor gets a token", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		protected.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/login", nil))
+
+		cookies := recorder.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != middleware.CSRFCookieName || cookies[0].Value == "" {
+			t.Fatalf("cookies = %+v, want one csrf cookie", cookies)
+		}
+	})
+
+	t.Run("existing token is kept", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/login", nil)
+		request.AddCookie(&http.Cookie{Name: middleware.CSRFCookieName, Value: testCSRFToken})
+
+		recorder := httptest.NewRecorder()
+		protected.ServeHTTP(recorder, request)
+
+		if cookies := recorder.Result().Cookies(); len(cookies) != 0 {
+			t.Fatalf("cookies = %+v, want none", cookies)
+		}
+	})
+
+	t.Run("post without cookie is rejected", func(t *testing.T) {
+		// Cookie baru dibuat di request ini, token yang dikirim tidak mungkin cocok
+		request := httptest.NewRequest(http.MethodPost, "/account/profile", nil)
+		request.Header.Set(middleware.CSRFHeaderName, testCSRFToken)
+
+		recorder := httptest.NewRecorder()
+		protected.ServeHTTP(recorder, request)
+
+		if recorder.Code != http.StatusForbidden {
+			t.Fatalf("status = %d, want %d", recorder.Code, http.StatusForbidden)
+		}
+	})
+}
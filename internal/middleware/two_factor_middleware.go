@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/service"
+)
+
+func NewTwoFactorMiddleware(twoFactorService service.TwoFactorService) TwoFactorMiddleware {
+	return &TwoFactorMiddlewareImpl{
+		TwoFactorService: twoFactorService,
+	}
+}
+
+type TwoFactorMiddleware interface {
+	RequireEnrollment(next http.Handler) http.Handler
+}
+
+type TwoFactorMiddlewareImpl struct {
+	TwoFactorService service.TwoFactorService
+}
+
+// RequireEnrollment sends users to the 2FA setup page while the admin requires 2FA for their
+// role and they have not enabled it. This middleware MUST run AFTER the Authenticate middleware.
+func (m *TwoFactorMiddlewareImpl) RequireEnrollment(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := r.Context().Value(CurrentUserKey).(domain.User)
+		if !ok {
+			slog.Error("user not found in context")
+
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		isRequired, err := m.TwoFactorService.IsEnrollmentRequired(r.Context(), user)
+		if err != nil {
+			slog.Error("failed when calling IsEnrollmentRequired service", "err", err)
+
+			if isAPIRequest(r) {
+				helper.WriteJSONError(w, http.StatusInternalServerError, "internal_error", "Internal Server Error")
+				return
+			}
+
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if isRequired && isAPIRequest(r) {
+			helper.WriteJSONError(w, http.StatusForbidden, "two_factor_required", "Enable two-factor authentication on the account page to use the API")
+			return
+		}
+		if isRequired {
+			http.Redirect(w, r, "/account/two-factor?status=required", http.StatusSeeOther)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
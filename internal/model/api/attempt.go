@@ -0,0 +1,66 @@
+package api
+
+import "time"
+
+// StudentExamResponse is an exam as students see it, without answer keys or join code
+type StudentExamResponse struct {
+	Id              string `json:"id"`
+	Name            string `json:"name"`
+	Year            int    `json:"year"`
+	DurationMinutes int    `json:"duration_minutes"`
+	TeacherName     string `json:"teacher_name"`
+}
+
+type AttemptResponse struct {
+	Id          string                    `json:"id"`
+	ExamId      string                    `json:"exam_id"`
+	IsCompleted bool                      `json:"is_completed"`
+	Score       int                       `json:"score"`
+	StartedAt   time.Time                 `json:"started_at"`
+	CompletedAt *time.Time                `json:"completed_at"`
+	Questions   []AttemptQuestionResponse `json:"questions"`
+}
+
+// AttemptQuestionResponse is a question in the order of the attempt, Answer is the saved answer of the student
+type AttemptQuestionResponse struct {
+	Id       string `json:"id"`
+	Number   int    `json:"number"`
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+type AnswerRequest struct {
+	Answer string `json:"answer"`
+}
+
+type AnswerResponse struct {
+	QuestionId string `json:"question_id"`
+	Answer     string `json:"answer"`
+}
+
+// ResultResponse is the best score of the student on one exam
+type ResultResponse struct {
+	ExamId      string `json:"exam_id"`
+	ExamName    string `json:"exam_name"`
+	Year        int    `json:"year"`
+	TeacherName string `json:"teacher_name"`
+	Score       int    `json:"score"`
+}
+
+type AttemptResultResponse struct {
+	AttemptId string                 `json:"attempt_id"`
+	ExamId    string                 `json:"exam_id"`
+	Score     int                    `json:"score"`
+	Answers   []AnswerResultResponse `json:"answers"`
+}
+
+type AnswerResultResponse struct {
+	QuestionId    string  `json:"question_id"`
+	Question      string  `json:"question"`
+	CorrectAnswer string  `json:"correct_answer"`
+	Answer        string  `json:"answer"`
+	Score         float64 `json:"score"`
+	MaxScore      float64 `json:"max_score"`
+	Similarity    float64 `json:"similarity"`
+	Feedback      string  `json:"feedback"`
+}
@@ -0,0 +1,64 @@
+package api
+
+import "time"
+
+type ExamCreateRequest struct {
+	Name            string            `json:"name"`
+	Year            int               `json:"year"`
+	DurationMinutes int               `json:"duration_minutes"`
+	Questions       []QuestionRequest `json:"questions"`
+}
+
+type ExamUpdateRequest struct {
+	Name              string `json:"name"`
+	Year              int    `json:"year"`
+	DurationMinutes   int    `json:"duration_minutes"`
+	ShuffleQuestions  bool   `json:"shuffle_questions"`
+	QuestionDrawCount int    `json:"question_draw_count"`
+	IsActive          bool   `json:"is_active"`
+}
+
+type ExamResponse struct {
+	Id                string    `json:"id"`
+	Name              string    `json:"name"`
+	Year              int       `json:"year"`
+	DurationMinutes   int       `json:"duration_minutes"`
+	TeacherName       string    `json:"teacher_name"`
+	IsActive          bool      `json:"is_active"`
+	IsPrivate         bool      `json:"is_private"`
+	JoinCode          string    `json:"join_code,omitempty"`
+	ShuffleQuestions  bool      `json:"shuffle_questions"`
+	QuestionDrawCount int       `json:"question_draw_count"`
+	Role              string    `json:"role,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// ExamDetailResponse is an exam together with its questions and answer keys
+type ExamDetailResponse struct {
+	ExamResponse
+	Questions []QuestionResponse `json:"questions"`
+}
+
+type QuestionRequest struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+type QuestionResponse struct {
+	Id       string `json:"id"`
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+	// FromBank is true when the question is a reference to the question bank, editing it changes the bank question
+	FromBank bool `json:"from_bank"`
+}
+
+// ExamResultResponse is the best attempt of one student on the exam
+type ExamResultResponse struct {
+	AttemptId   string     `json:"attempt_id"`
+	StudentId   string     `json:"student_id"`
+	StudentName string     `json:"student_name"`
+	Score       int        `json:"score"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
@@ -0,0 +1,18 @@
+package api
+
+// Response wraps every successful API response body
+type Response struct {
+	Data any `json:"data"`
+}
+
+// ErrorResponse is the body of every failed API response
+type ErrorResponse struct {
+	Error Error `json:"error"`
+}
+
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Fields maps a request field, e.g. "questions[0].answer", to what is wrong with it
+	Fields map[string]string `json:"fields,omitempty"`
+}
@@ -0,0 +1,45 @@
+package api
+
+import "time"
+
+// WebhookEvent is the body POSTed to webhook endpoints. Id is the same for every endpoint that
+// receives the event, so receivers can drop events they already handled.
+type WebhookEvent struct {
+	Id        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// ExamEventData is the data of exam.activated
+type ExamEventData struct {
+	ExamId    string `json:"exam_id"`
+	Name      string `json:"name"`
+	Year      int    `json:"year"`
+	TeacherId string `json:"teacher_id"`
+	IsActive  bool   `json:"is_active"`
+}
+
+// AttemptEventData is the data of attempt.submitted and attempt.scored
+type AttemptEventData struct {
+	AttemptId   string     `json:"attempt_id"`
+	ExamId      string     `json:"exam_id"`
+	StudentId   string     `json:"student_id"`
+	Score       int        `json:"score"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	// Regraded is true when the score comes from the teacher regrading the exam
+	Regraded bool `json:"regraded"`
+}
+
+// ExamRegradedData is the data of exam.regraded, sent after every attempt was scored again
+type ExamRegradedData struct {
+	ExamId           string `json:"exam_id"`
+	AttemptsRegraded int    `json:"attempts_regraded"`
+}
+
+// WebhookPingData is the data of webhook.ping
+type WebhookPingData struct {
+	EndpointId string `json:"endpoint_id"`
+	Message    string `json:"message"`
+}
@@ -0,0 +1,24 @@
+package domain
+
+// SystemStats is the system-wide summary shown on the admin dashboard
+type SystemStats struct {
+	TotalStudents   int
+	TotalTeachers   int
+	TotalAdmins     int
+	PendingTeachers int
+	DisabledUsers   int
+	TotalExams      int
+	ActiveExams     int
+	TotalAttempts   int
+	AverageScore    float64
+}
+
+type ExamStats struct {
+	ExamId       string
+	RoomName     string
+	TeacherName  string
+	IsActive     bool
+	AttemptCount int
+	StudentCount int
+	AverageScore float64
+}
@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+// Scopes of personal API tokens, a token can only call the API routes of its scopes
+const (
+	ScopeExamsRead     = "exams:read"
+	ScopeExamsWrite    = "exams:write"
+	ScopeAttemptsWrite = "attempts:write"
+	ScopeResultsRead   = "results:read"
+)
+
+// APIToken is a personal access token, only the hash of the secret is stored
+type APIToken struct {
+	Id     string
+	UserId string
+	Name   string
+	// TokenPrefix is the start of the token, shown so the user can tell tokens apart
+	TokenPrefix string
+	TokenHash   string
+	Scopes      []string
+	ExpiresAt   time.Time
+	LastUsedAt  *time.Time
+	RevokedAt   *time.Time
+	CreatedAt   time.Time
+}
+
+// APITokenUsage is one audited request made with a token
+type APITokenUsage struct {
+	TokenId   string
+	TokenName string
+	Method    string
+	Path      string
+	IPAddress string
+	UserAgent string
+	UsedAt    time.Time
+}
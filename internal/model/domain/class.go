@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+type Class struct {
+	Id          string
+	TeacherId   string
+	Name        string
+	JoinCode    string
+	MemberCount int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type ClassMember struct {
+	StudentId string
+	FullName  string
+	Email     string
+	JoinedAt  time.Time
+}
+
+// ClassInvitation is an invite for an email that has no student account yet
+type ClassInvitation struct {
+	Email     string
+	InvitedAt time.Time
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// Collaborator roles on an exam, each role includes the access of the roles after it
+const (
+	ExamRoleOwner  = "owner"
+	ExamRoleEditor = "editor"
+	ExamRoleGrader = "grader"
+	ExamRoleViewer = "viewer"
+)
+
+type ExamCollaborator struct {
+	ExamId    string
+	TeacherId string
+	FullName  string
+	Email     string
+	Role      string
+	CreatedAt time.Time
+}
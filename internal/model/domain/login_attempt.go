@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+type LoginAttempt struct {
+	Email       string
+	IPAddress   string
+	IsSuccess   bool
+	AttemptedAt time.Time
+}
+
+// LoginThrottle is the failure count used to decide lockouts and delays
+type LoginThrottle struct {
+	Failures int
+	// RetryAfter is how long until the oldest failure in the window stops counting
+	RetryAfter time.Duration
+}
+
+// LoginFailureSummary groups recent failed logins per email and IP for the admin console
+type LoginFailureSummary struct {
+	Email         string
+	IPAddress     string
+	Failures      int
+	LastAttemptAt time.Time
+}
@@ -0,0 +1,76 @@
+package domain
+
+import "time"
+
+// Score sync statuses of an LTI link user, "none" means no score was submitted yet
+const (
+	LTIScoreNone      = "none"
+	LTIScorePending   = "pending"
+	LTIScoreSucceeded = "succeeded"
+	LTIScoreFailed    = "failed"
+)
+
+// LTIPlatform is a learning platform registered by an admin, the URLs come from the tool
+// registration page of the platform
+type LTIPlatform struct {
+	Id           string
+	Name         string
+	Issuer       string
+	ClientId     string
+	AuthLoginURL string
+	AuthTokenURL string
+	JWKSURL      string
+	// DeploymentIds limits the accepted deployments, empty accepts every deployment
+	DeploymentIds []string
+	CreatedAt     time.Time
+	LinkCount     int
+}
+
+// LTILoginState ties a launch to the login initiation that started it
+type LTILoginState struct {
+	State      string
+	PlatformId string
+	Nonce      string
+	ExpiresAt  time.Time
+}
+
+// LTIResourceLink is a link in a course of the platform that opens one exam
+type LTIResourceLink struct {
+	Id             string
+	PlatformId     string
+	DeploymentId   string
+	ResourceLinkId string
+	ExamId         string
+	Title          string
+	ContextId      string
+	ContextTitle   string
+	// LineItemURL is the gradebook column scores are sent to, empty when the platform made none
+	LineItemURL string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// LTIScoreSync is the best score of a user on the exam of a link, waiting to be sent to the platform
+type LTIScoreSync struct {
+	Id           string
+	UserId       string
+	LTIUserId    string
+	ExamId       string
+	LineItemURL  string
+	Status       string
+	Attempts     int
+	LastError    string
+	LastScore    *int
+	SyncedAt     *time.Time
+	NextSyncAt   time.Time
+	PlatformName string
+	Issuer       string
+	ClientId     string
+	AuthTokenURL string
+	StudentName  string
+	LinkTitle    string
+	// Score and CompletedAt are read from the completed attempts when the sync is claimed, nil
+	// when the user has not completed one
+	Score       *int
+	CompletedAt *time.Time
+}
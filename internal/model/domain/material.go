@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+type Material struct {
+	Id          string
+	TeacherId   string
+	FileName    string
+	ContentHash string
+	SizeBytes   int64
+	StorageKey  string
+	ExamCount   int
+	CreatedAt   time.Time
+}
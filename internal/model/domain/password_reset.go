@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+type PasswordResetToken struct {
+	Id        string
+	UserId    string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+type BankQuestion struct {
+	Id         string
+	TeacherId  string
+	Question   string
+	Answer     string
+	Topic      string
+	Tags       []string
+	UsageCount int
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type BankTag struct {
+	Tag   string
+	Count int
+}
@@ -3,7 +3,12 @@ package domain
 import "time"
 
 type Session struct {
-	SessionId string
-	UserId    string
-	CreatedAt time.Time
+	// Id identifies the session on the sessions page, SessionId is the secret cookie value
+	Id         string
+	SessionId  string
+	UserId     string
+	UserAgent  string
+	IPAddress  string
+	LastSeenAt time.Time
+	CreatedAt  time.Time
 }
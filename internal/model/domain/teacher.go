@@ -3,19 +3,28 @@ package domain
 import "time"
 
 type QAItem struct {
-	Id       string
-	Question string `json:"question"`
-	Answer   string `json:"answer"`
-	ExamId   string
+	Id             string
+	Question       string `json:"question"`
+	Answer         string `json:"answer"`
+	ExamId         string
+	BankQuestionId string
 }
 
 type Exam struct {
-	Id        string
-	RoomName  string
-	Year      int
-	Duration  int
-	TeacherId string
-	IsActive  bool
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	Id                string
+	RoomName          string
+	Year              int
+	Duration          int
+	TeacherId         string
+	IsActive          bool
+	SourceURI         string
+	MaterialId        string
+	ShuffleQuestions  bool
+	QuestionDrawCount int
+	IsPrivate         bool
+	JoinCode          string
+	TeacherName       string
+	Role              string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
 }
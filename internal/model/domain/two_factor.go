@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// TwoFactor is the TOTP state of a user. Secret is set once enrollment started, IsEnabled only
+// after the first code was confirmed.
+type TwoFactor struct {
+	UserId    string
+	Secret    string
+	IsEnabled bool
+	// LastCounter is the time step of the last accepted code, a code is never accepted twice
+	LastCounter int64
+}
+
+// TwoFactorChallenge is the pending second login step, the session is created once it is passed
+type TwoFactorChallenge struct {
+	Id        string
+	UserId    string
+	TokenHash string
+	Attempts  int
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
@@ -3,13 +3,25 @@ package domain
 import "time"
 
 type User struct {
-	Id        string
-	Email     string
-	FullName  string
-	Password  string
-	Role      string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	Id         string
+	Email      string
+	FullName   string
+	Password   string
+	Role       string
+	IsDisabled bool
+	IsApproved bool
+	// IsEmailVerified is true once the user opened the link from the verification email
+	IsEmailVerified bool
+	// TwoFactorEnabled is true when login needs a TOTP code after the password
+	TwoFactorEnabled bool
+	// PendingEmail is the new email waiting for confirmation, empty when there is none
+	PendingEmail string
+	// MustChangePassword is true while the password is a temporary one given by an import
+	MustChangePassword bool
+	// TemporaryPasswordExpired is true when the temporary password can no longer be used to log in
+	TemporaryPasswordExpired bool
+	CreatedAt                time.Time
+	UpdatedAt                time.Time
 }
 
 type EssayCorrection struct {
@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// UserIdentity links a user to an account at an OpenID Connect identity provider
+type UserIdentity struct {
+	Id          string
+	UserId      string
+	Issuer      string
+	Subject     string
+	Email       string
+	LastLoginAt time.Time
+	CreatedAt   time.Time
+}
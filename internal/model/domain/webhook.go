@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+// Events sent to webhook endpoints
+const (
+	WebhookEventExamActivated    = "exam.activated"
+	WebhookEventAttemptSubmitted = "attempt.submitted"
+	WebhookEventAttemptScored    = "attempt.scored"
+	WebhookEventExamRegraded     = "exam.regraded"
+	// WebhookEventPing is only sent by the "send test event" button, endpoints can not subscribe to it
+	WebhookEventPing = "webhook.ping"
+)
+
+// Statuses of a webhook delivery
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookEndpoint receives the events it subscribed to. Endpoints of teachers only get the events of
+// exams they collaborate on, attempt and score events only as grader or above. Endpoints of admins
+// get every event.
+type WebhookEndpoint struct {
+	Id     string
+	UserId string
+	URL    string
+	// Secret signs every payload, receivers use it to check the X-SayGenFix-Signature header
+	Secret    string
+	Events    []string
+	IsActive  bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// WebhookDelivery is one event queued for one endpoint
+type WebhookDelivery struct {
+	Id             string
+	EndpointId     string
+	EndpointURL    string
+	EndpointSecret string
+	Event          string
+	Payload        string
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	LastAttemptAt  *time.Time
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+}
@@ -0,0 +1,5 @@
+package web
+
+type AdminUserRoleRequest struct {
+	Role string `validate:"required,oneof=student teacher admin"`
+}
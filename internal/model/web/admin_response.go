@@ -0,0 +1,23 @@
+package web
+
+import "github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+
+type AdminDashboardResponse struct {
+	User            domain.User
+	Stats           domain.SystemStats
+	Exams           []domain.ExamStats
+	PendingTeachers []domain.User
+	LoginFailures   []domain.LoginFailureSummary
+	// RequireTeacherTwoFactor is the admin setting that makes 2FA mandatory for teachers
+	RequireTeacherTwoFactor bool
+	FlashMessage            string
+}
+
+type AdminUsersResponse struct {
+	User         domain.User
+	Users        []domain.User
+	Query        string
+	SelectedRole string
+	FlashMessage string
+	ErrorMessage string
+}
@@ -0,0 +1,16 @@
+package web
+
+type APITokenCreateRequest struct {
+	Name          string   `validate:"required,max=100"`
+	Scopes        []string `validate:"required,min=1,dive,oneof=exams:read exams:write attempts:write results:read"`
+	ExpiresInDays int      `validate:"oneof=7 30 90 365"`
+}
+
+// APITokenAuthRequest is a bearer token with the request it was sent with, kept for the audit trail
+type APITokenAuthRequest struct {
+	Token     string
+	Method    string
+	Path      string
+	IPAddress string
+	UserAgent string
+}
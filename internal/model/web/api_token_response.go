@@ -0,0 +1,37 @@
+package web
+
+import (
+	"time"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+type APITokenScopeOption struct {
+	Value string
+	Label string
+}
+
+type APITokenResponse struct {
+	Id          string
+	Name        string
+	TokenPrefix string
+	Scopes      []string
+	ExpiresAt   time.Time
+	LastUsedAt  *time.Time
+	CreatedAt   time.Time
+	IsRevoked   bool
+	IsExpired   bool
+}
+
+type APITokenPageResponse struct {
+	User         domain.User
+	Tokens       []APITokenResponse
+	Usages       []domain.APITokenUsage
+	ScopeOptions []APITokenScopeOption
+	// NewToken is only filled right after the token is created, it is not shown again
+	NewToken     string
+	FlashMessage string
+	ErrorMessage string
+	// FieldErrors maps a field of the create form to the message shown under the input
+	FieldErrors map[string]string
+}
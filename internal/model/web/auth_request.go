@@ -3,4 +3,8 @@ package web
 type LoginRequest struct {
 	Email    string `validate:"required,email,max=255"`
 	Password string `validate:"required,min=6,max=255"`
+	// IPAddress is filled by the handler for login throttling
+	IPAddress string
+	// UserAgent is filled by the handler for the sessions page
+	UserAgent string
 }
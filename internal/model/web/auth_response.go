@@ -10,3 +10,11 @@ type LoginResponse struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
+
+type LoginPageResponse struct {
+	FlashMessage           string
+	ErrorMessage           string
+	ShowResendVerification bool
+	// SSOProviderName shows the single sign-on button when it is not empty
+	SSOProviderName string
+}
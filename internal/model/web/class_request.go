@@ -0,0 +1,9 @@
+package web
+
+type ClassCreateRequest struct {
+	Name string `validate:"required,max=255"`
+}
+
+type ClassInviteRequest struct {
+	Email string `validate:"required,email,max=255"`
+}
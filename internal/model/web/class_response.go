@@ -0,0 +1,29 @@
+package web
+
+import "github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+
+type TeacherClassesResponse struct {
+	User         domain.User
+	Classes      []domain.Class
+	FlashMessage string
+	ErrorMessage string
+}
+
+type TeacherClassDetailResponse struct {
+	User         domain.User
+	Class        domain.Class
+	Members      []domain.ClassMember
+	Invitations  []domain.ClassInvitation
+	Exams        []domain.Exam
+	FlashMessage string
+	ErrorMessage string
+}
+
+// ExamClassesResponse lists the teacher's classes with the ones the exam is assigned to
+type ExamClassesResponse struct {
+	ExamId           string
+	Classes          []domain.Class
+	AssignedClassIds map[string]bool
+	IsPrivate        bool
+	CanEdit          bool
+}
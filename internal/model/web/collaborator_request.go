@@ -0,0 +1,10 @@
+package web
+
+type ExamCollaboratorRequest struct {
+	Email string `validate:"required,email,max=255"`
+	Role  string `validate:"required,oneof=owner editor grader viewer"`
+}
+
+type ExamCollaboratorRoleRequest struct {
+	Role string `validate:"required,oneof=owner editor grader viewer"`
+}
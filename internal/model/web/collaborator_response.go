@@ -0,0 +1,11 @@
+package web
+
+import "github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+
+type ExamCollaboratorsResponse struct {
+	ExamId        string
+	TeacherId     string
+	Collaborators []domain.ExamCollaborator
+	CanManage     bool
+	ErrorMessage  string
+}
@@ -0,0 +1,5 @@
+package web
+
+type ResendVerificationRequest struct {
+	Email string `validate:"required,email,max=255"`
+}
@@ -0,0 +1,6 @@
+package web
+
+type EmailVerificationPageResponse struct {
+	FlashMessage string
+	ErrorMessage string
+}
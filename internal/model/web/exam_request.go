@@ -0,0 +1,47 @@
+package web
+
+// ExamCreateRequest creates an exam from questions written by the teacher instead of generating them.
+// The json names are the field names reported in validation errors.
+type ExamCreateRequest struct {
+	RoomName  string                `json:"name" validate:"required,max=255"`
+	Year      int                   `json:"year" validate:"required,min=2000,max=2100"`
+	Duration  int                   `json:"duration_minutes" validate:"required,min=1,max=600"`
+	Questions []QuestionSaveRequest `json:"questions" validate:"max=100,dive"`
+}
+
+type ExamUpdateRequest struct {
+	RoomName          string `json:"name" validate:"required,max=255"`
+	Year              int    `json:"year" validate:"required,min=2000,max=2100"`
+	Duration          int    `json:"duration_minutes" validate:"required,min=1,max=600"`
+	ShuffleQuestions  bool   `json:"shuffle_questions"`
+	QuestionDrawCount int    `json:"question_draw_count" validate:"min=0"`
+	IsActive          bool   `json:"is_active"`
+}
+
+// ExamEditRequest is the edit exam form, Questions are the exam's own questions shown on the form
+type ExamEditRequest struct {
+	RoomName          string `validate:"required,max=255"`
+	Year              int    `validate:"required,min=2000,max=2100"`
+	Duration          int    `validate:"required,min=1,max=600"`
+	ShuffleQuestions  bool
+	QuestionDrawCount int                   `validate:"min=0"`
+	Questions         []QuestionEditRequest `validate:"dive"`
+}
+
+type QuestionEditRequest struct {
+	Id       string `validate:"required"`
+	Question string `validate:"required,max=5000"`
+	Answer   string `validate:"required,max=5000"`
+}
+
+type QuestionSaveRequest struct {
+	Question string `json:"question" validate:"required,max=5000"`
+	Answer   string `json:"answer" validate:"required,max=5000"`
+}
+
+// AttemptAnswerRequest saves the answer of one question while the attempt is running
+type AttemptAnswerRequest struct {
+	AttemptId  string
+	QuestionId string
+	Answer     string `json:"answer" validate:"max=10000"`
+}
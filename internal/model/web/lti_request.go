@@ -0,0 +1,40 @@
+package web
+
+// LTIPlatformCreateRequest registers a platform, the values come from its tool registration page
+type LTIPlatformCreateRequest struct {
+	Name         string `validate:"required,max=255"`
+	Issuer       string `validate:"required,max=255,http_url"`
+	ClientId     string `validate:"required,max=255"`
+	AuthLoginURL string `validate:"required,max=2048,http_url"`
+	AuthTokenURL string `validate:"required,max=2048,http_url"`
+	JWKSURL      string `validate:"required,max=2048,http_url"`
+	// DeploymentIds is optional, empty accepts every deployment of the platform
+	DeploymentIds []string `validate:"max=20,dive,required,max=255"`
+}
+
+// LTILoginRequest is the third-party login initiation sent by the platform
+type LTILoginRequest struct {
+	Issuer        string `validate:"required,max=255"`
+	LoginHint     string `validate:"required,max=2048"`
+	TargetLinkURI string `validate:"max=2048"`
+	MessageHint   string `validate:"max=4096"`
+	// ClientId and DeploymentId are optional, they pick the registration when the issuer has more than one
+	ClientId     string `validate:"max=255"`
+	DeploymentId string `validate:"max=255"`
+}
+
+// LTILaunchRequest is the form the platform posts to the launch url
+type LTILaunchRequest struct {
+	IDToken string `validate:"required,max=65536"`
+	State   string `validate:"required,max=100"`
+	// IPAddress is filled by the handler for the login history
+	IPAddress string
+	// UserAgent is filled by the handler for the sessions page
+	UserAgent string
+}
+
+// LTIDeepLinkRequest is the exam picked on the deep linking page
+type LTIDeepLinkRequest struct {
+	Token  string `validate:"required,max=8192"`
+	ExamId string `validate:"required,uuid"`
+}
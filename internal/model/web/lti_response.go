@@ -0,0 +1,46 @@
+package web
+
+import "github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+
+// LTIToolURLs are entered at the platform when registering the tool
+type LTIToolURLs struct {
+	LoginURL  string
+	LaunchURL string
+	JWKSURL   string
+}
+
+type LTIPlatformPageResponse struct {
+	User       domain.User
+	Platforms  []domain.LTIPlatform
+	ScoreSyncs []domain.LTIScoreSync
+	Tool       LTIToolURLs
+	// Form keeps the values of a rejected create form
+	Form         LTIPlatformCreateRequest
+	FlashMessage string
+	ErrorMessage string
+	// FieldErrors maps a field of the create form to the message shown under the input
+	FieldErrors map[string]string
+}
+
+// LTILaunchResult is either a session with the page to open, or the deep linking page
+type LTILaunchResult struct {
+	User         domain.User
+	SessionId    string
+	RedirectPath string
+	DeepLink     *LTIDeepLinkPageResponse
+}
+
+type LTIDeepLinkPageResponse struct {
+	// Token authenticates the picker form, the page runs in an iframe of the platform where the
+	// session cookie is not sent
+	Token        string
+	PlatformName string
+	ContextTitle string
+	Exams        []domain.Exam
+}
+
+// LTIAutoPostResponse is a form the browser submits right away, used to return to the platform
+type LTIAutoPostResponse struct {
+	URL    string
+	Fields map[string]string
+}
@@ -0,0 +1,12 @@
+package web
+
+// OIDCCallbackRequest carries the authorization code and the values kept since the login redirect
+type OIDCCallbackRequest struct {
+	Code     string `validate:"required,max=2048"`
+	Verifier string `validate:"required,max=255"`
+	Nonce    string `validate:"required,max=255"`
+	// IPAddress is filled by the handler for the login history
+	IPAddress string
+	// UserAgent is filled by the handler for the sessions page
+	UserAgent string
+}
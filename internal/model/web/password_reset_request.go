@@ -0,0 +1,12 @@
+package web
+
+type ForgotPasswordRequest struct {
+	Email string `validate:"required,email,max=255"`
+	// IPAddress is filled by the handler for request throttling
+	IPAddress string
+}
+
+type ResetPasswordRequest struct {
+	Token    string `validate:"required,max=255"`
+	Password string `validate:"required,min=6,max=255"`
+}
@@ -0,0 +1,7 @@
+package web
+
+type PasswordResetPageResponse struct {
+	Token        string
+	FlashMessage string
+	ErrorMessage string
+}
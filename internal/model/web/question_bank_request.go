@@ -0,0 +1,17 @@
+package web
+
+type BankQuestionCreateRequest struct {
+	Question string   `validate:"required,max=5000"`
+	Answer   string   `validate:"required,max=5000"`
+	Topic    string   `validate:"max=100"`
+	Tags     []string `validate:"max=10,dive,min=1,max=50"`
+}
+
+// ComposeExamRequest builds an exam from picked bank questions plus a random draw per tag
+type ComposeExamRequest struct {
+	RoomName        string         `validate:"required,max=255"`
+	Year            int            `validate:"required,min=2000,max=2100"`
+	Duration        int            `validate:"required,min=1,max=600"`
+	BankQuestionIds []string       `validate:"dive,uuid"`
+	TagDraws        map[string]int `validate:"dive,keys,min=1,max=50,endkeys,min=0,max=100"`
+}
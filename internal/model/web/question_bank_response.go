@@ -0,0 +1,14 @@
+package web
+
+import "github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+
+type TeacherQuestionBankResponse struct {
+	User          domain.User
+	Questions     []domain.BankQuestion
+	Tags          []domain.BankTag
+	Topics        []string
+	SelectedTag   string
+	SelectedTopic string
+	FlashMessage  string
+	ErrorMessage  string
+}
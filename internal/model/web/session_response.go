@@ -0,0 +1,25 @@
+package web
+
+import (
+	"time"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+type SessionResponse struct {
+	Id string
+	// Device is a short description like "Chrome di Windows"
+	Device     string
+	UserAgent  string
+	IPAddress  string
+	LastSeenAt time.Time
+	CreatedAt  time.Time
+	IsCurrent  bool
+}
+
+type SessionPageResponse struct {
+	User         domain.User
+	Sessions     []SessionResponse
+	FlashMessage string
+	ErrorMessage string
+}
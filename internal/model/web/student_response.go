@@ -11,6 +11,12 @@ type StudentDashboardResponse struct {
 	Exams    []domain.Exam
 	Years    []int
 	Teachers map[string]domain.User
+	Classes  []domain.Class
+	// PublicExams are the public exams not joined yet, only filled when BrowsePublic is set
+	BrowsePublic bool
+	PublicExams  []domain.Exam
+	FlashMessage string
+	ErrorMessage string
 }
 
 type ExamPageData struct {
@@ -1,11 +1,30 @@
 package web
 
-import "github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+import (
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
 
 type TeacherDashboardResponse struct {
-	User  domain.User
-	Exams []domain.Exam
-	Years []int
+	User            domain.User
+	Exams           []domain.Exam
+	Years           []int
+	Classes         []domain.Class
+	SelectedClassId string
+	FlashMessage    string
+	// DuplicateWarnings is only filled right after an exam was generated
+	DuplicateWarnings DuplicateWarningsPanel
+}
+
+type TeacherUploadResponse struct {
+	User               domain.User
+	Materials          []domain.Material
+	SelectedMaterialId string
+}
+
+type TeacherMaterialsResponse struct {
+	User      domain.User
+	Materials []domain.Material
 }
 
 type ExamAttemptsWithStudentName struct {
@@ -17,14 +36,55 @@ type ExamAttemptsWithStudentName struct {
 }
 
 type TeacherCheckExamResponse struct {
-	User         domain.User
-	Exam         domain.Exam
-	FlashMessage string
-	ExamAttempts []ExamAttemptsWithStudentName
+	User            domain.User
+	Exam            domain.Exam
+	FlashMessage    string
+	ExamAttempts    []ExamAttemptsWithStudentName
+	ExamClasses     ExamClassesResponse
+	Collaborators   ExamCollaboratorsResponse
+	SelectedClassId string
+	// DuplicateWarnings is only filled right after the exam was edited
+	DuplicateWarnings DuplicateWarningsPanel
 }
 
 type TeacherEditExamResponse struct {
 	User               domain.User
 	Exam               domain.Exam
 	QuestionAndAnswers []domain.QAItem
+	DuplicateWarnings  DuplicateWarningsPanel
+}
+
+// DuplicateWarningsPanel is rendered by the duplicate-warnings partial. InPage is true on the
+// edit page itself, where the links jump to the question cards instead of opening the edit page.
+type DuplicateWarningsPanel struct {
+	Exam     domain.Exam
+	Warnings []DuplicateWarning
+	InPage   bool
+}
+
+// DuplicateWarning flags a question that is the same as or very close to another question.
+// MatchRoomName is empty when the match is in the same exam.
+type DuplicateWarning struct {
+	QuestionId      string
+	Question        string
+	MatchQuestionId string
+	MatchExamId     string
+	MatchRoomName   string
+	MatchQuestion   string
+	Similarity      int
+}
+
+// QuestionCardResponse is one question on the edit page, Role is the teacher's role on the exam
+type QuestionCardResponse struct {
+	Number   int
+	Question domain.QAItem
+	Role     string
+}
+
+type QuestionProposalResponse struct {
+	Number       int
+	Original     domain.QAItem
+	Proposed     domain.QAItem
+	QuestionDiff []helper.DiffSegment
+	AnswerDiff   []helper.DiffSegment
 }
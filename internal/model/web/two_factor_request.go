@@ -0,0 +1,22 @@
+package web
+
+// TwoFactorCodeRequest carries a TOTP code from the authenticator app
+type TwoFactorCodeRequest struct {
+	Code string `validate:"required,max=20"`
+}
+
+type TwoFactorDisableRequest struct {
+	Password string `validate:"required,max=255"`
+	// Code is a TOTP code or an unused recovery code
+	Code string `validate:"required,max=20"`
+}
+
+type TwoFactorLoginRequest struct {
+	Token string `validate:"required,max=255"`
+	// Code is a TOTP code or an unused recovery code
+	Code string `validate:"required,max=20"`
+	// IPAddress is filled by the handler for login throttling
+	IPAddress string
+	// UserAgent is filled by the handler for the sessions page
+	UserAgent string
+}
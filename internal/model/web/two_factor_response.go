@@ -0,0 +1,26 @@
+package web
+
+import "github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+
+type TwoFactorStatus struct {
+	IsEnabled bool
+	// IsRequired is true when the admin requires 2FA for the role of the user
+	IsRequired        bool
+	RecoveryCodesLeft int
+	// PendingSecret and ProvisioningURI are set while enrollment waits for the first code
+	PendingSecret   string
+	ProvisioningURI string
+}
+
+type TwoFactorPageResponse struct {
+	User   domain.User
+	Status TwoFactorStatus
+	// RecoveryCodes is only filled right after they are generated, they are not shown again
+	RecoveryCodes []string
+	FlashMessage  string
+	ErrorMessage  string
+}
+
+type TwoFactorLoginPageResponse struct {
+	ErrorMessage string
+}
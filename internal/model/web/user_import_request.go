@@ -0,0 +1,9 @@
+package web
+
+// UserImportRow is one CSV row, validated with the same rules as RegisterUserRequest
+type UserImportRow struct {
+	Email    string `validate:"required,email,max=255"`
+	FullName string `validate:"required,min=3,max=255,validName"`
+	Role     string `validate:"required,oneof=student teacher"`
+	Class    string `validate:"max=255"`
+}
@@ -0,0 +1,28 @@
+package web
+
+import "github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+
+type UserImportResult struct {
+	Line              int
+	Email             string
+	FullName          string
+	Role              string
+	Class             string
+	Status            string
+	Message           string
+	TemporaryPassword string
+	RegistrationLink  string
+}
+
+type UserImportResponse struct {
+	User         domain.User
+	ActionPath   string
+	Classes      []domain.Class
+	Mode         string
+	DryRun       bool
+	CSVContent   string
+	Results      []UserImportResult
+	TotalOk      int
+	TotalFailed  int
+	ErrorMessage string
+}
@@ -6,3 +6,23 @@ type RegisterUserRequest struct {
 	Password string `validate:"required,min=6,max=255"`
 	Role     string `validate:"required,oneof=student teacher"`
 }
+
+type UpdateProfileRequest struct {
+	FullName string `validate:"required,min=3,max=255,validName"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `validate:"required,max=255"`
+	NewPassword     string `validate:"required,min=6,max=255"`
+	ConfirmPassword string `validate:"required,eqfield=NewPassword"`
+}
+
+type ChangeEmailRequest struct {
+	Email string `validate:"required,email,max=255"`
+	// Password confirms that the owner of the session is making the change
+	Password string `validate:"required,max=255"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `validate:"required,max=255"`
+}
@@ -1,6 +1,10 @@
 package web
 
-import "time"
+import (
+	"time"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
 
 type RegisterUserResponse struct {
 	Id        int
@@ -10,3 +14,27 @@ type RegisterUserResponse struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
+
+// RegisterFormResponse pre-fills the register form, e.g. from a class invitation link
+type RegisterFormResponse struct {
+	Email    string
+	FullName string
+	Role     string
+}
+
+type RegisterSuccessResponse struct {
+	IsPendingApproval bool
+}
+
+type AccountPageResponse struct {
+	User         domain.User
+	FlashMessage string
+	ErrorMessage string
+	// FieldErrors maps "form.Field" like "password.NewPassword" to the message shown under the input
+	FieldErrors map[string]string
+	// FullName and Email keep what was typed when the form is shown again with errors
+	FullName string
+	Email    string
+	// SharedExamCount is the number of exams that keep the account from being deleted
+	SharedExamCount int
+}
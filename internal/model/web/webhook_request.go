@@ -0,0 +1,6 @@
+package web
+
+type WebhookEndpointCreateRequest struct {
+	URL    string   `validate:"required,max=2048,http_url"`
+	Events []string `validate:"required,min=1,dive,oneof=exam.activated attempt.submitted attempt.scored exam.regraded"`
+}
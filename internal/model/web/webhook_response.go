@@ -0,0 +1,21 @@
+package web
+
+import "github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+
+type WebhookEventOption struct {
+	Value string
+	Label string
+}
+
+type WebhookPageResponse struct {
+	User         domain.User
+	Endpoints    []domain.WebhookEndpoint
+	Deliveries   []domain.WebhookDelivery
+	EventOptions []WebhookEventOption
+	// NewSecret is only filled right after the endpoint is created or its secret is rotated
+	NewSecret    string
+	FlashMessage string
+	ErrorMessage string
+	// FieldErrors maps a field of the create form to the message shown under the input
+	FieldErrors map[string]string
+}
@@ -0,0 +1,95 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/jose"
+)
+
+// ErrIDTokenInvalid is returned when the signature or a required claim of the ID token does not check out
+var ErrIDTokenInvalid = errors.New("id token is invalid")
+
+// Claims are the ID token claims used for login, Raw keeps every claim for role mapping
+type Claims struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Raw           map[string]any
+}
+
+// Strings returns a claim as a list, identity providers send groups as a string or an array
+func (claims Claims) Strings(name string) []string {
+	return jose.Strings(claims.Raw, name)
+}
+
+// verifyIDToken checks the RS256 or ES256 signature against the JWKS of the issuer, then the
+// issuer, audience, expiry and nonce claims
+func (provider *Provider) verifyIDToken(ctx context.Context, rawIDToken, nonce string) (Claims, error) {
+	raw, err := jose.Verify(ctx, rawIDToken, provider.signingKey)
+	if err != nil {
+		if errors.Is(err, jose.ErrTokenInvalid) {
+			return Claims{}, fmt.Errorf("%w: %w", ErrIDTokenInvalid, err)
+		}
+		return Claims{}, err
+	}
+
+	claims := Claims{Raw: raw}
+	claims.Issuer, _ = raw["iss"].(string)
+	claims.Subject, _ = raw["sub"].(string)
+	claims.Email, _ = raw["email"].(string)
+	claims.Name, _ = raw["name"].(string)
+	// Beberapa IdP mengirim email_verified sebagai string
+	switch verified := raw["email_verified"].(type) {
+	case bool:
+		claims.EmailVerified = verified
+	case string:
+		claims.EmailVerified = verified == "true"
+	}
+
+	discovery, err := provider.discover(ctx)
+	if err != nil {
+		return Claims{}, err
+	}
+	if claims.Issuer != discovery.Issuer {
+		return Claims{}, fmt.Errorf("%w: unexpected issuer %q", ErrIDTokenInvalid, claims.Issuer)
+	}
+	if claims.Subject == "" {
+		return Claims{}, fmt.Errorf("%w: missing subject", ErrIDTokenInvalid)
+	}
+	if !slices.Contains(claims.Strings("aud"), provider.options.ClientID) {
+		return Claims{}, fmt.Errorf("%w: unexpected audience", ErrIDTokenInvalid)
+	}
+
+	if err := jose.CheckExpiry(raw); err != nil {
+		return Claims{}, fmt.Errorf("%w: %w", ErrIDTokenInvalid, err)
+	}
+
+	if tokenNonce, _ := raw["nonce"].(string); tokenNonce != nonce {
+		return Claims{}, fmt.Errorf("%w: nonce mismatch", ErrIDTokenInvalid)
+	}
+
+	return claims, nil
+}
+
+// signingKey looks up the key by id in the JWKS named by the discovery document
+func (provider *Provider) signingKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	discovery, err := provider.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	provider.mu.Lock()
+	if provider.keys == nil {
+		provider.keys = jose.NewRemoteKeySet(discovery.JWKSURI, provider.httpClient)
+	}
+	keys := provider.keys
+	provider.mu.Unlock()
+
+	return keys.Key(ctx, kid)
+}
@@ -0,0 +1,183 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/config"
+	"github.com/mhaatha/go-template-saygenfix/internal/jose"
+	"golang.org/x/oauth2"
+)
+
+// ErrProviderNotConfigured is returned when OIDC_ISSUER_URL or OIDC_CLIENT_ID is empty
+var ErrProviderNotConfigured = errors.New("oidc provider is not configured")
+
+// Options configures the relying party, RedirectURL must be registered at the identity provider
+type Options struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Provider runs the authorization code flow with PKCE against one OpenID Connect issuer. The
+// discovery document and signing keys are fetched on first use, so the app still starts while
+// the identity provider is unreachable.
+type Provider struct {
+	options    Options
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	discovery *discoveryDocument
+	keys      *jose.RemoteKeySet
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewProviderFromConfig returns nil without an error when single sign-on is not configured
+func NewProviderFromConfig(cfg *config.Config) (*Provider, error) {
+	if cfg.OIDCIssuerURL == "" && cfg.OIDCClientID == "" {
+		return nil, nil
+	}
+
+	return NewProvider(Options{
+		IssuerURL:    cfg.OIDCIssuerURL,
+		ClientID:     cfg.OIDCClientID,
+		ClientSecret: cfg.OIDCClientSecret,
+		RedirectURL:  cfg.OIDCRedirectURL,
+		Scopes:       cfg.OIDCScopes,
+	})
+}
+
+func NewProvider(options Options) (*Provider, error) {
+	if options.IssuerURL == "" || options.ClientID == "" {
+		return nil, ErrProviderNotConfigured
+	}
+	if options.RedirectURL == "" {
+		return nil, errors.New("oidc redirect url is required")
+	}
+	if len(options.Scopes) == 0 {
+		options.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	return &Provider{
+		options:    options,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// AuthCodeURL returns the URL of the identity provider login page. The caller keeps state,
+// nonce and verifier until the callback.
+func (provider *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	oauthConfig, err := provider.oauthConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return oauthConfig.AuthCodeURL(
+		state,
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	), nil
+}
+
+// Exchange redeems the authorization code and returns the claims of the verified ID token
+func (provider *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (Claims, error) {
+	oauthConfig, err := provider.oauthConfig(ctx)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, provider.httpClient)
+	token, err := oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return Claims{}, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return Claims{}, errors.New("token response has no id_token")
+	}
+
+	return provider.verifyIDToken(ctx, rawIDToken, nonce)
+}
+
+// NewVerifier returns a random PKCE code verifier
+func NewVerifier() string {
+	return oauth2.GenerateVerifier()
+}
+
+func (provider *Provider) oauthConfig(ctx context.Context) (oauth2.Config, error) {
+	discovery, err := provider.discover(ctx)
+	if err != nil {
+		return oauth2.Config{}, err
+	}
+
+	return oauth2.Config{
+		ClientID:     provider.options.ClientID,
+		ClientSecret: provider.options.ClientSecret,
+		RedirectURL:  provider.options.RedirectURL,
+		Scopes:       provider.options.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  discovery.AuthorizationEndpoint,
+			TokenURL: discovery.TokenEndpoint,
+		},
+	}, nil
+}
+
+// discover fetches the discovery document once, a failed fetch is retried on the next login
+func (provider *Provider) discover(ctx context.Context) (*discoveryDocument, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if provider.discovery != nil {
+		return provider.discovery, nil
+	}
+
+	discovery := &discoveryDocument{}
+	if err := provider.getJSON(ctx, provider.options.IssuerURL+"/.well-known/openid-configuration", discovery); err != nil {
+		return nil, fmt.Errorf("failed to fetch oidc discovery document: %w", err)
+	}
+
+	if strings.TrimSuffix(discovery.Issuer, "/") != provider.options.IssuerURL {
+		return nil, fmt.Errorf("oidc issuer mismatch, expected %q got %q", provider.options.IssuerURL, discovery.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("oidc discovery document is missing an endpoint")
+	}
+
+	provider.discovery = discovery
+	return discovery, nil
+}
+
+func (provider *Provider) getJSON(ctx context.Context, url string, target any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := provider.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
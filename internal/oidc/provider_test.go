@@ -0,0 +1,282 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/jose"
+)
+
+const (
+	testClientId     = "saygenfix"
+	testClientSecret = "rahasia"
+	testRedirectURL  = "http://app.test/auth/oidc/callback"
+	testKeyId        = "key-1"
+)
+
+// fakeIssuer is an OpenID Connect provider that issues one code per authorization and checks
+// the PKCE verifier when the code is redeemed
+type fakeIssuer struct {
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	signKey *rsa.PrivateKey
+	claims  map[string]any
+
+	mu    sync.Mutex
+	codes map[string]authorization
+}
+
+type authorization struct {
+	challenge string
+	nonce     string
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	issuer := &fakeIssuer{key: key, signKey: key, claims: map[string]any{}, codes: map[string]authorization{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer.server.URL,
+			"authorization_endpoint": issuer.server.URL + "/authorize",
+			"token_endpoint":         issuer.server.URL + "/token",
+			"jwks_uri":               issuer.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.KeySet{Keys: []jose.JSONWebKey{jose.PublicJSONWebKey(&issuer.key.PublicKey, testKeyId)}})
+	})
+	mux.HandleFunc("POST /token", issuer.token)
+
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+
+	return issuer
+}
+
+// authorize stands in for the login page, it keeps the challenge and nonce of the auth code URL
+func (issuer *fakeIssuer) authorize(t *testing.T, authCodeURL string) (code, state string) {
+	t.Helper()
+
+	parsed, err := url.Parse(authCodeURL)
+	if err != nil {
+		t.Fatalf("parse auth code url: %v", err)
+	}
+	query := parsed.Query()
+
+	if parsed.Path != "/authorize" || query.Get("client_id") != testClientId || query.Get("redirect_uri") != testRedirectURL {
+		t.Fatalf("unexpected auth code url %s", authCodeURL)
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("auth code url has no S256 challenge: %s", authCodeURL)
+	}
+
+	issuer.mu.Lock()
+	defer issuer.mu.Unlock()
+	code = "code-" + query.Get("state")
+	issuer.codes[code] = authorization{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+
+	return code, query.Get("state")
+}
+
+func (issuer *fakeIssuer) token(w http.ResponseWriter, r *http.Request) {
+	clientId, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientId, clientSecret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	if clientId != testClientId || clientSecret != testClientSecret {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+
+	issuer.mu.Lock()
+	auth, ok := issuer.codes[r.PostFormValue("code")]
+	delete(issuer.codes, r.PostFormValue("code"))
+	issuer.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(challenge[:]) != auth.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	claims := map[string]any{
+		"iss":   issuer.server.URL,
+		"sub":   "user-1",
+		"aud":   testClientId,
+		"exp":   time.Now().Add(5 * time.Minute).Unix(),
+		"nonce": auth.nonce,
+	}
+	for name, value := range issuer.claims {
+		claims[name] = value
+	}
+
+	idToken, err := jose.Sign(issuer.signKey, testKeyId, claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"access_token": "access", "token_type": "Bearer", "id_token": idToken})
+}
+
+func newTestProvider(t *testing.T, issuer *fakeIssuer) *Provider {
+	t.Helper()
+
+	provider, err := NewProvider(Options{
+		IssuerURL:    issuer.server.URL,
+		ClientID:     testClientId,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+	})
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	return provider
+}
+
+// startLogin sends the user to the issuer like the login handler does and returns the code of
+// the callback with the verifier kept in the login cookie
+func startLogin(t *testing.T, issuer *fakeIssuer) (*Provider, string, string) {
+	t.Helper()
+
+	provider := newTestProvider(t, issuer)
+	verifier := NewVerifier()
+
+	authCodeURL, err := provider.AuthCodeURL(context.Background(), "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code, state := issuer.authorize(t, authCodeURL)
+	if state != "state-1" {
+		t.Fatalf("state = %q, want state-1", state)
+	}
+
+	return provider, code, verifier
+}
+
+func TestProviderLogin(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	issuer.claims = map[string]any{
+		"email":          "guru@sekolah.sch.id",
+		"email_verified": true,
+		"name":           "Bu Guru",
+		"groups":         []string{"Guru Matematika", "staf"},
+	}
+
+	provider, code, verifier := startLogin(t, issuer)
+
+	claims, err := provider.Exchange(context.Background(), code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	if claims.Issuer != issuer.server.URL || claims.Subject != "user-1" || claims.Email != "guru@sekolah.sch.id" || !claims.EmailVerified || claims.Name != "Bu Guru" {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+	if groups := claims.Strings("groups"); !slices.Equal(groups, []string{"Guru Matematika", "staf"}) {
+		t.Fatalf("groups = %q", groups)
+	}
+}
+
+func TestProviderRejectsWrongVerifier(t *testing.T) {
+	issuer := newFakeIssuer(t)
+
+	provider, code, _ := startLogin(t, issuer)
+
+	if _, err := provider.Exchange(context.Background(), code, NewVerifier(), "nonce-1"); err == nil {
+		t.Fatal("Exchange with another verifier succeeded")
+	}
+}
+
+func TestProviderRejectsWrongNonce(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	provider, code, verifier := startLogin(t, issuer)
+
+	if _, err := provider.Exchange(context.Background(), code, verifier, "nonce-lain"); !errors.Is(err, ErrIDTokenInvalid) {
+		t.Fatalf("Exchange with another nonce = %v, want ErrIDTokenInvalid", err)
+	}
+}
+
+func TestProviderRejectsBadSignature(t *testing.T) {
+	issuer := newFakeIssuer(t)
+
+	// Token ditandatangani kunci lain dengan key id yang sama
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	issuer.signKey = otherKey
+
+	provider, code, verifier := startLogin(t, issuer)
+
+	if _, err := provider.Exchange(context.Background(), code, verifier, "nonce-1"); !errors.Is(err, ErrIDTokenInvalid) {
+		t.Fatalf("Exchange with a forged token = %v, want ErrIDTokenInvalid", err)
+	}
+}
+
+func TestProviderClaims(t *testing.T) {
+	tests := []struct {
+		name         string
+		claims       map[string]any
+		wantVerified bool
+		wantGroups   []string
+		wantErr      error
+	}{
+		{"email verified false", map[string]any{"email": "a@b.id", "email_verified": false}, false, nil, nil},
+		{"email verified as string", map[string]any{"email": "a@b.id", "email_verified": "true"}, true, nil, nil},
+		{"email verified missing", map[string]any{"email": "a@b.id"}, false, nil, nil},
+		{"single group with spaces", map[string]any{"groups": "Guru, Wali Kelas"}, false, []string{"Guru, Wali Kelas"}, nil},
+		{"other audience", map[string]any{"aud": "aplikasi-lain"}, false, nil, ErrIDTokenInvalid},
+		{"expired", map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}, false, nil, ErrIDTokenInvalid},
+		{"other issuer", map[string]any{"iss": "https://idp.lain"}, false, nil, ErrIDTokenInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newFakeIssuer(t)
+			issuer.claims = tt.claims
+			provider, code, verifier := startLogin(t, issuer)
+
+			claims, err := provider.Exchange(context.Background(), code, verifier, "nonce-1")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Exchange = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+
+			if claims.EmailVerified != tt.wantVerified {
+				t.Fatalf("EmailVerified = %v, want %v", claims.EmailVerified, tt.wantVerified)
+			}
+			if groups := claims.Strings("groups"); !slices.Equal(groups, tt.wantGroups) {
+				t.Fatalf("groups = %q, want %q", groups, tt.wantGroups)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package openapi
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/model/api"
+)
+
+// commonErrors can be answered by every route, by the auth middleware or when something breaks
+var commonErrors = []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError}
+
+var errorDescriptions = map[int]string{
+	http.StatusBadRequest:          "Body is not valid JSON or has unknown fields",
+	http.StatusUnauthorized:        "No valid session cookie or bearer token",
+	http.StatusForbidden:           "Role, token scope or exam permission does not allow this",
+	http.StatusNotFound:            "Resource does not exist",
+	http.StatusConflict:            "Resource is not in a state that allows this",
+	http.StatusUnprocessableEntity: "Some fields are invalid, error.fields tells which",
+	http.StatusInternalServerError: "Unexpected server error",
+}
+
+var tags = []Tag{
+	{Name: "teacher", Description: "Exams, questions and results of the teacher, only for teacher accounts"},
+	{Name: "student", Description: "Taking exams and reading results, only for student accounts"},
+}
+
+// Build creates the document from Operations
+func Build() Document {
+	registry := newSchemaRegistry()
+	errorSchema := registry.ref(api.ErrorResponse{}, true)
+
+	paths := map[string]*PathItem{}
+	for _, operation := range Operations {
+		item, ok := paths[operation.Path]
+		if !ok {
+			item = &PathItem{}
+			paths[operation.Path] = item
+		}
+
+		(*item)[strings.ToLower(operation.Method)] = buildOperation(registry, errorSchema, operation)
+	}
+
+	return Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:   "SayGenFix API",
+			Version: "1",
+			Description: "Every route accepts a personal API token as \"Authorization: Bearer\" or the session cookie of a logged in browser. " +
+				"Successful responses wrap the result in \"data\", failed responses return \"error\" with a code and message.",
+		},
+		Paths: paths,
+		Components: Components{
+			Schemas: registry.schemas,
+			SecuritySchemes: map[string]*SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", Description: "Personal API token created on the account page"},
+				"cookieAuth": {Type: "apiKey", In: "cookie", Name: "session", Description: "Session cookie, its name follows SESSION_NAME"},
+			},
+		},
+		Security: []map[string][]string{{"bearerAuth": {}}, {"cookieAuth": {}}},
+		Tags:     tags,
+	}
+}
+
+func buildOperation(registry *schemaRegistry, errorSchema *Schema, operation Operation) *OperationObject {
+	object := &OperationObject{
+		OperationId: operation.Id,
+		Summary:     operation.Summary,
+		Description: "Tokens need the " + operation.Scope + " scope.",
+		Tags:        []string{operation.Tag},
+		Responses:   map[string]*Response{},
+	}
+
+	for _, name := range PathParameters(operation.Path) {
+		object.Parameters = append(object.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	for _, query := range operation.Query {
+		object.Parameters = append(object.Parameters, Parameter{Name: query.Name, In: "query", Description: query.Description, Schema: &Schema{Type: "string"}})
+	}
+
+	if operation.Request != nil {
+		object.RequestBody = &RequestBody{
+			Required: true,
+			Content:  jsonContent(registry.ref(operation.Request, false)),
+		}
+	}
+
+	success := &Response{Description: http.StatusText(operation.Status)}
+	if operation.Response != nil {
+		success.Content = jsonContent(&Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{"data": registry.ref(operation.Response, true)},
+			Required:   []string{"data"},
+		})
+	}
+	object.Responses[strconv.Itoa(operation.Status)] = success
+
+	for _, status := range slices.Concat(operation.Errors, commonErrors) {
+		object.Responses[strconv.Itoa(status)] = &Response{Description: errorDescriptions[status], Content: jsonContent(errorSchema)}
+	}
+
+	return object
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+// PathParameters returns the names of the {wildcards} in a path
+func PathParameters(path string) []string {
+	names := []string{}
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append(names, strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}"))
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,132 @@
+package openapi
+
+import (
+	"net/http"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/model/api"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+// Operation documents one route of the JSON API. Method and Path are the same as the pattern given
+// to the mux in the router package, the router tests check that both lists stay the same.
+type Operation struct {
+	Method  string
+	Path    string
+	Id      string
+	Summary string
+	Tag     string
+	Scope   string
+	Query   []QueryParameter
+	// Request is a value of the request body type, nil when the route does not read a body
+	Request any
+	// Response is a value of the type in the "data" field, nil when the route answers without body
+	Response any
+	Status   int
+	// Errors lists the error statuses of the route besides the ones every route can answer
+	Errors []int
+}
+
+type QueryParameter struct {
+	Name        string
+	Description string
+}
+
+var classIdQuery = QueryParameter{Name: "class_id", Description: "Only include the members of this class"}
+
+// Operations are every route mounted under /api/v1
+var Operations = []Operation{
+	// Guru
+	{
+		Method: http.MethodGet, Path: "/api/v1/teacher/exams", Id: "listTeacherExams",
+		Summary: "List the exams the teacher owns or collaborates on", Tag: "teacher", Scope: domain.ScopeExamsRead,
+		Query:    []QueryParameter{{Name: "class_id", Description: "Only include exams of this class"}},
+		Response: []api.ExamResponse{}, Status: http.StatusOK,
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/teacher/exams", Id: "createExam",
+		Summary: "Create an exam with its questions", Tag: "teacher", Scope: domain.ScopeExamsWrite,
+		Request: api.ExamCreateRequest{}, Response: api.ExamDetailResponse{}, Status: http.StatusCreated,
+		Errors: []int{http.StatusBadRequest, http.StatusUnprocessableEntity},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/teacher/exams/{id}", Id: "getExam",
+		Summary: "Get an exam with its questions and answer keys", Tag: "teacher", Scope: domain.ScopeExamsRead,
+		Response: api.ExamDetailResponse{}, Status: http.StatusOK,
+	},
+	{
+		Method: http.MethodPut, Path: "/api/v1/teacher/exams/{id}", Id: "updateExam",
+		Summary: "Update the settings of an exam", Tag: "teacher", Scope: domain.ScopeExamsWrite,
+		Request: api.ExamUpdateRequest{}, Response: api.ExamDetailResponse{}, Status: http.StatusOK,
+		Errors: []int{http.StatusBadRequest, http.StatusUnprocessableEntity},
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/v1/teacher/exams/{id}", Id: "deleteExam",
+		Summary: "Delete an exam, only its owner can do this", Tag: "teacher", Scope: domain.ScopeExamsWrite,
+		Status: http.StatusNoContent,
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/teacher/exams/{id}/questions", Id: "listExamQuestions",
+		Summary: "List the questions of an exam", Tag: "teacher", Scope: domain.ScopeExamsRead,
+		Response: []api.QuestionResponse{}, Status: http.StatusOK,
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/teacher/exams/{id}/questions", Id: "createExamQuestion",
+		Summary: "Add a question to an exam", Tag: "teacher", Scope: domain.ScopeExamsWrite,
+		Request: api.QuestionRequest{}, Response: api.QuestionResponse{}, Status: http.StatusCreated,
+		Errors: []int{http.StatusBadRequest, http.StatusUnprocessableEntity},
+	},
+	{
+		Method: http.MethodPut, Path: "/api/v1/teacher/exams/{id}/questions/{questionId}", Id: "updateExamQuestion",
+		Summary: "Update a question of an exam, questions from the question bank are changed in the bank", Tag: "teacher", Scope: domain.ScopeExamsWrite,
+		Request: api.QuestionRequest{}, Response: api.QuestionResponse{}, Status: http.StatusOK,
+		Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity},
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/v1/teacher/exams/{id}/questions/{questionId}", Id: "deleteExamQuestion",
+		Summary: "Remove a question from an exam", Tag: "teacher", Scope: domain.ScopeExamsWrite,
+		Status: http.StatusNoContent, Errors: []int{http.StatusNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/teacher/exams/{id}/results", Id: "listExamResults",
+		Summary: "List the best attempt of every student on an exam", Tag: "teacher", Scope: domain.ScopeResultsRead,
+		Query: []QueryParameter{classIdQuery}, Response: []api.ExamResultResponse{}, Status: http.StatusOK,
+	},
+
+	// Siswa
+	{
+		Method: http.MethodGet, Path: "/api/v1/student/exams", Id: "listStudentExams",
+		Summary: "List the active exams the student has joined, by join code, from the public exams or through a class", Tag: "student", Scope: domain.ScopeExamsRead,
+		Response: []api.StudentExamResponse{}, Status: http.StatusOK,
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/student/exams/{examId}/attempts", Id: "startAttempt",
+		Summary: "Start an attempt on an exam", Tag: "student", Scope: domain.ScopeAttemptsWrite,
+		Response: api.AttemptResponse{}, Status: http.StatusCreated, Errors: []int{http.StatusNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/student/attempts/{id}", Id: "getAttempt",
+		Summary: "Get an attempt with its questions and saved answers", Tag: "student", Scope: domain.ScopeAttemptsWrite,
+		Response: api.AttemptResponse{}, Status: http.StatusOK, Errors: []int{http.StatusNotFound},
+	},
+	{
+		Method: http.MethodPut, Path: "/api/v1/student/attempts/{id}/answers/{questionId}", Id: "saveAnswer",
+		Summary: "Save the answer to one question, it can be changed until the attempt is submitted", Tag: "student", Scope: domain.ScopeAttemptsWrite,
+		Request: api.AnswerRequest{}, Response: api.AnswerResponse{}, Status: http.StatusOK,
+		Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/student/attempts/{id}/submit", Id: "submitAttempt",
+		Summary: "Submit and score an attempt", Tag: "student", Scope: domain.ScopeAttemptsWrite,
+		Response: api.AttemptResponse{}, Status: http.StatusOK, Errors: []int{http.StatusNotFound, http.StatusConflict},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/student/results", Id: "listStudentResults",
+		Summary: "List the best score of the student on every exam", Tag: "student", Scope: domain.ScopeResultsRead,
+		Response: []api.ResultResponse{}, Status: http.StatusOK,
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/student/attempts/{id}/result", Id: "getAttemptResult",
+		Summary: "Get the scored answers of a submitted attempt", Tag: "student", Scope: domain.ScopeResultsRead,
+		Response: api.AttemptResultResponse{}, Status: http.StatusOK, Errors: []int{http.StatusNotFound, http.StatusConflict},
+	},
+}
@@ -0,0 +1,109 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaRegistry turns Go types into schemas, every struct becomes a named component
+type schemaRegistry struct {
+	schemas map[string]*Schema
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{schemas: map[string]*Schema{}}
+}
+
+// ref returns the schema of v, a nil v is the empty schema that accepts any value
+func (registry *schemaRegistry) ref(v any, response bool) *Schema {
+	if v == nil {
+		return &Schema{}
+	}
+
+	return registry.schemaOf(reflect.TypeOf(v), response)
+}
+
+// schemaOf builds the schema of t. Response structs list every field without omitempty as required
+// because encoding/json always writes them, request fields are checked by the services instead.
+func (registry *schemaRegistry) schemaOf(t reflect.Type, response bool) *Schema {
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := *registry.schemaOf(t.Elem(), response)
+		if schema.Ref != "" {
+			return &Schema{AllOf: []*Schema{{Ref: schema.Ref}}, Nullable: true}
+		}
+		schema.Nullable = true
+		return &schema
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: registry.schemaOf(t.Elem(), response)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: registry.schemaOf(t.Elem(), response)}
+	case reflect.Struct:
+		return registry.component(t, response)
+	default:
+		// interface{} dan tipe lain bisa berisi apa saja
+		return &Schema{}
+	}
+}
+
+func (registry *schemaRegistry) component(t reflect.Type, response bool) *Schema {
+	ref := &Schema{Ref: "#/components/schemas/" + t.Name()}
+	if _, ok := registry.schemas[t.Name()]; ok {
+		return ref
+	}
+
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	// Didaftarkan lebih dulu supaya tipe yang merujuk dirinya sendiri tidak berulang tanpa henti
+	registry.schemas[t.Name()] = schema
+	registry.addFields(schema, t, response)
+
+	return ref
+}
+
+// addFields adds the json fields of t to schema, embedded structs are flattened like encoding/json does
+func (registry *schemaRegistry) addFields(schema *Schema, t reflect.Type, response bool) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			registry.addFields(schema, field.Type, response)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = registry.schemaOf(field.Type, response)
+		if response && !strings.Contains(options, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidateResponse checks that body is what the document promises for the status of the route.
+// Objects may not have properties the schema does not list, so undocumented fields are caught too.
+func (document Document) ValidateResponse(method, path string, status int, body []byte) error {
+	item, ok := document.Paths[path]
+	if !ok {
+		return fmt.Errorf("path %s is not documented", path)
+	}
+	operation, ok := (*item)[strings.ToLower(method)]
+	if !ok {
+		return fmt.Errorf("%s %s is not documented", method, path)
+	}
+	response, ok := operation.Responses[strconv.Itoa(status)]
+	if !ok {
+		return fmt.Errorf("%s %s does not document status %d", method, path, status)
+	}
+
+	if response.Content == nil {
+		if len(bytes.TrimSpace(body)) > 0 {
+			return fmt.Errorf("%s %s should answer %d without a body", method, path, status)
+		}
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("%s %s answered %d with invalid json: %w", method, path, status, err)
+	}
+
+	return document.validate(response.Content["application/json"].Schema, value, "$")
+}
+
+func (document Document) validate(schema *Schema, value any, at string) error {
+	if schema.Ref != "" {
+		return document.validate(document.resolve(schema.Ref), value, at)
+	}
+	if value == nil {
+		if schema.Nullable || (schema.Type == "" && len(schema.AllOf) == 0) {
+			return nil
+		}
+		return fmt.Errorf("%s is null but not nullable", at)
+	}
+	for _, part := range schema.AllOf {
+		if err := document.validate(part, value, at); err != nil {
+			return err
+		}
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s should be an object", at)
+		}
+		return document.validateObject(schema, object, at)
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s should be an array", at)
+		}
+		for i, element := range array {
+			if err := document.validate(schema.Items, element, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s should be a string", at)
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, text); err != nil {
+				return fmt.Errorf("%s should be a date-time: %w", at, err)
+			}
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s should be an integer", at)
+		}
+		if _, err := number.Int64(); err != nil {
+			return fmt.Errorf("%s should be an integer", at)
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return fmt.Errorf("%s should be a number", at)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s should be a boolean", at)
+		}
+	}
+
+	return nil
+}
+
+func (document Document) validateObject(schema *Schema, object map[string]any, at string) error {
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			return fmt.Errorf("%s.%s is required", at, name)
+		}
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property, ok := schema.Properties[name]
+		switch {
+		case ok:
+		case schema.AdditionalProperties != nil:
+			property = schema.AdditionalProperties
+		case schema.Properties != nil:
+			return fmt.Errorf("%s.%s is not documented", at, name)
+		default:
+			continue
+		}
+
+		if err := document.validate(property, object[name], at+"."+name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (document Document) resolve(ref string) *Schema {
+	schema, ok := document.Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+	if !ok {
+		return &Schema{}
+	}
+
+	return schema
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+type AdminRepository interface {
+	FindUsers(ctx context.Context, tx pgx.Tx, query, role string) ([]domain.User, error)
+	FindUserById(ctx context.Context, tx pgx.Tx, userId string) (domain.User, error)
+	FindPendingTeachers(ctx context.Context, tx pgx.Tx) ([]domain.User, error)
+	UpdateDisabled(ctx context.Context, tx pgx.Tx, userId string, isDisabled bool) error
+	UpdateRole(ctx context.Context, tx pgx.Tx, userId, role string) error
+	UpdateApproved(ctx context.Context, tx pgx.Tx, userId string, isApproved bool) error
+	Delete(ctx context.Context, tx pgx.Tx, userId string) error
+	DeleteSessionsByUserId(ctx context.Context, tx pgx.Tx, userId string) error
+	FindSystemStats(ctx context.Context, tx pgx.Tx) (domain.SystemStats, error)
+	FindExamStats(ctx context.Context, tx pgx.Tx) ([]domain.ExamStats, error)
+	FindLoginFailures(ctx context.Context, tx pgx.Tx) ([]domain.LoginFailureSummary, error)
+}
@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+// maxAdminUserResults limits the user list, admins are expected to search for the rest
+const maxAdminUserResults = 200
+
+func NewAdminRepository() AdminRepository {
+	return &AdminRepositoryImpl{}
+}
+
+type AdminRepositoryImpl struct{}
+
+// FindUsers searches users by name or email, role is an optional filter
+func (repository *AdminRepositoryImpl) FindUsers(ctx context.Context, tx pgx.Tx, query, role string) ([]domain.User, error) {
+	sqlQuery := `
+	SELECT id, email, full_name, role, is_disabled, is_approved, created_at, updated_at
+	FROM users
+	WHERE ($1 = '' OR full_name ILIKE '%' || $1 || '%' OR email ILIKE '%' || $1 || '%')
+		AND ($2 = '' OR role::text = $2)
+	ORDER BY created_at DESC
+	LIMIT $3
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, query, role, maxAdminUserResults)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanAdminUsers(rows)
+}
+
+func (repository *AdminRepositoryImpl) FindUserById(ctx context.Context, tx pgx.Tx, userId string) (domain.User, error) {
+	sqlQuery := `
+	SELECT id, email, full_name, role, is_disabled, is_approved, created_at, updated_at
+	FROM users
+	WHERE id = $1
+	`
+
+	user := domain.User{}
+	err := tx.QueryRow(ctx, sqlQuery, userId).Scan(
+		&user.Id,
+		&user.Email,
+		&user.FullName,
+		&user.Role,
+		&user.IsDisabled,
+		&user.IsApproved,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	return user, nil
+}
+
+func (repository *AdminRepositoryImpl) FindPendingTeachers(ctx context.Context, tx pgx.Tx) ([]domain.User, error) {
+	sqlQuery := `
+	SELECT id, email, full_name, role, is_disabled, is_approved, created_at, updated_at
+	FROM users
+	WHERE role = 'teacher' AND is_approved = FALSE
+	ORDER BY created_at
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanAdminUsers(rows)
+}
+
+func scanAdminUsers(rows pgx.Rows) ([]domain.User, error) {
+	defer rows.Close()
+
+	users := []domain.User{}
+	for rows.Next() {
+		user := domain.User{}
+		err := rows.Scan(
+			&user.Id,
+			&user.Email,
+			&user.FullName,
+			&user.Role,
+			&user.IsDisabled,
+			&user.IsApproved,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (repository *AdminRepositoryImpl) UpdateDisabled(ctx context.Context, tx pgx.Tx, userId string, isDisabled bool) error {
+	sqlQuery := `
+	UPDATE users
+	SET is_disabled = $2, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, userId, isDisabled)
+
+	return err
+}
+
+func (repository *AdminRepositoryImpl) UpdateRole(ctx context.Context, tx pgx.Tx, userId, role string) error {
+	sqlQuery := `
+	UPDATE users
+	SET role = $2, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, userId, role)
+
+	return err
+}
+
+func (repository *AdminRepositoryImpl) UpdateApproved(ctx context.Context, tx pgx.Tx, userId string, isApproved bool) error {
+	sqlQuery := `
+	UPDATE users
+	SET is_approved = $2, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, userId, isApproved)
+
+	return err
+}
+
+func (repository *AdminRepositoryImpl) Delete(ctx context.Context, tx pgx.Tx, userId string) error {
+	sqlQuery := `
+	DELETE FROM users
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, userId)
+
+	return err
+}
+
+// DeleteSessionsByUserId logs the user out of every device
+func (repository *AdminRepositoryImpl) DeleteSessionsByUserId(ctx context.Context, tx pgx.Tx, userId string) error {
+	sqlQuery := `
+	DELETE FROM sessions
+	WHERE user_id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, userId)
+
+	return err
+}
+
+func (repository *AdminRepositoryImpl) FindSystemStats(ctx context.Context, tx pgx.Tx) (domain.SystemStats, error) {
+	sqlQuery := `
+	SELECT
+		(SELECT COUNT(*) FROM users WHERE role = 'student'),
+		(SELECT COUNT(*) FROM users WHERE role = 'teacher'),
+		(SELECT COUNT(*) FROM users WHERE role = 'admin'),
+		(SELECT COUNT(*) FROM users WHERE role = 'teacher' AND is_approved = FALSE),
+		(SELECT COUNT(*) FROM users WHERE is_disabled = TRUE),
+		(SELECT COUNT(*) FROM exams),
+		(SELECT COUNT(*) FROM exams WHERE is_active = TRUE),
+		(SELECT COUNT(*) FROM exam_attempts),
+		(SELECT COALESCE(AVG(score), 0)::float8 FROM exam_attempts)
+	`
+
+	stats := domain.SystemStats{}
+	err := tx.QueryRow(ctx, sqlQuery).Scan(
+		&stats.TotalStudents,
+		&stats.TotalTeachers,
+		&stats.TotalAdmins,
+		&stats.PendingTeachers,
+		&stats.DisabledUsers,
+		&stats.TotalExams,
+		&stats.ActiveExams,
+		&stats.TotalAttempts,
+		&stats.AverageScore,
+	)
+	if err != nil {
+		return domain.SystemStats{}, err
+	}
+
+	return stats, nil
+}
+
+// FindExamStats lists every exam with its attempt numbers, most attempted first
+func (repository *AdminRepositoryImpl) FindExamStats(ctx context.Context, tx pgx.Tx) ([]domain.ExamStats, error) {
+	sqlQuery := `
+	SELECT e.id, e.name, u.full_name, COALESCE(e.is_active, FALSE),
+		COUNT(a.id), COUNT(DISTINCT a.student_id), COALESCE(AVG(a.score), 0)::float8
+	FROM exams e
+	JOIN users u ON u.id = e.teacher_id
+	LEFT JOIN exam_attempts a ON a.exam_id = e.id
+	GROUP BY e.id, e.name, u.full_name, e.is_active
+	ORDER BY COUNT(a.id) DESC, e.name
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exams := []domain.ExamStats{}
+	for rows.Next() {
+		exam := domain.ExamStats{}
+		err := rows.Scan(
+			&exam.ExamId,
+			&exam.RoomName,
+			&exam.TeacherName,
+			&exam.IsActive,
+			&exam.AttemptCount,
+			&exam.StudentCount,
+			&exam.AverageScore,
+		)
+		if err != nil {
+			return nil, err
+		}
+		exams = append(exams, exam)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return exams, nil
+}
+
+// FindLoginFailures summarizes failed logins of the last 24 hours, most recent first
+func (repository *AdminRepositoryImpl) FindLoginFailures(ctx context.Context, tx pgx.Tx) ([]domain.LoginFailureSummary, error) {
+	sqlQuery := `
+	SELECT email, ip_address, COUNT(*), MAX(attempted_at)
+	FROM login_attempts
+	WHERE is_success = FALSE AND attempted_at > CURRENT_TIMESTAMP - INTERVAL '24 hours'
+	GROUP BY email, ip_address
+	ORDER BY MAX(attempted_at) DESC
+	LIMIT 50
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	failures := []domain.LoginFailureSummary{}
+	for rows.Next() {
+		failure := domain.LoginFailureSummary{}
+		if err := rows.Scan(&failure.Email, &failure.IPAddress, &failure.Failures, &failure.LastAttemptAt); err != nil {
+			return nil, err
+		}
+		failures = append(failures, failure)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return failures, nil
+}
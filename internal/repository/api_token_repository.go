@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+type APITokenRepository interface {
+	// Personal API tokens
+	Save(ctx context.Context, tx pgx.Tx, token domain.APIToken, ttl time.Duration) (domain.APIToken, error)
+	FindByUserId(ctx context.Context, tx pgx.Tx, userId string) ([]domain.APIToken, error)
+	CountActiveByUserId(ctx context.Context, tx pgx.Tx, userId string) (int, error)
+	FindValidByTokenHash(ctx context.Context, tx pgx.Tx, tokenHash string) (domain.APIToken, error)
+	Revoke(ctx context.Context, tx pgx.Tx, userId, tokenId string) (bool, error)
+
+	// Audit trail
+	SaveUsage(ctx context.Context, tx pgx.Tx, usage domain.APITokenUsage) error
+	FindUsagesByUserId(ctx context.Context, tx pgx.Tx, userId string, limit int) ([]domain.APITokenUsage, error)
+}
@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+func NewAPITokenRepository() APITokenRepository {
+	return &APITokenRepositoryImpl{}
+}
+
+type APITokenRepositoryImpl struct{}
+
+// Save computes expires_at in the database so it uses the same clock as the validity check
+func (repository *APITokenRepositoryImpl) Save(ctx context.Context, tx pgx.Tx, token domain.APIToken, ttl time.Duration) (domain.APIToken, error) {
+	sqlQuery := `
+	INSERT INTO api_tokens (user_id, name, token_prefix, token_hash, scopes, expires_at)
+	VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP + make_interval(secs => $6))
+	RETURNING id, expires_at, created_at
+	`
+
+	err := tx.QueryRow(
+		ctx,
+		sqlQuery,
+		token.UserId,
+		token.Name,
+		token.TokenPrefix,
+		token.TokenHash,
+		token.Scopes,
+		ttl.Seconds(),
+	).Scan(
+		&token.Id,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return domain.APIToken{}, err
+	}
+
+	return token, nil
+}
+
+func (repository *APITokenRepositoryImpl) FindByUserId(ctx context.Context, tx pgx.Tx, userId string) ([]domain.APIToken, error) {
+	sqlQuery := `
+	SELECT id, user_id, name, token_prefix, scopes, expires_at, last_used_at, revoked_at, created_at
+	FROM api_tokens
+	WHERE user_id = $1
+	ORDER BY revoked_at IS NOT NULL, created_at DESC
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []domain.APIToken{}
+	for rows.Next() {
+		token := domain.APIToken{}
+		if err := rows.Scan(
+			&token.Id,
+			&token.UserId,
+			&token.Name,
+			&token.TokenPrefix,
+			&token.Scopes,
+			&token.ExpiresAt,
+			&token.LastUsedAt,
+			&token.RevokedAt,
+			&token.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// CountActiveByUserId counts tokens that are neither revoked nor expired
+func (repository *APITokenRepositoryImpl) CountActiveByUserId(ctx context.Context, tx pgx.Tx, userId string) (int, error) {
+	sqlQuery := `
+	SELECT COUNT(*)
+	FROM api_tokens
+	WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+	`
+
+	var count int
+	if err := tx.QueryRow(ctx, sqlQuery, userId).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// FindValidByTokenHash returns pgx.ErrNoRows for unknown, revoked and expired tokens. It also
+// records the time of use.
+func (repository *APITokenRepositoryImpl) FindValidByTokenHash(ctx context.Context, tx pgx.Tx, tokenHash string) (domain.APIToken, error) {
+	sqlQuery := `
+	UPDATE api_tokens
+	SET last_used_at = CURRENT_TIMESTAMP
+	WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+	RETURNING id, user_id, name, token_prefix, scopes, expires_at, last_used_at, revoked_at, created_at
+	`
+
+	token := domain.APIToken{}
+	err := tx.QueryRow(ctx, sqlQuery, tokenHash).Scan(
+		&token.Id,
+		&token.UserId,
+		&token.Name,
+		&token.TokenPrefix,
+		&token.Scopes,
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+		&token.RevokedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return domain.APIToken{}, err
+	}
+
+	return token, nil
+}
+
+// Revoke returns false when the token does not belong to the user or was already revoked
+func (repository *APITokenRepositoryImpl) Revoke(ctx context.Context, tx pgx.Tx, userId, tokenId string) (bool, error) {
+	sqlQuery := `
+	UPDATE api_tokens
+	SET revoked_at = CURRENT_TIMESTAMP
+	WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+
+	commandTag, err := tx.Exec(ctx, sqlQuery, tokenId, userId)
+	if err != nil {
+		return false, err
+	}
+
+	return commandTag.RowsAffected() == 1, nil
+}
+
+func (repository *APITokenRepositoryImpl) SaveUsage(ctx context.Context, tx pgx.Tx, usage domain.APITokenUsage) error {
+	sqlQuery := `
+	INSERT INTO api_token_usages (token_id, method, path, ip_address, user_agent)
+	VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, usage.TokenId, usage.Method, usage.Path, usage.IPAddress, usage.UserAgent)
+
+	return err
+}
+
+// FindUsagesByUserId returns the latest requests made with any token of the user
+func (repository *APITokenRepositoryImpl) FindUsagesByUserId(ctx context.Context, tx pgx.Tx, userId string, limit int) ([]domain.APITokenUsage, error) {
+	sqlQuery := `
+	SELECT u.token_id, t.name, u.method, u.path, u.ip_address, u.user_agent, u.used_at
+	FROM api_token_usages u
+	JOIN api_tokens t ON t.id = u.token_id
+	WHERE t.user_id = $1
+	ORDER BY u.used_at DESC, u.id DESC
+	LIMIT $2
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, userId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usages := []domain.APITokenUsage{}
+	for rows.Next() {
+		usage := domain.APITokenUsage{}
+		if err := rows.Scan(
+			&usage.TokenId,
+			&usage.TokenName,
+			&usage.Method,
+			&usage.Path,
+			&usage.IPAddress,
+			&usage.UserAgent,
+			&usage.UsedAt,
+		); err != nil {
+			return nil, err
+		}
+		usages = append(usages, usage)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return usages, nil
+}
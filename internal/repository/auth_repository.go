@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
@@ -14,4 +15,16 @@ type AuthRepository interface {
 	FindUserBySessionId(ctx context.Context, tx pgx.Tx, sessionId string) (domain.User, error)
 	// Delete session
 	Delete(ctx context.Context, tx pgx.Tx, sessionId string) error
+	// Delete all sessions of a user
+	DeleteByUserId(ctx context.Context, tx pgx.Tx, userId string) error
+	// Active sessions page
+	TouchSession(ctx context.Context, tx pgx.Tx, session domain.Session, interval time.Duration) error
+	FindSessionsByUserId(ctx context.Context, tx pgx.Tx, userId string) ([]domain.Session, error)
+	DeleteOtherSession(ctx context.Context, tx pgx.Tx, userId, id, currentSessionId string) (bool, error)
+	DeleteOtherSessions(ctx context.Context, tx pgx.Tx, userId, currentSessionId string) (int64, error)
+	// Save a login attempt for throttling
+	SaveLoginAttempt(ctx context.Context, tx pgx.Tx, attempt domain.LoginAttempt) error
+	// Find recent login failures by email or IP
+	FindLoginThrottleByEmail(ctx context.Context, tx pgx.Tx, email string, window time.Duration) (domain.LoginThrottle, error)
+	FindLoginThrottleByIP(ctx context.Context, tx pgx.Tx, ipAddress string, window time.Duration) (domain.LoginThrottle, error)
 }
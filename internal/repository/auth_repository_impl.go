@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
@@ -15,9 +16,9 @@ type AuthRepositoryImpl struct{}
 
 func (repository *AuthRepositoryImpl) Save(ctx context.Context, tx pgx.Tx, session domain.Session) (domain.Session, error) {
 	sqlQuery := `
-	INSERT INTO sessions (session_id, user_id)
-	VALUES ($1, $2)
-	RETURNING created_at
+	INSERT INTO sessions (session_id, user_id, user_agent, ip_address)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, last_seen_at, created_at
 	`
 
 	err := tx.QueryRow(
@@ -25,7 +26,11 @@ func (repository *AuthRepositoryImpl) Save(ctx context.Context, tx pgx.Tx, sessi
 		sqlQuery,
 		session.SessionId,
 		session.UserId,
+		session.UserAgent,
+		session.IPAddress,
 	).Scan(
+		&session.Id,
+		&session.LastSeenAt,
 		&session.CreatedAt,
 	)
 	if err != nil {
@@ -37,10 +42,11 @@ func (repository *AuthRepositoryImpl) Save(ctx context.Context, tx pgx.Tx, sessi
 
 func (repository *AuthRepositoryImpl) FindUserBySessionId(ctx context.Context, tx pgx.Tx, sessionId string) (domain.User, error) {
 	sqlQuery := `
-	SELECT u.id, u.email, u.full_name, u.password, u.role, u.created_at, u.updated_at
+	SELECT u.id, u.email, u.full_name, u.password, u.role, u.email_verified_at IS NOT NULL, u.totp_enabled,
+		u.temporary_password_expires_at IS NOT NULL, u.created_at, u.updated_at
 	FROM users u
 	JOIN sessions s ON u.id = s.user_id
-	WHERE s.session_id = $1
+	WHERE s.session_id = $1 AND u.is_disabled = FALSE AND u.is_approved = TRUE
 	`
 
 	var user = domain.User{}
@@ -51,6 +57,9 @@ func (repository *AuthRepositoryImpl) FindUserBySessionId(ctx context.Context, t
 		&user.FullName,
 		&user.Password,
 		&user.Role,
+		&user.IsEmailVerified,
+		&user.TwoFactorEnabled,
+		&user.MustChangePassword,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -74,3 +83,160 @@ func (repository *AuthRepositoryImpl) Delete(ctx context.Context, tx pgx.Tx, ses
 
 	return nil
 }
+
+// DeleteByUserId logs the user out of every device
+func (repository *AuthRepositoryImpl) DeleteByUserId(ctx context.Context, tx pgx.Tx, userId string) error {
+	sqlQuery := `
+	DELETE FROM sessions
+	WHERE user_id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, userId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// TouchSession records the latest device info. last_seen_at is only written once per interval
+// so that every page view does not turn into a write.
+func (repository *AuthRepositoryImpl) TouchSession(ctx context.Context, tx pgx.Tx, session domain.Session, interval time.Duration) error {
+	sqlQuery := `
+	UPDATE sessions
+	SET user_agent = $2, ip_address = $3, last_seen_at = CURRENT_TIMESTAMP
+	WHERE session_id = $1
+	AND (last_seen_at < CURRENT_TIMESTAMP - make_interval(secs => $4) OR user_agent <> $2 OR ip_address <> $3)
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, session.SessionId, session.UserAgent, session.IPAddress, interval.Seconds())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *AuthRepositoryImpl) FindSessionsByUserId(ctx context.Context, tx pgx.Tx, userId string) ([]domain.Session, error) {
+	sqlQuery := `
+	SELECT id, session_id, user_id, user_agent, ip_address, last_seen_at, created_at
+	FROM sessions
+	WHERE user_id = $1
+	ORDER BY last_seen_at DESC
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []domain.Session{}
+	for rows.Next() {
+		session := domain.Session{}
+		if err := rows.Scan(
+			&session.Id,
+			&session.SessionId,
+			&session.UserId,
+			&session.UserAgent,
+			&session.IPAddress,
+			&session.LastSeenAt,
+			&session.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// DeleteOtherSession revokes one session of the user, the current session is never matched
+func (repository *AuthRepositoryImpl) DeleteOtherSession(ctx context.Context, tx pgx.Tx, userId, id, currentSessionId string) (bool, error) {
+	sqlQuery := `
+	DELETE FROM sessions
+	WHERE id = $1 AND user_id = $2 AND session_id <> $3
+	`
+
+	commandTag, err := tx.Exec(ctx, sqlQuery, id, userId, currentSessionId)
+	if err != nil {
+		return false, err
+	}
+
+	return commandTag.RowsAffected() == 1, nil
+}
+
+// DeleteOtherSessions logs the user out of every device except the current one
+func (repository *AuthRepositoryImpl) DeleteOtherSessions(ctx context.Context, tx pgx.Tx, userId, currentSessionId string) (int64, error) {
+	sqlQuery := `
+	DELETE FROM sessions
+	WHERE user_id = $1 AND session_id <> $2
+	`
+
+	commandTag, err := tx.Exec(ctx, sqlQuery, userId, currentSessionId)
+	if err != nil {
+		return 0, err
+	}
+
+	return commandTag.RowsAffected(), nil
+}
+
+func (repository *AuthRepositoryImpl) SaveLoginAttempt(ctx context.Context, tx pgx.Tx, attempt domain.LoginAttempt) error {
+	sqlQuery := `
+	INSERT INTO login_attempts (email, ip_address, is_success)
+	VALUES ($1, $2, $3)
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, attempt.Email, attempt.IPAddress, attempt.IsSuccess)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FindLoginThrottleByEmail counts failures within window that happened after the last successful login
+func (repository *AuthRepositoryImpl) FindLoginThrottleByEmail(ctx context.Context, tx pgx.Tx, email string, window time.Duration) (domain.LoginThrottle, error) {
+	sqlQuery := `
+	SELECT COUNT(*), COALESCE(EXTRACT(EPOCH FROM MAX(attempted_at) + make_interval(secs => $2) - CURRENT_TIMESTAMP), 0)
+	FROM login_attempts
+	WHERE email = $1
+		AND is_success = FALSE
+		AND attempted_at > CURRENT_TIMESTAMP - make_interval(secs => $2)
+		AND attempted_at >= COALESCE((
+			SELECT MAX(attempted_at) FROM login_attempts WHERE email = $1 AND is_success = TRUE
+		), '-infinity')
+	`
+
+	return scanLoginThrottle(tx.QueryRow(ctx, sqlQuery, email, window.Seconds()))
+}
+
+// FindLoginThrottleByIP counts failures from one IP within window, a success does not reset it
+func (repository *AuthRepositoryImpl) FindLoginThrottleByIP(ctx context.Context, tx pgx.Tx, ipAddress string, window time.Duration) (domain.LoginThrottle, error) {
+	sqlQuery := `
+	SELECT COUNT(*), COALESCE(EXTRACT(EPOCH FROM MAX(attempted_at) + make_interval(secs => $2) - CURRENT_TIMESTAMP), 0)
+	FROM login_attempts
+	WHERE ip_address = $1
+		AND is_success = FALSE
+		AND attempted_at > CURRENT_TIMESTAMP - make_interval(secs => $2)
+	`
+
+	return scanLoginThrottle(tx.QueryRow(ctx, sqlQuery, ipAddress, window.Seconds()))
+}
+
+func scanLoginThrottle(row pgx.Row) (domain.LoginThrottle, error) {
+	var failures int
+	var retryAfterSeconds float64
+	if err := row.Scan(&failures, &retryAfterSeconds); err != nil {
+		return domain.LoginThrottle{}, err
+	}
+
+	return domain.LoginThrottle{
+		Failures:   failures,
+		RetryAfter: time.Duration(retryAfterSeconds * float64(time.Second)),
+	}, nil
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+type ClassRepository interface {
+	Save(ctx context.Context, tx pgx.Tx, class domain.Class) (domain.Class, error)
+	FindById(ctx context.Context, tx pgx.Tx, classId string) (domain.Class, error)
+	FindByTeacherId(ctx context.Context, tx pgx.Tx, teacherId string) ([]domain.Class, error)
+	FindByJoinCode(ctx context.Context, tx pgx.Tx, joinCode string) (domain.Class, error)
+	Delete(ctx context.Context, tx pgx.Tx, classId string) error
+	UpdateJoinCodeById(ctx context.Context, tx pgx.Tx, classId, joinCode string) error
+	IsJoinCodeTaken(ctx context.Context, tx pgx.Tx, joinCode string) (bool, error)
+
+	FindMembers(ctx context.Context, tx pgx.Tx, classId string) ([]domain.ClassMember, error)
+	SaveMember(ctx context.Context, tx pgx.Tx, classId, studentId string) error
+	DeleteMember(ctx context.Context, tx pgx.Tx, classId, studentId string) error
+
+	FindInvitations(ctx context.Context, tx pgx.Tx, classId string) ([]domain.ClassInvitation, error)
+	SaveInvitation(ctx context.Context, tx pgx.Tx, classId, email string) error
+	DeleteInvitation(ctx context.Context, tx pgx.Tx, classId, email string) error
+
+	FindExamsByClassId(ctx context.Context, tx pgx.Tx, classId string) ([]domain.Exam, error)
+	FindClassIdsByExamId(ctx context.Context, tx pgx.Tx, examId string) ([]string, error)
+	UpdateExamClasses(ctx context.Context, tx pgx.Tx, examId, teacherId string, classIds []string) error
+	MakePrivateWhenUnassigned(ctx context.Context, tx pgx.Tx, examIds []string) error
+}
@@ -0,0 +1,403 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+func NewClassRepository() ClassRepository {
+	return &ClassRepositoryImpl{}
+}
+
+type ClassRepositoryImpl struct{}
+
+func (repository *ClassRepositoryImpl) Save(ctx context.Context, tx pgx.Tx, class domain.Class) (domain.Class, error) {
+	sqlQuery := `
+	INSERT INTO classes (teacher_id, name, join_code)
+	VALUES ($1, $2, NULLIF($3, ''))
+	RETURNING id, created_at, updated_at
+	`
+
+	err := tx.QueryRow(
+		ctx,
+		sqlQuery,
+		class.TeacherId,
+		class.Name,
+		class.JoinCode,
+	).Scan(
+		&class.Id,
+		&class.CreatedAt,
+		&class.UpdatedAt,
+	)
+	if err != nil {
+		return domain.Class{}, err
+	}
+
+	return class, nil
+}
+
+func (repository *ClassRepositoryImpl) FindById(ctx context.Context, tx pgx.Tx, classId string) (domain.Class, error) {
+	sqlQuery := `
+	SELECT c.id, c.teacher_id, c.name, COALESCE(c.join_code, ''), c.created_at, c.updated_at,
+		(SELECT COUNT(*) FROM class_members m WHERE m.class_id = c.id)
+	FROM classes c
+	WHERE c.id = $1
+	`
+
+	class := domain.Class{}
+	err := tx.QueryRow(ctx, sqlQuery, classId).Scan(
+		&class.Id,
+		&class.TeacherId,
+		&class.Name,
+		&class.JoinCode,
+		&class.CreatedAt,
+		&class.UpdatedAt,
+		&class.MemberCount,
+	)
+	if err != nil {
+		return domain.Class{}, err
+	}
+
+	return class, nil
+}
+
+func (repository *ClassRepositoryImpl) FindByJoinCode(ctx context.Context, tx pgx.Tx, joinCode string) (domain.Class, error) {
+	sqlQuery := `
+	SELECT id, teacher_id, name, COALESCE(join_code, ''), created_at, updated_at
+	FROM classes
+	WHERE join_code = $1
+	`
+
+	class := domain.Class{}
+	err := tx.QueryRow(ctx, sqlQuery, joinCode).Scan(
+		&class.Id,
+		&class.TeacherId,
+		&class.Name,
+		&class.JoinCode,
+		&class.CreatedAt,
+		&class.UpdatedAt,
+	)
+	if err != nil {
+		return domain.Class{}, err
+	}
+
+	return class, nil
+}
+
+func (repository *ClassRepositoryImpl) FindByTeacherId(ctx context.Context, tx pgx.Tx, teacherId string) ([]domain.Class, error) {
+	sqlQuery := `
+	SELECT c.id, c.teacher_id, c.name, COALESCE(c.join_code, ''), c.created_at, c.updated_at,
+		(SELECT COUNT(*) FROM class_members m WHERE m.class_id = c.id)
+	FROM classes c
+	WHERE c.teacher_id = $1
+	ORDER BY c.name
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, teacherId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	classes := []domain.Class{}
+	for rows.Next() {
+		class := domain.Class{}
+		err := rows.Scan(
+			&class.Id,
+			&class.TeacherId,
+			&class.Name,
+			&class.JoinCode,
+			&class.CreatedAt,
+			&class.UpdatedAt,
+			&class.MemberCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		classes = append(classes, class)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return classes, nil
+}
+
+func (repository *ClassRepositoryImpl) Delete(ctx context.Context, tx pgx.Tx, classId string) error {
+	sqlQuery := `
+	DELETE FROM classes
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, classId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateJoinCodeById sets the class join code, an empty code revokes it
+func (repository *ClassRepositoryImpl) UpdateJoinCodeById(ctx context.Context, tx pgx.Tx, classId, joinCode string) error {
+	sqlQuery := `
+	UPDATE classes
+	SET join_code = NULLIF($1, ''), updated_at = now()
+	WHERE id = $2
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, joinCode, classId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *ClassRepositoryImpl) IsJoinCodeTaken(ctx context.Context, tx pgx.Tx, joinCode string) (bool, error) {
+	sqlQuery := `
+	SELECT EXISTS (
+		SELECT 1 FROM classes WHERE join_code = $1
+	)
+	`
+
+	var isTaken bool
+	err := tx.QueryRow(ctx, sqlQuery, joinCode).Scan(&isTaken)
+	if err != nil {
+		return false, err
+	}
+
+	return isTaken, nil
+}
+
+func (repository *ClassRepositoryImpl) FindMembers(ctx context.Context, tx pgx.Tx, classId string) ([]domain.ClassMember, error) {
+	sqlQuery := `
+	SELECT u.id, u.full_name, u.email, m.joined_at
+	FROM class_members m
+	JOIN users u ON u.id = m.student_id
+	WHERE m.class_id = $1
+	ORDER BY u.full_name
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, classId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []domain.ClassMember{}
+	for rows.Next() {
+		member := domain.ClassMember{}
+		err := rows.Scan(
+			&member.StudentId,
+			&member.FullName,
+			&member.Email,
+			&member.JoinedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (repository *ClassRepositoryImpl) SaveMember(ctx context.Context, tx pgx.Tx, classId, studentId string) error {
+	sqlQuery := `
+	INSERT INTO class_members (class_id, student_id)
+	VALUES ($1, $2)
+	ON CONFLICT DO NOTHING
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, classId, studentId)
+
+	return err
+}
+
+func (repository *ClassRepositoryImpl) DeleteMember(ctx context.Context, tx pgx.Tx, classId, studentId string) error {
+	sqlQuery := `
+	DELETE FROM class_members
+	WHERE class_id = $1 AND student_id = $2
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, classId, studentId)
+
+	return err
+}
+
+func (repository *ClassRepositoryImpl) FindInvitations(ctx context.Context, tx pgx.Tx, classId string) ([]domain.ClassInvitation, error) {
+	sqlQuery := `
+	SELECT email, invited_at
+	FROM class_invitations
+	WHERE class_id = $1
+	ORDER BY invited_at DESC
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, classId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []domain.ClassInvitation{}
+	for rows.Next() {
+		invitation := domain.ClassInvitation{}
+		err := rows.Scan(
+			&invitation.Email,
+			&invitation.InvitedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+func (repository *ClassRepositoryImpl) SaveInvitation(ctx context.Context, tx pgx.Tx, classId, email string) error {
+	sqlQuery := `
+	INSERT INTO class_invitations (class_id, email)
+	VALUES ($1, $2)
+	ON CONFLICT DO NOTHING
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, classId, email)
+
+	return err
+}
+
+func (repository *ClassRepositoryImpl) DeleteInvitation(ctx context.Context, tx pgx.Tx, classId, email string) error {
+	sqlQuery := `
+	DELETE FROM class_invitations
+	WHERE class_id = $1 AND email = $2
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, classId, email)
+
+	return err
+}
+
+func (repository *ClassRepositoryImpl) FindExamsByClassId(ctx context.Context, tx pgx.Tx, classId string) ([]domain.Exam, error) {
+	sqlQuery := `
+	SELECT e.id, e.name, e.year, e.teacher_id, e.duration_in_minutes, e.is_active, e.created_at, e.updated_at
+	FROM exams e
+	JOIN exam_classes ec ON ec.exam_id = e.id
+	WHERE ec.class_id = $1
+	ORDER BY e.created_at DESC
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, classId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exams := []domain.Exam{}
+	for rows.Next() {
+		exam := domain.Exam{}
+		err := rows.Scan(
+			&exam.Id,
+			&exam.RoomName,
+			&exam.Year,
+			&exam.TeacherId,
+			&exam.Duration,
+			&exam.IsActive,
+			&exam.CreatedAt,
+			&exam.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		exams = append(exams, exam)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return exams, nil
+}
+
+func (repository *ClassRepositoryImpl) FindClassIdsByExamId(ctx context.Context, tx pgx.Tx, examId string) ([]string, error) {
+	sqlQuery := `
+	SELECT class_id
+	FROM exam_classes
+	WHERE exam_id = $1
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, examId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	classIds := []string{}
+	for rows.Next() {
+		var classId string
+		if err := rows.Scan(&classId); err != nil {
+			return nil, err
+		}
+		classIds = append(classIds, classId)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return classIds, nil
+}
+
+// UpdateExamClasses replaces the exam's assignments to the teacher's classes, assignments to
+// classes of other teachers are left alone.
+func (repository *ClassRepositoryImpl) UpdateExamClasses(ctx context.Context, tx pgx.Tx, examId, teacherId string, classIds []string) error {
+	deleteQuery := `
+	DELETE FROM exam_classes ec
+	USING classes c
+	WHERE ec.class_id = c.id AND ec.exam_id = $1 AND c.teacher_id = $2
+	`
+
+	_, err := tx.Exec(ctx, deleteQuery, examId, teacherId)
+	if err != nil {
+		return err
+	}
+
+	insertQuery := `
+	INSERT INTO exam_classes (exam_id, class_id)
+	VALUES ($1, $2)
+	ON CONFLICT DO NOTHING
+	`
+
+	for _, classId := range classIds {
+		_, err := tx.Exec(ctx, insertQuery, examId, classId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MakePrivateWhenUnassigned makes the exams that are no longer assigned to any class private,
+// so removing the last class never opens an exam to every student
+func (repository *ClassRepositoryImpl) MakePrivateWhenUnassigned(ctx context.Context, tx pgx.Tx, examIds []string) error {
+	sqlQuery := `
+	UPDATE exams e
+	SET is_private = true, updated_at = now()
+	WHERE e.id = ANY($1)
+		AND NOT EXISTS (SELECT 1 FROM exam_classes ec WHERE ec.exam_id = e.id)
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, examIds)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+type LTIRepository interface {
+	// Platforms
+	SavePlatform(ctx context.Context, tx pgx.Tx, platform domain.LTIPlatform) (domain.LTIPlatform, error)
+	FindPlatforms(ctx context.Context, tx pgx.Tx) ([]domain.LTIPlatform, error)
+	FindPlatformById(ctx context.Context, tx pgx.Tx, platformId string) (domain.LTIPlatform, error)
+	FindPlatformsByIssuer(ctx context.Context, tx pgx.Tx, issuer string) ([]domain.LTIPlatform, error)
+	DeletePlatform(ctx context.Context, tx pgx.Tx, platformId string) (bool, error)
+
+	// Login initiation
+	SaveLoginState(ctx context.Context, tx pgx.Tx, state domain.LTILoginState, ttl time.Duration) error
+	ConsumeLoginState(ctx context.Context, tx pgx.Tx, state string) (domain.LTILoginState, error)
+	DeleteExpiredLoginStates(ctx context.Context, tx pgx.Tx) error
+
+	// Resource links and the users that opened them
+	FindResourceLink(ctx context.Context, tx pgx.Tx, platformId, resourceLinkId string) (domain.LTIResourceLink, error)
+	SaveResourceLink(ctx context.Context, tx pgx.Tx, link domain.LTIResourceLink) (domain.LTIResourceLink, error)
+	SaveLinkUser(ctx context.Context, tx pgx.Tx, linkId, userId, ltiUserId string) error
+
+	// Score sync queue
+	QueueScoreSync(ctx context.Context, tx pgx.Tx, examId, userId string) error
+	ClaimDueScoreSyncs(ctx context.Context, tx pgx.Tx, limit int, lease time.Duration) ([]domain.LTIScoreSync, error)
+	MarkScoreSynced(ctx context.Context, tx pgx.Tx, sync domain.LTIScoreSync, score int) error
+	MarkScoreSyncRetry(ctx context.Context, tx pgx.Tx, sync domain.LTIScoreSync, lastError string, delay time.Duration) error
+	MarkScoreSyncFailed(ctx context.Context, tx pgx.Tx, sync domain.LTIScoreSync, lastError string) error
+	MarkScoreSyncSkipped(ctx context.Context, tx pgx.Tx, sync domain.LTIScoreSync) error
+	FindRecentScoreSyncs(ctx context.Context, tx pgx.Tx, limit int) ([]domain.LTIScoreSync, error)
+}
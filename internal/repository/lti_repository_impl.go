@@ -0,0 +1,457 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+func NewLTIRepository() LTIRepository {
+	return &LTIRepositoryImpl{}
+}
+
+type LTIRepositoryImpl struct{}
+
+func (repository *LTIRepositoryImpl) SavePlatform(ctx context.Context, tx pgx.Tx, platform domain.LTIPlatform) (domain.LTIPlatform, error) {
+	sqlQuery := `
+	INSERT INTO lti_platforms (name, issuer, client_id, auth_login_url, auth_token_url, jwks_url, deployment_ids)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id, created_at
+	`
+
+	err := tx.QueryRow(ctx, sqlQuery,
+		platform.Name,
+		platform.Issuer,
+		platform.ClientId,
+		platform.AuthLoginURL,
+		platform.AuthTokenURL,
+		platform.JWKSURL,
+		platform.DeploymentIds,
+	).Scan(&platform.Id, &platform.CreatedAt)
+	if err != nil {
+		return domain.LTIPlatform{}, err
+	}
+
+	return platform, nil
+}
+
+func (repository *LTIRepositoryImpl) FindPlatforms(ctx context.Context, tx pgx.Tx) ([]domain.LTIPlatform, error) {
+	sqlQuery := `
+	SELECT p.id, p.name, p.issuer, p.client_id, p.auth_login_url, p.auth_token_url, p.jwks_url, p.deployment_ids, p.created_at,
+		(SELECT COUNT(*) FROM lti_resource_links l WHERE l.platform_id = p.id)
+	FROM lti_platforms p
+	ORDER BY p.name, p.created_at
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	platforms := []domain.LTIPlatform{}
+	for rows.Next() {
+		platform := domain.LTIPlatform{}
+		if err := rows.Scan(
+			&platform.Id,
+			&platform.Name,
+			&platform.Issuer,
+			&platform.ClientId,
+			&platform.AuthLoginURL,
+			&platform.AuthTokenURL,
+			&platform.JWKSURL,
+			&platform.DeploymentIds,
+			&platform.CreatedAt,
+			&platform.LinkCount,
+		); err != nil {
+			return nil, err
+		}
+		platforms = append(platforms, platform)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return platforms, nil
+}
+
+func (repository *LTIRepositoryImpl) FindPlatformById(ctx context.Context, tx pgx.Tx, platformId string) (domain.LTIPlatform, error) {
+	sqlQuery := `
+	SELECT id, name, issuer, client_id, auth_login_url, auth_token_url, jwks_url, deployment_ids, created_at
+	FROM lti_platforms
+	WHERE id = $1
+	`
+
+	platform := domain.LTIPlatform{}
+	err := tx.QueryRow(ctx, sqlQuery, platformId).Scan(
+		&platform.Id,
+		&platform.Name,
+		&platform.Issuer,
+		&platform.ClientId,
+		&platform.AuthLoginURL,
+		&platform.AuthTokenURL,
+		&platform.JWKSURL,
+		&platform.DeploymentIds,
+		&platform.CreatedAt,
+	)
+	if err != nil {
+		return domain.LTIPlatform{}, err
+	}
+
+	return platform, nil
+}
+
+// FindPlatformsByIssuer returns every registration of the issuer, one platform may register the tool more than once
+func (repository *LTIRepositoryImpl) FindPlatformsByIssuer(ctx context.Context, tx pgx.Tx, issuer string) ([]domain.LTIPlatform, error) {
+	sqlQuery := `
+	SELECT id, name, issuer, client_id, auth_login_url, auth_token_url, jwks_url, deployment_ids, created_at
+	FROM lti_platforms
+	WHERE issuer = $1
+	ORDER BY created_at
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, issuer)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	platforms := []domain.LTIPlatform{}
+	for rows.Next() {
+		platform := domain.LTIPlatform{}
+		if err := rows.Scan(
+			&platform.Id,
+			&platform.Name,
+			&platform.Issuer,
+			&platform.ClientId,
+			&platform.AuthLoginURL,
+			&platform.AuthTokenURL,
+			&platform.JWKSURL,
+			&platform.DeploymentIds,
+			&platform.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		platforms = append(platforms, platform)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return platforms, nil
+}
+
+func (repository *LTIRepositoryImpl) DeletePlatform(ctx context.Context, tx pgx.Tx, platformId string) (bool, error) {
+	sqlQuery := `
+	DELETE FROM lti_platforms
+	WHERE id = $1
+	`
+
+	tag, err := tx.Exec(ctx, sqlQuery, platformId)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// SaveLoginState computes expires_at in the database so it uses the same clock as ConsumeLoginState
+func (repository *LTIRepositoryImpl) SaveLoginState(ctx context.Context, tx pgx.Tx, state domain.LTILoginState, ttl time.Duration) error {
+	sqlQuery := `
+	INSERT INTO lti_login_states (state, platform_id, nonce, expires_at)
+	VALUES ($1, $2, $3, CURRENT_TIMESTAMP + make_interval(secs => $4))
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, state.State, state.PlatformId, state.Nonce, ttl.Seconds())
+
+	return err
+}
+
+// ConsumeLoginState deletes the state while reading it, so a launch can not be replayed. An
+// unknown or expired state returns pgx.ErrNoRows.
+func (repository *LTIRepositoryImpl) ConsumeLoginState(ctx context.Context, tx pgx.Tx, state string) (domain.LTILoginState, error) {
+	sqlQuery := `
+	DELETE FROM lti_login_states
+	WHERE state = $1 AND expires_at > CURRENT_TIMESTAMP
+	RETURNING state, platform_id, nonce, expires_at
+	`
+
+	loginState := domain.LTILoginState{}
+	err := tx.QueryRow(ctx, sqlQuery, state).Scan(
+		&loginState.State,
+		&loginState.PlatformId,
+		&loginState.Nonce,
+		&loginState.ExpiresAt,
+	)
+	if err != nil {
+		return domain.LTILoginState{}, err
+	}
+
+	return loginState, nil
+}
+
+func (repository *LTIRepositoryImpl) DeleteExpiredLoginStates(ctx context.Context, tx pgx.Tx) error {
+	sqlQuery := `
+	DELETE FROM lti_login_states
+	WHERE expires_at <= CURRENT_TIMESTAMP
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery)
+
+	return err
+}
+
+func (repository *LTIRepositoryImpl) FindResourceLink(ctx context.Context, tx pgx.Tx, platformId, resourceLinkId string) (domain.LTIResourceLink, error) {
+	sqlQuery := `
+	SELECT id, platform_id, deployment_id, resource_link_id, exam_id, title, context_id, context_title, lineitem_url, created_at, updated_at
+	FROM lti_resource_links
+	WHERE platform_id = $1 AND resource_link_id = $2
+	`
+
+	link := domain.LTIResourceLink{}
+	err := tx.QueryRow(ctx, sqlQuery, platformId, resourceLinkId).Scan(
+		&link.Id,
+		&link.PlatformId,
+		&link.DeploymentId,
+		&link.ResourceLinkId,
+		&link.ExamId,
+		&link.Title,
+		&link.ContextId,
+		&link.ContextTitle,
+		&link.LineItemURL,
+		&link.CreatedAt,
+		&link.UpdatedAt,
+	)
+	if err != nil {
+		return domain.LTIResourceLink{}, err
+	}
+
+	return link, nil
+}
+
+// SaveResourceLink inserts the link or refreshes its titles and line item, the exam of a link never changes
+func (repository *LTIRepositoryImpl) SaveResourceLink(ctx context.Context, tx pgx.Tx, link domain.LTIResourceLink) (domain.LTIResourceLink, error) {
+	sqlQuery := `
+	INSERT INTO lti_resource_links (platform_id, deployment_id, resource_link_id, exam_id, title, context_id, context_title, lineitem_url)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (platform_id, resource_link_id) DO UPDATE
+	SET title = EXCLUDED.title,
+		context_id = EXCLUDED.context_id,
+		context_title = EXCLUDED.context_title,
+		lineitem_url = CASE WHEN EXCLUDED.lineitem_url <> '' THEN EXCLUDED.lineitem_url ELSE lti_resource_links.lineitem_url END,
+		updated_at = CURRENT_TIMESTAMP
+	RETURNING id, exam_id, lineitem_url, created_at, updated_at
+	`
+
+	err := tx.QueryRow(ctx, sqlQuery,
+		link.PlatformId,
+		link.DeploymentId,
+		link.ResourceLinkId,
+		link.ExamId,
+		link.Title,
+		link.ContextId,
+		link.ContextTitle,
+		link.LineItemURL,
+	).Scan(&link.Id, &link.ExamId, &link.LineItemURL, &link.CreatedAt, &link.UpdatedAt)
+	if err != nil {
+		return domain.LTIResourceLink{}, err
+	}
+
+	return link, nil
+}
+
+func (repository *LTIRepositoryImpl) SaveLinkUser(ctx context.Context, tx pgx.Tx, linkId, userId, ltiUserId string) error {
+	sqlQuery := `
+	INSERT INTO lti_link_users (resource_link_id, user_id, lti_user_id)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (resource_link_id, user_id) DO UPDATE
+	SET lti_user_id = EXCLUDED.lti_user_id
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, linkId, userId, ltiUserId)
+
+	return err
+}
+
+// QueueScoreSync marks the user for a score sync on every link of the exam that has a line item.
+// The attempts counter starts over, a new score deserves the full retry schedule.
+func (repository *LTIRepositoryImpl) QueueScoreSync(ctx context.Context, tx pgx.Tx, examId, userId string) error {
+	sqlQuery := `
+	UPDATE lti_link_users u
+	SET score_status = 'pending', score_attempts = 0, next_sync_at = CURRENT_TIMESTAMP, last_error = ''
+	FROM lti_resource_links l
+	WHERE l.id = u.resource_link_id AND l.exam_id = $1 AND u.user_id = $2 AND l.lineitem_url <> ''
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, examId, userId)
+
+	return err
+}
+
+// ClaimDueScoreSyncs locks the pending syncs that are due and moves their next sync past the lease,
+// the best completed score is read in the same statement. The new next_sync_at is returned, a Mark
+// call only applies while it is unchanged, so a score queued during the send is not lost.
+func (repository *LTIRepositoryImpl) ClaimDueScoreSyncs(ctx context.Context, tx pgx.Tx, limit int, lease time.Duration) ([]domain.LTIScoreSync, error) {
+	sqlQuery := `
+	WITH due AS (
+		SELECT id
+		FROM lti_link_users
+		WHERE score_status = 'pending' AND next_sync_at <= CURRENT_TIMESTAMP
+		ORDER BY next_sync_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	UPDATE lti_link_users u
+	SET next_sync_at = CURRENT_TIMESTAMP + make_interval(secs => $2)
+	FROM due, lti_resource_links l, lti_platforms p
+	WHERE u.id = due.id AND l.id = u.resource_link_id AND p.id = l.platform_id
+	RETURNING u.id, u.user_id, u.lti_user_id, l.exam_id, l.lineitem_url, u.score_status, u.score_attempts, u.next_sync_at,
+		p.name, p.issuer, p.client_id, p.auth_token_url,
+		(SELECT MAX(a.score) FROM exam_attempts a
+			WHERE a.exam_id = l.exam_id AND a.student_id = u.user_id AND a.completed_at > '0001-01-01 00:00:00'),
+		(SELECT MAX(a.completed_at) FROM exam_attempts a
+			WHERE a.exam_id = l.exam_id AND a.student_id = u.user_id AND a.completed_at > '0001-01-01 00:00:00')
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	syncs := []domain.LTIScoreSync{}
+	for rows.Next() {
+		sync := domain.LTIScoreSync{}
+		if err := rows.Scan(
+			&sync.Id,
+			&sync.UserId,
+			&sync.LTIUserId,
+			&sync.ExamId,
+			&sync.LineItemURL,
+			&sync.Status,
+			&sync.Attempts,
+			&sync.NextSyncAt,
+			&sync.PlatformName,
+			&sync.Issuer,
+			&sync.ClientId,
+			&sync.AuthTokenURL,
+			&sync.Score,
+			&sync.CompletedAt,
+		); err != nil {
+			return nil, err
+		}
+		syncs = append(syncs, sync)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return syncs, nil
+}
+
+func (repository *LTIRepositoryImpl) MarkScoreSynced(ctx context.Context, tx pgx.Tx, sync domain.LTIScoreSync, score int) error {
+	sqlQuery := `
+	UPDATE lti_link_users
+	SET score_status = 'succeeded', score_attempts = score_attempts + 1, last_error = '',
+		last_score = $1, synced_at = CURRENT_TIMESTAMP
+	WHERE id = $2 AND next_sync_at = $3
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, score, sync.Id, sync.NextSyncAt)
+
+	return err
+}
+
+func (repository *LTIRepositoryImpl) MarkScoreSyncRetry(ctx context.Context, tx pgx.Tx, sync domain.LTIScoreSync, lastError string, delay time.Duration) error {
+	sqlQuery := `
+	UPDATE lti_link_users
+	SET score_attempts = score_attempts + 1, last_error = $1,
+		next_sync_at = CURRENT_TIMESTAMP + make_interval(secs => $2)
+	WHERE id = $3 AND next_sync_at = $4
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, lastError, delay.Seconds(), sync.Id, sync.NextSyncAt)
+
+	return err
+}
+
+func (repository *LTIRepositoryImpl) MarkScoreSyncFailed(ctx context.Context, tx pgx.Tx, sync domain.LTIScoreSync, lastError string) error {
+	sqlQuery := `
+	UPDATE lti_link_users
+	SET score_status = 'failed', score_attempts = score_attempts + 1, last_error = $1
+	WHERE id = $2 AND next_sync_at = $3
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, lastError, sync.Id, sync.NextSyncAt)
+
+	return err
+}
+
+// MarkScoreSyncSkipped puts the sync back to "none" when the user has no completed attempt to send
+func (repository *LTIRepositoryImpl) MarkScoreSyncSkipped(ctx context.Context, tx pgx.Tx, sync domain.LTIScoreSync) error {
+	sqlQuery := `
+	UPDATE lti_link_users
+	SET score_status = 'none'
+	WHERE id = $1 AND next_sync_at = $2
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, sync.Id, sync.NextSyncAt)
+
+	return err
+}
+
+// FindRecentScoreSyncs lists the latest syncs that have a status other than "none", for the admin page
+func (repository *LTIRepositoryImpl) FindRecentScoreSyncs(ctx context.Context, tx pgx.Tx, limit int) ([]domain.LTIScoreSync, error) {
+	sqlQuery := `
+	SELECT u.id, u.user_id, usr.full_name, u.lti_user_id, l.exam_id, l.title, l.lineitem_url, u.score_status, u.score_attempts,
+		u.last_error, u.last_score, u.synced_at, u.next_sync_at, p.name
+	FROM lti_link_users u
+	JOIN lti_resource_links l ON l.id = u.resource_link_id
+	JOIN lti_platforms p ON p.id = l.platform_id
+	JOIN users usr ON usr.id = u.user_id
+	WHERE u.score_status <> 'none'
+	ORDER BY COALESCE(u.synced_at, u.next_sync_at) DESC
+	LIMIT $1
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	syncs := []domain.LTIScoreSync{}
+	for rows.Next() {
+		sync := domain.LTIScoreSync{}
+		if err := rows.Scan(
+			&sync.Id,
+			&sync.UserId,
+			&sync.StudentName,
+			&sync.LTIUserId,
+			&sync.ExamId,
+			&sync.LinkTitle,
+			&sync.LineItemURL,
+			&sync.Status,
+			&sync.Attempts,
+			&sync.LastError,
+			&sync.LastScore,
+			&sync.SyncedAt,
+			&sync.NextSyncAt,
+			&sync.PlatformName,
+		); err != nil {
+			return nil, err
+		}
+		syncs = append(syncs, sync)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return syncs, nil
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+type MaterialRepository interface {
+	Save(ctx context.Context, tx pgx.Tx, material domain.Material) (domain.Material, error)
+	FindById(ctx context.Context, tx pgx.Tx, materialId string) (domain.Material, error)
+	FindByTeacherIdAndHash(ctx context.Context, tx pgx.Tx, teacherId, contentHash string) (domain.Material, error)
+	FindByTeacherId(ctx context.Context, tx pgx.Tx, teacherId string) ([]domain.Material, error)
+	Delete(ctx context.Context, tx pgx.Tx, materialId string) error
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+func NewMaterialRepository() MaterialRepository {
+	return &MaterialRepositoryImpl{}
+}
+
+type MaterialRepositoryImpl struct{}
+
+func (repository *MaterialRepositoryImpl) Save(ctx context.Context, tx pgx.Tx, material domain.Material) (domain.Material, error) {
+	sqlQuery := `
+	INSERT INTO materials (teacher_id, file_name, content_hash, size_bytes, storage_key)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, created_at
+	`
+
+	err := tx.QueryRow(
+		ctx,
+		sqlQuery,
+		material.TeacherId,
+		material.FileName,
+		material.ContentHash,
+		material.SizeBytes,
+		material.StorageKey,
+	).Scan(
+		&material.Id,
+		&material.CreatedAt,
+	)
+	if err != nil {
+		return domain.Material{}, err
+	}
+
+	return material, nil
+}
+
+func (repository *MaterialRepositoryImpl) FindById(ctx context.Context, tx pgx.Tx, materialId string) (domain.Material, error) {
+	sqlQuery := `
+	SELECT id, teacher_id, file_name, content_hash, size_bytes, storage_key, created_at
+	FROM materials
+	WHERE id = $1
+	`
+
+	material := domain.Material{}
+	err := tx.QueryRow(ctx, sqlQuery, materialId).Scan(
+		&material.Id,
+		&material.TeacherId,
+		&material.FileName,
+		&material.ContentHash,
+		&material.SizeBytes,
+		&material.StorageKey,
+		&material.CreatedAt,
+	)
+	if err != nil {
+		return domain.Material{}, err
+	}
+
+	return material, nil
+}
+
+func (repository *MaterialRepositoryImpl) FindByTeacherIdAndHash(ctx context.Context, tx pgx.Tx, teacherId, contentHash string) (domain.Material, error) {
+	sqlQuery := `
+	SELECT id, teacher_id, file_name, content_hash, size_bytes, storage_key, created_at
+	FROM materials
+	WHERE teacher_id = $1 AND content_hash = $2
+	`
+
+	material := domain.Material{}
+	err := tx.QueryRow(ctx, sqlQuery, teacherId, contentHash).Scan(
+		&material.Id,
+		&material.TeacherId,
+		&material.FileName,
+		&material.ContentHash,
+		&material.SizeBytes,
+		&material.StorageKey,
+		&material.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Material{}, nil
+		}
+
+		return domain.Material{}, err
+	}
+
+	return material, nil
+}
+
+func (repository *MaterialRepositoryImpl) FindByTeacherId(ctx context.Context, tx pgx.Tx, teacherId string) ([]domain.Material, error) {
+	sqlQuery := `
+	SELECT m.id, m.teacher_id, m.file_name, m.content_hash, m.size_bytes, m.storage_key, m.created_at, COUNT(e.id)
+	FROM materials m
+	LEFT JOIN exams e ON e.material_id = m.id
+	WHERE m.teacher_id = $1
+	GROUP BY m.id
+	ORDER BY m.created_at DESC
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, teacherId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	materials := []domain.Material{}
+	for rows.Next() {
+		material := domain.Material{}
+		err := rows.Scan(
+			&material.Id,
+			&material.TeacherId,
+			&material.FileName,
+			&material.ContentHash,
+			&material.SizeBytes,
+			&material.StorageKey,
+			&material.CreatedAt,
+			&material.ExamCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		materials = append(materials, material)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return materials, nil
+}
+
+func (repository *MaterialRepositoryImpl) Delete(ctx context.Context, tx pgx.Tx, materialId string) error {
+	sqlQuery := `
+	DELETE FROM materials
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, materialId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
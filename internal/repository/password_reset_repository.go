@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+type PasswordResetRepository interface {
+	Save(ctx context.Context, tx pgx.Tx, token domain.PasswordResetToken, ttl time.Duration) (domain.PasswordResetToken, error)
+	FindValidByTokenHash(ctx context.Context, tx pgx.Tx, tokenHash string) (domain.PasswordResetToken, error)
+	MarkUsed(ctx context.Context, tx pgx.Tx, tokenId string) error
+	DeleteUnusedByUserId(ctx context.Context, tx pgx.Tx, userId string) error
+
+	SaveRequest(ctx context.Context, tx pgx.Tx, email, ipAddress string) error
+	CountRequestsByEmail(ctx context.Context, tx pgx.Tx, email string, window time.Duration) (int, error)
+	CountRequestsByIP(ctx context.Context, tx pgx.Tx, ipAddress string, window time.Duration) (int, error)
+}
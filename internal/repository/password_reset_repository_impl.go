@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+func NewPasswordResetRepository() PasswordResetRepository {
+	return &PasswordResetRepositoryImpl{}
+}
+
+type PasswordResetRepositoryImpl struct{}
+
+// Save computes expires_at in the database so it uses the same clock as the validity check
+func (repository *PasswordResetRepositoryImpl) Save(ctx context.Context, tx pgx.Tx, token domain.PasswordResetToken, ttl time.Duration) (domain.PasswordResetToken, error) {
+	sqlQuery := `
+	INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+	VALUES ($1, $2, CURRENT_TIMESTAMP + make_interval(secs => $3))
+	RETURNING id, expires_at, created_at
+	`
+
+	err := tx.QueryRow(
+		ctx,
+		sqlQuery,
+		token.UserId,
+		token.TokenHash,
+		ttl.Seconds(),
+	).Scan(
+		&token.Id,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return domain.PasswordResetToken{}, err
+	}
+
+	return token, nil
+}
+
+// FindValidByTokenHash only returns tokens that are unused and not expired, otherwise pgx.ErrNoRows.
+// The row is locked so the same token can not be used twice at the same time.
+func (repository *PasswordResetRepositoryImpl) FindValidByTokenHash(ctx context.Context, tx pgx.Tx, tokenHash string) (domain.PasswordResetToken, error) {
+	sqlQuery := `
+	SELECT id, user_id, token_hash, expires_at, created_at
+	FROM password_reset_tokens
+	WHERE token_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+	FOR UPDATE
+	`
+
+	token := domain.PasswordResetToken{}
+
+	err := tx.QueryRow(ctx, sqlQuery, tokenHash).Scan(
+		&token.Id,
+		&token.UserId,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return domain.PasswordResetToken{}, err
+	}
+
+	return token, nil
+}
+
+func (repository *PasswordResetRepositoryImpl) MarkUsed(ctx context.Context, tx pgx.Tx, tokenId string) error {
+	sqlQuery := `
+	UPDATE password_reset_tokens
+	SET used_at = CURRENT_TIMESTAMP
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, tokenId)
+
+	return err
+}
+
+// DeleteUnusedByUserId invalidates older links when a new one is requested or the password changed
+func (repository *PasswordResetRepositoryImpl) DeleteUnusedByUserId(ctx context.Context, tx pgx.Tx, userId string) error {
+	sqlQuery := `
+	DELETE FROM password_reset_tokens
+	WHERE user_id = $1 AND used_at IS NULL
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, userId)
+
+	return err
+}
+
+// SaveRequest records a forgot password request, also for emails that are not registered
+func (repository *PasswordResetRepositoryImpl) SaveRequest(ctx context.Context, tx pgx.Tx, email, ipAddress string) error {
+	sqlQuery := `
+	INSERT INTO password_reset_requests (email, ip_address)
+	VALUES ($1, $2)
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, email, ipAddress)
+
+	return err
+}
+
+func (repository *PasswordResetRepositoryImpl) CountRequestsByEmail(ctx context.Context, tx pgx.Tx, email string, window time.Duration) (int, error) {
+	sqlQuery := `
+	SELECT COUNT(*)
+	FROM password_reset_requests
+	WHERE email = $1
+		AND requested_at > CURRENT_TIMESTAMP - make_interval(secs => $2)
+	`
+
+	var count int
+	err := tx.QueryRow(ctx, sqlQuery, email, window.Seconds()).Scan(&count)
+
+	return count, err
+}
+
+func (repository *PasswordResetRepositoryImpl) CountRequestsByIP(ctx context.Context, tx pgx.Tx, ipAddress string, window time.Duration) (int, error) {
+	sqlQuery := `
+	SELECT COUNT(*)
+	FROM password_reset_requests
+	WHERE ip_address = $1
+		AND requested_at > CURRENT_TIMESTAMP - make_interval(secs => $2)
+	`
+
+	var count int
+	err := tx.QueryRow(ctx, sqlQuery, ipAddress, window.Seconds()).Scan(&count)
+
+	return count, err
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+type QuestionBankRepository interface {
+	Save(ctx context.Context, tx pgx.Tx, bankQuestion domain.BankQuestion) (domain.BankQuestion, error)
+	FindById(ctx context.Context, tx pgx.Tx, bankQuestionId string) (domain.BankQuestion, error)
+	FindByTeacherId(ctx context.Context, tx pgx.Tx, teacherId, tag, topic string) ([]domain.BankQuestion, error)
+	FindTagsByTeacherId(ctx context.Context, tx pgx.Tx, teacherId string) ([]domain.BankTag, error)
+	FindTopicsByTeacherId(ctx context.Context, tx pgx.Tx, teacherId string) ([]string, error)
+	FindRandomIdsByTag(ctx context.Context, tx pgx.Tx, teacherId, tag string, limit int, excludeIds []string) ([]string, error)
+	Update(ctx context.Context, tx pgx.Tx, bankQuestion domain.BankQuestion) error
+	Delete(ctx context.Context, tx pgx.Tx, bankQuestionId string) error
+
+	LinkQuestion(ctx context.Context, tx pgx.Tx, questionId, bankQuestionId string) error
+	BulkSaveReferences(ctx context.Context, tx pgx.Tx, bankQuestionIds []string, examId string) error
+}
@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+func NewQuestionBankRepository() QuestionBankRepository {
+	return &QuestionBankRepositoryImpl{}
+}
+
+type QuestionBankRepositoryImpl struct{}
+
+func (repository *QuestionBankRepositoryImpl) Save(ctx context.Context, tx pgx.Tx, bankQuestion domain.BankQuestion) (domain.BankQuestion, error) {
+	sqlQuery := `
+	INSERT INTO bank_questions (teacher_id, question, correct_answer, topic)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at, updated_at
+	`
+
+	err := tx.QueryRow(
+		ctx,
+		sqlQuery,
+		bankQuestion.TeacherId,
+		bankQuestion.Question,
+		bankQuestion.Answer,
+		bankQuestion.Topic,
+	).Scan(
+		&bankQuestion.Id,
+		&bankQuestion.CreatedAt,
+		&bankQuestion.UpdatedAt,
+	)
+	if err != nil {
+		return domain.BankQuestion{}, err
+	}
+
+	tagQuery := `
+	INSERT INTO bank_question_tags (bank_question_id, tag)
+	VALUES ($1, $2)
+	ON CONFLICT DO NOTHING
+	`
+
+	for _, tag := range bankQuestion.Tags {
+		_, err := tx.Exec(ctx, tagQuery, bankQuestion.Id, tag)
+		if err != nil {
+			return domain.BankQuestion{}, err
+		}
+	}
+
+	return bankQuestion, nil
+}
+
+func (repository *QuestionBankRepositoryImpl) FindById(ctx context.Context, tx pgx.Tx, bankQuestionId string) (domain.BankQuestion, error) {
+	sqlQuery := `
+	SELECT b.id, b.teacher_id, b.question, b.correct_answer, b.topic, b.created_at, b.updated_at,
+		COALESCE(array_agg(DISTINCT t.tag) FILTER (WHERE t.tag IS NOT NULL), '{}'),
+		(SELECT COUNT(*) FROM questions q WHERE q.bank_question_id = b.id)
+	FROM bank_questions b
+	LEFT JOIN bank_question_tags t ON t.bank_question_id = b.id
+	WHERE b.id = $1
+	GROUP BY b.id
+	`
+
+	bankQuestion := domain.BankQuestion{}
+	err := tx.QueryRow(ctx, sqlQuery, bankQuestionId).Scan(
+		&bankQuestion.Id,
+		&bankQuestion.TeacherId,
+		&bankQuestion.Question,
+		&bankQuestion.Answer,
+		&bankQuestion.Topic,
+		&bankQuestion.CreatedAt,
+		&bankQuestion.UpdatedAt,
+		&bankQuestion.Tags,
+		&bankQuestion.UsageCount,
+	)
+	if err != nil {
+		return domain.BankQuestion{}, err
+	}
+
+	return bankQuestion, nil
+}
+
+// FindByTeacherId lists the teacher's bank, tag and topic are optional filters
+func (repository *QuestionBankRepositoryImpl) FindByTeacherId(ctx context.Context, tx pgx.Tx, teacherId, tag, topic string) ([]domain.BankQuestion, error) {
+	sqlQuery := `
+	SELECT b.id, b.teacher_id, b.question, b.correct_answer, b.topic, b.created_at, b.updated_at,
+		COALESCE(array_agg(DISTINCT t.tag) FILTER (WHERE t.tag IS NOT NULL), '{}'),
+		(SELECT COUNT(*) FROM questions q WHERE q.bank_question_id = b.id)
+	FROM bank_questions b
+	LEFT JOIN bank_question_tags t ON t.bank_question_id = b.id
+	WHERE b.teacher_id = $1
+		AND ($2 = '' OR EXISTS (SELECT 1 FROM bank_question_tags ft WHERE ft.bank_question_id = b.id AND ft.tag = $2))
+		AND ($3 = '' OR b.topic = $3)
+	GROUP BY b.id
+	ORDER BY b.created_at DESC
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, teacherId, tag, topic)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bankQuestions := []domain.BankQuestion{}
+	for rows.Next() {
+		bankQuestion := domain.BankQuestion{}
+		err := rows.Scan(
+			&bankQuestion.Id,
+			&bankQuestion.TeacherId,
+			&bankQuestion.Question,
+			&bankQuestion.Answer,
+			&bankQuestion.Topic,
+			&bankQuestion.CreatedAt,
+			&bankQuestion.UpdatedAt,
+			&bankQuestion.Tags,
+			&bankQuestion.UsageCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		bankQuestions = append(bankQuestions, bankQuestion)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return bankQuestions, nil
+}
+
+func (repository *QuestionBankRepositoryImpl) FindTagsByTeacherId(ctx context.Context, tx pgx.Tx, teacherId string) ([]domain.BankTag, error) {
+	sqlQuery := `
+	SELECT t.tag, COUNT(*)
+	FROM bank_question_tags t
+	JOIN bank_questions b ON b.id = t.bank_question_id
+	WHERE b.teacher_id = $1
+	GROUP BY t.tag
+	ORDER BY t.tag
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, teacherId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []domain.BankTag{}
+	for rows.Next() {
+		tag := domain.BankTag{}
+		if err := rows.Scan(&tag.Tag, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (repository *QuestionBankRepositoryImpl) FindTopicsByTeacherId(ctx context.Context, tx pgx.Tx, teacherId string) ([]string, error) {
+	sqlQuery := `
+	SELECT DISTINCT topic
+	FROM bank_questions
+	WHERE teacher_id = $1 AND topic <> ''
+	ORDER BY topic
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, teacherId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	topics := []string{}
+	for rows.Next() {
+		var topic string
+		if err := rows.Scan(&topic); err != nil {
+			return nil, err
+		}
+		topics = append(topics, topic)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return topics, nil
+}
+
+// FindRandomIdsByTag draws up to limit random bank questions with the tag, skipping excludeIds
+func (repository *QuestionBankRepositoryImpl) FindRandomIdsByTag(ctx context.Context, tx pgx.Tx, teacherId, tag string, limit int, excludeIds []string) ([]string, error) {
+	sqlQuery := `
+	SELECT b.id
+	FROM bank_questions b
+	JOIN bank_question_tags t ON t.bank_question_id = b.id
+	WHERE b.teacher_id = $1 AND t.tag = $2 AND NOT (b.id::text = ANY($3))
+	ORDER BY random()
+	LIMIT $4
+	`
+
+	if excludeIds == nil {
+		excludeIds = []string{}
+	}
+
+	rows, err := tx.Query(ctx, sqlQuery, teacherId, tag, excludeIds, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Update replaces the text, topic and tags of the bank question
+func (repository *QuestionBankRepositoryImpl) Update(ctx context.Context, tx pgx.Tx, bankQuestion domain.BankQuestion) error {
+	sqlQuery := `
+	UPDATE bank_questions
+	SET question = $1, correct_answer = $2, topic = $3, updated_at = now()
+	WHERE id = $4
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, bankQuestion.Question, bankQuestion.Answer, bankQuestion.Topic, bankQuestion.Id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM bank_question_tags WHERE bank_question_id = $1`, bankQuestion.Id)
+	if err != nil {
+		return err
+	}
+
+	tagQuery := `
+	INSERT INTO bank_question_tags (bank_question_id, tag)
+	VALUES ($1, $2)
+	ON CONFLICT DO NOTHING
+	`
+
+	for _, tag := range bankQuestion.Tags {
+		_, err := tx.Exec(ctx, tagQuery, bankQuestion.Id, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (repository *QuestionBankRepositoryImpl) Delete(ctx context.Context, tx pgx.Tx, bankQuestionId string) error {
+	sqlQuery := `
+	DELETE FROM bank_questions
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, bankQuestionId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LinkQuestion turns an exam question into a reference to the bank question
+func (repository *QuestionBankRepositoryImpl) LinkQuestion(ctx context.Context, tx pgx.Tx, questionId, bankQuestionId string) error {
+	sqlQuery := `
+	UPDATE questions
+	SET bank_question_id = $1, question = '', correct_answer = ''
+	WHERE id = $2
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, bankQuestionId, questionId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// BulkSaveReferences adds the bank questions to the exam without copying their text
+func (repository *QuestionBankRepositoryImpl) BulkSaveReferences(ctx context.Context, tx pgx.Tx, bankQuestionIds []string, examId string) error {
+	sqlQuery := `
+	INSERT INTO questions (id, question, correct_answer, exam_id, bank_question_id)
+	VALUES ($1, '', '', $2, $3)
+	`
+
+	for _, bankQuestionId := range bankQuestionIds {
+		_, err := tx.Exec(ctx, sqlQuery, uuid.New(), examId, bankQuestionId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
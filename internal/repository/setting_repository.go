@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type SettingRepository interface {
+	FindBool(ctx context.Context, tx pgx.Tx, key string) (bool, error)
+	SaveBool(ctx context.Context, tx pgx.Tx, key string, value bool) error
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func NewSettingRepository() SettingRepository {
+	return &SettingRepositoryImpl{}
+}
+
+type SettingRepositoryImpl struct{}
+
+// FindBool returns false when the setting was never saved
+func (repository *SettingRepositoryImpl) FindBool(ctx context.Context, tx pgx.Tx, key string) (bool, error) {
+	sqlQuery := `
+	SELECT value
+	FROM system_settings
+	WHERE key = $1
+	`
+
+	var value string
+	err := tx.QueryRow(ctx, sqlQuery, key).Scan(&value)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return strconv.ParseBool(value)
+}
+
+func (repository *SettingRepositoryImpl) SaveBool(ctx context.Context, tx pgx.Tx, key string, value bool) error {
+	sqlQuery := `
+	INSERT INTO system_settings (key, value)
+	VALUES ($1, $2)
+	ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, key, strconv.FormatBool(value))
+
+	return err
+}
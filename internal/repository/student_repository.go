@@ -9,13 +9,27 @@ import (
 )
 
 type StudentRepository interface {
-	FindActiveExams(ctx context.Context, tx pgx.Tx) ([]domain.Exam, error)
+	FindActiveExams(ctx context.Context, tx pgx.Tx, studentId string) ([]domain.Exam, error)
+	FindPublicExams(ctx context.Context, tx pgx.Tx, studentId string) ([]domain.Exam, error)
 	FindTeacherById(ctx context.Context, tx pgx.Tx, teacherId string) (domain.User, error)
 	FindExamById(ctx context.Context, tx pgx.Tx, examId string) (domain.Exam, error)
+	FindExamByJoinCode(ctx context.Context, tx pgx.Tx, joinCode string) (domain.Exam, error)
+	SaveExamMember(ctx context.Context, tx pgx.Tx, examId, studentId string) error
+	HasExamAccess(ctx context.Context, tx pgx.Tx, examId, studentId string) (bool, error)
+	IsEmailVerified(ctx context.Context, tx pgx.Tx, studentId string) (bool, error)
+	IsClassExam(ctx context.Context, tx pgx.Tx, examId string) (bool, error)
+	FindClassByJoinCode(ctx context.Context, tx pgx.Tx, joinCode string) (domain.Class, error)
+	SaveClassMember(ctx context.Context, tx pgx.Tx, classId, studentId string) error
+	ClaimClassInvitations(ctx context.Context, tx pgx.Tx, studentId string) error
+	FindClassesByStudentId(ctx context.Context, tx pgx.Tx, studentId string) ([]domain.Class, error)
 	FindQuestionsByExamId(ctx context.Context, tx pgx.Tx, examId string) ([]domain.QAItem, error)
-	CreateExamAttempt(ctx context.Context, tx pgx.Tx, studentId, examId string) (string, error)
+	CreateExamAttempt(ctx context.Context, tx pgx.Tx, studentId, examId string, questionSeed int64, questionIds []string) (string, error)
+	FindAttemptQuestionIds(ctx context.Context, tx pgx.Tx, attemptId string) ([]string, error)
 	SaveAnswer(ctx context.Context, tx pgx.Tx, answer web.StudentAnswer) error
+	SaveOrUpdateAnswer(ctx context.Context, tx pgx.Tx, answer web.StudentAnswer) error
+	FindAttemptById(ctx context.Context, tx pgx.Tx, attemptId string) (web.ExamAttempt, error)
 	CompleteExamAttempt(ctx context.Context, tx pgx.Tx, attemptId string) error
+	FindCompletedAttemptIdsByExamId(ctx context.Context, tx pgx.Tx, examId string) ([]string, error)
 
 	FindExamByAttemptId(ctx context.Context, tx pgx.Tx, attemptId string) (domain.Exam, error)
 	FindAnswersByAttemptId(ctx context.Context, tx pgx.Tx, attemptId string) ([]web.StudentAnswer, error)
@@ -14,14 +14,55 @@ func NewStudentRepository() StudentRepository {
 
 type StudentRepositoryImpl struct{}
 
-func (repository *StudentRepositoryImpl) FindActiveExams(ctx context.Context, tx pgx.Tx) ([]domain.Exam, error) {
+// examJoinedCondition matches exams (aliased e) that student $1 has joined: exams joined with a join
+// code or from the public exam list, and exams assigned to one of the student's classes
+const examJoinedCondition = `(
+		EXISTS (SELECT 1 FROM exam_members m WHERE m.exam_id = e.id AND m.student_id = $1)
+		OR EXISTS (
+			SELECT 1 FROM exam_classes ec
+			JOIN class_members cm ON cm.class_id = ec.class_id
+			WHERE ec.exam_id = e.id AND cm.student_id = $1
+		)
+	)
+	`
+
+// publicExamCondition matches public exams (aliased e) that are not assigned to any class. An exam
+// whose last class is removed is made private, so only the teacher can open it up again.
+const publicExamCondition = `(e.is_private = false AND NOT EXISTS (SELECT 1 FROM exam_classes ec WHERE ec.exam_id = e.id))`
+
+// examAccessCondition matches exams (aliased e) that student $1 may take: the exams they joined and
+// public exams, which any student can join
+const examAccessCondition = `(` + examJoinedCondition + ` OR ` + publicExamCondition + `)`
+
+// FindActiveExams returns the active exams the student has joined, see examJoinedCondition
+func (repository *StudentRepositoryImpl) FindActiveExams(ctx context.Context, tx pgx.Tx, studentId string) ([]domain.Exam, error) {
 	sqlQuery := `
-	SELECT id, name, year, teacher_id, duration_in_minutes, is_active, created_at, updated_at
-	FROM exams
-	WHERE is_active = true
+	SELECT e.id, e.name, e.year, e.teacher_id, e.duration_in_minutes, e.is_active, e.created_at, e.updated_at, u.full_name
+	FROM exams e
+	JOIN users u ON u.id = e.teacher_id
+	WHERE e.is_active = true
+		AND ` + examJoinedCondition
+
+	return repository.findExams(ctx, tx, sqlQuery, studentId)
+}
+
+// FindPublicExams returns the active public exams the student has not joined yet
+func (repository *StudentRepositoryImpl) FindPublicExams(ctx context.Context, tx pgx.Tx, studentId string) ([]domain.Exam, error) {
+	sqlQuery := `
+	SELECT e.id, e.name, e.year, e.teacher_id, e.duration_in_minutes, e.is_active, e.created_at, e.updated_at, u.full_name
+	FROM exams e
+	JOIN users u ON u.id = e.teacher_id
+	WHERE e.is_active = true
+		AND ` + publicExamCondition + `
+		AND NOT ` + examJoinedCondition + `
+	ORDER BY e.created_at DESC
 	`
 
-	rows, err := tx.Query(ctx, sqlQuery)
+	return repository.findExams(ctx, tx, sqlQuery, studentId)
+}
+
+func (repository *StudentRepositoryImpl) findExams(ctx context.Context, tx pgx.Tx, sqlQuery, studentId string) ([]domain.Exam, error) {
+	rows, err := tx.Query(ctx, sqlQuery, studentId)
 	if err != nil {
 		return nil, err
 	}
@@ -38,6 +79,7 @@ func (repository *StudentRepositoryImpl) FindActiveExams(ctx context.Context, tx
 			&exam.IsActive,
 			&exam.CreatedAt,
 			&exam.UpdatedAt,
+			&exam.TeacherName,
 		)
 		if err != nil {
 			return nil, err
@@ -78,7 +120,7 @@ func (repository *StudentRepositoryImpl) FindTeacherById(ctx context.Context, tx
 
 func (repository *StudentRepositoryImpl) FindExamById(ctx context.Context, tx pgx.Tx, examId string) (domain.Exam, error) {
 	sqlQuery := `
-	SELECT id, name, year, teacher_id, duration_in_minutes, is_active, created_at, updated_at
+	SELECT id, name, year, teacher_id, duration_in_minutes, is_active, shuffle_questions, question_draw_count, is_private, COALESCE(join_code, ''), created_at, updated_at
 	FROM exams
 	WHERE id = $1
 	`
@@ -91,6 +133,10 @@ func (repository *StudentRepositoryImpl) FindExamById(ctx context.Context, tx pg
 		&exam.TeacherId,
 		&exam.Duration,
 		&exam.IsActive,
+		&exam.ShuffleQuestions,
+		&exam.QuestionDrawCount,
+		&exam.IsPrivate,
+		&exam.JoinCode,
 		&exam.CreatedAt,
 		&exam.UpdatedAt,
 	)
@@ -101,11 +147,186 @@ func (repository *StudentRepositoryImpl) FindExamById(ctx context.Context, tx pg
 	return exam, nil
 }
 
+func (repository *StudentRepositoryImpl) FindExamByJoinCode(ctx context.Context, tx pgx.Tx, joinCode string) (domain.Exam, error) {
+	sqlQuery := `
+	SELECT id, name, year, teacher_id, duration_in_minutes, is_active, is_private
+	FROM exams
+	WHERE join_code = $1
+	`
+
+	exam := domain.Exam{}
+	err := tx.QueryRow(ctx, sqlQuery, joinCode).Scan(
+		&exam.Id,
+		&exam.RoomName,
+		&exam.Year,
+		&exam.TeacherId,
+		&exam.Duration,
+		&exam.IsActive,
+		&exam.IsPrivate,
+	)
+	if err != nil {
+		return domain.Exam{}, err
+	}
+
+	return exam, nil
+}
+
+func (repository *StudentRepositoryImpl) SaveExamMember(ctx context.Context, tx pgx.Tx, examId, studentId string) error {
+	sqlQuery := `
+	INSERT INTO exam_members (exam_id, student_id)
+	VALUES ($1, $2)
+	ON CONFLICT DO NOTHING
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, examId, studentId)
+
+	return err
+}
+
+func (repository *StudentRepositoryImpl) HasExamAccess(ctx context.Context, tx pgx.Tx, examId, studentId string) (bool, error) {
+	sqlQuery := `
+	SELECT EXISTS (
+		SELECT 1 FROM exams e WHERE e.id = $2 AND ` + examAccessCondition + `
+	)
+	`
+
+	var hasAccess bool
+	err := tx.QueryRow(ctx, sqlQuery, studentId, examId).Scan(&hasAccess)
+	if err != nil {
+		return false, err
+	}
+
+	return hasAccess, nil
+}
+
+func (repository *StudentRepositoryImpl) IsEmailVerified(ctx context.Context, tx pgx.Tx, studentId string) (bool, error) {
+	sqlQuery := `
+	SELECT email_verified_at IS NOT NULL
+	FROM users
+	WHERE id = $1
+	`
+
+	var isVerified bool
+	err := tx.QueryRow(ctx, sqlQuery, studentId).Scan(&isVerified)
+	if err != nil {
+		return false, err
+	}
+
+	return isVerified, nil
+}
+
+func (repository *StudentRepositoryImpl) IsClassExam(ctx context.Context, tx pgx.Tx, examId string) (bool, error) {
+	sqlQuery := `
+	SELECT EXISTS (
+		SELECT 1 FROM exam_classes WHERE exam_id = $1
+	)
+	`
+
+	var isClassExam bool
+	err := tx.QueryRow(ctx, sqlQuery, examId).Scan(&isClassExam)
+	if err != nil {
+		return false, err
+	}
+
+	return isClassExam, nil
+}
+
+func (repository *StudentRepositoryImpl) FindClassByJoinCode(ctx context.Context, tx pgx.Tx, joinCode string) (domain.Class, error) {
+	sqlQuery := `
+	SELECT id, teacher_id, name
+	FROM classes
+	WHERE join_code = $1
+	`
+
+	class := domain.Class{}
+	err := tx.QueryRow(ctx, sqlQuery, joinCode).Scan(
+		&class.Id,
+		&class.TeacherId,
+		&class.Name,
+	)
+	if err != nil {
+		return domain.Class{}, err
+	}
+
+	return class, nil
+}
+
+func (repository *StudentRepositoryImpl) SaveClassMember(ctx context.Context, tx pgx.Tx, classId, studentId string) error {
+	sqlQuery := `
+	INSERT INTO class_members (class_id, student_id)
+	VALUES ($1, $2)
+	ON CONFLICT DO NOTHING
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, classId, studentId)
+
+	return err
+}
+
+// ClaimClassInvitations turns the pending invitations for the student's email into class
+// memberships. Nothing is claimed while the email is unverified, anyone can register with it.
+func (repository *StudentRepositoryImpl) ClaimClassInvitations(ctx context.Context, tx pgx.Tx, studentId string) error {
+	sqlQuery := `
+	WITH claimed AS (
+		DELETE FROM class_invitations i
+		USING users u
+		WHERE u.id = $1
+			AND u.role = 'student'
+			AND u.email_verified_at IS NOT NULL
+			AND i.email = lower(u.email)
+		RETURNING i.class_id
+	)
+	INSERT INTO class_members (class_id, student_id)
+	SELECT class_id, $1 FROM claimed
+	ON CONFLICT DO NOTHING
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, studentId)
+
+	return err
+}
+
+func (repository *StudentRepositoryImpl) FindClassesByStudentId(ctx context.Context, tx pgx.Tx, studentId string) ([]domain.Class, error) {
+	sqlQuery := `
+	SELECT c.id, c.teacher_id, c.name
+	FROM classes c
+	JOIN class_members m ON m.class_id = c.id
+	WHERE m.student_id = $1
+	ORDER BY c.name
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, studentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	classes := []domain.Class{}
+	for rows.Next() {
+		class := domain.Class{}
+		err := rows.Scan(
+			&class.Id,
+			&class.TeacherId,
+			&class.Name,
+		)
+		if err != nil {
+			return nil, err
+		}
+		classes = append(classes, class)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return classes, nil
+}
+
 func (repository *StudentRepositoryImpl) FindQuestionsByExamId(ctx context.Context, tx pgx.Tx, examId string) ([]domain.QAItem, error) {
 	sqlQuery := `
-	SELECT id, question, correct_answer, exam_id
-	FROM questions
-	WHERE exam_id = $1
+	SELECT q.id, COALESCE(b.question, q.question), COALESCE(b.correct_answer, q.correct_answer), q.exam_id
+	FROM questions q
+	LEFT JOIN bank_questions b ON b.id = q.bank_question_id
+	WHERE q.exam_id = $1
 	`
 
 	rows, err := tx.Query(ctx, sqlQuery, examId)
@@ -135,15 +356,15 @@ func (repository *StudentRepositoryImpl) FindQuestionsByExamId(ctx context.Conte
 	return questions, nil
 }
 
-func (repository *StudentRepositoryImpl) CreateExamAttempt(ctx context.Context, tx pgx.Tx, studentId, examId string) (string, error) {
+func (repository *StudentRepositoryImpl) CreateExamAttempt(ctx context.Context, tx pgx.Tx, studentId, examId string, questionSeed int64, questionIds []string) (string, error) {
 	sqlQuery := `
-	INSERT INTO exam_attempts (student_id, exam_id)
-	VALUES ($1, $2)
+	INSERT INTO exam_attempts (student_id, exam_id, question_seed, question_ids)
+	VALUES ($1, $2, $3, $4::uuid[])
 	RETURNING id
 	`
 
 	var examAttemptId string
-	err := tx.QueryRow(ctx, sqlQuery, studentId, examId).Scan(&examAttemptId)
+	err := tx.QueryRow(ctx, sqlQuery, studentId, examId, questionSeed, questionIds).Scan(&examAttemptId)
 	if err != nil {
 		return "", err
 	}
@@ -151,6 +372,23 @@ func (repository *StudentRepositoryImpl) CreateExamAttempt(ctx context.Context,
 	return examAttemptId, nil
 }
 
+// FindAttemptQuestionIds returns the attempt's own question list in order, empty for attempts made before it was stored
+func (repository *StudentRepositoryImpl) FindAttemptQuestionIds(ctx context.Context, tx pgx.Tx, attemptId string) ([]string, error) {
+	sqlQuery := `
+	SELECT question_ids::text[]
+	FROM exam_attempts
+	WHERE id = $1
+	`
+
+	var questionIds []string
+	err := tx.QueryRow(ctx, sqlQuery, attemptId).Scan(&questionIds)
+	if err != nil {
+		return nil, err
+	}
+
+	return questionIds, nil
+}
+
 func (repository *StudentRepositoryImpl) SaveAnswer(ctx context.Context, tx pgx.Tx, answer web.StudentAnswer) error {
 	sqlQuery := `
 	INSERT INTO student_answers (exam_attempt_id, question_id, student_answer)
@@ -162,6 +400,48 @@ func (repository *StudentRepositoryImpl) SaveAnswer(ctx context.Context, tx pgx.
 	return err
 }
 
+// SaveOrUpdateAnswer replaces the saved answer of the question, so an answer can be sent more than once
+func (repository *StudentRepositoryImpl) SaveOrUpdateAnswer(ctx context.Context, tx pgx.Tx, answer web.StudentAnswer) error {
+	sqlQuery := `
+	UPDATE student_answers
+	SET student_answer = $3
+	WHERE exam_attempt_id = $1 AND question_id = $2
+	`
+
+	commandTag, err := tx.Exec(ctx, sqlQuery, answer.ExamAttemptID, answer.QuestionID, answer.StudentAnswer)
+	if err != nil {
+		return err
+	}
+	if commandTag.RowsAffected() > 0 {
+		return nil
+	}
+
+	return repository.SaveAnswer(ctx, tx, answer)
+}
+
+func (repository *StudentRepositoryImpl) FindAttemptById(ctx context.Context, tx pgx.Tx, attemptId string) (web.ExamAttempt, error) {
+	sqlQuery := `
+	SELECT id, student_id, exam_id, score, started_at, completed_at
+	FROM exam_attempts
+	WHERE id = $1
+	`
+
+	attempt := web.ExamAttempt{}
+	err := tx.QueryRow(ctx, sqlQuery, attemptId).Scan(
+		&attempt.ID,
+		&attempt.StudentID,
+		&attempt.ExamID,
+		&attempt.Score,
+		&attempt.StartedAt,
+		&attempt.CompletedAt,
+	)
+	if err != nil {
+		return web.ExamAttempt{}, err
+	}
+
+	return attempt, nil
+}
+
 func (repository *StudentRepositoryImpl) CompleteExamAttempt(ctx context.Context, tx pgx.Tx, attemptId string) error {
 	sqlQuery := `
 	UPDATE exam_attempts
@@ -177,9 +457,36 @@ func (repository *StudentRepositoryImpl) CompleteExamAttempt(ctx context.Context
 	return nil
 }
 
+// FindCompletedAttemptIdsByExamId returns the submitted attempts, oldest first
+func (repository *StudentRepositoryImpl) FindCompletedAttemptIdsByExamId(ctx context.Context, tx pgx.Tx, examId string) ([]string, error) {
+	sqlQuery := `
+	SELECT id
+	FROM exam_attempts
+	WHERE exam_id = $1 AND completed_at > '0001-01-01 00:00:00'
+	ORDER BY started_at
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, examId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attemptIds := []string{}
+	for rows.Next() {
+		var attemptId string
+		if err := rows.Scan(&attemptId); err != nil {
+			return nil, err
+		}
+		attemptIds = append(attemptIds, attemptId)
+	}
+
+	return attemptIds, rows.Err()
+}
+
 func (repository *StudentRepositoryImpl) FindExamByAttemptId(ctx context.Context, tx pgx.Tx, attemptId string) (domain.Exam, error) {
 	sqlQuery := `
-	SELECT id, name, year, teacher_id, duration_in_minutes, is_active, created_at, updated_at
+	SELECT id, name, year, teacher_id, duration_in_minutes, is_active, shuffle_questions, question_draw_count, is_private, COALESCE(join_code, ''), created_at, updated_at
 	FROM exams
 	WHERE id = (
 		SELECT exam_id
@@ -196,6 +503,10 @@ func (repository *StudentRepositoryImpl) FindExamByAttemptId(ctx context.Context
 		&exam.TeacherId,
 		&exam.Duration,
 		&exam.IsActive,
+		&exam.ShuffleQuestions,
+		&exam.QuestionDrawCount,
+		&exam.IsPrivate,
+		&exam.JoinCode,
 		&exam.CreatedAt,
 		&exam.UpdatedAt,
 	)
@@ -462,9 +773,10 @@ func (repository *StudentRepositoryImpl) FindStudentAnswersByAttemptId(ctx conte
 
 func (repository *StudentRepositoryImpl) FindQuestionById(ctx context.Context, tx pgx.Tx, questionId string) (web.QuestionAndRightAnswer, error) {
 	sqlQuery := `
-	SELECT question, correct_answer
-	FROM questions
-	WHERE id = $1
+	SELECT COALESCE(b.question, q.question), COALESCE(b.correct_answer, q.correct_answer)
+	FROM questions q
+	LEFT JOIN bank_questions b ON b.id = q.bank_question_id
+	WHERE q.id = $1
 	`
 
 	var question web.QuestionAndRightAnswer
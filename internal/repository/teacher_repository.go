@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
@@ -11,18 +12,37 @@ import (
 type TeacherRepository interface {
 	SaveExam(ctx context.Context, tx pgx.Tx, examData domain.Exam, teacherId string, examId string) error
 	BulkSaveQuestionAnswer(ctx context.Context, tx pgx.Tx, questionsAndAnswers []domain.QAItem, examId string) (string, error)
+	SaveQuestion(ctx context.Context, tx pgx.Tx, question domain.QAItem) (domain.QAItem, error)
+	DeleteExamById(ctx context.Context, tx pgx.Tx, examId string) error
+	DeleteQuestionById(ctx context.Context, tx pgx.Tx, questionId string) error
 
 	FindUserById(ctx context.Context, tx pgx.Tx, userId string) (domain.User, error)
-	FindExamsByUserId(ctx context.Context, tx pgx.Tx, userId string) ([]domain.Exam, error)
+	FindExamsByUserId(ctx context.Context, tx pgx.Tx, userId, classId string) ([]domain.Exam, error)
 
 	FindExamById(ctx context.Context, tx pgx.Tx, examId string) (domain.Exam, error)
+	FindExamRole(ctx context.Context, tx pgx.Tx, examId, teacherId string) (string, error)
+	FindCollaborators(ctx context.Context, tx pgx.Tx, examId string) ([]domain.ExamCollaborator, error)
+	SaveCollaborator(ctx context.Context, tx pgx.Tx, examId, teacherId, role string) error
+	DeleteCollaborator(ctx context.Context, tx pgx.Tx, examId, teacherId string) error
+	CountOwners(ctx context.Context, tx pgx.Tx, examId string) (int, error)
+	FindUserByEmail(ctx context.Context, tx pgx.Tx, email string) (domain.User, error)
 	UpdateIsActiveExamById(ctx context.Context, tx pgx.Tx, examId string, currentIsActive bool) error
+	UpdateIsPrivateExamById(ctx context.Context, tx pgx.Tx, examId string, isPrivate bool) error
+	UpdateJoinCodeById(ctx context.Context, tx pgx.Tx, examId, joinCode string) error
+	IsJoinCodeTaken(ctx context.Context, tx pgx.Tx, joinCode string) (bool, error)
 
 	FindQAByExamId(ctx context.Context, tx pgx.Tx, examId string) ([]domain.QAItem, error)
+	FindQAByTeacherId(ctx context.Context, tx pgx.Tx, teacherId string) ([]domain.QAItem, error)
 
 	UpdateExamById(ctx context.Context, tx pgx.Tx, examId, roomName string, yearInt, durationInt int) error
+	UpdateExamQuestionSettingsById(ctx context.Context, tx pgx.Tx, examId string, shuffleQuestions bool, questionDrawCount int) error
 	UpdateQuestionById(ctx context.Context, tx pgx.Tx, questionId, questionText, answerText string) error
+	FindQuestionById(ctx context.Context, tx pgx.Tx, questionId string) (domain.QAItem, error)
+	FindExamSourceById(ctx context.Context, tx pgx.Tx, examId string) (string, string, error)
 
-	FindBiggestAttemptsByExamId(ctx context.Context, tx pgx.Tx, examId string) ([]web.ExamAttempt, error)
+	FindCachedQA(ctx context.Context, tx pgx.Tx, cacheKey string) ([]domain.QAItem, error)
+	SaveCachedQA(ctx context.Context, tx pgx.Tx, cacheKey, model string, totalQuestion int, qaList []domain.QAItem, expiresAt time.Time) error
+
+	FindBiggestAttemptsByExamId(ctx context.Context, tx pgx.Tx, examId, classId string) ([]web.ExamAttempt, error)
 	FindStudentFullNameByExamAttemptsId(ctx context.Context, tx pgx.Tx, examAttemptsId string) (string, string, error)
 }
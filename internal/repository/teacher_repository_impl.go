@@ -2,6 +2,9 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -17,8 +20,8 @@ type teacherRepositoryImpl struct{}
 
 func (r *teacherRepositoryImpl) SaveExam(ctx context.Context, tx pgx.Tx, examData domain.Exam, teacherId string, examId string) error {
 	sqlQuery := `
-	INSERT INTO exams (id, name, year, duration_in_minutes, teacher_id)
-	VALUES ($1, $2, $3, $4, $5)
+	INSERT INTO exams (id, name, year, duration_in_minutes, teacher_id, source_file_uri, material_id)
+	VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, '')::uuid)
 	`
 
 	_, err := tx.Exec(
@@ -29,12 +32,15 @@ func (r *teacherRepositoryImpl) SaveExam(ctx context.Context, tx pgx.Tx, examDat
 		examData.Year,
 		examData.Duration,
 		teacherId,
+		examData.SourceURI,
+		examData.MaterialId,
 	)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// Pembuat ujian otomatis menjadi owner
+	return r.SaveCollaborator(ctx, tx, examId, teacherId, domain.ExamRoleOwner)
 }
 
 func (r *teacherRepositoryImpl) BulkSaveQuestionAnswer(ctx context.Context, tx pgx.Tx, questionsAndAnswers []domain.QAItem, examId string) (string, error) {
@@ -66,6 +72,45 @@ func (r *teacherRepositoryImpl) BulkSaveQuestionAnswer(ctx context.Context, tx p
 	return "", nil
 }
 
+func (r *teacherRepositoryImpl) SaveQuestion(ctx context.Context, tx pgx.Tx, question domain.QAItem) (domain.QAItem, error) {
+	sqlQuery := `
+	INSERT INTO questions (question, correct_answer, exam_id)
+	VALUES ($1, $2, $3)
+	RETURNING id
+	`
+
+	err := tx.QueryRow(ctx, sqlQuery, question.Question, question.Answer, question.ExamId).Scan(&question.Id)
+	if err != nil {
+		return domain.QAItem{}, err
+	}
+
+	return question, nil
+}
+
+// DeleteExamById also removes the questions, attempts and answers of the exam through ON DELETE CASCADE
+func (r *teacherRepositoryImpl) DeleteExamById(ctx context.Context, tx pgx.Tx, examId string) error {
+	sqlQuery := `
+	DELETE FROM exams
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, examId)
+
+	return err
+}
+
+// DeleteQuestionById only removes the question from the exam, a bank question it refers to is kept
+func (r *teacherRepositoryImpl) DeleteQuestionById(ctx context.Context, tx pgx.Tx, questionId string) error {
+	sqlQuery := `
+	DELETE FROM questions
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, questionId)
+
+	return err
+}
+
 func (r *teacherRepositoryImpl) FindUserById(ctx context.Context, tx pgx.Tx, userId string) (domain.User, error) {
 	sqlQuery := `
 	SELECT id, email, full_name, password, role, created_at, updated_at
@@ -91,14 +136,18 @@ func (r *teacherRepositoryImpl) FindUserById(ctx context.Context, tx pgx.Tx, use
 	return user, nil
 }
 
-func (r *teacherRepositoryImpl) FindExamsByUserId(ctx context.Context, tx pgx.Tx, userId string) ([]domain.Exam, error) {
+// FindExamsByUserId lists the exams the teacher collaborates on together with the teacher's role,
+// classId is an optional filter on assigned class
+func (r *teacherRepositoryImpl) FindExamsByUserId(ctx context.Context, tx pgx.Tx, userId, classId string) ([]domain.Exam, error) {
 	sqlQuery := `
-	SELECT id, name, year, teacher_id, duration_in_minutes, is_active, created_at, updated_at
-	FROM exams
-	WHERE teacher_id = $1
+	SELECT e.id, e.name, e.year, e.teacher_id, e.duration_in_minutes, e.is_active, e.created_at, e.updated_at, u.full_name, c.role
+	FROM exams e
+	JOIN exam_collaborators c ON c.exam_id = e.id AND c.teacher_id = $1
+	JOIN users u ON u.id = e.teacher_id
+	WHERE ($2 = '' OR EXISTS (SELECT 1 FROM exam_classes ec WHERE ec.exam_id = e.id AND ec.class_id::text = $2))
 	`
 
-	rows, err := tx.Query(ctx, sqlQuery, userId)
+	rows, err := tx.Query(ctx, sqlQuery, userId, classId)
 	if err != nil {
 		return nil, err
 	}
@@ -115,6 +164,8 @@ func (r *teacherRepositoryImpl) FindExamsByUserId(ctx context.Context, tx pgx.Tx
 			&exam.IsActive,
 			&exam.CreatedAt,
 			&exam.UpdatedAt,
+			&exam.TeacherName,
+			&exam.Role,
 		)
 		if err != nil {
 			return nil, err
@@ -131,9 +182,10 @@ func (r *teacherRepositoryImpl) FindExamsByUserId(ctx context.Context, tx pgx.Tx
 
 func (r *teacherRepositoryImpl) FindExamById(ctx context.Context, tx pgx.Tx, examId string) (domain.Exam, error) {
 	sqlQuery := `
-	SELECT id, name, year, teacher_id, duration_in_minutes, is_active, created_at, updated_at
-	FROM exams
-	WHERE id = $1
+	SELECT e.id, e.name, e.year, e.teacher_id, e.duration_in_minutes, e.is_active, e.shuffle_questions, e.question_draw_count, e.is_private, COALESCE(e.join_code, ''), e.created_at, e.updated_at, u.full_name
+	FROM exams e
+	JOIN users u ON u.id = e.teacher_id
+	WHERE e.id = $1
 	`
 
 	exam := domain.Exam{}
@@ -144,8 +196,13 @@ func (r *teacherRepositoryImpl) FindExamById(ctx context.Context, tx pgx.Tx, exa
 		&exam.TeacherId,
 		&exam.Duration,
 		&exam.IsActive,
+		&exam.ShuffleQuestions,
+		&exam.QuestionDrawCount,
+		&exam.IsPrivate,
+		&exam.JoinCode,
 		&exam.CreatedAt,
 		&exam.UpdatedAt,
+		&exam.TeacherName,
 	)
 	if err != nil {
 		return domain.Exam{}, err
@@ -153,6 +210,127 @@ func (r *teacherRepositoryImpl) FindExamById(ctx context.Context, tx pgx.Tx, exa
 
 	return exam, nil
 }
+
+// FindExamRole returns the teacher's collaborator role on the exam, empty when the teacher is not a collaborator
+func (r *teacherRepositoryImpl) FindExamRole(ctx context.Context, tx pgx.Tx, examId, teacherId string) (string, error) {
+	sqlQuery := `
+	SELECT role
+	FROM exam_collaborators
+	WHERE exam_id = $1 AND teacher_id = $2
+	`
+
+	var role string
+	err := tx.QueryRow(ctx, sqlQuery, examId, teacherId).Scan(&role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return role, nil
+}
+
+func (r *teacherRepositoryImpl) FindCollaborators(ctx context.Context, tx pgx.Tx, examId string) ([]domain.ExamCollaborator, error) {
+	sqlQuery := `
+	SELECT c.exam_id, c.teacher_id, u.full_name, u.email, c.role, c.created_at
+	FROM exam_collaborators c
+	JOIN users u ON u.id = c.teacher_id
+	WHERE c.exam_id = $1
+	ORDER BY c.role, u.full_name
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, examId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collaborators := []domain.ExamCollaborator{}
+	for rows.Next() {
+		collaborator := domain.ExamCollaborator{}
+		err := rows.Scan(
+			&collaborator.ExamId,
+			&collaborator.TeacherId,
+			&collaborator.FullName,
+			&collaborator.Email,
+			&collaborator.Role,
+			&collaborator.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		collaborators = append(collaborators, collaborator)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return collaborators, nil
+}
+
+// SaveCollaborator adds the teacher to the exam or changes their role
+func (r *teacherRepositoryImpl) SaveCollaborator(ctx context.Context, tx pgx.Tx, examId, teacherId, role string) error {
+	sqlQuery := `
+	INSERT INTO exam_collaborators (exam_id, teacher_id, role)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (exam_id, teacher_id) DO UPDATE SET role = EXCLUDED.role
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, examId, teacherId, role)
+
+	return err
+}
+
+func (r *teacherRepositoryImpl) DeleteCollaborator(ctx context.Context, tx pgx.Tx, examId, teacherId string) error {
+	sqlQuery := `
+	DELETE FROM exam_collaborators
+	WHERE exam_id = $1 AND teacher_id = $2
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, examId, teacherId)
+
+	return err
+}
+
+func (r *teacherRepositoryImpl) CountOwners(ctx context.Context, tx pgx.Tx, examId string) (int, error) {
+	sqlQuery := `
+	SELECT COUNT(*)
+	FROM exam_collaborators
+	WHERE exam_id = $1 AND role = 'owner'
+	`
+
+	var total int
+	err := tx.QueryRow(ctx, sqlQuery, examId).Scan(&total)
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+func (r *teacherRepositoryImpl) FindUserByEmail(ctx context.Context, tx pgx.Tx, email string) (domain.User, error) {
+	sqlQuery := `
+	SELECT id, email, full_name, role
+	FROM users
+	WHERE lower(email) = lower($1)
+	`
+
+	user := domain.User{}
+	err := tx.QueryRow(ctx, sqlQuery, email).Scan(
+		&user.Id,
+		&user.Email,
+		&user.FullName,
+		&user.Role,
+	)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	return user, nil
+}
+
 func (r *teacherRepositoryImpl) UpdateIsActiveExamById(ctx context.Context, tx pgx.Tx, examId string, currentIsActive bool) error {
 	sqlQuery := `
 	UPDATE exams
@@ -169,11 +347,59 @@ func (r *teacherRepositoryImpl) UpdateIsActiveExamById(ctx context.Context, tx p
 	return nil
 }
 
+func (r *teacherRepositoryImpl) UpdateIsPrivateExamById(ctx context.Context, tx pgx.Tx, examId string, isPrivate bool) error {
+	sqlQuery := `
+	UPDATE exams
+	SET is_private = $1, updated_at = now()
+	WHERE id = $2
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, isPrivate, examId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateJoinCodeById sets the exam's join code, an empty code revokes it
+func (r *teacherRepositoryImpl) UpdateJoinCodeById(ctx context.Context, tx pgx.Tx, examId, joinCode string) error {
+	sqlQuery := `
+	UPDATE exams
+	SET join_code = NULLIF($1, ''), updated_at = now()
+	WHERE id = $2
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, joinCode, examId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *teacherRepositoryImpl) IsJoinCodeTaken(ctx context.Context, tx pgx.Tx, joinCode string) (bool, error) {
+	sqlQuery := `
+	SELECT EXISTS (
+		SELECT 1 FROM exams WHERE join_code = $1
+	)
+	`
+
+	var isTaken bool
+	err := tx.QueryRow(ctx, sqlQuery, joinCode).Scan(&isTaken)
+	if err != nil {
+		return false, err
+	}
+
+	return isTaken, nil
+}
+
 func (r *teacherRepositoryImpl) FindQAByExamId(ctx context.Context, tx pgx.Tx, examId string) ([]domain.QAItem, error) {
 	sqlQuery := `
-	SELECT id, question, correct_answer, exam_id
-	FROM questions
-	WHERE exam_id = $1
+	SELECT q.id, COALESCE(b.question, q.question), COALESCE(b.correct_answer, q.correct_answer), q.exam_id, COALESCE(q.bank_question_id::text, '')
+	FROM questions q
+	LEFT JOIN bank_questions b ON b.id = q.bank_question_id
+	WHERE q.exam_id = $1
 	`
 
 	rows, err := tx.Query(ctx, sqlQuery, examId)
@@ -189,6 +415,44 @@ func (r *teacherRepositoryImpl) FindQAByExamId(ctx context.Context, tx pgx.Tx, e
 			&question.Question,
 			&question.Answer,
 			&question.ExamId,
+			&question.BankQuestionId,
+		)
+		if err != nil {
+			return nil, err
+		}
+		questions = append(questions, question)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return questions, nil
+}
+
+func (r *teacherRepositoryImpl) FindQAByTeacherId(ctx context.Context, tx pgx.Tx, teacherId string) ([]domain.QAItem, error) {
+	sqlQuery := `
+	SELECT q.id, COALESCE(b.question, q.question), COALESCE(b.correct_answer, q.correct_answer), q.exam_id, COALESCE(q.bank_question_id::text, '')
+	FROM questions q
+	JOIN exams e ON e.id = q.exam_id
+	LEFT JOIN bank_questions b ON b.id = q.bank_question_id
+	WHERE e.teacher_id = $1
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, teacherId)
+	if err != nil {
+		return nil, err
+	}
+
+	questions := []domain.QAItem{}
+	for rows.Next() {
+		question := domain.QAItem{}
+		err := rows.Scan(
+			&question.Id,
+			&question.Question,
+			&question.Answer,
+			&question.ExamId,
+			&question.BankQuestionId,
 		)
 		if err != nil {
 			return nil, err
@@ -218,11 +482,28 @@ func (r *teacherRepositoryImpl) UpdateExamById(ctx context.Context, tx pgx.Tx, e
 	return nil
 }
 
+func (r *teacherRepositoryImpl) UpdateExamQuestionSettingsById(ctx context.Context, tx pgx.Tx, examId string, shuffleQuestions bool, questionDrawCount int) error {
+	sqlQuery := `
+	UPDATE exams
+	SET shuffle_questions = $1, question_draw_count = $2, updated_at = now()
+	WHERE id = $3
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, shuffleQuestions, questionDrawCount, examId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateQuestionById updates the question text. Questions taken from the bank only refer to the
+// bank question and are left alone, the bank question is changed from the question bank page.
 func (r *teacherRepositoryImpl) UpdateQuestionById(ctx context.Context, tx pgx.Tx, questionId, questionText, answerText string) error {
 	sqlQuery := `
 	UPDATE questions
 	SET question = $1, correct_answer = $2
-	WHERE id = $3
+	WHERE id = $3 AND bank_question_id IS NULL
 	`
 
 	_, err := tx.Exec(ctx, sqlQuery, questionText, answerText, questionId)
@@ -233,15 +514,112 @@ func (r *teacherRepositoryImpl) UpdateQuestionById(ctx context.Context, tx pgx.T
 	return nil
 }
 
-func (r *teacherRepositoryImpl) FindBiggestAttemptsByExamId(ctx context.Context, tx pgx.Tx, examId string) ([]web.ExamAttempt, error) {
-	// 1. Query tetap sama, ambil semua attempt untuk ujian ini.
+func (r *teacherRepositoryImpl) FindQuestionById(ctx context.Context, tx pgx.Tx, questionId string) (domain.QAItem, error) {
+	sqlQuery := `
+	SELECT q.id, COALESCE(b.question, q.question), COALESCE(b.correct_answer, q.correct_answer), q.exam_id, COALESCE(q.bank_question_id::text, '')
+	FROM questions q
+	LEFT JOIN bank_questions b ON b.id = q.bank_question_id
+	WHERE q.id = $1
+	`
+
+	question := domain.QAItem{}
+	err := tx.QueryRow(ctx, sqlQuery, questionId).Scan(
+		&question.Id,
+		&question.Question,
+		&question.Answer,
+		&question.ExamId,
+		&question.BankQuestionId,
+	)
+	if err != nil {
+		return domain.QAItem{}, err
+	}
+
+	return question, nil
+}
+
+// FindExamSourceById returns the Gemini file URI the exam was generated from and
+// the storage key of its material, either can be empty for older exams
+func (r *teacherRepositoryImpl) FindExamSourceById(ctx context.Context, tx pgx.Tx, examId string) (string, string, error) {
+	sqlQuery := `
+	SELECT e.source_file_uri, COALESCE(m.storage_key, '')
+	FROM exams e
+	LEFT JOIN materials m ON m.id = e.material_id
+	WHERE e.id = $1
+	`
+
+	var sourceURI string
+	var storageKey string
+	err := tx.QueryRow(ctx, sqlQuery, examId).Scan(&sourceURI, &storageKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	return sourceURI, storageKey, nil
+}
+
+// FindCachedQA returns the cached generation result for cacheKey, or nil when there is no fresh entry
+func (r *teacherRepositoryImpl) FindCachedQA(ctx context.Context, tx pgx.Tx, cacheKey string) ([]domain.QAItem, error) {
+	sqlQuery := `
+	SELECT qa_items
+	FROM generation_cache
+	WHERE cache_key = $1 AND expires_at > now()
+	`
+
+	var qaItems []byte
+	err := tx.QueryRow(ctx, sqlQuery, cacheKey).Scan(&qaItems)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var qaList []domain.QAItem
+	if err := json.Unmarshal(qaItems, &qaList); err != nil {
+		return nil, err
+	}
+
+	return qaList, nil
+}
+
+func (r *teacherRepositoryImpl) SaveCachedQA(ctx context.Context, tx pgx.Tx, cacheKey, model string, totalQuestion int, qaList []domain.QAItem, expiresAt time.Time) error {
+	qaItems, err := json.Marshal(qaList)
+	if err != nil {
+		return err
+	}
+
+	// Clean up expired entries while we are here, the table only ever needs fresh results
+	_, err = tx.Exec(ctx, `DELETE FROM generation_cache WHERE expires_at <= now()`)
+	if err != nil {
+		return err
+	}
+
+	sqlQuery := `
+	INSERT INTO generation_cache (cache_key, model, total_question, qa_items, expires_at)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (cache_key) DO UPDATE
+	SET qa_items = EXCLUDED.qa_items, created_at = now(), expires_at = EXCLUDED.expires_at
+	`
+
+	_, err = tx.Exec(ctx, sqlQuery, cacheKey, model, totalQuestion, qaItems, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *teacherRepositoryImpl) FindBiggestAttemptsByExamId(ctx context.Context, tx pgx.Tx, examId, classId string) ([]web.ExamAttempt, error) {
+	// 1. Ambil semua attempt untuk ujian ini, classId opsional untuk membatasi ke anggota kelas.
 	sqlQuery := `
     SELECT id, student_id, score, started_at, completed_at
     FROM exam_attempts
     WHERE exam_id = $1
+        AND ($2 = '' OR student_id IN (SELECT student_id FROM class_members WHERE class_id::text = $2))
     `
 
-	rows, err := tx.Query(ctx, sqlQuery, examId)
+	rows, err := tx.Query(ctx, sqlQuery, examId, classId)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+type TwoFactorRepository interface {
+	// TOTP secret of a user
+	FindByUserId(ctx context.Context, tx pgx.Tx, userId string) (domain.TwoFactor, error)
+	SaveSecret(ctx context.Context, tx pgx.Tx, userId, secret string) error
+	Enable(ctx context.Context, tx pgx.Tx, userId string, counter int64) error
+	Disable(ctx context.Context, tx pgx.Tx, userId string) error
+	UpdateLastCounter(ctx context.Context, tx pgx.Tx, userId string, counter int64) (bool, error)
+
+	// Recovery codes
+	SaveRecoveryCodes(ctx context.Context, tx pgx.Tx, userId string, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, tx pgx.Tx, userId, codeHash string) (bool, error)
+	CountUnusedRecoveryCodes(ctx context.Context, tx pgx.Tx, userId string) (int, error)
+
+	// Second login step
+	SaveChallenge(ctx context.Context, tx pgx.Tx, challenge domain.TwoFactorChallenge, ttl time.Duration) (domain.TwoFactorChallenge, error)
+	FindValidChallengeByTokenHash(ctx context.Context, tx pgx.Tx, tokenHash string) (domain.TwoFactorChallenge, error)
+	IncrementChallengeAttempts(ctx context.Context, tx pgx.Tx, challengeId string) error
+	DeleteChallenge(ctx context.Context, tx pgx.Tx, challengeId string) error
+}
@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+func NewTwoFactorRepository() TwoFactorRepository {
+	return &TwoFactorRepositoryImpl{}
+}
+
+type TwoFactorRepositoryImpl struct{}
+
+func (repository *TwoFactorRepositoryImpl) FindByUserId(ctx context.Context, tx pgx.Tx, userId string) (domain.TwoFactor, error) {
+	sqlQuery := `
+	SELECT id, COALESCE(totp_secret, ''), totp_enabled, COALESCE(totp_last_counter, 0)
+	FROM users
+	WHERE id = $1
+	`
+
+	twoFactor := domain.TwoFactor{}
+
+	err := tx.QueryRow(ctx, sqlQuery, userId).Scan(
+		&twoFactor.UserId,
+		&twoFactor.Secret,
+		&twoFactor.IsEnabled,
+		&twoFactor.LastCounter,
+	)
+	if err != nil {
+		return domain.TwoFactor{}, err
+	}
+
+	return twoFactor, nil
+}
+
+// SaveSecret starts a new enrollment, the secret is not used for login until Enable is called
+func (repository *TwoFactorRepositoryImpl) SaveSecret(ctx context.Context, tx pgx.Tx, userId, secret string) error {
+	sqlQuery := `
+	UPDATE users
+	SET totp_secret = $2, totp_enabled = FALSE, totp_last_counter = NULL, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, userId, secret)
+
+	return err
+}
+
+func (repository *TwoFactorRepositoryImpl) Enable(ctx context.Context, tx pgx.Tx, userId string, counter int64) error {
+	sqlQuery := `
+	UPDATE users
+	SET totp_enabled = TRUE, totp_last_counter = $2, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, userId, counter)
+
+	return err
+}
+
+// Disable removes the secret and every recovery code of the user
+func (repository *TwoFactorRepositoryImpl) Disable(ctx context.Context, tx pgx.Tx, userId string) error {
+	sqlQuery := `
+	UPDATE users
+	SET totp_secret = NULL, totp_enabled = FALSE, totp_last_counter = NULL, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1
+	`
+
+	if _, err := tx.Exec(ctx, sqlQuery, userId); err != nil {
+		return err
+	}
+
+	sqlQuery = `
+	DELETE FROM user_recovery_codes
+	WHERE user_id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, userId)
+
+	return err
+}
+
+// UpdateLastCounter only moves the counter forward. It returns false when a code of this or a
+// later time step was already accepted, so the same code can not be replayed.
+func (repository *TwoFactorRepositoryImpl) UpdateLastCounter(ctx context.Context, tx pgx.Tx, userId string, counter int64) (bool, error) {
+	sqlQuery := `
+	UPDATE users
+	SET totp_last_counter = $2
+	WHERE id = $1 AND (totp_last_counter IS NULL OR totp_last_counter < $2)
+	`
+
+	commandTag, err := tx.Exec(ctx, sqlQuery, userId, counter)
+	if err != nil {
+		return false, err
+	}
+
+	return commandTag.RowsAffected() == 1, nil
+}
+
+// SaveRecoveryCodes replaces the previous recovery codes of the user
+func (repository *TwoFactorRepositoryImpl) SaveRecoveryCodes(ctx context.Context, tx pgx.Tx, userId string, codeHashes []string) error {
+	sqlQuery := `
+	DELETE FROM user_recovery_codes
+	WHERE user_id = $1
+	`
+
+	if _, err := tx.Exec(ctx, sqlQuery, userId); err != nil {
+		return err
+	}
+
+	sqlQuery = `
+	INSERT INTO user_recovery_codes (user_id, code_hash)
+	SELECT $1, UNNEST($2::TEXT[])
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, userId, codeHashes)
+
+	return err
+}
+
+// UseRecoveryCode marks the code as used and returns false when it does not exist or was already used
+func (repository *TwoFactorRepositoryImpl) UseRecoveryCode(ctx context.Context, tx pgx.Tx, userId, codeHash string) (bool, error) {
+	sqlQuery := `
+	UPDATE user_recovery_codes
+	SET used_at = CURRENT_TIMESTAMP
+	WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	commandTag, err := tx.Exec(ctx, sqlQuery, userId, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	return commandTag.RowsAffected() > 0, nil
+}
+
+func (repository *TwoFactorRepositoryImpl) CountUnusedRecoveryCodes(ctx context.Context, tx pgx.Tx, userId string) (int, error) {
+	sqlQuery := `
+	SELECT COUNT(*)
+	FROM user_recovery_codes
+	WHERE user_id = $1 AND used_at IS NULL
+	`
+
+	var count int
+	if err := tx.QueryRow(ctx, sqlQuery, userId).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// SaveChallenge computes expires_at in the database so it uses the same clock as the validity check
+func (repository *TwoFactorRepositoryImpl) SaveChallenge(ctx context.Context, tx pgx.Tx, challenge domain.TwoFactorChallenge, ttl time.Duration) (domain.TwoFactorChallenge, error) {
+	sqlQuery := `
+	INSERT INTO two_factor_challenges (user_id, token_hash, expires_at)
+	VALUES ($1, $2, CURRENT_TIMESTAMP + make_interval(secs => $3))
+	RETURNING id, expires_at, created_at
+	`
+
+	err := tx.QueryRow(
+		ctx,
+		sqlQuery,
+		challenge.UserId,
+		challenge.TokenHash,
+		ttl.Seconds(),
+	).Scan(
+		&challenge.Id,
+		&challenge.ExpiresAt,
+		&challenge.CreatedAt,
+	)
+	if err != nil {
+		return domain.TwoFactorChallenge{}, err
+	}
+
+	return challenge, nil
+}
+
+// FindValidChallengeByTokenHash only returns challenges that are not expired, otherwise pgx.ErrNoRows.
+// The row is locked so parallel guesses are counted one after another.
+func (repository *TwoFactorRepositoryImpl) FindValidChallengeByTokenHash(ctx context.Context, tx pgx.Tx, tokenHash string) (domain.TwoFactorChallenge, error) {
+	sqlQuery := `
+	SELECT id, user_id, token_hash, attempts, expires_at, created_at
+	FROM two_factor_challenges
+	WHERE token_hash = $1 AND expires_at > CURRENT_TIMESTAMP
+	FOR UPDATE
+	`
+
+	challenge := domain.TwoFactorChallenge{}
+
+	err := tx.QueryRow(ctx, sqlQuery, tokenHash).Scan(
+		&challenge.Id,
+		&challenge.UserId,
+		&challenge.TokenHash,
+		&challenge.Attempts,
+		&challenge.ExpiresAt,
+		&challenge.CreatedAt,
+	)
+	if err != nil {
+		return domain.TwoFactorChallenge{}, err
+	}
+
+	return challenge, nil
+}
+
+func (repository *TwoFactorRepositoryImpl) IncrementChallengeAttempts(ctx context.Context, tx pgx.Tx, challengeId string) error {
+	sqlQuery := `
+	UPDATE two_factor_challenges
+	SET attempts = attempts + 1
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, challengeId)
+
+	return err
+}
+
+// DeleteChallenge also removes expired challenges so the table does not grow
+func (repository *TwoFactorRepositoryImpl) DeleteChallenge(ctx context.Context, tx pgx.Tx, challengeId string) error {
+	sqlQuery := `
+	DELETE FROM two_factor_challenges
+	WHERE id = $1 OR expires_at < CURRENT_TIMESTAMP
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, challengeId)
+
+	return err
+}
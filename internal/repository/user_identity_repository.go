@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+type UserIdentityRepository interface {
+	FindByIssuerAndSubject(ctx context.Context, tx pgx.Tx, issuer, subject string) (domain.UserIdentity, error)
+	Save(ctx context.Context, tx pgx.Tx, identity domain.UserIdentity) (domain.UserIdentity, error)
+	TouchLastLogin(ctx context.Context, tx pgx.Tx, identityId, email string) error
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+func NewUserIdentityRepository() UserIdentityRepository {
+	return &UserIdentityRepositoryImpl{}
+}
+
+type UserIdentityRepositoryImpl struct{}
+
+// FindByIssuerAndSubject returns pgx.ErrNoRows when the identity was never linked
+func (repository *UserIdentityRepositoryImpl) FindByIssuerAndSubject(ctx context.Context, tx pgx.Tx, issuer, subject string) (domain.UserIdentity, error) {
+	sqlQuery := `
+	SELECT id, user_id, issuer, subject, email, last_login_at, created_at
+	FROM user_identities
+	WHERE issuer = $1 AND subject = $2
+	`
+
+	identity := domain.UserIdentity{}
+
+	err := tx.QueryRow(ctx, sqlQuery, issuer, subject).Scan(
+		&identity.Id,
+		&identity.UserId,
+		&identity.Issuer,
+		&identity.Subject,
+		&identity.Email,
+		&identity.LastLoginAt,
+		&identity.CreatedAt,
+	)
+	if err != nil {
+		return domain.UserIdentity{}, err
+	}
+
+	return identity, nil
+}
+
+func (repository *UserIdentityRepositoryImpl) Save(ctx context.Context, tx pgx.Tx, identity domain.UserIdentity) (domain.UserIdentity, error) {
+	sqlQuery := `
+	INSERT INTO user_identities (user_id, issuer, subject, email)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, last_login_at, created_at
+	`
+
+	err := tx.QueryRow(
+		ctx,
+		sqlQuery,
+		identity.UserId,
+		identity.Issuer,
+		identity.Subject,
+		identity.Email,
+	).Scan(
+		&identity.Id,
+		&identity.LastLoginAt,
+		&identity.CreatedAt,
+	)
+	if err != nil {
+		return domain.UserIdentity{}, err
+	}
+
+	return identity, nil
+}
+
+// TouchLastLogin also stores the latest email sent by the identity provider
+func (repository *UserIdentityRepositoryImpl) TouchLastLogin(ctx context.Context, tx pgx.Tx, identityId, email string) error {
+	sqlQuery := `
+	UPDATE user_identities
+	SET email = $2, last_login_at = CURRENT_TIMESTAMP
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, identityId, email)
+
+	return err
+}
@@ -2,12 +2,26 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
 )
 
 type UserRepository interface {
-	Save(ctx context.Context, tx pgx.Tx, user domain.User) error
+	Save(ctx context.Context, tx pgx.Tx, user domain.User) (domain.User, error)
 	FindByEmail(ctx context.Context, tx pgx.Tx, email string) (domain.User, error)
+	UpdatePassword(ctx context.Context, tx pgx.Tx, userId, hashedPassword string) error
+	FindById(ctx context.Context, tx pgx.Tx, userId string) (domain.User, error)
+	MarkEmailVerified(ctx context.Context, tx pgx.Tx, userId string) error
+	MarkApproved(ctx context.Context, tx pgx.Tx, userId string) error
+	MarkTemporaryPassword(ctx context.Context, tx pgx.Tx, userId string, ttl time.Duration) error
+	TouchVerificationSentAt(ctx context.Context, tx pgx.Tx, userId string, interval time.Duration) (bool, error)
+	UpdateFullName(ctx context.Context, tx pgx.Tx, userId, fullName string) error
+	SavePendingEmail(ctx context.Context, tx pgx.Tx, userId, email string) error
+	ConfirmPendingEmail(ctx context.Context, tx pgx.Tx, userId, email string) (bool, error)
+	CountActiveByRole(ctx context.Context, tx pgx.Tx, role string) (int, error)
+	CountSharedOwnedExams(ctx context.Context, tx pgx.Tx, userId string) (int, error)
+	HandOverOwnedExams(ctx context.Context, tx pgx.Tx, userId string) error
+	Delete(ctx context.Context, tx pgx.Tx, userId string) error
 }
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -15,10 +16,10 @@ func NewUserRepository() UserRepository {
 
 type UserRepositoryImpl struct{}
 
-func (repository *UserRepositoryImpl) Save(ctx context.Context, tx pgx.Tx, user domain.User) error {
+func (repository *UserRepositoryImpl) Save(ctx context.Context, tx pgx.Tx, user domain.User) (domain.User, error) {
 	sqlQuery := `
-	INSERT INTO users (id, email, full_name, password, role)
-	VALUES ($1, $2, $3, $4, $5)
+	INSERT INTO users (id, email, full_name, password, role, is_approved)
+	VALUES ($1, $2, $3, $4, $5, $6)
 	RETURNING id, created_at, updated_at
 	`
 
@@ -30,21 +31,23 @@ func (repository *UserRepositoryImpl) Save(ctx context.Context, tx pgx.Tx, user
 		user.FullName,
 		user.Password,
 		user.Role,
+		user.IsApproved,
 	).Scan(
 		&user.Id,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
 	if err != nil {
-		return err
+		return domain.User{}, err
 	}
 
-	return nil
+	return user, nil
 }
 
 func (repository *UserRepositoryImpl) FindByEmail(ctx context.Context, tx pgx.Tx, email string) (domain.User, error) {
 	sqlQuery := `
-	SELECT id, email, full_name, password, role
+	SELECT id, email, full_name, password, role, is_disabled, is_approved, email_verified_at IS NOT NULL, totp_enabled,
+		temporary_password_expires_at IS NOT NULL, COALESCE(temporary_password_expires_at < CURRENT_TIMESTAMP, FALSE)
 	FROM users
 	WHERE email = $1
 	`
@@ -61,6 +64,12 @@ func (repository *UserRepositoryImpl) FindByEmail(ctx context.Context, tx pgx.Tx
 		&user.FullName,
 		&user.Password,
 		&user.Role,
+		&user.IsDisabled,
+		&user.IsApproved,
+		&user.IsEmailVerified,
+		&user.TwoFactorEnabled,
+		&user.MustChangePassword,
+		&user.TemporaryPasswordExpired,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -72,3 +81,228 @@ func (repository *UserRepositoryImpl) FindByEmail(ctx context.Context, tx pgx.Tx
 
 	return user, nil
 }
+
+func (repository *UserRepositoryImpl) UpdatePassword(ctx context.Context, tx pgx.Tx, userId, hashedPassword string) error {
+	sqlQuery := `
+	UPDATE users
+	SET password = $2, temporary_password_expires_at = NULL, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, userId, hashedPassword)
+
+	return err
+}
+
+func (repository *UserRepositoryImpl) FindById(ctx context.Context, tx pgx.Tx, userId string) (domain.User, error) {
+	sqlQuery := `
+	SELECT id, email, full_name, password, role, is_disabled, is_approved, email_verified_at IS NOT NULL, totp_enabled, COALESCE(pending_email, ''),
+		temporary_password_expires_at IS NOT NULL, created_at, updated_at
+	FROM users
+	WHERE id = $1
+	`
+
+	user := domain.User{}
+
+	err := tx.QueryRow(ctx, sqlQuery, userId).Scan(
+		&user.Id,
+		&user.Email,
+		&user.FullName,
+		&user.Password,
+		&user.Role,
+		&user.IsDisabled,
+		&user.IsApproved,
+		&user.IsEmailVerified,
+		&user.TwoFactorEnabled,
+		&user.PendingEmail,
+		&user.MustChangePassword,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	return user, nil
+}
+
+func (repository *UserRepositoryImpl) MarkEmailVerified(ctx context.Context, tx pgx.Tx, userId string) error {
+	sqlQuery := `
+	UPDATE users
+	SET email_verified_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1 AND email_verified_at IS NULL
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, userId)
+
+	return err
+}
+
+func (repository *UserRepositoryImpl) MarkApproved(ctx context.Context, tx pgx.Tx, userId string) error {
+	sqlQuery := `
+	UPDATE users
+	SET is_approved = TRUE, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, userId)
+
+	return err
+}
+
+// MarkTemporaryPassword makes the user change the password at the next login. The password
+// stops working after ttl, UpdatePassword clears the mark.
+func (repository *UserRepositoryImpl) MarkTemporaryPassword(ctx context.Context, tx pgx.Tx, userId string, ttl time.Duration) error {
+	sqlQuery := `
+	UPDATE users
+	SET temporary_password_expires_at = CURRENT_TIMESTAMP + make_interval(secs => $2), updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, userId, ttl.Seconds())
+
+	return err
+}
+
+// TouchVerificationSentAt records that a verification email is sent. It returns false without
+// updating anything when the previous email was sent less than interval ago.
+func (repository *UserRepositoryImpl) TouchVerificationSentAt(ctx context.Context, tx pgx.Tx, userId string, interval time.Duration) (bool, error) {
+	sqlQuery := `
+	UPDATE users
+	SET email_verification_sent_at = CURRENT_TIMESTAMP
+	WHERE id = $1
+		AND (email_verification_sent_at IS NULL
+			OR email_verification_sent_at <= CURRENT_TIMESTAMP - make_interval(secs => $2))
+	`
+
+	commandTag, err := tx.Exec(ctx, sqlQuery, userId, interval.Seconds())
+	if err != nil {
+		return false, err
+	}
+
+	return commandTag.RowsAffected() > 0, nil
+}
+
+func (repository *UserRepositoryImpl) UpdateFullName(ctx context.Context, tx pgx.Tx, userId, fullName string) error {
+	sqlQuery := `
+	UPDATE users
+	SET full_name = $2, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, userId, fullName)
+
+	return err
+}
+
+// SavePendingEmail keeps the new email until it is confirmed, the current email stays in use
+func (repository *UserRepositoryImpl) SavePendingEmail(ctx context.Context, tx pgx.Tx, userId, email string) error {
+	sqlQuery := `
+	UPDATE users
+	SET pending_email = $2, email_verification_sent_at = NULL, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, userId, email)
+
+	return err
+}
+
+// ConfirmPendingEmail swaps in the pending email when it still matches the confirmed one
+func (repository *UserRepositoryImpl) ConfirmPendingEmail(ctx context.Context, tx pgx.Tx, userId, email string) (bool, error) {
+	sqlQuery := `
+	UPDATE users
+	SET email = pending_email, pending_email = NULL, email_verified_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1 AND pending_email = $2
+	`
+
+	commandTag, err := tx.Exec(ctx, sqlQuery, userId, email)
+	if err != nil {
+		return false, err
+	}
+
+	return commandTag.RowsAffected() == 1, nil
+}
+
+func (repository *UserRepositoryImpl) CountActiveByRole(ctx context.Context, tx pgx.Tx, role string) (int, error) {
+	sqlQuery := `
+	SELECT COUNT(*)
+	FROM users
+	WHERE role = $1 AND is_disabled = FALSE
+	`
+
+	var count int
+	err := tx.QueryRow(ctx, sqlQuery, role).Scan(&count)
+
+	return count, err
+}
+
+// CountSharedOwnedExams counts the exams where the user is the only owner while other teachers
+// collaborate on them or students already attempted them
+func (repository *UserRepositoryImpl) CountSharedOwnedExams(ctx context.Context, tx pgx.Tx, userId string) (int, error) {
+	sqlQuery := `
+	SELECT COUNT(*)
+	FROM exam_collaborators c
+	WHERE c.teacher_id = $1
+		AND c.role = 'owner'
+		AND NOT EXISTS (
+			SELECT 1 FROM exam_collaborators o
+			WHERE o.exam_id = c.exam_id AND o.teacher_id <> $1 AND o.role = 'owner'
+		)
+		AND (
+			EXISTS (SELECT 1 FROM exam_collaborators o WHERE o.exam_id = c.exam_id AND o.teacher_id <> $1)
+			OR EXISTS (SELECT 1 FROM exam_attempts a WHERE a.exam_id = c.exam_id)
+		)
+	`
+
+	var count int
+	err := tx.QueryRow(ctx, sqlQuery, userId).Scan(&count)
+
+	return count, err
+}
+
+// HandOverOwnedExams keeps the exams created by the user that have another owner: the earliest
+// other owner becomes the creator, and the user's bank questions used by exams that stay are
+// copied into them before the bank is deleted with the account
+func (repository *UserRepositoryImpl) HandOverOwnedExams(ctx context.Context, tx pgx.Tx, userId string) error {
+	sqlQuery := `
+	UPDATE exams e
+	SET teacher_id = o.teacher_id, updated_at = CURRENT_TIMESTAMP
+	FROM (
+		SELECT DISTINCT ON (exam_id) exam_id, teacher_id
+		FROM exam_collaborators
+		WHERE teacher_id <> $1 AND role = 'owner'
+		ORDER BY exam_id, created_at
+	) o
+	WHERE e.id = o.exam_id AND e.teacher_id = $1
+	`
+
+	if _, err := tx.Exec(ctx, sqlQuery, userId); err != nil {
+		return err
+	}
+
+	sqlQuery = `
+	UPDATE questions q
+	SET question = b.question, correct_answer = b.correct_answer, bank_question_id = NULL
+	FROM bank_questions b, exams e
+	WHERE q.bank_question_id = b.id
+		AND b.teacher_id = $1
+		AND e.id = q.exam_id
+		AND e.teacher_id <> $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, userId)
+
+	return err
+}
+
+func (repository *UserRepositoryImpl) Delete(ctx context.Context, tx pgx.Tx, userId string) error {
+	sqlQuery := `
+	DELETE FROM users
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, userId)
+
+	return err
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+type WebhookRepository interface {
+	// Endpoints, every query is limited to the endpoints of the user
+	SaveEndpoint(ctx context.Context, tx pgx.Tx, endpoint domain.WebhookEndpoint) (domain.WebhookEndpoint, error)
+	FindEndpointsByUserId(ctx context.Context, tx pgx.Tx, userId string) ([]domain.WebhookEndpoint, error)
+	FindEndpointById(ctx context.Context, tx pgx.Tx, userId, endpointId string) (domain.WebhookEndpoint, error)
+	CountEndpointsByUserId(ctx context.Context, tx pgx.Tx, userId string) (int, error)
+	UpdateEndpointActive(ctx context.Context, tx pgx.Tx, userId, endpointId string, isActive bool) (bool, error)
+	UpdateEndpointSecret(ctx context.Context, tx pgx.Tx, userId, endpointId, secret string) (bool, error)
+	DeleteEndpoint(ctx context.Context, tx pgx.Tx, userId, endpointId string) (bool, error)
+
+	// Delivery queue
+	EnqueueEvent(ctx context.Context, tx pgx.Tx, event, examId string, roles []string, payload []byte) (int64, error)
+	SaveDelivery(ctx context.Context, tx pgx.Tx, endpointId, event string, payload []byte) error
+	FindDeliveriesByUserId(ctx context.Context, tx pgx.Tx, userId string, limit int) ([]domain.WebhookDelivery, error)
+	Redeliver(ctx context.Context, tx pgx.Tx, userId, deliveryId string) (bool, error)
+	ClaimDueDeliveries(ctx context.Context, tx pgx.Tx, limit int, lease time.Duration) ([]domain.WebhookDelivery, error)
+	MarkDeliverySucceeded(ctx context.Context, tx pgx.Tx, deliveryId string, statusCode int) error
+	MarkDeliveryRetry(ctx context.Context, tx pgx.Tx, deliveryId string, statusCode int, lastError string, delay time.Duration) error
+	MarkDeliveryFailed(ctx context.Context, tx pgx.Tx, deliveryId string, statusCode int, lastError string) error
+}
@@ -0,0 +1,360 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+func NewWebhookRepository() WebhookRepository {
+	return &WebhookRepositoryImpl{}
+}
+
+type WebhookRepositoryImpl struct{}
+
+func (repository *WebhookRepositoryImpl) SaveEndpoint(ctx context.Context, tx pgx.Tx, endpoint domain.WebhookEndpoint) (domain.WebhookEndpoint, error) {
+	sqlQuery := `
+	INSERT INTO webhook_endpoints (user_id, url, secret, events)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, is_active, created_at, updated_at
+	`
+
+	err := tx.QueryRow(ctx, sqlQuery, endpoint.UserId, endpoint.URL, endpoint.Secret, endpoint.Events).Scan(
+		&endpoint.Id,
+		&endpoint.IsActive,
+		&endpoint.CreatedAt,
+		&endpoint.UpdatedAt,
+	)
+	if err != nil {
+		return domain.WebhookEndpoint{}, err
+	}
+
+	return endpoint, nil
+}
+
+func (repository *WebhookRepositoryImpl) FindEndpointsByUserId(ctx context.Context, tx pgx.Tx, userId string) ([]domain.WebhookEndpoint, error) {
+	sqlQuery := `
+	SELECT id, user_id, url, secret, events, is_active, created_at, updated_at
+	FROM webhook_endpoints
+	WHERE user_id = $1
+	ORDER BY created_at DESC
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := []domain.WebhookEndpoint{}
+	for rows.Next() {
+		endpoint := domain.WebhookEndpoint{}
+		if err := rows.Scan(
+			&endpoint.Id,
+			&endpoint.UserId,
+			&endpoint.URL,
+			&endpoint.Secret,
+			&endpoint.Events,
+			&endpoint.IsActive,
+			&endpoint.CreatedAt,
+			&endpoint.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func (repository *WebhookRepositoryImpl) FindEndpointById(ctx context.Context, tx pgx.Tx, userId, endpointId string) (domain.WebhookEndpoint, error) {
+	sqlQuery := `
+	SELECT id, user_id, url, secret, events, is_active, created_at, updated_at
+	FROM webhook_endpoints
+	WHERE id = $1 AND user_id = $2
+	`
+
+	endpoint := domain.WebhookEndpoint{}
+	err := tx.QueryRow(ctx, sqlQuery, endpointId, userId).Scan(
+		&endpoint.Id,
+		&endpoint.UserId,
+		&endpoint.URL,
+		&endpoint.Secret,
+		&endpoint.Events,
+		&endpoint.IsActive,
+		&endpoint.CreatedAt,
+		&endpoint.UpdatedAt,
+	)
+	if err != nil {
+		return domain.WebhookEndpoint{}, err
+	}
+
+	return endpoint, nil
+}
+
+func (repository *WebhookRepositoryImpl) CountEndpointsByUserId(ctx context.Context, tx pgx.Tx, userId string) (int, error) {
+	sqlQuery := `
+	SELECT COUNT(*)
+	FROM webhook_endpoints
+	WHERE user_id = $1
+	`
+
+	var count int
+	if err := tx.QueryRow(ctx, sqlQuery, userId).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// UpdateEndpointActive returns false when the endpoint does not belong to the user
+func (repository *WebhookRepositoryImpl) UpdateEndpointActive(ctx context.Context, tx pgx.Tx, userId, endpointId string, isActive bool) (bool, error) {
+	sqlQuery := `
+	UPDATE webhook_endpoints
+	SET is_active = $1, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $2 AND user_id = $3
+	`
+
+	commandTag, err := tx.Exec(ctx, sqlQuery, isActive, endpointId, userId)
+	if err != nil {
+		return false, err
+	}
+
+	return commandTag.RowsAffected() == 1, nil
+}
+
+// UpdateEndpointSecret returns false when the endpoint does not belong to the user
+func (repository *WebhookRepositoryImpl) UpdateEndpointSecret(ctx context.Context, tx pgx.Tx, userId, endpointId, secret string) (bool, error) {
+	sqlQuery := `
+	UPDATE webhook_endpoints
+	SET secret = $1, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $2 AND user_id = $3
+	`
+
+	commandTag, err := tx.Exec(ctx, sqlQuery, secret, endpointId, userId)
+	if err != nil {
+		return false, err
+	}
+
+	return commandTag.RowsAffected() == 1, nil
+}
+
+// DeleteEndpoint also removes its deliveries through ON DELETE CASCADE
+func (repository *WebhookRepositoryImpl) DeleteEndpoint(ctx context.Context, tx pgx.Tx, userId, endpointId string) (bool, error) {
+	sqlQuery := `
+	DELETE FROM webhook_endpoints
+	WHERE id = $1 AND user_id = $2
+	`
+
+	commandTag, err := tx.Exec(ctx, sqlQuery, endpointId, userId)
+	if err != nil {
+		return false, err
+	}
+
+	return commandTag.RowsAffected() == 1, nil
+}
+
+// EnqueueEvent queues the payload for every active endpoint subscribed to the event that may see the
+// exam: endpoints of admins and of teachers who collaborate on it with one of roles. It returns the
+// number of deliveries.
+func (repository *WebhookRepositoryImpl) EnqueueEvent(ctx context.Context, tx pgx.Tx, event, examId string, roles []string, payload []byte) (int64, error) {
+	sqlQuery := `
+	INSERT INTO webhook_deliveries (endpoint_id, event, payload)
+	SELECT e.id, $1::text, $3::text
+	FROM webhook_endpoints e
+	JOIN users u ON u.id = e.user_id
+	WHERE e.is_active AND $1::text = ANY(e.events)
+	AND (
+		u.role = 'admin'
+		OR EXISTS (
+			SELECT 1
+			FROM exam_collaborators c
+			WHERE c.exam_id = $2 AND c.teacher_id = e.user_id AND c.role::text = ANY($4::text[])
+		)
+	)
+	`
+
+	commandTag, err := tx.Exec(ctx, sqlQuery, event, examId, string(payload), roles)
+	if err != nil {
+		return 0, err
+	}
+
+	return commandTag.RowsAffected(), nil
+}
+
+// SaveDelivery queues a payload for a single endpoint, used for test events
+func (repository *WebhookRepositoryImpl) SaveDelivery(ctx context.Context, tx pgx.Tx, endpointId, event string, payload []byte) error {
+	sqlQuery := `
+	INSERT INTO webhook_deliveries (endpoint_id, event, payload)
+	VALUES ($1, $2, $3)
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, endpointId, event, string(payload))
+
+	return err
+}
+
+// FindDeliveriesByUserId returns the latest deliveries to any endpoint of the user
+func (repository *WebhookRepositoryImpl) FindDeliveriesByUserId(ctx context.Context, tx pgx.Tx, userId string, limit int) ([]domain.WebhookDelivery, error) {
+	sqlQuery := `
+	SELECT d.id, d.endpoint_id, e.url, d.event, d.payload, d.status, d.attempts, d.next_attempt_at, d.last_status_code, d.last_error, d.last_attempt_at, d.delivered_at, d.created_at
+	FROM webhook_deliveries d
+	JOIN webhook_endpoints e ON e.id = d.endpoint_id
+	WHERE e.user_id = $1
+	ORDER BY d.created_at DESC, d.id
+	LIMIT $2
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, userId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []domain.WebhookDelivery{}
+	for rows.Next() {
+		delivery := domain.WebhookDelivery{}
+		if err := rows.Scan(
+			&delivery.Id,
+			&delivery.EndpointId,
+			&delivery.EndpointURL,
+			&delivery.Event,
+			&delivery.Payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.NextAttemptAt,
+			&delivery.LastStatusCode,
+			&delivery.LastError,
+			&delivery.LastAttemptAt,
+			&delivery.DeliveredAt,
+			&delivery.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// Redeliver queues the delivery again with a fresh attempt count, false when it does not belong
+// to the user or is still waiting in the queue
+func (repository *WebhookRepositoryImpl) Redeliver(ctx context.Context, tx pgx.Tx, userId, deliveryId string) (bool, error) {
+	sqlQuery := `
+	UPDATE webhook_deliveries d
+	SET status = 'pending', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP
+	FROM webhook_endpoints e
+	WHERE d.id = $1 AND e.id = d.endpoint_id AND e.user_id = $2 AND d.status <> 'pending'
+	`
+
+	commandTag, err := tx.Exec(ctx, sqlQuery, deliveryId, userId)
+	if err != nil {
+		return false, err
+	}
+
+	return commandTag.RowsAffected() == 1, nil
+}
+
+// ClaimDueDeliveries locks the pending deliveries that are due and moves their next attempt past the
+// lease, so another worker does not send them again while they are in flight. Deliveries of disabled
+// endpoints stay in the queue until the endpoint is enabled again.
+func (repository *WebhookRepositoryImpl) ClaimDueDeliveries(ctx context.Context, tx pgx.Tx, limit int, lease time.Duration) ([]domain.WebhookDelivery, error) {
+	sqlQuery := `
+	WITH due AS (
+		SELECT d.id
+		FROM webhook_deliveries d
+		JOIN webhook_endpoints e ON e.id = d.endpoint_id
+		WHERE d.status = 'pending' AND d.next_attempt_at <= CURRENT_TIMESTAMP AND e.is_active
+		ORDER BY d.next_attempt_at, d.created_at
+		LIMIT $1
+		FOR UPDATE OF d SKIP LOCKED
+	)
+	UPDATE webhook_deliveries d
+	SET next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $2)
+	FROM due, webhook_endpoints e
+	WHERE d.id = due.id AND e.id = d.endpoint_id
+	RETURNING d.id, d.endpoint_id, e.url, e.secret, d.event, d.payload, d.status, d.attempts, d.created_at
+	`
+
+	rows, err := tx.Query(ctx, sqlQuery, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []domain.WebhookDelivery{}
+	for rows.Next() {
+		delivery := domain.WebhookDelivery{}
+		if err := rows.Scan(
+			&delivery.Id,
+			&delivery.EndpointId,
+			&delivery.EndpointURL,
+			&delivery.EndpointSecret,
+			&delivery.Event,
+			&delivery.Payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (repository *WebhookRepositoryImpl) MarkDeliverySucceeded(ctx context.Context, tx pgx.Tx, deliveryId string, statusCode int) error {
+	sqlQuery := `
+	UPDATE webhook_deliveries
+	SET status = 'succeeded', attempts = attempts + 1, last_status_code = $1, last_error = '',
+		last_attempt_at = CURRENT_TIMESTAMP, delivered_at = CURRENT_TIMESTAMP
+	WHERE id = $2
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, statusCode, deliveryId)
+
+	return err
+}
+
+// MarkDeliveryRetry keeps the delivery pending and schedules the next attempt after delay
+func (repository *WebhookRepositoryImpl) MarkDeliveryRetry(ctx context.Context, tx pgx.Tx, deliveryId string, statusCode int, lastError string, delay time.Duration) error {
+	sqlQuery := `
+	UPDATE webhook_deliveries
+	SET attempts = attempts + 1, last_status_code = $1, last_error = $2,
+		last_attempt_at = CURRENT_TIMESTAMP, next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $3)
+	WHERE id = $4
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, statusCode, lastError, delay.Seconds(), deliveryId)
+
+	return err
+}
+
+// MarkDeliveryFailed gives up on the delivery, it can still be sent again by hand
+func (repository *WebhookRepositoryImpl) MarkDeliveryFailed(ctx context.Context, tx pgx.Tx, deliveryId string, statusCode int, lastError string) error {
+	sqlQuery := `
+	UPDATE webhook_deliveries
+	SET status = 'failed', attempts = attempts + 1, last_status_code = $1, last_error = $2,
+		last_attempt_at = CURRENT_TIMESTAMP
+	WHERE id = $3
+	`
+
+	_, err := tx.Exec(ctx, sqlQuery, statusCode, lastError, deliveryId)
+
+	return err
+}
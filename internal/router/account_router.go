@@ -0,0 +1,16 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/handler"
+)
+
+func AccountRouter(handler handler.AccountHandler, mux *http.ServeMux) {
+	// Pengaturan akun untuk semua role
+	mux.HandleFunc("GET /account/{$}", handler.AccountView)
+	mux.HandleFunc("POST /account/profile", handler.UpdateProfile)
+	mux.HandleFunc("POST /account/password", handler.ChangePassword)
+	mux.HandleFunc("POST /account/email", handler.ChangeEmail)
+	mux.HandleFunc("POST /account/delete", handler.DeleteAccount)
+}
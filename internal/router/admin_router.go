@@ -0,0 +1,25 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/handler"
+)
+
+func AdminRouter(handler handler.AdminHandler, mux *http.ServeMux) {
+	// Dashboard, statistik sistem dan antrean persetujuan guru
+	mux.Handle("GET /admin/{$}", http.RedirectHandler("/admin/dashboard", http.StatusSeeOther))
+	mux.HandleFunc("GET /admin/dashboard", handler.DashboardView)
+	mux.HandleFunc("POST /admin/users/{id}/approve", handler.ApproveTeacher)
+	mux.HandleFunc("POST /admin/users/{id}/reject", handler.RejectTeacher)
+
+	// Pengaturan keamanan
+	mux.HandleFunc("POST /admin/settings/two-factor", handler.SetTeacherTwoFactor)
+
+	// Kelola pengguna
+	mux.HandleFunc("GET /admin/users", handler.UsersView)
+	mux.HandleFunc("POST /admin/users/{id}/disable", handler.DisableUser)
+	mux.HandleFunc("POST /admin/users/{id}/enable", handler.EnableUser)
+	mux.HandleFunc("POST /admin/users/{id}/role", handler.ChangeUserRole)
+	mux.HandleFunc("DELETE /admin/users/{id}", handler.DeleteUser)
+}
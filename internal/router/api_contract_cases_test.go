@@ -0,0 +1,95 @@
+package router_test
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/model/api"
+)
+
+// contractCase is one request to an operation and the status it must answer with
+type contractCase struct {
+	Name      string
+	Operation string
+	Token     string
+	Params    map[string]string
+	// Request replaces the sample request of the operation, Body replaces the encoded body completely
+	Request any
+	Body    string
+	Status  int
+}
+
+var (
+	examCreateRequest = api.ExamCreateRequest{
+		Name:            "Biologi Kelas X",
+		Year:            2026,
+		DurationMinutes: 90,
+		Questions:       []api.QuestionRequest{{Question: "Apa fungsi mitokondria?", Answer: "Menghasilkan energi"}},
+	}
+	examUpdateRequest = api.ExamUpdateRequest{
+		Name:              "Biologi Kelas X",
+		Year:              2026,
+		DurationMinutes:   90,
+		ShuffleQuestions:  true,
+		QuestionDrawCount: 2,
+		IsActive:          true,
+	}
+	questionRequest = api.QuestionRequest{Question: "Apa itu osmosis?", Answer: "Perpindahan air melalui membran"}
+	answerRequest   = api.AnswerRequest{Answer: "Tempat respirasi sel"}
+)
+
+const invalidJSON = `{"name":`
+
+var cases = []contractCase{
+	// Autentikasi dan otorisasi yang berlaku untuk semua route
+	{Name: "no token", Operation: "listTeacherExams", Status: http.StatusUnauthorized},
+	{Name: "unknown token", Operation: "listStudentExams", Token: "unknown-token", Status: http.StatusUnauthorized},
+	{Name: "student on teacher route", Operation: "listTeacherExams", Token: studentToken, Status: http.StatusForbidden},
+	{Name: "teacher on student route", Operation: "listStudentResults", Token: teacherToken, Status: http.StatusForbidden},
+	{Name: "token without scope", Operation: "createExam", Token: noScopeToken, Request: examCreateRequest, Status: http.StatusForbidden},
+
+	// Guru
+	{Name: "list exams", Operation: "listTeacherExams", Token: teacherToken, Status: http.StatusOK},
+	{Name: "create exam", Operation: "createExam", Token: teacherToken, Request: examCreateRequest, Status: http.StatusCreated},
+	{Name: "create exam invalid json", Operation: "createExam", Token: teacherToken, Body: invalidJSON, Status: http.StatusBadRequest},
+	{Name: "create exam unknown field", Operation: "createExam", Token: teacherToken, Body: `{"room_name":"Biologi"}`, Status: http.StatusBadRequest},
+	{Name: "create exam invalid fields", Operation: "createExam", Token: teacherToken, Request: api.ExamCreateRequest{Year: 1990, Questions: []api.QuestionRequest{{Question: "Tanpa jawaban"}}}, Status: http.StatusUnprocessableEntity},
+	{Name: "get exam", Operation: "getExam", Token: teacherToken, Params: map[string]string{"id": "exam-1"}, Status: http.StatusOK},
+	{Name: "get exam without permission", Operation: "getExam", Token: teacherToken, Params: map[string]string{"id": missingId}, Status: http.StatusForbidden},
+	{Name: "update exam", Operation: "updateExam", Token: teacherToken, Params: map[string]string{"id": "exam-1"}, Request: examUpdateRequest, Status: http.StatusOK},
+	{Name: "update exam invalid json", Operation: "updateExam", Token: teacherToken, Params: map[string]string{"id": "exam-1"}, Body: invalidJSON, Status: http.StatusBadRequest},
+	{Name: "update exam invalid fields", Operation: "updateExam", Token: teacherToken, Params: map[string]string{"id": "exam-1"}, Request: api.ExamUpdateRequest{Name: "Biologi", Year: 2026, DurationMinutes: 0}, Status: http.StatusUnprocessableEntity},
+	{Name: "update exam draw count too large", Operation: "updateExam", Token: teacherToken, Params: map[string]string{"id": "exam-1"}, Request: api.ExamUpdateRequest{Name: "Biologi", Year: 2026, DurationMinutes: 90, QuestionDrawCount: 5}, Status: http.StatusUnprocessableEntity},
+	{Name: "delete exam", Operation: "deleteExam", Token: teacherToken, Params: map[string]string{"id": "exam-1"}, Status: http.StatusNoContent},
+	{Name: "list questions", Operation: "listExamQuestions", Token: teacherToken, Params: map[string]string{"id": "exam-1"}, Status: http.StatusOK},
+	{Name: "create question", Operation: "createExamQuestion", Token: teacherToken, Params: map[string]string{"id": "exam-1"}, Request: questionRequest, Status: http.StatusCreated},
+	{Name: "create question invalid json", Operation: "createExamQuestion", Token: teacherToken, Params: map[string]string{"id": "exam-1"}, Body: invalidJSON, Status: http.StatusBadRequest},
+	{Name: "create question invalid fields", Operation: "createExamQuestion", Token: teacherToken, Params: map[string]string{"id": "exam-1"}, Request: api.QuestionRequest{}, Status: http.StatusUnprocessableEntity},
+	{Name: "update question", Operation: "updateExamQuestion", Token: teacherToken, Params: map[string]string{"id": "exam-1", "questionId": questionOne}, Request: questionRequest, Status: http.StatusOK},
+	{Name: "update question invalid json", Operation: "updateExamQuestion", Token: teacherToken, Params: map[string]string{"id": "exam-1", "questionId": questionOne}, Body: invalidJSON, Status: http.StatusBadRequest},
+	{Name: "update missing question", Operation: "updateExamQuestion", Token: teacherToken, Params: map[string]string{"id": "exam-1", "questionId": missingId}, Request: questionRequest, Status: http.StatusNotFound},
+	{Name: "update question from bank", Operation: "updateExamQuestion", Token: teacherToken, Params: map[string]string{"id": "exam-1", "questionId": questionFromBQ}, Request: questionRequest, Status: http.StatusConflict},
+	{Name: "update question invalid fields", Operation: "updateExamQuestion", Token: teacherToken, Params: map[string]string{"id": "exam-1", "questionId": questionOne}, Request: api.QuestionRequest{Question: "Tanpa jawaban"}, Status: http.StatusUnprocessableEntity},
+	{Name: "delete question", Operation: "deleteExamQuestion", Token: teacherToken, Params: map[string]string{"id": "exam-1", "questionId": questionOne}, Status: http.StatusNoContent},
+	{Name: "delete missing question", Operation: "deleteExamQuestion", Token: teacherToken, Params: map[string]string{"id": "exam-1", "questionId": missingId}, Status: http.StatusNotFound},
+	{Name: "list results", Operation: "listExamResults", Token: teacherToken, Params: map[string]string{"id": "exam-1"}, Status: http.StatusOK},
+
+	// Siswa
+	{Name: "list active exams", Operation: "listStudentExams", Token: studentToken, Status: http.StatusOK},
+	{Name: "start attempt", Operation: "startAttempt", Token: studentToken, Params: map[string]string{"examId": "exam-1"}, Status: http.StatusCreated},
+	{Name: "start attempt on missing exam", Operation: "startAttempt", Token: studentToken, Params: map[string]string{"examId": missingId}, Status: http.StatusNotFound},
+	{Name: "get attempt", Operation: "getAttempt", Token: studentToken, Params: map[string]string{"id": openAttempt}, Status: http.StatusOK},
+	{Name: "get missing attempt", Operation: "getAttempt", Token: studentToken, Params: map[string]string{"id": missingId}, Status: http.StatusNotFound},
+	{Name: "save answer", Operation: "saveAnswer", Token: studentToken, Params: map[string]string{"id": openAttempt, "questionId": questionOne}, Request: answerRequest, Status: http.StatusOK},
+	{Name: "save answer invalid json", Operation: "saveAnswer", Token: studentToken, Params: map[string]string{"id": openAttempt, "questionId": questionOne}, Body: invalidJSON, Status: http.StatusBadRequest},
+	{Name: "save answer to missing question", Operation: "saveAnswer", Token: studentToken, Params: map[string]string{"id": openAttempt, "questionId": missingId}, Request: answerRequest, Status: http.StatusNotFound},
+	{Name: "save answer after submit", Operation: "saveAnswer", Token: studentToken, Params: map[string]string{"id": doneAttempt, "questionId": questionOne}, Request: answerRequest, Status: http.StatusConflict},
+	{Name: "save answer too long", Operation: "saveAnswer", Token: studentToken, Params: map[string]string{"id": openAttempt, "questionId": questionOne}, Request: api.AnswerRequest{Answer: strings.Repeat("a", 10001)}, Status: http.StatusUnprocessableEntity},
+	{Name: "submit attempt", Operation: "submitAttempt", Token: studentToken, Params: map[string]string{"id": openAttempt}, Status: http.StatusOK},
+	{Name: "submit missing attempt", Operation: "submitAttempt", Token: studentToken, Params: map[string]string{"id": missingId}, Status: http.StatusNotFound},
+	{Name: "submit attempt twice", Operation: "submitAttempt", Token: studentToken, Params: map[string]string{"id": doneAttempt}, Status: http.StatusConflict},
+	{Name: "list student results", Operation: "listStudentResults", Token: studentToken, Status: http.StatusOK},
+	{Name: "get attempt result", Operation: "getAttemptResult", Token: studentToken, Params: map[string]string{"id": doneAttempt}, Status: http.StatusOK},
+	{Name: "get result of missing attempt", Operation: "getAttemptResult", Token: studentToken, Params: map[string]string{"id": missingId}, Status: http.StatusNotFound},
+	{Name: "get result before submit", Operation: "getAttemptResult", Token: studentToken, Params: map[string]string{"id": openAttempt}, Status: http.StatusConflict},
+}
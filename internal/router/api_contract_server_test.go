@@ -0,0 +1,294 @@
+package router_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mhaatha/go-template-saygenfix/internal/config"
+	"github.com/mhaatha/go-template-saygenfix/internal/handler"
+	"github.com/mhaatha/go-template-saygenfix/internal/middleware"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/router"
+	"github.com/mhaatha/go-template-saygenfix/internal/service"
+)
+
+// Bearer tokens known by fakeAPITokenService, any other token is rejected
+const (
+	teacherToken = "teacher-token"
+	studentToken = "student-token"
+	noScopeToken = "no-scope-token"
+)
+
+// Ids the fake services answer differently for, every other exam id exists
+const (
+	missingId      = "missing"
+	openAttempt    = "attempt-open"
+	doneAttempt    = "attempt-done"
+	questionOne    = "question-1"
+	questionFromBQ = "question-2"
+)
+
+var allScopes = []string{domain.ScopeExamsRead, domain.ScopeExamsWrite, domain.ScopeAttemptsWrite, domain.ScopeResultsRead}
+
+var sampleTime = time.Date(2026, time.March, 2, 8, 0, 0, 0, time.UTC)
+
+// newAPIServer mounts the API routers with the same middleware as cmd/web
+func newAPIServer() http.Handler {
+	validate := config.ValidatorInit()
+	authMiddleware := middleware.NewAuthMiddleware(nil, fakeAPITokenService{}, &config.Config{SessionName: "session"})
+	twoFactorMiddleware := middleware.NewTwoFactorMiddleware(fakeTwoFactorService{})
+
+	teacherAPIRouter := http.NewServeMux()
+	router.TeacherAPIRouter(handler.NewTeacherAPIHandler(fakeTeacherService{validate: validate}), teacherAPIRouter, authMiddleware)
+	teacherAPIRouter.HandleFunc("/api/v1/teacher/", handler.APINotFound)
+
+	studentAPIRouter := http.NewServeMux()
+	router.StudentAPIRouter(handler.NewStudentAPIHandler(fakeStudentService{validate: validate}), studentAPIRouter, authMiddleware)
+	studentAPIRouter.HandleFunc("/api/v1/student/", handler.APINotFound)
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/teacher/", authMiddleware.Authenticate(authMiddleware.RequireRole("teacher")(twoFactorMiddleware.RequireEnrollment(teacherAPIRouter))))
+	mux.Handle("/api/v1/student/", authMiddleware.Authenticate(authMiddleware.RequireRole("student")(studentAPIRouter)))
+	mux.HandleFunc("/api/", handler.APINotFound)
+
+	return mux
+}
+
+// Fake services embed the interface, a method the API does not call panics when it is called anyway
+
+type fakeAPITokenService struct {
+	service.APITokenService
+}
+
+func (fakeAPITokenService) Authenticate(ctx context.Context, request web.APITokenAuthRequest) (domain.User, domain.APIToken, error) {
+	switch request.Token {
+	case teacherToken:
+		return domain.User{Id: "teacher-1", FullName: "Guru", Role: "teacher"}, domain.APIToken{Scopes: allScopes}, nil
+	case studentToken:
+		return domain.User{Id: "student-1", FullName: "Siswa", Role: "student"}, domain.APIToken{Scopes: allScopes}, nil
+	case noScopeToken:
+		return domain.User{Id: "teacher-1", FullName: "Guru", Role: "teacher"}, domain.APIToken{}, nil
+	default:
+		return domain.User{}, domain.APIToken{}, service.ErrAPITokenInvalid
+	}
+}
+
+type fakeTwoFactorService struct {
+	service.TwoFactorService
+}
+
+func (fakeTwoFactorService) IsEnrollmentRequired(ctx context.Context, user domain.User) (bool, error) {
+	return false, nil
+}
+
+type fakeTeacherService struct {
+	service.TeacherService
+	validate *validator.Validate
+}
+
+func sampleExam(examId string) domain.Exam {
+	return domain.Exam{
+		Id:                examId,
+		RoomName:          "Biologi Kelas X",
+		Year:              2026,
+		Duration:          90,
+		TeacherId:         "teacher-1",
+		TeacherName:       "Guru",
+		IsActive:          true,
+		IsPrivate:         true,
+		JoinCode:          "AB12CD",
+		QuestionDrawCount: 2,
+		Role:              domain.ExamRoleOwner,
+		CreatedAt:         sampleTime,
+		UpdatedAt:         sampleTime,
+	}
+}
+
+func (fakeTeacherService) TeacherDashboard(ctx context.Context, userId, classId string) (web.TeacherDashboardResponse, error) {
+	// Ujian publik tanpa join code dan role, field omitempty juga ikut dicek
+	exam := sampleExam("exam-2")
+	exam.IsPrivate, exam.JoinCode, exam.Role = false, "", ""
+
+	return web.TeacherDashboardResponse{Exams: []domain.Exam{sampleExam("exam-1"), exam}}, nil
+}
+
+func (fakeTeacherService) GetExamForTeacher(ctx context.Context, teacherId, examId, requiredRole string) (domain.Exam, error) {
+	if examId == missingId {
+		return domain.Exam{}, service.ErrExamForbidden
+	}
+
+	return sampleExam(examId), nil
+}
+
+func (fakeTeacherService) GetQAByExamId(ctx context.Context, examId string) ([]domain.QAItem, error) {
+	return []domain.QAItem{
+		{Id: questionOne, Question: "Apa fungsi mitokondria?", Answer: "Menghasilkan energi", ExamId: examId},
+		{Id: questionFromBQ, Question: "Apa itu fotosintesis?", Answer: "Pembuatan makanan dengan cahaya", ExamId: examId, BankQuestionId: "bank-1"},
+	}, nil
+}
+
+func (fake fakeTeacherService) CreateExam(ctx context.Context, teacherId string, request web.ExamCreateRequest) (domain.Exam, error) {
+	if err := fake.validate.Struct(request); err != nil {
+		return domain.Exam{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	return sampleExam("exam-new"), nil
+}
+
+func (fake fakeTeacherService) UpdateExam(ctx context.Context, teacherId, examId string, request web.ExamUpdateRequest) (domain.Exam, error) {
+	if examId == missingId {
+		return domain.Exam{}, service.ErrExamForbidden
+	}
+	if err := fake.validate.Struct(request); err != nil {
+		return domain.Exam{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+	if request.QuestionDrawCount > 2 {
+		return domain.Exam{}, service.ErrQuestionDrawCount
+	}
+
+	return sampleExam(examId), nil
+}
+
+func (fakeTeacherService) DeleteExam(ctx context.Context, teacherId, examId string) error {
+	if examId == missingId {
+		return service.ErrExamForbidden
+	}
+
+	return nil
+}
+
+func (fake fakeTeacherService) CreateQuestion(ctx context.Context, teacherId, examId string, request web.QuestionSaveRequest) (domain.QAItem, error) {
+	if err := fake.validate.Struct(request); err != nil {
+		return domain.QAItem{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	return domain.QAItem{Id: "question-new", Question: request.Question, Answer: request.Answer, ExamId: examId}, nil
+}
+
+func (fake fakeTeacherService) UpdateQuestion(ctx context.Context, teacherId, examId, questionId string, request web.QuestionSaveRequest) (domain.QAItem, error) {
+	if questionId == missingId {
+		return domain.QAItem{}, service.ErrQuestionNotFound
+	}
+	if questionId == questionFromBQ {
+		return domain.QAItem{}, service.ErrBankQuestionLinked
+	}
+	if err := fake.validate.Struct(request); err != nil {
+		return domain.QAItem{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	return domain.QAItem{Id: questionId, Question: request.Question, Answer: request.Answer, ExamId: examId}, nil
+}
+
+func (fakeTeacherService) DeleteQuestion(ctx context.Context, teacherId, examId, questionId string) error {
+	if questionId == missingId {
+		return service.ErrQuestionNotFound
+	}
+
+	return nil
+}
+
+func (fakeTeacherService) GetBiggestExamAttemptsScoreByExamId(ctx context.Context, examId, classId string) ([]web.ExamAttempt, error) {
+	// Attempt kedua belum di-submit, completed_at harus null
+	return []web.ExamAttempt{
+		{ID: "attempt-1", StudentID: "student-1", ExamID: examId, Score: 85, StartedAt: sampleTime, CompletedAt: sampleTime.Add(time.Hour)},
+		{ID: "attempt-2", StudentID: "student-2", ExamID: examId, StartedAt: sampleTime},
+	}, nil
+}
+
+func (fakeTeacherService) GetStudentFullNameByExamAttemptsId(ctx context.Context, examAttemptsId string) (string, string, error) {
+	return "Siswa " + examAttemptsId, "student-" + examAttemptsId, nil
+}
+
+type fakeStudentService struct {
+	service.StudentService
+	validate *validator.Validate
+}
+
+func (fakeStudentService) GetActiveExams(ctx context.Context, studentId string) ([]domain.Exam, error) {
+	return []domain.Exam{sampleExam("exam-1")}, nil
+}
+
+func (fakeStudentService) CreateExamAttempt(ctx context.Context, studentId, examId string) (string, error) {
+	if examId == missingId {
+		return "", service.ErrExamNotFound
+	}
+
+	return openAttempt, nil
+}
+
+func (fakeStudentService) GetAttempt(ctx context.Context, studentId, attemptId string) (web.ExamAttempt, error) {
+	switch attemptId {
+	case openAttempt:
+		return web.ExamAttempt{ID: attemptId, StudentID: studentId, ExamID: "exam-1", StartedAt: sampleTime}, nil
+	case doneAttempt:
+		return web.ExamAttempt{ID: attemptId, StudentID: studentId, ExamID: "exam-1", Score: 75, StartedAt: sampleTime, CompletedAt: sampleTime.Add(time.Hour)}, nil
+	default:
+		return web.ExamAttempt{}, service.ErrAttemptNotFound
+	}
+}
+
+func (fakeStudentService) GetAttemptQuestions(ctx context.Context, attemptId string) ([]domain.QAItem, error) {
+	return []domain.QAItem{
+		{Id: questionFromBQ, Question: "Apa itu fotosintesis?"},
+		{Id: questionOne, Question: "Apa fungsi mitokondria?"},
+	}, nil
+}
+
+func (fakeStudentService) GetAnswersByAttemptId(ctx context.Context, attemptId string) ([]web.StudentAnswer, error) {
+	return []web.StudentAnswer{{ExamAttemptID: attemptId, QuestionID: questionOne, StudentAnswer: "Tempat respirasi sel"}}, nil
+}
+
+func (fake fakeStudentService) SaveAttemptAnswer(ctx context.Context, studentId string, request web.AttemptAnswerRequest) error {
+	if err := fake.validate.Struct(request); err != nil {
+		return fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	switch {
+	case request.AttemptId == doneAttempt:
+		return service.ErrAttemptCompleted
+	case request.AttemptId != openAttempt:
+		return service.ErrAttemptNotFound
+	case request.QuestionId != questionOne && request.QuestionId != questionFromBQ:
+		return service.ErrQuestionNotInAttempt
+	}
+
+	return nil
+}
+
+func (fakeStudentService) SubmitAttempt(ctx context.Context, studentId, attemptId string) (web.ExamAttempt, error) {
+	switch attemptId {
+	case openAttempt:
+		return web.ExamAttempt{ID: attemptId, Score: 75}, nil
+	case doneAttempt:
+		return web.ExamAttempt{}, service.ErrAttemptCompleted
+	default:
+		return web.ExamAttempt{}, service.ErrAttemptNotFound
+	}
+}
+
+func (fakeStudentService) GetBiggestExamAttemptsByStudentId(ctx context.Context, userId string) ([]web.ExamAttemptsCustom, error) {
+	return []web.ExamAttemptsCustom{{Id: doneAttempt, ExamId: "exam-1", Score: 75}}, nil
+}
+
+func (fakeStudentService) GetExamsWithScoreAndTeacherNameByExamId(ctx context.Context, examAttempts []web.ExamAttemptsCustom) ([]web.ExamWithScoreAndTeacherName, error) {
+	exams := []web.ExamWithScoreAndTeacherName{}
+	for _, attempt := range examAttempts {
+		exams = append(exams, web.ExamWithScoreAndTeacherName{Id: attempt.ExamId, Name: "Biologi Kelas X", Year: 2026, TeacherName: "Guru", Score: attempt.Score})
+	}
+
+	return exams, nil
+}
+
+func (fakeStudentService) GetStudentAnswersByExamAttemptId(ctx context.Context, attemptId string) ([]web.StudentAnswer, error) {
+	return []web.StudentAnswer{
+		{ExamAttemptID: attemptId, QuestionID: questionOne, StudentAnswer: "Tempat respirasi sel", Score: 7.5, QuestionMaxScore: 10, Similarity: 0.75, Feedback: "Sebagian besar benar"},
+	}, nil
+}
+
+func (fakeStudentService) FindQuestionById(ctx context.Context, questionId string) (web.QuestionAndRightAnswer, error) {
+	return web.QuestionAndRightAnswer{Question: "Apa fungsi mitokondria?", RightAnswer: "Menghasilkan energi"}, nil
+}
@@ -0,0 +1,184 @@
+package router_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/openapi"
+)
+
+// The JSON API is checked against its OpenAPI document. A route registered in this package must
+// be in openapi.Operations and the other way around, and every response of the real handlers,
+// called through the real middleware with fake services, must match the documented status and
+// schema.
+
+func TestMain(m *testing.M) {
+	// Log handler hanya mengganggu output, error yang diharapkan juga dicatat
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	os.Exit(m.Run())
+}
+
+// TestAPIRoutesDocumented compares the API patterns given to the mux with the documented operations
+func TestAPIRoutesDocumented(t *testing.T) {
+	// go test menjalankan test dari direktori package, jadi source router ada di "."
+	registered, err := routerPatterns(".")
+	if err != nil {
+		t.Fatalf("reading router package: %v", err)
+	}
+
+	documented := []string{}
+	for _, operation := range openapi.Operations {
+		documented = append(documented, operation.Method+" "+operation.Path)
+	}
+
+	for _, pattern := range registered {
+		if !slices.Contains(documented, pattern) {
+			t.Errorf("route is not documented: %s", pattern)
+		}
+	}
+	for _, pattern := range documented {
+		if !slices.Contains(registered, pattern) {
+			t.Errorf("documented route is not registered: %s", pattern)
+		}
+	}
+}
+
+// routerPatterns returns every "METHOD /api/..." string literal passed to Handle or HandleFunc
+func routerPatterns(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := []string{}
+	fileSet := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+
+		parsed, err := parser.ParseFile(fileSet, file, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		ast.Inspect(parsed, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			selector, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || (selector.Sel.Name != "Handle" && selector.Sel.Name != "HandleFunc") {
+				return true
+			}
+			literal, ok := call.Args[0].(*ast.BasicLit)
+			if !ok || literal.Kind != token.STRING {
+				return true
+			}
+
+			pattern, err := strconv.Unquote(literal.Value)
+			if err == nil && strings.Contains(pattern, " /api/") {
+				patterns = append(patterns, pattern)
+			}
+			return true
+		})
+	}
+
+	return patterns, nil
+}
+
+// TestAPIContract sends every case to the server and validates the answer against the document
+func TestAPIContract(t *testing.T) {
+	document := openapi.Build()
+	server := newAPIServer()
+
+	exercised := map[string]bool{}
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			operation, ok := findOperation(c.Operation)
+			if !ok {
+				t.Fatalf("unknown operation %s", c.Operation)
+			}
+
+			path := operation.Path
+			for name, value := range c.Params {
+				path = strings.ReplaceAll(path, "{"+name+"}", value)
+			}
+			if strings.Contains(path, "{") {
+				t.Fatalf("path parameters missing in %s", path)
+			}
+
+			request := httptest.NewRequest(operation.Method, path, bytes.NewReader(c.body(t, operation)))
+			request.Header.Set("Content-Type", "application/json")
+			if c.Token != "" {
+				request.Header.Set("Authorization", "Bearer "+c.Token)
+			}
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, request)
+
+			if recorder.Code != c.Status {
+				t.Fatalf("%s %s answered %d, want %d: %s", operation.Method, path, recorder.Code, c.Status, recorder.Body.String())
+			}
+			if err := document.ValidateResponse(operation.Method, operation.Path, recorder.Code, recorder.Body.Bytes()); err != nil {
+				t.Fatal(err)
+			}
+
+			exercised[c.Operation+" "+strconv.Itoa(c.Status)] = true
+		})
+	}
+
+	// Setiap status yang didokumentasikan khusus untuk route harus pernah dicoba
+	for _, operation := range openapi.Operations {
+		for _, status := range append([]int{operation.Status}, operation.Errors...) {
+			if !exercised[operation.Id+" "+strconv.Itoa(status)] {
+				t.Errorf("%s: status %d is documented but no case checks it", operation.Id, status)
+			}
+		}
+	}
+}
+
+func findOperation(id string) (openapi.Operation, bool) {
+	for _, operation := range openapi.Operations {
+		if operation.Id == id {
+			return operation, true
+		}
+	}
+
+	return openapi.Operation{}, false
+}
+
+// body is the raw body of the case, or the encoded sample request of the operation
+func (c contractCase) body(t *testing.T, operation openapi.Operation) []byte {
+	t.Helper()
+
+	if c.Body != "" {
+		return []byte(c.Body)
+	}
+	if operation.Request == nil {
+		return nil
+	}
+
+	request := c.Request
+	if request == nil {
+		request = operation.Request
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		t.Fatalf("encoding request: %v", err)
+	}
+	return body
+}
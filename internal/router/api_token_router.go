@@ -0,0 +1,14 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/handler"
+)
+
+func APITokenRouter(handler handler.APITokenHandler, mux *http.ServeMux) {
+	// Token API pribadi untuk skrip dan integrasi
+	mux.HandleFunc("GET /account/tokens", handler.TokensView)
+	mux.HandleFunc("POST /account/tokens", handler.CreateToken)
+	mux.HandleFunc("POST /account/tokens/{id}/revoke", handler.RevokeToken)
+}
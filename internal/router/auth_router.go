@@ -9,4 +9,12 @@ import (
 func AuthRouter(handler handler.AuthHandler, mux *http.ServeMux) {
 	mux.HandleFunc("POST /login", handler.Login)
 	mux.HandleFunc("GET /login", handler.LoginView)
+
+	// Langkah kedua login untuk akun dengan 2FA
+	mux.HandleFunc("GET /login/two-factor", handler.TwoFactorLoginView)
+	mux.HandleFunc("POST /login/two-factor", handler.TwoFactorLogin)
+
+	// Single sign-on melalui penyedia identitas OpenID Connect
+	mux.HandleFunc("GET /auth/oidc/login", handler.OIDCLogin)
+	mux.HandleFunc("GET /auth/oidc/callback", handler.OIDCCallback)
 }
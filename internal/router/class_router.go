@@ -0,0 +1,24 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/handler"
+)
+
+func ClassRouter(handler handler.ClassHandler, mux *http.ServeMux) {
+	// Kelas dan daftar siswa milik guru
+	mux.HandleFunc("GET /teacher/classes", handler.ClassesView)
+	mux.HandleFunc("POST /teacher/classes", handler.CreateClass)
+	mux.HandleFunc("GET /teacher/classes/{id}", handler.ClassDetailView)
+	mux.HandleFunc("DELETE /teacher/classes/{id}", handler.DeleteClass)
+	mux.HandleFunc("POST /teacher/classes/{id}/join-code", handler.RegenerateJoinCode)
+
+	// Undang siswa lewat email, siswa yang belum terdaftar disimpan sebagai undangan
+	mux.HandleFunc("POST /teacher/classes/{id}/invitations", handler.InviteStudent)
+	mux.HandleFunc("DELETE /teacher/classes/{id}/invitations", handler.CancelInvitation)
+	mux.HandleFunc("DELETE /teacher/classes/{id}/members/{studentId}", handler.RemoveMember)
+
+	// Tugaskan ujian ke satu atau beberapa kelas dari halaman periksa ujian
+	mux.HandleFunc("POST /teacher/exam/{id}/classes", handler.AssignExamClasses)
+}
@@ -0,0 +1,15 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/handler"
+)
+
+func EmailVerificationRouter(handler handler.EmailVerificationHandler, mux *http.ServeMux) {
+	// Link verifikasi dari email, dan form untuk mengirim ulang link tersebut
+	mux.HandleFunc("GET /verify-email", handler.VerifyEmail)
+	mux.HandleFunc("GET /verify-email/change", handler.ConfirmEmailChange)
+	mux.HandleFunc("GET /verify-email/resend", handler.ResendVerificationView)
+	mux.HandleFunc("POST /verify-email/resend", handler.ResendVerification)
+}
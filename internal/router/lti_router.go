@@ -0,0 +1,23 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/handler"
+)
+
+func LTIRouter(handler handler.LTIHandler, mux *http.ServeMux) {
+	// Launch LTI 1.3 dari platform seperti Moodle atau Canvas, tanpa session SayGenFix
+	mux.HandleFunc("GET /lti/login", handler.Login)
+	mux.HandleFunc("POST /lti/login", handler.Login)
+	mux.HandleFunc("POST /lti/launch", handler.Launch)
+	mux.HandleFunc("GET /lti/jwks", handler.JWKS)
+	mux.HandleFunc("POST /lti/deep-link", handler.DeepLink)
+}
+
+func LTIPlatformRouter(handler handler.LTIHandler, mux *http.ServeMux) {
+	// Pendaftaran platform LTI, hanya untuk admin
+	mux.HandleFunc("GET /admin/lti", handler.PlatformsView)
+	mux.HandleFunc("POST /admin/lti", handler.CreatePlatform)
+	mux.HandleFunc("POST /admin/lti/{id}/delete", handler.DeletePlatform)
+}
@@ -0,0 +1,14 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/handler"
+)
+
+func MaterialRouter(handler handler.MaterialHandler, mux *http.ServeMux) {
+	// Library materi milik guru
+	mux.HandleFunc("GET /teacher/materials", handler.MaterialsView)
+	mux.HandleFunc("POST /teacher/materials", handler.UploadMaterial)
+	mux.HandleFunc("DELETE /teacher/materials/{id}", handler.DeleteMaterial)
+}
@@ -0,0 +1,15 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/handler"
+)
+
+func PasswordResetRouter(handler handler.PasswordResetHandler, mux *http.ServeMux) {
+	// Minta link reset lewat email, lalu buat password baru dari link tersebut
+	mux.HandleFunc("GET /forgot-password", handler.ForgotPasswordView)
+	mux.HandleFunc("POST /forgot-password", handler.ForgotPassword)
+	mux.HandleFunc("GET /reset-password", handler.ResetPasswordView)
+	mux.HandleFunc("POST /reset-password", handler.ResetPassword)
+}
@@ -0,0 +1,19 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/handler"
+)
+
+func QuestionBankRouter(handler handler.QuestionBankHandler, mux *http.ServeMux) {
+	// Bank soal milik guru
+	mux.HandleFunc("GET /teacher/bank", handler.QuestionBankView)
+	mux.HandleFunc("POST /teacher/bank", handler.CreateBankQuestion)
+	mux.HandleFunc("POST /teacher/bank/{id}", handler.UpdateBankQuestion)
+	mux.HandleFunc("DELETE /teacher/bank/{id}", handler.DeleteBankQuestion)
+	mux.HandleFunc("POST /teacher/bank/compose", handler.ComposeExam)
+
+	// Simpan soal dari halaman edit ujian ke bank
+	mux.HandleFunc("POST /teacher/edit-exam/{id}/question/{questionId}/bank", handler.SaveQuestionToBank)
+}
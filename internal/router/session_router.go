@@ -0,0 +1,14 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/handler"
+)
+
+func SessionRouter(handler handler.SessionHandler, mux *http.ServeMux) {
+	// Daftar perangkat yang sedang login, untuk semua role
+	mux.HandleFunc("GET /account/sessions", handler.SessionsView)
+	mux.HandleFunc("POST /account/sessions/revoke-others", handler.RevokeOthers)
+	mux.HandleFunc("POST /account/sessions/{id}/revoke", handler.Revoke)
+}
@@ -0,0 +1,29 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/handler"
+	"github.com/mhaatha/go-template-saygenfix/internal/middleware"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+func StudentAPIRouter(handler handler.StudentAPIHandler, mux *http.ServeMux, authMiddleware middleware.AuthMiddleware) {
+	examsRead := authMiddleware.RequireScope(domain.ScopeExamsRead)
+	attemptsWrite := authMiddleware.RequireScope(domain.ScopeAttemptsWrite)
+	resultsRead := authMiddleware.RequireScope(domain.ScopeResultsRead)
+
+	// Ujian aktif yang bisa dikerjakan
+	mux.Handle("GET /api/v1/student/exams", examsRead(http.HandlerFunc(handler.ListExams)))
+
+	// Mengerjakan ujian, jawaban bisa dikirim berkali-kali sampai attempt di-submit
+	mux.Handle("POST /api/v1/student/exams/{examId}/attempts", attemptsWrite(http.HandlerFunc(handler.StartAttempt)))
+	mux.Handle("GET /api/v1/student/attempts/{id}", attemptsWrite(http.HandlerFunc(handler.GetAttempt)))
+	mux.Handle("PUT /api/v1/student/attempts/{id}/answers/{questionId}", attemptsWrite(http.HandlerFunc(handler.SaveAnswer)))
+	mux.Handle("POST /api/v1/student/attempts/{id}/submit", attemptsWrite(http.HandlerFunc(handler.SubmitAttempt)))
+
+	// Hasil ujian
+	mux.Handle("GET /api/v1/student/results", resultsRead(http.HandlerFunc(handler.ListResults)))
+	mux.Handle("GET /api/v1/student/attempts/{id}/result", resultsRead(http.HandlerFunc(handler.GetAttemptResult)))
+
+}
@@ -10,6 +10,15 @@ func StudentRouter(handler handler.StudentHandler, mux *http.ServeMux) {
 	// Dashboard
 	mux.HandleFunc("GET /student/dashboard", handler.DashboardView)
 
+	// Bergabung ke ujian privat dengan join code
+	mux.HandleFunc("POST /student/join-exam", handler.JoinExam)
+
+	// Bergabung ke ujian publik dari daftar ujian publik
+	mux.HandleFunc("POST /student/join-exam/{examId}", handler.JoinPublicExam)
+
+	// Bergabung ke kelas dengan kode kelas
+	mux.HandleFunc("POST /student/join-class", handler.JoinClass)
+
 	// Rute utama untuk memulai ujian (hanya untuk load awal)
 	mux.HandleFunc("GET /student/take-exam/{examId}", handler.TakeExamView)
 
@@ -0,0 +1,32 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/handler"
+	"github.com/mhaatha/go-template-saygenfix/internal/middleware"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+func TeacherAPIRouter(handler handler.TeacherAPIHandler, mux *http.ServeMux, authMiddleware middleware.AuthMiddleware) {
+	examsRead := authMiddleware.RequireScope(domain.ScopeExamsRead)
+	examsWrite := authMiddleware.RequireScope(domain.ScopeExamsWrite)
+	resultsRead := authMiddleware.RequireScope(domain.ScopeResultsRead)
+
+	// Ujian
+	mux.Handle("GET /api/v1/teacher/exams", examsRead(http.HandlerFunc(handler.ListExams)))
+	mux.Handle("POST /api/v1/teacher/exams", examsWrite(http.HandlerFunc(handler.CreateExam)))
+	mux.Handle("GET /api/v1/teacher/exams/{id}", examsRead(http.HandlerFunc(handler.GetExam)))
+	mux.Handle("PUT /api/v1/teacher/exams/{id}", examsWrite(http.HandlerFunc(handler.UpdateExam)))
+	mux.Handle("DELETE /api/v1/teacher/exams/{id}", examsWrite(http.HandlerFunc(handler.DeleteExam)))
+
+	// Soal dalam ujian
+	mux.Handle("GET /api/v1/teacher/exams/{id}/questions", examsRead(http.HandlerFunc(handler.ListQuestions)))
+	mux.Handle("POST /api/v1/teacher/exams/{id}/questions", examsWrite(http.HandlerFunc(handler.CreateQuestion)))
+	mux.Handle("PUT /api/v1/teacher/exams/{id}/questions/{questionId}", examsWrite(http.HandlerFunc(handler.UpdateQuestion)))
+	mux.Handle("DELETE /api/v1/teacher/exams/{id}/questions/{questionId}", examsWrite(http.HandlerFunc(handler.DeleteQuestion)))
+
+	// Nilai terbaik tiap siswa
+	mux.Handle("GET /api/v1/teacher/exams/{id}/results", resultsRead(http.HandlerFunc(handler.ListResults)))
+
+}
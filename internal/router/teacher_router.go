@@ -20,11 +20,28 @@ func TeacherRouter(handler handler.TeacherHandler, mux *http.ServeMux) {
 	// Check Exam
 	mux.HandleFunc("GET /teacher/check-exam/{examId}", handler.CheckExamView)
 
+	// Ujian privat, siswa hanya bisa ikut setelah memasukkan kode gabung
+	mux.HandleFunc("POST /teacher/exam/{id}/privacy", handler.SetExamPrivacy)
+	mux.HandleFunc("POST /teacher/exam/{id}/join-code", handler.RegenerateJoinCode)
+	mux.HandleFunc("DELETE /teacher/exam/{id}/join-code", handler.RevokeJoinCode)
+
+	// Co-teacher, hanya owner yang bisa mengatur kolaborator ujian
+	mux.HandleFunc("POST /teacher/exam/{id}/collaborators", handler.AddCollaborator)
+	mux.HandleFunc("PUT /teacher/exam/{id}/collaborators/{teacherId}", handler.UpdateCollaboratorRole)
+	mux.HandleFunc("DELETE /teacher/exam/{id}/collaborators/{teacherId}", handler.RemoveCollaborator)
+
 	// Edit exam (yang diedit exam sama question-answer)
 	mux.HandleFunc("GET /teacher/edit-exam/{id}", handler.EditExamView)
 	mux.HandleFunc("POST /teacher/edit-exam/{id}", handler.EditExam)
 
+	// Regenerate satu soal, hasilnya harus di-accept dulu sebelum disimpan
+	mux.HandleFunc("POST /teacher/edit-exam/{id}/question/{questionId}/regenerate", handler.RegenerateQuestion)
+	mux.HandleFunc("POST /teacher/edit-exam/{id}/question/{questionId}/accept", handler.AcceptRegeneratedQuestion)
+
 	mux.HandleFunc("GET /teacher/exam-result/{id}", handler.ExamResultView)
 
+	// Nilai ulang semua attempt yang sudah dikumpulkan, misalnya setelah kunci jawaban diperbaiki
+	mux.HandleFunc("POST /teacher/exam/{id}/regrade", handler.RegradeExam)
+
 	// mux.HandleFunc("GET /teacher/generate-result", handler.GenerateResultView)
 }
@@ -0,0 +1,16 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/handler"
+)
+
+func TwoFactorRouter(handler handler.TwoFactorHandler, mux *http.ServeMux) {
+	// Pengaturan verifikasi dua langkah untuk guru dan admin
+	mux.HandleFunc("GET /account/two-factor", handler.TwoFactorView)
+	mux.HandleFunc("POST /account/two-factor/setup", handler.Setup)
+	mux.HandleFunc("POST /account/two-factor/confirm", handler.Confirm)
+	mux.HandleFunc("POST /account/two-factor/recovery-codes", handler.RegenerateRecoveryCodes)
+	mux.HandleFunc("POST /account/two-factor/disable", handler.Disable)
+}
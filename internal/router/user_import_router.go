@@ -0,0 +1,14 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/handler"
+)
+
+// UserImportRouter dipasang di router guru dan admin, prefix menentukan panel yang dipakai
+func UserImportRouter(handler handler.UserImportHandler, mux *http.ServeMux, prefix string) {
+	// Impor akun dari CSV, pratinjau dulu sebelum disimpan
+	mux.HandleFunc("GET "+prefix+"/import", handler.ImportView)
+	mux.HandleFunc("POST "+prefix+"/import", handler.Import)
+}
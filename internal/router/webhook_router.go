@@ -0,0 +1,18 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/handler"
+)
+
+func WebhookRouter(handler handler.WebhookHandler, mux *http.ServeMux) {
+	// Webhook untuk mengirim event ujian ke sistem lain, hanya untuk guru dan admin
+	mux.HandleFunc("GET /account/webhooks", handler.WebhooksView)
+	mux.HandleFunc("POST /account/webhooks", handler.CreateEndpoint)
+	mux.HandleFunc("POST /account/webhooks/{id}/toggle", handler.ToggleEndpoint)
+	mux.HandleFunc("POST /account/webhooks/{id}/secret", handler.RotateSecret)
+	mux.HandleFunc("POST /account/webhooks/{id}/ping", handler.PingEndpoint)
+	mux.HandleFunc("POST /account/webhooks/{id}/delete", handler.DeleteEndpoint)
+	mux.HandleFunc("POST /account/webhooks/deliveries/{id}/redeliver", handler.Redeliver)
+}
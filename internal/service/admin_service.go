@@ -0,0 +1,21 @@
+package service
+
+import (
+	"context"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+)
+
+type AdminService interface {
+	GetDashboard(ctx context.Context) (domain.SystemStats, []domain.ExamStats, []domain.User, error)
+	GetLoginFailures(ctx context.Context) ([]domain.LoginFailureSummary, error)
+	IsTeacherTwoFactorRequired(ctx context.Context) (bool, error)
+	SetTeacherTwoFactorRequired(ctx context.Context, isRequired bool) error
+	SearchUsers(ctx context.Context, query, role string) ([]domain.User, error)
+	SetUserDisabled(ctx context.Context, adminId, userId string, isDisabled bool) error
+	ChangeUserRole(ctx context.Context, adminId, userId string, request web.AdminUserRoleRequest) error
+	ApproveTeacher(ctx context.Context, userId string) error
+	RejectTeacher(ctx context.Context, userId string) error
+	DeleteUser(ctx context.Context, adminId, userId string) error
+}
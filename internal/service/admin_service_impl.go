@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/repository"
+)
+
+// ErrAdminSelfAction is returned when an admin tries to disable, delete or demote their own account,
+// which could leave the system without an admin
+var ErrAdminSelfAction = errors.New("admin cannot change their own account from the console")
+
+func NewAdminService(adminRepository repository.AdminRepository, settingRepository repository.SettingRepository, db *pgxpool.Pool, validate *validator.Validate) AdminService {
+	return &AdminServiceImpl{
+		AdminRepository:   adminRepository,
+		SettingRepository: settingRepository,
+		DB:                db,
+		Validate:          validate,
+	}
+}
+
+type AdminServiceImpl struct {
+	AdminRepository   repository.AdminRepository
+	SettingRepository repository.SettingRepository
+	DB                *pgxpool.Pool
+	Validate          *validator.Validate
+}
+
+// GetDashboard returns the system-wide stats, per exam stats and teachers waiting for approval
+func (service *AdminServiceImpl) GetDashboard(ctx context.Context) (domain.SystemStats, []domain.ExamStats, []domain.User, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.SystemStats{}, nil, nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	stats, err := service.AdminRepository.FindSystemStats(ctx, tx)
+	if err != nil {
+		return domain.SystemStats{}, nil, nil, fmt.Errorf("failed when calling FindSystemStats repository: %w", err)
+	}
+
+	exams, err := service.AdminRepository.FindExamStats(ctx, tx)
+	if err != nil {
+		return domain.SystemStats{}, nil, nil, fmt.Errorf("failed when calling FindExamStats repository: %w", err)
+	}
+
+	pendingTeachers, err := service.AdminRepository.FindPendingTeachers(ctx, tx)
+	if err != nil {
+		return domain.SystemStats{}, nil, nil, fmt.Errorf("failed when calling FindPendingTeachers repository: %w", err)
+	}
+
+	return stats, exams, pendingTeachers, nil
+}
+
+// GetLoginFailures lists failed logins of the last 24 hours for spotting brute-force attempts
+func (service *AdminServiceImpl) GetLoginFailures(ctx context.Context) ([]domain.LoginFailureSummary, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	failures, err := service.AdminRepository.FindLoginFailures(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindLoginFailures repository: %w", err)
+	}
+
+	return failures, nil
+}
+
+func (service *AdminServiceImpl) IsTeacherTwoFactorRequired(ctx context.Context) (bool, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	isRequired, err := service.SettingRepository.FindBool(ctx, tx, settingRequireTeacherTwoFactor)
+	if err != nil {
+		return false, fmt.Errorf("failed when calling FindBool repository: %w", err)
+	}
+
+	return isRequired, nil
+}
+
+// SetTeacherTwoFactorRequired makes teachers without 2FA set it up before they can use the panel
+func (service *AdminServiceImpl) SetTeacherTwoFactorRequired(ctx context.Context, isRequired bool) error {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if err := service.SettingRepository.SaveBool(ctx, tx, settingRequireTeacherTwoFactor, isRequired); err != nil {
+		return fmt.Errorf("failed when calling SaveBool repository: %w", err)
+	}
+
+	return nil
+}
+
+func (service *AdminServiceImpl) SearchUsers(ctx context.Context, query, role string) ([]domain.User, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	users, err := service.AdminRepository.FindUsers(ctx, tx, query, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindUsers repository: %w", err)
+	}
+
+	return users, nil
+}
+
+// SetUserDisabled disables or enables the account. A disabled user is logged out everywhere.
+func (service *AdminServiceImpl) SetUserDisabled(ctx context.Context, adminId, userId string, isDisabled bool) error {
+	if adminId == userId {
+		return ErrAdminSelfAction
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if _, err := service.AdminRepository.FindUserById(ctx, tx, userId); err != nil {
+		return fmt.Errorf("failed when calling FindUserById repository: %w", err)
+	}
+
+	err = service.AdminRepository.UpdateDisabled(ctx, tx, userId, isDisabled)
+	if err != nil {
+		return fmt.Errorf("failed when calling UpdateDisabled repository: %w", err)
+	}
+
+	if isDisabled {
+		err = service.AdminRepository.DeleteSessionsByUserId(ctx, tx, userId)
+		if err != nil {
+			return fmt.Errorf("failed when calling DeleteSessionsByUserId repository: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (service *AdminServiceImpl) ChangeUserRole(ctx context.Context, adminId, userId string, request web.AdminUserRoleRequest) error {
+	// Validate request
+	if err := service.Validate.Struct(request); err != nil {
+		return fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	if adminId == userId {
+		return ErrAdminSelfAction
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if _, err := service.AdminRepository.FindUserById(ctx, tx, userId); err != nil {
+		return fmt.Errorf("failed when calling FindUserById repository: %w", err)
+	}
+
+	err = service.AdminRepository.UpdateRole(ctx, tx, userId, request.Role)
+	if err != nil {
+		return fmt.Errorf("failed when calling UpdateRole repository: %w", err)
+	}
+
+	return nil
+}
+
+func (service *AdminServiceImpl) ApproveTeacher(ctx context.Context, userId string) error {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if _, err := service.findPendingTeacher(ctx, tx, userId); err != nil {
+		return err
+	}
+
+	err = service.AdminRepository.UpdateApproved(ctx, tx, userId, true)
+	if err != nil {
+		return fmt.Errorf("failed when calling UpdateApproved repository: %w", err)
+	}
+
+	return nil
+}
+
+// RejectTeacher removes a teacher registration that is still waiting for approval
+func (service *AdminServiceImpl) RejectTeacher(ctx context.Context, userId string) error {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if _, err := service.findPendingTeacher(ctx, tx, userId); err != nil {
+		return err
+	}
+
+	err = service.AdminRepository.Delete(ctx, tx, userId)
+	if err != nil {
+		return fmt.Errorf("failed when calling Delete repository: %w", err)
+	}
+
+	return nil
+}
+
+func (service *AdminServiceImpl) findPendingTeacher(ctx context.Context, tx pgx.Tx, userId string) (domain.User, error) {
+	user, err := service.AdminRepository.FindUserById(ctx, tx, userId)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed when calling FindUserById repository: %w", err)
+	}
+	if user.Role != "teacher" || user.IsApproved {
+		return domain.User{}, errors.New("user is not a teacher waiting for approval")
+	}
+
+	return user, nil
+}
+
+// DeleteUser removes the account together with everything it owns, such as exams and attempts
+func (service *AdminServiceImpl) DeleteUser(ctx context.Context, adminId, userId string) error {
+	if adminId == userId {
+		return ErrAdminSelfAction
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	err = service.AdminRepository.Delete(ctx, tx, userId)
+	if err != nil {
+		return fmt.Errorf("failed when calling Delete repository: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package service
+
+import (
+	"context"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+)
+
+type APITokenService interface {
+	// Token management on the account page
+	ScopesForRole(role string) []web.APITokenScopeOption
+	ListTokens(ctx context.Context, userId string) ([]web.APITokenResponse, error)
+	ListUsages(ctx context.Context, userId string) ([]domain.APITokenUsage, error)
+	CreateToken(ctx context.Context, user domain.User, request web.APITokenCreateRequest) (string, error)
+	RevokeToken(ctx context.Context, userId, tokenId string) error
+
+	// Authenticate a bearer token on API routes
+	Authenticate(ctx context.Context, request web.APITokenAuthRequest) (domain.User, domain.APIToken, error)
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/repository"
+)
+
+const (
+	// apiTokenPrefix marks our tokens so they are easy to find by secret scanners
+	apiTokenPrefix = "sgf_"
+	// apiTokenDisplayLength is how much of the token is kept in plain text for the token list
+	apiTokenDisplayLength = 12
+	// maxActiveAPITokens limits the tokens a user can have at the same time
+	maxActiveAPITokens = 20
+	// apiTokenUsageLimit is how many audit entries the account page shows
+	apiTokenUsageLimit = 50
+)
+
+var (
+	// ErrAPITokenInvalid is returned for unknown, revoked and expired tokens and for disabled accounts
+	ErrAPITokenInvalid = errors.New("api token is invalid")
+	// ErrAPITokenNotFound is returned when revoking a token the user does not own
+	ErrAPITokenNotFound = errors.New("api token not found")
+	// ErrAPITokenScopeNotAllowed is returned when a scope is not available for the role of the user
+	ErrAPITokenScopeNotAllowed = errors.New("api token scope is not allowed for this role")
+	// ErrAPITokenLimit is returned when the user already has maxActiveAPITokens active tokens
+	ErrAPITokenLimit = errors.New("too many active api tokens")
+)
+
+// apiTokenScopes lists every scope with its label, in the order shown on the form
+var apiTokenScopes = []web.APITokenScopeOption{
+	{Value: domain.ScopeExamsRead, Label: "Membaca ujian dan soal"},
+	{Value: domain.ScopeExamsWrite, Label: "Membuat dan mengubah ujian dan soal"},
+	{Value: domain.ScopeAttemptsWrite, Label: "Mengerjakan ujian"},
+	{Value: domain.ScopeResultsRead, Label: "Membaca hasil dan nilai ujian"},
+}
+
+// apiTokenRoleScopes are the scopes each role may grant, a token never gets more than its owner
+var apiTokenRoleScopes = map[string][]string{
+	"student": {domain.ScopeExamsRead, domain.ScopeAttemptsWrite, domain.ScopeResultsRead},
+	"teacher": {domain.ScopeExamsRead, domain.ScopeExamsWrite, domain.ScopeResultsRead},
+	"admin":   {domain.ScopeExamsRead, domain.ScopeResultsRead},
+}
+
+func NewAPITokenService(apiTokenRepository repository.APITokenRepository, userRepository repository.UserRepository, db *pgxpool.Pool, validate *validator.Validate) APITokenService {
+	return &APITokenServiceImpl{
+		APITokenRepository: apiTokenRepository,
+		UserRepository:     userRepository,
+		DB:                 db,
+		Validate:           validate,
+	}
+}
+
+type APITokenServiceImpl struct {
+	APITokenRepository repository.APITokenRepository
+	UserRepository     repository.UserRepository
+	DB                 *pgxpool.Pool
+	Validate           *validator.Validate
+}
+
+func (service *APITokenServiceImpl) ScopesForRole(role string) []web.APITokenScopeOption {
+	options := []web.APITokenScopeOption{}
+	for _, option := range apiTokenScopes {
+		if slices.Contains(apiTokenRoleScopes[role], option.Value) {
+			options = append(options, option)
+		}
+	}
+
+	return options
+}
+
+func (service *APITokenServiceImpl) ListTokens(ctx context.Context, userId string) ([]web.APITokenResponse, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	tokens, err := service.APITokenRepository.FindByUserId(ctx, tx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindByUserId repository: %w", err)
+	}
+
+	now := time.Now()
+	tokenResponses := make([]web.APITokenResponse, 0, len(tokens))
+	for _, token := range tokens {
+		tokenResponses = append(tokenResponses, web.APITokenResponse{
+			Id:          token.Id,
+			Name:        token.Name,
+			TokenPrefix: token.TokenPrefix,
+			Scopes:      token.Scopes,
+			ExpiresAt:   token.ExpiresAt,
+			LastUsedAt:  token.LastUsedAt,
+			CreatedAt:   token.CreatedAt,
+			IsRevoked:   token.RevokedAt != nil,
+			IsExpired:   !token.ExpiresAt.After(now),
+		})
+	}
+
+	return tokenResponses, nil
+}
+
+func (service *APITokenServiceImpl) ListUsages(ctx context.Context, userId string) ([]domain.APITokenUsage, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	usages, err := service.APITokenRepository.FindUsagesByUserId(ctx, tx, userId, apiTokenUsageLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindUsagesByUserId repository: %w", err)
+	}
+
+	return usages, nil
+}
+
+// CreateToken returns the token in plain text, only its hash is stored so it can not be shown again
+func (service *APITokenServiceImpl) CreateToken(ctx context.Context, user domain.User, request web.APITokenCreateRequest) (string, error) {
+	request.Name = strings.TrimSpace(request.Name)
+
+	// Validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	for _, scope := range request.Scopes {
+		if !slices.Contains(apiTokenRoleScopes[user.Role], scope) {
+			return "", ErrAPITokenScopeNotAllowed
+		}
+	}
+	slices.Sort(request.Scopes)
+	request.Scopes = slices.Compact(request.Scopes)
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	activeTokens, err := service.APITokenRepository.CountActiveByUserId(ctx, tx, user.Id)
+	if err != nil {
+		return "", fmt.Errorf("failed when calling CountActiveByUserId repository: %w", err)
+	}
+	if activeTokens >= maxActiveAPITokens {
+		return "", ErrAPITokenLimit
+	}
+
+	secret, err := helper.GenerateToken()
+	if err != nil {
+		return "", fmt.Errorf("failed when calling GenerateToken helper: %w", err)
+	}
+	token := apiTokenPrefix + secret
+
+	_, err = service.APITokenRepository.Save(ctx, tx, domain.APIToken{
+		UserId:      user.Id,
+		Name:        request.Name,
+		TokenPrefix: token[:apiTokenDisplayLength],
+		TokenHash:   helper.HashToken(token),
+		Scopes:      request.Scopes,
+	}, time.Duration(request.ExpiresInDays)*24*time.Hour)
+	if err != nil {
+		return "", fmt.Errorf("failed when calling Save repository: %w", err)
+	}
+
+	return token, nil
+}
+
+// RevokeToken keeps the token row so its audit trail stays visible
+func (service *APITokenServiceImpl) RevokeToken(ctx context.Context, userId, tokenId string) error {
+	if uuid.Validate(tokenId) != nil {
+		return ErrAPITokenNotFound
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	revoked, err := service.APITokenRepository.Revoke(ctx, tx, userId, tokenId)
+	if err != nil {
+		return fmt.Errorf("failed when calling Revoke repository: %w", err)
+	}
+	if !revoked {
+		return ErrAPITokenNotFound
+	}
+
+	return nil
+}
+
+// Authenticate resolves the owner of a bearer token and records the request in the audit trail
+func (service *APITokenServiceImpl) Authenticate(ctx context.Context, request web.APITokenAuthRequest) (domain.User, domain.APIToken, error) {
+	if !strings.HasPrefix(request.Token, apiTokenPrefix) {
+		return domain.User{}, domain.APIToken{}, ErrAPITokenInvalid
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.User{}, domain.APIToken{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	token, err := service.APITokenRepository.FindValidByTokenHash(ctx, tx, helper.HashToken(request.Token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.User{}, domain.APIToken{}, ErrAPITokenInvalid
+		}
+
+		return domain.User{}, domain.APIToken{}, fmt.Errorf("failed when calling FindValidByTokenHash repository: %w", err)
+	}
+
+	user, err := service.UserRepository.FindById(ctx, tx, token.UserId)
+	if err != nil {
+		return domain.User{}, domain.APIToken{}, fmt.Errorf("failed when calling FindById repository: %w", err)
+	}
+	if user.IsDisabled || !user.IsApproved {
+		return domain.User{}, domain.APIToken{}, ErrAPITokenInvalid
+	}
+
+	path := request.Path
+	if len(path) > 255 {
+		path = path[:255]
+	}
+	if err := service.APITokenRepository.SaveUsage(ctx, tx, domain.APITokenUsage{
+		TokenId:   token.Id,
+		Method:    request.Method,
+		Path:      strings.ToValidUTF8(path, ""),
+		IPAddress: request.IPAddress,
+		UserAgent: truncateUserAgent(request.UserAgent),
+	}); err != nil {
+		return domain.User{}, domain.APIToken{}, fmt.Errorf("failed when calling SaveUsage repository: %w", err)
+	}
+
+	slog.Info("api token used", "token_id", token.Id, "user_id", user.Id, "method", request.Method, "path", path, "ip", request.IPAddress)
+
+	return user, token, nil
+}
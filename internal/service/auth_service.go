@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
 	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
@@ -9,10 +10,19 @@ import (
 
 type AuthService interface {
 	// Login
-	Login(ctx context.Context, request web.LoginRequest, email, userHashedPassword, userId string) (string, error)
+	Login(ctx context.Context, request web.LoginRequest, user domain.User) (string, error)
+
+	// Login throttling
+	CheckLoginThrottle(ctx context.Context, email, ipAddress string) (time.Duration, error)
+	RecordLoginFailure(ctx context.Context, email, ipAddress string) (time.Duration, error)
 
 	// ValidateSession
-	ValidateSession(ctx context.Context, sessionId string) (domain.User, error)
+	ValidateSession(ctx context.Context, sessionId, userAgent, ipAddress string) (domain.User, error)
+
+	// Active sessions
+	FindSessions(ctx context.Context, userId, currentSessionId string) ([]web.SessionResponse, error)
+	RevokeSession(ctx context.Context, userId, currentSessionId, id string) error
+	RevokeOtherSessions(ctx context.Context, userId, currentSessionId string) (int64, error)
 
 	// Logout
 }
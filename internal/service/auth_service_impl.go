@@ -2,21 +2,54 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mhaatha/go-template-saygenfix/internal/config"
 	"github.com/mhaatha/go-template-saygenfix/internal/helper"
 	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
 	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
 	"github.com/mhaatha/go-template-saygenfix/internal/repository"
 )
 
-func NewAuthService(authRepository repository.AuthRepository, db *pgxpool.Pool, validate *validator.Validate) AuthService {
+var (
+	// ErrAccountDisabled is returned when an admin has disabled the account
+	ErrAccountDisabled = errors.New("account is disabled")
+	// ErrAccountPending is returned when a teacher registration has not been approved yet
+	ErrAccountPending = errors.New("account is waiting for approval")
+	// ErrInvalidCredentials is returned when the password does not match
+	ErrInvalidCredentials = errors.New("incorrect email or password")
+	// ErrLoginLocked is returned when an email or IP has too many failed logins
+	ErrLoginLocked = errors.New("too many failed login attempts")
+	// ErrTemporaryPasswordExpired is returned when an imported account logs in with its temporary
+	// password after it expired, the user sets a new one through the forgot password page
+	ErrTemporaryPasswordExpired = errors.New("temporary password has expired")
+	// ErrTwoFactorRequired is returned when the password matched but a TOTP code is still needed
+	ErrTwoFactorRequired = errors.New("two-factor code is required")
+	// ErrSessionNotFound is returned when revoking a session that is not owned by the user or is the current one
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// maxLoginFailureDelay caps the progressive delay after a failed login
+const maxLoginFailureDelay = 8 * time.Second
+
+// sessionTouchInterval is how often last_seen_at of a session is written
+const sessionTouchInterval = time.Minute
+
+// maxUserAgentLength matches sessions.user_agent
+const maxUserAgentLength = 512
+
+func NewAuthService(authRepository repository.AuthRepository, db *pgxpool.Pool, validate *validator.Validate, cfg *config.Config) AuthService {
 	return &AuthServiceImpl{
 		AuthRepository: authRepository,
 		DB:             db,
 		Validate:       validate,
+		Config:         cfg,
 	}
 }
 
@@ -24,9 +57,11 @@ type AuthServiceImpl struct {
 	AuthRepository repository.AuthRepository
 	DB             *pgxpool.Pool
 	Validate       *validator.Validate
+	Config         *config.Config
 }
 
-func (service *AuthServiceImpl) ValidateSession(ctx context.Context, sessionId string) (domain.User, error) {
+// ValidateSession returns the user of the session and records the device that used it
+func (service *AuthServiceImpl) ValidateSession(ctx context.Context, sessionId, userAgent, ipAddress string) (domain.User, error) {
 	tx, err := service.DB.Begin(ctx)
 	if err != nil {
 		return domain.User{}, fmt.Errorf("failed to open db transaction: %w", err)
@@ -38,10 +73,92 @@ func (service *AuthServiceImpl) ValidateSession(ctx context.Context, sessionId s
 		return domain.User{}, fmt.Errorf("failed when calling FindUserBySessionId repository: %w", err)
 	}
 
+	err = service.AuthRepository.TouchSession(ctx, tx, domain.Session{
+		SessionId: sessionId,
+		UserAgent: truncateUserAgent(userAgent),
+		IPAddress: ipAddress,
+	}, sessionTouchInterval)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed when calling TouchSession repository: %w", err)
+	}
+
 	return user, nil
 }
 
-func (service *AuthServiceImpl) Login(ctx context.Context, request web.LoginRequest, email, userHashedPassword, userId string) (string, error) {
+// FindSessions lists the sessions of the user, most recently used first
+func (service *AuthServiceImpl) FindSessions(ctx context.Context, userId, currentSessionId string) ([]web.SessionResponse, error) {
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	sessions, err := service.AuthRepository.FindSessionsByUserId(ctx, tx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindSessionsByUserId repository: %w", err)
+	}
+
+	sessionResponses := make([]web.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		sessionResponses = append(sessionResponses, web.SessionResponse{
+			Id:         session.Id,
+			Device:     helper.DescribeUserAgent(session.UserAgent),
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			LastSeenAt: session.LastSeenAt,
+			CreatedAt:  session.CreatedAt,
+			IsCurrent:  session.SessionId == currentSessionId,
+		})
+	}
+
+	return sessionResponses, nil
+}
+
+// RevokeSession logs out one of the other devices of the user
+func (service *AuthServiceImpl) RevokeSession(ctx context.Context, userId, currentSessionId, id string) error {
+	if uuid.Validate(id) != nil {
+		return ErrSessionNotFound
+	}
+
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	deleted, err := service.AuthRepository.DeleteOtherSession(ctx, tx, userId, id, currentSessionId)
+	if err != nil {
+		return fmt.Errorf("failed when calling DeleteOtherSession repository: %w", err)
+	}
+	if !deleted {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions logs out every device of the user except the current one
+func (service *AuthServiceImpl) RevokeOtherSessions(ctx context.Context, userId, currentSessionId string) (int64, error) {
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	revoked, err := service.AuthRepository.DeleteOtherSessions(ctx, tx, userId, currentSessionId)
+	if err != nil {
+		return 0, fmt.Errorf("failed when calling DeleteOtherSessions repository: %w", err)
+	}
+
+	return revoked, nil
+}
+
+// Login checks the password and creates a session. Disabled accounts, teachers waiting for
+// approval, unverified emails under the "login" policy and expired temporary passwords get
+// ErrAccountDisabled, ErrAccountPending, ErrEmailNotVerified or ErrTemporaryPasswordExpired,
+// only after the password matched. Users with 2FA get ErrTwoFactorRequired and no session, it
+// is created once the code is verified.
+func (service *AuthServiceImpl) Login(ctx context.Context, request web.LoginRequest, user domain.User) (string, error) {
 	// Validate request
 	err := service.Validate.Struct(request)
 	if err != nil {
@@ -56,18 +173,133 @@ func (service *AuthServiceImpl) Login(ctx context.Context, request web.LoginRequ
 	defer helper.CommitOrRollback(ctx, tx)
 
 	// Check if request password matched the hashed password
-	if !helper.CheckPasswordHash(userHashedPassword, request.Password) {
-		return "", fmt.Errorf("failed when calling CheckPasswordHash: %w", err)
+	if !helper.CheckPasswordHash(user.Password, request.Password) {
+		return "", ErrInvalidCredentials
+	}
+
+	if user.IsDisabled {
+		return "", ErrAccountDisabled
+	}
+	if !user.IsApproved {
+		return "", ErrAccountPending
+	}
+	if service.Config.EmailVerificationPolicy == EmailVerificationPolicyLogin && !user.IsEmailVerified && user.Role != "admin" {
+		return "", ErrEmailNotVerified
+	}
+	if user.TemporaryPasswordExpired {
+		return "", ErrTemporaryPasswordExpired
+	}
+	if user.TwoFactorEnabled {
+		return "", ErrTwoFactorRequired
 	}
 
 	// Save session to db
 	session, err := service.AuthRepository.Save(ctx, tx, domain.Session{
 		SessionId: helper.Base64SessionId(),
-		UserId:    userId,
+		UserId:    user.Id,
+		UserAgent: truncateUserAgent(request.UserAgent),
+		IPAddress: request.IPAddress,
 	})
 	if err != nil {
 		return "", fmt.Errorf("failed when calling Save repository: %w", err)
 	}
 
+	// Login berhasil mereset hitungan gagal untuk email ini
+	if err := service.AuthRepository.SaveLoginAttempt(ctx, tx, domain.LoginAttempt{
+		Email:     normalizeLoginEmail(request.Email),
+		IPAddress: request.IPAddress,
+		IsSuccess: true,
+	}); err != nil {
+		return "", fmt.Errorf("failed when calling SaveLoginAttempt repository: %w", err)
+	}
+
 	return session.SessionId, nil
 }
+
+// CheckLoginThrottle returns ErrLoginLocked and the remaining lockout time when the email or
+// the IP reached the failure limit within the lockout window
+func (service *AuthServiceImpl) CheckLoginThrottle(ctx context.Context, email, ipAddress string) (time.Duration, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	ipThrottle, err := service.AuthRepository.FindLoginThrottleByIP(ctx, tx, ipAddress, service.Config.LoginLockoutDuration)
+	if err != nil {
+		return 0, fmt.Errorf("failed when calling FindLoginThrottleByIP repository: %w", err)
+	}
+	if ipThrottle.Failures >= service.Config.LoginIPMaxFailures {
+		return ipThrottle.RetryAfter, ErrLoginLocked
+	}
+
+	emailThrottle, err := service.AuthRepository.FindLoginThrottleByEmail(ctx, tx, normalizeLoginEmail(email), service.Config.LoginLockoutDuration)
+	if err != nil {
+		return 0, fmt.Errorf("failed when calling FindLoginThrottleByEmail repository: %w", err)
+	}
+	if emailThrottle.Failures >= service.Config.LoginMaxFailures {
+		return emailThrottle.RetryAfter, ErrLoginLocked
+	}
+
+	return 0, nil
+}
+
+// RecordLoginFailure saves a failed login, unknown emails included, and returns how long the
+// response should be delayed. The delay doubles with every consecutive failure.
+func (service *AuthServiceImpl) RecordLoginFailure(ctx context.Context, email, ipAddress string) (time.Duration, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	email = normalizeLoginEmail(email)
+	if err := service.AuthRepository.SaveLoginAttempt(ctx, tx, domain.LoginAttempt{
+		Email:     email,
+		IPAddress: ipAddress,
+		IsSuccess: false,
+	}); err != nil {
+		return 0, fmt.Errorf("failed when calling SaveLoginAttempt repository: %w", err)
+	}
+
+	emailThrottle, err := service.AuthRepository.FindLoginThrottleByEmail(ctx, tx, email, service.Config.LoginLockoutDuration)
+	if err != nil {
+		return 0, fmt.Errorf("failed when calling FindLoginThrottleByEmail repository: %w", err)
+	}
+
+	return loginFailureDelay(emailThrottle.Failures), nil
+}
+
+// loginFailureDelay is 0 for the first failure, then 500ms, 1s, 2s and so on up to maxLoginFailureDelay
+func loginFailureDelay(failures int) time.Duration {
+	if failures < 2 {
+		return 0
+	}
+
+	delay := 500 * time.Millisecond
+	for i := 2; i < failures && delay < maxLoginFailureDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxLoginFailureDelay)
+}
+
+func normalizeLoginEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if len(email) > 255 {
+		email = email[:255]
+	}
+
+	return email
+}
+
+// truncateUserAgent keeps the user agent within the sessions.user_agent column
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) > maxUserAgentLength {
+		return strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+	}
+
+	return userAgent
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"context"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+)
+
+type ClassService interface {
+	GetClassesByTeacherId(ctx context.Context, teacherId string) ([]domain.Class, error)
+	CreateClass(ctx context.Context, teacherId string, request web.ClassCreateRequest) (domain.Class, error)
+	GetClassDetail(ctx context.Context, teacherId, classId string) (web.TeacherClassDetailResponse, error)
+	DeleteClass(ctx context.Context, teacherId, classId string) error
+	RegenerateJoinCode(ctx context.Context, teacherId, classId string) (domain.Class, error)
+
+	InviteStudent(ctx context.Context, teacherId, classId string, request web.ClassInviteRequest) (bool, error)
+	RemoveMember(ctx context.Context, teacherId, classId, studentId string) error
+	CancelInvitation(ctx context.Context, teacherId, classId, email string) error
+
+	GetExamClasses(ctx context.Context, teacherId, examId string) (web.ExamClassesResponse, error)
+	AssignExamToClasses(ctx context.Context, teacherId, examId string, classIds []string) (web.ExamClassesResponse, error)
+}
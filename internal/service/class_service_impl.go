@@ -0,0 +1,427 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/repository"
+)
+
+// ErrInviteeNotStudent is returned when the invited email belongs to a teacher account
+var ErrInviteeNotStudent = errors.New("invited email does not belong to a student")
+
+func NewClassService(classRepository repository.ClassRepository, teacherRepository repository.TeacherRepository, userRepository repository.UserRepository, db *pgxpool.Pool, validate *validator.Validate) ClassService {
+	return &ClassServiceImpl{
+		ClassRepository:   classRepository,
+		TeacherRepository: teacherRepository,
+		UserRepository:    userRepository,
+		DB:                db,
+		Validate:          validate,
+	}
+}
+
+type ClassServiceImpl struct {
+	ClassRepository   repository.ClassRepository
+	TeacherRepository repository.TeacherRepository
+	UserRepository    repository.UserRepository
+	DB                *pgxpool.Pool
+	Validate          *validator.Validate
+}
+
+func (service *ClassServiceImpl) GetClassesByTeacherId(ctx context.Context, teacherId string) ([]domain.Class, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	classes, err := service.ClassRepository.FindByTeacherId(ctx, tx, teacherId)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindByTeacherId repository: %w", err)
+	}
+
+	return classes, nil
+}
+
+// CreateClass creates a class with a join code so students can enroll themselves
+func (service *ClassServiceImpl) CreateClass(ctx context.Context, teacherId string, request web.ClassCreateRequest) (domain.Class, error) {
+	// Validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return domain.Class{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.Class{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	class, err := service.ClassRepository.Save(ctx, tx, domain.Class{
+		TeacherId: teacherId,
+		Name:      request.Name,
+	})
+	if err != nil {
+		return domain.Class{}, fmt.Errorf("failed when calling Save repository: %w", err)
+	}
+
+	class.JoinCode, err = service.saveNewJoinCode(ctx, tx, class.Id)
+	if err != nil {
+		return domain.Class{}, err
+	}
+
+	return class, nil
+}
+
+func (service *ClassServiceImpl) GetClassDetail(ctx context.Context, teacherId, classId string) (web.TeacherClassDetailResponse, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return web.TeacherClassDetailResponse{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	class, err := service.findOwnedClass(ctx, tx, teacherId, classId)
+	if err != nil {
+		return web.TeacherClassDetailResponse{}, err
+	}
+
+	members, err := service.ClassRepository.FindMembers(ctx, tx, classId)
+	if err != nil {
+		return web.TeacherClassDetailResponse{}, fmt.Errorf("failed when calling FindMembers repository: %w", err)
+	}
+
+	invitations, err := service.ClassRepository.FindInvitations(ctx, tx, classId)
+	if err != nil {
+		return web.TeacherClassDetailResponse{}, fmt.Errorf("failed when calling FindInvitations repository: %w", err)
+	}
+
+	exams, err := service.ClassRepository.FindExamsByClassId(ctx, tx, classId)
+	if err != nil {
+		return web.TeacherClassDetailResponse{}, fmt.Errorf("failed when calling FindExamsByClassId repository: %w", err)
+	}
+
+	return web.TeacherClassDetailResponse{
+		Class:       class,
+		Members:     members,
+		Invitations: invitations,
+		Exams:       exams,
+	}, nil
+}
+
+// DeleteClass removes the class, its roster and its exam assignments. Exams that were
+// only assigned to this class become private, the teacher decides whether to publish them.
+func (service *ClassServiceImpl) DeleteClass(ctx context.Context, teacherId, classId string) error {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if _, err := service.findOwnedClass(ctx, tx, teacherId, classId); err != nil {
+		return err
+	}
+
+	exams, err := service.ClassRepository.FindExamsByClassId(ctx, tx, classId)
+	if err != nil {
+		return fmt.Errorf("failed when calling FindExamsByClassId repository: %w", err)
+	}
+
+	err = service.ClassRepository.Delete(ctx, tx, classId)
+	if err != nil {
+		return fmt.Errorf("failed when calling Delete repository: %w", err)
+	}
+
+	examIds := []string{}
+	for _, exam := range exams {
+		examIds = append(examIds, exam.Id)
+	}
+
+	err = service.ClassRepository.MakePrivateWhenUnassigned(ctx, tx, examIds)
+	if err != nil {
+		return fmt.Errorf("failed when calling MakePrivateWhenUnassigned repository: %w", err)
+	}
+
+	return nil
+}
+
+// RegenerateJoinCode replaces the class join code, the old code stops working immediately
+func (service *ClassServiceImpl) RegenerateJoinCode(ctx context.Context, teacherId, classId string) (domain.Class, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.Class{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	class, err := service.findOwnedClass(ctx, tx, teacherId, classId)
+	if err != nil {
+		return domain.Class{}, err
+	}
+
+	class.JoinCode, err = service.saveNewJoinCode(ctx, tx, classId)
+	if err != nil {
+		return domain.Class{}, err
+	}
+
+	return class, nil
+}
+
+// InviteStudent adds a registered student to the class right away, other emails are kept as
+// invitations until a student with that email opens the dashboard. It reports whether the
+// student was added directly.
+func (service *ClassServiceImpl) InviteStudent(ctx context.Context, teacherId, classId string, request web.ClassInviteRequest) (bool, error) {
+	request.Email = strings.ToLower(strings.TrimSpace(request.Email))
+
+	// Validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return false, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if _, err := service.findOwnedClass(ctx, tx, teacherId, classId); err != nil {
+		return false, err
+	}
+
+	user, err := service.UserRepository.FindByEmail(ctx, tx, request.Email)
+	if err != nil {
+		return false, fmt.Errorf("failed when calling FindByEmail repository: %w", err)
+	}
+
+	if user.Id == "" {
+		err = service.ClassRepository.SaveInvitation(ctx, tx, classId, request.Email)
+		if err != nil {
+			return false, fmt.Errorf("failed when calling SaveInvitation repository: %w", err)
+		}
+
+		return false, nil
+	}
+
+	if user.Role != "student" {
+		return false, ErrInviteeNotStudent
+	}
+
+	err = service.ClassRepository.SaveMember(ctx, tx, classId, user.Id)
+	if err != nil {
+		return false, fmt.Errorf("failed when calling SaveMember repository: %w", err)
+	}
+
+	return true, nil
+}
+
+func (service *ClassServiceImpl) RemoveMember(ctx context.Context, teacherId, classId, studentId string) error {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if _, err := service.findOwnedClass(ctx, tx, teacherId, classId); err != nil {
+		return err
+	}
+
+	err = service.ClassRepository.DeleteMember(ctx, tx, classId, studentId)
+	if err != nil {
+		return fmt.Errorf("failed when calling DeleteMember repository: %w", err)
+	}
+
+	return nil
+}
+
+func (service *ClassServiceImpl) CancelInvitation(ctx context.Context, teacherId, classId, email string) error {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if _, err := service.findOwnedClass(ctx, tx, teacherId, classId); err != nil {
+		return err
+	}
+
+	err = service.ClassRepository.DeleteInvitation(ctx, tx, classId, email)
+	if err != nil {
+		return fmt.Errorf("failed when calling DeleteInvitation repository: %w", err)
+	}
+
+	return nil
+}
+
+func (service *ClassServiceImpl) GetExamClasses(ctx context.Context, teacherId, examId string) (web.ExamClassesResponse, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return web.ExamClassesResponse{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	role, err := service.findExamRole(ctx, tx, teacherId, examId, domain.ExamRoleViewer)
+	if err != nil {
+		return web.ExamClassesResponse{}, err
+	}
+
+	examClasses, err := service.findExamClasses(ctx, tx, teacherId, examId)
+	if err != nil {
+		return web.ExamClassesResponse{}, err
+	}
+	examClasses.CanEdit = hasExamRole(role, domain.ExamRoleEditor)
+
+	return examClasses, nil
+}
+
+// AssignExamToClasses replaces the teacher's own classes the exam is assigned to, classes
+// assigned by co-teachers are kept. Removing the last class makes the exam private.
+func (service *ClassServiceImpl) AssignExamToClasses(ctx context.Context, teacherId, examId string, classIds []string) (web.ExamClassesResponse, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return web.ExamClassesResponse{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if _, err := service.findExamRole(ctx, tx, teacherId, examId, domain.ExamRoleEditor); err != nil {
+		return web.ExamClassesResponse{}, err
+	}
+
+	classes, err := service.ClassRepository.FindByTeacherId(ctx, tx, teacherId)
+	if err != nil {
+		return web.ExamClassesResponse{}, fmt.Errorf("failed when calling FindByTeacherId repository: %w", err)
+	}
+
+	ownedClassIds := map[string]bool{}
+	for _, class := range classes {
+		ownedClassIds[class.Id] = true
+	}
+	for _, classId := range classIds {
+		if !ownedClassIds[classId] {
+			return web.ExamClassesResponse{}, errors.New("class does not belong to this teacher")
+		}
+	}
+
+	previousClassIds, err := service.ClassRepository.FindClassIdsByExamId(ctx, tx, examId)
+	if err != nil {
+		return web.ExamClassesResponse{}, fmt.Errorf("failed when calling FindClassIdsByExamId repository: %w", err)
+	}
+
+	err = service.ClassRepository.UpdateExamClasses(ctx, tx, examId, teacherId, classIds)
+	if err != nil {
+		return web.ExamClassesResponse{}, fmt.Errorf("failed when calling UpdateExamClasses repository: %w", err)
+	}
+
+	// Ujian yang tadinya dibatasi kelas tidak boleh tiba-tiba terbuka untuk semua siswa
+	if len(previousClassIds) > 0 {
+		err = service.ClassRepository.MakePrivateWhenUnassigned(ctx, tx, []string{examId})
+		if err != nil {
+			return web.ExamClassesResponse{}, fmt.Errorf("failed when calling MakePrivateWhenUnassigned repository: %w", err)
+		}
+	}
+
+	examClasses, err := service.findExamClasses(ctx, tx, teacherId, examId)
+	if err != nil {
+		return web.ExamClassesResponse{}, err
+	}
+	examClasses.CanEdit = true
+
+	return examClasses, nil
+}
+
+// findExamRole returns the teacher's role on the exam, or ErrExamForbidden when it is below requiredRole
+func (service *ClassServiceImpl) findExamRole(ctx context.Context, tx pgx.Tx, teacherId, examId, requiredRole string) (string, error) {
+	role, err := service.TeacherRepository.FindExamRole(ctx, tx, examId, teacherId)
+	if err != nil {
+		return "", fmt.Errorf("failed when calling FindExamRole repository: %w", err)
+	}
+	if !hasExamRole(role, requiredRole) {
+		return "", ErrExamForbidden
+	}
+
+	return role, nil
+}
+
+func (service *ClassServiceImpl) findExamClasses(ctx context.Context, tx pgx.Tx, teacherId, examId string) (web.ExamClassesResponse, error) {
+	classes, err := service.ClassRepository.FindByTeacherId(ctx, tx, teacherId)
+	if err != nil {
+		return web.ExamClassesResponse{}, fmt.Errorf("failed when calling FindByTeacherId repository: %w", err)
+	}
+
+	classIds, err := service.ClassRepository.FindClassIdsByExamId(ctx, tx, examId)
+	if err != nil {
+		return web.ExamClassesResponse{}, fmt.Errorf("failed when calling FindClassIdsByExamId repository: %w", err)
+	}
+
+	assignedClassIds := map[string]bool{}
+	for _, classId := range classIds {
+		assignedClassIds[classId] = true
+	}
+
+	exam, err := service.TeacherRepository.FindExamById(ctx, tx, examId)
+	if err != nil {
+		return web.ExamClassesResponse{}, fmt.Errorf("failed when calling FindExamById repository: %w", err)
+	}
+
+	return web.ExamClassesResponse{
+		ExamId:           examId,
+		Classes:          classes,
+		AssignedClassIds: assignedClassIds,
+		IsPrivate:        exam.IsPrivate,
+	}, nil
+}
+
+func (service *ClassServiceImpl) findOwnedClass(ctx context.Context, tx pgx.Tx, teacherId, classId string) (domain.Class, error) {
+	class, err := service.ClassRepository.FindById(ctx, tx, classId)
+	if err != nil {
+		return domain.Class{}, fmt.Errorf("failed when calling FindById repository: %w", err)
+	}
+	if class.TeacherId != teacherId {
+		return domain.Class{}, errors.New("class does not belong to this teacher")
+	}
+
+	return class, nil
+}
+
+// saveNewJoinCode generates an unused class join code and stores it on the class
+func (service *ClassServiceImpl) saveNewJoinCode(ctx context.Context, tx pgx.Tx, classId string) (string, error) {
+	for attempt := 1; attempt <= maxJoinCodeAttempts; attempt++ {
+		joinCode, err := helper.GenerateSecureString()
+		if err != nil {
+			return "", fmt.Errorf("failed when calling GenerateSecureString helper: %w", err)
+		}
+
+		isTaken, err := service.ClassRepository.IsJoinCodeTaken(ctx, tx, joinCode)
+		if err != nil {
+			return "", fmt.Errorf("failed when calling IsJoinCodeTaken repository: %w", err)
+		}
+		if isTaken {
+			continue
+		}
+
+		err = service.ClassRepository.UpdateJoinCodeById(ctx, tx, classId, joinCode)
+		if err != nil {
+			return "", fmt.Errorf("failed when calling UpdateJoinCodeById repository: %w", err)
+		}
+
+		return joinCode, nil
+	}
+
+	return "", errors.New("failed to generate an unused join code")
+}
@@ -0,0 +1,16 @@
+package service
+
+import (
+	"context"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+)
+
+type EmailVerificationService interface {
+	SendVerification(ctx context.Context, user domain.User) error
+	ResendVerification(ctx context.Context, request web.ResendVerificationRequest) error
+	VerifyEmail(ctx context.Context, token string) error
+	SendEmailChange(ctx context.Context, user domain.User) error
+	ConfirmEmailChange(ctx context.Context, token string) error
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mhaatha/go-template-saygenfix/internal/config"
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/mail"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/repository"
+)
+
+// Email verification policies, see config.Config.EmailVerificationPolicy
+const (
+	EmailVerificationPolicyNone  = "none"
+	EmailVerificationPolicyLogin = "login"
+	EmailVerificationPolicyExam  = "exam"
+)
+
+const (
+	// verificationTokenTTL is how long a verification link stays valid
+	verificationTokenTTL = 24 * time.Hour
+	// verificationResendInterval is the minimum time between two verification emails
+	verificationResendInterval = 2 * time.Minute
+	// emailChangeTokenPrefix keeps email change links apart from registration links
+	emailChangeTokenPrefix = "email-change:"
+)
+
+var (
+	// ErrEmailNotVerified is returned when the verification policy blocks an unverified account
+	ErrEmailNotVerified = errors.New("email is not verified")
+	// ErrVerificationTokenInvalid is returned for tampered, expired or outdated verification links
+	ErrVerificationTokenInvalid = errors.New("email verification token is invalid")
+	// ErrVerificationThrottled is returned when a verification email was sent moments ago
+	ErrVerificationThrottled = errors.New("verification email was sent recently")
+)
+
+func NewEmailVerificationService(userRepository repository.UserRepository, studentRepository repository.StudentRepository, mailer mail.Mailer, db *pgxpool.Pool, validate *validator.Validate, cfg *config.Config) EmailVerificationService {
+	secret := []byte(cfg.AppSecret)
+	if len(secret) == 0 {
+		// Tanpa APP_SECRET link verifikasi tidak berlaku lagi setelah server restart
+		slog.Warn("APP_SECRET tidak diset, menggunakan secret acak untuk link verifikasi email")
+
+		secret = make([]byte, 32)
+		rand.Read(secret)
+	}
+
+	return &EmailVerificationServiceImpl{
+		UserRepository:    userRepository,
+		StudentRepository: studentRepository,
+		Mailer:            mailer,
+		DB:                db,
+		Validate:          validate,
+		Config:            cfg,
+		Secret:            secret,
+	}
+}
+
+type EmailVerificationServiceImpl struct {
+	UserRepository    repository.UserRepository
+	StudentRepository repository.StudentRepository
+	Mailer            mail.Mailer
+	DB                *pgxpool.Pool
+	Validate          *validator.Validate
+	Config            *config.Config
+	Secret            []byte
+}
+
+// SendVerification emails the verification link right after registration
+func (service *EmailVerificationServiceImpl) SendVerification(ctx context.Context, user domain.User) error {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	return service.sendVerification(ctx, tx, user)
+}
+
+// ResendVerification sends a new link at most once per verificationResendInterval. Unknown
+// and already verified emails are ignored without an error so the form does not reveal them.
+func (service *EmailVerificationServiceImpl) ResendVerification(ctx context.Context, request web.ResendVerificationRequest) error {
+	request.Email = strings.TrimSpace(request.Email)
+
+	// Validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	user, err := service.UserRepository.FindByEmail(ctx, tx, request.Email)
+	if err != nil {
+		return fmt.Errorf("failed when calling FindByEmail repository: %w", err)
+	}
+	if user.Id == "" || user.IsEmailVerified || user.IsDisabled {
+		return nil
+	}
+
+	return service.sendVerification(ctx, tx, user)
+}
+
+// VerifyEmail marks the address as verified. The link is bound to the email it was sent to,
+// so it stops working if the account email changes. A pending teacher whose email domain is
+// in the auto-approve allowlist is approved here, and a student joins the classes that invited
+// the email, once the address is proven to be theirs.
+func (service *EmailVerificationServiceImpl) VerifyEmail(ctx context.Context, token string) error {
+	payload, err := helper.VerifySignedToken(service.Secret, token)
+	if err != nil || strings.HasPrefix(payload, emailChangeTokenPrefix) {
+		return ErrVerificationTokenInvalid
+	}
+	userId, email, found := strings.Cut(payload, ":")
+	if !found {
+		return ErrVerificationTokenInvalid
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	user, err := service.UserRepository.FindById(ctx, tx, userId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrVerificationTokenInvalid
+		}
+
+		return fmt.Errorf("failed when calling FindById repository: %w", err)
+	}
+	if user.Email != email {
+		return ErrVerificationTokenInvalid
+	}
+
+	if err := service.UserRepository.MarkEmailVerified(ctx, tx, user.Id); err != nil {
+		return fmt.Errorf("failed when calling MarkEmailVerified repository: %w", err)
+	}
+
+	if user.Role == "teacher" && !user.IsApproved && isAutoApprovedDomain(service.Config, user.Email) {
+		if err := service.UserRepository.MarkApproved(ctx, tx, user.Id); err != nil {
+			return fmt.Errorf("failed when calling MarkApproved repository: %w", err)
+		}
+	}
+
+	if err := service.StudentRepository.ClaimClassInvitations(ctx, tx, user.Id); err != nil {
+		return fmt.Errorf("failed when calling ClaimClassInvitations repository: %w", err)
+	}
+
+	return nil
+}
+
+// SendEmailChange emails a confirmation link to the pending email of the user
+func (service *EmailVerificationServiceImpl) SendEmailChange(ctx context.Context, user domain.User) error {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	canSend, err := service.UserRepository.TouchVerificationSentAt(ctx, tx, user.Id, verificationResendInterval)
+	if err != nil {
+		return fmt.Errorf("failed when calling TouchVerificationSentAt repository: %w", err)
+	}
+	if !canSend {
+		return ErrVerificationThrottled
+	}
+
+	token := helper.SignToken(service.Secret, emailChangeTokenPrefix+user.Id+":"+user.PendingEmail, time.Now().Add(verificationTokenTTL))
+	confirmLink := service.Config.AppBaseURL + "/verify-email/change?token=" + url.QueryEscape(token)
+
+	if err := service.Mailer.Send(ctx, mail.Message{
+		To:      user.PendingEmail,
+		Subject: "Konfirmasi perubahan email SayGenFix",
+		Body: fmt.Sprintf(
+			"Halo %s,\n\nAnda meminta untuk mengganti email akun SayGenFix menjadi alamat ini. Buka link berikut untuk mengonfirmasi:\n\n%s\n\nLink ini berlaku selama 24 jam. Email lama tetap dipakai sampai perubahan dikonfirmasi. Abaikan email ini jika Anda tidak merasa memintanya.\n",
+			user.FullName,
+			confirmLink,
+		),
+	}); err != nil {
+		return fmt.Errorf("failed when calling Send mailer: %w", err)
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange replaces the email with the pending one and claims the class invitations
+// of the new address. The link stops working once a different email is requested or the email
+// was taken by another account in the meantime.
+func (service *EmailVerificationServiceImpl) ConfirmEmailChange(ctx context.Context, token string) error {
+	payload, err := helper.VerifySignedToken(service.Secret, token)
+	if err != nil {
+		return ErrVerificationTokenInvalid
+	}
+	payload, found := strings.CutPrefix(payload, emailChangeTokenPrefix)
+	if !found {
+		return ErrVerificationTokenInvalid
+	}
+	userId, email, found := strings.Cut(payload, ":")
+	if !found {
+		return ErrVerificationTokenInvalid
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	existingUser, err := service.UserRepository.FindByEmail(ctx, tx, email)
+	if err != nil {
+		return fmt.Errorf("failed when calling FindByEmail repository: %w", err)
+	}
+	if existingUser.Id != "" {
+		return ErrEmailAlreadyUsed
+	}
+
+	confirmed, err := service.UserRepository.ConfirmPendingEmail(ctx, tx, userId, email)
+	if err != nil {
+		return fmt.Errorf("failed when calling ConfirmPendingEmail repository: %w", err)
+	}
+	if !confirmed {
+		return ErrVerificationTokenInvalid
+	}
+
+	if err := service.StudentRepository.ClaimClassInvitations(ctx, tx, userId); err != nil {
+		return fmt.Errorf("failed when calling ClaimClassInvitations repository: %w", err)
+	}
+
+	return nil
+}
+
+func (service *EmailVerificationServiceImpl) sendVerification(ctx context.Context, tx pgx.Tx, user domain.User) error {
+	canSend, err := service.UserRepository.TouchVerificationSentAt(ctx, tx, user.Id, verificationResendInterval)
+	if err != nil {
+		return fmt.Errorf("failed when calling TouchVerificationSentAt repository: %w", err)
+	}
+	if !canSend {
+		return ErrVerificationThrottled
+	}
+
+	token := helper.SignToken(service.Secret, user.Id+":"+user.Email, time.Now().Add(verificationTokenTTL))
+	verifyLink := service.Config.AppBaseURL + "/verify-email?token=" + url.QueryEscape(token)
+
+	if err := service.Mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verifikasi email SayGenFix",
+		Body: fmt.Sprintf(
+			"Halo %s,\n\nTerima kasih sudah mendaftar di SayGenFix. Buka link berikut untuk memverifikasi alamat email Anda:\n\n%s\n\nLink ini berlaku selama 24 jam. Abaikan email ini jika Anda tidak merasa mendaftar.\n",
+			user.FullName,
+			verifyLink,
+		),
+	}); err != nil {
+		return fmt.Errorf("failed when calling Send mailer: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/repository"
+)
+
+// The fakes keep rows in memory and ignore tx. Methods a test does not need are left to the
+// embedded interface, calling one panics.
+
+type fakeUserRepository struct {
+	repository.UserRepository
+	users         map[string]domain.User
+	emailVerified []string
+}
+
+func newFakeUserRepository(users ...domain.User) *fakeUserRepository {
+	repo := &fakeUserRepository{users: map[string]domain.User{}}
+	for _, user := range users {
+		repo.users[user.Id] = user
+	}
+	return repo
+}
+
+func (repo *fakeUserRepository) Save(ctx context.Context, tx pgx.Tx, user domain.User) (domain.User, error) {
+	user.Id = fmt.Sprintf("00000000-0000-0000-0000-%012d", len(repo.users)+1)
+	repo.users[user.Id] = user
+	return user, nil
+}
+
+func (repo *fakeUserRepository) FindByEmail(ctx context.Context, tx pgx.Tx, email string) (domain.User, error) {
+	for _, user := range repo.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return domain.User{}, nil
+}
+
+func (repo *fakeUserRepository) FindById(ctx context.Context, tx pgx.Tx, userId string) (domain.User, error) {
+	user, ok := repo.users[userId]
+	if !ok {
+		return domain.User{}, pgx.ErrNoRows
+	}
+	return user, nil
+}
+
+func (repo *fakeUserRepository) MarkEmailVerified(ctx context.Context, tx pgx.Tx, userId string) error {
+	repo.emailVerified = append(repo.emailVerified, userId)
+	return nil
+}
+
+func (repo *fakeUserRepository) UpdatePassword(ctx context.Context, tx pgx.Tx, userId, hashedPassword string) error {
+	user := repo.users[userId]
+	user.Password = hashedPassword
+	repo.users[userId] = user
+	return nil
+}
+
+type fakeUserIdentityRepository struct {
+	repository.UserIdentityRepository
+	identities []domain.UserIdentity
+}
+
+func (repo *fakeUserIdentityRepository) FindByIssuerAndSubject(ctx context.Context, tx pgx.Tx, issuer, subject string) (domain.UserIdentity, error) {
+	for _, identity := range repo.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return domain.UserIdentity{}, pgx.ErrNoRows
+}
+
+func (repo *fakeUserIdentityRepository) Save(ctx context.Context, tx pgx.Tx, identity domain.UserIdentity) (domain.UserIdentity, error) {
+	identity.Id = fmt.Sprintf("identity-%d", len(repo.identities)+1)
+	repo.identities = append(repo.identities, identity)
+	return identity, nil
+}
+
+func (repo *fakeUserIdentityRepository) TouchLastLogin(ctx context.Context, tx pgx.Tx, identityId, email string) error {
+	return nil
+}
+
+// webhookMark is one call to a MarkDelivery method of fakeWebhookRepository
+type webhookMark struct {
+	status     string
+	statusCode int
+	lastError  string
+	delay      time.Duration
+}
+
+type fakeWebhookRepository struct {
+	repository.WebhookRepository
+	marks map[string]webhookMark
+}
+
+func newFakeWebhookRepository() *fakeWebhookRepository {
+	return &fakeWebhookRepository{marks: map[string]webhookMark{}}
+}
+
+func (repo *fakeWebhookRepository) MarkDeliverySucceeded(ctx context.Context, tx pgx.Tx, deliveryId string, statusCode int) error {
+	repo.marks[deliveryId] = webhookMark{status: domain.WebhookDeliverySucceeded, statusCode: statusCode}
+	return nil
+}
+
+func (repo *fakeWebhookRepository) MarkDeliveryRetry(ctx context.Context, tx pgx.Tx, deliveryId string, statusCode int, lastError string, delay time.Duration) error {
+	repo.marks[deliveryId] = webhookMark{status: domain.WebhookDeliveryPending, statusCode: statusCode, lastError: lastError, delay: delay}
+	return nil
+}
+
+func (repo *fakeWebhookRepository) MarkDeliveryFailed(ctx context.Context, tx pgx.Tx, deliveryId string, statusCode int, lastError string) error {
+	repo.marks[deliveryId] = webhookMark{status: domain.WebhookDeliveryFailed, statusCode: statusCode, lastError: lastError}
+	return nil
+}
+
+// fakeDB hands out transactions that commit without a database
+type fakeDB struct{}
+
+func (fakeDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	return fakeTx{}, nil
+}
+
+type fakeTx struct {
+	pgx.Tx
+}
+
+func (fakeTx) Commit(ctx context.Context) error   { return nil }
+func (fakeTx) Rollback(ctx context.Context) error { return nil }
+
+type fakeAuthRepository struct {
+	repository.AuthRepository
+	sessions []domain.Session
+}
+
+func (repo *fakeAuthRepository) Save(ctx context.Context, tx pgx.Tx, session domain.Session) (domain.Session, error) {
+	repo.sessions = append(repo.sessions, session)
+	return session, nil
+}
+
+func (repo *fakeAuthRepository) SaveLoginAttempt(ctx context.Context, tx pgx.Tx, attempt domain.LoginAttempt) error {
+	return nil
+}
+
+func (repo *fakeAuthRepository) DeleteByUserId(ctx context.Context, tx pgx.Tx, userId string) error {
+	repo.sessions = slices.DeleteFunc(repo.sessions, func(session domain.Session) bool {
+		return session.UserId == userId
+	})
+	return nil
+}
+
+type fakeTwoFactorRepository struct {
+	repository.TwoFactorRepository
+	// lastCounters maps a user id to the time step of the last accepted code
+	lastCounters map[string]int64
+}
+
+func (repo *fakeTwoFactorRepository) UpdateLastCounter(ctx context.Context, tx pgx.Tx, userId string, counter int64) (bool, error) {
+	if last, ok := repo.lastCounters[userId]; ok && last >= counter {
+		return false, nil
+	}
+	repo.lastCounters[userId] = counter
+	return true, nil
+}
+
+type fakeTeacherRepository struct {
+	repository.TeacherRepository
+	exams map[string]domain.Exam
+	// roles maps "<exam id> <teacher id>" to the collaborator role
+	roles map[string]string
+}
+
+func (repo *fakeTeacherRepository) FindExamById(ctx context.Context, tx pgx.Tx, examId string) (domain.Exam, error) {
+	exam, ok := repo.exams[examId]
+	if !ok {
+		return domain.Exam{}, pgx.ErrNoRows
+	}
+	return exam, nil
+}
+
+func (repo *fakeTeacherRepository) FindExamRole(ctx context.Context, tx pgx.Tx, examId, teacherId string) (string, error) {
+	return repo.roles[examId+" "+teacherId], nil
+}
+
+func (repo *fakeTeacherRepository) FindExamsByUserId(ctx context.Context, tx pgx.Tx, userId, classId string) ([]domain.Exam, error) {
+	exams := []domain.Exam{}
+	for _, exam := range repo.exams {
+		if role := repo.roles[exam.Id+" "+userId]; role != "" {
+			exam.Role = role
+			exams = append(exams, exam)
+		}
+	}
+	return exams, nil
+}
+
+type fakeStudentRepository struct {
+	repository.StudentRepository
+	// members maps an exam id to the students added to it
+	members map[string][]string
+}
+
+func (repo *fakeStudentRepository) SaveExamMember(ctx context.Context, tx pgx.Tx, examId, studentId string) error {
+	repo.members[examId] = append(repo.members[examId], studentId)
+	return nil
+}
+
+type fakeLTIRepository struct {
+	repository.LTIRepository
+	platforms   []domain.LTIPlatform
+	loginStates map[string]domain.LTILoginState
+	links       []domain.LTIResourceLink
+	linkUsers   map[string]string
+	syncs       []domain.LTIScoreSync
+	synced      map[string]int
+	syncErrors  map[string]string
+}
+
+func newFakeLTIRepository(platforms ...domain.LTIPlatform) *fakeLTIRepository {
+	return &fakeLTIRepository{
+		platforms:   platforms,
+		loginStates: map[string]domain.LTILoginState{},
+		linkUsers:   map[string]string{},
+		synced:      map[string]int{},
+		syncErrors:  map[string]string{},
+	}
+}
+
+func (repo *fakeLTIRepository) FindPlatformById(ctx context.Context, tx pgx.Tx, platformId string) (domain.LTIPlatform, error) {
+	for _, platform := range repo.platforms {
+		if platform.Id == platformId {
+			return platform, nil
+		}
+	}
+	return domain.LTIPlatform{}, pgx.ErrNoRows
+}
+
+func (repo *fakeLTIRepository) FindPlatformsByIssuer(ctx context.Context, tx pgx.Tx, issuer string) ([]domain.LTIPlatform, error) {
+	platforms := []domain.LTIPlatform{}
+	for _, platform := range repo.platforms {
+		if platform.Issuer == issuer {
+			platforms = append(platforms, platform)
+		}
+	}
+	return platforms, nil
+}
+
+func (repo *fakeLTIRepository) SaveLoginState(ctx context.Context, tx pgx.Tx, state domain.LTILoginState, ttl time.Duration) error {
+	state.ExpiresAt = time.Now().Add(ttl)
+	repo.loginStates[state.State] = state
+	return nil
+}
+
+// ConsumeLoginState deletes the state like the DELETE ... RETURNING of the real repository
+func (repo *fakeLTIRepository) ConsumeLoginState(ctx context.Context, tx pgx.Tx, state string) (domain.LTILoginState, error) {
+	loginState, ok := repo.loginStates[state]
+	delete(repo.loginStates, state)
+	if !ok || time.Now().After(loginState.ExpiresAt) {
+		return domain.LTILoginState{}, pgx.ErrNoRows
+	}
+	return loginState, nil
+}
+
+func (repo *fakeLTIRepository) DeleteExpiredLoginStates(ctx context.Context, tx pgx.Tx) error {
+	return nil
+}
+
+func (repo *fakeLTIRepository) FindResourceLink(ctx context.Context, tx pgx.Tx, platformId, resourceLinkId string) (domain.LTIResourceLink, error) {
+	for _, link := range repo.links {
+		if link.PlatformId == platformId && link.ResourceLinkId == resourceLinkId {
+			return link, nil
+		}
+	}
+	return domain.LTIResourceLink{}, pgx.ErrNoRows
+}
+
+func (repo *fakeLTIRepository) SaveResourceLink(ctx context.Context, tx pgx.Tx, link domain.LTIResourceLink) (domain.LTIResourceLink, error) {
+	for i, saved := range repo.links {
+		if saved.PlatformId == link.PlatformId && saved.ResourceLinkId == link.ResourceLinkId {
+			link.Id = saved.Id
+			repo.links[i] = link
+			return link, nil
+		}
+	}
+
+	link.Id = fmt.Sprintf("link-%d", len(repo.links)+1)
+	repo.links = append(repo.links, link)
+	return link, nil
+}
+
+func (repo *fakeLTIRepository) SaveLinkUser(ctx context.Context, tx pgx.Tx, linkId, userId, ltiUserId string) error {
+	repo.linkUsers[linkId+" "+userId] = ltiUserId
+	return nil
+}
+
+func (repo *fakeLTIRepository) ClaimDueScoreSyncs(ctx context.Context, tx pgx.Tx, limit int, lease time.Duration) ([]domain.LTIScoreSync, error) {
+	syncs := repo.syncs
+	repo.syncs = nil
+	return syncs, nil
+}
+
+func (repo *fakeLTIRepository) MarkScoreSynced(ctx context.Context, tx pgx.Tx, sync domain.LTIScoreSync, score int) error {
+	repo.synced[sync.Id] = score
+	return nil
+}
+
+func (repo *fakeLTIRepository) MarkScoreSyncRetry(ctx context.Context, tx pgx.Tx, sync domain.LTIScoreSync, lastError string, delay time.Duration) error {
+	repo.syncErrors[sync.Id] = lastError
+	return nil
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/jose"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+)
+
+type LTIService interface {
+	// Platform registrations, managed by admins
+	ToolURLs() web.LTIToolURLs
+	ListPlatforms(ctx context.Context) ([]domain.LTIPlatform, error)
+	CreatePlatform(ctx context.Context, request web.LTIPlatformCreateRequest) (domain.LTIPlatform, error)
+	DeletePlatform(ctx context.Context, platformId string) error
+	ListScoreSyncs(ctx context.Context) ([]domain.LTIScoreSync, error)
+
+	// Launch flow
+	KeySet() jose.KeySet
+	InitiateLogin(ctx context.Context, request web.LTILoginRequest) (string, error)
+	Launch(ctx context.Context, request web.LTILaunchRequest) (web.LTILaunchResult, error)
+	CompleteDeepLink(ctx context.Context, request web.LTIDeepLinkRequest) (web.LTIAutoPostResponse, error)
+
+	// Score sync worker
+	SyncDueScores(ctx context.Context) (int, error)
+	RunScoreSync(ctx context.Context, interval time.Duration)
+}
@@ -0,0 +1,819 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mhaatha/go-template-saygenfix/internal/config"
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/jose"
+	"github.com/mhaatha/go-template-saygenfix/internal/lti"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/repository"
+)
+
+const (
+	// ltiLoginStateTTL is how long the platform has to post the launch after the login initiation
+	ltiLoginStateTTL = 10 * time.Minute
+	// ltiDeepLinkTokenTTL is how long the exam picker stays usable
+	ltiDeepLinkTokenTTL = time.Hour
+	// ltiScoreMaximum is the highest score of an exam attempt
+	ltiScoreMaximum = 100
+	// ltiScoreSyncBatchSize is how many scores one worker round sends
+	ltiScoreSyncBatchSize = 20
+	// ltiScoreSyncLease covers a full batch where the token request and the score post both time out
+	ltiScoreSyncLease = 10 * time.Minute
+	// ltiScoreSyncLogLimit is how many syncs the admin page shows
+	ltiScoreSyncLogLimit = 50
+	// ltiErrorLength is how much of the error is kept for the admin page
+	ltiErrorLength = 1000
+
+	// Custom parameters of the links made by deep linking. exam_sig proves the link was made by this
+	// tool, so a course can not open another exam by editing exam_id at the platform.
+	ltiCustomExamId        = "exam_id"
+	ltiCustomExamSignature = "exam_sig"
+)
+
+var (
+	// ErrLTILaunchInvalid is returned for a launch with an unknown state, a bad signature or a missing claim
+	ErrLTILaunchInvalid = lti.ErrLaunchInvalid
+	// ErrLTIPlatformNotFound is returned when no registration matches the issuer and client id
+	ErrLTIPlatformNotFound = errors.New("lti platform not found")
+	// ErrLTIPlatformExists is returned when the issuer and client id are already registered
+	ErrLTIPlatformExists = errors.New("lti platform is already registered")
+	// ErrLTIEmailMissing is returned for new users when the platform does not share the email
+	ErrLTIEmailMissing = errors.New("lti launch has no email")
+	// ErrLTIEmailTaken is returned for new users whose email already belongs to an account, only an
+	// admin can sort out which account the platform user is
+	ErrLTIEmailTaken = errors.New("lti launch email belongs to an existing account")
+	// ErrLTIAccountNotAllowed is returned when the launch resolves to an admin account
+	ErrLTIAccountNotAllowed = errors.New("admin accounts can not sign in through lti")
+	// ErrLTIExamNotLinked is returned when a new link was not made by deep linking or its exam is gone
+	ErrLTIExamNotLinked = errors.New("lti link does not point to an exam")
+	// ErrLTIInstructorOnly is returned when a student account opens deep linking
+	ErrLTIInstructorOnly = errors.New("only teachers can pick an exam")
+	// ErrLTIDeepLinkInvalid is returned for a tampered or expired exam picker
+	ErrLTIDeepLinkInvalid = errors.New("lti deep link token is invalid")
+)
+
+// ltiDeepLinkClaims is what the exam picker needs to answer the platform, kept in a signed token
+type ltiDeepLinkClaims struct {
+	PlatformId   string `json:"p"`
+	UserId       string `json:"u"`
+	DeploymentId string `json:"d"`
+	ReturnURL    string `json:"r"`
+	Data         string `json:"x,omitempty"`
+}
+
+func NewLTIService(tool *lti.Tool, ltiRepository repository.LTIRepository, userRepository repository.UserRepository, userIdentityRepository repository.UserIdentityRepository, authRepository repository.AuthRepository, teacherRepository repository.TeacherRepository, studentRepository repository.StudentRepository, db *pgxpool.Pool, validate *validator.Validate, cfg *config.Config) LTIService {
+	secret := []byte(cfg.AppSecret)
+	if len(secret) == 0 {
+		// Tanpa APP_SECRET link LTI yang belum pernah dibuka tidak berlaku lagi setelah server restart
+		slog.Warn("APP_SECRET tidak diset, menggunakan secret acak untuk link LTI")
+
+		secret = make([]byte, 32)
+		rand.Read(secret)
+	}
+
+	return &LTIServiceImpl{
+		Tool:                   tool,
+		LTIRepository:          ltiRepository,
+		UserRepository:         userRepository,
+		UserIdentityRepository: userIdentityRepository,
+		AuthRepository:         authRepository,
+		TeacherRepository:      teacherRepository,
+		StudentRepository:      studentRepository,
+		DB:                     db,
+		Validate:               validate,
+		Config:                 cfg,
+		Secret:                 secret,
+	}
+}
+
+type LTIServiceImpl struct {
+	Tool                   *lti.Tool
+	LTIRepository          repository.LTIRepository
+	UserRepository         repository.UserRepository
+	UserIdentityRepository repository.UserIdentityRepository
+	AuthRepository         repository.AuthRepository
+	TeacherRepository      repository.TeacherRepository
+	StudentRepository      repository.StudentRepository
+	DB                     helper.TxBeginner
+	Validate               *validator.Validate
+	Config                 *config.Config
+	Secret                 []byte
+}
+
+func (service *LTIServiceImpl) ToolURLs() web.LTIToolURLs {
+	return web.LTIToolURLs{
+		LoginURL:  service.Config.AppBaseURL + "/lti/login",
+		LaunchURL: service.Config.AppBaseURL + "/lti/launch",
+		JWKSURL:   service.Config.AppBaseURL + "/lti/jwks",
+	}
+}
+
+func (service *LTIServiceImpl) ListPlatforms(ctx context.Context) ([]domain.LTIPlatform, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	platforms, err := service.LTIRepository.FindPlatforms(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindPlatforms repository: %w", err)
+	}
+
+	return platforms, nil
+}
+
+func (service *LTIServiceImpl) CreatePlatform(ctx context.Context, request web.LTIPlatformCreateRequest) (domain.LTIPlatform, error) {
+	request.Name = strings.TrimSpace(request.Name)
+	request.Issuer = strings.TrimSpace(request.Issuer)
+	request.ClientId = strings.TrimSpace(request.ClientId)
+	request.AuthLoginURL = strings.TrimSpace(request.AuthLoginURL)
+	request.AuthTokenURL = strings.TrimSpace(request.AuthTokenURL)
+	request.JWKSURL = strings.TrimSpace(request.JWKSURL)
+
+	// Validate request
+	if err := service.Validate.Struct(request); err != nil {
+		return domain.LTIPlatform{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.LTIPlatform{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	registered, err := service.LTIRepository.FindPlatformsByIssuer(ctx, tx, request.Issuer)
+	if err != nil {
+		return domain.LTIPlatform{}, fmt.Errorf("failed when calling FindPlatformsByIssuer repository: %w", err)
+	}
+	if slices.ContainsFunc(registered, func(platform domain.LTIPlatform) bool { return platform.ClientId == request.ClientId }) {
+		return domain.LTIPlatform{}, ErrLTIPlatformExists
+	}
+
+	platform, err := service.LTIRepository.SavePlatform(ctx, tx, domain.LTIPlatform{
+		Name:          request.Name,
+		Issuer:        request.Issuer,
+		ClientId:      request.ClientId,
+		AuthLoginURL:  request.AuthLoginURL,
+		AuthTokenURL:  request.AuthTokenURL,
+		JWKSURL:       request.JWKSURL,
+		DeploymentIds: request.DeploymentIds,
+	})
+	if err != nil {
+		return domain.LTIPlatform{}, fmt.Errorf("failed when calling SavePlatform repository: %w", err)
+	}
+
+	return platform, nil
+}
+
+// DeletePlatform also removes its links, the users made by launches keep their accounts
+func (service *LTIServiceImpl) DeletePlatform(ctx context.Context, platformId string) error {
+	if uuid.Validate(platformId) != nil {
+		return ErrLTIPlatformNotFound
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	deleted, err := service.LTIRepository.DeletePlatform(ctx, tx, platformId)
+	if err != nil {
+		return fmt.Errorf("failed when calling DeletePlatform repository: %w", err)
+	}
+	if !deleted {
+		return ErrLTIPlatformNotFound
+	}
+
+	return nil
+}
+
+func (service *LTIServiceImpl) ListScoreSyncs(ctx context.Context) ([]domain.LTIScoreSync, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	syncs, err := service.LTIRepository.FindRecentScoreSyncs(ctx, tx, ltiScoreSyncLogLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindRecentScoreSyncs repository: %w", err)
+	}
+
+	return syncs, nil
+}
+
+func (service *LTIServiceImpl) KeySet() jose.KeySet {
+	return service.Tool.KeySet()
+}
+
+// InitiateLogin stores a single-use state and nonce, then returns the authentication request to
+// the platform. A platform with more than one registration must send client_id.
+func (service *LTIServiceImpl) InitiateLogin(ctx context.Context, request web.LTILoginRequest) (string, error) {
+	// Validate request
+	if err := service.Validate.Struct(request); err != nil {
+		return "", fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	platforms, err := service.LTIRepository.FindPlatformsByIssuer(ctx, tx, request.Issuer)
+	if err != nil {
+		return "", fmt.Errorf("failed when calling FindPlatformsByIssuer repository: %w", err)
+	}
+	if request.ClientId != "" {
+		platforms = slices.DeleteFunc(platforms, func(platform domain.LTIPlatform) bool { return platform.ClientId != request.ClientId })
+	}
+	if len(platforms) != 1 {
+		return "", ErrLTIPlatformNotFound
+	}
+	platform := platforms[0]
+
+	if err := service.LTIRepository.DeleteExpiredLoginStates(ctx, tx); err != nil {
+		return "", fmt.Errorf("failed when calling DeleteExpiredLoginStates repository: %w", err)
+	}
+
+	state, err := helper.GenerateToken()
+	if err != nil {
+		return "", fmt.Errorf("failed when calling GenerateToken helper: %w", err)
+	}
+	nonce, err := helper.GenerateToken()
+	if err != nil {
+		return "", fmt.Errorf("failed when calling GenerateToken helper: %w", err)
+	}
+
+	err = service.LTIRepository.SaveLoginState(ctx, tx, domain.LTILoginState{
+		State:      state,
+		PlatformId: platform.Id,
+		Nonce:      nonce,
+	}, ltiLoginStateTTL)
+	if err != nil {
+		return "", fmt.Errorf("failed when calling SaveLoginState repository: %w", err)
+	}
+
+	authRequestURL, err := service.Tool.AuthRequestURL(ltiRegistration(platform), service.ToolURLs().LaunchURL, request.LoginHint, request.MessageHint, state, nonce)
+	if err != nil {
+		return "", fmt.Errorf("failed when calling AuthRequestURL tool: %w", err)
+	}
+
+	return authRequestURL, nil
+}
+
+// Launch verifies the launch and provisions the user. A resource link launch signs the user in
+// and returns the page of the linked exam, users with 2FA get ErrTwoFactorRequired and no
+// session like the form login. A deep linking launch returns the exam picker without a session.
+func (service *LTIServiceImpl) Launch(ctx context.Context, request web.LTILaunchRequest) (web.LTILaunchResult, error) {
+	// Validate request
+	if err := service.Validate.Struct(request); err != nil {
+		return web.LTILaunchResult{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	loginState, platform, err := service.consumeLoginState(ctx, request.State)
+	if err != nil {
+		return web.LTILaunchResult{}, err
+	}
+
+	// Verifikasi token di luar transaksi karena JWKS platform mungkin perlu diambil lewat jaringan
+	launch, err := service.Tool.VerifyLaunch(ctx, ltiRegistration(platform), request.IDToken, loginState.Nonce)
+	if err != nil {
+		return web.LTILaunchResult{}, fmt.Errorf("failed when calling VerifyLaunch tool: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return web.LTILaunchResult{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	user, err := service.findOrCreateUser(ctx, tx, launch)
+	if err != nil {
+		return web.LTILaunchResult{}, err
+	}
+
+	if user.IsDisabled {
+		return web.LTILaunchResult{}, ErrAccountDisabled
+	}
+	if !user.IsApproved {
+		return web.LTILaunchResult{}, ErrAccountPending
+	}
+
+	if launch.MessageType == lti.MessageTypeDeepLinking {
+		return service.deepLinkPage(ctx, tx, platform, launch, user)
+	}
+
+	link, err := service.saveResourceLink(ctx, tx, platform, launch)
+	if err != nil {
+		return web.LTILaunchResult{}, err
+	}
+
+	if err := service.LTIRepository.SaveLinkUser(ctx, tx, link.Id, user.Id, launch.Subject); err != nil {
+		return web.LTILaunchResult{}, fmt.Errorf("failed when calling SaveLinkUser repository: %w", err)
+	}
+
+	result := web.LTILaunchResult{User: user}
+	switch user.Role {
+	case "student":
+		// Siswa dari kursus platform mendapat akses ke ujian seperti anggota yang ditambahkan guru
+		if err := service.StudentRepository.SaveExamMember(ctx, tx, link.ExamId, user.Id); err != nil {
+			return web.LTILaunchResult{}, fmt.Errorf("failed when calling SaveExamMember repository: %w", err)
+		}
+		result.RedirectPath = "/student/take-exam/" + link.ExamId
+	case "teacher":
+		role, err := service.TeacherRepository.FindExamRole(ctx, tx, link.ExamId, user.Id)
+		if err != nil {
+			return web.LTILaunchResult{}, fmt.Errorf("failed when calling FindExamRole repository: %w", err)
+		}
+		result.RedirectPath = "/teacher/dashboard"
+		if role != "" {
+			result.RedirectPath = "/teacher/check-exam/" + link.ExamId
+		}
+	}
+
+	if user.TwoFactorEnabled {
+		return result, ErrTwoFactorRequired
+	}
+
+	// Save session to db
+	session, err := service.AuthRepository.Save(ctx, tx, domain.Session{
+		SessionId: helper.Base64SessionId(),
+		UserId:    user.Id,
+		UserAgent: truncateUserAgent(request.UserAgent),
+		IPAddress: request.IPAddress,
+	})
+	if err != nil {
+		return web.LTILaunchResult{}, fmt.Errorf("failed when calling Save repository: %w", err)
+	}
+
+	if err := service.AuthRepository.SaveLoginAttempt(ctx, tx, domain.LoginAttempt{
+		Email:     normalizeLoginEmail(user.Email),
+		IPAddress: request.IPAddress,
+		IsSuccess: true,
+	}); err != nil {
+		return web.LTILaunchResult{}, fmt.Errorf("failed when calling SaveLoginAttempt repository: %w", err)
+	}
+
+	result.SessionId = session.SessionId
+	return result, nil
+}
+
+// CompleteDeepLink returns the signed deep linking response for the picked exam, the teacher needs
+// at least the editor role on it
+func (service *LTIServiceImpl) CompleteDeepLink(ctx context.Context, request web.LTIDeepLinkRequest) (web.LTIAutoPostResponse, error) {
+	// Validate request
+	if err := service.Validate.Struct(request); err != nil {
+		return web.LTIAutoPostResponse{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	payload, err := helper.VerifySignedToken(service.Secret, request.Token)
+	if err != nil {
+		return web.LTIAutoPostResponse{}, ErrLTIDeepLinkInvalid
+	}
+	var claims ltiDeepLinkClaims
+	if err := json.Unmarshal([]byte(payload), &claims); err != nil {
+		return web.LTIAutoPostResponse{}, ErrLTIDeepLinkInvalid
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return web.LTIAutoPostResponse{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	user, err := service.UserRepository.FindById(ctx, tx, claims.UserId)
+	if err != nil {
+		return web.LTIAutoPostResponse{}, fmt.Errorf("failed when calling FindById repository: %w", err)
+	}
+	if user.IsDisabled {
+		return web.LTIAutoPostResponse{}, ErrAccountDisabled
+	}
+
+	role, err := service.TeacherRepository.FindExamRole(ctx, tx, request.ExamId, user.Id)
+	if err != nil {
+		return web.LTIAutoPostResponse{}, fmt.Errorf("failed when calling FindExamRole repository: %w", err)
+	}
+	if !hasExamRole(role, domain.ExamRoleEditor) {
+		return web.LTIAutoPostResponse{}, ErrExamForbidden
+	}
+
+	exam, err := service.TeacherRepository.FindExamById(ctx, tx, request.ExamId)
+	if err != nil {
+		return web.LTIAutoPostResponse{}, fmt.Errorf("failed when calling FindExamById repository: %w", err)
+	}
+
+	platform, err := service.LTIRepository.FindPlatformById(ctx, tx, claims.PlatformId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return web.LTIAutoPostResponse{}, ErrLTIPlatformNotFound
+		}
+		return web.LTIAutoPostResponse{}, fmt.Errorf("failed when calling FindPlatformById repository: %w", err)
+	}
+
+	jwt, err := service.Tool.DeepLinkingResponse(ltiRegistration(platform), claims.DeploymentId, claims.Data, []lti.ContentItem{{
+		Type:  "ltiResourceLink",
+		Title: exam.RoomName,
+		URL:   service.ToolURLs().LaunchURL,
+		Custom: map[string]string{
+			ltiCustomExamId:        exam.Id,
+			ltiCustomExamSignature: service.examSignature(platform.Id, exam.Id),
+		},
+		LineItem: &lti.LineItem{
+			ScoreMaximum: ltiScoreMaximum,
+			Label:        exam.RoomName,
+			ResourceID:   exam.Id,
+		},
+	}})
+	if err != nil {
+		return web.LTIAutoPostResponse{}, fmt.Errorf("failed when calling DeepLinkingResponse tool: %w", err)
+	}
+
+	return web.LTIAutoPostResponse{
+		URL:    claims.ReturnURL,
+		Fields: map[string]string{"JWT": jwt},
+	}, nil
+}
+
+// SyncDueScores sends the due scores, each sync is recorded in its own transaction
+func (service *LTIServiceImpl) SyncDueScores(ctx context.Context) (int, error) {
+	syncs, err := service.claimDueScoreSyncs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, sync := range syncs {
+		if err := service.recordScoreSync(ctx, sync, service.postScore(ctx, sync)); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(syncs), nil
+}
+
+// RunScoreSync sends due scores until ctx is done. A full batch is followed by the next one right
+// away, otherwise the worker waits for the next tick.
+func (service *LTIServiceImpl) RunScoreSync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			sent, err := service.SyncDueScores(ctx)
+			if err != nil {
+				slog.Error("failed to sync lti scores", "err", err)
+				break
+			}
+			if sent < ltiScoreSyncBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (service *LTIServiceImpl) consumeLoginState(ctx context.Context, state string) (domain.LTILoginState, domain.LTIPlatform, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.LTILoginState{}, domain.LTIPlatform{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	loginState, err := service.LTIRepository.ConsumeLoginState(ctx, tx, state)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.LTILoginState{}, domain.LTIPlatform{}, fmt.Errorf("%w: unknown or expired state", ErrLTILaunchInvalid)
+		}
+		return domain.LTILoginState{}, domain.LTIPlatform{}, fmt.Errorf("failed when calling ConsumeLoginState repository: %w", err)
+	}
+
+	platform, err := service.LTIRepository.FindPlatformById(ctx, tx, loginState.PlatformId)
+	if err != nil {
+		return domain.LTILoginState{}, domain.LTIPlatform{}, fmt.Errorf("failed when calling FindPlatformById repository: %w", err)
+	}
+
+	return loginState, platform, nil
+}
+
+// findOrCreateUser resolves the platform user by issuer and subject first, like single sign-on.
+// Unlinked users get a new, already approved account, instructors become teachers and everyone
+// else a student. An unlinked user is never matched to an existing account by email, the platform
+// admin controls that email and could otherwise sign in as any user of this app.
+func (service *LTIServiceImpl) findOrCreateUser(ctx context.Context, tx pgx.Tx, launch lti.Launch) (domain.User, error) {
+	identity, err := service.UserIdentityRepository.FindByIssuerAndSubject(ctx, tx, launch.Issuer, launch.Subject)
+	if err == nil {
+		if err := service.UserIdentityRepository.TouchLastLogin(ctx, tx, identity.Id, launch.Email); err != nil {
+			return domain.User{}, fmt.Errorf("failed when calling TouchLastLogin repository: %w", err)
+		}
+
+		user, err := service.UserRepository.FindById(ctx, tx, identity.UserId)
+		if err != nil {
+			return domain.User{}, fmt.Errorf("failed when calling FindById repository: %w", err)
+		}
+		if user.Role == "admin" {
+			return domain.User{}, ErrLTIAccountNotAllowed
+		}
+
+		return user, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return domain.User{}, fmt.Errorf("failed when calling FindByIssuerAndSubject repository: %w", err)
+	}
+
+	email := strings.ToLower(strings.TrimSpace(launch.Email))
+	if email == "" {
+		return domain.User{}, ErrLTIEmailMissing
+	}
+
+	user, err := service.UserRepository.FindByEmail(ctx, tx, email)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed when calling FindByEmail repository: %w", err)
+	}
+
+	if user.Id != "" {
+		return domain.User{}, ErrLTIEmailTaken
+	}
+
+	user, err = service.createUser(ctx, tx, launch, email)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	if _, err := service.UserIdentityRepository.Save(ctx, tx, domain.UserIdentity{
+		UserId:  user.Id,
+		Issuer:  launch.Issuer,
+		Subject: launch.Subject,
+		Email:   email,
+	}); err != nil {
+		return domain.User{}, fmt.Errorf("failed when calling Save repository: %w", err)
+	}
+
+	// Platform didaftarkan admin dan sudah mengenal email pengguna barunya
+	if err := service.UserRepository.MarkEmailVerified(ctx, tx, user.Id); err != nil {
+		return domain.User{}, fmt.Errorf("failed when calling MarkEmailVerified repository: %w", err)
+	}
+	user.IsEmailVerified = true
+
+	return user, nil
+}
+
+// createUser registers an account with a random password the user never sees, they can still set
+// one later through the forgot password page
+func (service *LTIServiceImpl) createUser(ctx context.Context, tx pgx.Tx, launch lti.Launch, email string) (domain.User, error) {
+	role := "student"
+	if launch.IsInstructor() {
+		role = "teacher"
+	}
+
+	fullName := launch.Name
+	if fullName == "" {
+		fullName, _, _ = strings.Cut(email, "@")
+	}
+	if len(fullName) > 255 {
+		fullName = fullName[:255]
+	}
+
+	randomPassword, err := helper.GenerateToken()
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed when calling GenerateToken helper: %w", err)
+	}
+
+	hashedPassword, err := helper.HashPassword(randomPassword)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed when calling HashPassword: %w", err)
+	}
+
+	user, err := service.UserRepository.Save(ctx, tx, domain.User{
+		Email:      email,
+		FullName:   fullName,
+		Password:   hashedPassword,
+		Role:       role,
+		IsApproved: true,
+	})
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed when calling Save repository: %w", err)
+	}
+
+	return user, nil
+}
+
+// deepLinkPage lists the exams the teacher may link, the picker form carries a signed token
+// instead of a session because the page runs in an iframe of the platform
+func (service *LTIServiceImpl) deepLinkPage(ctx context.Context, tx pgx.Tx, platform domain.LTIPlatform, launch lti.Launch, user domain.User) (web.LTILaunchResult, error) {
+	if user.Role != "teacher" {
+		return web.LTILaunchResult{}, ErrLTIInstructorOnly
+	}
+
+	exams, err := service.TeacherRepository.FindExamsByUserId(ctx, tx, user.Id, "")
+	if err != nil {
+		return web.LTILaunchResult{}, fmt.Errorf("failed when calling FindExamsByUserId repository: %w", err)
+	}
+	exams = slices.DeleteFunc(exams, func(exam domain.Exam) bool { return !hasExamRole(exam.Role, domain.ExamRoleEditor) })
+	slices.SortFunc(exams, func(a, b domain.Exam) int { return strings.Compare(a.RoomName, b.RoomName) })
+
+	payload, err := json.Marshal(ltiDeepLinkClaims{
+		PlatformId:   platform.Id,
+		UserId:       user.Id,
+		DeploymentId: launch.DeploymentID,
+		ReturnURL:    launch.DeepLinkReturnURL,
+		Data:         launch.DeepLinkData,
+	})
+	if err != nil {
+		return web.LTILaunchResult{}, fmt.Errorf("failed to encode deep link token: %w", err)
+	}
+
+	return web.LTILaunchResult{
+		User: user,
+		DeepLink: &web.LTIDeepLinkPageResponse{
+			Token:        helper.SignToken(service.Secret, string(payload), time.Now().Add(ltiDeepLinkTokenTTL)),
+			PlatformName: platform.Name,
+			ContextTitle: launch.ContextTitle,
+			Exams:        exams,
+		},
+	}, nil
+}
+
+// saveResourceLink binds a new link to the exam in its signed custom parameters, a known link
+// keeps its exam and only gets its titles and line item refreshed
+func (service *LTIServiceImpl) saveResourceLink(ctx context.Context, tx pgx.Tx, platform domain.LTIPlatform, launch lti.Launch) (domain.LTIResourceLink, error) {
+	link, err := service.LTIRepository.FindResourceLink(ctx, tx, platform.Id, launch.ResourceLinkID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return domain.LTIResourceLink{}, fmt.Errorf("failed when calling FindResourceLink repository: %w", err)
+	}
+
+	examId := link.ExamId
+	if link.Id == "" {
+		examId = launch.Custom[ltiCustomExamId]
+		signature := launch.Custom[ltiCustomExamSignature]
+		if examId == "" || !hmac.Equal([]byte(signature), []byte(service.examSignature(platform.Id, examId))) {
+			return domain.LTIResourceLink{}, ErrLTIExamNotLinked
+		}
+
+		if _, err := service.TeacherRepository.FindExamById(ctx, tx, examId); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return domain.LTIResourceLink{}, ErrLTIExamNotLinked
+			}
+			return domain.LTIResourceLink{}, fmt.Errorf("failed when calling FindExamById repository: %w", err)
+		}
+	}
+
+	lineItemURL := ""
+	if launch.CanPostScores() {
+		lineItemURL = launch.LineItemURL
+	}
+
+	link, err = service.LTIRepository.SaveResourceLink(ctx, tx, domain.LTIResourceLink{
+		PlatformId:     platform.Id,
+		DeploymentId:   launch.DeploymentID,
+		ResourceLinkId: launch.ResourceLinkID,
+		ExamId:         examId,
+		Title:          truncateText(launch.ResourceLinkTitle, 255),
+		ContextId:      truncateText(launch.ContextID, 255),
+		ContextTitle:   truncateText(launch.ContextTitle, 255),
+		LineItemURL:    lineItemURL,
+	})
+	if err != nil {
+		return domain.LTIResourceLink{}, fmt.Errorf("failed when calling SaveResourceLink repository: %w", err)
+	}
+
+	return link, nil
+}
+
+// examSignature ties an exam to the platform it was linked from
+func (service *LTIServiceImpl) examSignature(platformId, examId string) string {
+	mac := hmac.New(sha256.New, service.Secret)
+	mac.Write([]byte("lti-exam:" + platformId + ":" + examId))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// claimDueScoreSyncs commits the claim before sending, so a slow platform does not hold the row locks
+func (service *LTIServiceImpl) claimDueScoreSyncs(ctx context.Context) ([]domain.LTIScoreSync, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	syncs, err := service.LTIRepository.ClaimDueScoreSyncs(ctx, tx, ltiScoreSyncBatchSize, ltiScoreSyncLease)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling ClaimDueScoreSyncs repository: %w", err)
+	}
+
+	return syncs, nil
+}
+
+// postScore sends the best completed score, a user without one has nothing to send
+func (service *LTIServiceImpl) postScore(ctx context.Context, sync domain.LTIScoreSync) error {
+	if sync.Score == nil || sync.CompletedAt == nil {
+		return nil
+	}
+
+	registration := lti.Registration{
+		Issuer:       sync.Issuer,
+		ClientID:     sync.ClientId,
+		AuthTokenURL: sync.AuthTokenURL,
+	}
+
+	return service.Tool.PostScore(ctx, registration, sync.LineItemURL, lti.NewScore(sync.LTIUserId, float64(*sync.Score), ltiScoreMaximum, *sync.CompletedAt))
+}
+
+// recordScoreSync stores the result, failures are retried on the webhook schedule until LTIScoreMaxAttempts
+func (service *LTIServiceImpl) recordScoreSync(ctx context.Context, sync domain.LTIScoreSync, syncErr error) error {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if syncErr == nil {
+		if sync.Score == nil {
+			if err := service.LTIRepository.MarkScoreSyncSkipped(ctx, tx, sync); err != nil {
+				return fmt.Errorf("failed when calling MarkScoreSyncSkipped repository: %w", err)
+			}
+			return nil
+		}
+
+		if err := service.LTIRepository.MarkScoreSynced(ctx, tx, sync, *sync.Score); err != nil {
+			return fmt.Errorf("failed when calling MarkScoreSynced repository: %w", err)
+		}
+		return nil
+	}
+
+	lastError := strings.ToValidUTF8(truncateText(syncErr.Error(), ltiErrorLength), "")
+
+	attempt := sync.Attempts + 1
+	if attempt >= service.Config.LTIScoreMaxAttempts {
+		slog.Warn("lti score sync failed for good", "sync_id", sync.Id, "platform", sync.PlatformName, "attempts", attempt, "err", syncErr)
+
+		if err := service.LTIRepository.MarkScoreSyncFailed(ctx, tx, sync, lastError); err != nil {
+			return fmt.Errorf("failed when calling MarkScoreSyncFailed repository: %w", err)
+		}
+		return nil
+	}
+
+	delay := webhookRetryDelays[min(attempt, len(webhookRetryDelays))-1]
+	slog.Info("lti score sync failed, retrying later", "sync_id", sync.Id, "platform", sync.PlatformName, "attempt", attempt, "retry_in", delay, "err", syncErr)
+
+	if err := service.LTIRepository.MarkScoreSyncRetry(ctx, tx, sync, lastError, delay); err != nil {
+		return fmt.Errorf("failed when calling MarkScoreSyncRetry repository: %w", err)
+	}
+
+	return nil
+}
+
+func ltiRegistration(platform domain.LTIPlatform) lti.Registration {
+	return lti.Registration{
+		Issuer:        platform.Issuer,
+		ClientID:      platform.ClientId,
+		AuthLoginURL:  platform.AuthLoginURL,
+		AuthTokenURL:  platform.AuthTokenURL,
+		JWKSURL:       platform.JWKSURL,
+		DeploymentIDs: platform.DeploymentIds,
+	}
+}
+
+// truncateText cuts text to at most limit bytes without splitting a character
+func truncateText(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+
+	return strings.ToValidUTF8(text[:limit], "")
+}
@@ -0,0 +1,462 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/config"
+	"github.com/mhaatha/go-template-saygenfix/internal/jose"
+	"github.com/mhaatha/go-template-saygenfix/internal/lti"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+)
+
+const (
+	testLTIPlatformId  = "5a8f3c2e-1b4d-4e6f-9a0b-7c8d9e0f1a2b"
+	testLTIExamId      = "3f0b6a4e-2c1d-4e5f-8a9b-0c1d2e3f4a5b"
+	testLTIOtherExam   = "9d8c7b6a-5f4e-4d3c-8b2a-1f0e9d8c7b6a"
+	testLTIClientId    = "saygenfix-tool"
+	testLTIPlatformKid = "platform-key"
+	testLTITeacherId   = "00000000-0000-0000-0000-0000000000aa"
+)
+
+// ltiPlatform is a fake LMS: it publishes its JWKS, signs launches, hands out access tokens and
+// keeps the scores posted to its line item
+type ltiPlatform struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	scores []lti.Score
+}
+
+func newLTIPlatform(t *testing.T) *ltiPlatform {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	platform := &ltiPlatform{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.KeySet{Keys: []jose.JSONWebKey{jose.PublicJSONWebKey(&key.PublicKey, testLTIPlatformKid)}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "access", "token_type": "Bearer", "expires_in": 3600})
+	})
+	mux.HandleFunc("POST /lineitems/1/scores", func(w http.ResponseWriter, r *http.Request) {
+		var score lti.Score
+		if err := json.NewDecoder(r.Body).Decode(&score); err != nil || r.Header.Get("Authorization") != "Bearer access" {
+			http.Error(w, "bad score", http.StatusBadRequest)
+			return
+		}
+		platform.mu.Lock()
+		platform.scores = append(platform.scores, score)
+		platform.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /lineitems/2/scores", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gradebook is locked", http.StatusInternalServerError)
+	})
+
+	platform.server = httptest.NewServer(mux)
+	t.Cleanup(platform.server.Close)
+
+	return platform
+}
+
+func (platform *ltiPlatform) registration() domain.LTIPlatform {
+	return domain.LTIPlatform{
+		Id:            testLTIPlatformId,
+		Name:          "LMS Sekolah",
+		Issuer:        platform.server.URL,
+		ClientId:      testLTIClientId,
+		AuthLoginURL:  platform.server.URL + "/auth",
+		AuthTokenURL:  platform.server.URL + "/token",
+		JWKSURL:       platform.server.URL + "/jwks",
+		DeploymentIds: []string{"deployment-1"},
+	}
+}
+
+// launch signs a resource link launch of a student, change tweaks the claims before signing
+func (platform *ltiPlatform) launch(t *testing.T, key *rsa.PrivateKey, nonce string, custom map[string]string, change func(claims map[string]any)) string {
+	t.Helper()
+
+	claims := map[string]any{
+		"iss":                 platform.server.URL,
+		"sub":                 "platform-student-1",
+		"aud":                 testLTIClientId,
+		"exp":                 time.Now().Add(5 * time.Minute).Unix(),
+		"nonce":               nonce,
+		"email":               "Siswa@Sekolah.sch.id",
+		"name":                "Siswa Satu",
+		lti.ClaimMessageType:  lti.MessageTypeResourceLink,
+		lti.ClaimVersion:      lti.Version,
+		lti.ClaimDeploymentID: "deployment-1",
+		lti.ClaimResourceLink: map[string]any{"id": "link-1", "title": "Ujian Biologi"},
+		lti.ClaimRoles:        []string{"http://purl.imsglobal.org/vocab/lis/v2/membership#Learner"},
+		lti.ClaimCustom:       custom,
+		lti.ClaimAGSEndpoint: map[string]any{
+			"scope":    []string{lti.ScopeScore},
+			"lineitem": platform.server.URL + "/lineitems/1",
+		},
+	}
+	if change != nil {
+		change(claims)
+	}
+
+	token, err := jose.Sign(key, testLTIPlatformKid, claims)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return token
+}
+
+type ltiTestEnv struct {
+	service    *LTIServiceImpl
+	platform   *ltiPlatform
+	ltiRepo    *fakeLTIRepository
+	users      *fakeUserRepository
+	identities *fakeUserIdentityRepository
+	auth       *fakeAuthRepository
+	students   *fakeStudentRepository
+}
+
+func newLTITestEnv(t *testing.T, users ...domain.User) *ltiTestEnv {
+	t.Helper()
+
+	toolKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	platform := newLTIPlatform(t)
+	env := &ltiTestEnv{
+		platform:   platform,
+		ltiRepo:    newFakeLTIRepository(platform.registration()),
+		users:      newFakeUserRepository(users...),
+		identities: &fakeUserIdentityRepository{},
+		auth:       &fakeAuthRepository{},
+		students:   &fakeStudentRepository{members: map[string][]string{}},
+	}
+	env.service = &LTIServiceImpl{
+		Tool:                   lti.NewTool(toolKey),
+		LTIRepository:          env.ltiRepo,
+		UserRepository:         env.users,
+		UserIdentityRepository: env.identities,
+		AuthRepository:         env.auth,
+		TeacherRepository: &fakeTeacherRepository{
+			exams: map[string]domain.Exam{
+				testLTIExamId:    {Id: testLTIExamId, RoomName: "Biologi Kelas X"},
+				testLTIOtherExam: {Id: testLTIOtherExam, RoomName: "Kimia Kelas X"},
+			},
+			roles: map[string]string{
+				testLTIExamId + " " + testLTITeacherId:    domain.ExamRoleEditor,
+				testLTIOtherExam + " " + testLTITeacherId: domain.ExamRoleViewer,
+			},
+		},
+		StudentRepository: env.students,
+		DB:                fakeDB{},
+		Validate:          config.ValidatorInit(),
+		Config:            &config.Config{AppBaseURL: "https://app.test", LTIScoreMaxAttempts: 5},
+		Secret:            []byte("rahasia-aplikasi"),
+	}
+
+	return env
+}
+
+// login runs the login initiation and returns the state and nonce the platform got back
+func (env *ltiTestEnv) login(t *testing.T) (string, string) {
+	t.Helper()
+
+	authRequestURL, err := env.service.InitiateLogin(context.Background(), web.LTILoginRequest{
+		Issuer:    env.platform.server.URL,
+		LoginHint: "hint",
+	})
+	if err != nil {
+		t.Fatalf("InitiateLogin: %v", err)
+	}
+
+	parsed, err := url.Parse(authRequestURL)
+	if err != nil {
+		t.Fatalf("parse auth request url: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("client_id") != testLTIClientId || query.Get("redirect_uri") != "https://app.test/lti/launch" || query.Get("login_hint") != "hint" {
+		t.Fatalf("unexpected auth request %s", authRequestURL)
+	}
+
+	return query.Get("state"), query.Get("nonce")
+}
+
+// linkedExam are the custom parameters deep linking puts on a link to the exam
+func (env *ltiTestEnv) linkedExam(examId string) map[string]string {
+	return map[string]string{
+		ltiCustomExamId:        examId,
+		ltiCustomExamSignature: env.service.examSignature(testLTIPlatformId, examId),
+	}
+}
+
+func TestLTIKeySet(t *testing.T) {
+	env := newLTITestEnv(t)
+
+	keySet := env.service.KeySet()
+	if len(keySet.Keys) != 1 || keySet.Keys[0].Kty != "RSA" || keySet.Keys[0].Kid == "" {
+		t.Fatalf("unexpected key set %+v", keySet)
+	}
+}
+
+func TestLTILaunchStudent(t *testing.T) {
+	env := newLTITestEnv(t)
+	ctx := context.Background()
+
+	state, nonce := env.login(t)
+	request := web.LTILaunchRequest{
+		IDToken:   env.platform.launch(t, env.platform.key, nonce, env.linkedExam(testLTIExamId), nil),
+		State:     state,
+		IPAddress: "203.0.113.7",
+	}
+
+	result, err := env.service.Launch(ctx, request)
+	if err != nil {
+		t.Fatalf("Launch: %v", err)
+	}
+
+	if result.SessionId == "" || result.RedirectPath != "/student/take-exam/"+testLTIExamId {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if result.User.Email != "siswa@sekolah.sch.id" || result.User.Role != "student" || !result.User.IsEmailVerified {
+		t.Fatalf("unexpected user %+v", result.User)
+	}
+	if !slices.Contains(env.students.members[testLTIExamId], result.User.Id) {
+		t.Fatal("student was not added to the exam")
+	}
+	if len(env.identities.identities) != 1 || env.identities.identities[0].Subject != "platform-student-1" {
+		t.Fatalf("identity not saved: %+v", env.identities.identities)
+	}
+	if len(env.ltiRepo.links) != 1 || env.ltiRepo.links[0].LineItemURL != env.platform.server.URL+"/lineitems/1" {
+		t.Fatalf("resource link not saved: %+v", env.ltiRepo.links)
+	}
+
+	// State hanya berlaku sekali, launch yang sama tidak boleh diputar ulang
+	if _, err := env.service.Launch(ctx, request); !errors.Is(err, ErrLTILaunchInvalid) {
+		t.Fatalf("replayed launch = %v, want ErrLTILaunchInvalid", err)
+	}
+	if len(env.auth.sessions) != 1 {
+		t.Fatalf("sessions = %d, want 1", len(env.auth.sessions))
+	}
+}
+
+func TestLTILaunchRejects(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		launch  func(t *testing.T, env *ltiTestEnv, state, nonce string) web.LTILaunchRequest
+		wantErr error
+	}{
+		{
+			name: "bad signature",
+			launch: func(t *testing.T, env *ltiTestEnv, state, nonce string) web.LTILaunchRequest {
+				return web.LTILaunchRequest{IDToken: env.platform.launch(t, otherKey, nonce, env.linkedExam(testLTIExamId), nil), State: state}
+			},
+			wantErr: ErrLTILaunchInvalid,
+		},
+		{
+			name: "wrong nonce",
+			launch: func(t *testing.T, env *ltiTestEnv, state, nonce string) web.LTILaunchRequest {
+				return web.LTILaunchRequest{IDToken: env.platform.launch(t, env.platform.key, "nonce-lain", env.linkedExam(testLTIExamId), nil), State: state}
+			},
+			wantErr: ErrLTILaunchInvalid,
+		},
+		{
+			name: "unknown state",
+			launch: func(t *testing.T, env *ltiTestEnv, state, nonce string) web.LTILaunchRequest {
+				return web.LTILaunchRequest{IDToken: env.platform.launch(t, env.platform.key, nonce, env.linkedExam(testLTIExamId), nil), State: "state-lain"}
+			},
+			wantErr: ErrLTILaunchInvalid,
+		},
+		{
+			name: "exam id edited at the platform",
+			launch: func(t *testing.T, env *ltiTestEnv, state, nonce string) web.LTILaunchRequest {
+				custom := env.linkedExam(testLTIExamId)
+				custom[ltiCustomExamId] = testLTIOtherExam
+				return web.LTILaunchRequest{IDToken: env.platform.launch(t, env.platform.key, nonce, custom, nil), State: state}
+			},
+			wantErr: ErrLTIExamNotLinked,
+		},
+		{
+			name: "no email",
+			launch: func(t *testing.T, env *ltiTestEnv, state, nonce string) web.LTILaunchRequest {
+				token := env.platform.launch(t, env.platform.key, nonce, env.linkedExam(testLTIExamId), func(claims map[string]any) { delete(claims, "email") })
+				return web.LTILaunchRequest{IDToken: token, State: state}
+			},
+			wantErr: ErrLTIEmailMissing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newLTITestEnv(t)
+			state, nonce := env.login(t)
+
+			if _, err := env.service.Launch(context.Background(), tt.launch(t, env, state, nonce)); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Launch = %v, want %v", err, tt.wantErr)
+			}
+			if len(env.auth.sessions) != 0 {
+				t.Fatal("a session was created for a rejected launch")
+			}
+		})
+	}
+}
+
+func TestLTILaunchEmailTaken(t *testing.T) {
+	existing := domain.User{Id: "00000000-0000-0000-0000-0000000000bb", Email: "siswa@sekolah.sch.id", Role: "teacher", IsApproved: true}
+	env := newLTITestEnv(t, existing)
+
+	state, nonce := env.login(t)
+	_, err := env.service.Launch(context.Background(), web.LTILaunchRequest{
+		IDToken: env.platform.launch(t, env.platform.key, nonce, env.linkedExam(testLTIExamId), nil),
+		State:   state,
+	})
+	if !errors.Is(err, ErrLTIEmailTaken) {
+		t.Fatalf("Launch = %v, want ErrLTIEmailTaken", err)
+	}
+
+	// Akun yang sudah ada tidak boleh ditautkan atau diubah oleh platform
+	if len(env.identities.identities) != 0 || len(env.users.emailVerified) != 0 || len(env.auth.sessions) != 0 {
+		t.Fatalf("existing account was touched: identities %v, verified %v", env.identities.identities, env.users.emailVerified)
+	}
+}
+
+func TestLTIDeepLinking(t *testing.T) {
+	teacher := domain.User{Id: testLTITeacherId, Email: "guru@sekolah.sch.id", Role: "teacher", IsApproved: true}
+	env := newLTITestEnv(t)
+	env.users.users[teacher.Id] = teacher
+	env.identities.identities = []domain.UserIdentity{{Id: "identity-1", UserId: teacher.Id, Issuer: env.platform.server.URL, Subject: "platform-teacher-1"}}
+	ctx := context.Background()
+
+	state, nonce := env.login(t)
+	token := env.platform.launch(t, env.platform.key, nonce, nil, func(claims map[string]any) {
+		claims["sub"] = "platform-teacher-1"
+		claims[lti.ClaimMessageType] = lti.MessageTypeDeepLinking
+		claims[lti.ClaimRoles] = []string{"http://purl.imsglobal.org/vocab/lis/v2/membership#Instructor"}
+		claims[lti.ClaimDeepLinkingSettings] = map[string]any{
+			"deep_link_return_url": env.platform.server.URL + "/deep-link-return",
+			"accept_types":         []string{"ltiResourceLink"},
+			"data":                 "opaque-data",
+		}
+		delete(claims, lti.ClaimResourceLink)
+	})
+
+	result, err := env.service.Launch(ctx, web.LTILaunchRequest{IDToken: token, State: state})
+	if err != nil {
+		t.Fatalf("Launch: %v", err)
+	}
+	if result.DeepLink == nil || result.SessionId != "" {
+		t.Fatalf("deep linking launch = %+v, want the exam picker without a session", result)
+	}
+	// Ujian dengan role viewer tidak boleh dipilih
+	if len(result.DeepLink.Exams) != 1 || result.DeepLink.Exams[0].Id != testLTIExamId {
+		t.Fatalf("picker exams = %+v, want only the exam the teacher can edit", result.DeepLink.Exams)
+	}
+
+	if _, err := env.service.CompleteDeepLink(ctx, web.LTIDeepLinkRequest{Token: result.DeepLink.Token, ExamId: testLTIOtherExam}); !errors.Is(err, ErrExamForbidden) {
+		t.Fatalf("CompleteDeepLink for a viewer exam = %v, want ErrExamForbidden", err)
+	}
+	if _, err := env.service.CompleteDeepLink(ctx, web.LTIDeepLinkRequest{Token: result.DeepLink.Token + "x", ExamId: testLTIExamId}); !errors.Is(err, ErrLTIDeepLinkInvalid) {
+		t.Fatalf("CompleteDeepLink with a tampered token = %v, want ErrLTIDeepLinkInvalid", err)
+	}
+
+	response, err := env.service.CompleteDeepLink(ctx, web.LTIDeepLinkRequest{Token: result.DeepLink.Token, ExamId: testLTIExamId})
+	if err != nil {
+		t.Fatalf("CompleteDeepLink: %v", err)
+	}
+	if response.URL != env.platform.server.URL+"/deep-link-return" {
+		t.Fatalf("auto post url = %q", response.URL)
+	}
+
+	// Platform memverifikasi respons dengan JWKS tool lalu menyimpan custom parameter link
+	toolKey, err := jose.ParseJSONWebKey(env.service.KeySet().Keys[0])
+	if err != nil {
+		t.Fatalf("ParseJSONWebKey: %v", err)
+	}
+	claims, err := jose.Verify(ctx, response.Fields["JWT"], func(ctx context.Context, kid string) (crypto.PublicKey, error) { return toolKey, nil })
+	if err != nil {
+		t.Fatalf("platform could not verify the deep linking response: %v", err)
+	}
+	if claims["aud"] != env.platform.server.URL || claims[lti.ClaimDeepLinkingData] != "opaque-data" {
+		t.Fatalf("unexpected deep linking response %v", claims)
+	}
+
+	var items []lti.ContentItem
+	encoded, _ := json.Marshal(claims[lti.ClaimContentItems])
+	if err := json.Unmarshal(encoded, &items); err != nil || len(items) != 1 {
+		t.Fatalf("content items = %s", encoded)
+	}
+
+	// Siswa membuka link yang dibuat dari deep linking
+	state, nonce = env.login(t)
+	launchResult, err := env.service.Launch(ctx, web.LTILaunchRequest{
+		IDToken: env.platform.launch(t, env.platform.key, nonce, items[0].Custom, nil),
+		State:   state,
+	})
+	if err != nil {
+		t.Fatalf("Launch of the linked exam: %v", err)
+	}
+	if launchResult.RedirectPath != "/student/take-exam/"+testLTIExamId {
+		t.Fatalf("redirect = %q", launchResult.RedirectPath)
+	}
+}
+
+func TestLTISyncDueScores(t *testing.T) {
+	env := newLTITestEnv(t)
+	score := 85
+	completedAt := time.Date(2026, time.March, 2, 8, 0, 0, 0, time.UTC)
+
+	sync := domain.LTIScoreSync{
+		Id:           "sync-1",
+		LTIUserId:    "platform-student-1",
+		LineItemURL:  env.platform.server.URL + "/lineitems/1",
+		Issuer:       env.platform.server.URL,
+		ClientId:     testLTIClientId,
+		AuthTokenURL: env.platform.server.URL + "/token",
+		Score:        &score,
+		CompletedAt:  &completedAt,
+	}
+	failing := sync
+	failing.Id = "sync-2"
+	failing.LineItemURL = env.platform.server.URL + "/lineitems/2"
+	env.ltiRepo.syncs = []domain.LTIScoreSync{sync, failing}
+
+	sent, err := env.service.SyncDueScores(context.Background())
+	if err != nil || sent != 2 {
+		t.Fatalf("SyncDueScores = %d, %v, want 2 without error", sent, err)
+	}
+
+	if len(env.platform.scores) != 1 || env.platform.scores[0].UserID != "platform-student-1" || env.platform.scores[0].ScoreGiven != 85 || env.platform.scores[0].ScoreMaximum != ltiScoreMaximum {
+		t.Fatalf("platform scores = %+v", env.platform.scores)
+	}
+	if env.ltiRepo.synced["sync-1"] != 85 {
+		t.Fatalf("sync-1 not marked synced: %v", env.ltiRepo.synced)
+	}
+	if env.ltiRepo.syncErrors["sync-2"] == "" {
+		t.Fatal("failed sync was not scheduled for a retry")
+	}
+}
@@ -0,0 +1,15 @@
+package service
+
+import (
+	"context"
+	"io"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+type MaterialService interface {
+	SaveMaterial(ctx context.Context, teacherId, fileName string, file io.Reader) (domain.Material, error)
+	GetMaterialsByTeacherId(ctx context.Context, teacherId string) ([]domain.Material, error)
+	GetMaterialById(ctx context.Context, teacherId, materialId string) (domain.Material, error)
+	DeleteMaterial(ctx context.Context, teacherId, materialId string) error
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/repository"
+	"github.com/mhaatha/go-template-saygenfix/internal/storage"
+)
+
+// MaxMaterialSize is the largest document accepted into the materials library
+const MaxMaterialSize = 20 << 20
+
+func NewMaterialService(materialRepository repository.MaterialRepository, db *pgxpool.Pool, fileStore storage.FileStore) MaterialService {
+	return &MaterialServiceImpl{
+		MaterialRepository: materialRepository,
+		DB:                 db,
+		FileStore:          fileStore,
+	}
+}
+
+type MaterialServiceImpl struct {
+	MaterialRepository repository.MaterialRepository
+	DB                 *pgxpool.Pool
+	FileStore          storage.FileStore
+}
+
+// SaveMaterial stores the document under its content hash. Uploading the same document
+// twice returns the material that is already in the teacher's library.
+func (service *MaterialServiceImpl) SaveMaterial(ctx context.Context, teacherId, fileName string, file io.Reader) (domain.Material, error) {
+	content, err := io.ReadAll(io.LimitReader(file, MaxMaterialSize+1))
+	if err != nil {
+		return domain.Material{}, fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	if len(content) > MaxMaterialSize {
+		return domain.Material{}, errors.New("file is larger than 20MB")
+	}
+	if http.DetectContentType(content) != "application/pdf" {
+		return domain.Material{}, errors.New("file is not a pdf document")
+	}
+
+	sum := sha256.Sum256(content)
+	contentHash := hex.EncodeToString(sum[:])
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.Material{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	existingMaterial, err := service.MaterialRepository.FindByTeacherIdAndHash(ctx, tx, teacherId, contentHash)
+	if err != nil {
+		return domain.Material{}, fmt.Errorf("failed when calling FindByTeacherIdAndHash repository: %w", err)
+	}
+	if existingMaterial.Id != "" {
+		return existingMaterial, nil
+	}
+
+	storageKey := "materials/" + teacherId + "/" + contentHash + ".pdf"
+	if err := service.FileStore.Save(ctx, storageKey, bytes.NewReader(content)); err != nil {
+		return domain.Material{}, fmt.Errorf("failed when saving file to store: %w", err)
+	}
+
+	material, err := service.MaterialRepository.Save(ctx, tx, domain.Material{
+		TeacherId:   teacherId,
+		FileName:    fileName,
+		ContentHash: contentHash,
+		SizeBytes:   int64(len(content)),
+		StorageKey:  storageKey,
+	})
+	if err != nil {
+		return domain.Material{}, fmt.Errorf("failed when calling Save repository: %w", err)
+	}
+
+	return material, nil
+}
+
+func (service *MaterialServiceImpl) GetMaterialsByTeacherId(ctx context.Context, teacherId string) ([]domain.Material, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	materials, err := service.MaterialRepository.FindByTeacherId(ctx, tx, teacherId)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindByTeacherId repository: %w", err)
+	}
+
+	return materials, nil
+}
+
+func (service *MaterialServiceImpl) GetMaterialById(ctx context.Context, teacherId, materialId string) (domain.Material, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.Material{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	material, err := service.MaterialRepository.FindById(ctx, tx, materialId)
+	if err != nil {
+		return domain.Material{}, fmt.Errorf("failed when calling FindById repository: %w", err)
+	}
+	if material.TeacherId != teacherId {
+		return domain.Material{}, errors.New("material does not belong to this teacher")
+	}
+
+	return material, nil
+}
+
+func (service *MaterialServiceImpl) DeleteMaterial(ctx context.Context, teacherId, materialId string) error {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	material, err := service.MaterialRepository.FindById(ctx, tx, materialId)
+	if err != nil {
+		return fmt.Errorf("failed when calling FindById repository: %w", err)
+	}
+	if material.TeacherId != teacherId {
+		return errors.New("material does not belong to this teacher")
+	}
+
+	err = service.MaterialRepository.Delete(ctx, tx, materialId)
+	if err != nil {
+		return fmt.Errorf("failed when calling Delete repository: %w", err)
+	}
+
+	// The row is gone either way, an orphaned file only wastes space
+	if err := service.FileStore.Delete(ctx, material.StorageKey); err != nil {
+		slog.Warn("failed to delete material file from store", "key", material.StorageKey, "err", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,14 @@
+package service
+
+import (
+	"context"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+)
+
+type OIDCService interface {
+	IsEnabled() bool
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	Login(ctx context.Context, request web.OIDCCallbackRequest) (string, domain.User, error)
+}
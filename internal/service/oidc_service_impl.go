@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mhaatha/go-template-saygenfix/internal/config"
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/oidc"
+	"github.com/mhaatha/go-template-saygenfix/internal/repository"
+)
+
+// oidcRoleNone as OIDC_DEFAULT_ROLE only lets in users that belong to a mapped group
+const oidcRoleNone = "none"
+
+var (
+	// ErrOIDCDisabled is returned when single sign-on is not configured
+	ErrOIDCDisabled = errors.New("single sign-on is not configured")
+	// ErrOIDCEmailNotVerified is returned when the identity provider did not verify the email,
+	// which is needed to link or create an account
+	ErrOIDCEmailNotVerified = errors.New("identity provider did not verify the email")
+	// ErrOIDCRoleNotAllowed is returned for new users outside the mapped groups when OIDC_DEFAULT_ROLE is "none"
+	ErrOIDCRoleNotAllowed = errors.New("identity provider groups do not map to a role")
+)
+
+func NewOIDCService(provider *oidc.Provider, userRepository repository.UserRepository, userIdentityRepository repository.UserIdentityRepository, adminRepository repository.AdminRepository, authRepository repository.AuthRepository, db *pgxpool.Pool, validate *validator.Validate, cfg *config.Config) OIDCService {
+	return &OIDCServiceImpl{
+		Provider:               provider,
+		UserRepository:         userRepository,
+		UserIdentityRepository: userIdentityRepository,
+		AdminRepository:        adminRepository,
+		AuthRepository:         authRepository,
+		DB:                     db,
+		Validate:               validate,
+		Config:                 cfg,
+	}
+}
+
+type OIDCServiceImpl struct {
+	// Provider is nil when single sign-on is not configured
+	Provider               *oidc.Provider
+	UserRepository         repository.UserRepository
+	UserIdentityRepository repository.UserIdentityRepository
+	AdminRepository        repository.AdminRepository
+	AuthRepository         repository.AuthRepository
+	DB                     *pgxpool.Pool
+	Validate               *validator.Validate
+	Config                 *config.Config
+}
+
+func (service *OIDCServiceImpl) IsEnabled() bool {
+	return service.Provider != nil
+}
+
+func (service *OIDCServiceImpl) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	if service.Provider == nil {
+		return "", ErrOIDCDisabled
+	}
+
+	authCodeURL, err := service.Provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", fmt.Errorf("failed when calling AuthCodeURL provider: %w", err)
+	}
+
+	return authCodeURL, nil
+}
+
+// Login redeems the code and signs in the user linked to the identity. Unlinked identities are
+// linked by verified email or get a new, already approved account with the role mapped from
+// the groups claim. Users with 2FA get ErrTwoFactorRequired and no session, like the form login.
+func (service *OIDCServiceImpl) Login(ctx context.Context, request web.OIDCCallbackRequest) (string, domain.User, error) {
+	if service.Provider == nil {
+		return "", domain.User{}, ErrOIDCDisabled
+	}
+
+	// Validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return "", domain.User{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	claims, err := service.Provider.Exchange(ctx, request.Code, request.Verifier, request.Nonce)
+	if err != nil {
+		return "", domain.User{}, fmt.Errorf("failed when calling Exchange provider: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return "", domain.User{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	mappedRole := service.mapRole(claims)
+
+	user, err := service.findOrCreateUser(ctx, tx, claims, mappedRole)
+	if err != nil {
+		return "", domain.User{}, err
+	}
+
+	if user.IsDisabled {
+		return "", domain.User{}, ErrAccountDisabled
+	}
+	if !user.IsApproved {
+		return "", domain.User{}, ErrAccountPending
+	}
+
+	// Role hanya diperbarui jika grup di IdP dipetakan secara eksplisit, role default tidak menurunkan role yang diatur admin
+	if mappedRole != "" && mappedRole != user.Role {
+		if err := service.AdminRepository.UpdateRole(ctx, tx, user.Id, mappedRole); err != nil {
+			return "", domain.User{}, fmt.Errorf("failed when calling UpdateRole repository: %w", err)
+		}
+		user.Role = mappedRole
+	}
+
+	if user.TwoFactorEnabled {
+		return "", user, ErrTwoFactorRequired
+	}
+
+	// Save session to db
+	session, err := service.AuthRepository.Save(ctx, tx, domain.Session{
+		SessionId: helper.Base64SessionId(),
+		UserId:    user.Id,
+		UserAgent: truncateUserAgent(request.UserAgent),
+		IPAddress: request.IPAddress,
+	})
+	if err != nil {
+		return "", domain.User{}, fmt.Errorf("failed when calling Save repository: %w", err)
+	}
+
+	if err := service.AuthRepository.SaveLoginAttempt(ctx, tx, domain.LoginAttempt{
+		Email:     normalizeLoginEmail(user.Email),
+		IPAddress: request.IPAddress,
+		IsSuccess: true,
+	}); err != nil {
+		return "", domain.User{}, fmt.Errorf("failed when calling SaveLoginAttempt repository: %w", err)
+	}
+
+	return session.SessionId, user, nil
+}
+
+// findOrCreateUser resolves the identity by issuer and subject first, so a changed email at the
+// identity provider still reaches the same account
+func (service *OIDCServiceImpl) findOrCreateUser(ctx context.Context, tx pgx.Tx, claims oidc.Claims, mappedRole string) (domain.User, error) {
+	identity, err := service.UserIdentityRepository.FindByIssuerAndSubject(ctx, tx, claims.Issuer, claims.Subject)
+	if err == nil {
+		if err := service.UserIdentityRepository.TouchLastLogin(ctx, tx, identity.Id, claims.Email); err != nil {
+			return domain.User{}, fmt.Errorf("failed when calling TouchLastLogin repository: %w", err)
+		}
+
+		user, err := service.UserRepository.FindById(ctx, tx, identity.UserId)
+		if err != nil {
+			return domain.User{}, fmt.Errorf("failed when calling FindById repository: %w", err)
+		}
+
+		return user, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return domain.User{}, fmt.Errorf("failed when calling FindByIssuerAndSubject repository: %w", err)
+	}
+
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	if email == "" || !claims.EmailVerified {
+		return domain.User{}, ErrOIDCEmailNotVerified
+	}
+
+	user, err := service.UserRepository.FindByEmail(ctx, tx, email)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed when calling FindByEmail repository: %w", err)
+	}
+
+	// Email belum terdaftar, buat akun baru dengan role dari grup IdP
+	if user.Id == "" {
+		user, err = service.createUser(ctx, tx, claims, email, mappedRole)
+		if err != nil {
+			return domain.User{}, err
+		}
+	} else if !user.IsEmailVerified {
+		// Akun lokal belum membuktikan email ini, bisa jadi didaftarkan orang lain.
+		// Password dan sesi pendaftar diganti sebelum akun diserahkan ke pemilik email.
+		if err := service.revokeLocalAccess(ctx, tx, user.Id); err != nil {
+			return domain.User{}, err
+		}
+	}
+
+	if _, err := service.UserIdentityRepository.Save(ctx, tx, domain.UserIdentity{
+		UserId:  user.Id,
+		Issuer:  claims.Issuer,
+		Subject: claims.Subject,
+		Email:   email,
+	}); err != nil {
+		return domain.User{}, fmt.Errorf("failed when calling Save repository: %w", err)
+	}
+
+	// IdP sudah memverifikasi email ini
+	if err := service.UserRepository.MarkEmailVerified(ctx, tx, user.Id); err != nil {
+		return domain.User{}, fmt.Errorf("failed when calling MarkEmailVerified repository: %w", err)
+	}
+	user.IsEmailVerified = true
+
+	return user, nil
+}
+
+// createUser registers an approved account with a random password the user never sees, they
+// can still set one later through the forgot password page
+func (service *OIDCServiceImpl) createUser(ctx context.Context, tx pgx.Tx, claims oidc.Claims, email, mappedRole string) (domain.User, error) {
+	role := mappedRole
+	if role == "" {
+		role = service.Config.OIDCDefaultRole
+	}
+	if role == oidcRoleNone || !slices.Contains([]string{"student", "teacher", "admin"}, role) {
+		return domain.User{}, ErrOIDCRoleNotAllowed
+	}
+
+	fullName := strings.TrimSpace(claims.Name)
+	if fullName == "" {
+		fullName, _, _ = strings.Cut(email, "@")
+	}
+	if len(fullName) > 255 {
+		fullName = fullName[:255]
+	}
+
+	hashedPassword, err := randomPasswordHash()
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	user, err := service.UserRepository.Save(ctx, tx, domain.User{
+		Email:      email,
+		FullName:   fullName,
+		Password:   hashedPassword,
+		Role:       role,
+		IsApproved: true,
+	})
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed when calling Save repository: %w", err)
+	}
+
+	return user, nil
+}
+
+// revokeLocalAccess replaces the password with a random one and logs out every session of an
+// account whose email was never verified, so whoever registered it loses access once the
+// identity provider proves the email belongs to someone else
+func (service *OIDCServiceImpl) revokeLocalAccess(ctx context.Context, tx pgx.Tx, userId string) error {
+	hashedPassword, err := randomPasswordHash()
+	if err != nil {
+		return err
+	}
+
+	if err := service.UserRepository.UpdatePassword(ctx, tx, userId, hashedPassword); err != nil {
+		return fmt.Errorf("failed when calling UpdatePassword repository: %w", err)
+	}
+
+	if err := service.AuthRepository.DeleteByUserId(ctx, tx, userId); err != nil {
+		return fmt.Errorf("failed when calling DeleteByUserId repository: %w", err)
+	}
+
+	return nil
+}
+
+// randomPasswordHash returns the hash of a random password nobody knows
+func randomPasswordHash() (string, error) {
+	randomPassword, err := helper.GenerateToken()
+	if err != nil {
+		return "", fmt.Errorf("failed when calling GenerateToken helper: %w", err)
+	}
+
+	hashedPassword, err := helper.HashPassword(randomPassword)
+	if err != nil {
+		return "", fmt.Errorf("failed when calling HashPassword: %w", err)
+	}
+
+	return hashedPassword, nil
+}
+
+// mapRole returns the role of the first configured group found in the role claim, admin
+// groups win over teacher groups. An empty result means no group matched.
+func (service *OIDCServiceImpl) mapRole(claims oidc.Claims) string {
+	groups := claims.Strings(service.Config.OIDCRoleClaim)
+
+	for _, group := range groups {
+		if slices.Contains(service.Config.OIDCAdminGroups, group) {
+			return "admin"
+		}
+	}
+	for _, group := range groups {
+		if slices.Contains(service.Config.OIDCTeacherGroups, group) {
+			return "teacher"
+		}
+	}
+
+	return ""
+}
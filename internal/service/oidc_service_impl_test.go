@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/config"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/oidc"
+)
+
+func newTestOIDCService(users *fakeUserRepository, identities *fakeUserIdentityRepository, defaultRole string) *OIDCServiceImpl {
+	return &OIDCServiceImpl{
+		UserRepository:         users,
+		UserIdentityRepository: identities,
+		Config: &config.Config{
+			OIDCRoleClaim:     "groups",
+			OIDCAdminGroups:   []string{"IT Sekolah"},
+			OIDCTeacherGroups: []string{"Guru", "Wali Kelas"},
+			OIDCDefaultRole:   defaultRole,
+		},
+	}
+}
+
+func TestOIDCMapRole(t *testing.T) {
+	service := newTestOIDCService(nil, nil, "student")
+
+	tests := []struct {
+		name   string
+		groups any
+		want   string
+	}{
+		{"teacher group", []any{"staf", "Guru"}, "teacher"},
+		{"admin wins over teacher", []any{"Guru", "IT Sekolah"}, "admin"},
+		{"single string group", "Wali Kelas", "teacher"},
+		{"single string is not split", "Guru, Wali Kelas", ""},
+		{"no mapped group", []any{"Siswa"}, ""},
+		{"no groups claim", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := oidc.Claims{Raw: map[string]any{}}
+			if tt.groups != nil {
+				claims.Raw["groups"] = tt.groups
+			}
+
+			if got := service.mapRole(claims); got != tt.want {
+				t.Fatalf("mapRole = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOIDCFindOrCreateUser(t *testing.T) {
+	ctx := context.Background()
+	claims := oidc.Claims{
+		Issuer:        "https://idp.sekolah.sch.id",
+		Subject:       "sub-1",
+		Email:         "Guru@Sekolah.sch.id",
+		EmailVerified: true,
+		Name:          "Bu Guru",
+	}
+
+	t.Run("unverified email is refused", func(t *testing.T) {
+		users := newFakeUserRepository()
+		service := newTestOIDCService(users, &fakeUserIdentityRepository{}, "student")
+
+		unverified := claims
+		unverified.EmailVerified = false
+		if _, err := service.findOrCreateUser(ctx, nil, unverified, "teacher"); !errors.Is(err, ErrOIDCEmailNotVerified) {
+			t.Fatalf("findOrCreateUser = %v, want ErrOIDCEmailNotVerified", err)
+		}
+		if len(users.users) != 0 {
+			t.Fatal("an account was created for an unverified email")
+		}
+	})
+
+	t.Run("new user gets the mapped role", func(t *testing.T) {
+		users := newFakeUserRepository()
+		identities := &fakeUserIdentityRepository{}
+		service := newTestOIDCService(users, identities, "student")
+
+		user, err := service.findOrCreateUser(ctx, nil, claims, "teacher")
+		if err != nil {
+			t.Fatalf("findOrCreateUser: %v", err)
+		}
+		if user.Email != "guru@sekolah.sch.id" || user.Role != "teacher" || !user.IsApproved || user.FullName != "Bu Guru" {
+			t.Fatalf("unexpected user %+v", user)
+		}
+		if len(identities.identities) != 1 || identities.identities[0].UserId != user.Id {
+			t.Fatalf("identity not linked: %+v", identities.identities)
+		}
+	})
+
+	t.Run("default role none refuses unmapped users", func(t *testing.T) {
+		users := newFakeUserRepository()
+		service := newTestOIDCService(users, &fakeUserIdentityRepository{}, oidcRoleNone)
+
+		if _, err := service.findOrCreateUser(ctx, nil, claims, ""); !errors.Is(err, ErrOIDCRoleNotAllowed) {
+			t.Fatalf("findOrCreateUser = %v, want ErrOIDCRoleNotAllowed", err)
+		}
+	})
+
+	t.Run("linked identity is found by subject", func(t *testing.T) {
+		users := newFakeUserRepository(domain.User{Id: "user-1", Email: "lama@sekolah.sch.id", Role: "student"})
+		identities := &fakeUserIdentityRepository{identities: []domain.UserIdentity{
+			{Id: "identity-1", UserId: "user-1", Issuer: claims.Issuer, Subject: claims.Subject},
+		}}
+		service := newTestOIDCService(users, identities, "student")
+
+		// Email di IdP sudah berubah dan belum diverifikasi, identitas tetap dikenali dari subject
+		changed := claims
+		changed.EmailVerified = false
+		user, err := service.findOrCreateUser(ctx, nil, changed, "")
+		if err != nil {
+			t.Fatalf("findOrCreateUser: %v", err)
+		}
+		if user.Id != "user-1" {
+			t.Fatalf("user = %+v, want user-1", user)
+		}
+	})
+
+	t.Run("verified local account is linked by email", func(t *testing.T) {
+		users := newFakeUserRepository(domain.User{Id: "user-1", Email: "guru@sekolah.sch.id", Password: "hash-lama", Role: "teacher", IsEmailVerified: true})
+		identities := &fakeUserIdentityRepository{}
+		sessions := &fakeAuthRepository{sessions: []domain.Session{{SessionId: "session-1", UserId: "user-1"}}}
+		service := newTestOIDCService(users, identities, "student")
+		service.AuthRepository = sessions
+
+		user, err := service.findOrCreateUser(ctx, nil, claims, "")
+		if err != nil {
+			t.Fatalf("findOrCreateUser: %v", err)
+		}
+		if user.Id != "user-1" || user.Role != "teacher" {
+			t.Fatalf("user = %+v, want user-1 as teacher", user)
+		}
+		if len(identities.identities) != 1 || identities.identities[0].UserId != "user-1" {
+			t.Fatalf("identity not linked: %+v", identities.identities)
+		}
+		// Akun yang sudah terverifikasi milik pemilik email, password dan sesinya tetap
+		if users.users["user-1"].Password != "hash-lama" || len(sessions.sessions) != 1 {
+			t.Fatal("password or sessions of a verified account were revoked")
+		}
+	})
+
+	t.Run("unverified local account loses its password and sessions", func(t *testing.T) {
+		users := newFakeUserRepository(domain.User{Id: "user-1", Email: "guru@sekolah.sch.id", Password: "hash-pendaftar", Role: "student"})
+		identities := &fakeUserIdentityRepository{}
+		sessions := &fakeAuthRepository{sessions: []domain.Session{
+			{SessionId: "session-1", UserId: "user-1"},
+			{SessionId: "session-2", UserId: "user-2"},
+		}}
+		service := newTestOIDCService(users, identities, "student")
+		service.AuthRepository = sessions
+
+		user, err := service.findOrCreateUser(ctx, nil, claims, "")
+		if err != nil {
+			t.Fatalf("findOrCreateUser: %v", err)
+		}
+		if user.Id != "user-1" || !user.IsEmailVerified {
+			t.Fatalf("user = %+v, want verified user-1", user)
+		}
+		if len(identities.identities) != 1 || identities.identities[0].UserId != "user-1" {
+			t.Fatalf("identity not linked: %+v", identities.identities)
+		}
+		if users.users["user-1"].Password == "hash-pendaftar" {
+			t.Fatal("password chosen at registration still works")
+		}
+		if len(sessions.sessions) != 1 || sessions.sessions[0].UserId != "user-2" {
+			t.Fatalf("sessions = %+v, want only the other user's session", sessions.sessions)
+		}
+	})
+}
@@ -0,0 +1,13 @@
+package service
+
+import (
+	"context"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+)
+
+type PasswordResetService interface {
+	RequestReset(ctx context.Context, request web.ForgotPasswordRequest) error
+	CheckToken(ctx context.Context, token string) error
+	ResetPassword(ctx context.Context, request web.ResetPasswordRequest) error
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mhaatha/go-template-saygenfix/internal/config"
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/mail"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/repository"
+)
+
+const (
+	// passwordResetTTL is how long a reset link stays valid
+	passwordResetTTL = time.Hour
+	// passwordResetRequestWindow is the period the request limits below apply to
+	passwordResetRequestWindow = time.Hour
+	// passwordResetMaxPerEmail limits the reset emails one address can receive
+	passwordResetMaxPerEmail = 3
+	// passwordResetMaxPerIP limits the requests from one IP, whichever emails they are for
+	passwordResetMaxPerIP = 10
+)
+
+var (
+	// ErrResetTokenInvalid is returned when the reset token is unknown, expired or already used
+	ErrResetTokenInvalid = errors.New("password reset token is invalid")
+	// ErrResetThrottled is returned when an email or IP asked for too many reset links
+	ErrResetThrottled = errors.New("too many password reset requests")
+)
+
+func NewPasswordResetService(passwordResetRepository repository.PasswordResetRepository, userRepository repository.UserRepository, authRepository repository.AuthRepository, mailer mail.Mailer, db *pgxpool.Pool, validate *validator.Validate, cfg *config.Config) PasswordResetService {
+	return &PasswordResetServiceImpl{
+		PasswordResetRepository: passwordResetRepository,
+		UserRepository:          userRepository,
+		AuthRepository:          authRepository,
+		Mailer:                  mailer,
+		DB:                      db,
+		Validate:                validate,
+		Cfg:                     cfg,
+	}
+}
+
+type PasswordResetServiceImpl struct {
+	PasswordResetRepository repository.PasswordResetRepository
+	UserRepository          repository.UserRepository
+	AuthRepository          repository.AuthRepository
+	Mailer                  mail.Mailer
+	DB                      *pgxpool.Pool
+	Validate                *validator.Validate
+	Cfg                     *config.Config
+}
+
+// RequestReset emails a reset link. Unknown and disabled accounts are ignored without an error
+// so the form does not reveal which emails are registered. Requests are limited per email and
+// per IP, for registered and unknown emails alike.
+func (service *PasswordResetServiceImpl) RequestReset(ctx context.Context, request web.ForgotPasswordRequest) error {
+	request.Email = strings.TrimSpace(request.Email)
+
+	// Validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	user, token, err := service.saveResetToken(ctx, request)
+	if err != nil {
+		return err
+	}
+	if user.Id == "" {
+		return nil
+	}
+
+	// Email dikirim setelah commit, sehingga koneksi database tidak tertahan selama SMTP dan
+	// link yang dikirim pasti sudah tersimpan
+	resetLink := service.Cfg.AppBaseURL + "/reset-password?token=" + url.QueryEscape(token)
+	if err := service.Mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Atur ulang password SayGenFix",
+		Body: fmt.Sprintf(
+			"Halo %s,\n\nKami menerima permintaan untuk mengatur ulang password akun SayGenFix Anda. Buka link berikut untuk membuat password baru:\n\n%s\n\nLink ini berlaku selama 1 jam dan hanya bisa dipakai sekali. Abaikan email ini jika Anda tidak meminta reset password.\n",
+			user.FullName,
+			resetLink,
+		),
+	}); err != nil {
+		return fmt.Errorf("failed when calling Send mailer: %w", err)
+	}
+
+	return nil
+}
+
+// saveResetToken records the request and stores a new token. The returned user is empty when
+// no email should be sent.
+func (service *PasswordResetServiceImpl) saveResetToken(ctx context.Context, request web.ForgotPasswordRequest) (domain.User, string, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.User{}, "", fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	email := strings.ToLower(request.Email)
+
+	ipRequests, err := service.PasswordResetRepository.CountRequestsByIP(ctx, tx, request.IPAddress, passwordResetRequestWindow)
+	if err != nil {
+		return domain.User{}, "", fmt.Errorf("failed when calling CountRequestsByIP repository: %w", err)
+	}
+	emailRequests, err := service.PasswordResetRepository.CountRequestsByEmail(ctx, tx, email, passwordResetRequestWindow)
+	if err != nil {
+		return domain.User{}, "", fmt.Errorf("failed when calling CountRequestsByEmail repository: %w", err)
+	}
+	if ipRequests >= passwordResetMaxPerIP || emailRequests >= passwordResetMaxPerEmail {
+		return domain.User{}, "", ErrResetThrottled
+	}
+
+	if err := service.PasswordResetRepository.SaveRequest(ctx, tx, email, request.IPAddress); err != nil {
+		return domain.User{}, "", fmt.Errorf("failed when calling SaveRequest repository: %w", err)
+	}
+
+	user, err := service.UserRepository.FindByEmail(ctx, tx, request.Email)
+	if err != nil {
+		return domain.User{}, "", fmt.Errorf("failed when calling FindByEmail repository: %w", err)
+	}
+	if user.Id == "" || user.IsDisabled {
+		return domain.User{}, "", nil
+	}
+
+	token, err := helper.GenerateToken()
+	if err != nil {
+		return domain.User{}, "", fmt.Errorf("failed when calling GenerateToken helper: %w", err)
+	}
+
+	// Link lama tidak berlaku lagi setelah link baru dibuat
+	if err := service.PasswordResetRepository.DeleteUnusedByUserId(ctx, tx, user.Id); err != nil {
+		return domain.User{}, "", fmt.Errorf("failed when calling DeleteUnusedByUserId repository: %w", err)
+	}
+
+	if _, err := service.PasswordResetRepository.Save(ctx, tx, domain.PasswordResetToken{
+		UserId:    user.Id,
+		TokenHash: helper.HashToken(token),
+	}, passwordResetTTL); err != nil {
+		return domain.User{}, "", fmt.Errorf("failed when calling Save repository: %w", err)
+	}
+
+	return user, token, nil
+}
+
+// CheckToken lets the reset form tell the user early that the link is no longer valid
+func (service *PasswordResetServiceImpl) CheckToken(ctx context.Context, token string) error {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if _, err := service.findValidToken(ctx, tx, token); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ResetPassword sets the new password, uses up the token and logs the user out of every device
+func (service *PasswordResetServiceImpl) ResetPassword(ctx context.Context, request web.ResetPasswordRequest) error {
+	// Validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	resetToken, err := service.findValidToken(ctx, tx, request.Token)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := helper.HashPassword(request.Password)
+	if err != nil {
+		return fmt.Errorf("failed when calling HashPassword: %w", err)
+	}
+
+	if err := service.UserRepository.UpdatePassword(ctx, tx, resetToken.UserId, hashedPassword); err != nil {
+		return fmt.Errorf("failed when calling UpdatePassword repository: %w", err)
+	}
+
+	if err := service.PasswordResetRepository.MarkUsed(ctx, tx, resetToken.Id); err != nil {
+		return fmt.Errorf("failed when calling MarkUsed repository: %w", err)
+	}
+
+	if err := service.PasswordResetRepository.DeleteUnusedByUserId(ctx, tx, resetToken.UserId); err != nil {
+		return fmt.Errorf("failed when calling DeleteUnusedByUserId repository: %w", err)
+	}
+
+	if err := service.AuthRepository.DeleteByUserId(ctx, tx, resetToken.UserId); err != nil {
+		return fmt.Errorf("failed when calling DeleteByUserId repository: %w", err)
+	}
+
+	return nil
+}
+
+func (service *PasswordResetServiceImpl) findValidToken(ctx context.Context, tx pgx.Tx, token string) (domain.PasswordResetToken, error) {
+	if token == "" {
+		return domain.PasswordResetToken{}, ErrResetTokenInvalid
+	}
+
+	resetToken, err := service.PasswordResetRepository.FindValidByTokenHash(ctx, tx, helper.HashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.PasswordResetToken{}, ErrResetTokenInvalid
+		}
+
+		return domain.PasswordResetToken{}, fmt.Errorf("failed when calling FindValidByTokenHash repository: %w", err)
+	}
+
+	return resetToken, nil
+}
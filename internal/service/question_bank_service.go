@@ -0,0 +1,18 @@
+package service
+
+import (
+	"context"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+)
+
+type QuestionBankService interface {
+	GetQuestionBank(ctx context.Context, teacherId, tag, topic string) ([]domain.BankQuestion, []domain.BankTag, []string, error)
+	CreateBankQuestion(ctx context.Context, teacherId string, request web.BankQuestionCreateRequest) (domain.BankQuestion, error)
+	SaveExamQuestionToBank(ctx context.Context, teacherId, questionId, topic string, tags []string) (domain.BankQuestion, error)
+	UpdateBankQuestion(ctx context.Context, teacherId, bankQuestionId string, request web.BankQuestionCreateRequest) (domain.BankQuestion, error)
+	DeleteBankQuestion(ctx context.Context, teacherId, bankQuestionId string) error
+
+	ComposeExam(ctx context.Context, teacherId string, request web.ComposeExamRequest) (string, error)
+}
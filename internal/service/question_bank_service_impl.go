@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/repository"
+)
+
+// ErrBankQuestionInUse is returned when deleting a bank question that an exam still refers to
+var ErrBankQuestionInUse = errors.New("bank question is still used by an exam")
+
+func NewQuestionBankService(questionBankRepository repository.QuestionBankRepository, teacherRepository repository.TeacherRepository, db *pgxpool.Pool, validate *validator.Validate) QuestionBankService {
+	return &QuestionBankServiceImpl{
+		QuestionBankRepository: questionBankRepository,
+		TeacherRepository:      teacherRepository,
+		DB:                     db,
+		Validate:               validate,
+	}
+}
+
+type QuestionBankServiceImpl struct {
+	QuestionBankRepository repository.QuestionBankRepository
+	TeacherRepository      repository.TeacherRepository
+	DB                     *pgxpool.Pool
+	Validate               *validator.Validate
+}
+
+func (service *QuestionBankServiceImpl) GetQuestionBank(ctx context.Context, teacherId, tag, topic string) ([]domain.BankQuestion, []domain.BankTag, []string, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	questions, err := service.QuestionBankRepository.FindByTeacherId(ctx, tx, teacherId, tag, topic)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed when calling FindByTeacherId repository: %w", err)
+	}
+
+	tags, err := service.QuestionBankRepository.FindTagsByTeacherId(ctx, tx, teacherId)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed when calling FindTagsByTeacherId repository: %w", err)
+	}
+
+	topics, err := service.QuestionBankRepository.FindTopicsByTeacherId(ctx, tx, teacherId)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed when calling FindTopicsByTeacherId repository: %w", err)
+	}
+
+	return questions, tags, topics, nil
+}
+
+func (service *QuestionBankServiceImpl) CreateBankQuestion(ctx context.Context, teacherId string, request web.BankQuestionCreateRequest) (domain.BankQuestion, error) {
+	// Validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return domain.BankQuestion{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.BankQuestion{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	bankQuestion, err := service.QuestionBankRepository.Save(ctx, tx, domain.BankQuestion{
+		TeacherId: teacherId,
+		Question:  request.Question,
+		Answer:    request.Answer,
+		Topic:     request.Topic,
+		Tags:      request.Tags,
+	})
+	if err != nil {
+		return domain.BankQuestion{}, fmt.Errorf("failed when calling Save repository: %w", err)
+	}
+
+	return bankQuestion, nil
+}
+
+// SaveExamQuestionToBank moves the text of an exam question into the bank and leaves
+// a reference behind, so the exam keeps showing the same question. Only the exam owner can do
+// this, the bank question then belongs to the same teacher as the exam referring to it.
+func (service *QuestionBankServiceImpl) SaveExamQuestionToBank(ctx context.Context, teacherId, questionId, topic string, tags []string) (domain.BankQuestion, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.BankQuestion{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	question, err := service.TeacherRepository.FindQuestionById(ctx, tx, questionId)
+	if err != nil {
+		return domain.BankQuestion{}, fmt.Errorf("failed when calling FindQuestionById repository: %w", err)
+	}
+
+	role, err := service.TeacherRepository.FindExamRole(ctx, tx, question.ExamId, teacherId)
+	if err != nil {
+		return domain.BankQuestion{}, fmt.Errorf("failed when calling FindExamRole repository: %w", err)
+	}
+	if !hasExamRole(role, domain.ExamRoleOwner) {
+		return domain.BankQuestion{}, ErrExamForbidden
+	}
+
+	// Soal sudah ada di bank, tidak perlu disimpan lagi
+	if question.BankQuestionId != "" {
+		bankQuestion, err := service.QuestionBankRepository.FindById(ctx, tx, question.BankQuestionId)
+		if err != nil {
+			return domain.BankQuestion{}, fmt.Errorf("failed when calling FindById repository: %w", err)
+		}
+
+		return bankQuestion, nil
+	}
+
+	request := web.BankQuestionCreateRequest{
+		Question: question.Question,
+		Answer:   question.Answer,
+		Topic:    topic,
+		Tags:     tags,
+	}
+	if err := service.Validate.Struct(request); err != nil {
+		return domain.BankQuestion{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	bankQuestion, err := service.QuestionBankRepository.Save(ctx, tx, domain.BankQuestion{
+		TeacherId: teacherId,
+		Question:  request.Question,
+		Answer:    request.Answer,
+		Topic:     request.Topic,
+		Tags:      request.Tags,
+	})
+	if err != nil {
+		return domain.BankQuestion{}, fmt.Errorf("failed when calling Save repository: %w", err)
+	}
+
+	err = service.QuestionBankRepository.LinkQuestion(ctx, tx, questionId, bankQuestion.Id)
+	if err != nil {
+		return domain.BankQuestion{}, fmt.Errorf("failed when calling LinkQuestion repository: %w", err)
+	}
+	bankQuestion.UsageCount = 1
+
+	return bankQuestion, nil
+}
+
+// UpdateBankQuestion changes a bank question of the teacher, every exam referring to it shows the new text
+func (service *QuestionBankServiceImpl) UpdateBankQuestion(ctx context.Context, teacherId, bankQuestionId string, request web.BankQuestionCreateRequest) (domain.BankQuestion, error) {
+	// Validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return domain.BankQuestion{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.BankQuestion{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	bankQuestion, err := service.QuestionBankRepository.FindById(ctx, tx, bankQuestionId)
+	if err != nil {
+		return domain.BankQuestion{}, fmt.Errorf("failed when calling FindById repository: %w", err)
+	}
+	if bankQuestion.TeacherId != teacherId {
+		return domain.BankQuestion{}, errors.New("bank question does not belong to this teacher")
+	}
+
+	bankQuestion.Question = request.Question
+	bankQuestion.Answer = request.Answer
+	bankQuestion.Topic = request.Topic
+	bankQuestion.Tags = request.Tags
+
+	err = service.QuestionBankRepository.Update(ctx, tx, bankQuestion)
+	if err != nil {
+		return domain.BankQuestion{}, fmt.Errorf("failed when calling Update repository: %w", err)
+	}
+
+	return bankQuestion, nil
+}
+
+func (service *QuestionBankServiceImpl) DeleteBankQuestion(ctx context.Context, teacherId, bankQuestionId string) error {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	bankQuestion, err := service.QuestionBankRepository.FindById(ctx, tx, bankQuestionId)
+	if err != nil {
+		return fmt.Errorf("failed when calling FindById repository: %w", err)
+	}
+	if bankQuestion.TeacherId != teacherId {
+		return errors.New("bank question does not belong to this teacher")
+	}
+	if bankQuestion.UsageCount > 0 {
+		return ErrBankQuestionInUse
+	}
+
+	err = service.QuestionBankRepository.Delete(ctx, tx, bankQuestionId)
+	if err != nil {
+		return fmt.Errorf("failed when calling Delete repository: %w", err)
+	}
+
+	return nil
+}
+
+// ComposeExam creates an exam that refers to the picked bank questions followed by
+// a random draw for every tag in TagDraws. A question is never added twice.
+func (service *QuestionBankServiceImpl) ComposeExam(ctx context.Context, teacherId string, request web.ComposeExamRequest) (string, error) {
+	// Validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	selectedIds := []string{}
+	selected := map[string]bool{}
+	for _, bankQuestionId := range request.BankQuestionIds {
+		if selected[bankQuestionId] {
+			continue
+		}
+
+		bankQuestion, err := service.QuestionBankRepository.FindById(ctx, tx, bankQuestionId)
+		if err != nil {
+			return "", fmt.Errorf("failed when calling FindById repository: %w", err)
+		}
+		if bankQuestion.TeacherId != teacherId {
+			return "", errors.New("bank question does not belong to this teacher")
+		}
+
+		selectedIds = append(selectedIds, bankQuestionId)
+		selected[bankQuestionId] = true
+	}
+
+	for tag, count := range request.TagDraws {
+		if count == 0 {
+			continue
+		}
+
+		drawnIds, err := service.QuestionBankRepository.FindRandomIdsByTag(ctx, tx, teacherId, tag, count, selectedIds)
+		if err != nil {
+			return "", fmt.Errorf("failed when calling FindRandomIdsByTag repository: %w", err)
+		}
+		if len(drawnIds) < count {
+			return "", fmt.Errorf("tag %q only has %d unused questions, %d requested", tag, len(drawnIds), count)
+		}
+
+		selectedIds = append(selectedIds, drawnIds...)
+	}
+
+	if len(selectedIds) == 0 {
+		return "", errors.New("exam must contain at least one question")
+	}
+
+	examId := "EXAM-" + uuid.NewString()[:8]
+	err = service.TeacherRepository.SaveExam(ctx, tx, domain.Exam{
+		RoomName: request.RoomName,
+		Year:     request.Year,
+		Duration: request.Duration,
+	}, teacherId, examId)
+	if err != nil {
+		return "", fmt.Errorf("failed when calling SaveExam repository: %w", err)
+	}
+
+	err = service.QuestionBankRepository.BulkSaveReferences(ctx, tx, selectedIds, examId)
+	if err != nil {
+		return "", fmt.Errorf("failed when calling BulkSaveReferences repository: %w", err)
+	}
+
+	return examId, nil
+}
@@ -8,20 +8,32 @@ import (
 )
 
 type StudentService interface {
-	GetActiveExams(ctx context.Context) ([]domain.Exam, error)
+	GetActiveExams(ctx context.Context, studentId string) ([]domain.Exam, error)
+	GetPublicExams(ctx context.Context, studentId string) ([]domain.Exam, error)
+	JoinExamByCode(ctx context.Context, studentId, joinCode string) (domain.Exam, error)
+	JoinPublicExam(ctx context.Context, studentId, examId string) error
+	JoinClassByCode(ctx context.Context, studentId, joinCode string) (domain.Class, error)
+	GetClasses(ctx context.Context, studentId string) ([]domain.Class, error)
 	GetTeacherById(ctx context.Context, teacherId string) (domain.User, error)
 	GetExamById(ctx context.Context, examId string) (domain.Exam, error)
 	GetQuestionsByExamId(ctx context.Context, examId string) ([]domain.QAItem, error)
 
 	CreateExamAttempt(ctx context.Context, studentId, examId string) (string, error)
+	GetAttemptQuestions(ctx context.Context, attemptId string) ([]domain.QAItem, error)
 	SaveAnswer(ctx context.Context, answer web.StudentAnswer) error
 	CompleteExamAttempt(ctx context.Context, attemptId string) error
+	// Attempts of the JSON API, addressed by id so the owner is checked on every call
+	GetAttempt(ctx context.Context, studentId, attemptId string) (web.ExamAttempt, error)
+	SaveAttemptAnswer(ctx context.Context, studentId string, request web.AttemptAnswerRequest) error
+	SubmitAttempt(ctx context.Context, studentId, attemptId string) (web.ExamAttempt, error)
 
 	GetExamByAttempId(ctx context.Context, attemptId string) (domain.Exam, error)
 	GetAnswersByAttemptId(ctx context.Context, attemptId string) ([]web.StudentAnswer, error)
 
 	GetExamAttemptsByExamIdAndStudentId(ctx context.Context, userId string, examId string) ([]web.ExamAttempt, error)
 	CalculateScore(ctx context.Context, attemptId string) ([]domain.EssayCorrection, error)
+	// RegradeExamAttempts scores every submitted attempt again, the caller checks the teacher may grade the exam
+	RegradeExamAttempts(ctx context.Context, examId string) (int, error)
 
 	GetBiggestExamAttemptsByStudentId(ctx context.Context, userId string) ([]web.ExamAttemptsCustom, error)
 	GetExamsWithScoreAndTeacherNameByExamId(ctx context.Context, examAttempts []web.ExamAttemptsCustom) ([]web.ExamWithScoreAndTeacherName, error)
@@ -4,23 +4,51 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"math/rand/v2"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/mhaatha/go-template-saygenfix/internal/config"
 	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/api"
 	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
 	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
 	"github.com/mhaatha/go-template-saygenfix/internal/repository"
 )
 
-func NewStudentService(studentRepository repository.StudentRepository, db *pgxpool.Pool, validate *validator.Validate, cfg *config.Config) *StudentServiceImpl {
+var (
+	// ErrJoinCodeNotFound is returned when no exam uses the join code
+	ErrJoinCodeNotFound = errors.New("join code not found")
+	// ErrExamNotJoined is returned when a student opens a private exam without joining it first
+	ErrExamNotJoined = errors.New("student has not joined this private exam")
+	// ErrExamNotInClass is returned when a student opens an exam assigned to classes they are not in
+	ErrExamNotInClass = errors.New("student is not in a class this exam is assigned to")
+	// ErrExamNotFound is returned when a student starts an exam that does not exist
+	ErrExamNotFound = errors.New("exam not found")
+	// ErrExamNotActive is returned when a student starts an exam the teacher has not activated
+	ErrExamNotActive = errors.New("exam is not active")
+	// ErrAttemptNotFound is returned when the attempt does not exist or belongs to another student
+	ErrAttemptNotFound = errors.New("exam attempt not found")
+	// ErrAttemptCompleted is returned when answering or submitting an attempt that was already submitted
+	ErrAttemptCompleted = errors.New("exam attempt is already completed")
+	// ErrQuestionNotInAttempt is returned when answering a question that was not drawn for the attempt
+	ErrQuestionNotInAttempt = errors.New("question is not part of this attempt")
+)
+
+func NewStudentService(studentRepository repository.StudentRepository, db *pgxpool.Pool, validate *validator.Validate, cfg *config.Config, webhookRepository repository.WebhookRepository, ltiRepository repository.LTIRepository) *StudentServiceImpl {
 	return &StudentServiceImpl{
 		StudentRepository: studentRepository,
+		WebhookRepository: webhookRepository,
+		LTIRepository:     ltiRepository,
 		DB:                db,
 		Validate:          validate,
 		Config:            cfg,
@@ -29,12 +57,14 @@ func NewStudentService(studentRepository repository.StudentRepository, db *pgxpo
 
 type StudentServiceImpl struct {
 	StudentRepository repository.StudentRepository
+	WebhookRepository repository.WebhookRepository
+	LTIRepository     repository.LTIRepository
 	DB                *pgxpool.Pool
 	Validate          *validator.Validate
 	Config            *config.Config
 }
 
-func (service *StudentServiceImpl) GetActiveExams(ctx context.Context) ([]domain.Exam, error) {
+func (service *StudentServiceImpl) GetActiveExams(ctx context.Context, studentId string) ([]domain.Exam, error) {
 	// Open transaction
 	tx, err := service.DB.Begin(ctx)
 	if err != nil {
@@ -42,7 +72,7 @@ func (service *StudentServiceImpl) GetActiveExams(ctx context.Context) ([]domain
 	}
 	defer helper.CommitOrRollback(ctx, tx)
 
-	exams, err := service.StudentRepository.FindActiveExams(ctx, tx)
+	exams, err := service.StudentRepository.FindActiveExams(ctx, tx, studentId)
 	if err != nil {
 		return nil, fmt.Errorf("failed when calling FincActiveExams repository: %w", err)
 	}
@@ -50,6 +80,53 @@ func (service *StudentServiceImpl) GetActiveExams(ctx context.Context) ([]domain
 	return exams, nil
 }
 
+// GetPublicExams returns the active public exams the student can still join from the dashboard
+func (service *StudentServiceImpl) GetPublicExams(ctx context.Context, studentId string) ([]domain.Exam, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	exams, err := service.StudentRepository.FindPublicExams(ctx, tx, studentId)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindPublicExams repository: %w", err)
+	}
+
+	return exams, nil
+}
+
+// JoinPublicExam adds the student to a public exam picked from the public exam list, private
+// exams and exams of other classes return ErrExamNotFound
+func (service *StudentServiceImpl) JoinPublicExam(ctx context.Context, studentId, examId string) error {
+	if uuid.Validate(examId) != nil {
+		return ErrExamNotFound
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	hasAccess, err := service.StudentRepository.HasExamAccess(ctx, tx, examId, studentId)
+	if err != nil {
+		return fmt.Errorf("failed when calling HasExamAccess repository: %w", err)
+	}
+	if !hasAccess {
+		return ErrExamNotFound
+	}
+
+	err = service.StudentRepository.SaveExamMember(ctx, tx, examId, studentId)
+	if err != nil {
+		return fmt.Errorf("failed when calling SaveExamMember repository: %w", err)
+	}
+
+	return nil
+}
+
 func (service *StudentServiceImpl) GetTeacherById(ctx context.Context, teacherId string) (domain.User, error) {
 	// Open transaction
 	tx, err := service.DB.Begin(ctx)
@@ -98,6 +175,92 @@ func (service *StudentServiceImpl) GetQuestionsByExamId(ctx context.Context, exa
 	return qaList, nil
 }
 
+// JoinExamByCode adds the student to the exam that uses the join code
+func (service *StudentServiceImpl) JoinExamByCode(ctx context.Context, studentId, joinCode string) (domain.Exam, error) {
+	joinCode = strings.ToUpper(strings.TrimSpace(joinCode))
+	if joinCode == "" {
+		return domain.Exam{}, ErrJoinCodeNotFound
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.Exam{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	exam, err := service.StudentRepository.FindExamByJoinCode(ctx, tx, joinCode)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Exam{}, ErrJoinCodeNotFound
+		}
+
+		return domain.Exam{}, fmt.Errorf("failed when calling FindExamByJoinCode repository: %w", err)
+	}
+
+	err = service.StudentRepository.SaveExamMember(ctx, tx, exam.Id, studentId)
+	if err != nil {
+		return domain.Exam{}, fmt.Errorf("failed when calling SaveExamMember repository: %w", err)
+	}
+
+	return exam, nil
+}
+
+// JoinClassByCode enrolls the student in the class that uses the join code
+func (service *StudentServiceImpl) JoinClassByCode(ctx context.Context, studentId, joinCode string) (domain.Class, error) {
+	joinCode = strings.ToUpper(strings.TrimSpace(joinCode))
+	if joinCode == "" {
+		return domain.Class{}, ErrJoinCodeNotFound
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.Class{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	class, err := service.StudentRepository.FindClassByJoinCode(ctx, tx, joinCode)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Class{}, ErrJoinCodeNotFound
+		}
+
+		return domain.Class{}, fmt.Errorf("failed when calling FindClassByJoinCode repository: %w", err)
+	}
+
+	err = service.StudentRepository.SaveClassMember(ctx, tx, class.Id, studentId)
+	if err != nil {
+		return domain.Class{}, fmt.Errorf("failed when calling SaveClassMember repository: %w", err)
+	}
+
+	return class, nil
+}
+
+// GetClasses accepts the pending invitations for the student's verified email and returns their classes
+func (service *StudentServiceImpl) GetClasses(ctx context.Context, studentId string) ([]domain.Class, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	err = service.StudentRepository.ClaimClassInvitations(ctx, tx, studentId)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling ClaimClassInvitations repository: %w", err)
+	}
+
+	classes, err := service.StudentRepository.FindClassesByStudentId(ctx, tx, studentId)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindClassesByStudentId repository: %w", err)
+	}
+
+	return classes, nil
+}
+
+// CreateExamAttempt starts an attempt with its own question list, shuffled and drawn
+// from the exam's questions according to the exam settings
 func (service *StudentServiceImpl) CreateExamAttempt(ctx context.Context, studentId, examId string) (string, error) {
 	// Open transaction
 	tx, err := service.DB.Begin(ctx)
@@ -106,7 +269,55 @@ func (service *StudentServiceImpl) CreateExamAttempt(ctx context.Context, studen
 	}
 	defer helper.CommitOrRollback(ctx, tx)
 
-	examAttemptId, err := service.StudentRepository.CreateExamAttempt(ctx, tx, studentId, examId)
+	// Kebijakan "exam" hanya mengizinkan siswa dengan email terverifikasi memulai ujian
+	if service.Config.EmailVerificationPolicy == EmailVerificationPolicyExam {
+		isVerified, err := service.StudentRepository.IsEmailVerified(ctx, tx, studentId)
+		if err != nil {
+			return "", fmt.Errorf("failed when calling IsEmailVerified repository: %w", err)
+		}
+		if !isVerified {
+			return "", ErrEmailNotVerified
+		}
+	}
+
+	exam, err := service.StudentRepository.FindExamById(ctx, tx, examId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrExamNotFound
+		}
+
+		return "", fmt.Errorf("failed when calling FindExamById repository: %w", err)
+	}
+	if !exam.IsActive {
+		return "", ErrExamNotActive
+	}
+
+	hasAccess, err := service.StudentRepository.HasExamAccess(ctx, tx, examId, studentId)
+	if err != nil {
+		return "", fmt.Errorf("failed when calling HasExamAccess repository: %w", err)
+	}
+	if !hasAccess {
+		if exam.IsPrivate {
+			return "", ErrExamNotJoined
+		}
+
+		return "", ErrExamNotInClass
+	}
+
+	questions, err := service.StudentRepository.FindQuestionsByExamId(ctx, tx, examId)
+	if err != nil {
+		return "", fmt.Errorf("failed when calling FindQuestionsByExamId repository: %w", err)
+	}
+
+	questionIds := []string{}
+	for _, question := range questions {
+		questionIds = append(questionIds, question.Id)
+	}
+
+	questionSeed := rand.Int64()
+	attemptQuestionIds := helper.AttemptQuestionIds(questionIds, questionSeed, exam.ShuffleQuestions, exam.QuestionDrawCount)
+
+	examAttemptId, err := service.StudentRepository.CreateExamAttempt(ctx, tx, studentId, examId, questionSeed, attemptQuestionIds)
 	if err != nil {
 		return "", fmt.Errorf("failed when calling CreateExamAttempt repository: %w", err)
 	}
@@ -114,6 +325,55 @@ func (service *StudentServiceImpl) CreateExamAttempt(ctx context.Context, studen
 	return examAttemptId, nil
 }
 
+// GetAttemptQuestions returns the questions of the attempt in the order the student sees them
+func (service *StudentServiceImpl) GetAttemptQuestions(ctx context.Context, attemptId string) ([]domain.QAItem, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	return service.findAttemptQuestions(ctx, tx, attemptId)
+}
+
+func (service *StudentServiceImpl) findAttemptQuestions(ctx context.Context, tx pgx.Tx, attemptId string) ([]domain.QAItem, error) {
+	exam, err := service.StudentRepository.FindExamByAttemptId(ctx, tx, attemptId)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindExamByAttemptId repository: %w", err)
+	}
+
+	questions, err := service.StudentRepository.FindQuestionsByExamId(ctx, tx, exam.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindQuestionsByExamId repository: %w", err)
+	}
+
+	questionIds, err := service.StudentRepository.FindAttemptQuestionIds(ctx, tx, attemptId)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindAttemptQuestionIds repository: %w", err)
+	}
+
+	// Attempt lama tidak menyimpan daftar soal, pakai semua soal ujian
+	if len(questionIds) == 0 {
+		return questions, nil
+	}
+
+	questionsById := map[string]domain.QAItem{}
+	for _, question := range questions {
+		questionsById[question.Id] = question
+	}
+
+	attemptQuestions := []domain.QAItem{}
+	for _, questionId := range questionIds {
+		// Soal yang sudah dihapus guru dilewati
+		if question, ok := questionsById[questionId]; ok {
+			attemptQuestions = append(attemptQuestions, question)
+		}
+	}
+
+	return attemptQuestions, nil
+}
+
 func (service *StudentServiceImpl) SaveAnswer(ctx context.Context, answer web.StudentAnswer) error {
 	// Open transaction
 	tx, err := service.DB.Begin(ctx)
@@ -138,14 +398,234 @@ func (service *StudentServiceImpl) CompleteExamAttempt(ctx context.Context, atte
 	}
 	defer helper.CommitOrRollback(ctx, tx)
 
-	err = service.StudentRepository.CompleteExamAttempt(ctx, tx, attemptId)
+	_, err = service.completeAttempt(ctx, tx, attemptId)
+	return err
+}
+
+// completeAttempt closes the attempt and queues attempt.submitted and the LTI score in the same transaction
+func (service *StudentServiceImpl) completeAttempt(ctx context.Context, tx pgx.Tx, attemptId string) (web.ExamAttempt, error) {
+	err := service.StudentRepository.CompleteExamAttempt(ctx, tx, attemptId)
+	if err != nil {
+		return web.ExamAttempt{}, fmt.Errorf("failed when calling CompleteExamAttempt repository: %w", err)
+	}
+
+	attempt, err := service.StudentRepository.FindAttemptById(ctx, tx, attemptId)
+	if err != nil {
+		return web.ExamAttempt{}, fmt.Errorf("failed when calling FindAttemptById repository: %w", err)
+	}
+
+	err = service.LTIRepository.QueueScoreSync(ctx, tx, attempt.ExamID, attempt.StudentID)
+	if err != nil {
+		return web.ExamAttempt{}, fmt.Errorf("failed when calling QueueScoreSync repository: %w", err)
+	}
+
+	err = enqueueWebhookEvent(ctx, tx, service.WebhookRepository, domain.WebhookEventAttemptSubmitted, attempt.ExamID, attemptEventData(attempt, false))
+	if err != nil {
+		return web.ExamAttempt{}, err
+	}
+
+	return attempt, nil
+}
+
+func (service *StudentServiceImpl) GetAttempt(ctx context.Context, studentId, attemptId string) (web.ExamAttempt, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return web.ExamAttempt{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	return service.findStudentAttempt(ctx, tx, studentId, attemptId)
+}
+
+// SaveAttemptAnswer saves or replaces the answer of one question of a running attempt
+func (service *StudentServiceImpl) SaveAttemptAnswer(ctx context.Context, studentId string, request web.AttemptAnswerRequest) error {
+	// Validate request
+	if err := service.Validate.Struct(request); err != nil {
+		return fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	attempt, err := service.findStudentAttempt(ctx, tx, studentId, request.AttemptId)
 	if err != nil {
-		return fmt.Errorf("failed when calling CompleteExamAttempt repository: %w", err)
+		return err
+	}
+	if !attempt.CompletedAt.IsZero() {
+		return ErrAttemptCompleted
+	}
+
+	questions, err := service.findAttemptQuestions(ctx, tx, request.AttemptId)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(questions, func(question domain.QAItem) bool { return question.Id == request.QuestionId }) {
+		return ErrQuestionNotInAttempt
+	}
+
+	err = service.StudentRepository.SaveOrUpdateAnswer(ctx, tx, web.StudentAnswer{
+		ExamAttemptID: request.AttemptId,
+		QuestionID:    request.QuestionId,
+		StudentAnswer: request.Answer,
+	})
+	if err != nil {
+		return fmt.Errorf("failed when calling SaveOrUpdateAnswer repository: %w", err)
 	}
 
 	return nil
 }
 
+// SubmitAttempt scores the saved answers and closes the attempt. Scoring and closing share one
+// transaction, so when scoring fails the attempt stays open and can be submitted again.
+func (service *StudentServiceImpl) SubmitAttempt(ctx context.Context, studentId, attemptId string) (web.ExamAttempt, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return web.ExamAttempt{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	attempt, err := service.findStudentAttempt(ctx, tx, studentId, attemptId)
+	if err != nil {
+		return web.ExamAttempt{}, err
+	}
+	if !attempt.CompletedAt.IsZero() {
+		return web.ExamAttempt{}, ErrAttemptCompleted
+	}
+
+	if _, err := service.calculateScore(ctx, tx, attemptId); err != nil {
+		return web.ExamAttempt{}, err
+	}
+
+	attempt, err = service.completeAttempt(ctx, tx, attemptId)
+	if err != nil {
+		return web.ExamAttempt{}, err
+	}
+
+	err = enqueueWebhookEvent(ctx, tx, service.WebhookRepository, domain.WebhookEventAttemptScored, attempt.ExamID, attemptEventData(attempt, false))
+	if err != nil {
+		return web.ExamAttempt{}, err
+	}
+
+	return attempt, nil
+}
+
+// RegradeExamAttempts scores every submitted attempt of the exam again, for example after the
+// answer key was corrected. Each attempt is scored in its own transaction, so when the scoring API
+// fails halfway the attempts before it keep their new score. It returns how many were regraded.
+func (service *StudentServiceImpl) RegradeExamAttempts(ctx context.Context, examId string) (int, error) {
+	attemptIds, err := service.findCompletedAttemptIds(ctx, examId)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, attemptId := range attemptIds {
+		if err := service.regradeAttempt(ctx, attemptId); err != nil {
+			return i, err
+		}
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return len(attemptIds), fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	err = enqueueWebhookEvent(ctx, tx, service.WebhookRepository, domain.WebhookEventExamRegraded, examId, api.ExamRegradedData{
+		ExamId:           examId,
+		AttemptsRegraded: len(attemptIds),
+	})
+	if err != nil {
+		return len(attemptIds), err
+	}
+
+	return len(attemptIds), nil
+}
+
+func (service *StudentServiceImpl) findCompletedAttemptIds(ctx context.Context, examId string) ([]string, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	attemptIds, err := service.StudentRepository.FindCompletedAttemptIdsByExamId(ctx, tx, examId)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindCompletedAttemptIdsByExamId repository: %w", err)
+	}
+
+	return attemptIds, nil
+}
+
+func (service *StudentServiceImpl) regradeAttempt(ctx context.Context, attemptId string) error {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if _, err := service.calculateScore(ctx, tx, attemptId); err != nil {
+		return err
+	}
+
+	attempt, err := service.StudentRepository.FindAttemptById(ctx, tx, attemptId)
+	if err != nil {
+		return fmt.Errorf("failed when calling FindAttemptById repository: %w", err)
+	}
+
+	err = service.LTIRepository.QueueScoreSync(ctx, tx, attempt.ExamID, attempt.StudentID)
+	if err != nil {
+		return fmt.Errorf("failed when calling QueueScoreSync repository: %w", err)
+	}
+
+	return enqueueWebhookEvent(ctx, tx, service.WebhookRepository, domain.WebhookEventAttemptScored, attempt.ExamID, attemptEventData(attempt, true))
+}
+
+func attemptEventData(attempt web.ExamAttempt, regraded bool) api.AttemptEventData {
+	data := api.AttemptEventData{
+		AttemptId: attempt.ID,
+		ExamId:    attempt.ExamID,
+		StudentId: attempt.StudentID,
+		Score:     attempt.Score,
+		StartedAt: attempt.StartedAt,
+		Regraded:  regraded,
+	}
+	if !attempt.CompletedAt.IsZero() {
+		data.CompletedAt = &attempt.CompletedAt
+	}
+
+	return data
+}
+
+// findStudentAttempt returns ErrAttemptNotFound unless the attempt belongs to the student
+func (service *StudentServiceImpl) findStudentAttempt(ctx context.Context, tx pgx.Tx, studentId, attemptId string) (web.ExamAttempt, error) {
+	if uuid.Validate(attemptId) != nil {
+		return web.ExamAttempt{}, ErrAttemptNotFound
+	}
+
+	attempt, err := service.StudentRepository.FindAttemptById(ctx, tx, attemptId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return web.ExamAttempt{}, ErrAttemptNotFound
+		}
+
+		return web.ExamAttempt{}, fmt.Errorf("failed when calling FindAttemptById repository: %w", err)
+	}
+	if attempt.StudentID != studentId {
+		return web.ExamAttempt{}, ErrAttemptNotFound
+	}
+
+	return attempt, nil
+}
+
 func (service *StudentServiceImpl) GetExamByAttempId(ctx context.Context, attemptId string) (domain.Exam, error) {
 	// Open transaction
 	tx, err := service.DB.Begin(ctx)
@@ -186,16 +666,15 @@ func (service *StudentServiceImpl) CalculateScore(ctx context.Context, attemptId
 	}
 	defer helper.CommitOrRollback(ctx, tx)
 
-	// Get exam by attempId
-	exam, err := service.StudentRepository.FindExamByAttemptId(ctx, tx, attemptId)
-	if err != nil {
-		return nil, fmt.Errorf("failed when calling FindExamByAttemptId repository: %w", err)
-	}
+	return service.calculateScore(ctx, tx, attemptId)
+}
 
-	// Get questions by examId
-	questions, err := service.StudentRepository.FindQuestionsByExamId(ctx, tx, exam.Id)
+// calculateScore sends the answers to the scoring API and stores the scores in tx
+func (service *StudentServiceImpl) calculateScore(ctx context.Context, tx pgx.Tx, attemptId string) ([]domain.EssayCorrection, error) {
+	// Get the questions of this attempt
+	questions, err := service.findAttemptQuestions(ctx, tx, attemptId)
 	if err != nil {
-		return nil, fmt.Errorf("failed when calling FindQuestionsByExamId repository: %w", err)
+		return nil, err
 	}
 
 	type QuestionAnswer struct {
@@ -2,23 +2,39 @@ package service
 
 import (
 	"context"
-	"mime/multipart"
 
 	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
 	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
 )
 
 type TeacherService interface {
-	GenerateQuestionAnswer(ctx context.Context, file multipart.File, totalQuestion int, examData domain.Exam, teacherId string) error
+	GenerateQuestionAnswer(ctx context.Context, material domain.Material, totalQuestion int, examData domain.Exam, teacherId string, bypassCache bool) (string, bool, error)
+	CheckDuplicateQuestions(ctx context.Context, teacherId, examId string) ([]web.DuplicateWarning, error)
 
-	TeacherDashboard(ctx context.Context, userId string) (web.TeacherDashboardResponse, error)
+	TeacherDashboard(ctx context.Context, userId, classId string) (web.TeacherDashboardResponse, error)
 	UpdateIsActiveExamById(ctx context.Context, userId, examId string) (domain.Exam, error)
+	SetExamPrivate(ctx context.Context, teacherId, examId string, isPrivate bool) (domain.Exam, error)
+	RegenerateJoinCode(ctx context.Context, teacherId, examId string) (domain.Exam, error)
+	RevokeJoinCode(ctx context.Context, teacherId, examId string) (domain.Exam, error)
 	GetExamById(ctx context.Context, examId string) (domain.Exam, error)
+	GetExamForTeacher(ctx context.Context, teacherId, examId, requiredRole string) (domain.Exam, error)
 	GetQAByExamId(ctx context.Context, examId string) ([]domain.QAItem, error)
-	UpdateExamById(ctx context.Context, examId, roomName string, yearInt, durationInt int) error
+	EditExam(ctx context.Context, teacherId, examId string, request web.ExamEditRequest) error
 
-	UpdateQuestionById(ctx context.Context, questionId, questionText, answerText string) error
+	RegenerateQuestion(ctx context.Context, examId, questionId string) (domain.QAItem, domain.QAItem, error)
 
-	GetBiggestExamAttemptsScoreByExamId(ctx context.Context, examId string) ([]web.ExamAttempt, error)
+	GetExamCollaborators(ctx context.Context, teacherId, examId string) (web.ExamCollaboratorsResponse, error)
+	AddExamCollaborator(ctx context.Context, teacherId, examId string, request web.ExamCollaboratorRequest) (web.ExamCollaboratorsResponse, error)
+	UpdateExamCollaboratorRole(ctx context.Context, teacherId, examId, collaboratorId string, request web.ExamCollaboratorRoleRequest) (web.ExamCollaboratorsResponse, error)
+	RemoveExamCollaborator(ctx context.Context, teacherId, examId, collaboratorId string) (web.ExamCollaboratorsResponse, error)
+
+	// Exam and question management used by the JSON API
+	CreateExam(ctx context.Context, teacherId string, request web.ExamCreateRequest) (domain.Exam, error)
+	UpdateExam(ctx context.Context, teacherId, examId string, request web.ExamUpdateRequest) (domain.Exam, error)
+	DeleteExam(ctx context.Context, teacherId, examId string) error
+	CreateQuestion(ctx context.Context, teacherId, examId string, request web.QuestionSaveRequest) (domain.QAItem, error)
+	UpdateQuestion(ctx context.Context, teacherId, examId, questionId string, request web.QuestionSaveRequest) (domain.QAItem, error)
+	DeleteQuestion(ctx context.Context, teacherId, examId, questionId string) error
+	GetBiggestExamAttemptsScoreByExamId(ctx context.Context, examId, classId string) ([]web.ExamAttempt, error)
 	GetStudentFullNameByExamAttemptsId(ctx context.Context, examAttemptsId string) (string, string, error)
 }
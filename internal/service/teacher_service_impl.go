@@ -2,79 +2,179 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"mime/multipart"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/generative-ai-go/genai"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/mhaatha/go-template-saygenfix/internal/config"
 	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/api"
 	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
 	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
 	"github.com/mhaatha/go-template-saygenfix/internal/repository"
+	"github.com/mhaatha/go-template-saygenfix/internal/storage"
 	"google.golang.org/api/option"
 )
 
-func NewTeacherService(teacherRepository repository.TeacherRepository, db *pgxpool.Pool, validate *validator.Validate, cfg *config.Config) TeacherService {
+// defaultGenerationCacheTTL is used when GENERATION_CACHE_TTL is not set
+const defaultGenerationCacheTTL = 7 * 24 * time.Hour
+
+// nearDuplicateThreshold is the shingle Jaccard similarity from which two questions are treated as duplicates
+const nearDuplicateThreshold = 0.5
+
+// maxJoinCodeAttempts is how many join codes are tried before giving up on finding an unused one
+const maxJoinCodeAttempts = 5
+
+// maxRegenerateAttempts is how many times the model is asked again when it keeps returning a duplicate question
+const maxRegenerateAttempts = 3
+
+var (
+	// ErrExamForbidden is returned when the teacher is not a collaborator or their role is too low for the action
+	ErrExamForbidden = errors.New("teacher does not have the required role on this exam")
+	// ErrLastExamOwner is returned when removing or demoting the only owner of an exam
+	ErrLastExamOwner = errors.New("exam must keep at least one owner")
+	// ErrCollaboratorNotTeacher is returned when the collaborator email is unknown or not a teacher account
+	ErrCollaboratorNotTeacher = errors.New("collaborator email does not belong to a teacher")
+	// ErrQuestionNotFound is returned when the question does not exist or belongs to another exam
+	ErrQuestionNotFound = errors.New("question not found in this exam")
+	// ErrQuestionDrawCount is returned when more questions should be drawn per attempt than the exam has
+	ErrQuestionDrawCount = errors.New("question draw count is larger than the number of questions")
+	// ErrBankQuestionLinked is returned when changing an exam question that refers to a bank question,
+	// those are only changed by the bank owner on the question bank page
+	ErrBankQuestionLinked = errors.New("question refers to a bank question")
+)
+
+// examRoleRank orders the collaborator roles, a higher rank includes every lower one
+var examRoleRank = map[string]int{
+	domain.ExamRoleViewer: 1,
+	domain.ExamRoleGrader: 2,
+	domain.ExamRoleEditor: 3,
+	domain.ExamRoleOwner:  4,
+}
+
+func hasExamRole(role, requiredRole string) bool {
+	return examRoleRank[role] > 0 && examRoleRank[role] >= examRoleRank[requiredRole]
+}
+
+func NewTeacherService(teacherRepository repository.TeacherRepository, db *pgxpool.Pool, validate *validator.Validate, cfg *config.Config, fileStore storage.FileStore, webhookRepository repository.WebhookRepository) TeacherService {
 	return &TeacherServiceImpl{
 		TeacherRepository: teacherRepository,
+		WebhookRepository: webhookRepository,
 		DB:                db,
 		Validate:          validate,
 		Config:            cfg,
+		FileStore:         fileStore,
 	}
 }
 
 type TeacherServiceImpl struct {
 	TeacherRepository repository.TeacherRepository
+	WebhookRepository repository.WebhookRepository
 	DB                *pgxpool.Pool
 	Validate          *validator.Validate
 	Config            *config.Config
+	FileStore         storage.FileStore
 }
 
-func (service *TeacherServiceImpl) GenerateQuestionAnswer(ctx context.Context, file multipart.File, totalQuestion int, examData domain.Exam, teacherId string) error {
-	// Handle Gemini API
-	client, err := genai.NewClient(ctx, option.WithAPIKey(service.Config.GeminiAPIKey))
-	if err != nil {
-		return fmt.Errorf("error when calling NewClient: %w", err)
-	}
-	defer client.Close()
+// GenerateQuestionAnswer creates an exam from the material. The generated questions are cached
+// by document, prompt, question count and model. It returns the new exam id and whether the cache was used.
+func (service *TeacherServiceImpl) GenerateQuestionAnswer(ctx context.Context, material domain.Material, totalQuestion int, examData domain.Exam, teacherId string, bypassCache bool) (string, bool, error) {
+	cacheKey := helper.GenerationCacheKey(material.ContentHash, helper.QAPromptTemplate, totalQuestion, helper.GeminiModel)
 
-	fileURL, err := helper.UploadPDF(ctx, client, file)
-	if err != nil {
-		return fmt.Errorf("failed when calling UploadPDF helper: %w", err)
+	var qaList []domain.QAItem
+	if !bypassCache {
+		cachedQA, err := service.findCachedQA(ctx, cacheKey)
+		if err != nil {
+			return "", false, err
+		}
+		qaList = cachedQA
 	}
+	cacheHit := qaList != nil
 
-	qaList, err := helper.GenerateQAFromPDF(ctx, client, fileURL, totalQuestion)
-	if err != nil {
-		return fmt.Errorf("failed when calling GenerateQAFromPDF helper: %w", err)
+	if !cacheHit {
+		// Handle Gemini API
+		client, err := genai.NewClient(ctx, option.WithAPIKey(service.Config.GeminiAPIKey))
+		if err != nil {
+			return "", false, fmt.Errorf("error when calling NewClient: %w", err)
+		}
+		defer client.Close()
+
+		fileURL, err := service.uploadStoredPDF(ctx, client, material.StorageKey)
+		if err != nil {
+			return "", false, err
+		}
+
+		qaList, err = helper.GenerateQAFromPDF(ctx, client, fileURL, totalQuestion)
+		if err != nil {
+			return "", false, fmt.Errorf("failed when calling GenerateQAFromPDF helper: %w", err)
+		}
+		examData.SourceURI = fileURL
 	}
 
 	// Open transaction
 	tx, err := service.DB.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to open db transaction: %w", err)
+		return "", false, fmt.Errorf("failed to open db transaction: %w", err)
 	}
 	defer helper.CommitOrRollback(ctx, tx)
 
 	// Create new exam and save it to database
 	examId := "EXAM-" + uuid.NewString()[:8]
+	examData.MaterialId = material.Id
 	err = service.TeacherRepository.SaveExam(ctx, tx, examData, teacherId, examId)
 	if err != nil {
-		return fmt.Errorf("failed when SaveExam repository: %w", err)
+		return "", false, fmt.Errorf("failed when SaveExam repository: %w", err)
 	}
 
 	// Save the qaList to the database
 	_, err = service.TeacherRepository.BulkSaveQuestionAnswer(ctx, tx, qaList, examId)
 	if err != nil {
-		return fmt.Errorf("failed when calling BulkSaveQuestionAnswer repository: %w", err)
+		return "", false, fmt.Errorf("failed when calling BulkSaveQuestionAnswer repository: %w", err)
 	}
 
-	return nil
+	if !cacheHit {
+		expiresAt := time.Now().Add(service.generationCacheTTL())
+		err = service.TeacherRepository.SaveCachedQA(ctx, tx, cacheKey, helper.GeminiModel, totalQuestion, qaList, expiresAt)
+		if err != nil {
+			return "", false, fmt.Errorf("failed when calling SaveCachedQA repository: %w", err)
+		}
+	}
+
+	return examId, cacheHit, nil
+}
+
+func (service *TeacherServiceImpl) findCachedQA(ctx context.Context, cacheKey string) ([]domain.QAItem, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	qaList, err := service.TeacherRepository.FindCachedQA(ctx, tx, cacheKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindCachedQA repository: %w", err)
+	}
+
+	return qaList, nil
+}
+
+// generationCacheTTL reads GENERATION_CACHE_TTL (e.g. "72h") and falls back to seven days
+func (service *TeacherServiceImpl) generationCacheTTL() time.Duration {
+	ttl, err := time.ParseDuration(service.Config.GenerationCacheTTL)
+	if err != nil || ttl <= 0 {
+		return defaultGenerationCacheTTL
+	}
+
+	return ttl
 }
 
-func (service *TeacherServiceImpl) TeacherDashboard(ctx context.Context, userId string) (web.TeacherDashboardResponse, error) {
+func (service *TeacherServiceImpl) TeacherDashboard(ctx context.Context, userId, classId string) (web.TeacherDashboardResponse, error) {
 	// Open transaction
 	tx, err := service.DB.Begin(ctx)
 	if err != nil {
@@ -92,8 +192,8 @@ func (service *TeacherServiceImpl) TeacherDashboard(ctx context.Context, userId
 		user.Role = "Teacher"
 	}
 
-	// Get exams by userId
-	exams, err := service.TeacherRepository.FindExamsByUserId(ctx, tx, userId)
+	// Get exams by userId, optionally only the ones assigned to classId
+	exams, err := service.TeacherRepository.FindExamsByUserId(ctx, tx, userId, classId)
 	if err != nil {
 		return web.TeacherDashboardResponse{}, fmt.Errorf("failed when calling FindExamsByUserId repository: %w", err)
 	}
@@ -114,9 +214,9 @@ func (service *TeacherServiceImpl) UpdateIsActiveExamById(ctx context.Context, u
 	}
 	defer helper.CommitOrRollback(ctx, tx)
 
-	exam, err := service.TeacherRepository.FindExamById(ctx, tx, examId)
+	exam, err := service.authorizeExam(ctx, tx, userId, examId, domain.ExamRoleEditor)
 	if err != nil {
-		return domain.Exam{}, fmt.Errorf("failed when calling FindExamById repository: %w", err)
+		return domain.Exam{}, err
 	}
 
 	err = service.TeacherRepository.UpdateIsActiveExamById(ctx, tx, examId, exam.IsActive)
@@ -128,10 +228,317 @@ func (service *TeacherServiceImpl) UpdateIsActiveExamById(ctx context.Context, u
 	if err != nil {
 		return domain.Exam{}, fmt.Errorf("failed when calling FindExamById repository: %w", err)
 	}
+	updatedExam.Role = exam.Role
+
+	if updatedExam.IsActive {
+		if err := service.enqueueExamActivated(ctx, tx, updatedExam); err != nil {
+			return domain.Exam{}, err
+		}
+	}
 
 	return updatedExam, nil
 }
 
+// SetExamPrivate makes the exam private or public again. A private exam gets a join code
+// right away so the teacher has something to share.
+func (service *TeacherServiceImpl) SetExamPrivate(ctx context.Context, teacherId, examId string, isPrivate bool) (domain.Exam, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.Exam{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	exam, err := service.authorizeExam(ctx, tx, teacherId, examId, domain.ExamRoleEditor)
+	if err != nil {
+		return domain.Exam{}, err
+	}
+
+	err = service.TeacherRepository.UpdateIsPrivateExamById(ctx, tx, examId, isPrivate)
+	if err != nil {
+		return domain.Exam{}, fmt.Errorf("failed when calling UpdateIsPrivateExamById repository: %w", err)
+	}
+	exam.IsPrivate = isPrivate
+
+	if isPrivate && exam.JoinCode == "" {
+		exam.JoinCode, err = service.saveNewJoinCode(ctx, tx, examId)
+		if err != nil {
+			return domain.Exam{}, err
+		}
+	}
+
+	return exam, nil
+}
+
+// RegenerateJoinCode replaces the join code, the old code stops working immediately
+func (service *TeacherServiceImpl) RegenerateJoinCode(ctx context.Context, teacherId, examId string) (domain.Exam, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.Exam{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	exam, err := service.authorizeExam(ctx, tx, teacherId, examId, domain.ExamRoleEditor)
+	if err != nil {
+		return domain.Exam{}, err
+	}
+
+	exam.JoinCode, err = service.saveNewJoinCode(ctx, tx, examId)
+	if err != nil {
+		return domain.Exam{}, err
+	}
+
+	return exam, nil
+}
+
+// RevokeJoinCode removes the join code. Students who already joined keep access.
+func (service *TeacherServiceImpl) RevokeJoinCode(ctx context.Context, teacherId, examId string) (domain.Exam, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.Exam{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	exam, err := service.authorizeExam(ctx, tx, teacherId, examId, domain.ExamRoleEditor)
+	if err != nil {
+		return domain.Exam{}, err
+	}
+
+	err = service.TeacherRepository.UpdateJoinCodeById(ctx, tx, examId, "")
+	if err != nil {
+		return domain.Exam{}, fmt.Errorf("failed when calling UpdateJoinCodeById repository: %w", err)
+	}
+	exam.JoinCode = ""
+
+	return exam, nil
+}
+
+// authorizeExam loads the exam when the teacher has at least requiredRole on it
+func (service *TeacherServiceImpl) authorizeExam(ctx context.Context, tx pgx.Tx, teacherId, examId, requiredRole string) (domain.Exam, error) {
+	role, err := service.TeacherRepository.FindExamRole(ctx, tx, examId, teacherId)
+	if err != nil {
+		return domain.Exam{}, fmt.Errorf("failed when calling FindExamRole repository: %w", err)
+	}
+	if !hasExamRole(role, requiredRole) {
+		return domain.Exam{}, ErrExamForbidden
+	}
+
+	exam, err := service.TeacherRepository.FindExamById(ctx, tx, examId)
+	if err != nil {
+		return domain.Exam{}, fmt.Errorf("failed when calling FindExamById repository: %w", err)
+	}
+	exam.Role = role
+
+	return exam, nil
+}
+
+// GetExamForTeacher returns the exam with the teacher's role filled in, or ErrExamForbidden
+// when the teacher's role is below requiredRole
+func (service *TeacherServiceImpl) GetExamForTeacher(ctx context.Context, teacherId, examId, requiredRole string) (domain.Exam, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.Exam{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	return service.authorizeExam(ctx, tx, teacherId, examId, requiredRole)
+}
+
+func (service *TeacherServiceImpl) GetExamCollaborators(ctx context.Context, teacherId, examId string) (web.ExamCollaboratorsResponse, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return web.ExamCollaboratorsResponse{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if _, err := service.authorizeExam(ctx, tx, teacherId, examId, domain.ExamRoleViewer); err != nil {
+		return web.ExamCollaboratorsResponse{}, err
+	}
+
+	return service.findExamCollaborators(ctx, tx, teacherId, examId)
+}
+
+// AddExamCollaborator shares the exam with another teacher, or changes their role when
+// they already collaborate on it. Only owners can manage collaborators.
+func (service *TeacherServiceImpl) AddExamCollaborator(ctx context.Context, teacherId, examId string, request web.ExamCollaboratorRequest) (web.ExamCollaboratorsResponse, error) {
+	// Validate request
+	if err := service.Validate.Struct(request); err != nil {
+		return web.ExamCollaboratorsResponse{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return web.ExamCollaboratorsResponse{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if _, err := service.authorizeExam(ctx, tx, teacherId, examId, domain.ExamRoleOwner); err != nil {
+		return web.ExamCollaboratorsResponse{}, err
+	}
+
+	collaborator, err := service.TeacherRepository.FindUserByEmail(ctx, tx, request.Email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return web.ExamCollaboratorsResponse{}, ErrCollaboratorNotTeacher
+		}
+
+		return web.ExamCollaboratorsResponse{}, fmt.Errorf("failed when calling FindUserByEmail repository: %w", err)
+	}
+	if collaborator.Role != "teacher" {
+		return web.ExamCollaboratorsResponse{}, ErrCollaboratorNotTeacher
+	}
+
+	if err := service.saveCollaboratorRole(ctx, tx, examId, collaborator.Id, request.Role); err != nil {
+		return web.ExamCollaboratorsResponse{}, err
+	}
+
+	return service.findExamCollaborators(ctx, tx, teacherId, examId)
+}
+
+func (service *TeacherServiceImpl) UpdateExamCollaboratorRole(ctx context.Context, teacherId, examId, collaboratorId string, request web.ExamCollaboratorRoleRequest) (web.ExamCollaboratorsResponse, error) {
+	// Validate request
+	if err := service.Validate.Struct(request); err != nil {
+		return web.ExamCollaboratorsResponse{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return web.ExamCollaboratorsResponse{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if _, err := service.authorizeExam(ctx, tx, teacherId, examId, domain.ExamRoleOwner); err != nil {
+		return web.ExamCollaboratorsResponse{}, err
+	}
+
+	currentRole, err := service.TeacherRepository.FindExamRole(ctx, tx, examId, collaboratorId)
+	if err != nil {
+		return web.ExamCollaboratorsResponse{}, fmt.Errorf("failed when calling FindExamRole repository: %w", err)
+	}
+	if currentRole == "" {
+		return web.ExamCollaboratorsResponse{}, errors.New("teacher is not a collaborator on this exam")
+	}
+
+	if err := service.saveCollaboratorRole(ctx, tx, examId, collaboratorId, request.Role); err != nil {
+		return web.ExamCollaboratorsResponse{}, err
+	}
+
+	return service.findExamCollaborators(ctx, tx, teacherId, examId)
+}
+
+func (service *TeacherServiceImpl) RemoveExamCollaborator(ctx context.Context, teacherId, examId, collaboratorId string) (web.ExamCollaboratorsResponse, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return web.ExamCollaboratorsResponse{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if _, err := service.authorizeExam(ctx, tx, teacherId, examId, domain.ExamRoleOwner); err != nil {
+		return web.ExamCollaboratorsResponse{}, err
+	}
+
+	if err := service.ensureOtherOwner(ctx, tx, examId, collaboratorId); err != nil {
+		return web.ExamCollaboratorsResponse{}, err
+	}
+
+	err = service.TeacherRepository.DeleteCollaborator(ctx, tx, examId, collaboratorId)
+	if err != nil {
+		return web.ExamCollaboratorsResponse{}, fmt.Errorf("failed when calling DeleteCollaborator repository: %w", err)
+	}
+
+	return service.findExamCollaborators(ctx, tx, teacherId, examId)
+}
+
+func (service *TeacherServiceImpl) saveCollaboratorRole(ctx context.Context, tx pgx.Tx, examId, collaboratorId, role string) error {
+	if role != domain.ExamRoleOwner {
+		if err := service.ensureOtherOwner(ctx, tx, examId, collaboratorId); err != nil {
+			return err
+		}
+	}
+
+	err := service.TeacherRepository.SaveCollaborator(ctx, tx, examId, collaboratorId, role)
+	if err != nil {
+		return fmt.Errorf("failed when calling SaveCollaborator repository: %w", err)
+	}
+
+	return nil
+}
+
+// ensureOtherOwner returns ErrLastExamOwner when the collaborator is the only owner left on the exam
+func (service *TeacherServiceImpl) ensureOtherOwner(ctx context.Context, tx pgx.Tx, examId, collaboratorId string) error {
+	role, err := service.TeacherRepository.FindExamRole(ctx, tx, examId, collaboratorId)
+	if err != nil {
+		return fmt.Errorf("failed when calling FindExamRole repository: %w", err)
+	}
+	if role != domain.ExamRoleOwner {
+		return nil
+	}
+
+	totalOwners, err := service.TeacherRepository.CountOwners(ctx, tx, examId)
+	if err != nil {
+		return fmt.Errorf("failed when calling CountOwners repository: %w", err)
+	}
+	if totalOwners <= 1 {
+		return ErrLastExamOwner
+	}
+
+	return nil
+}
+
+func (service *TeacherServiceImpl) findExamCollaborators(ctx context.Context, tx pgx.Tx, teacherId, examId string) (web.ExamCollaboratorsResponse, error) {
+	collaborators, err := service.TeacherRepository.FindCollaborators(ctx, tx, examId)
+	if err != nil {
+		return web.ExamCollaboratorsResponse{}, fmt.Errorf("failed when calling FindCollaborators repository: %w", err)
+	}
+
+	// Role dibaca ulang karena owner bisa saja baru menurunkan role-nya sendiri
+	role, err := service.TeacherRepository.FindExamRole(ctx, tx, examId, teacherId)
+	if err != nil {
+		return web.ExamCollaboratorsResponse{}, fmt.Errorf("failed when calling FindExamRole repository: %w", err)
+	}
+
+	return web.ExamCollaboratorsResponse{
+		ExamId:        examId,
+		TeacherId:     teacherId,
+		Collaborators: collaborators,
+		CanManage:     role == domain.ExamRoleOwner,
+	}, nil
+}
+
+// saveNewJoinCode generates an unused join code and stores it on the exam
+func (service *TeacherServiceImpl) saveNewJoinCode(ctx context.Context, tx pgx.Tx, examId string) (string, error) {
+	for attempt := 1; attempt <= maxJoinCodeAttempts; attempt++ {
+		joinCode, err := helper.GenerateSecureString()
+		if err != nil {
+			return "", fmt.Errorf("failed when calling GenerateSecureString helper: %w", err)
+		}
+
+		isTaken, err := service.TeacherRepository.IsJoinCodeTaken(ctx, tx, joinCode)
+		if err != nil {
+			return "", fmt.Errorf("failed when calling IsJoinCodeTaken repository: %w", err)
+		}
+		if isTaken {
+			continue
+		}
+
+		err = service.TeacherRepository.UpdateJoinCodeById(ctx, tx, examId, joinCode)
+		if err != nil {
+			return "", fmt.Errorf("failed when calling UpdateJoinCodeById repository: %w", err)
+		}
+
+		return joinCode, nil
+	}
+
+	return "", errors.New("failed to generate an unused join code")
+}
+
 func (service *TeacherServiceImpl) GetExamById(ctx context.Context, examId string) (domain.Exam, error) {
 	// Open transaction
 	tx, err := service.DB.Begin(ctx)
@@ -164,7 +571,14 @@ func (service *TeacherServiceImpl) GetQAByExamId(ctx context.Context, examId str
 	return qaList, nil
 }
 
-func (service *TeacherServiceImpl) UpdateExamById(ctx context.Context, examId, roomName string, yearInt, durationInt int) error {
+// EditExam saves the edit exam form: the exam details, the question settings and the text of the
+// exam's own questions. Everything is checked before the first write, so a rejected form changes nothing.
+func (service *TeacherServiceImpl) EditExam(ctx context.Context, teacherId, examId string, request web.ExamEditRequest) error {
+	// Validate request
+	if err := service.Validate.Struct(request); err != nil {
+		return fmt.Errorf("failed to validate request body: %w", err)
+	}
+
 	// Open transaction
 	tx, err := service.DB.Begin(ctx)
 	if err != nil {
@@ -172,15 +586,446 @@ func (service *TeacherServiceImpl) UpdateExamById(ctx context.Context, examId, r
 	}
 	defer helper.CommitOrRollback(ctx, tx)
 
-	err = service.TeacherRepository.UpdateExamById(ctx, tx, examId, roomName, yearInt, durationInt)
+	if _, err := service.authorizeExam(ctx, tx, teacherId, examId, domain.ExamRoleEditor); err != nil {
+		return err
+	}
+
+	questions, err := service.TeacherRepository.FindQAByExamId(ctx, tx, examId)
+	if err != nil {
+		return fmt.Errorf("failed when calling FindQAByExamId repository: %w", err)
+	}
+	if request.QuestionDrawCount > len(questions) {
+		return ErrQuestionDrawCount
+	}
+
+	// Hanya soal milik ujian ini yang boleh diubah, soal dari bank diubah lewat halaman bank
+	examQuestions := map[string]domain.QAItem{}
+	for _, question := range questions {
+		examQuestions[question.Id] = question
+	}
+	for _, question := range request.Questions {
+		examQuestion, ok := examQuestions[question.Id]
+		if !ok {
+			return ErrQuestionNotFound
+		}
+		if examQuestion.BankQuestionId != "" {
+			return ErrBankQuestionLinked
+		}
+	}
+
+	err = service.TeacherRepository.UpdateExamById(ctx, tx, examId, request.RoomName, request.Year, request.Duration)
 	if err != nil {
 		return fmt.Errorf("failed when calling UpdateExamById repository: %w", err)
 	}
 
+	err = service.TeacherRepository.UpdateExamQuestionSettingsById(ctx, tx, examId, request.ShuffleQuestions, request.QuestionDrawCount)
+	if err != nil {
+		return fmt.Errorf("failed when calling UpdateExamQuestionSettingsById repository: %w", err)
+	}
+
+	for _, question := range request.Questions {
+		err = service.TeacherRepository.UpdateQuestionById(ctx, tx, question.Id, question.Question, question.Answer)
+		if err != nil {
+			return fmt.Errorf("failed when calling UpdateQuestionById repository: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// RegenerateQuestion returns the current question and a proposed replacement generated
+// from the exam's source document. Nothing is saved, the teacher must accept the proposal first.
+func (service *TeacherServiceImpl) RegenerateQuestion(ctx context.Context, examId, questionId string) (domain.QAItem, domain.QAItem, error) {
+	sourceURI, storageKey, original, questions, err := service.findRegenerateSource(ctx, examId, questionId)
+	if err != nil {
+		return domain.QAItem{}, domain.QAItem{}, err
+	}
+
+	// Handle Gemini API
+	client, err := genai.NewClient(ctx, option.WithAPIKey(service.Config.GeminiAPIKey))
+	if err != nil {
+		return domain.QAItem{}, domain.QAItem{}, fmt.Errorf("error when calling NewClient: %w", err)
+	}
+	defer client.Close()
+
+	// Files uploaded to Gemini expire, so upload the stored material again when the exam has one
+	if storageKey != "" {
+		sourceURI, err = service.uploadStoredPDF(ctx, client, storageKey)
+		if err != nil {
+			return domain.QAItem{}, domain.QAItem{}, err
+		}
+	}
+
+	for attempt := 1; attempt <= maxRegenerateAttempts; attempt++ {
+		proposed, err := helper.GenerateReplacementQA(ctx, client, sourceURI, original, questions)
+		if err != nil {
+			return domain.QAItem{}, domain.QAItem{}, fmt.Errorf("failed when calling GenerateReplacementQA helper: %w", err)
+		}
+
+		if !isDuplicateQuestion(proposed, questions) {
+			proposed.Id = original.Id
+			proposed.ExamId = original.ExamId
+			return original, proposed, nil
+		}
+	}
+
+	return domain.QAItem{}, domain.QAItem{}, errors.New("generator kept returning duplicate questions")
+}
+
+func (service *TeacherServiceImpl) findRegenerateSource(ctx context.Context, examId, questionId string) (string, string, domain.QAItem, []domain.QAItem, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return "", "", domain.QAItem{}, nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	sourceURI, storageKey, err := service.TeacherRepository.FindExamSourceById(ctx, tx, examId)
+	if err != nil {
+		return "", "", domain.QAItem{}, nil, fmt.Errorf("failed when calling FindExamSourceById repository: %w", err)
+	}
+	if sourceURI == "" && storageKey == "" {
+		return "", "", domain.QAItem{}, nil, errors.New("exam has no source document")
+	}
+
+	original, err := service.TeacherRepository.FindQuestionById(ctx, tx, questionId)
+	if err != nil {
+		return "", "", domain.QAItem{}, nil, fmt.Errorf("failed when calling FindQuestionById repository: %w", err)
+	}
+	if original.ExamId != examId {
+		return "", "", domain.QAItem{}, nil, errors.New("question does not belong to exam")
+	}
+	if original.BankQuestionId != "" {
+		return "", "", domain.QAItem{}, nil, ErrBankQuestionLinked
+	}
+
+	questions, err := service.TeacherRepository.FindQAByExamId(ctx, tx, examId)
+	if err != nil {
+		return "", "", domain.QAItem{}, nil, fmt.Errorf("failed when calling FindQAByExamId repository: %w", err)
+	}
+
+	return sourceURI, storageKey, original, questions, nil
+}
+
+// uploadStoredPDF sends a document from the file store to Gemini and returns its file URI
+func (service *TeacherServiceImpl) uploadStoredPDF(ctx context.Context, client *genai.Client, storageKey string) (string, error) {
+	file, err := service.FileStore.Open(ctx, storageKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to open material from file store: %w", err)
+	}
+	defer file.Close()
+
+	fileURL, err := helper.UploadPDF(ctx, client, file)
+	if err != nil {
+		return "", fmt.Errorf("failed when calling UploadPDF helper: %w", err)
+	}
+
+	return fileURL, nil
+}
+
+// isDuplicateQuestion reports whether candidate is the same as or a near duplicate of one of questions
+func isDuplicateQuestion(candidate domain.QAItem, questions []domain.QAItem) bool {
+	normalized := helper.NormalizeText(candidate.Question)
+	if normalized == "" {
+		return true
+	}
+
+	for _, question := range questions {
+		if questionSimilarity(candidate.Question, question.Question) >= nearDuplicateThreshold {
+			return true
+		}
+	}
+
+	return false
+}
+
+// questionSimilarity returns 1 for texts that are equal after normalization and the shingle Jaccard similarity otherwise
+func questionSimilarity(a, b string) float64 {
+	if helper.NormalizeText(a) == helper.NormalizeText(b) {
+		return 1
+	}
+
+	return helper.TextSimilarity(a, b)
+}
+
+// CheckDuplicateQuestions compares every question of the exam with the other questions of the same exam
+// and with the questions of the teacher's other exams
+func (service *TeacherServiceImpl) CheckDuplicateQuestions(ctx context.Context, teacherId, examId string) ([]web.DuplicateWarning, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	questions, err := service.TeacherRepository.FindQAByExamId(ctx, tx, examId)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindQAByExamId repository: %w", err)
+	}
+
+	teacherQuestions, err := service.TeacherRepository.FindQAByTeacherId(ctx, tx, teacherId)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindQAByTeacherId repository: %w", err)
+	}
+
+	exams, err := service.TeacherRepository.FindExamsByUserId(ctx, tx, teacherId, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindExamsByUserId repository: %w", err)
+	}
+
+	roomNames := map[string]string{}
+	for _, exam := range exams {
+		roomNames[exam.Id] = exam.RoomName
+	}
+
+	otherQuestions := []domain.QAItem{}
+	for _, question := range teacherQuestions {
+		if question.ExamId != examId {
+			otherQuestions = append(otherQuestions, question)
+		}
+	}
+
+	return findDuplicateQuestions(questions, otherQuestions, roomNames), nil
+}
+
+// findDuplicateQuestions returns one warning per similar pair inside questions and one per match in otherQuestions
+func findDuplicateQuestions(questions, otherQuestions []domain.QAItem, roomNames map[string]string) []web.DuplicateWarning {
+	warnings := []web.DuplicateWarning{}
+
+	for i, question := range questions {
+		for j := i + 1; j < len(questions); j++ {
+			similarity := questionSimilarity(question.Question, questions[j].Question)
+			if similarity < nearDuplicateThreshold {
+				continue
+			}
+
+			warnings = append(warnings, web.DuplicateWarning{
+				QuestionId:      question.Id,
+				Question:        question.Question,
+				MatchQuestionId: questions[j].Id,
+				MatchExamId:     questions[j].ExamId,
+				MatchQuestion:   questions[j].Question,
+				Similarity:      int(similarity * 100),
+			})
+		}
+
+		for _, other := range otherQuestions {
+			similarity := questionSimilarity(question.Question, other.Question)
+			if similarity < nearDuplicateThreshold {
+				continue
+			}
+
+			warnings = append(warnings, web.DuplicateWarning{
+				QuestionId:      question.Id,
+				Question:        question.Question,
+				MatchQuestionId: other.Id,
+				MatchExamId:     other.ExamId,
+				MatchRoomName:   roomNames[other.ExamId],
+				MatchQuestion:   other.Question,
+				Similarity:      int(similarity * 100),
+			})
+		}
+	}
+
+	return warnings
+}
+
+// CreateExam creates an exam with questions written by the teacher, the exam starts inactive
+func (service *TeacherServiceImpl) CreateExam(ctx context.Context, teacherId string, request web.ExamCreateRequest) (domain.Exam, error) {
+	// Validate request
+	if err := service.Validate.Struct(request); err != nil {
+		return domain.Exam{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.Exam{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	examId := "EXAM-" + uuid.NewString()[:8]
+	err = service.TeacherRepository.SaveExam(ctx, tx, domain.Exam{
+		RoomName: request.RoomName,
+		Year:     request.Year,
+		Duration: request.Duration,
+	}, teacherId, examId)
+	if err != nil {
+		return domain.Exam{}, fmt.Errorf("failed when calling SaveExam repository: %w", err)
+	}
+
+	for _, questionRequest := range request.Questions {
+		_, err := service.TeacherRepository.SaveQuestion(ctx, tx, domain.QAItem{
+			Question: questionRequest.Question,
+			Answer:   questionRequest.Answer,
+			ExamId:   examId,
+		})
+		if err != nil {
+			return domain.Exam{}, fmt.Errorf("failed when calling SaveQuestion repository: %w", err)
+		}
+	}
+
+	return service.authorizeExam(ctx, tx, teacherId, examId, domain.ExamRoleOwner)
+}
+
+// UpdateExam replaces the exam details and question settings and activates or deactivates the exam
+func (service *TeacherServiceImpl) UpdateExam(ctx context.Context, teacherId, examId string, request web.ExamUpdateRequest) (domain.Exam, error) {
+	// Validate request
+	if err := service.Validate.Struct(request); err != nil {
+		return domain.Exam{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.Exam{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	exam, err := service.authorizeExam(ctx, tx, teacherId, examId, domain.ExamRoleEditor)
+	if err != nil {
+		return domain.Exam{}, err
+	}
+
+	questions, err := service.TeacherRepository.FindQAByExamId(ctx, tx, examId)
+	if err != nil {
+		return domain.Exam{}, fmt.Errorf("failed when calling FindQAByExamId repository: %w", err)
+	}
+	if request.QuestionDrawCount > len(questions) {
+		return domain.Exam{}, ErrQuestionDrawCount
+	}
+
+	err = service.TeacherRepository.UpdateExamById(ctx, tx, examId, request.RoomName, request.Year, request.Duration)
+	if err != nil {
+		return domain.Exam{}, fmt.Errorf("failed when calling UpdateExamById repository: %w", err)
+	}
+
+	err = service.TeacherRepository.UpdateExamQuestionSettingsById(ctx, tx, examId, request.ShuffleQuestions, request.QuestionDrawCount)
+	if err != nil {
+		return domain.Exam{}, fmt.Errorf("failed when calling UpdateExamQuestionSettingsById repository: %w", err)
+	}
+
+	// Repository membalik status aktif, jadi hanya dipanggil kalau statusnya memang berubah
+	if request.IsActive != exam.IsActive {
+		err = service.TeacherRepository.UpdateIsActiveExamById(ctx, tx, examId, exam.IsActive)
+		if err != nil {
+			return domain.Exam{}, fmt.Errorf("failed when calling UpdateIsActiveExamById repository: %w", err)
+		}
+	}
+
+	updatedExam, err := service.authorizeExam(ctx, tx, teacherId, examId, domain.ExamRoleEditor)
+	if err != nil {
+		return domain.Exam{}, err
+	}
+
+	if updatedExam.IsActive && !exam.IsActive {
+		if err := service.enqueueExamActivated(ctx, tx, updatedExam); err != nil {
+			return domain.Exam{}, err
+		}
+	}
+
+	return updatedExam, nil
+}
+
+// enqueueExamActivated queues exam.activated in the transaction that activated the exam
+func (service *TeacherServiceImpl) enqueueExamActivated(ctx context.Context, tx pgx.Tx, exam domain.Exam) error {
+	return enqueueWebhookEvent(ctx, tx, service.WebhookRepository, domain.WebhookEventExamActivated, exam.Id, api.ExamEventData{
+		ExamId:    exam.Id,
+		Name:      exam.RoomName,
+		Year:      exam.Year,
+		TeacherId: exam.TeacherId,
+		IsActive:  exam.IsActive,
+	})
+}
+
+// DeleteExam removes the exam with its questions and every attempt, only owners can do this
+func (service *TeacherServiceImpl) DeleteExam(ctx context.Context, teacherId, examId string) error {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if _, err := service.authorizeExam(ctx, tx, teacherId, examId, domain.ExamRoleOwner); err != nil {
+		return err
+	}
+
+	err = service.TeacherRepository.DeleteExamById(ctx, tx, examId)
+	if err != nil {
+		return fmt.Errorf("failed when calling DeleteExamById repository: %w", err)
+	}
+
 	return nil
 }
 
-func (service *TeacherServiceImpl) UpdateQuestionById(ctx context.Context, questionId, questionText, answerText string) error {
+func (service *TeacherServiceImpl) CreateQuestion(ctx context.Context, teacherId, examId string, request web.QuestionSaveRequest) (domain.QAItem, error) {
+	// Validate request
+	if err := service.Validate.Struct(request); err != nil {
+		return domain.QAItem{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.QAItem{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if _, err := service.authorizeExam(ctx, tx, teacherId, examId, domain.ExamRoleEditor); err != nil {
+		return domain.QAItem{}, err
+	}
+
+	question, err := service.TeacherRepository.SaveQuestion(ctx, tx, domain.QAItem{
+		Question: request.Question,
+		Answer:   request.Answer,
+		ExamId:   examId,
+	})
+	if err != nil {
+		return domain.QAItem{}, fmt.Errorf("failed when calling SaveQuestion repository: %w", err)
+	}
+
+	return question, nil
+}
+
+// UpdateQuestion changes the question text. A question from the bank returns ErrBankQuestionLinked,
+// its text is read from the bank question and only changes there.
+func (service *TeacherServiceImpl) UpdateQuestion(ctx context.Context, teacherId, examId, questionId string, request web.QuestionSaveRequest) (domain.QAItem, error) {
+	// Validate request
+	if err := service.Validate.Struct(request); err != nil {
+		return domain.QAItem{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.QAItem{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if _, err := service.authorizeExam(ctx, tx, teacherId, examId, domain.ExamRoleEditor); err != nil {
+		return domain.QAItem{}, err
+	}
+
+	question, err := service.findExamQuestion(ctx, tx, examId, questionId)
+	if err != nil {
+		return domain.QAItem{}, err
+	}
+	if question.BankQuestionId != "" {
+		return domain.QAItem{}, ErrBankQuestionLinked
+	}
+
+	err = service.TeacherRepository.UpdateQuestionById(ctx, tx, questionId, request.Question, request.Answer)
+	if err != nil {
+		return domain.QAItem{}, fmt.Errorf("failed when calling UpdateQuestionById repository: %w", err)
+	}
+
+	return service.findExamQuestion(ctx, tx, examId, questionId)
+}
+
+// DeleteQuestion removes the question from the exam. The draw count is lowered when the exam
+// would otherwise draw more questions than it has left.
+func (service *TeacherServiceImpl) DeleteQuestion(ctx context.Context, teacherId, examId, questionId string) error {
 	// Open transaction
 	tx, err := service.DB.Begin(ctx)
 	if err != nil {
@@ -188,15 +1033,56 @@ func (service *TeacherServiceImpl) UpdateQuestionById(ctx context.Context, quest
 	}
 	defer helper.CommitOrRollback(ctx, tx)
 
-	err = service.TeacherRepository.UpdateQuestionById(ctx, tx, questionId, questionText, answerText)
+	exam, err := service.authorizeExam(ctx, tx, teacherId, examId, domain.ExamRoleEditor)
+	if err != nil {
+		return err
+	}
+
+	if _, err := service.findExamQuestion(ctx, tx, examId, questionId); err != nil {
+		return err
+	}
+
+	err = service.TeacherRepository.DeleteQuestionById(ctx, tx, questionId)
 	if err != nil {
-		return fmt.Errorf("failed when calling UpdateQuestionById repository: %w", err)
+		return fmt.Errorf("failed when calling DeleteQuestionById repository: %w", err)
+	}
+
+	questions, err := service.TeacherRepository.FindQAByExamId(ctx, tx, examId)
+	if err != nil {
+		return fmt.Errorf("failed when calling FindQAByExamId repository: %w", err)
+	}
+	if exam.QuestionDrawCount > len(questions) {
+		err = service.TeacherRepository.UpdateExamQuestionSettingsById(ctx, tx, examId, exam.ShuffleQuestions, len(questions))
+		if err != nil {
+			return fmt.Errorf("failed when calling UpdateExamQuestionSettingsById repository: %w", err)
+		}
 	}
 
 	return nil
 }
 
-func (service *TeacherServiceImpl) GetBiggestExamAttemptsScoreByExamId(ctx context.Context, examId string) ([]web.ExamAttempt, error) {
+// findExamQuestion returns ErrQuestionNotFound unless the question belongs to the exam
+func (service *TeacherServiceImpl) findExamQuestion(ctx context.Context, tx pgx.Tx, examId, questionId string) (domain.QAItem, error) {
+	if uuid.Validate(questionId) != nil {
+		return domain.QAItem{}, ErrQuestionNotFound
+	}
+
+	question, err := service.TeacherRepository.FindQuestionById(ctx, tx, questionId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.QAItem{}, ErrQuestionNotFound
+		}
+
+		return domain.QAItem{}, fmt.Errorf("failed when calling FindQuestionById repository: %w", err)
+	}
+	if question.ExamId != examId {
+		return domain.QAItem{}, ErrQuestionNotFound
+	}
+
+	return question, nil
+}
+
+func (service *TeacherServiceImpl) GetBiggestExamAttemptsScoreByExamId(ctx context.Context, examId, classId string) ([]web.ExamAttempt, error) {
 	// Open transaction
 	tx, err := service.DB.Begin(ctx)
 	if err != nil {
@@ -204,7 +1090,7 @@ func (service *TeacherServiceImpl) GetBiggestExamAttemptsScoreByExamId(ctx conte
 	}
 	defer helper.CommitOrRollback(ctx, tx)
 
-	attempts, err := service.TeacherRepository.FindBiggestAttemptsByExamId(ctx, tx, examId)
+	attempts, err := service.TeacherRepository.FindBiggestAttemptsByExamId(ctx, tx, examId, classId)
 	if err != nil {
 		return nil, fmt.Errorf("failed when calling FindBiggestAttemptsByExamId repository: %w", err)
 	}
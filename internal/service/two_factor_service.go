@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+)
+
+type TwoFactorService interface {
+	// Enrollment from the account page
+	GetStatus(ctx context.Context, user domain.User) (web.TwoFactorStatus, error)
+	BeginEnrollment(ctx context.Context, user domain.User) error
+	ConfirmEnrollment(ctx context.Context, user domain.User, request web.TwoFactorCodeRequest) ([]string, error)
+	RegenerateRecoveryCodes(ctx context.Context, user domain.User, request web.TwoFactorCodeRequest) ([]string, error)
+	Disable(ctx context.Context, user domain.User, request web.TwoFactorDisableRequest) error
+	IsEnrollmentRequired(ctx context.Context, user domain.User) (bool, error)
+
+	// Second login step
+	CreateChallenge(ctx context.Context, user domain.User) (string, error)
+	VerifyChallenge(ctx context.Context, request web.TwoFactorLoginRequest) (string, domain.User, error)
+}
@@ -0,0 +1,445 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/repository"
+)
+
+const (
+	// twoFactorIssuer is the account label shown in authenticator apps
+	twoFactorIssuer = "SayGenFix"
+	// twoFactorChallengeTTL is how long the second login step waits for a code
+	twoFactorChallengeTTL = 5 * time.Minute
+	// maxTwoFactorAttempts is how many wrong codes one challenge accepts before the password is needed again
+	maxTwoFactorAttempts = 5
+	recoveryCodeCount    = 10
+)
+
+// settingRequireTeacherTwoFactor is the system setting admins toggle from the dashboard
+const settingRequireTeacherTwoFactor = "require_teacher_two_factor"
+
+var (
+	// ErrTwoFactorCodeInvalid is returned when the TOTP or recovery code does not match
+	ErrTwoFactorCodeInvalid = errors.New("two-factor code is invalid")
+	// ErrTwoFactorChallengeInvalid is returned when the second login step expired or had too many wrong codes
+	ErrTwoFactorChallengeInvalid = errors.New("two-factor challenge is invalid")
+	// ErrTwoFactorAlreadyEnabled is returned when enrollment starts while 2FA is already on
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorNotEnabled is returned when 2FA is not on or enrollment was not started
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrTwoFactorEnforced is returned when a teacher turns off 2FA while the admin requires it
+	ErrTwoFactorEnforced = errors.New("two-factor authentication is required by the admin")
+)
+
+func NewTwoFactorService(twoFactorRepository repository.TwoFactorRepository, userRepository repository.UserRepository, authRepository repository.AuthRepository, settingRepository repository.SettingRepository, db *pgxpool.Pool, validate *validator.Validate) TwoFactorService {
+	return &TwoFactorServiceImpl{
+		TwoFactorRepository: twoFactorRepository,
+		UserRepository:      userRepository,
+		AuthRepository:      authRepository,
+		SettingRepository:   settingRepository,
+		DB:                  db,
+		Validate:            validate,
+	}
+}
+
+type TwoFactorServiceImpl struct {
+	TwoFactorRepository repository.TwoFactorRepository
+	UserRepository      repository.UserRepository
+	AuthRepository      repository.AuthRepository
+	SettingRepository   repository.SettingRepository
+	DB                  *pgxpool.Pool
+	Validate            *validator.Validate
+}
+
+func (service *TwoFactorServiceImpl) GetStatus(ctx context.Context, user domain.User) (web.TwoFactorStatus, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return web.TwoFactorStatus{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	twoFactor, err := service.TwoFactorRepository.FindByUserId(ctx, tx, user.Id)
+	if err != nil {
+		return web.TwoFactorStatus{}, fmt.Errorf("failed when calling FindByUserId repository: %w", err)
+	}
+
+	isRequired, err := service.isRequiredForRole(ctx, tx, user.Role)
+	if err != nil {
+		return web.TwoFactorStatus{}, err
+	}
+
+	status := web.TwoFactorStatus{
+		IsEnabled:  twoFactor.IsEnabled,
+		IsRequired: isRequired,
+	}
+
+	if twoFactor.IsEnabled {
+		status.RecoveryCodesLeft, err = service.TwoFactorRepository.CountUnusedRecoveryCodes(ctx, tx, user.Id)
+		if err != nil {
+			return web.TwoFactorStatus{}, fmt.Errorf("failed when calling CountUnusedRecoveryCodes repository: %w", err)
+		}
+	} else if twoFactor.Secret != "" {
+		status.PendingSecret = twoFactor.Secret
+		status.ProvisioningURI = helper.TOTPProvisioningURI(twoFactorIssuer, user.Email, twoFactor.Secret)
+	}
+
+	return status, nil
+}
+
+// BeginEnrollment stores a new secret that becomes active once ConfirmEnrollment gets a valid code.
+// Starting again replaces the pending secret.
+func (service *TwoFactorServiceImpl) BeginEnrollment(ctx context.Context, user domain.User) error {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	twoFactor, err := service.TwoFactorRepository.FindByUserId(ctx, tx, user.Id)
+	if err != nil {
+		return fmt.Errorf("failed when calling FindByUserId repository: %w", err)
+	}
+	if twoFactor.IsEnabled {
+		return ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := helper.GenerateTOTPSecret()
+	if err != nil {
+		return fmt.Errorf("failed when calling GenerateTOTPSecret helper: %w", err)
+	}
+
+	if err := service.TwoFactorRepository.SaveSecret(ctx, tx, user.Id, secret); err != nil {
+		return fmt.Errorf("failed when calling SaveSecret repository: %w", err)
+	}
+
+	return nil
+}
+
+// ConfirmEnrollment turns on 2FA when the code matches the pending secret and returns the recovery codes
+func (service *TwoFactorServiceImpl) ConfirmEnrollment(ctx context.Context, user domain.User, request web.TwoFactorCodeRequest) ([]string, error) {
+	// Validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	twoFactor, err := service.TwoFactorRepository.FindByUserId(ctx, tx, user.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindByUserId repository: %w", err)
+	}
+	if twoFactor.IsEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if twoFactor.Secret == "" {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	counter, ok := helper.ValidateTOTP(twoFactor.Secret, request.Code, time.Now())
+	if !ok {
+		return nil, ErrTwoFactorCodeInvalid
+	}
+
+	if err := service.TwoFactorRepository.Enable(ctx, tx, user.Id, counter); err != nil {
+		return nil, fmt.Errorf("failed when calling Enable repository: %w", err)
+	}
+
+	return service.saveRecoveryCodes(ctx, tx, user.Id)
+}
+
+// RegenerateRecoveryCodes replaces every recovery code, a current TOTP code is needed
+func (service *TwoFactorServiceImpl) RegenerateRecoveryCodes(ctx context.Context, user domain.User, request web.TwoFactorCodeRequest) ([]string, error) {
+	// Validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	twoFactor, err := service.TwoFactorRepository.FindByUserId(ctx, tx, user.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindByUserId repository: %w", err)
+	}
+	if !twoFactor.IsEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	ok, err := service.verifyCode(ctx, tx, twoFactor, request.Code, false)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrTwoFactorCodeInvalid
+	}
+
+	return service.saveRecoveryCodes(ctx, tx, user.Id)
+}
+
+// Disable turns off 2FA after checking the password and a code. Teachers can not turn it off
+// while the admin requires it.
+func (service *TwoFactorServiceImpl) Disable(ctx context.Context, user domain.User, request web.TwoFactorDisableRequest) error {
+	// Validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	isRequired, err := service.isRequiredForRole(ctx, tx, user.Role)
+	if err != nil {
+		return err
+	}
+	if isRequired {
+		return ErrTwoFactorEnforced
+	}
+
+	currentUser, err := service.UserRepository.FindById(ctx, tx, user.Id)
+	if err != nil {
+		return fmt.Errorf("failed when calling FindById repository: %w", err)
+	}
+	if !helper.CheckPasswordHash(currentUser.Password, request.Password) {
+		return ErrInvalidCredentials
+	}
+
+	twoFactor, err := service.TwoFactorRepository.FindByUserId(ctx, tx, user.Id)
+	if err != nil {
+		return fmt.Errorf("failed when calling FindByUserId repository: %w", err)
+	}
+	if !twoFactor.IsEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	ok, err := service.verifyCode(ctx, tx, twoFactor, request.Code, true)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrTwoFactorCodeInvalid
+	}
+
+	if err := service.TwoFactorRepository.Disable(ctx, tx, user.Id); err != nil {
+		return fmt.Errorf("failed when calling Disable repository: %w", err)
+	}
+
+	return nil
+}
+
+// IsEnrollmentRequired reports whether the user has to set up 2FA before using the panel
+func (service *TwoFactorServiceImpl) IsEnrollmentRequired(ctx context.Context, user domain.User) (bool, error) {
+	if user.TwoFactorEnabled {
+		return false, nil
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	return service.isRequiredForRole(ctx, tx, user.Role)
+}
+
+// CreateChallenge starts the second login step after the password matched and returns its token
+func (service *TwoFactorServiceImpl) CreateChallenge(ctx context.Context, user domain.User) (string, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	token, err := helper.GenerateToken()
+	if err != nil {
+		return "", fmt.Errorf("failed when calling GenerateToken helper: %w", err)
+	}
+
+	if _, err := service.TwoFactorRepository.SaveChallenge(ctx, tx, domain.TwoFactorChallenge{
+		UserId:    user.Id,
+		TokenHash: helper.HashToken(token),
+	}, twoFactorChallengeTTL); err != nil {
+		return "", fmt.Errorf("failed when calling SaveChallenge repository: %w", err)
+	}
+
+	return token, nil
+}
+
+// VerifyChallenge checks the TOTP or recovery code and creates the session. On
+// ErrTwoFactorCodeInvalid the user is still returned so the failure can be throttled by email.
+func (service *TwoFactorServiceImpl) VerifyChallenge(ctx context.Context, request web.TwoFactorLoginRequest) (string, domain.User, error) {
+	// Validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return "", domain.User{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return "", domain.User{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	challenge, err := service.TwoFactorRepository.FindValidChallengeByTokenHash(ctx, tx, helper.HashToken(request.Token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", domain.User{}, ErrTwoFactorChallengeInvalid
+		}
+
+		return "", domain.User{}, fmt.Errorf("failed when calling FindValidChallengeByTokenHash repository: %w", err)
+	}
+
+	if challenge.Attempts >= maxTwoFactorAttempts {
+		if err := service.TwoFactorRepository.DeleteChallenge(ctx, tx, challenge.Id); err != nil {
+			return "", domain.User{}, fmt.Errorf("failed when calling DeleteChallenge repository: %w", err)
+		}
+
+		return "", domain.User{}, ErrTwoFactorChallengeInvalid
+	}
+
+	user, err := service.UserRepository.FindById(ctx, tx, challenge.UserId)
+	if err != nil {
+		return "", domain.User{}, fmt.Errorf("failed when calling FindById repository: %w", err)
+	}
+
+	// Akun bisa dinonaktifkan admin di antara dua langkah login
+	if user.IsDisabled || !user.IsApproved {
+		if err := service.TwoFactorRepository.DeleteChallenge(ctx, tx, challenge.Id); err != nil {
+			return "", domain.User{}, fmt.Errorf("failed when calling DeleteChallenge repository: %w", err)
+		}
+
+		return "", domain.User{}, ErrAccountDisabled
+	}
+
+	twoFactor, err := service.TwoFactorRepository.FindByUserId(ctx, tx, user.Id)
+	if err != nil {
+		return "", domain.User{}, fmt.Errorf("failed when calling FindByUserId repository: %w", err)
+	}
+	if !twoFactor.IsEnabled {
+		return "", domain.User{}, ErrTwoFactorChallengeInvalid
+	}
+
+	ok, err := service.verifyCode(ctx, tx, twoFactor, request.Code, true)
+	if err != nil {
+		return "", domain.User{}, err
+	}
+	if !ok {
+		// Percobaan gagal tetap tersimpan karena transaksi selalu di-commit
+		if err := service.TwoFactorRepository.IncrementChallengeAttempts(ctx, tx, challenge.Id); err != nil {
+			return "", domain.User{}, fmt.Errorf("failed when calling IncrementChallengeAttempts repository: %w", err)
+		}
+
+		return "", user, ErrTwoFactorCodeInvalid
+	}
+
+	if err := service.TwoFactorRepository.DeleteChallenge(ctx, tx, challenge.Id); err != nil {
+		return "", domain.User{}, fmt.Errorf("failed when calling DeleteChallenge repository: %w", err)
+	}
+
+	// Save session to db
+	session, err := service.AuthRepository.Save(ctx, tx, domain.Session{
+		SessionId: helper.Base64SessionId(),
+		UserId:    user.Id,
+		UserAgent: truncateUserAgent(request.UserAgent),
+		IPAddress: request.IPAddress,
+	})
+	if err != nil {
+		return "", domain.User{}, fmt.Errorf("failed when calling Save repository: %w", err)
+	}
+
+	// Login berhasil mereset hitungan gagal untuk email ini
+	if err := service.AuthRepository.SaveLoginAttempt(ctx, tx, domain.LoginAttempt{
+		Email:     normalizeLoginEmail(user.Email),
+		IPAddress: request.IPAddress,
+		IsSuccess: true,
+	}); err != nil {
+		return "", domain.User{}, fmt.Errorf("failed when calling SaveLoginAttempt repository: %w", err)
+	}
+
+	return session.SessionId, user, nil
+}
+
+// verifyCode accepts a TOTP code that was not used before or, when allowed, an unused recovery code
+func (service *TwoFactorServiceImpl) verifyCode(ctx context.Context, tx pgx.Tx, twoFactor domain.TwoFactor, code string, allowRecoveryCode bool) (bool, error) {
+	if counter, ok := helper.ValidateTOTP(twoFactor.Secret, code, time.Now()); ok {
+		accepted, err := service.TwoFactorRepository.UpdateLastCounter(ctx, tx, twoFactor.UserId, counter)
+		if err != nil {
+			return false, fmt.Errorf("failed when calling UpdateLastCounter repository: %w", err)
+		}
+
+		return accepted, nil
+	}
+
+	if !allowRecoveryCode {
+		return false, nil
+	}
+
+	used, err := service.TwoFactorRepository.UseRecoveryCode(ctx, tx, twoFactor.UserId, helper.HashToken(helper.NormalizeRecoveryCode(code)))
+	if err != nil {
+		return false, fmt.Errorf("failed when calling UseRecoveryCode repository: %w", err)
+	}
+
+	return used, nil
+}
+
+func (service *TwoFactorServiceImpl) saveRecoveryCodes(ctx context.Context, tx pgx.Tx, userId string) ([]string, error) {
+	recoveryCodes, err := helper.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling GenerateRecoveryCodes helper: %w", err)
+	}
+
+	codeHashes := make([]string, 0, len(recoveryCodes))
+	for _, code := range recoveryCodes {
+		codeHashes = append(codeHashes, helper.HashToken(code))
+	}
+
+	if err := service.TwoFactorRepository.SaveRecoveryCodes(ctx, tx, userId, codeHashes); err != nil {
+		return nil, fmt.Errorf("failed when calling SaveRecoveryCodes repository: %w", err)
+	}
+
+	return recoveryCodes, nil
+}
+
+// isRequiredForRole is only true for teachers, the admin setting does not apply to other roles
+func (service *TwoFactorServiceImpl) isRequiredForRole(ctx context.Context, tx pgx.Tx, role string) (bool, error) {
+	if role != "teacher" {
+		return false, nil
+	}
+
+	isRequired, err := service.SettingRepository.FindBool(ctx, tx, settingRequireTeacherTwoFactor)
+	if err != nil {
+		return false, fmt.Errorf("failed when calling FindBool repository: %w", err)
+	}
+
+	return isRequired, nil
+}
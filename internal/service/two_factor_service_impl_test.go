@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+// testTOTPSecret is the SHA1 seed of RFC 6238 appendix B in base32
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// totpAt computes the code an authenticator app shows at the given time
+func totpAt(t *testing.T, at time.Time) string {
+	t.Helper()
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(testTOTPSecret)
+	if err != nil {
+		t.Fatalf("decoding secret: %v", err)
+	}
+
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], uint64(at.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+func TestTwoFactorVerifyCodeReplay(t *testing.T) {
+	ctx := context.Background()
+	twoFactor := domain.TwoFactor{UserId: "user-1", Secret: testTOTPSecret, IsEnabled: true}
+
+	t.Run("a code is accepted once", func(t *testing.T) {
+		service := &TwoFactorServiceImpl{TwoFactorRepository: &fakeTwoFactorRepository{lastCounters: map[string]int64{}}}
+		code := totpAt(t, time.Now())
+
+		accepted, err := service.verifyCode(ctx, nil, twoFactor, code, false)
+		if err != nil || !accepted {
+			t.Fatalf("first use = %v, %v, want accepted", accepted, err)
+		}
+
+		accepted, err = service.verifyCode(ctx, nil, twoFactor, code, false)
+		if err != nil || accepted {
+			t.Fatalf("replay = %v, %v, want rejected", accepted, err)
+		}
+	})
+
+	t.Run("an older code is rejected after a newer one", func(t *testing.T) {
+		service := &TwoFactorServiceImpl{TwoFactorRepository: &fakeTwoFactorRepository{lastCounters: map[string]int64{}}}
+		now := time.Now()
+
+		// Kode langkah berikutnya masih diterima karena toleransi selisih jam
+		accepted, err := service.verifyCode(ctx, nil, twoFactor, totpAt(t, now.Add(30*time.Second)), false)
+		if err != nil || !accepted {
+			t.Fatalf("next step code = %v, %v, want accepted", accepted, err)
+		}
+
+		accepted, err = service.verifyCode(ctx, nil, twoFactor, totpAt(t, now), false)
+		if err != nil || accepted {
+			t.Fatalf("older code = %v, %v, want rejected", accepted, err)
+		}
+	})
+
+	t.Run("expired code does not move the counter", func(t *testing.T) {
+		twoFactors := &fakeTwoFactorRepository{lastCounters: map[string]int64{}}
+		service := &TwoFactorServiceImpl{TwoFactorRepository: twoFactors}
+
+		accepted, err := service.verifyCode(ctx, nil, twoFactor, totpAt(t, time.Now().Add(-10*time.Minute)), false)
+		if err != nil || accepted {
+			t.Fatalf("expired code = %v, %v, want rejected", accepted, err)
+		}
+		if len(twoFactors.lastCounters) != 0 {
+			t.Fatalf("counter moved to %v", twoFactors.lastCounters)
+		}
+	})
+}
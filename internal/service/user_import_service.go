@@ -0,0 +1,13 @@
+package service
+
+import (
+	"context"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+)
+
+type UserImportService interface {
+	GetImporterClasses(ctx context.Context, importer domain.User) ([]domain.Class, error)
+	Import(ctx context.Context, importer domain.User, csvContent, mode string, dryRun bool) ([]web.UserImportResult, error)
+}
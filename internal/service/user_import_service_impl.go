@@ -0,0 +1,380 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mhaatha/go-template-saygenfix/internal/config"
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/repository"
+)
+
+// Import modes, password creates the accounts right away while invite only works for students
+// in a class and lets them register themselves. Teachers always invite, so the import does not
+// tell them which emails already have an account.
+const (
+	UserImportModePassword = "password"
+	UserImportModeInvite   = "invite"
+)
+
+// Per row import statuses
+const (
+	UserImportStatusValid   = "valid"
+	UserImportStatusCreated = "created"
+	UserImportStatusAdded   = "added"
+	UserImportStatusInvited = "invited"
+	UserImportStatusSkipped = "skipped"
+	UserImportStatusError   = "error"
+)
+
+// maxImportRows keeps a single import within one request
+const maxImportRows = 1000
+
+// temporaryPasswordTTL is how long a temporary password from an import can be used to log in
+const temporaryPasswordTTL = 7 * 24 * time.Hour
+
+var (
+	ErrImportEmpty       = errors.New("csv file has no rows")
+	ErrImportTooManyRows = fmt.Errorf("csv file has more than %d rows", maxImportRows)
+)
+
+func NewUserImportService(userRepository repository.UserRepository, classRepository repository.ClassRepository, db *pgxpool.Pool, validate *validator.Validate, cfg *config.Config) UserImportService {
+	return &UserImportServiceImpl{
+		UserRepository:  userRepository,
+		ClassRepository: classRepository,
+		DB:              db,
+		Validate:        validate,
+		Config:          cfg,
+	}
+}
+
+type UserImportServiceImpl struct {
+	UserRepository  repository.UserRepository
+	ClassRepository repository.ClassRepository
+	DB              *pgxpool.Pool
+	Validate        *validator.Validate
+	Config          *config.Config
+}
+
+// GetImporterClasses returns the classes a teacher can import into, admins pick classes by join code
+func (service *UserImportServiceImpl) GetImporterClasses(ctx context.Context, importer domain.User) ([]domain.Class, error) {
+	if importer.Role != "teacher" {
+		return []domain.Class{}, nil
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	classes, err := service.ClassRepository.FindByTeacherId(ctx, tx, importer.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindByTeacherId repository: %w", err)
+	}
+
+	return classes, nil
+}
+
+// Import registers the users in the CSV (email, full name, role, optional class) and reports
+// the outcome per row. Invalid rows are skipped, the rest is still imported. With dryRun
+// nothing is written and the report shows what would happen.
+func (service *UserImportServiceImpl) Import(ctx context.Context, importer domain.User, csvContent, mode string, dryRun bool) ([]web.UserImportResult, error) {
+	if mode != UserImportModeInvite {
+		mode = UserImportModePassword
+	}
+	if importer.Role == "teacher" {
+		mode = UserImportModeInvite
+	}
+
+	records, firstLine, err := parseImportCSV(csvContent)
+	if err != nil {
+		return nil, err
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	teacherClasses := []domain.Class{}
+	if importer.Role == "teacher" {
+		teacherClasses, err = service.ClassRepository.FindByTeacherId(ctx, tx, importer.Id)
+		if err != nil {
+			return nil, fmt.Errorf("failed when calling FindByTeacherId repository: %w", err)
+		}
+	}
+
+	results := []web.UserImportResult{}
+	seenEmails := map[string]bool{}
+	for i, record := range records {
+		row := importRowFromRecord(record)
+		result := web.UserImportResult{
+			Line:     firstLine + i,
+			Email:    row.Email,
+			FullName: row.FullName,
+			Role:     row.Role,
+			Class:    row.Class,
+		}
+
+		class, message, err := service.checkImportRow(ctx, tx, importer, teacherClasses, row, mode, seenEmails)
+		if err != nil {
+			return nil, err
+		}
+		seenEmails[row.Email] = true
+		if message != "" {
+			result.Status = UserImportStatusError
+			result.Message = message
+			results = append(results, result)
+			continue
+		}
+
+		result, err = service.importRow(ctx, tx, importer, result, row, class, mode, dryRun)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// checkImportRow validates the row and resolves its class. A non-empty message means the row is rejected.
+func (service *UserImportServiceImpl) checkImportRow(ctx context.Context, tx pgx.Tx, importer domain.User, teacherClasses []domain.Class, row web.UserImportRow, mode string, seenEmails map[string]bool) (domain.Class, string, error) {
+	if err := service.Validate.Struct(row); err != nil {
+		return domain.Class{}, importValidationMessage(err), nil
+	}
+	if seenEmails[row.Email] {
+		return domain.Class{}, "Email muncul lebih dari sekali di file", nil
+	}
+	if importer.Role == "teacher" && row.Role != "student" {
+		return domain.Class{}, "Guru hanya bisa mengimpor akun siswa", nil
+	}
+	if row.Class != "" && row.Role != "student" {
+		return domain.Class{}, "Kelas hanya bisa diisi untuk akun siswa", nil
+	}
+	if mode == UserImportModeInvite && row.Class == "" {
+		return domain.Class{}, "Mode undangan membutuhkan kolom kelas", nil
+	}
+	if row.Class == "" {
+		return domain.Class{}, "", nil
+	}
+
+	// Guru memakai nama atau kode kelas miliknya, admin memakai kode kelas
+	if importer.Role == "teacher" {
+		for _, class := range teacherClasses {
+			if strings.EqualFold(class.Name, row.Class) || class.JoinCode == strings.ToUpper(row.Class) {
+				return class, "", nil
+			}
+		}
+
+		return domain.Class{}, "Kelas tidak ditemukan di daftar kelas Anda", nil
+	}
+
+	class, err := service.ClassRepository.FindByJoinCode(ctx, tx, strings.ToUpper(row.Class))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Class{}, "Kode kelas tidak ditemukan", nil
+		}
+
+		return domain.Class{}, "", fmt.Errorf("failed when calling FindByJoinCode repository: %w", err)
+	}
+
+	return class, "", nil
+}
+
+func (service *UserImportServiceImpl) importRow(ctx context.Context, tx pgx.Tx, importer domain.User, result web.UserImportResult, row web.UserImportRow, class domain.Class, mode string, dryRun bool) (web.UserImportResult, error) {
+	// Guru tidak boleh tahu email mana yang sudah punya akun, setiap baris menjadi undangan
+	// yang diterima siswa setelah email tersebut terverifikasi
+	if importer.Role == "teacher" {
+		return service.inviteRow(ctx, tx, result, row, class, dryRun)
+	}
+
+	existingUser, err := service.UserRepository.FindByEmail(ctx, tx, row.Email)
+	if err != nil {
+		return web.UserImportResult{}, fmt.Errorf("failed when calling FindByEmail repository: %w", err)
+	}
+
+	// Email sudah terdaftar, admin cukup memasukkan siswa ke kelas
+	if existingUser.Id != "" {
+		if existingUser.Role != "student" || class.Id == "" {
+			result.Status = UserImportStatusSkipped
+			result.Message = "Email sudah terdaftar"
+			return result, nil
+		}
+
+		result.Class = class.Name
+		if dryRun {
+			result.Status = UserImportStatusValid
+			result.Message = "Siswa sudah terdaftar, akan ditambahkan ke kelas"
+			return result, nil
+		}
+
+		if err := service.ClassRepository.SaveMember(ctx, tx, class.Id, existingUser.Id); err != nil {
+			return web.UserImportResult{}, fmt.Errorf("failed when calling SaveMember repository: %w", err)
+		}
+		result.Status = UserImportStatusAdded
+		result.Message = "Siswa sudah terdaftar, ditambahkan ke kelas"
+		return result, nil
+	}
+
+	if mode == UserImportModeInvite {
+		return service.inviteRow(ctx, tx, result, row, class, dryRun)
+	}
+
+	result.Class = class.Name
+
+	if dryRun {
+		result.Status = UserImportStatusValid
+		result.Message = "Akun baru akan dibuat dengan kata sandi sementara"
+		return result, nil
+	}
+
+	temporaryPassword, err := helper.GenerateTemporaryPassword()
+	if err != nil {
+		return web.UserImportResult{}, fmt.Errorf("failed when calling GenerateTemporaryPassword helper: %w", err)
+	}
+
+	hashedPassword, err := helper.HashPassword(temporaryPassword)
+	if err != nil {
+		return web.UserImportResult{}, fmt.Errorf("failed when calling HashPassword: %w", err)
+	}
+
+	// Akun guru yang dibuat admin langsung disetujui
+	user, err := service.UserRepository.Save(ctx, tx, domain.User{
+		Email:      row.Email,
+		FullName:   row.FullName,
+		Password:   hashedPassword,
+		Role:       row.Role,
+		IsApproved: true,
+	})
+	if err != nil {
+		return web.UserImportResult{}, fmt.Errorf("failed when calling Save repository: %w", err)
+	}
+
+	if err := service.UserRepository.MarkTemporaryPassword(ctx, tx, user.Id, temporaryPasswordTTL); err != nil {
+		return web.UserImportResult{}, fmt.Errorf("failed when calling MarkTemporaryPassword repository: %w", err)
+	}
+
+	if class.Id != "" {
+		if err := service.ClassRepository.SaveMember(ctx, tx, class.Id, user.Id); err != nil {
+			return web.UserImportResult{}, fmt.Errorf("failed when calling SaveMember repository: %w", err)
+		}
+	}
+
+	result.Status = UserImportStatusCreated
+	result.Message = "Akun dibuat, kata sandi sementara berlaku 7 hari dan harus diganti saat login pertama"
+	result.TemporaryPassword = temporaryPassword
+	return result, nil
+}
+
+// inviteRow saves a class invitation for the email, it is claimed once the student has an
+// account with this email verified
+func (service *UserImportServiceImpl) inviteRow(ctx context.Context, tx pgx.Tx, result web.UserImportResult, row web.UserImportRow, class domain.Class, dryRun bool) (web.UserImportResult, error) {
+	result.Class = class.Name
+	if dryRun {
+		result.Status = UserImportStatusValid
+		result.Message = "Undangan kelas akan dibuat"
+		return result, nil
+	}
+
+	if err := service.ClassRepository.SaveInvitation(ctx, tx, class.Id, row.Email); err != nil {
+		return web.UserImportResult{}, fmt.Errorf("failed when calling SaveInvitation repository: %w", err)
+	}
+	result.Status = UserImportStatusInvited
+	result.Message = "Bagikan link pendaftaran, siswa masuk kelas otomatis setelah email ini terverifikasi"
+	result.RegistrationLink = service.registrationLink(row)
+	return result, nil
+}
+
+// registrationLink opens the register form filled with the invited student's email and name
+func (service *UserImportServiceImpl) registrationLink(row web.UserImportRow) string {
+	query := url.Values{}
+	query.Set("email", row.Email)
+	query.Set("full_name", row.FullName)
+	query.Set("role", "student")
+
+	return service.Config.AppBaseURL + "/register?" + query.Encode()
+}
+
+// parseImportCSV returns the data records and the line number of the first one. The header row is optional.
+func parseImportCSV(csvContent string) ([][]string, int, error) {
+	reader := csv.NewReader(strings.NewReader(strings.TrimPrefix(csvContent, "\ufeff")))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse csv: %w", err)
+	}
+
+	firstLine := 1
+	if len(records) > 0 && len(records[0]) > 0 && strings.EqualFold(strings.TrimSpace(records[0][0]), "email") {
+		records = records[1:]
+		firstLine = 2
+	}
+
+	if len(records) == 0 {
+		return nil, 0, ErrImportEmpty
+	}
+	if len(records) > maxImportRows {
+		return nil, 0, ErrImportTooManyRows
+	}
+
+	return records, firstLine, nil
+}
+
+func importRowFromRecord(record []string) web.UserImportRow {
+	field := func(index int) string {
+		if index < len(record) {
+			return strings.TrimSpace(record[index])
+		}
+		return ""
+	}
+
+	// Role kosong dianggap siswa
+	role := strings.ToLower(field(2))
+	if role == "" {
+		role = "student"
+	}
+
+	return web.UserImportRow{
+		Email:    strings.ToLower(field(0)),
+		FullName: field(1),
+		Role:     role,
+		Class:    field(3),
+	}
+}
+
+func importValidationMessage(err error) string {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) || len(validationErrors) == 0 {
+		return "Data tidak valid"
+	}
+
+	switch validationErrors[0].StructField() {
+	case "Email":
+		return "Email tidak valid"
+	case "FullName":
+		return "Nama harus 3-255 karakter dan hanya berisi huruf, spasi, titik, apostrof, atau tanda hubung"
+	case "Role":
+		return "Role harus student atau teacher"
+	case "Class":
+		return "Nama kelas terlalu panjang"
+	}
+
+	return "Data tidak valid"
+}
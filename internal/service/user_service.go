@@ -8,6 +8,14 @@ import (
 )
 
 type UserService interface {
-	RegisterNewUser(ctx context.Context, request web.RegisterUserRequest) error
+	RegisterNewUser(ctx context.Context, request web.RegisterUserRequest) (domain.User, error)
 	GetUserByEmail(ctx context.Context, email string) (domain.User, error)
+
+	// Account settings
+	GetProfile(ctx context.Context, userId string) (domain.User, error)
+	UpdateProfile(ctx context.Context, userId string, request web.UpdateProfileRequest) error
+	ChangePassword(ctx context.Context, userId, currentSessionId string, request web.ChangePasswordRequest) error
+	RequestEmailChange(ctx context.Context, userId string, request web.ChangeEmailRequest) (domain.User, error)
+	GetSharedExamCount(ctx context.Context, userId string) (int, error)
+	DeleteAccount(ctx context.Context, userId string, request web.DeleteAccountRequest) error
 }
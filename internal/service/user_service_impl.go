@@ -4,66 +4,105 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mhaatha/go-template-saygenfix/internal/config"
 	"github.com/mhaatha/go-template-saygenfix/internal/helper"
 	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
 	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
 	"github.com/mhaatha/go-template-saygenfix/internal/repository"
 )
 
-func NewUserService(userRepository repository.UserRepository, db *pgxpool.Pool, validate *validator.Validate) UserService {
+var (
+	// ErrEmailAlreadyUsed is returned when the new email belongs to another account
+	ErrEmailAlreadyUsed = errors.New("email already exists")
+	// ErrEmailUnchanged is returned when the new email is the current one
+	ErrEmailUnchanged = errors.New("email is the current email")
+	// ErrPasswordUnchanged is returned when a temporary password is "changed" to itself
+	ErrPasswordUnchanged = errors.New("new password is the temporary password")
+	// ErrLastAdmin is returned when the only active admin tries to delete their account
+	ErrLastAdmin = errors.New("the last admin account cannot be deleted")
+	// ErrSharedExamsOwned is returned when deleting the account would delete exams other
+	// teachers collaborate on or students already attempted
+	ErrSharedExamsOwned = errors.New("the account is the only owner of shared exams")
+)
+
+func NewUserService(userRepository repository.UserRepository, authRepository repository.AuthRepository, db *pgxpool.Pool, validate *validator.Validate, cfg *config.Config) UserService {
 	return &UserServiceImpl{
 		UserRepository: userRepository,
+		AuthRepository: authRepository,
 		DB:             db,
 		Validate:       validate,
+		Config:         cfg,
 	}
 }
 
 type UserServiceImpl struct {
 	UserRepository repository.UserRepository
+	AuthRepository repository.AuthRepository
 	DB             *pgxpool.Pool
 	Validate       *validator.Validate
+	Config         *config.Config
 }
 
-func (service *UserServiceImpl) RegisterNewUser(ctx context.Context, request web.RegisterUserRequest) error {
+// RegisterNewUser creates the account. Teacher sign-ups wait for admin approval, teachers with
+// an email domain in the auto-approve allowlist are approved once they verify the address.
+func (service *UserServiceImpl) RegisterNewUser(ctx context.Context, request web.RegisterUserRequest) (domain.User, error) {
 	// Validate request
 	err := service.Validate.Struct(request)
 	if err != nil {
-		return fmt.Errorf("failed to validate request body: %w", err)
+		return domain.User{}, fmt.Errorf("failed to validate request body: %w", err)
 	}
 
 	// Open transaction
 	tx, err := service.DB.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to open db transaction: %w", err)
+		return domain.User{}, fmt.Errorf("failed to open db transaction: %w", err)
 	}
 	defer helper.CommitOrRollback(ctx, tx)
 
 	// Check if email already exists
 	existingUser, err := service.UserRepository.FindByEmail(ctx, tx, request.Email)
 	if err != nil {
-		return fmt.Errorf("failed when calling FindByEmail repository: %w", err)
+		return domain.User{}, fmt.Errorf("failed when calling FindByEmail repository: %w", err)
 	}
 	if existingUser.Id != "" {
-		return errors.New("email already exists")
+		return domain.User{}, ErrEmailAlreadyUsed
 	}
 
 	// Hash password
 	hashedPassword, err := helper.HashPassword(request.Password)
 	if err != nil {
-		return fmt.Errorf("failed when calling HashPassword: %w", err)
+		return domain.User{}, fmt.Errorf("failed when calling HashPassword: %w", err)
 	}
 
 	user := domain.User{
-		Email:    request.Email,
-		FullName: request.FullName,
-		Password: hashedPassword,
-		Role:     request.Role,
+		Email:      request.Email,
+		FullName:   request.FullName,
+		Password:   hashedPassword,
+		Role:       request.Role,
+		IsApproved: request.Role != "teacher",
+	}
+
+	user, err = service.UserRepository.Save(ctx, tx, user)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed when calling Save repository: %w", err)
 	}
 
-	return service.UserRepository.Save(ctx, tx, user)
+	return user, nil
+}
+
+// isAutoApprovedDomain reports whether the email domain is in the teacher auto-approve allowlist
+func isAutoApprovedDomain(cfg *config.Config, email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+
+	return slices.Contains(cfg.TeacherAutoApproveDomains, strings.ToLower(email[at+1:]))
 }
 
 func (service *UserServiceImpl) GetUserByEmail(ctx context.Context, email string) (domain.User, error) {
@@ -85,3 +124,203 @@ func (service *UserServiceImpl) GetUserByEmail(ctx context.Context, email string
 
 	return user, nil
 }
+
+// GetProfile returns the user with the pending email, which the session user does not carry
+func (service *UserServiceImpl) GetProfile(ctx context.Context, userId string) (domain.User, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	user, err := service.UserRepository.FindById(ctx, tx, userId)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed when calling FindById repository: %w", err)
+	}
+
+	return user, nil
+}
+
+func (service *UserServiceImpl) UpdateProfile(ctx context.Context, userId string, request web.UpdateProfileRequest) error {
+	request.FullName = strings.TrimSpace(request.FullName)
+
+	// Validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	if err := service.UserRepository.UpdateFullName(ctx, tx, userId, request.FullName); err != nil {
+		return fmt.Errorf("failed when calling UpdateFullName repository: %w", err)
+	}
+
+	return nil
+}
+
+// ChangePassword checks the current password, then logs out every other device. A temporary
+// password from an import has to be replaced by a different one.
+func (service *UserServiceImpl) ChangePassword(ctx context.Context, userId, currentSessionId string, request web.ChangePasswordRequest) error {
+	// Validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	user, err := service.UserRepository.FindById(ctx, tx, userId)
+	if err != nil {
+		return fmt.Errorf("failed when calling FindById repository: %w", err)
+	}
+	if !helper.CheckPasswordHash(user.Password, request.CurrentPassword) {
+		return ErrInvalidCredentials
+	}
+	if user.MustChangePassword && request.NewPassword == request.CurrentPassword {
+		return ErrPasswordUnchanged
+	}
+
+	hashedPassword, err := helper.HashPassword(request.NewPassword)
+	if err != nil {
+		return fmt.Errorf("failed when calling HashPassword: %w", err)
+	}
+
+	if err := service.UserRepository.UpdatePassword(ctx, tx, userId, hashedPassword); err != nil {
+		return fmt.Errorf("failed when calling UpdatePassword repository: %w", err)
+	}
+
+	if _, err := service.AuthRepository.DeleteOtherSessions(ctx, tx, userId, currentSessionId); err != nil {
+		return fmt.Errorf("failed when calling DeleteOtherSessions repository: %w", err)
+	}
+
+	return nil
+}
+
+// RequestEmailChange stores the new email as pending. It only replaces the current email once
+// the link sent by EmailVerificationService.SendEmailChange is opened.
+func (service *UserServiceImpl) RequestEmailChange(ctx context.Context, userId string, request web.ChangeEmailRequest) (domain.User, error) {
+	request.Email = strings.ToLower(strings.TrimSpace(request.Email))
+
+	// Validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	user, err := service.UserRepository.FindById(ctx, tx, userId)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed when calling FindById repository: %w", err)
+	}
+	if !helper.CheckPasswordHash(user.Password, request.Password) {
+		return domain.User{}, ErrInvalidCredentials
+	}
+	if strings.EqualFold(user.Email, request.Email) {
+		return domain.User{}, ErrEmailUnchanged
+	}
+
+	existingUser, err := service.UserRepository.FindByEmail(ctx, tx, request.Email)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed when calling FindByEmail repository: %w", err)
+	}
+	if existingUser.Id != "" {
+		return domain.User{}, ErrEmailAlreadyUsed
+	}
+
+	if err := service.UserRepository.SavePendingEmail(ctx, tx, userId, request.Email); err != nil {
+		return domain.User{}, fmt.Errorf("failed when calling SavePendingEmail repository: %w", err)
+	}
+	user.PendingEmail = request.Email
+
+	return user, nil
+}
+
+// GetSharedExamCount returns how many exams block deleting the account, see ErrSharedExamsOwned
+func (service *UserServiceImpl) GetSharedExamCount(ctx context.Context, userId string) (int, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	count, err := service.UserRepository.CountSharedOwnedExams(ctx, tx, userId)
+	if err != nil {
+		return 0, fmt.Errorf("failed when calling CountSharedOwnedExams repository: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteAccount removes the user and everything owned by them. The last active admin is kept
+// so the school is not locked out of the admin panel. Exams with another owner are handed over
+// to them, and the account is kept while it is the only owner of exams with collaborators or
+// attempts so their results are not lost with it.
+func (service *UserServiceImpl) DeleteAccount(ctx context.Context, userId string, request web.DeleteAccountRequest) error {
+	// Validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return fmt.Errorf("failed to validate request body: %w", err)
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	user, err := service.UserRepository.FindById(ctx, tx, userId)
+	if err != nil {
+		return fmt.Errorf("failed when calling FindById repository: %w", err)
+	}
+	if !helper.CheckPasswordHash(user.Password, request.Password) {
+		return ErrInvalidCredentials
+	}
+
+	if user.Role == "admin" {
+		adminCount, err := service.UserRepository.CountActiveByRole(ctx, tx, "admin")
+		if err != nil {
+			return fmt.Errorf("failed when calling CountActiveByRole repository: %w", err)
+		}
+		if adminCount <= 1 {
+			return ErrLastAdmin
+		}
+	}
+
+	sharedExamCount, err := service.UserRepository.CountSharedOwnedExams(ctx, tx, userId)
+	if err != nil {
+		return fmt.Errorf("failed when calling CountSharedOwnedExams repository: %w", err)
+	}
+	if sharedExamCount > 0 {
+		return ErrSharedExamsOwned
+	}
+
+	if err := service.UserRepository.HandOverOwnedExams(ctx, tx, userId); err != nil {
+		return fmt.Errorf("failed when calling HandOverOwnedExams repository: %w", err)
+	}
+
+	if err := service.UserRepository.Delete(ctx, tx, userId); err != nil {
+		return fmt.Errorf("failed when calling Delete repository: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+)
+
+type WebhookService interface {
+	// Endpoint management on the webhook page
+	EventOptions() []web.WebhookEventOption
+	ListEndpoints(ctx context.Context, userId string) ([]domain.WebhookEndpoint, error)
+	ListDeliveries(ctx context.Context, userId string) ([]domain.WebhookDelivery, error)
+	CreateEndpoint(ctx context.Context, user domain.User, request web.WebhookEndpointCreateRequest) (domain.WebhookEndpoint, error)
+	SetEndpointActive(ctx context.Context, userId, endpointId string, isActive bool) error
+	RotateSecret(ctx context.Context, userId, endpointId string) (string, error)
+	DeleteEndpoint(ctx context.Context, userId, endpointId string) error
+	SendPing(ctx context.Context, userId, endpointId string) error
+	Redeliver(ctx context.Context, userId, deliveryId string) error
+
+	// Delivery worker
+	DeliverDue(ctx context.Context) (int, error)
+	RunDeliveries(ctx context.Context, interval time.Duration)
+}
@@ -0,0 +1,539 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mhaatha/go-template-saygenfix/internal/config"
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/api"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/web"
+	"github.com/mhaatha/go-template-saygenfix/internal/repository"
+)
+
+const (
+	// webhookSecretPrefix marks our signing secrets so they are easy to find by secret scanners
+	webhookSecretPrefix = "whsec_"
+	// maxWebhookEndpoints limits the endpoints a user can register
+	maxWebhookEndpoints = 10
+	// webhookDeliveryLimit is how many deliveries the delivery log shows
+	webhookDeliveryLimit = 50
+	// webhookBatchSize is how many deliveries one worker round sends
+	webhookBatchSize = 20
+	// webhookErrorLength is how much of the error or response body is kept for the delivery log
+	webhookErrorLength = 1000
+)
+
+var (
+	// ErrWebhookEndpointNotFound is returned when the endpoint does not belong to the user
+	ErrWebhookEndpointNotFound = errors.New("webhook endpoint not found")
+	// ErrWebhookEndpointLimit is returned when the user already has maxWebhookEndpoints endpoints
+	ErrWebhookEndpointLimit = errors.New("too many webhook endpoints")
+	// ErrWebhookDeliveryNotFound is returned when redelivering a delivery of another user or one still in the queue
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+
+	errWebhookPrivateAddress = errors.New("webhook endpoint resolves to a private or local address")
+)
+
+// webhookEvents lists the events endpoints can subscribe to, in the order shown on the form
+var webhookEvents = []web.WebhookEventOption{
+	{Value: domain.WebhookEventExamActivated, Label: "Ujian diaktifkan"},
+	{Value: domain.WebhookEventAttemptSubmitted, Label: "Siswa mengumpulkan ujian"},
+	{Value: domain.WebhookEventAttemptScored, Label: "Jawaban siswa selesai dinilai"},
+	{Value: domain.WebhookEventExamRegraded, Label: "Ujian dinilai ulang"},
+}
+
+// webhookRetryDelays is the wait before each retry, the last one repeats until WebhookMaxAttempts
+var webhookRetryDelays = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	15 * time.Minute,
+	time.Hour,
+	3 * time.Hour,
+	6 * time.Hour,
+	12 * time.Hour,
+}
+
+func NewWebhookService(webhookRepository repository.WebhookRepository, db *pgxpool.Pool, validate *validator.Validate, cfg *config.Config) WebhookService {
+	return &WebhookServiceImpl{
+		WebhookRepository: webhookRepository,
+		DB:                db,
+		Validate:          validate,
+		Config:            cfg,
+		Client:            newWebhookClient(cfg.WebhookTimeout, cfg.WebhookAllowPrivateNetworks),
+	}
+}
+
+type WebhookServiceImpl struct {
+	WebhookRepository repository.WebhookRepository
+	DB                *pgxpool.Pool
+	Validate          *validator.Validate
+	Config            *config.Config
+	// Client sends the deliveries, replace it to deliver to an httptest server
+	Client *http.Client
+}
+
+// newWebhookClient does not follow redirects and, unless allowPrivate is set, refuses to connect to
+// loopback, private and link-local addresses so endpoints can not be used to reach internal services.
+// The check runs on the resolved address, a DNS name pointing inside the network is refused as well.
+func newWebhookClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+
+			ip = ip.Unmap()
+			if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+				return errWebhookPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// enqueueWebhookEvent queues the event for every endpoint that should receive it. It runs in the
+// transaction of the change, so no event is sent for a change that was rolled back.
+func enqueueWebhookEvent(ctx context.Context, tx pgx.Tx, webhookRepository repository.WebhookRepository, event, examId string, data any) error {
+	payload, err := json.Marshal(api.WebhookEvent{
+		Id:        uuid.NewString(),
+		Type:      event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+
+	if _, err := webhookRepository.EnqueueEvent(ctx, tx, event, examId, webhookEventRoles(event), payload); err != nil {
+		return fmt.Errorf("failed when calling EnqueueEvent repository: %w", err)
+	}
+
+	return nil
+}
+
+// webhookEventRoles returns the collaborator roles that receive the event. Attempts and scores are
+// only sent to collaborators who may see the results of the exam, like the results page.
+func webhookEventRoles(event string) []string {
+	requiredRole := domain.ExamRoleViewer
+	switch event {
+	case domain.WebhookEventAttemptSubmitted, domain.WebhookEventAttemptScored, domain.WebhookEventExamRegraded:
+		requiredRole = domain.ExamRoleGrader
+	}
+
+	roles := []string{}
+	for role := range examRoleRank {
+		if hasExamRole(role, requiredRole) {
+			roles = append(roles, role)
+		}
+	}
+	slices.Sort(roles)
+
+	return roles
+}
+
+func (service *WebhookServiceImpl) EventOptions() []web.WebhookEventOption {
+	return webhookEvents
+}
+
+func (service *WebhookServiceImpl) ListEndpoints(ctx context.Context, userId string) ([]domain.WebhookEndpoint, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	endpoints, err := service.WebhookRepository.FindEndpointsByUserId(ctx, tx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindEndpointsByUserId repository: %w", err)
+	}
+
+	return endpoints, nil
+}
+
+func (service *WebhookServiceImpl) ListDeliveries(ctx context.Context, userId string) ([]domain.WebhookDelivery, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	deliveries, err := service.WebhookRepository.FindDeliveriesByUserId(ctx, tx, userId, webhookDeliveryLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling FindDeliveriesByUserId repository: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// CreateEndpoint returns the endpoint with its signing secret
+func (service *WebhookServiceImpl) CreateEndpoint(ctx context.Context, user domain.User, request web.WebhookEndpointCreateRequest) (domain.WebhookEndpoint, error) {
+	request.URL = strings.TrimSpace(request.URL)
+
+	// Validate request
+	err := service.Validate.Struct(request)
+	if err != nil {
+		return domain.WebhookEndpoint{}, fmt.Errorf("failed to validate request body: %w", err)
+	}
+	slices.Sort(request.Events)
+	request.Events = slices.Compact(request.Events)
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return domain.WebhookEndpoint{}, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	count, err := service.WebhookRepository.CountEndpointsByUserId(ctx, tx, user.Id)
+	if err != nil {
+		return domain.WebhookEndpoint{}, fmt.Errorf("failed when calling CountEndpointsByUserId repository: %w", err)
+	}
+	if count >= maxWebhookEndpoints {
+		return domain.WebhookEndpoint{}, ErrWebhookEndpointLimit
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return domain.WebhookEndpoint{}, err
+	}
+
+	endpoint, err := service.WebhookRepository.SaveEndpoint(ctx, tx, domain.WebhookEndpoint{
+		UserId: user.Id,
+		URL:    request.URL,
+		Secret: secret,
+		Events: request.Events,
+	})
+	if err != nil {
+		return domain.WebhookEndpoint{}, fmt.Errorf("failed when calling SaveEndpoint repository: %w", err)
+	}
+
+	return endpoint, nil
+}
+
+// SetEndpointActive pauses or resumes an endpoint, deliveries queued while it is paused are kept
+func (service *WebhookServiceImpl) SetEndpointActive(ctx context.Context, userId, endpointId string, isActive bool) error {
+	if uuid.Validate(endpointId) != nil {
+		return ErrWebhookEndpointNotFound
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	updated, err := service.WebhookRepository.UpdateEndpointActive(ctx, tx, userId, endpointId, isActive)
+	if err != nil {
+		return fmt.Errorf("failed when calling UpdateEndpointActive repository: %w", err)
+	}
+	if !updated {
+		return ErrWebhookEndpointNotFound
+	}
+
+	return nil
+}
+
+// RotateSecret replaces the signing secret, deliveries sent after this are signed with the new one
+func (service *WebhookServiceImpl) RotateSecret(ctx context.Context, userId, endpointId string) (string, error) {
+	if uuid.Validate(endpointId) != nil {
+		return "", ErrWebhookEndpointNotFound
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return "", err
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	updated, err := service.WebhookRepository.UpdateEndpointSecret(ctx, tx, userId, endpointId, secret)
+	if err != nil {
+		return "", fmt.Errorf("failed when calling UpdateEndpointSecret repository: %w", err)
+	}
+	if !updated {
+		return "", ErrWebhookEndpointNotFound
+	}
+
+	return secret, nil
+}
+
+func (service *WebhookServiceImpl) DeleteEndpoint(ctx context.Context, userId, endpointId string) error {
+	if uuid.Validate(endpointId) != nil {
+		return ErrWebhookEndpointNotFound
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	deleted, err := service.WebhookRepository.DeleteEndpoint(ctx, tx, userId, endpointId)
+	if err != nil {
+		return fmt.Errorf("failed when calling DeleteEndpoint repository: %w", err)
+	}
+	if !deleted {
+		return ErrWebhookEndpointNotFound
+	}
+
+	return nil
+}
+
+// SendPing queues a webhook.ping event for the endpoint only, so the receiver can be checked
+func (service *WebhookServiceImpl) SendPing(ctx context.Context, userId, endpointId string) error {
+	if uuid.Validate(endpointId) != nil {
+		return ErrWebhookEndpointNotFound
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	endpoint, err := service.WebhookRepository.FindEndpointById(ctx, tx, userId, endpointId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrWebhookEndpointNotFound
+		}
+
+		return fmt.Errorf("failed when calling FindEndpointById repository: %w", err)
+	}
+
+	payload, err := json.Marshal(api.WebhookEvent{
+		Id:        uuid.NewString(),
+		Type:      domain.WebhookEventPing,
+		CreatedAt: time.Now().UTC(),
+		Data:      api.WebhookPingData{EndpointId: endpoint.Id, Message: "Test event from SayGenFix"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+
+	if err := service.WebhookRepository.SaveDelivery(ctx, tx, endpoint.Id, domain.WebhookEventPing, payload); err != nil {
+		return fmt.Errorf("failed when calling SaveDelivery repository: %w", err)
+	}
+
+	return nil
+}
+
+// Redeliver puts a succeeded or failed delivery back in the queue with the same payload
+func (service *WebhookServiceImpl) Redeliver(ctx context.Context, userId, deliveryId string) error {
+	if uuid.Validate(deliveryId) != nil {
+		return ErrWebhookDeliveryNotFound
+	}
+
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	queued, err := service.WebhookRepository.Redeliver(ctx, tx, userId, deliveryId)
+	if err != nil {
+		return fmt.Errorf("failed when calling Redeliver repository: %w", err)
+	}
+	if !queued {
+		return ErrWebhookDeliveryNotFound
+	}
+
+	return nil
+}
+
+// DeliverDue sends one batch of due deliveries and returns how many were attempted
+func (service *WebhookServiceImpl) DeliverDue(ctx context.Context) (int, error) {
+	deliveries, err := service.claimDueDeliveries(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range deliveries {
+		statusCode, sendErr := service.send(ctx, delivery)
+		if err := service.recordAttempt(ctx, delivery, statusCode, sendErr); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// RunDeliveries sends due deliveries until ctx is done. A full batch is followed by the next one
+// right away, otherwise the worker waits for the next tick.
+func (service *WebhookServiceImpl) RunDeliveries(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			sent, err := service.DeliverDue(ctx)
+			if err != nil {
+				slog.Error("failed to deliver webhooks", "err", err)
+				break
+			}
+			if sent < webhookBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// claimDueDeliveries commits the claim before sending, so a slow endpoint does not hold the row locks
+func (service *WebhookServiceImpl) claimDueDeliveries(ctx context.Context) ([]domain.WebhookDelivery, error) {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	// Lease cukup untuk satu batch penuh yang semuanya timeout, setelah itu delivery dianggap macet dan dicoba lagi
+	lease := time.Duration(webhookBatchSize)*service.Client.Timeout + time.Minute
+
+	deliveries, err := service.WebhookRepository.ClaimDueDeliveries(ctx, tx, webhookBatchSize, lease)
+	if err != nil {
+		return nil, fmt.Errorf("failed when calling ClaimDueDeliveries repository: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// send POSTs the payload with its signature, any answer outside 2xx is an error
+func (service *WebhookServiceImpl) send(ctx context.Context, delivery domain.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.EndpointURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SayGenFix-Webhooks/1.0")
+	req.Header.Set("X-SayGenFix-Event", delivery.Event)
+	req.Header.Set("X-SayGenFix-Delivery", delivery.Id)
+	req.Header.Set(helper.WebhookSignatureHeader, helper.SignWebhook(delivery.EndpointSecret, time.Now(), body))
+
+	resp, err := service.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, webhookErrorLength))
+		if snippet := strings.TrimSpace(string(responseBody)); snippet != "" {
+			return resp.StatusCode, fmt.Errorf("endpoint answered %d: %s", resp.StatusCode, snippet)
+		}
+		return resp.StatusCode, fmt.Errorf("endpoint answered %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// recordAttempt stores the result of one attempt and schedules the retry when it failed
+func (service *WebhookServiceImpl) recordAttempt(ctx context.Context, delivery domain.WebhookDelivery, statusCode int, sendErr error) error {
+	// Open transaction
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open db transaction: %w", err)
+	}
+	defer helper.CommitOrRollback(ctx, tx)
+
+	return service.saveAttempt(ctx, tx, delivery, statusCode, sendErr)
+}
+
+// saveAttempt marks the delivery as succeeded, failed for good after WebhookMaxAttempts, or due
+// again after the retry delay of the attempt
+func (service *WebhookServiceImpl) saveAttempt(ctx context.Context, tx pgx.Tx, delivery domain.WebhookDelivery, statusCode int, sendErr error) error {
+	if sendErr == nil {
+		if err := service.WebhookRepository.MarkDeliverySucceeded(ctx, tx, delivery.Id, statusCode); err != nil {
+			return fmt.Errorf("failed when calling MarkDeliverySucceeded repository: %w", err)
+		}
+		return nil
+	}
+
+	lastError := sendErr.Error()
+	if len(lastError) > webhookErrorLength {
+		lastError = lastError[:webhookErrorLength]
+	}
+	lastError = strings.ToValidUTF8(lastError, "")
+
+	attempt := delivery.Attempts + 1
+	if attempt >= service.Config.WebhookMaxAttempts {
+		slog.Warn("webhook delivery failed for good", "delivery_id", delivery.Id, "endpoint_id", delivery.EndpointId, "attempts", attempt, "err", sendErr)
+
+		if err := service.WebhookRepository.MarkDeliveryFailed(ctx, tx, delivery.Id, statusCode, lastError); err != nil {
+			return fmt.Errorf("failed when calling MarkDeliveryFailed repository: %w", err)
+		}
+		return nil
+	}
+
+	delay := webhookRetryDelays[min(attempt, len(webhookRetryDelays))-1]
+	slog.Info("webhook delivery failed, retrying later", "delivery_id", delivery.Id, "endpoint_id", delivery.EndpointId, "attempt", attempt, "retry_in", delay, "err", sendErr)
+
+	if err := service.WebhookRepository.MarkDeliveryRetry(ctx, tx, delivery.Id, statusCode, lastError, delay); err != nil {
+		return fmt.Errorf("failed when calling MarkDeliveryRetry repository: %w", err)
+	}
+
+	return nil
+}
+
+func newWebhookSecret() (string, error) {
+	secret, err := helper.GenerateToken()
+	if err != nil {
+		return "", fmt.Errorf("failed when calling GenerateToken helper: %w", err)
+	}
+
+	return webhookSecretPrefix + secret, nil
+}
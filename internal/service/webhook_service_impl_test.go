@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/config"
+	"github.com/mhaatha/go-template-saygenfix/internal/helper"
+	"github.com/mhaatha/go-template-saygenfix/internal/model/domain"
+)
+
+const testWebhookSecret = "whsec_rahasia"
+
+// webhookReceiver checks the signature like a receiver would and answers with status
+type webhookReceiver struct {
+	status   int
+	body     string
+	requests []*http.Request
+	errors   []error
+}
+
+func (receiver *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	receiver.requests = append(receiver.requests, r)
+	receiver.errors = append(receiver.errors, helper.VerifyWebhookSignature(testWebhookSecret, r.Header.Get(helper.WebhookSignatureHeader), body, time.Now(), 5*time.Minute))
+
+	w.WriteHeader(receiver.status)
+	w.Write([]byte(receiver.body))
+}
+
+func newTestWebhookService(t *testing.T, receiver http.Handler) (*WebhookServiceImpl, *httptest.Server) {
+	t.Helper()
+
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	return &WebhookServiceImpl{
+		WebhookRepository: newFakeWebhookRepository(),
+		Config:            &config.Config{WebhookMaxAttempts: 5},
+		Client:            server.Client(),
+	}, server
+}
+
+func testDelivery(endpointURL string) domain.WebhookDelivery {
+	return domain.WebhookDelivery{
+		Id:             "delivery-1",
+		EndpointId:     "endpoint-1",
+		EndpointURL:    endpointURL,
+		EndpointSecret: testWebhookSecret,
+		Event:          domain.WebhookEventAttemptScored,
+		Payload:        `{"type":"attempt.scored"}`,
+	}
+}
+
+func TestWebhookSendSignsPayload(t *testing.T) {
+	receiver := &webhookReceiver{status: http.StatusNoContent}
+	service, server := newTestWebhookService(t, receiver)
+
+	statusCode, err := service.send(context.Background(), testDelivery(server.URL))
+	if err != nil || statusCode != http.StatusNoContent {
+		t.Fatalf("send = %d, %v, want 204 without error", statusCode, err)
+	}
+
+	request := receiver.requests[0]
+	if receiver.errors[0] != nil {
+		t.Fatalf("receiver could not verify the signature: %v", receiver.errors[0])
+	}
+	if request.Header.Get("X-SayGenFix-Event") != domain.WebhookEventAttemptScored || request.Header.Get("X-SayGenFix-Delivery") != "delivery-1" {
+		t.Fatalf("unexpected headers %v", request.Header)
+	}
+
+	// Secret lain tidak boleh lolos verifikasi
+	delivery := testDelivery(server.URL)
+	delivery.EndpointSecret = "whsec_lain"
+	service.send(context.Background(), delivery)
+	if !errors.Is(receiver.errors[1], helper.ErrWebhookSignature) {
+		t.Fatalf("signature with another secret = %v, want ErrWebhookSignature", receiver.errors[1])
+	}
+}
+
+func TestWebhookSendServerError(t *testing.T) {
+	service, server := newTestWebhookService(t, &webhookReceiver{status: http.StatusBadGateway, body: "upstream down"})
+
+	statusCode, err := service.send(context.Background(), testDelivery(server.URL))
+	if statusCode != http.StatusBadGateway || err == nil || !strings.Contains(err.Error(), "upstream down") {
+		t.Fatalf("send = %d, %v, want 502 with the response body", statusCode, err)
+	}
+}
+
+func TestWebhookSaveAttempt(t *testing.T) {
+	sendErr := errors.New("endpoint answered 500")
+
+	tests := []struct {
+		name       string
+		attempts   int
+		statusCode int
+		sendErr    error
+		want       webhookMark
+	}{
+		{"success", 0, http.StatusOK, nil, webhookMark{status: domain.WebhookDeliverySucceeded, statusCode: http.StatusOK}},
+		{"first failure retries after a minute", 0, http.StatusInternalServerError, sendErr, webhookMark{status: domain.WebhookDeliveryPending, statusCode: http.StatusInternalServerError, lastError: sendErr.Error(), delay: time.Minute}},
+		{"third failure waits longer", 2, http.StatusInternalServerError, sendErr, webhookMark{status: domain.WebhookDeliveryPending, statusCode: http.StatusInternalServerError, lastError: sendErr.Error(), delay: 15 * time.Minute}},
+		{"last attempt fails for good", 4, http.StatusInternalServerError, sendErr, webhookMark{status: domain.WebhookDeliveryFailed, statusCode: http.StatusInternalServerError, lastError: sendErr.Error()}},
+		{"long error is cut", 0, 0, errors.New(strings.Repeat("x", 2*webhookErrorLength)), webhookMark{status: domain.WebhookDeliveryPending, lastError: strings.Repeat("x", webhookErrorLength), delay: time.Minute}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeWebhookRepository()
+			service := &WebhookServiceImpl{WebhookRepository: repo, Config: &config.Config{WebhookMaxAttempts: 5}}
+
+			delivery := testDelivery("https://example.test/hook")
+			delivery.Attempts = tt.attempts
+			if err := service.saveAttempt(context.Background(), nil, delivery, tt.statusCode, tt.sendErr); err != nil {
+				t.Fatalf("saveAttempt: %v", err)
+			}
+
+			if got := repo.marks[delivery.Id]; got != tt.want {
+				t.Fatalf("delivery marked %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookClientRefusesPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	delivery := testDelivery(server.URL)
+
+	service := &WebhookServiceImpl{Client: newWebhookClient(time.Second, false)}
+	if _, err := service.send(context.Background(), delivery); !errors.Is(err, errWebhookPrivateAddress) {
+		t.Fatalf("send to loopback = %v, want errWebhookPrivateAddress", err)
+	}
+
+	service.Client = newWebhookClient(time.Second, true)
+	if _, err := service.send(context.Background(), delivery); err != nil {
+		t.Fatalf("send to loopback with private networks allowed: %v", err)
+	}
+}
+
+func TestWebhookEventRoles(t *testing.T) {
+	tests := []struct {
+		event string
+		want  []string
+	}{
+		{domain.WebhookEventExamActivated, []string{domain.ExamRoleEditor, domain.ExamRoleGrader, domain.ExamRoleOwner, domain.ExamRoleViewer}},
+		{domain.WebhookEventAttemptSubmitted, []string{domain.ExamRoleEditor, domain.ExamRoleGrader, domain.ExamRoleOwner}},
+		{domain.WebhookEventAttemptScored, []string{domain.ExamRoleEditor, domain.ExamRoleGrader, domain.ExamRoleOwner}},
+		{domain.WebhookEventExamRegraded, []string{domain.ExamRoleEditor, domain.ExamRoleGrader, domain.ExamRoleOwner}},
+	}
+
+	for _, tt := range tests {
+		if got := webhookEventRoles(tt.event); !slices.Equal(got, tt.want) {
+			t.Errorf("webhookEventRoles(%s) = %q, want %q", tt.event, got, tt.want)
+		}
+	}
+}
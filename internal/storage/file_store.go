@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mhaatha/go-template-saygenfix/internal/config"
+)
+
+// ErrNotFound is returned when a key does not exist in the store
+var ErrNotFound = errors.New("file not found in store")
+
+// FileStore keeps uploaded documents so they can be reused after the request ends
+type FileStore interface {
+	Save(ctx context.Context, key string, content io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewFileStore returns the store selected by FILE_STORE_DRIVER, local disk is the default
+func NewFileStore(cfg *config.Config) (FileStore, error) {
+	switch cfg.FileStoreDriver {
+	case "", "local":
+		return NewLocalFileStore(cfg.FileStoreLocalDir), nil
+	case "s3":
+		return NewS3FileStore(S3Options{
+			Endpoint:     cfg.S3Endpoint,
+			Region:       cfg.S3Region,
+			Bucket:       cfg.S3Bucket,
+			AccessKey:    cfg.S3AccessKey,
+			SecretKey:    cfg.S3SecretKey,
+			UsePathStyle: cfg.S3UsePathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown file store driver %q", cfg.FileStoreDriver)
+	}
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func NewLocalFileStore(baseDir string) FileStore {
+	if baseDir == "" {
+		baseDir = "../../storage"
+	}
+
+	return &LocalFileStore{
+		BaseDir: baseDir,
+	}
+}
+
+type LocalFileStore struct {
+	BaseDir string
+}
+
+func (store *LocalFileStore) Save(ctx context.Context, key string, content io.Reader) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Write to a temporary file first so a failed upload never leaves a partial file behind
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := io.Copy(tmpFile, content); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+
+	return os.Rename(tmpFile.Name(), path)
+}
+
+func (store *LocalFileStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := store.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (store *LocalFileStore) Delete(ctx context.Context, key string) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// path resolves key inside BaseDir and rejects keys that try to escape it
+func (store *LocalFileStore) path(key string) (string, error) {
+	cleanKey := filepath.Clean("/" + key)
+	if strings.Contains(cleanKey, "..") {
+		return "", fmt.Errorf("invalid file key %q", key)
+	}
+
+	return filepath.Join(store.BaseDir, filepath.FromSlash(cleanKey)), nil
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type S3Options struct {
+	// Endpoint is the base URL of the S3 compatible service, e.g. http://localhost:9000 for MinIO
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// UsePathStyle puts the bucket in the path instead of the host name, MinIO needs this
+	UsePathStyle bool
+}
+
+func NewS3FileStore(opts S3Options) (FileStore, error) {
+	if opts.Endpoint == "" || opts.Bucket == "" {
+		return nil, errors.New("s3 endpoint and bucket are required")
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+
+	endpoint, err := url.Parse(opts.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint: %w", err)
+	}
+
+	return &S3FileStore{
+		Options:  opts,
+		Endpoint: endpoint,
+		Client:   &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+// S3FileStore talks to S3 compatible object storage using signature version 4
+type S3FileStore struct {
+	Options  S3Options
+	Endpoint *url.URL
+	Client   *http.Client
+}
+
+func (store *S3FileStore) Save(ctx context.Context, key string, content io.Reader) error {
+	body, err := io.ReadAll(content)
+	if err != nil {
+		return fmt.Errorf("failed to read content: %w", err)
+	}
+
+	resp, err := store.do(ctx, http.MethodPut, key, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return store.responseError(resp)
+	}
+
+	return nil
+}
+
+func (store *S3FileStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := store.do(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, store.responseError(resp)
+	}
+
+	return resp.Body, nil
+}
+
+func (store *S3FileStore) Delete(ctx context.Context, key string) error {
+	resp, err := store.do(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return store.responseError(resp)
+	}
+
+	return nil
+}
+
+func (store *S3FileStore) do(ctx context.Context, method, key string, body []byte) (*http.Response, error) {
+	// Path dari endpoint dipertahankan, misal MinIO di belakang reverse proxy pada /storage
+	objectURL := *store.Endpoint
+	basePath := strings.TrimSuffix(store.Endpoint.Path, "/")
+	if store.Options.UsePathStyle {
+		objectURL.Path = basePath + "/" + store.Options.Bucket + "/" + key
+	} else {
+		objectURL.Host = store.Options.Bucket + "." + store.Endpoint.Host
+		objectURL.Path = basePath + "/" + key
+	}
+	// Path yang dikirim harus sama persis dengan yang ditandatangani
+	objectURL.RawPath = uriEncodePath(objectURL.Path)
+
+	req, err := http.NewRequestWithContext(ctx, method, objectURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 request: %w", err)
+	}
+	req.ContentLength = int64(len(body))
+
+	store.sign(req, body, time.Now().UTC())
+
+	resp, err := store.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send s3 request: %w", err)
+	}
+
+	return resp, nil
+}
+
+// sign adds the AWS signature version 4 headers to req
+func (store *S3FileStore) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	shortDate := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := shortDate + "/" + store.Options.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	signingKey := hmacSHA256([]byte("AWS4"+store.Options.SecretKey), shortDate)
+	signingKey = hmacSHA256(signingKey, store.Options.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		store.Options.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+func (store *S3FileStore) responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("s3 returned status %d: %s", resp.StatusCode, string(body))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncodePath encodes every byte except the unreserved characters and '/' as S3 expects
+func uriEncodePath(path string) string {
+	var encoded strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			encoded.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&encoded, "%%%02X", c)
+	}
+	return encoded.String()
+}
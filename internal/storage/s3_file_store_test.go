@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testRegion    = "ap-southeast-3"
+)
+
+// fakeS3 is a minimal in-memory S3 that checks the signature version 4 of every request
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	paths   []string
+	hosts   []string
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string][]byte{}}
+}
+
+func (s3 *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if err := verifySigV4(r, body); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	s3.mu.Lock()
+	defer s3.mu.Unlock()
+	s3.paths = append(s3.paths, r.URL.Path)
+	s3.hosts = append(s3.hosts, r.Host)
+
+	switch r.Method {
+	case http.MethodPut:
+		s3.objects[r.URL.Path] = body
+	case http.MethodGet:
+		object, ok := s3.objects[r.URL.Path]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Write(object)
+	case http.MethodDelete:
+		delete(s3.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verifySigV4 recomputes the signature from what arrived on the wire, independent of the signer
+func verifySigV4(r *http.Request, body []byte) error {
+	auth := r.Header.Get("Authorization")
+	prefix := "AWS4-HMAC-SHA256 Credential=" + testAccessKey + "/"
+	if !strings.HasPrefix(auth, prefix) {
+		return errors.New("unexpected authorization header: " + auth)
+	}
+	parts := strings.Split(strings.TrimPrefix(auth, prefix), ", ")
+	if len(parts) != 3 {
+		return errors.New("malformed authorization header")
+	}
+	scope := parts[0]
+	signedHeaders := strings.TrimPrefix(parts[1], "SignedHeaders=")
+	signature := strings.TrimPrefix(parts[2], "Signature=")
+
+	scopeParts := strings.Split(scope, "/")
+	if len(scopeParts) != 4 || scopeParts[1] != testRegion || scopeParts[2] != "s3" || scopeParts[3] != "aws4_request" {
+		return errors.New("unexpected credential scope: " + scope)
+	}
+
+	bodySum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(bodySum[:])
+	if r.Header.Get("X-Amz-Content-Sha256") != payloadHash {
+		return errors.New("payload hash mismatch")
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	requestSum := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + r.Header.Get("X-Amz-Date") + "\n" + scope + "\n" + hex.EncodeToString(requestSum[:])
+
+	key := []byte("AWS4" + testSecretKey)
+	for _, part := range []string{scopeParts[0], testRegion, "s3", "aws4_request", stringToSign} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+	if !hmac.Equal([]byte(hex.EncodeToString(key)), []byte(signature)) {
+		return errors.New("signature mismatch")
+	}
+
+	return nil
+}
+
+func newTestS3Store(t *testing.T, endpoint string, pathStyle bool) *S3FileStore {
+	t.Helper()
+
+	store, err := NewS3FileStore(S3Options{
+		Endpoint:     endpoint,
+		Region:       testRegion,
+		Bucket:       "materials",
+		AccessKey:    testAccessKey,
+		SecretKey:    testSecretKey,
+		UsePathStyle: pathStyle,
+	})
+	if err != nil {
+		t.Fatalf("NewS3FileStore: %v", err)
+	}
+	return store.(*S3FileStore)
+}
+
+func TestS3FileStoreRoundTrip(t *testing.T) {
+	fake := newFakeS3()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		endpoint string
+		wantPath string
+	}{
+		{"root endpoint", server.URL, "/materials/docs/Bab 1 (final)+revisi.pdf"},
+		{"endpoint with path", server.URL + "/storage/", "/storage/materials/docs/Bab 1 (final)+revisi.pdf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestS3Store(t, tt.endpoint, true)
+			ctx := context.Background()
+			key := "docs/Bab 1 (final)+revisi.pdf"
+
+			if err := store.Save(ctx, key, strings.NewReader("isi dokumen")); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			if got := fake.paths[len(fake.paths)-1]; got != tt.wantPath {
+				t.Fatalf("object path = %q, want %q", got, tt.wantPath)
+			}
+
+			reader, err := store.Open(ctx, key)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			content, _ := io.ReadAll(reader)
+			reader.Close()
+			if string(content) != "isi dokumen" {
+				t.Fatalf("content = %q", content)
+			}
+
+			if err := store.Delete(ctx, key); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if _, err := store.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Open after delete = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestS3FileStoreVirtualHostStyle(t *testing.T) {
+	fake := newFakeS3()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store := newTestS3Store(t, "http://s3.test:9000/", false)
+	// Semua host diarahkan ke server test
+	store.Client = &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	}}
+
+	if err := store.Save(context.Background(), "a.pdf", strings.NewReader("x")); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if fake.hosts[0] != "materials.s3.test:9000" || fake.paths[0] != "/a.pdf" {
+		t.Fatalf("request went to %s%s", fake.hosts[0], fake.paths[0])
+	}
+}
+
+func TestS3FileStoreRejectsWrongSecret(t *testing.T) {
+	server := httptest.NewServer(newFakeS3())
+	defer server.Close()
+
+	store := newTestS3Store(t, server.URL, true)
+	store.Options.SecretKey = "bukan-rahasia-yang-benar"
+
+	err := store.Save(context.Background(), "a.pdf", strings.NewReader("x"))
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("Save with wrong secret = %v, want 403", err)
+	}
+}
@@ -0,0 +1,29 @@
+// Kirim token CSRF dari cookie csrf_token pada setiap request yang mengubah data
+(function () {
+    function csrfToken() {
+        var match = document.cookie.match(/(?:^|;\s*)csrf_token=([^;]+)/);
+        return match ? decodeURIComponent(match[1]) : '';
+    }
+
+    // Request HTMX memakai header X-CSRF-Token
+    document.addEventListener('htmx:configRequest', function (event) {
+        event.detail.headers['X-CSRF-Token'] = csrfToken();
+    });
+
+    // Form biasa dengan method POST mendapat input tersembunyi csrf_token
+    document.addEventListener('submit', function (event) {
+        var form = event.target;
+        if (!(form instanceof HTMLFormElement) || form.method.toLowerCase() !== 'post') {
+            return;
+        }
+
+        var input = form.querySelector('input[name="csrf_token"]');
+        if (!input) {
+            input = document.createElement('input');
+            input.type = 'hidden';
+            input.name = 'csrf_token';
+            form.appendChild(input);
+        }
+        input.value = csrfToken();
+    }, true);
+})();